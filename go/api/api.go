@@ -3,35 +3,107 @@ package api
 
 import (
 	"context"
+	"io"
 	"time"
 
 	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/datadir"
 	"github.com/oasisprotocol/oasis-core/go/common/errors"
+	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	"github.com/oasisprotocol/oasis-core/go/common/memlimit"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
 	"github.com/oasisprotocol/oasis-core/go/common/version"
 	"github.com/oasisprotocol/oasis-core/go/config"
 	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
 	p2p "github.com/oasisprotocol/oasis-core/go/p2p/api"
 	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
 	block "github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	"github.com/oasisprotocol/oasis-core/go/runtime/bundle"
 	"github.com/oasisprotocol/oasis-core/go/runtime/bundle/component"
 	"github.com/oasisprotocol/oasis-core/go/runtime/history"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
 	storage "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
+	nodedb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
 	upgrade "github.com/oasisprotocol/oasis-core/go/upgrade/api"
 	commonWorker "github.com/oasisprotocol/oasis-core/go/worker/common/api"
 	executorWorker "github.com/oasisprotocol/oasis-core/go/worker/compute/executor/api"
 	keymanagerWorker "github.com/oasisprotocol/oasis-core/go/worker/keymanager/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/registration/selfcheck"
 	storageWorker "github.com/oasisprotocol/oasis-core/go/worker/storage/api"
 )
 
 // ModuleName is the module name for the controller service.
 const ModuleName = "control"
 
-// ErrNotImplemented is the error raised when the node does not support the required functionality.
-var ErrNotImplemented = errors.New(ModuleName, 1, "control: not implemented")
+var (
+	// ErrNotImplemented is the error raised when the node does not support the required functionality.
+	ErrNotImplemented = errors.New(ModuleName, 1, "control: not implemented")
+
+	// ErrDebugOnly is the error raised when the requested operation is only available in debug mode.
+	ErrDebugOnly = errors.New(ModuleName, 2, "control: operation only available in debug mode")
+
+	// ErrBundleNotFound is the error raised when the requested bundle is not registered.
+	ErrBundleNotFound = errors.New(ModuleName, 3, "control: bundle not found")
+
+	// ErrBundleInUse is the error raised when removing a bundle that is required by the active
+	// runtime descriptor.
+	ErrBundleInUse = errors.New(ModuleName, 4, "control: bundle in use by the active runtime descriptor")
+
+	// ErrBundleTooLarge is the error raised when an uploaded bundle exceeds the size limit.
+	ErrBundleTooLarge = errors.New(ModuleName, 5, "control: bundle too large")
+
+	// ErrBundleChecksumMismatch is the error raised when an uploaded bundle does not match its
+	// checksum.
+	ErrBundleChecksumMismatch = errors.New(ModuleName, 6, "control: bundle checksum mismatch")
+
+	// ErrMalformedBundleStream is the error raised when a bundle upload stream is malformed.
+	ErrMalformedBundleStream = errors.New(ModuleName, 7, "control: malformed bundle stream")
+
+	// ErrInvalidShutdownRequest is the error raised when a shutdown request is invalid.
+	ErrInvalidShutdownRequest = errors.New(ModuleName, 8, "control: invalid shutdown request")
+
+	// ErrInvalidLogLevelRequest is the error raised when a log level request is invalid.
+	ErrInvalidLogLevelRequest = errors.New(ModuleName, 9, "control: invalid log level request")
+
+	// ErrStorageBackupInProgress is the error raised when a storage backup is requested while
+	// another one is still being streamed.
+	ErrStorageBackupInProgress = errors.New(ModuleName, 10, "control: storage backup already in progress")
+
+	// ErrUnknownRuntime is the error raised when the requested runtime is not configured on the
+	// node.
+	ErrUnknownRuntime = errors.New(ModuleName, 11, "control: unknown runtime")
+
+	// ErrInvalidRotateKeysRequest is the error raised when a key rotation request is invalid.
+	ErrInvalidRotateKeysRequest = errors.New(ModuleName, 12, "control: invalid key rotation request")
+
+	// ErrKeyRotationNotSupported is the error raised when rotation of the requested key is not
+	// supported while the node is running.
+	ErrKeyRotationNotSupported = errors.New(ModuleName, 13, "control: key rotation not supported")
+
+	// ErrInvalidPruneStorageRequest is the error raised when a storage pruning request is invalid.
+	ErrInvalidPruneStorageRequest = errors.New(ModuleName, 14, "control: invalid storage pruning request")
+
+	// ErrStorageRestoreInProgress is the error raised when storage pruning is requested while a
+	// checkpoint restore is in progress.
+	ErrStorageRestoreInProgress = errors.New(ModuleName, 15, "control: storage restore in progress")
+
+	// ErrInvalidConfig is the error raised when a configuration file that fails to parse or
+	// validate is applied.
+	ErrInvalidConfig = errors.New(ModuleName, 16, "control: invalid configuration")
+
+	// ErrRuntimeNotStarted is the error raised when restarting a runtime whose host has not been
+	// started yet.
+	ErrRuntimeNotStarted = errors.New(ModuleName, 17, "control: runtime not started")
+
+	// ErrRuntimeRestarting is the error raised when restarting a runtime whose host is already
+	// being restarted.
+	ErrRuntimeRestarting = errors.New(ModuleName, 18, "control: runtime restart already in progress")
+)
 
 // NodeController is a node controller interface.
 type NodeController interface {
@@ -39,8 +111,17 @@ type NodeController interface {
 	//
 	// If the wait argument is true then the method will also wait for the
 	// shutdown to complete.
+	//
+	// This is equivalent to calling RequestShutdownEx with only Wait set.
 	RequestShutdown(ctx context.Context, wait bool) error
 
+	// RequestShutdownEx requests the node to shut down gracefully and records the given
+	// reason and annotation together with the shutdown reason.
+	//
+	// In case a grace period is given, the node exits once it elapses even if not all of
+	// the workers have drained.
+	RequestShutdownEx(ctx context.Context, req *ShutdownRequest) error
+
 	// WaitSync waits for the node to finish syncing.
 	WaitSync(ctx context.Context) error
 
@@ -48,27 +129,201 @@ type NodeController interface {
 	IsSynced(ctx context.Context) (bool, error)
 
 	// WaitReady waits for the node to accept runtime work.
+	//
+	// The node is ready once all of the configured runtimes are ready.
 	WaitReady(ctx context.Context) error
 
 	// IsReady checks whether the node is ready to accept runtime work.
+	//
+	// The node is ready iff all of the configured runtimes are ready.
 	IsReady(ctx context.Context) (bool, error)
 
+	// WaitRuntimeReady waits for the given runtime to accept runtime work, regardless of the
+	// readiness of other runtimes.
+	//
+	// In case the runtime is not configured on the node, ErrUnknownRuntime is returned.
+	WaitRuntimeReady(ctx context.Context, runtimeID common.Namespace) error
+
+	// IsRuntimeReady checks whether the given runtime is ready to accept runtime work.
+	//
+	// In case the runtime is not configured on the node, ErrUnknownRuntime is returned.
+	IsRuntimeReady(ctx context.Context, runtimeID common.Namespace) (bool, error)
+
+	// RestartRuntime tears down and restarts the host of the given runtime, e.g., when the runtime
+	// is stuck.
+	//
+	// Unless force is set, in-flight batch executions are given up to a timeout to complete before
+	// the runtime is restarted. In case the runtime is not configured on the node, ErrUnknownRuntime
+	// is returned, in case its host has not been started yet, ErrRuntimeNotStarted is returned and
+	// in case its host is already being restarted, ErrRuntimeRestarting is returned.
+	RestartRuntime(ctx context.Context, runtimeID common.Namespace, force bool) error
+
 	// UpgradeBinary submits an upgrade descriptor to a running node.
 	// The node will wait for the appropriate epoch, then update its binaries
 	// and shut down.
 	UpgradeBinary(ctx context.Context, descriptor *upgrade.Descriptor) error
 
 	// CancelUpgrade cancels the specific pending upgrade, unless it is already in progress.
+	//
+	// In case no such upgrade is pending, this returns upgrade.ErrUpgradeNotFound.
 	CancelUpgrade(ctx context.Context, descriptor *upgrade.Descriptor) error
 
+	// CancelUpgradeByName cancels the pending upgrade with the given handler name, regardless of
+	// the other descriptor fields, unless it is already in progress.
+	//
+	// In case no such upgrade is pending, this returns upgrade.ErrUpgradeNotFound.
+	CancelUpgradeByName(ctx context.Context, name string) error
+
+	// ListUpgrades returns the node's pending upgrades.
+	ListUpgrades(ctx context.Context) ([]*upgrade.PendingUpgrade, error)
+
+	// WatchUpgradeEvents returns a channel that produces a stream of upgrade progress events,
+	// emitted when an upgrade is scheduled, reaches its epoch, runs its migration handler stages
+	// or is cancelled.
+	WatchUpgradeEvents(ctx context.Context) (<-chan *upgrade.Event, pubsub.ClosableSubscription, error)
+
+	// SetHaltEpoch sets the epoch at which the consensus layer should stop processing blocks.
+	// Passing beacon.EpochInvalid clears the halt epoch and resumes block processing.
+	SetHaltEpoch(ctx context.Context, epoch beacon.EpochTime) error
+
+	// VerifyDataDir verifies the node's data directory against the expected layout and reports
+	// unknown and stale entries.
+	VerifyDataDir(ctx context.Context) (*datadir.VerifyResult, error)
+
 	// GetStatus returns the current status overview of the node.
 	GetStatus(ctx context.Context) (*Status, error)
 
+	// GetStatusEx returns the current status overview of the node, gathering only the sections
+	// selected by the given filter. A nil filter selects all sections.
+	GetStatusEx(ctx context.Context, filter *StatusFilter) (*Status, error)
+
+	// WatchStatus returns a channel that produces a stream of node status overviews. A new status
+	// is published whenever the registration, consensus sync or runtime status changes, and
+	// periodically as a heartbeat.
+	WatchStatus(ctx context.Context) (<-chan *Status, pubsub.ClosableSubscription, error)
+
+	// WipeRuntimeLocalStorage wipes the untrusted node-local key-value store of the given runtime.
+	WipeRuntimeLocalStorage(ctx context.Context, runtimeID common.Namespace) error
+
+	// GetGRPCSessions returns the active connections of all gRPC listeners.
+	GetGRPCSessions(ctx context.Context) ([]*cmnGrpc.SessionInfo, error)
+
+	// CloseGRPCSession forcibly closes the gRPC connection with the given session identifier.
+	//
+	// This method is only available when the node is running in debug mode.
+	CloseGRPCSession(ctx context.Context, id uint64) error
+
+	// GetGRPCSlowCallThresholds returns the thresholds above which gRPC calls are logged as slow.
+	GetGRPCSlowCallThresholds(ctx context.Context) (*cmnGrpc.SlowCallThresholds, error)
+
+	// SetGRPCSlowCallThresholds updates the thresholds above which gRPC calls are logged as slow.
+	SetGRPCSlowCallThresholds(ctx context.Context, thresholds *cmnGrpc.SlowCallThresholds) error
+
+	// SetLogLevel atomically sets the log level of all loggers whose module matches the module
+	// glob in the request and returns the number of affected loggers.
+	//
+	// If no module glob is given, the default log level is set instead.
+	SetLogLevel(ctx context.Context, req *LogLevelRequest) (uint64, error)
+
+	// GetLogLevels returns the current log levels, keyed by logger module. The default log level
+	// is keyed by DefaultLogLevelModule.
+	GetLogLevels(ctx context.Context) (map[string]string, error)
+
+	// TriggerStorageGC triggers garbage collection of the given runtime's storage, e.g., to
+	// reclaim space after a big prune, and returns its statistics once it completes.
+	TriggerStorageGC(ctx context.Context, req *TriggerStorageGCRequest) (*nodedb.GCStats, error)
+
+	// BackupStorage writes a backup of the given runtime's storage into the given writer while
+	// the node keeps running, and returns the version at which the next incremental backup
+	// should start.
+	//
+	// Backups are streamed at a limited rate and only one backup may be streamed at a time,
+	// otherwise ErrStorageBackupInProgress is returned.
+	BackupStorage(ctx context.Context, req *BackupStorageRequest, w io.Writer) (uint64, error)
+
+	// PruneStorage prunes old versions of the given runtime's storage, either up to the given
+	// earliest version or keeping the given number of latest versions, and returns the number of
+	// pruned versions and reclaimed bytes.
+	//
+	// Pruning is rejected with ErrStorageRestoreInProgress while a checkpoint restore is in
+	// progress. Versions that are being read are skipped, so it is safe to prune storage while
+	// the node is serving reads.
+	PruneStorage(ctx context.Context, req *PruneStorageRequest) (*PruneStorageResponse, error)
+
+	// CreateCheckpoint synchronously creates a storage checkpoint of the given runtime at the
+	// requested version, outside the regular checkpoint schedule, and returns its summary.
+	//
+	// In case the runtime is not configured on the node, ErrUnknownRuntime is returned. In case
+	// the version has not yet been finalized, checkpoint.ErrVersionNotFinalized is returned and in
+	// case the checkpoint already exists, checkpoint.ErrCheckpointAlreadyExists is returned.
+	CreateCheckpoint(ctx context.Context, req *CreateCheckpointRequest) (*CheckpointInfo, error)
+
+	// GetPublicKeys returns all public keys of the node together with their roles, including
+	// rotated out keys that are still valid during their grace window.
+	GetPublicKeys(ctx context.Context) ([]*PublicKeyInfo, error)
+
+	// RotateKeys rotates the requested node keys without restarting the node and returns the new
+	// public keys. The rotated out keys remain valid until the end of the grace window.
+	//
+	// The new keys are pushed to the configured sentry nodes and the node re-registers in order
+	// to update its descriptor.
+	RotateKeys(ctx context.Context, req *RotateKeysRequest) (*RotateKeysResponse, error)
+
+	// RunSelfTest asks the configured self-check peers to connect back to each address that the
+	// node would advertise in its node descriptor and returns the aggregated reachability results.
+	RunSelfTest(ctx context.Context) (*selfcheck.Report, error)
+
+	// ValidateConfig parses and validates the given configuration file the same way as the node
+	// does at startup, without applying it, and reports its changes against the currently
+	// effective configuration, classified as hot-applicable, restart-required or invalid.
+	ValidateConfig(ctx context.Context, raw []byte) (*ConfigValidationResult, error)
+
+	// ApplyConfig validates the given configuration file like ValidateConfig and applies only its
+	// hot-applicable changes to the running node. Other changes take effect after a restart.
+	//
+	// In case the configuration file is not valid, ErrInvalidConfig is returned.
+	ApplyConfig(ctx context.Context, raw []byte) (*ConfigValidationResult, error)
+
 	// AddBundle adds bundle from the given path.
 	//
 	// If the bundle upgrades an existing ROFL component, the latter will
-	// be upgraded to the new version.
+	// be upgraded to the new version. If the bundle's runtime is configured
+	// or dynamic runtimes are allowed, the runtime is started and the node
+	// re-registers for it without a restart.
 	AddBundle(ctx context.Context, path string) error
+
+	// ListBundles returns the bundles registered with the node.
+	ListBundles(ctx context.Context) ([]BundleInfo, error)
+
+	// RemoveBundle detaches the bundle with the given manifest hash from the node.
+	//
+	// Bundles required by the active runtime descriptor cannot be removed and ErrBundleInUse is
+	// returned instead.
+	RemoveBundle(ctx context.Context, manifestHash hash.Hash) error
+}
+
+// RestartRuntimeRequest is a request to restart a runtime's host.
+type RestartRuntimeRequest struct {
+	// RuntimeID is the identifier of the runtime to restart.
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// Force skips waiting for in-flight host calls to complete.
+	Force bool `json:"force,omitempty"`
+}
+
+// BundleInfo describes a bundle registered with the node.
+type BundleInfo struct {
+	// RuntimeID is the identifier of the runtime the bundle belongs to.
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// Version is the version of the bundle's runtime.
+	Version version.Version `json:"version"`
+
+	// ManifestHash is the hash of the bundle manifest.
+	ManifestHash hash.Hash `json:"manifest_hash"`
+
+	// Path is the path the bundle has been loaded from.
+	Path string `json:"path"`
 }
 
 // Status is the current status overview.
@@ -103,11 +358,23 @@ type Status struct {
 	// PendingUpgrades are the node's pending upgrades.
 	PendingUpgrades []*upgrade.PendingUpgrade `json:"pending_upgrades,omitempty"`
 
+	// Halt is the status of the operator-requested consensus halt, if any.
+	Halt *upgrade.HaltStatus `json:"halt,omitempty"`
+
 	// P2P is the P2P status of the node.
 	P2P *p2p.Status `json:"p2p,omitempty"`
 
 	// Seed is the seed node status if the node is a seed node.
 	Seed *SeedStatus `json:"seed,omitempty"`
+
+	// Shutdown is the reason for the shutdown in case the node is shutting down.
+	Shutdown *ShutdownReason `json:"shutdown,omitempty"`
+
+	// LastShutdown is the reason for the previous shutdown of the node, if recorded.
+	LastShutdown *ShutdownReason `json:"last_shutdown,omitempty"`
+
+	// MemoryLimit is the soft memory limit manager status in case it is enabled.
+	MemoryLimit *memlimit.Status `json:"memory_limit,omitempty"`
 }
 
 // DebugStatus is the current node debug status, listing the various node
@@ -159,12 +426,20 @@ type RegistrationStatus struct {
 
 	// NodeStatus is the registry live status of the node.
 	NodeStatus *registry.NodeStatus `json:"node_status,omitempty"`
+
+	// ElectionEligibility is the node's current committee election eligibility.
+	ElectionEligibility *scheduler.ElectionEligibility `json:"election_eligibility,omitempty"`
+
+	// SelfCheck is the result of the last advertised address self-check, if enabled.
+	SelfCheck *selfcheck.Report `json:"self_check,omitempty"`
 }
 
 // RuntimeStatus is the per-runtime status overview.
 type RuntimeStatus struct {
 	// Descriptor is the runtime registration descriptor.
 	Descriptor *registry.Runtime `json:"descriptor"`
+	// Dynamic is true iff the runtime was added while the node was running.
+	Dynamic bool `json:"dynamic,omitempty"`
 
 	// LatestRound is the round of the latest runtime block.
 	LatestRound uint64 `json:"latest_round"`
@@ -218,6 +493,10 @@ type ComponentStatus struct {
 	// Disabled specifies whether the component is disabled by default
 	// and needs to be explicitly enabled via node configuration to be used.
 	Disabled bool `json:"disabled,omitempty"`
+
+	// Requirements are the node capabilities required by the component and
+	// whether they are satisfied.
+	Requirements []bundle.RequirementStatus `json:"requirements,omitempty"`
 }
 
 // SeedStatus is the status of the seed node.
@@ -232,6 +511,63 @@ type SeedStatus struct {
 	NodePeers []string `json:"node_peers"`
 }
 
+// DefaultStorageGCDiscardRatio is the default ratio of data that must be discardable in order for a
+// file to be rewritten by a manually triggered storage garbage collection.
+const DefaultStorageGCDiscardRatio = 0.5
+
+// TriggerStorageGCRequest is a request to trigger garbage collection of a runtime's storage.
+type TriggerStorageGCRequest struct {
+	// RuntimeID is the identifier of the runtime whose storage should be garbage collected.
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// DiscardRatio is the ratio of data that must be discardable in order for a file to be
+	// rewritten. If zero, DefaultStorageGCDiscardRatio is used.
+	DiscardRatio float64 `json:"discard_ratio,omitempty"`
+}
+
+// CreateCheckpointRequest is a request to create a storage checkpoint of a runtime.
+type CreateCheckpointRequest struct {
+	// RuntimeID is the identifier of the runtime whose storage should be checkpointed.
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// Version is the finalized version to checkpoint. If not set, the latest finalized version
+	// is checkpointed.
+	Version *uint64 `json:"version,omitempty"`
+}
+
+// CheckpointInfo is the summary of a created storage checkpoint.
+type CheckpointInfo struct {
+	// Version is the checkpointed version.
+	Version uint64 `json:"version"`
+
+	// Roots are the checkpointed storage roots.
+	Roots []storage.Root `json:"roots"`
+
+	// Chunks is the total number of chunks of the checkpoint.
+	Chunks uint64 `json:"chunks"`
+
+	// Size is the total size of all chunks of the checkpoint in bytes.
+	Size uint64 `json:"size"`
+}
+
+// NewCheckpointInfo summarizes the given checkpoints of a single version, using the given chunk
+// provider to determine their size.
+func NewCheckpointInfo(ctx context.Context, provider checkpoint.ChunkProvider, cps []*checkpoint.Metadata) (*CheckpointInfo, error) {
+	var info CheckpointInfo
+	for _, cp := range cps {
+		size, err := checkpoint.GetCheckpointSize(ctx, provider, cp)
+		if err != nil {
+			return nil, err
+		}
+
+		info.Version = cp.Root.Version
+		info.Roots = append(info.Roots, cp.Root)
+		info.Chunks += uint64(len(cp.Chunks))
+		info.Size += size
+	}
+	return &info, nil
+}
+
 // DebugModuleName is the module name for the debug controller service.
 const DebugModuleName = "control/debug"
 
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/config"
+)
+
+const (
+	// BundleUploadDir is the directory within the node's data directory where bundles uploaded via
+	// AddBundleData are stored.
+	BundleUploadDir = "bundle-uploads"
+
+	// DefaultMaxBundleUploadSize is the default maximum size of bundles uploaded via AddBundleData.
+	DefaultMaxBundleUploadSize = 1024 * 1024 * 1024
+
+	// AddBundleDataChunkSize is the size of the chunks the client streams bundles in.
+	AddBundleDataChunkSize = 1024 * 1024
+)
+
+// AddBundleDataChunk is a chunk of a bundle streamed via AddBundleData.
+type AddBundleDataChunk struct {
+	// Checksum is the hash of the whole bundle. It must be set in the first chunk only.
+	Checksum *hash.Hash `json:"checksum,omitempty"`
+
+	// Data is the chunk data.
+	Data []byte `json:"data,omitempty"`
+}
+
+// bundleUploadConfig returns the directory and the maximum size of bundle uploads.
+func bundleUploadConfig() (string, uint64) {
+	hotConfigLock.RLock()
+	defer hotConfigLock.RUnlock()
+
+	maxSize := uint64(DefaultMaxBundleUploadSize)
+	if size := config.GlobalConfig.Common.Control.MaxBundleUploadSize; size != "" {
+		maxSize = uint64(config.ParseSizeInBytes(size))
+	}
+	return filepath.Join(config.GlobalConfig.Common.DataDir, BundleUploadDir), maxSize
+}
+
+// receiveBundle assembles the bundle chunks received via the given function into a file in the
+// given directory and returns its path.
+//
+// The received bundle is verified against the checksum in the first chunk. In case the bundle
+// exceeds the maximum size or fails verification, the file is removed and an error is returned.
+func receiveBundle(recv func(*AddBundleDataChunk) error, dir string, maxSize uint64) (_ string, err error) {
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("control: failed to create bundle upload directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, "upload-*.orc")
+	if err != nil {
+		return "", fmt.Errorf("control: failed to create bundle upload file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	var (
+		checksum *hash.Hash
+		size     uint64
+	)
+	hb := hash.NewBuilder()
+	for {
+		var chunk AddBundleDataChunk
+		switch rerr := recv(&chunk); {
+		case rerr == nil:
+		case errors.Is(rerr, io.EOF):
+			if checksum == nil {
+				return "", fmt.Errorf("%w: empty stream", ErrMalformedBundleStream)
+			}
+			if h := hb.Build(); !h.Equal(checksum) {
+				return "", fmt.Errorf("%w: expected %s got %s", ErrBundleChecksumMismatch, checksum, h)
+			}
+
+			if err = f.Close(); err != nil {
+				return "", fmt.Errorf("control: failed to write bundle upload file: %w", err)
+			}
+
+			// Name the file after its checksum so that repeated uploads of the same bundle reuse it.
+			path := filepath.Join(dir, checksum.String()+".orc")
+			if err = os.Rename(f.Name(), path); err != nil {
+				return "", fmt.Errorf("control: failed to store uploaded bundle: %w", err)
+			}
+			return path, nil
+		default:
+			return "", rerr
+		}
+
+		switch {
+		case checksum == nil && chunk.Checksum == nil:
+			return "", fmt.Errorf("%w: missing checksum", ErrMalformedBundleStream)
+		case checksum != nil && chunk.Checksum != nil:
+			return "", fmt.Errorf("%w: checksum in non-first chunk", ErrMalformedBundleStream)
+		case checksum == nil:
+			checksum = chunk.Checksum
+		}
+
+		size += uint64(len(chunk.Data))
+		if size > maxSize {
+			return "", fmt.Errorf("%w: exceeds %d bytes", ErrBundleTooLarge, maxSize)
+		}
+		_, _ = hb.Write(chunk.Data)
+		if _, err = f.Write(chunk.Data); err != nil {
+			return "", fmt.Errorf("control: failed to write bundle upload file: %w", err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+)
+
+func chunkReceiver(chunks []*AddBundleDataChunk, final error) func(*AddBundleDataChunk) error {
+	return func(chunk *AddBundleDataChunk) error {
+		if len(chunks) == 0 {
+			return final
+		}
+		*chunk = *chunks[0]
+		chunks = chunks[1:]
+		return nil
+	}
+}
+
+func TestReceiveBundle(t *testing.T) {
+	require := require.New(t)
+
+	data := []byte("this is not really an ORC archive")
+	checksum := hash.NewFromBytes(data)
+	var bogus hash.Hash
+	bogus.Empty()
+
+	requireEmpty := func(dir string) {
+		entries, err := os.ReadDir(dir)
+		require.NoError(err, "ReadDir")
+		require.Empty(entries, "upload directory should be cleaned up")
+	}
+
+	// Successful upload.
+	dir := filepath.Join(t.TempDir(), BundleUploadDir)
+	path, err := receiveBundle(chunkReceiver([]*AddBundleDataChunk{
+		{Checksum: &checksum, Data: data[:10]},
+		{Data: data[10:20]},
+		{Data: data[20:]},
+	}, io.EOF), dir, 1024)
+	require.NoError(err, "receiveBundle")
+	require.Equal(filepath.Join(dir, checksum.String()+".orc"), path)
+	stored, err := os.ReadFile(path)
+	require.NoError(err, "ReadFile")
+	require.Equal(data, stored)
+	entries, err := os.ReadDir(dir)
+	require.NoError(err, "ReadDir")
+	require.Len(entries, 1, "only the stored bundle should remain")
+
+	// Checksum mismatch.
+	dir = t.TempDir()
+	_, err = receiveBundle(chunkReceiver([]*AddBundleDataChunk{
+		{Checksum: &bogus, Data: data},
+	}, io.EOF), dir, 1024)
+	require.ErrorIs(err, ErrBundleChecksumMismatch)
+	requireEmpty(dir)
+
+	// Too large.
+	dir = t.TempDir()
+	_, err = receiveBundle(chunkReceiver([]*AddBundleDataChunk{
+		{Checksum: &checksum, Data: data[:20]},
+		{Data: data[20:]},
+	}, io.EOF), dir, 20)
+	require.ErrorIs(err, ErrBundleTooLarge)
+	requireEmpty(dir)
+
+	// Missing checksum.
+	dir = t.TempDir()
+	_, err = receiveBundle(chunkReceiver([]*AddBundleDataChunk{
+		{Data: data},
+	}, io.EOF), dir, 1024)
+	require.ErrorIs(err, ErrMalformedBundleStream)
+	requireEmpty(dir)
+
+	// Repeated checksum.
+	dir = t.TempDir()
+	_, err = receiveBundle(chunkReceiver([]*AddBundleDataChunk{
+		{Checksum: &checksum, Data: data[:10]},
+		{Checksum: &checksum, Data: data[10:]},
+	}, io.EOF), dir, 1024)
+	require.ErrorIs(err, ErrMalformedBundleStream)
+	requireEmpty(dir)
+
+	// Empty stream.
+	dir = t.TempDir()
+	_, err = receiveBundle(chunkReceiver(nil, io.EOF), dir, 1024)
+	require.ErrorIs(err, ErrMalformedBundleStream)
+	requireEmpty(dir)
+
+	// Interrupted stream.
+	dir = t.TempDir()
+	errInterrupted := fmt.Errorf("interrupted")
+	_, err = receiveBundle(chunkReceiver([]*AddBundleDataChunk{
+		{Checksum: &checksum, Data: data[:10]},
+	}, errInterrupted), dir, 1024)
+	require.ErrorIs(err, errInterrupted)
+	requireEmpty(dir)
+}
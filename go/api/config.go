@@ -0,0 +1,240 @@
+package api
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/config"
+)
+
+const (
+	configLogLevelPath             = "common.log.level"
+	configMaxBundleUploadSizePath  = "common.control.max_bundle_upload_size"
+	configMaxStorageBackupRatePath = "common.control.max_storage_backup_rate"
+)
+
+// hotConfigLock guards the hot-applicable fields of the live configuration, which ApplyHotConfig
+// may update while the node is running.
+var hotConfigLock sync.RWMutex
+
+// ConfigChangeKind is the kind of a configuration change.
+type ConfigChangeKind string
+
+const (
+	// ConfigChangeHot is a change that can be applied to the running node via ApplyConfig.
+	ConfigChangeHot ConfigChangeKind = "hot"
+	// ConfigChangeRestart is a change that requires a node restart to take effect.
+	ConfigChangeRestart ConfigChangeKind = "restart"
+	// ConfigChangeInvalid is a change that does not pass validation.
+	ConfigChangeInvalid ConfigChangeKind = "invalid"
+)
+
+// ConfigChange is a change of a single configuration field.
+type ConfigChange struct {
+	// Path is the dotted path of the field, as used in the configuration file.
+	Path string `json:"path"`
+
+	// Old is the currently effective value, if any.
+	Old string `json:"old,omitempty"`
+
+	// New is the value in the validated configuration, if any.
+	New string `json:"new,omitempty"`
+
+	// Kind is the kind of the change.
+	Kind ConfigChangeKind `json:"kind"`
+
+	// Reason explains why the change is invalid.
+	Reason string `json:"reason,omitempty"`
+}
+
+// ConfigValidationResult is the result of validating a configuration file against the currently
+// effective configuration.
+type ConfigValidationResult struct {
+	// Valid is true iff the configuration file can be loaded by the node.
+	Valid bool `json:"valid"`
+
+	// Error is the parse or validation error in case the configuration file is not valid.
+	Error string `json:"error,omitempty"`
+
+	// Changes are the changes against the currently effective configuration, sorted by path.
+	Changes []*ConfigChange `json:"changes,omitempty"`
+}
+
+// HasChanges returns true iff there is at least one change of the given kind.
+func (r *ConfigValidationResult) HasChanges(kind ConfigChangeKind) bool {
+	for _, ch := range r.Changes {
+		if ch.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateConfigUpdate parses and validates the given configuration file the same way as the
+// node does at startup, diffs it against the currently effective configuration and classifies
+// each change.
+//
+// The parsed configuration is also returned in case it could be parsed.
+func ValidateConfigUpdate(current *config.Config, raw []byte) (*ConfigValidationResult, *config.Config, error) {
+	var res ConfigValidationResult
+
+	updated, err := config.Parse(raw)
+	if err != nil {
+		res.Error = err.Error()
+		return &res, nil, nil
+	}
+	validateErr := updated.Validate()
+	res.Valid = validateErr == nil
+	if validateErr != nil {
+		res.Error = validateErr.Error()
+	}
+
+	hotConfigLock.RLock()
+	oldTree, err := current.Tree()
+	hotConfigLock.RUnlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	newTree, err := updated.Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Validate changed sections in isolation so that invalid changes can be pinpointed.
+	sectionErrs := make(map[string]error)
+	checkSection := func(section string) error {
+		if err, ok := sectionErrs[section]; ok {
+			return err
+		}
+		err := updated.ValidateSection(section)
+		sectionErrs[section] = err
+		return err
+	}
+
+	addChange := func(path string) {
+		ch := &ConfigChange{
+			Path: path,
+			Old:  oldTree[path],
+			New:  newTree[path],
+			Kind: ConfigChangeRestart,
+		}
+		_, present := newTree[path]
+		if err := checkSection(config.Section(path)); err != nil {
+			ch.Kind = ConfigChangeInvalid
+			ch.Reason = err.Error()
+		} else if reason := validateHotConfigChange(path, ch.New, present); reason != "" {
+			ch.Kind = ConfigChangeInvalid
+			ch.Reason = reason
+		} else if isHotConfigChange(path, present) {
+			ch.Kind = ConfigChangeHot
+		}
+		res.Changes = append(res.Changes, ch)
+	}
+	for path, value := range newTree {
+		if old, ok := oldTree[path]; !ok || old != value {
+			addChange(path)
+		}
+	}
+	for path := range oldTree {
+		if _, ok := newTree[path]; !ok {
+			addChange(path)
+		}
+	}
+	sort.Slice(res.Changes, func(i, j int) bool {
+		return res.Changes[i].Path < res.Changes[j].Path
+	})
+
+	return &res, updated, nil
+}
+
+// isHotConfigChange returns true iff a change of the field with the given path can be applied to
+// the running node.
+//
+// Prune intervals are not hot-applicable as the node database pruner is configured once when the
+// node database is opened and does not observe configuration changes.
+func isHotConfigChange(path string, present bool) bool {
+	switch path {
+	case configMaxBundleUploadSizePath, configMaxStorageBackupRatePath:
+		// Limits are read from the configuration on each use.
+		return true
+	}
+	if strings.HasPrefix(path, configLogLevelPath+".") {
+		// Loggers cannot be reset to the default level once their level has been set.
+		return present
+	}
+	return false
+}
+
+// validateHotConfigChange performs validity checks of hot-applicable fields which are not covered
+// by configuration validation and returns the reason in case the new value is invalid.
+func validateHotConfigChange(path, value string, present bool) string {
+	if !present {
+		return ""
+	}
+
+	switch path {
+	case configMaxBundleUploadSizePath, configMaxStorageBackupRatePath:
+		if value != "" && config.ParseSizeInBytes(value) == 0 {
+			return fmt.Sprintf("malformed size: %s", value)
+		}
+		return ""
+	}
+	if module, ok := strings.CutPrefix(path, configLogLevelPath+"."); ok {
+		if err := logLevelRequest(module, value).ValidateBasic(); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+func logLevelRequest(module, level string) *LogLevelRequest {
+	if module == DefaultLogLevelModule {
+		module = ""
+	}
+	return &LogLevelRequest{Module: module, Level: level}
+}
+
+// ApplyHotConfig applies the hot-applicable changes from the validation result to the given
+// configuration and returns the log level requests that need to be applied to running loggers.
+//
+// The log level map is replaced rather than updated in place so that holders of the previous map
+// never observe concurrent writes.
+func ApplyHotConfig(cfg, updated *config.Config, res *ConfigValidationResult) []*LogLevelRequest {
+	hotConfigLock.Lock()
+	defer hotConfigLock.Unlock()
+
+	var reqs []*LogLevelRequest
+	var levels map[string]string
+	for _, ch := range res.Changes {
+		if ch.Kind != ConfigChangeHot {
+			continue
+		}
+
+		switch ch.Path {
+		case configMaxBundleUploadSizePath:
+			cfg.Common.Control.MaxBundleUploadSize = updated.Common.Control.MaxBundleUploadSize
+		case configMaxStorageBackupRatePath:
+			cfg.Common.Control.MaxStorageBackupRate = updated.Common.Control.MaxStorageBackupRate
+		default:
+			module, ok := strings.CutPrefix(ch.Path, configLogLevelPath+".")
+			if !ok {
+				continue
+			}
+			if levels == nil {
+				levels = maps.Clone(cfg.Common.Log.Level)
+				if levels == nil {
+					levels = make(map[string]string)
+				}
+			}
+			levels[module] = ch.New
+			reqs = append(reqs, logLevelRequest(module, ch.New))
+		}
+	}
+	if levels != nil {
+		cfg.Common.Log.Level = levels
+	}
+	return reqs
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/config"
+)
+
+func requireConfigChange(t *testing.T, res *ConfigValidationResult, path string) *ConfigChange {
+	for _, ch := range res.Changes {
+		if ch.Path == path {
+			return ch
+		}
+	}
+	require.FailNow(t, "missing config change", "path: %s, changes: %+v", path, res.Changes)
+	return nil
+}
+
+func TestValidateConfigUpdate(t *testing.T) {
+	require := require.New(t)
+
+	current := config.DefaultConfig()
+
+	t.Run("InvalidFile", func(_ *testing.T) {
+		for _, raw := range []string{
+			"common: [",
+			"common:\n  unknown_field: 42\n",
+		} {
+			res, updated, err := ValidateConfigUpdate(&current, []byte(raw))
+			require.NoError(err, "ValidateConfigUpdate")
+			require.False(res.Valid, "invalid config file should be reported as such")
+			require.NotEmpty(res.Error, "invalid config file should be reported as such")
+			require.Empty(res.Changes)
+			require.Nil(updated)
+		}
+
+		res, _, err := ValidateConfigUpdate(&current, []byte("mode: bogus\n"))
+		require.NoError(err, "ValidateConfigUpdate")
+		require.False(res.Valid, "invalid config file should be reported as such")
+		ch := requireConfigChange(t, res, "mode")
+		require.Equal(ConfigChangeInvalid, ch.Kind)
+		require.NotEmpty(ch.Reason)
+	})
+
+	t.Run("RestartRequired", func(_ *testing.T) {
+		res, _, err := ValidateConfigUpdate(&current, []byte("storage:\n  fetcher_count: 8\n"))
+		require.NoError(err, "ValidateConfigUpdate")
+		require.Len(res.Changes, 1)
+		ch := res.Changes[0]
+		require.Equal("storage.fetcher_count", ch.Path)
+		require.Equal("4", ch.Old)
+		require.Equal("8", ch.New)
+		require.Equal(ConfigChangeRestart, ch.Kind)
+		require.True(res.HasChanges(ConfigChangeRestart))
+		require.False(res.HasChanges(ConfigChangeHot))
+
+		// Restart-required changes should not be applied.
+		cfg := config.DefaultConfig()
+		reqs := ApplyHotConfig(&cfg, &cfg, res)
+		require.Empty(reqs)
+		require.EqualValues(4, cfg.Storage.FetcherCount)
+	})
+
+	t.Run("HotApplied", func(_ *testing.T) {
+		raw := "common:\n" +
+			"  log:\n" +
+			"    level:\n" +
+			"      default: debug\n" +
+			"      mkvs/db: error\n" +
+			"  control:\n" +
+			"    max_storage_backup_rate: 16mb\n"
+		res, updated, err := ValidateConfigUpdate(&current, []byte(raw))
+		require.NoError(err, "ValidateConfigUpdate")
+		for _, path := range []string{
+			"common.log.level.default",
+			"common.log.level.mkvs/db",
+			"common.control.max_storage_backup_rate",
+		} {
+			ch := requireConfigChange(t, res, path)
+			require.Equal(ConfigChangeHot, ch.Kind, "change of %s should be hot-applicable", path)
+		}
+		require.False(res.HasChanges(ConfigChangeRestart))
+
+		cfg := config.DefaultConfig()
+		reqs := ApplyHotConfig(&cfg, updated, res)
+		require.ElementsMatch([]*LogLevelRequest{
+			{Module: "", Level: "debug"},
+			{Module: "mkvs/db", Level: "error"},
+		}, reqs)
+		require.Equal("debug", cfg.Common.Log.Level[DefaultLogLevelModule])
+		require.Equal("error", cfg.Common.Log.Level["mkvs/db"])
+		require.Equal("16mb", cfg.Common.Control.MaxStorageBackupRate)
+
+		// Once applied, there should be no more changes.
+		res, _, err = ValidateConfigUpdate(&cfg, []byte(raw))
+		require.NoError(err, "ValidateConfigUpdate")
+		require.Empty(res.Changes)
+
+		// Malformed hot-applicable values should be rejected.
+		res, _, err = ValidateConfigUpdate(&current, []byte("common:\n  log:\n    level:\n      default: bogus\n"))
+		require.NoError(err, "ValidateConfigUpdate")
+		ch := requireConfigChange(t, res, "common.log.level.default")
+		require.Equal(ConfigChangeInvalid, ch.Kind)
+		require.Empty(ApplyHotConfig(&cfg, &cfg, res))
+	})
+
+	t.Run("HotAppliedConcurrently", func(_ *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Common.Log.Level = map[string]string{DefaultLogLevelModule: "info"}
+		levels := cfg.Common.Log.Level
+
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				raw := fmt.Sprintf("common:\n  log:\n    level:\n      module-%d: debug\n", i)
+				res, updated, err := ValidateConfigUpdate(&cfg, []byte(raw))
+				if err != nil {
+					t.Errorf("ValidateConfigUpdate: %s", err)
+					return
+				}
+				ApplyHotConfig(&cfg, updated, res)
+			}()
+		}
+		wg.Wait()
+
+		// The previous log level map should not be modified.
+		require.Equal(map[string]string{DefaultLogLevelModule: "info"}, levels)
+		require.NotEmpty(cfg.Common.Log.Level)
+		require.Equal("info", cfg.Common.Log.Level[DefaultLogLevelModule])
+	})
+}
@@ -2,11 +2,20 @@ package api
 
 import (
 	"context"
+	"io"
+	"os"
 
 	"google.golang.org/grpc"
 
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/datadir"
 	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	nodedb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
 	upgradeApi "github.com/oasisprotocol/oasis-core/go/upgrade/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/registration/selfcheck"
 )
 
 var (
@@ -23,14 +32,76 @@ var (
 	methodWaitReady = serviceName.NewMethod("WaitReady", nil)
 	// methodIsReady is the IsReady method.
 	methodIsReady = serviceName.NewMethod("IsReady", nil)
+	// methodWaitRuntimeReady is the WaitRuntimeReady method.
+	methodWaitRuntimeReady = serviceName.NewMethod("WaitRuntimeReady", common.Namespace{})
+	// methodIsRuntimeReady is the IsRuntimeReady method.
+	methodIsRuntimeReady = serviceName.NewMethod("IsRuntimeReady", common.Namespace{})
+	// methodRestartRuntime is the RestartRuntime method.
+	methodRestartRuntime = serviceName.NewMethod("RestartRuntime", RestartRuntimeRequest{})
 	// methodUpgradeBinary is the UpgradeBinary method.
 	methodUpgradeBinary = serviceName.NewMethod("UpgradeBinary", upgradeApi.Descriptor{})
 	// methodCancelUpgrade is the CancelUpgrade method.
 	methodCancelUpgrade = serviceName.NewMethod("CancelUpgrade", nil)
+	// methodCancelUpgradeByName is the CancelUpgradeByName method.
+	methodCancelUpgradeByName = serviceName.NewMethod("CancelUpgradeByName", "")
+	// methodListUpgrades is the ListUpgrades method.
+	methodListUpgrades = serviceName.NewMethod("ListUpgrades", nil)
 	// methodGetStatus is the GetStatus method.
 	methodGetStatus = serviceName.NewMethod("GetStatus", nil)
 	// methodAddBundle is the AddBundle method.
 	methodAddBundle = serviceName.NewMethod("AddBundle", nil)
+	// methodRequestShutdownEx is the RequestShutdownEx method.
+	methodRequestShutdownEx = serviceName.NewMethod("RequestShutdownEx", ShutdownRequest{})
+	// methodWipeRuntimeLocalStorage is the WipeRuntimeLocalStorage method.
+	methodWipeRuntimeLocalStorage = serviceName.NewMethod("WipeRuntimeLocalStorage", common.Namespace{})
+	// methodGetGRPCSessions is the GetGRPCSessions method.
+	methodGetGRPCSessions = serviceName.NewMethod("GetGRPCSessions", nil)
+	// methodCloseGRPCSession is the CloseGRPCSession method.
+	methodCloseGRPCSession = serviceName.NewMethod("CloseGRPCSession", uint64(0))
+	// methodSetHaltEpoch is the SetHaltEpoch method.
+	methodSetHaltEpoch = serviceName.NewMethod("SetHaltEpoch", beacon.EpochTime(0))
+	// methodVerifyDataDir is the VerifyDataDir method.
+	methodVerifyDataDir = serviceName.NewMethod("VerifyDataDir", nil)
+	// methodGetGRPCSlowCallThresholds is the GetGRPCSlowCallThresholds method.
+	methodGetGRPCSlowCallThresholds = serviceName.NewMethod("GetGRPCSlowCallThresholds", nil)
+	// methodSetGRPCSlowCallThresholds is the SetGRPCSlowCallThresholds method.
+	methodSetGRPCSlowCallThresholds = serviceName.NewMethod("SetGRPCSlowCallThresholds", cmnGrpc.SlowCallThresholds{})
+	// methodTriggerStorageGC is the TriggerStorageGC method.
+	methodTriggerStorageGC = serviceName.NewMethod("TriggerStorageGC", TriggerStorageGCRequest{})
+	// methodPruneStorage is the PruneStorage method.
+	methodPruneStorage = serviceName.NewMethod("PruneStorage", PruneStorageRequest{})
+	// methodCreateCheckpoint is the CreateCheckpoint method.
+	methodCreateCheckpoint = serviceName.NewMethod("CreateCheckpoint", CreateCheckpointRequest{})
+	// methodGetPublicKeys is the GetPublicKeys method.
+	methodGetPublicKeys = serviceName.NewMethod("GetPublicKeys", nil)
+	// methodRotateKeys is the RotateKeys method.
+	methodRotateKeys = serviceName.NewMethod("RotateKeys", RotateKeysRequest{})
+	// methodRunSelfTest is the RunSelfTest method.
+	methodRunSelfTest = serviceName.NewMethod("RunSelfTest", nil)
+	// methodValidateConfig is the ValidateConfig method.
+	methodValidateConfig = serviceName.NewMethod("ValidateConfig", []byte{})
+	// methodApplyConfig is the ApplyConfig method.
+	methodApplyConfig = serviceName.NewMethod("ApplyConfig", []byte{})
+
+	// methodListBundles is the ListBundles method.
+	methodListBundles = serviceName.NewMethod("ListBundles", nil)
+	// methodRemoveBundle is the RemoveBundle method.
+	methodRemoveBundle = serviceName.NewMethod("RemoveBundle", hash.Hash{})
+	// methodSetLogLevel is the SetLogLevel method.
+	methodSetLogLevel = serviceName.NewMethod("SetLogLevel", LogLevelRequest{})
+	// methodGetLogLevels is the GetLogLevels method.
+	methodGetLogLevels = serviceName.NewMethod("GetLogLevels", nil)
+	// methodGetStatusEx is the GetStatusEx method.
+	methodGetStatusEx = serviceName.NewMethod("GetStatusEx", StatusFilter{})
+
+	// methodWatchStatus is the WatchStatus method.
+	methodWatchStatus = serviceName.NewMethod("WatchStatus", nil)
+	// methodAddBundleData is the AddBundleData method.
+	methodAddBundleData = serviceName.NewMethod("AddBundleData", AddBundleDataChunk{})
+	// methodBackupStorage is the BackupStorage method.
+	methodBackupStorage = serviceName.NewMethod("BackupStorage", BackupStorageRequest{})
+	// methodWatchUpgradeEvents is the WatchUpgradeEvents method.
+	methodWatchUpgradeEvents = serviceName.NewMethod("WatchUpgradeEvents", nil)
 
 	// serviceDesc is the gRPC service descriptor.
 	serviceDesc = grpc.ServiceDesc{
@@ -57,6 +128,18 @@ var (
 				MethodName: methodIsReady.ShortName(),
 				Handler:    handlerIsReady,
 			},
+			{
+				MethodName: methodWaitRuntimeReady.ShortName(),
+				Handler:    handlerWaitRuntimeReady,
+			},
+			{
+				MethodName: methodIsRuntimeReady.ShortName(),
+				Handler:    handlerIsRuntimeReady,
+			},
+			{
+				MethodName: methodRestartRuntime.ShortName(),
+				Handler:    handlerRestartRuntime,
+			},
 			{
 				MethodName: methodUpgradeBinary.ShortName(),
 				Handler:    handlerUpgradeBinary,
@@ -65,6 +148,14 @@ var (
 				MethodName: methodCancelUpgrade.ShortName(),
 				Handler:    handlerCancelUpgrade,
 			},
+			{
+				MethodName: methodCancelUpgradeByName.ShortName(),
+				Handler:    handlerCancelUpgradeByName,
+			},
+			{
+				MethodName: methodListUpgrades.ShortName(),
+				Handler:    handlerListUpgrades,
+			},
 			{
 				MethodName: methodGetStatus.ShortName(),
 				Handler:    handlerGetStatus,
@@ -73,8 +164,113 @@ var (
 				MethodName: methodAddBundle.ShortName(),
 				Handler:    handlerAddBundle,
 			},
+			{
+				MethodName: methodRequestShutdownEx.ShortName(),
+				Handler:    handlerRequestShutdownEx,
+			},
+			{
+				MethodName: methodWipeRuntimeLocalStorage.ShortName(),
+				Handler:    handlerWipeRuntimeLocalStorage,
+			},
+			{
+				MethodName: methodGetGRPCSessions.ShortName(),
+				Handler:    handlerGetGRPCSessions,
+			},
+			{
+				MethodName: methodCloseGRPCSession.ShortName(),
+				Handler:    handlerCloseGRPCSession,
+			},
+			{
+				MethodName: methodSetHaltEpoch.ShortName(),
+				Handler:    handlerSetHaltEpoch,
+			},
+			{
+				MethodName: methodVerifyDataDir.ShortName(),
+				Handler:    handlerVerifyDataDir,
+			},
+			{
+				MethodName: methodGetGRPCSlowCallThresholds.ShortName(),
+				Handler:    handlerGetGRPCSlowCallThresholds,
+			},
+			{
+				MethodName: methodSetGRPCSlowCallThresholds.ShortName(),
+				Handler:    handlerSetGRPCSlowCallThresholds,
+			},
+			{
+				MethodName: methodTriggerStorageGC.ShortName(),
+				Handler:    handlerTriggerStorageGC,
+			},
+			{
+				MethodName: methodPruneStorage.ShortName(),
+				Handler:    handlerPruneStorage,
+			},
+			{
+				MethodName: methodCreateCheckpoint.ShortName(),
+				Handler:    handlerCreateCheckpoint,
+			},
+			{
+				MethodName: methodGetPublicKeys.ShortName(),
+				Handler:    handlerGetPublicKeys,
+			},
+			{
+				MethodName: methodRotateKeys.ShortName(),
+				Handler:    handlerRotateKeys,
+			},
+			{
+				MethodName: methodRunSelfTest.ShortName(),
+				Handler:    handlerRunSelfTest,
+			},
+			{
+				MethodName: methodValidateConfig.ShortName(),
+				Handler:    handlerValidateConfig,
+			},
+			{
+				MethodName: methodApplyConfig.ShortName(),
+				Handler:    handlerApplyConfig,
+			},
+			{
+				MethodName: methodListBundles.ShortName(),
+				Handler:    handlerListBundles,
+			},
+			{
+				MethodName: methodRemoveBundle.ShortName(),
+				Handler:    handlerRemoveBundle,
+			},
+			{
+				MethodName: methodSetLogLevel.ShortName(),
+				Handler:    handlerSetLogLevel,
+			},
+			{
+				MethodName: methodGetLogLevels.ShortName(),
+				Handler:    handlerGetLogLevels,
+			},
+			{
+				MethodName: methodGetStatusEx.ShortName(),
+				Handler:    handlerGetStatusEx,
+			},
+		},
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    methodWatchStatus.ShortName(),
+				Handler:       handlerWatchStatus,
+				ServerStreams: true,
+			},
+			{
+				StreamName:    methodAddBundleData.ShortName(),
+				Handler:       handlerAddBundleData,
+				ClientStreams: true,
+			},
+			{
+				StreamName:    methodBackupStorage.ShortName(),
+				Handler:       handlerBackupStorage,
+				ServerStreams: true,
+			},
+			{
+				StreamName:    methodWatchUpgradeEvents.ShortName(),
+				Handler:       handlerWatchUpgradeEvents,
+				ServerStreams: true,
+			},
 		},
-		Streams: []grpc.StreamDesc{},
 	}
 )
 
@@ -177,6 +373,76 @@ func handlerIsReady(
 	return interceptor(ctx, nil, info, handler)
 }
 
+func handlerWaitRuntimeReady(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var runtimeID common.Namespace
+	if err := dec(&runtimeID); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return nil, srv.(NodeController).WaitRuntimeReady(ctx, runtimeID)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodWaitRuntimeReady.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, srv.(NodeController).WaitRuntimeReady(ctx, req.(common.Namespace))
+	}
+	return interceptor(ctx, runtimeID, info, handler)
+}
+
+func handlerIsRuntimeReady(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var runtimeID common.Namespace
+	if err := dec(&runtimeID); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeController).IsRuntimeReady(ctx, runtimeID)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodIsRuntimeReady.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(NodeController).IsRuntimeReady(ctx, req.(common.Namespace))
+	}
+	return interceptor(ctx, runtimeID, info, handler)
+}
+
+func handlerRestartRuntime(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var req RestartRuntimeRequest
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return nil, srv.(NodeController).RestartRuntime(ctx, req.RuntimeID, req.Force)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodRestartRuntime.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		r := req.(*RestartRuntimeRequest)
+		return nil, srv.(NodeController).RestartRuntime(ctx, r.RuntimeID, r.Force)
+	}
+	return interceptor(ctx, &req, info, handler)
+}
+
 func handlerUpgradeBinary(
 	srv any,
 	ctx context.Context,
@@ -223,6 +489,48 @@ func handlerCancelUpgrade(
 	return interceptor(ctx, &descriptor, info, handler)
 }
 
+func handlerCancelUpgradeByName(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var name string
+	if err := dec(&name); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return nil, srv.(NodeController).CancelUpgradeByName(ctx, name)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodCancelUpgradeByName.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, srv.(NodeController).CancelUpgradeByName(ctx, req.(string))
+	}
+	return interceptor(ctx, name, info, handler)
+}
+
+func handlerListUpgrades(
+	srv any,
+	ctx context.Context,
+	_ func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	if interceptor == nil {
+		return srv.(NodeController).ListUpgrades(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodListUpgrades.FullName(),
+	}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return srv.(NodeController).ListUpgrades(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
 func handlerGetStatus(
 	srv any,
 	ctx context.Context,
@@ -242,6 +550,29 @@ func handlerGetStatus(
 	return interceptor(ctx, nil, info, handler)
 }
 
+func handlerGetStatusEx(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var filter *StatusFilter
+	if err := dec(&filter); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeController).GetStatusEx(ctx, filter)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetStatusEx.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(NodeController).GetStatusEx(ctx, req.(*StatusFilter))
+	}
+	return interceptor(ctx, filter, info, handler)
+}
+
 func handlerAddBundle(
 	srv any,
 	ctx context.Context,
@@ -265,65 +596,668 @@ func handlerAddBundle(
 	return interceptor(ctx, &path, info, handler)
 }
 
-// RegisterService registers a new node controller service with the given gRPC server.
-func RegisterService(server *grpc.Server, service NodeController) {
-	server.RegisterService(&serviceDesc, service)
-}
-
-// NodeControllerClient is a gRPC node controller client.
-type NodeControllerClient struct {
-	conn *grpc.ClientConn
-}
-
-// NewNodeControllerClient creates a new gRPC node controller client.
-func NewNodeControllerClient(c *grpc.ClientConn) *NodeControllerClient {
-	return &NodeControllerClient{
-		conn: c,
+func handlerRequestShutdownEx(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var req ShutdownRequest
+	if err := dec(&req); err != nil {
+		return nil, err
 	}
-}
-
-func (c *NodeControllerClient) RequestShutdown(ctx context.Context, wait bool) error {
-	return c.conn.Invoke(ctx, methodRequestShutdown.FullName(), wait, nil)
-}
-
-func (c *NodeControllerClient) WaitSync(ctx context.Context) error {
-	return c.conn.Invoke(ctx, methodWaitSync.FullName(), nil, nil)
-}
-
-func (c *NodeControllerClient) IsSynced(ctx context.Context) (bool, error) {
-	var rsp bool
-	if err := c.conn.Invoke(ctx, methodIsSynced.FullName(), nil, &rsp); err != nil {
-		return false, err
+	if interceptor == nil {
+		return nil, srv.(NodeController).RequestShutdownEx(ctx, &req)
 	}
-	return rsp, nil
-}
-
-func (c *NodeControllerClient) WaitReady(ctx context.Context) error {
-	return c.conn.Invoke(ctx, methodWaitReady.FullName(), nil, nil)
-}
-
-func (c *NodeControllerClient) IsReady(ctx context.Context) (bool, error) {
-	var rsp bool
-	if err := c.conn.Invoke(ctx, methodIsReady.FullName(), nil, &rsp); err != nil {
-		return false, err
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodRequestShutdownEx.FullName(),
 	}
-	return rsp, nil
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, srv.(NodeController).RequestShutdownEx(ctx, req.(*ShutdownRequest))
+	}
+	return interceptor(ctx, &req, info, handler)
 }
 
-func (c *NodeControllerClient) UpgradeBinary(ctx context.Context, descriptor *upgradeApi.Descriptor) error {
-	return c.conn.Invoke(ctx, methodUpgradeBinary.FullName(), descriptor, nil)
+func handlerWipeRuntimeLocalStorage(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var runtimeID common.Namespace
+	if err := dec(&runtimeID); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return nil, srv.(NodeController).WipeRuntimeLocalStorage(ctx, runtimeID)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodWipeRuntimeLocalStorage.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, srv.(NodeController).WipeRuntimeLocalStorage(ctx, req.(common.Namespace))
+	}
+	return interceptor(ctx, runtimeID, info, handler)
 }
 
-func (c *NodeControllerClient) CancelUpgrade(ctx context.Context, descriptor *upgradeApi.Descriptor) error {
-	return c.conn.Invoke(ctx, methodCancelUpgrade.FullName(), descriptor, nil)
+func handlerGetGRPCSessions(
+	srv any,
+	ctx context.Context,
+	_ func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	if interceptor == nil {
+		return srv.(NodeController).GetGRPCSessions(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetGRPCSessions.FullName(),
+	}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return srv.(NodeController).GetGRPCSessions(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
 }
 
-func (c *NodeControllerClient) GetStatus(ctx context.Context) (*Status, error) {
-	var rsp Status
-	if err := c.conn.Invoke(ctx, methodGetStatus.FullName(), nil, &rsp); err != nil {
+func handlerCloseGRPCSession(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var id uint64
+	if err := dec(&id); err != nil {
 		return nil, err
 	}
-	return &rsp, nil
+	if interceptor == nil {
+		return nil, srv.(NodeController).CloseGRPCSession(ctx, id)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodCloseGRPCSession.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, srv.(NodeController).CloseGRPCSession(ctx, req.(uint64))
+	}
+	return interceptor(ctx, id, info, handler)
+}
+
+func handlerSetHaltEpoch(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var epoch beacon.EpochTime
+	if err := dec(&epoch); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return nil, srv.(NodeController).SetHaltEpoch(ctx, epoch)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodSetHaltEpoch.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, srv.(NodeController).SetHaltEpoch(ctx, req.(beacon.EpochTime))
+	}
+	return interceptor(ctx, epoch, info, handler)
+}
+
+func handlerVerifyDataDir(
+	srv any,
+	ctx context.Context,
+	_ func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	if interceptor == nil {
+		return srv.(NodeController).VerifyDataDir(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodVerifyDataDir.FullName(),
+	}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return srv.(NodeController).VerifyDataDir(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
+func handlerGetGRPCSlowCallThresholds(
+	srv any,
+	ctx context.Context,
+	_ func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	if interceptor == nil {
+		return srv.(NodeController).GetGRPCSlowCallThresholds(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetGRPCSlowCallThresholds.FullName(),
+	}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return srv.(NodeController).GetGRPCSlowCallThresholds(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
+func handlerSetGRPCSlowCallThresholds(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var thresholds cmnGrpc.SlowCallThresholds
+	if err := dec(&thresholds); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return nil, srv.(NodeController).SetGRPCSlowCallThresholds(ctx, &thresholds)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodSetGRPCSlowCallThresholds.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, srv.(NodeController).SetGRPCSlowCallThresholds(ctx, req.(*cmnGrpc.SlowCallThresholds))
+	}
+	return interceptor(ctx, &thresholds, info, handler)
+}
+
+func handlerTriggerStorageGC(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var req TriggerStorageGCRequest
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeController).TriggerStorageGC(ctx, &req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodTriggerStorageGC.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(NodeController).TriggerStorageGC(ctx, req.(*TriggerStorageGCRequest))
+	}
+	return interceptor(ctx, &req, info, handler)
+}
+
+func handlerPruneStorage(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var req PruneStorageRequest
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeController).PruneStorage(ctx, &req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodPruneStorage.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(NodeController).PruneStorage(ctx, req.(*PruneStorageRequest))
+	}
+	return interceptor(ctx, &req, info, handler)
+}
+
+func handlerCreateCheckpoint(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var req CreateCheckpointRequest
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeController).CreateCheckpoint(ctx, &req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodCreateCheckpoint.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(NodeController).CreateCheckpoint(ctx, req.(*CreateCheckpointRequest))
+	}
+	return interceptor(ctx, &req, info, handler)
+}
+
+func handlerGetPublicKeys(
+	srv any,
+	ctx context.Context,
+	_ func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	if interceptor == nil {
+		return srv.(NodeController).GetPublicKeys(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetPublicKeys.FullName(),
+	}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return srv.(NodeController).GetPublicKeys(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
+func handlerRotateKeys(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var req RotateKeysRequest
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeController).RotateKeys(ctx, &req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodRotateKeys.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(NodeController).RotateKeys(ctx, req.(*RotateKeysRequest))
+	}
+	return interceptor(ctx, &req, info, handler)
+}
+
+func handlerRunSelfTest(
+	srv any,
+	ctx context.Context,
+	_ func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	if interceptor == nil {
+		return srv.(NodeController).RunSelfTest(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodRunSelfTest.FullName(),
+	}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return srv.(NodeController).RunSelfTest(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
+func handlerValidateConfig(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var raw []byte
+	if err := dec(&raw); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeController).ValidateConfig(ctx, raw)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodValidateConfig.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(NodeController).ValidateConfig(ctx, *req.(*[]byte))
+	}
+	return interceptor(ctx, &raw, info, handler)
+}
+
+func handlerApplyConfig(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var raw []byte
+	if err := dec(&raw); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeController).ApplyConfig(ctx, raw)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodApplyConfig.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(NodeController).ApplyConfig(ctx, *req.(*[]byte))
+	}
+	return interceptor(ctx, &raw, info, handler)
+}
+
+func handlerListBundles(
+	srv any,
+	ctx context.Context,
+	_ func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	if interceptor == nil {
+		return srv.(NodeController).ListBundles(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodListBundles.FullName(),
+	}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return srv.(NodeController).ListBundles(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
+func handlerRemoveBundle(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var manifestHash hash.Hash
+	if err := dec(&manifestHash); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return nil, srv.(NodeController).RemoveBundle(ctx, manifestHash)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodRemoveBundle.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, srv.(NodeController).RemoveBundle(ctx, *req.(*hash.Hash))
+	}
+	return interceptor(ctx, &manifestHash, info, handler)
+}
+
+func handlerSetLogLevel(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var req LogLevelRequest
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NodeController).SetLogLevel(ctx, &req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodSetLogLevel.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(NodeController).SetLogLevel(ctx, req.(*LogLevelRequest))
+	}
+	return interceptor(ctx, &req, info, handler)
+}
+
+func handlerGetLogLevels(
+	srv any,
+	ctx context.Context,
+	_ func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	if interceptor == nil {
+		return srv.(NodeController).GetLogLevels(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetLogLevels.FullName(),
+	}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return srv.(NodeController).GetLogLevels(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
+func handlerWatchStatus(srv any, stream grpc.ServerStream) error {
+	if err := stream.RecvMsg(nil); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	ch, sub, err := srv.(NodeController).WatchStatus(ctx)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case s, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			if err := stream.SendMsg(s); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func handlerWatchUpgradeEvents(srv any, stream grpc.ServerStream) error {
+	if err := stream.RecvMsg(nil); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	ch, sub, err := srv.(NodeController).WatchUpgradeEvents(ctx)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			if err := stream.SendMsg(ev); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func handlerAddBundleData(srv any, stream grpc.ServerStream) error {
+	dir, maxSize := bundleUploadConfig()
+	path, err := receiveBundle(func(chunk *AddBundleDataChunk) error {
+		return stream.RecvMsg(chunk)
+	}, dir, maxSize)
+	if err != nil {
+		return err
+	}
+
+	if err = srv.(NodeController).AddBundle(stream.Context(), path); err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+	return stream.SendMsg(nil)
+}
+
+func handlerBackupStorage(srv any, stream grpc.ServerStream) error {
+	var req BackupStorageRequest
+	if err := stream.RecvMsg(&req); err != nil {
+		return err
+	}
+
+	if !storageBackupLock.TryLock() {
+		return ErrStorageBackupInProgress
+	}
+	defer storageBackupLock.Unlock()
+
+	ctx := stream.Context()
+	w := newRateLimitedWriter(ctx, &chunkWriter{
+		send: func(chunk *BackupStorageChunk) error {
+			return stream.SendMsg(chunk)
+		},
+	}, storageBackupRate(&req))
+	nextSinceVersion, err := srv.(NodeController).BackupStorage(ctx, &req, w)
+	if err != nil {
+		return err
+	}
+	return stream.SendMsg(&BackupStorageChunk{NextSinceVersion: &nextSinceVersion})
+}
+
+// RegisterService registers a new node controller service with the given gRPC server.
+func RegisterService(server *grpc.Server, service NodeController) {
+	server.RegisterService(&serviceDesc, service)
+}
+
+// NodeControllerClient is a gRPC node controller client.
+type NodeControllerClient struct {
+	conn *grpc.ClientConn
+}
+
+// NewNodeControllerClient creates a new gRPC node controller client.
+func NewNodeControllerClient(c *grpc.ClientConn) *NodeControllerClient {
+	return &NodeControllerClient{
+		conn: c,
+	}
+}
+
+func (c *NodeControllerClient) RequestShutdown(ctx context.Context, wait bool) error {
+	return c.conn.Invoke(ctx, methodRequestShutdown.FullName(), wait, nil)
+}
+
+func (c *NodeControllerClient) WaitSync(ctx context.Context) error {
+	return c.conn.Invoke(ctx, methodWaitSync.FullName(), nil, nil)
+}
+
+func (c *NodeControllerClient) IsSynced(ctx context.Context) (bool, error) {
+	var rsp bool
+	if err := c.conn.Invoke(ctx, methodIsSynced.FullName(), nil, &rsp); err != nil {
+		return false, err
+	}
+	return rsp, nil
+}
+
+func (c *NodeControllerClient) WaitReady(ctx context.Context) error {
+	return c.conn.Invoke(ctx, methodWaitReady.FullName(), nil, nil)
+}
+
+func (c *NodeControllerClient) IsReady(ctx context.Context) (bool, error) {
+	var rsp bool
+	if err := c.conn.Invoke(ctx, methodIsReady.FullName(), nil, &rsp); err != nil {
+		return false, err
+	}
+	return rsp, nil
+}
+
+func (c *NodeControllerClient) WaitRuntimeReady(ctx context.Context, runtimeID common.Namespace) error {
+	return c.conn.Invoke(ctx, methodWaitRuntimeReady.FullName(), runtimeID, nil)
+}
+
+func (c *NodeControllerClient) IsRuntimeReady(ctx context.Context, runtimeID common.Namespace) (bool, error) {
+	var rsp bool
+	if err := c.conn.Invoke(ctx, methodIsRuntimeReady.FullName(), runtimeID, &rsp); err != nil {
+		return false, err
+	}
+	return rsp, nil
+}
+
+func (c *NodeControllerClient) RestartRuntime(ctx context.Context, runtimeID common.Namespace, force bool) error {
+	req := RestartRuntimeRequest{
+		RuntimeID: runtimeID,
+		Force:     force,
+	}
+	return c.conn.Invoke(ctx, methodRestartRuntime.FullName(), req, nil)
+}
+
+func (c *NodeControllerClient) UpgradeBinary(ctx context.Context, descriptor *upgradeApi.Descriptor) error {
+	return c.conn.Invoke(ctx, methodUpgradeBinary.FullName(), descriptor, nil)
+}
+
+func (c *NodeControllerClient) CancelUpgrade(ctx context.Context, descriptor *upgradeApi.Descriptor) error {
+	return c.conn.Invoke(ctx, methodCancelUpgrade.FullName(), descriptor, nil)
+}
+
+func (c *NodeControllerClient) CancelUpgradeByName(ctx context.Context, name string) error {
+	return c.conn.Invoke(ctx, methodCancelUpgradeByName.FullName(), name, nil)
+}
+
+func (c *NodeControllerClient) ListUpgrades(ctx context.Context) ([]*upgradeApi.PendingUpgrade, error) {
+	var rsp []*upgradeApi.PendingUpgrade
+	if err := c.conn.Invoke(ctx, methodListUpgrades.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func (c *NodeControllerClient) WatchUpgradeEvents(ctx context.Context) (<-chan *upgradeApi.Event, pubsub.ClosableSubscription, error) {
+	ctx, sub := pubsub.NewContextSubscription(ctx)
+
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[3], methodWatchUpgradeEvents.FullName())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = stream.SendMsg(nil); err != nil {
+		return nil, nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan *upgradeApi.Event)
+	go func() {
+		defer close(ch)
+
+		for {
+			var ev upgradeApi.Event
+			if serr := stream.RecvMsg(&ev); serr != nil {
+				return
+			}
+
+			select {
+			case ch <- &ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, sub, nil
+}
+
+func (c *NodeControllerClient) GetStatus(ctx context.Context) (*Status, error) {
+	var rsp Status
+	if err := c.conn.Invoke(ctx, methodGetStatus.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *NodeControllerClient) GetStatusEx(ctx context.Context, filter *StatusFilter) (*Status, error) {
+	var rsp Status
+	if err := c.conn.Invoke(ctx, methodGetStatusEx.FullName(), filter, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
 }
 
 func (c *NodeControllerClient) AddBundle(ctx context.Context, path string) error {
@@ -333,3 +1267,247 @@ func (c *NodeControllerClient) AddBundle(ctx context.Context, path string) error
 	}
 	return nil
 }
+
+func (c *NodeControllerClient) RequestShutdownEx(ctx context.Context, req *ShutdownRequest) error {
+	return c.conn.Invoke(ctx, methodRequestShutdownEx.FullName(), req, nil)
+}
+
+func (c *NodeControllerClient) WipeRuntimeLocalStorage(ctx context.Context, runtimeID common.Namespace) error {
+	return c.conn.Invoke(ctx, methodWipeRuntimeLocalStorage.FullName(), runtimeID, nil)
+}
+
+func (c *NodeControllerClient) GetGRPCSessions(ctx context.Context) ([]*cmnGrpc.SessionInfo, error) {
+	var rsp []*cmnGrpc.SessionInfo
+	if err := c.conn.Invoke(ctx, methodGetGRPCSessions.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func (c *NodeControllerClient) CloseGRPCSession(ctx context.Context, id uint64) error {
+	return c.conn.Invoke(ctx, methodCloseGRPCSession.FullName(), id, nil)
+}
+
+func (c *NodeControllerClient) SetHaltEpoch(ctx context.Context, epoch beacon.EpochTime) error {
+	return c.conn.Invoke(ctx, methodSetHaltEpoch.FullName(), epoch, nil)
+}
+
+func (c *NodeControllerClient) VerifyDataDir(ctx context.Context) (*datadir.VerifyResult, error) {
+	var rsp datadir.VerifyResult
+	if err := c.conn.Invoke(ctx, methodVerifyDataDir.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *NodeControllerClient) GetGRPCSlowCallThresholds(ctx context.Context) (*cmnGrpc.SlowCallThresholds, error) {
+	var rsp cmnGrpc.SlowCallThresholds
+	if err := c.conn.Invoke(ctx, methodGetGRPCSlowCallThresholds.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *NodeControllerClient) SetGRPCSlowCallThresholds(ctx context.Context, thresholds *cmnGrpc.SlowCallThresholds) error {
+	return c.conn.Invoke(ctx, methodSetGRPCSlowCallThresholds.FullName(), thresholds, nil)
+}
+
+func (c *NodeControllerClient) TriggerStorageGC(ctx context.Context, req *TriggerStorageGCRequest) (*nodedb.GCStats, error) {
+	var rsp nodedb.GCStats
+	if err := c.conn.Invoke(ctx, methodTriggerStorageGC.FullName(), req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *NodeControllerClient) PruneStorage(ctx context.Context, req *PruneStorageRequest) (*PruneStorageResponse, error) {
+	var rsp PruneStorageResponse
+	if err := c.conn.Invoke(ctx, methodPruneStorage.FullName(), req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *NodeControllerClient) CreateCheckpoint(ctx context.Context, req *CreateCheckpointRequest) (*CheckpointInfo, error) {
+	var rsp CheckpointInfo
+	if err := c.conn.Invoke(ctx, methodCreateCheckpoint.FullName(), req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *NodeControllerClient) GetPublicKeys(ctx context.Context) ([]*PublicKeyInfo, error) {
+	var rsp []*PublicKeyInfo
+	if err := c.conn.Invoke(ctx, methodGetPublicKeys.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func (c *NodeControllerClient) RotateKeys(ctx context.Context, req *RotateKeysRequest) (*RotateKeysResponse, error) {
+	var rsp RotateKeysResponse
+	if err := c.conn.Invoke(ctx, methodRotateKeys.FullName(), req, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *NodeControllerClient) RunSelfTest(ctx context.Context) (*selfcheck.Report, error) {
+	var rsp selfcheck.Report
+	if err := c.conn.Invoke(ctx, methodRunSelfTest.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *NodeControllerClient) ValidateConfig(ctx context.Context, raw []byte) (*ConfigValidationResult, error) {
+	var rsp ConfigValidationResult
+	if err := c.conn.Invoke(ctx, methodValidateConfig.FullName(), raw, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *NodeControllerClient) ApplyConfig(ctx context.Context, raw []byte) (*ConfigValidationResult, error) {
+	var rsp ConfigValidationResult
+	if err := c.conn.Invoke(ctx, methodApplyConfig.FullName(), raw, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *NodeControllerClient) ListBundles(ctx context.Context) ([]BundleInfo, error) {
+	var rsp []BundleInfo
+	if err := c.conn.Invoke(ctx, methodListBundles.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+func (c *NodeControllerClient) RemoveBundle(ctx context.Context, manifestHash hash.Hash) error {
+	return c.conn.Invoke(ctx, methodRemoveBundle.FullName(), manifestHash, nil)
+}
+
+func (c *NodeControllerClient) SetLogLevel(ctx context.Context, req *LogLevelRequest) (uint64, error) {
+	var rsp uint64
+	if err := c.conn.Invoke(ctx, methodSetLogLevel.FullName(), req, &rsp); err != nil {
+		return 0, err
+	}
+	return rsp, nil
+}
+
+func (c *NodeControllerClient) GetLogLevels(ctx context.Context) (map[string]string, error) {
+	var rsp map[string]string
+	if err := c.conn.Invoke(ctx, methodGetLogLevels.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
+// AddBundleData uploads the bundle read from the given reader to the node and adds it the same
+// way as AddBundle does. The checksum is the hash of the whole bundle.
+func (c *NodeControllerClient) AddBundleData(ctx context.Context, checksum hash.Hash, r io.Reader) error {
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[1], methodAddBundleData.FullName())
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, AddBundleDataChunkSize)
+	for first := true; ; first = false {
+		n, rerr := io.ReadFull(r, buf)
+		switch rerr {
+		case nil, io.EOF, io.ErrUnexpectedEOF:
+		default:
+			return rerr
+		}
+		if n == 0 && !first {
+			break
+		}
+
+		chunk := AddBundleDataChunk{
+			Data: buf[:n],
+		}
+		if first {
+			chunk.Checksum = &checksum
+		}
+		if err = stream.SendMsg(&chunk); err != nil {
+			if err == io.EOF {
+				// The node has terminated the stream, the actual error is returned by RecvMsg.
+				break
+			}
+			return err
+		}
+		if rerr != nil {
+			break
+		}
+	}
+
+	if err = stream.CloseSend(); err != nil {
+		return err
+	}
+	return stream.RecvMsg(nil)
+}
+
+func (c *NodeControllerClient) BackupStorage(ctx context.Context, req *BackupStorageRequest, w io.Writer) (uint64, error) {
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[2], methodBackupStorage.FullName())
+	if err != nil {
+		return 0, err
+	}
+	if err = stream.SendMsg(req); err != nil {
+		return 0, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return 0, err
+	}
+
+	for {
+		var chunk BackupStorageChunk
+		if err = stream.RecvMsg(&chunk); err != nil {
+			if err == io.EOF {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		if _, err = w.Write(chunk.Data); err != nil {
+			return 0, err
+		}
+		if chunk.NextSinceVersion != nil {
+			return *chunk.NextSinceVersion, nil
+		}
+	}
+}
+
+func (c *NodeControllerClient) WatchStatus(ctx context.Context) (<-chan *Status, pubsub.ClosableSubscription, error) {
+	ctx, sub := pubsub.NewContextSubscription(ctx)
+
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], methodWatchStatus.FullName())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = stream.SendMsg(nil); err != nil {
+		return nil, nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan *Status)
+	go func() {
+		defer close(ch)
+
+		for {
+			var s Status
+			if serr := stream.RecvMsg(&s); serr != nil {
+				return
+			}
+
+			select {
+			case ch <- &s:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, sub, nil
+}
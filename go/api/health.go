@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	commonWorker "github.com/oasisprotocol/oasis-core/go/worker/common/api"
+)
+
+// RuntimeHealthServicePrefix is the prefix of the health service names of runtimes.
+const RuntimeHealthServicePrefix = "oasis.runtime."
+
+// RuntimeHealthService returns the health service name of the given runtime.
+func RuntimeHealthService(runtimeID common.Namespace) string {
+	return RuntimeHealthServicePrefix + runtimeID.String()
+}
+
+// RegisterHealthService registers the standard gRPC health service with the given gRPC server
+// and keeps it updated until the context is canceled.
+//
+// The node is reported as serving iff it is ready to accept runtime work, as reported by
+// IsReady, and each runtime is reported as serving iff its committee worker is ready.
+func RegisterHealthService(ctx context.Context, server *grpc.Server, service NodeController) *cmnGrpc.HealthServer {
+	hs := cmnGrpc.NewHealthServer()
+	hs.Register(server)
+	go watchHealth(ctx, hs, service)
+	return hs
+}
+
+// watchHealth updates the health server based on the readiness transitions and the status
+// updates of the node until the context is canceled.
+func watchHealth(ctx context.Context, hs *cmnGrpc.HealthServer, service NodeController) {
+	defer hs.Shutdown()
+
+	readyCh := make(chan struct{})
+	go func() {
+		if err := service.WaitReady(ctx); err == nil {
+			close(readyCh)
+		}
+	}()
+
+	// Status updates catch readiness regressions and drive the health of individual runtimes.
+	statusCh, sub, err := service.WatchStatus(ctx)
+	if err != nil {
+		statusCh = nil
+	} else {
+		defer sub.Close()
+	}
+
+	runtimes := make(map[common.Namespace]struct{})
+	for {
+		select {
+		case <-readyCh:
+			readyCh = nil
+			hs.SetServing("", true)
+		case status, ok := <-statusCh:
+			if !ok {
+				statusCh = nil
+				continue
+			}
+			updateRuntimeHealth(hs, runtimes, status)
+
+			ready, err := service.IsReady(ctx)
+			hs.SetServing("", err == nil && ready)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// updateRuntimeHealth updates the health of all runtimes in the given status as well as of the
+// previously seen runtimes that are no longer there.
+func updateRuntimeHealth(hs *cmnGrpc.HealthServer, runtimes map[common.Namespace]struct{}, status *Status) {
+	for runtimeID := range runtimes {
+		if _, ok := status.Runtimes[runtimeID]; !ok {
+			hs.SetServing(RuntimeHealthService(runtimeID), false)
+			delete(runtimes, runtimeID)
+		}
+	}
+	for runtimeID, rs := range status.Runtimes {
+		ready := rs.Committee != nil && rs.Committee.Status == commonWorker.StatusStateReady
+		hs.SetServing(RuntimeHealthService(runtimeID), ready)
+		runtimes[runtimeID] = struct{}{}
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	commonWorker "github.com/oasisprotocol/oasis-core/go/worker/common/api"
+)
+
+type healthTestController struct {
+	NodeController
+
+	readyCh  chan struct{}
+	ready    atomic.Bool
+	statusCh chan *Status
+}
+
+func (c *healthTestController) WaitReady(ctx context.Context) error {
+	select {
+	case <-c.readyCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *healthTestController) IsReady(context.Context) (bool, error) {
+	return c.ready.Load(), nil
+}
+
+func (c *healthTestController) WatchStatus(ctx context.Context) (<-chan *Status, pubsub.ClosableSubscription, error) {
+	_, sub := pubsub.NewContextSubscription(ctx)
+	return c.statusCh, sub, nil
+}
+
+func TestHealthService(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	controller := &healthTestController{
+		readyCh:  make(chan struct{}),
+		statusCh: make(chan *Status),
+	}
+	hs := cmnGrpc.NewHealthServer()
+	go watchHealth(ctx, hs, controller)
+
+	requireStatus := func(service string, expected healthpb.HealthCheckResponse_ServingStatus) {
+		require.Eventually(func() bool {
+			rsp, err := hs.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+			return err == nil && rsp.Status == expected
+		}, time.Second, 10*time.Millisecond, "service '%s' should be %s", service, expected)
+	}
+
+	requireStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	// Readiness should be reported as soon as WaitReady returns.
+	controller.ready.Store(true)
+	close(controller.readyCh)
+	requireStatus("", healthpb.HealthCheckResponse_SERVING)
+
+	// Runtimes should be reported individually.
+	var rt1, rt2 common.Namespace
+	rt2[0] = 1
+	controller.statusCh <- &Status{
+		Runtimes: map[common.Namespace]RuntimeStatus{
+			rt1: {Committee: &commonWorker.Status{Status: commonWorker.StatusStateReady}},
+			rt2: {Committee: &commonWorker.Status{Status: commonWorker.StatusStateWaitingHostedRuntime}},
+		},
+	}
+	requireStatus(RuntimeHealthService(rt1), healthpb.HealthCheckResponse_SERVING)
+	requireStatus(RuntimeHealthService(rt2), healthpb.HealthCheckResponse_NOT_SERVING)
+
+	// Regressions should be picked up from status updates.
+	controller.ready.Store(false)
+	controller.statusCh <- &Status{
+		Runtimes: map[common.Namespace]RuntimeStatus{
+			rt2: {Committee: &commonWorker.Status{Status: commonWorker.StatusStateReady}},
+		},
+	}
+	requireStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	requireStatus(RuntimeHealthService(rt1), healthpb.HealthCheckResponse_NOT_SERVING)
+	requireStatus(RuntimeHealthService(rt2), healthpb.HealthCheckResponse_SERVING)
+
+	// Unknown services should be reported as such.
+	_, err := hs.Check(ctx, &healthpb.HealthCheckRequest{Service: RuntimeHealthServicePrefix + "unknown"})
+	require.Error(err, "unknown services should not be found")
+
+	// Everything should stop serving once the watcher terminates.
+	cancel()
+	requireStatus(RuntimeHealthService(rt2), healthpb.HealthCheckResponse_NOT_SERVING)
+}
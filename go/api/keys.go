@@ -0,0 +1,154 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// DefaultKeyRotationGracePeriod is the default time during which rotated out keys remain valid.
+const DefaultKeyRotationGracePeriod = time.Hour
+
+// KeyRole is the role of a node key.
+type KeyRole string
+
+const (
+	// KeyRoleNode is the role of the node identity key.
+	KeyRoleNode KeyRole = "node"
+	// KeyRoleConsensus is the role of the consensus key.
+	KeyRoleConsensus KeyRole = "consensus"
+	// KeyRoleP2P is the role of the P2P key.
+	KeyRoleP2P KeyRole = "p2p"
+	// KeyRoleTLS is the role of the TLS key.
+	KeyRoleTLS KeyRole = "tls"
+	// KeyRoleVRF is the role of the VRF key.
+	KeyRoleVRF KeyRole = "vrf"
+)
+
+// PublicKeyInfo describes a public key of the node.
+type PublicKeyInfo struct {
+	// Role is the role of the key.
+	Role KeyRole `json:"role"`
+
+	// PublicKey is the public key.
+	PublicKey signature.PublicKey `json:"public_key"`
+
+	// ValidUntil is the end of the grace window of a rotated out key. It is not set for the
+	// current keys.
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
+
+// RotateKeysRequest is a request to rotate node keys.
+type RotateKeysRequest struct {
+	// TLS specifies whether the TLS key should be rotated.
+	TLS bool `json:"tls,omitempty"`
+
+	// P2P specifies whether the P2P key should be rotated.
+	P2P bool `json:"p2p,omitempty"`
+
+	// GracePeriod is the time during which the rotated out keys remain valid. If zero,
+	// DefaultKeyRotationGracePeriod is used.
+	GracePeriod time.Duration `json:"grace_period,omitempty"`
+}
+
+// ValidateBasic performs basic key rotation request validity checks.
+func (r *RotateKeysRequest) ValidateBasic() error {
+	if !r.TLS && !r.P2P {
+		return fmt.Errorf("%w: no keys to rotate", ErrInvalidRotateKeysRequest)
+	}
+	if r.GracePeriod < 0 {
+		return fmt.Errorf("%w: negative grace period", ErrInvalidRotateKeysRequest)
+	}
+	return nil
+}
+
+// EffectiveGracePeriod returns the grace period that should be used for the request.
+func (r *RotateKeysRequest) EffectiveGracePeriod() time.Duration {
+	if r.GracePeriod == 0 {
+		return DefaultKeyRotationGracePeriod
+	}
+	return r.GracePeriod
+}
+
+// RotateKeysResponse is a key rotation response.
+type RotateKeysResponse struct {
+	// TLS is the new TLS public key if the TLS key has been rotated.
+	TLS *signature.PublicKey `json:"tls,omitempty"`
+
+	// P2P is the new P2P public key if the P2P key has been rotated.
+	P2P *signature.PublicKey `json:"p2p,omitempty"`
+
+	// GracePeriod is the time during which the rotated out keys remain valid.
+	GracePeriod time.Duration `json:"grace_period"`
+
+	// ValidUntil is the end of the grace window of the rotated out keys.
+	ValidUntil time.Time `json:"valid_until"`
+}
+
+// RotatedKeyRegistry keeps track of rotated out node keys which remain valid until the end of
+// their grace window.
+type RotatedKeyRegistry struct {
+	mu   sync.Mutex
+	keys []*PublicKeyInfo
+
+	nowFn func() time.Time
+}
+
+// NewRotatedKeyRegistry creates a new rotated key registry.
+func NewRotatedKeyRegistry() *RotatedKeyRegistry {
+	return &RotatedKeyRegistry{
+		nowFn: time.Now,
+	}
+}
+
+// Add records that the given key has been rotated out and returns the end of its grace window.
+func (r *RotatedKeyRegistry) Add(role KeyRole, pk signature.PublicKey, gracePeriod time.Duration) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	validUntil := r.nowFn().Add(gracePeriod)
+	r.pruneLocked()
+	r.keys = append(r.keys, &PublicKeyInfo{
+		Role:       role,
+		PublicKey:  pk,
+		ValidUntil: &validUntil,
+	})
+	return validUntil
+}
+
+// Keys returns the rotated out keys that are still within their grace window.
+func (r *RotatedKeyRegistry) Keys() []*PublicKeyInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneLocked()
+	return slices.Clone(r.keys)
+}
+
+// ValidKeys returns the given current key of the given role followed by all rotated out keys of
+// that role which are still within their grace window.
+func (r *RotatedKeyRegistry) ValidKeys(role KeyRole, current signature.PublicKey) []signature.PublicKey {
+	keys := []signature.PublicKey{current}
+	for _, ki := range r.Keys() {
+		if ki.Role == role && !ki.PublicKey.Equal(current) {
+			keys = append(keys, ki.PublicKey)
+		}
+	}
+	return keys
+}
+
+// IsValid checks whether the given key is valid for the given role, i.e. whether it is either
+// the given current key or a rotated out key which is still within its grace window.
+func (r *RotatedKeyRegistry) IsValid(role KeyRole, current, pk signature.PublicKey) bool {
+	return slices.ContainsFunc(r.ValidKeys(role, current), pk.Equal)
+}
+
+func (r *RotatedKeyRegistry) pruneLocked() {
+	now := r.nowFn()
+	r.keys = slices.DeleteFunc(r.keys, func(ki *PublicKeyInfo) bool {
+		return !now.Before(*ki.ValidUntil)
+	})
+}
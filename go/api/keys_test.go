@@ -0,0 +1,73 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+)
+
+func TestRotateKeysRequest(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		req   RotateKeysRequest
+		valid bool
+		grace time.Duration
+	}{
+		{RotateKeysRequest{}, false, DefaultKeyRotationGracePeriod},
+		{RotateKeysRequest{TLS: true}, true, DefaultKeyRotationGracePeriod},
+		{RotateKeysRequest{P2P: true, GracePeriod: time.Minute}, true, time.Minute},
+		{RotateKeysRequest{TLS: true, GracePeriod: -time.Minute}, false, -time.Minute},
+	} {
+		err := tc.req.ValidateBasic()
+		switch tc.valid {
+		case true:
+			require.NoError(err, "ValidateBasic(%+v)", tc.req)
+		case false:
+			require.ErrorIs(err, ErrInvalidRotateKeysRequest, "ValidateBasic(%+v)", tc.req)
+		}
+		require.Equal(tc.grace, tc.req.EffectiveGracePeriod())
+	}
+}
+
+func TestRotatedKeyRegistry(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(1_700_000_000, 0).UTC()
+	r := NewRotatedKeyRegistry()
+	r.nowFn = func() time.Time { return now }
+
+	oldKey := memorySigner.NewTestSigner("control test TLS key 1").Public()
+	newKey := memorySigner.NewTestSigner("control test TLS key 2").Public()
+	p2pKey := memorySigner.NewTestSigner("control test P2P key").Public()
+
+	// Before rotation only the current key is valid.
+	require.True(r.IsValid(KeyRoleTLS, oldKey, oldKey))
+	require.False(r.IsValid(KeyRoleTLS, oldKey, newKey))
+	require.Empty(r.Keys())
+
+	// Rotate the TLS key, both keys should be valid during the grace window.
+	validUntil := r.Add(KeyRoleTLS, oldKey, time.Hour)
+	require.Equal(now.Add(time.Hour), validUntil)
+	require.True(r.IsValid(KeyRoleTLS, newKey, newKey), "new key should be valid")
+	require.True(r.IsValid(KeyRoleTLS, newKey, oldKey), "old key should be valid during the grace window")
+	require.False(r.IsValid(KeyRoleP2P, p2pKey, oldKey), "rotated out keys should only be valid for their role")
+	require.Equal([]signature.PublicKey{newKey, oldKey}, r.ValidKeys(KeyRoleTLS, newKey))
+	require.Equal([]*PublicKeyInfo{
+		{Role: KeyRoleTLS, PublicKey: oldKey, ValidUntil: &validUntil},
+	}, r.Keys())
+
+	now = now.Add(59 * time.Minute)
+	require.True(r.IsValid(KeyRoleTLS, newKey, oldKey), "old key should be valid during the grace window")
+
+	// Once the grace window ends, only the new key should be valid.
+	now = now.Add(time.Minute)
+	require.True(r.IsValid(KeyRoleTLS, newKey, newKey), "new key should be valid")
+	require.False(r.IsValid(KeyRoleTLS, newKey, oldKey), "old key should expire after the grace window")
+	require.Equal([]signature.PublicKey{newKey}, r.ValidKeys(KeyRoleTLS, newKey))
+	require.Empty(r.Keys())
+}
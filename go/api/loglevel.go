@@ -0,0 +1,43 @@
+package api
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+)
+
+// DefaultLogLevelModule is the module under which the default log level is reported, matching
+// the key used in the log level configuration.
+const DefaultLogLevelModule = "default"
+
+// LogLevelRequest is a request to change the log level.
+type LogLevelRequest struct {
+	// Module is the glob matching the modules of the loggers (as passed to logging.GetLogger)
+	// whose level should be changed, e.g. "mkvs/db/*". If empty, the default level is changed.
+	Module string `json:"module,omitempty"`
+
+	// Level is the new log level.
+	Level string `json:"level"`
+}
+
+// ValidateBasic performs basic log level request validity checks.
+func (r *LogLevelRequest) ValidateBasic() error {
+	if _, err := path.Match(r.Module, ""); err != nil {
+		return fmt.Errorf("%w: malformed module glob: %w", ErrInvalidLogLevelRequest, err)
+	}
+	var lvl logging.Level
+	if err := lvl.Set(r.Level); err != nil {
+		return fmt.Errorf("%w: malformed level: %w", ErrInvalidLogLevelRequest, err)
+	}
+	return nil
+}
+
+// Matches returns true iff the logger with the given module is affected by the request.
+func (r *LogLevelRequest) Matches(module string) bool {
+	if r.Module == "" {
+		return module == DefaultLogLevelModule
+	}
+	matched, _ := path.Match(r.Module, module)
+	return matched
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLevelRequest(t *testing.T) {
+	require := require.New(t)
+
+	req := LogLevelRequest{
+		Module: "mkvs/db/*",
+		Level:  "debug",
+	}
+	require.NoError(req.ValidateBasic(), "ValidateBasic")
+	require.True(req.Matches("mkvs/db/badger"))
+	require.False(req.Matches("mkvs/db/badger/gc"), "globs should not cross module separators")
+	require.False(req.Matches("mkvs/tree"))
+	require.False(req.Matches(DefaultLogLevelModule))
+
+	req = LogLevelRequest{Level: "warn"}
+	require.NoError(req.ValidateBasic(), "ValidateBasic")
+	require.True(req.Matches(DefaultLogLevelModule), "empty module should match the default level")
+	require.False(req.Matches("mkvs/tree"))
+
+	req = LogLevelRequest{Module: "mkvs/[", Level: "debug"}
+	require.ErrorIs(req.ValidateBasic(), ErrInvalidLogLevelRequest)
+
+	req = LogLevelRequest{Module: "mkvs/*", Level: "verbose"}
+	require.ErrorIs(req.ValidateBasic(), ErrInvalidLogLevelRequest)
+}
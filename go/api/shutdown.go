@@ -0,0 +1,243 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ShutdownReasonFilename is the name of the file in the node's data directory that holds the
+// reason for the last node shutdown.
+const ShutdownReasonFilename = "shutdown-reason.json"
+
+// ShutdownReasonKind is the kind of the reason for a node shutdown.
+type ShutdownReasonKind string
+
+const (
+	// ShutdownReasonOperator is the reason used when the operator requested the shutdown.
+	ShutdownReasonOperator ShutdownReasonKind = "operator"
+	// ShutdownReasonUpgrade is the reason used when the node shuts down for an upgrade.
+	ShutdownReasonUpgrade ShutdownReasonKind = "upgrade"
+	// ShutdownReasonStorageFailure is the reason used when the node shuts down due to a fatal
+	// storage error.
+	ShutdownReasonStorageFailure ShutdownReasonKind = "storage_failure"
+	// ShutdownReasonPanic is the reason used when the node shuts down due to a panic in one of
+	// its components.
+	ShutdownReasonPanic ShutdownReasonKind = "panic"
+	// ShutdownReasonSignal is the reason used when the node shuts down due to a signal.
+	ShutdownReasonSignal ShutdownReasonKind = "signal"
+	// ShutdownReasonHalt is the reason used when the node shuts down due to reaching the
+	// operator-requested consensus halt epoch.
+	ShutdownReasonHalt ShutdownReasonKind = "halt"
+)
+
+// ShutdownReason is a structured reason for a node shutdown.
+type ShutdownReason struct {
+	// Kind is the kind of the shutdown reason.
+	Kind ShutdownReasonKind `json:"kind"`
+
+	// Component is the name of the component that requested the shutdown.
+	Component string `json:"component,omitempty"`
+
+	// Message is a component-provided description of the shutdown reason.
+	Message string `json:"message,omitempty"`
+
+	// Annotation is a free-form annotation attached by the operator.
+	Annotation string `json:"annotation,omitempty"`
+
+	// RestartIntended is true iff the component expects the node to be restarted (e.g., with
+	// an upgraded binary).
+	RestartIntended bool `json:"restart_intended,omitempty"`
+
+	// Timestamp is the time at which the shutdown was requested.
+	Timestamp time.Time `json:"timestamp"`
+
+	// Deadline is the time after which the node exits even if not all of the workers have
+	// drained, if any.
+	Deadline *time.Time `json:"deadline,omitempty"`
+}
+
+// ShutdownRequest is a node shutdown request.
+type ShutdownRequest struct {
+	// Wait specifies whether the call should wait for the shutdown to complete.
+	Wait bool `json:"wait,omitempty"`
+
+	// Annotation is a free-form annotation recorded together with the shutdown reason.
+	Annotation string `json:"annotation,omitempty"`
+
+	// GracePeriod is the time the node is given to drain the workers after which it exits
+	// regardless. Zero means that the node waits for the workers indefinitely.
+	GracePeriod time.Duration `json:"grace_period,omitempty"`
+
+	// Reason is a free-form description of why the shutdown has been requested.
+	Reason string `json:"reason,omitempty"`
+
+	// Kind is the kind of the shutdown reason for requests made by node components. Remote
+	// requests are always recorded as operator-requested.
+	Kind ShutdownReasonKind `json:"-"`
+
+	// Component is the name of the node component that requested the shutdown.
+	Component string `json:"-"`
+}
+
+// ValidateBasic performs basic shutdown request validity checks.
+func (r *ShutdownRequest) ValidateBasic() error {
+	if r.GracePeriod < 0 {
+		return fmt.Errorf("%w: negative grace period", ErrInvalidShutdownRequest)
+	}
+	return nil
+}
+
+// ShutdownReason returns the shutdown reason that should be recorded for the request received
+// at the given time.
+func (r *ShutdownRequest) ShutdownReason(now time.Time) ShutdownReason {
+	reason := ShutdownReason{
+		Kind:       ShutdownReasonOperator,
+		Component:  ModuleName,
+		Message:    r.Reason,
+		Annotation: r.Annotation,
+		Timestamp:  now,
+	}
+	if r.Kind != "" {
+		reason.Kind = r.Kind
+	}
+	if r.Component != "" {
+		reason.Component = r.Component
+	}
+	if r.GracePeriod > 0 {
+		deadline := now.Add(r.GracePeriod)
+		reason.Deadline = &deadline
+	}
+	return reason
+}
+
+// ShutdownReasonRegistry records the reason for the node shutdown.
+//
+// Only the first recorded reason is kept as subsequent shutdown requests are usually just
+// consequences of the first one.
+type ShutdownReasonRegistry struct {
+	mu     sync.Mutex
+	reason *ShutdownReason
+	last   *ShutdownReason
+
+	nowFn func() time.Time
+}
+
+// NewShutdownReasonRegistry creates a new shutdown reason registry.
+func NewShutdownReasonRegistry() *ShutdownReasonRegistry {
+	return &ShutdownReasonRegistry{
+		nowFn: time.Now,
+	}
+}
+
+// OpenShutdownReasonRegistry creates a new shutdown reason registry and loads the reason for the
+// previous shutdown from the given data directory.
+//
+// The persisted reason is removed once loaded so that a stale reason is never reported after a
+// later shutdown that did not record one.
+func OpenShutdownReasonRegistry(dataDir string) (*ShutdownReasonRegistry, error) {
+	last, err := LoadShutdownReason(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		if err = os.Remove(filepath.Join(dataDir, ShutdownReasonFilename)); err != nil {
+			return nil, fmt.Errorf("control: failed to remove shutdown reason: %w", err)
+		}
+	}
+
+	r := NewShutdownReasonRegistry()
+	r.last = last
+	return r, nil
+}
+
+// Record records the reason for the node shutdown.
+//
+// Returns true iff the reason has been recorded, i.e. no reason has been recorded before.
+func (r *ShutdownReasonRegistry) Record(reason ShutdownReason) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.reason != nil {
+		return false
+	}
+	if reason.Timestamp.IsZero() {
+		reason.Timestamp = r.nowFn()
+	}
+	r.reason = &reason
+	return true
+}
+
+// Reason returns the recorded shutdown reason or nil if no reason has been recorded yet.
+func (r *ShutdownReasonRegistry) Reason() *ShutdownReason {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.reason == nil {
+		return nil
+	}
+	reason := *r.reason
+	return &reason
+}
+
+// LastReason returns the reason for the previous shutdown loaded when the registry was opened or
+// nil if no reason has been recorded.
+func (r *ShutdownReasonRegistry) LastReason() *ShutdownReason {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.last == nil {
+		return nil
+	}
+	reason := *r.last
+	return &reason
+}
+
+// Persist writes the recorded shutdown reason into the given data directory.
+//
+// In case no reason has been recorded, nothing is written.
+func (r *ShutdownReasonRegistry) Persist(dataDir string) error {
+	reason := r.Reason()
+	if reason == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(reason)
+	if err != nil {
+		return fmt.Errorf("control: failed to marshal shutdown reason: %w", err)
+	}
+
+	fn := filepath.Join(dataDir, ShutdownReasonFilename)
+	tmpFn := fn + ".tmp"
+	if err = os.WriteFile(tmpFn, data, 0o600); err != nil {
+		return fmt.Errorf("control: failed to write shutdown reason: %w", err)
+	}
+	if err = os.Rename(tmpFn, fn); err != nil {
+		return fmt.Errorf("control: failed to write shutdown reason: %w", err)
+	}
+	return nil
+}
+
+// LoadShutdownReason loads the reason for the previous shutdown from the given data directory.
+//
+// Returns nil in case no reason has been recorded.
+func LoadShutdownReason(dataDir string) (*ShutdownReason, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, ShutdownReasonFilename))
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("control: failed to read shutdown reason: %w", err)
+	}
+
+	var reason ShutdownReason
+	if err = json.Unmarshal(data, &reason); err != nil {
+		return nil, fmt.Errorf("control: malformed shutdown reason: %w", err)
+	}
+	return &reason, nil
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+func TestShutdownReasonRegistry(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(1_700_000_000, 0).UTC()
+
+	// Nothing recorded yet.
+	dataDir := t.TempDir()
+	reason, err := LoadShutdownReason(dataDir)
+	require.NoError(err, "LoadShutdownReason")
+	require.Nil(reason, "no shutdown reason should be recorded")
+
+	// Operator-requested shutdown.
+	r := NewShutdownReasonRegistry()
+	r.nowFn = func() time.Time { return now }
+	require.Nil(r.Reason())
+	require.True(r.Record(ShutdownReason{
+		Kind:       ShutdownReasonOperator,
+		Component:  "control",
+		Annotation: "host maintenance",
+	}))
+	require.False(r.Record(ShutdownReason{Kind: ShutdownReasonSignal}), "only the first reason should be kept")
+	require.NoError(r.Persist(dataDir), "Persist")
+
+	reason, err = LoadShutdownReason(dataDir)
+	require.NoError(err, "LoadShutdownReason")
+	require.Equal(&ShutdownReason{
+		Kind:       ShutdownReasonOperator,
+		Component:  "control",
+		Annotation: "host maintenance",
+		Timestamp:  now,
+	}, reason)
+
+	// Upgrade-triggered shutdown after a restart overwrites the previous reason.
+	r = NewShutdownReasonRegistry()
+	r.nowFn = func() time.Time { return now.Add(time.Hour) }
+	require.True(r.Record(ShutdownReason{
+		Kind:            ShutdownReasonUpgrade,
+		Component:       "upgrade",
+		Message:         "upgrade 'test' reached",
+		RestartIntended: true,
+	}))
+	require.NoError(r.Persist(dataDir), "Persist")
+
+	reason, err = LoadShutdownReason(dataDir)
+	require.NoError(err, "LoadShutdownReason")
+	require.Equal(ShutdownReasonUpgrade, reason.Kind)
+	require.Equal("upgrade 'test' reached", reason.Message)
+	require.True(reason.RestartIntended)
+	require.Empty(reason.Annotation)
+	require.Equal(now.Add(time.Hour), reason.Timestamp)
+
+	// Persisting without a recorded reason should keep the previous reason.
+	require.NoError(NewShutdownReasonRegistry().Persist(dataDir))
+	reason, err = LoadShutdownReason(dataDir)
+	require.NoError(err, "LoadShutdownReason")
+	require.Equal(ShutdownReasonUpgrade, reason.Kind)
+
+	// Opening the registry should report the previous reason and clear it.
+	r, err = OpenShutdownReasonRegistry(dataDir)
+	require.NoError(err, "OpenShutdownReasonRegistry")
+	require.Nil(r.Reason())
+	require.NotNil(r.LastReason())
+	require.Equal(ShutdownReasonUpgrade, r.LastReason().Kind)
+	reason, err = LoadShutdownReason(dataDir)
+	require.NoError(err, "LoadShutdownReason")
+	require.Nil(reason, "previous shutdown reason should be cleared once loaded")
+
+	r, err = OpenShutdownReasonRegistry(dataDir)
+	require.NoError(err, "OpenShutdownReasonRegistry")
+	require.Nil(r.LastReason(), "stale shutdown reason should not be reported again")
+}
+
+func TestShutdownRequest(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Unix(1_700_000_000, 0).UTC()
+
+	req := ShutdownRequest{
+		Wait:       true,
+		Annotation: "host maintenance",
+		Reason:     "kernel upgrade",
+	}
+	require.NoError(req.ValidateBasic(), "ValidateBasic")
+	require.Equal(ShutdownReason{
+		Kind:       ShutdownReasonOperator,
+		Component:  ModuleName,
+		Message:    "kernel upgrade",
+		Annotation: "host maintenance",
+		Timestamp:  now,
+	}, req.ShutdownReason(now))
+
+	req.GracePeriod = 30 * time.Second
+	require.NoError(req.ValidateBasic(), "ValidateBasic")
+	reason := req.ShutdownReason(now)
+	require.NotNil(reason.Deadline)
+	require.Equal(now.Add(30*time.Second), *reason.Deadline)
+
+	req.GracePeriod = -time.Second
+	require.ErrorIs(req.ValidateBasic(), ErrInvalidShutdownRequest)
+
+	// Requests made by node components carry their own reason kind.
+	req = ShutdownRequest{
+		Reason:    "failed to finalize round 42",
+		Kind:      ShutdownReasonStorageFailure,
+		Component: "worker/storage",
+	}
+	reason = req.ShutdownReason(now)
+	require.Equal(ShutdownReasonStorageFailure, reason.Kind)
+	require.Equal("worker/storage", reason.Component)
+
+	// Component-provided fields are never accepted from remote requests.
+	var remote ShutdownRequest
+	require.NoError(cbor.Unmarshal(cbor.Marshal(&req), &remote), "Unmarshal")
+	require.Equal(ShutdownReasonOperator, remote.ShutdownReason(now).Kind)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+)
+
+// DefaultStatusHeartbeatInterval is the default interval at which the node status is published to
+// WatchStatus subscribers even when nothing has changed.
+const DefaultStatusHeartbeatInterval = 30 * time.Second
+
+// statusChanges are the parts of the node status whose changes cause a new status to be published.
+type statusChanges struct {
+	Registration *RegistrationStatus                `json:"registration,omitempty"`
+	Consensus    consensus.StatusState              `json:"consensus"`
+	Runtimes     map[common.Namespace]RuntimeStatus `json:"runtimes,omitempty"`
+}
+
+// StatusNotifier publishes node status updates to WatchStatus subscribers.
+//
+// A new status is published when the notifier is triggered and the registration, consensus sync
+// or runtime status differ from the last published status. In addition, the current status is
+// published periodically as a heartbeat.
+type StatusNotifier struct {
+	getStatus func(context.Context) (*Status, error)
+	heartbeat time.Duration
+
+	triggerCh chan struct{}
+	notifier  *pubsub.Broker
+
+	// last is the fingerprint of the last published status. It is only accessed by Run.
+	last []byte
+
+	logger *logging.Logger
+}
+
+// NewStatusNotifier creates a new status notifier that obtains the node status using the given
+// function. If the heartbeat interval is zero, DefaultStatusHeartbeatInterval is used.
+func NewStatusNotifier(getStatus func(context.Context) (*Status, error), heartbeat time.Duration) *StatusNotifier {
+	if heartbeat == 0 {
+		heartbeat = DefaultStatusHeartbeatInterval
+	}
+	return &StatusNotifier{
+		getStatus: getStatus,
+		heartbeat: heartbeat,
+		triggerCh: make(chan struct{}, 1),
+		notifier:  pubsub.NewBroker(true),
+		logger:    logging.GetLogger("control/status"),
+	}
+}
+
+// Trigger notifies the status notifier that the node status may have changed. It never blocks.
+func (n *StatusNotifier) Trigger() {
+	select {
+	case n.triggerCh <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes status updates until the given context is canceled.
+func (n *StatusNotifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		var heartbeat bool
+		select {
+		case <-ctx.Done():
+			return
+		case <-n.triggerCh:
+		case <-ticker.C:
+			heartbeat = true
+		}
+
+		n.publish(ctx, heartbeat)
+	}
+}
+
+func (n *StatusNotifier) publish(ctx context.Context, heartbeat bool) {
+	status, err := n.getStatus(ctx)
+	if err != nil {
+		n.logger.Warn("failed to get node status",
+			"err", err,
+		)
+		return
+	}
+
+	changes := statusChanges{
+		Registration: status.Registration,
+		Runtimes:     status.Runtimes,
+	}
+	if status.Consensus != nil {
+		changes.Consensus = status.Consensus.Status
+	}
+	fingerprint := cbor.Marshal(changes)
+
+	if !heartbeat && bytes.Equal(fingerprint, n.last) {
+		return
+	}
+	n.last = fingerprint
+	n.notifier.Broadcast(status)
+}
+
+// WatchStatus returns a channel that produces a stream of node status updates. The last published
+// status is sent to new subscribers immediately.
+func (n *StatusNotifier) WatchStatus(context.Context) (<-chan *Status, pubsub.ClosableSubscription, error) {
+	ch := make(chan *Status)
+	sub := n.notifier.Subscribe()
+	sub.Unwrap(ch)
+	return ch, sub, nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Status sections that can be selected using a StatusFilter.
+const (
+	StatusSectionConsensus       = "consensus"
+	StatusSectionLightClient     = "light_client"
+	StatusSectionRuntimes        = "runtimes"
+	StatusSectionRegistration    = "registration"
+	StatusSectionKeymanager      = "keymanager"
+	StatusSectionPendingUpgrades = "pending_upgrades"
+	StatusSectionP2P             = "p2p"
+	StatusSectionSeed            = "seed"
+)
+
+// StatusFilter selects the sections of the node status that should be gathered.
+//
+// Sections that are not selected are not gathered at all, which avoids the cost of the
+// corresponding sub-queries. Cheap sections (e.g., the software version, identity and shutdown
+// status) are always included.
+type StatusFilter struct {
+	// Consensus selects the consensus layer status.
+	Consensus bool `json:"consensus,omitempty"`
+	// LightClient selects the light client service status.
+	LightClient bool `json:"light_client,omitempty"`
+	// Runtimes selects the per-runtime status, including the runtime storage status.
+	Runtimes bool `json:"runtimes,omitempty"`
+	// Registration selects the node registration status.
+	Registration bool `json:"registration,omitempty"`
+	// Keymanager selects the key manager worker status.
+	Keymanager bool `json:"keymanager,omitempty"`
+	// PendingUpgrades selects the pending upgrades.
+	PendingUpgrades bool `json:"pending_upgrades,omitempty"`
+	// P2P selects the P2P status.
+	P2P bool `json:"p2p,omitempty"`
+	// Seed selects the seed node status.
+	Seed bool `json:"seed,omitempty"`
+}
+
+// OrAll returns the filter itself, or a filter selecting all sections in case it is nil.
+func (f *StatusFilter) OrAll() *StatusFilter {
+	if f != nil {
+		return f
+	}
+	return &StatusFilter{
+		Consensus:       true,
+		LightClient:     true,
+		Runtimes:        true,
+		Registration:    true,
+		Keymanager:      true,
+		PendingUpgrades: true,
+		P2P:             true,
+		Seed:            true,
+	}
+}
+
+// ParseStatusFilter parses a list of status section names into a status filter.
+//
+// An empty list selects all sections and results in a nil filter.
+func ParseStatusFilter(sections []string) (*StatusFilter, error) {
+	if len(sections) == 0 {
+		return nil, nil
+	}
+
+	var f StatusFilter
+	for _, section := range sections {
+		switch strings.TrimSpace(section) {
+		case StatusSectionConsensus:
+			f.Consensus = true
+		case StatusSectionLightClient:
+			f.LightClient = true
+		case StatusSectionRuntimes:
+			f.Runtimes = true
+		case StatusSectionRegistration:
+			f.Registration = true
+		case StatusSectionKeymanager:
+			f.Keymanager = true
+		case StatusSectionPendingUpgrades:
+			f.PendingUpgrades = true
+		case StatusSectionP2P:
+			f.P2P = true
+		case StatusSectionSeed:
+			f.Seed = true
+		default:
+			return nil, fmt.Errorf("unknown status section: '%s'", section)
+		}
+	}
+	return &f, nil
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusFilter(t *testing.T) {
+	require := require.New(t)
+
+	f, err := ParseStatusFilter(nil)
+	require.NoError(err, "ParseStatusFilter")
+	require.Nil(f, "empty section list should select everything")
+
+	all := f.OrAll()
+	require.True(all.Consensus)
+	require.True(all.Runtimes)
+	require.True(all.Seed)
+
+	f, err = ParseStatusFilter([]string{StatusSectionConsensus, " " + StatusSectionP2P})
+	require.NoError(err, "ParseStatusFilter")
+	require.Equal(&StatusFilter{Consensus: true, P2P: true}, f)
+	require.Same(f, f.OrAll(), "non-nil filter should be used as is")
+
+	_, err = ParseStatusFilter([]string{StatusSectionConsensus, "bogus"})
+	require.Error(err, "unknown sections should be rejected")
+}
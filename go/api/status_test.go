@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusNotifier(t *testing.T) {
+	require := require.New(t)
+
+	var (
+		mu     sync.Mutex
+		status = Status{
+			SoftwareVersion: "test",
+			Registration:    &RegistrationStatus{},
+		}
+	)
+	getStatus := func(context.Context) (*Status, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		s := status
+		reg := *status.Registration
+		s.Registration = &reg
+		return &s, nil
+	}
+	update := func(fn func(*Status)) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn(&status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := NewStatusNotifier(getStatus, time.Hour)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.Run(ctx)
+	}()
+
+	ch, sub, err := n.WatchStatus(ctx)
+	require.NoError(err, "WatchStatus")
+	defer sub.Close()
+
+	requireStatus := func(msg string) *Status {
+		select {
+		case s := <-ch:
+			return s
+		case <-time.After(time.Second):
+			require.FailNow("timed out waiting for status", msg)
+			return nil
+		}
+	}
+	requireNoStatus := func(msg string) {
+		select {
+		case <-ch:
+			require.FailNow("unexpected status", msg)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	n.Trigger()
+	s := requireStatus("initial status")
+	require.False(s.Registration.LastAttemptSuccessful)
+
+	n.Trigger()
+	requireNoStatus("unchanged status should not be published")
+
+	update(func(s *Status) {
+		s.SoftwareVersion = "other"
+	})
+	n.Trigger()
+	requireNoStatus("unrelated changes should not be published")
+
+	update(func(s *Status) {
+		s.Registration.LastAttemptSuccessful = true
+	})
+	n.Trigger()
+	s = requireStatus("registration change")
+	require.True(s.Registration.LastAttemptSuccessful)
+	require.Equal("other", s.SoftwareVersion)
+
+	// New subscribers should get the last published status.
+	ch2, sub2, err := n.WatchStatus(ctx)
+	require.NoError(err, "WatchStatus")
+	select {
+	case s = <-ch2:
+		require.True(s.Registration.LastAttemptSuccessful)
+	case <-time.After(time.Second):
+		require.FailNow("timed out waiting for last status")
+	}
+	sub2.Close()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow("Run should terminate on context cancellation")
+	}
+
+	// Heartbeats should be published even without changes.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	n = NewStatusNotifier(getStatus, 10*time.Millisecond)
+	go n.Run(ctx)
+
+	ch, sub, err = n.WatchStatus(ctx)
+	require.NoError(err, "WatchStatus")
+	defer sub.Close()
+	requireStatus("first heartbeat")
+	requireStatus("second heartbeat")
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/config"
+)
+
+const (
+	// DefaultMaxStorageBackupRate is the default maximum rate in bytes per second at which storage
+	// backups are streamed via the control API.
+	DefaultMaxStorageBackupRate = 64 * 1024 * 1024
+
+	// BackupStorageChunkSize is the maximum size of the chunks storage backups are streamed in.
+	BackupStorageChunkSize = 1024 * 1024
+)
+
+// BackupStorageRequest is a request to back up a runtime's storage.
+type BackupStorageRequest struct {
+	// RuntimeID is the identifier of the runtime whose storage should be backed up.
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// SinceVersion is the first version to include in the backup. It should be set to the version
+	// returned by the previous backup in order to take an incremental backup. If zero, a full
+	// backup is taken.
+	SinceVersion uint64 `json:"since_version,omitempty"`
+
+	// MaxRate is the maximum rate in bytes per second at which the backup is streamed. It can only
+	// lower the rate limit configured on the node. If zero, the configured rate limit is used.
+	MaxRate uint64 `json:"max_rate,omitempty"`
+}
+
+// BackupStorageChunk is a chunk of a storage backup streamed via BackupStorage.
+type BackupStorageChunk struct {
+	// Data is the chunk data.
+	Data []byte `json:"data,omitempty"`
+
+	// NextSinceVersion is the version at which the next incremental backup should start. It is
+	// only set in the last chunk.
+	NextSinceVersion *uint64 `json:"next_since_version,omitempty"`
+}
+
+// storageBackupLock makes sure that only one storage backup is streamed at a time.
+var storageBackupLock sync.Mutex
+
+// storageBackupRate returns the rate limit of the given storage backup request.
+func storageBackupRate(req *BackupStorageRequest) uint64 {
+	rate := uint64(DefaultMaxStorageBackupRate)
+	hotConfigLock.RLock()
+	if r := config.GlobalConfig.Common.Control.MaxStorageBackupRate; r != "" {
+		rate = uint64(config.ParseSizeInBytes(r))
+	}
+	hotConfigLock.RUnlock()
+	if req.MaxRate > 0 {
+		rate = min(rate, req.MaxRate)
+	}
+	return rate
+}
+
+// chunkWriter is a writer that sends everything written into it as storage backup chunks.
+type chunkWriter struct {
+	send func(*BackupStorageChunk) error
+}
+
+func (cw *chunkWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		size := min(len(p), BackupStorageChunkSize)
+		if err := cw.send(&BackupStorageChunk{Data: p[:size]}); err != nil {
+			return n, err
+		}
+		n += size
+		p = p[size:]
+	}
+	return n, nil
+}
+
+// rateLimitedWriter is a writer that delays writes so that the average rate at which data is
+// written does not exceed the given rate in bytes per second.
+type rateLimitedWriter struct {
+	ctx  context.Context
+	w    io.Writer
+	rate uint64
+
+	start   time.Time
+	written uint64
+}
+
+func newRateLimitedWriter(ctx context.Context, w io.Writer, rate uint64) *rateLimitedWriter {
+	return &rateLimitedWriter{
+		ctx:   ctx,
+		w:     w,
+		rate:  rate,
+		start: time.Now(),
+	}
+}
+
+func (rw *rateLimitedWriter) Write(p []byte) (int, error) {
+	n, err := rw.w.Write(p)
+	rw.written += uint64(n)
+	if err != nil {
+		return n, err
+	}
+
+	due := time.Duration(float64(rw.written) / float64(rw.rate) * float64(time.Second))
+	if delay := due - time.Since(rw.start); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-rw.ctx.Done():
+			return n, rw.ctx.Err()
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageBackupStream(t *testing.T) {
+	require := require.New(t)
+
+	var chunks []*BackupStorageChunk
+	cw := &chunkWriter{
+		send: func(chunk *BackupStorageChunk) error {
+			chunks = append(chunks, &BackupStorageChunk{Data: bytes.Clone(chunk.Data)})
+			return nil
+		},
+	}
+
+	data := bytes.Repeat([]byte{0x42}, 2*BackupStorageChunkSize+1)
+	n, err := cw.Write(data)
+	require.NoError(err, "Write")
+	require.Equal(len(data), n)
+	require.Len(chunks, 3, "writes should be split into chunks")
+	require.Len(chunks[2].Data, 1)
+
+	// Writes should be delayed to stay within the rate limit.
+	const rate = 64 * 1024
+	var buf bytes.Buffer
+	rw := newRateLimitedWriter(context.Background(), &buf, rate)
+	start := time.Now()
+	for range 4 {
+		_, err = rw.Write(make([]byte, rate/16))
+		require.NoError(err, "Write")
+	}
+	require.GreaterOrEqual(time.Since(start), 200*time.Millisecond, "writes should be rate limited")
+	require.Equal(rate/4, buf.Len())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rw = newRateLimitedWriter(ctx, &buf, rate)
+	_, err = rw.Write(make([]byte, rate))
+	require.ErrorIs(err, context.Canceled, "rate limited writes should honor the context")
+}
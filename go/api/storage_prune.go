@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+)
+
+// PruneStorageRequest is a request to prune old versions of a runtime's storage.
+//
+// Exactly one of EarliestVersion and KeepVersions must be set.
+type PruneStorageRequest struct {
+	// RuntimeID is the identifier of the runtime whose storage should be pruned.
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// EarliestVersion is the earliest version that should be kept, all versions before it are
+	// pruned.
+	EarliestVersion uint64 `json:"earliest_version,omitempty"`
+
+	// KeepVersions is the number of latest versions that should be kept, all versions before
+	// them are pruned.
+	KeepVersions uint64 `json:"keep_versions,omitempty"`
+}
+
+// ValidateBasic performs basic storage pruning request validity checks.
+func (r *PruneStorageRequest) ValidateBasic() error {
+	switch {
+	case r.EarliestVersion == 0 && r.KeepVersions == 0:
+		return fmt.Errorf("%w: either earliest version or versions to keep must be set", ErrInvalidPruneStorageRequest)
+	case r.EarliestVersion != 0 && r.KeepVersions != 0:
+		return fmt.Errorf("%w: earliest version and versions to keep are mutually exclusive", ErrInvalidPruneStorageRequest)
+	default:
+		return nil
+	}
+}
+
+// TargetEarliestVersion returns the earliest version that should be kept given the latest
+// finalized version.
+func (r *PruneStorageRequest) TargetEarliestVersion(latestVersion uint64) uint64 {
+	if r.KeepVersions == 0 {
+		return r.EarliestVersion
+	}
+	if r.KeepVersions > latestVersion {
+		return 0
+	}
+	return latestVersion - r.KeepVersions + 1
+}
+
+// PruneStorageResponse is a storage pruning response.
+type PruneStorageResponse struct {
+	// VersionsPruned is the number of versions that have been pruned.
+	VersionsPruned uint64 `json:"versions_pruned"`
+
+	// EarliestVersion is the earliest version remaining after pruning.
+	EarliestVersion uint64 `json:"earliest_version"`
+
+	// BytesReclaimed is the difference between the size of the storage database before and
+	// after pruning, as reported by the database. Space of pruned versions may only be fully
+	// reclaimed after the database is garbage collected.
+	BytesReclaimed uint64 `json:"bytes_reclaimed"`
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneStorageRequest(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		req    PruneStorageRequest
+		valid  bool
+		target uint64
+	}{
+		{PruneStorageRequest{}, false, 0},
+		{PruneStorageRequest{EarliestVersion: 10, KeepVersions: 5}, false, 96},
+		{PruneStorageRequest{EarliestVersion: 10}, true, 10},
+		{PruneStorageRequest{KeepVersions: 5}, true, 96},
+		{PruneStorageRequest{KeepVersions: 100}, true, 1},
+		{PruneStorageRequest{KeepVersions: 101}, true, 0},
+		{PruneStorageRequest{KeepVersions: 1000}, true, 0},
+	} {
+		err := tc.req.ValidateBasic()
+		switch tc.valid {
+		case true:
+			require.NoError(err, "ValidateBasic(%+v)", tc.req)
+		case false:
+			require.ErrorIs(err, ErrInvalidPruneStorageRequest, "ValidateBasic(%+v)", tc.req)
+		}
+		require.Equal(tc.target, tc.req.TargetEarliestVersion(100), "TargetEarliestVersion(%+v)", tc.req)
+	}
+}
@@ -0,0 +1,344 @@
+// Package datadir implements node data directory layout management.
+//
+// The node maintains a manifest in its data directory recording the layout version and the
+// owner of each known top-level entry. On startup, any pending layout migrations are applied
+// and the manifest is brought up to date.
+package datadir
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+)
+
+const (
+	// ManifestFilename is the name of the manifest file in the data directory.
+	ManifestFilename = "datadir-manifest.json"
+	// JournalFilename is the name of the migration journal file in the data directory.
+	JournalFilename = "datadir-migration.json"
+
+	// ManifestSchemaVersion is the supported manifest schema version.
+	ManifestSchemaVersion = 1
+
+	tmpSuffix = ".tmp"
+)
+
+// Manifest is the data directory manifest.
+type Manifest struct {
+	// SchemaVersion is the manifest schema version.
+	SchemaVersion uint16 `json:"schema_version"`
+
+	// LayoutVersion is the data directory layout version.
+	LayoutVersion uint64 `json:"layout_version"`
+
+	// Entries are the known top-level entries present in the data directory.
+	Entries map[string]*ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry is a data directory manifest entry.
+type ManifestEntry struct {
+	// Owner is the name of the component owning the entry.
+	Owner string `json:"owner"`
+
+	// Dir is true iff the entry is a directory.
+	Dir bool `json:"dir,omitempty"`
+
+	// ContentHash is the hash of the entry content in case it is tracked.
+	ContentHash *hash.Hash `json:"content_hash,omitempty"`
+}
+
+// VerifyResult is the result of data directory verification.
+type VerifyResult struct {
+	// LayoutVersion is the data directory layout version.
+	LayoutVersion uint64 `json:"layout_version"`
+
+	// Unknown are entries that are not known to any node component. They are not managed by the
+	// node and should be reviewed before being deleted.
+	Unknown []string `json:"unknown,omitempty"`
+
+	// Stale are entries that are no longer used by the node and are safe to delete.
+	Stale []string `json:"stale,omitempty"`
+
+	// Missing are entries recorded in the manifest that no longer exist.
+	Missing []string `json:"missing,omitempty"`
+
+	// Modified are entries whose content no longer matches the content hash in the manifest.
+	Modified []string `json:"modified,omitempty"`
+}
+
+// IsClean returns true iff verification did not find any problems.
+func (r *VerifyResult) IsClean() bool {
+	return len(r.Unknown) == 0 && len(r.Stale) == 0 && len(r.Missing) == 0 && len(r.Modified) == 0
+}
+
+// DataDir is a node data directory.
+type DataDir struct {
+	mu sync.Mutex
+
+	path     string
+	layout   *Layout
+	manifest *Manifest
+
+	logger *logging.Logger
+}
+
+// Open opens the data directory at the given path, recovers any interrupted layout migration,
+// applies all pending migrations of the given layout and updates the manifest.
+func Open(path string, layout *Layout) (*DataDir, error) {
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+
+	d := &DataDir{
+		path:   path,
+		layout: layout,
+		logger: logging.GetLogger("common/datadir"),
+	}
+
+	manifest, err := loadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		// Data directories without a manifest predate layout versioning.
+		manifest = &Manifest{
+			SchemaVersion: ManifestSchemaVersion,
+		}
+	}
+	if manifest.LayoutVersion > layout.Version {
+		return nil, fmt.Errorf("datadir: layout version %d is newer than the supported version %d",
+			manifest.LayoutVersion, layout.Version,
+		)
+	}
+	d.manifest = manifest
+
+	if err = d.recoverMigration(); err != nil {
+		return nil, err
+	}
+	for i := range layout.Migrations {
+		m := &layout.Migrations[i]
+		if m.Version <= d.manifest.LayoutVersion {
+			continue
+		}
+		if err = d.migrate(m); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = d.Refresh(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Path returns the path of the data directory.
+func (d *DataDir) Path() string {
+	return d.path
+}
+
+// Manifest returns a copy of the current manifest.
+func (d *DataDir) Manifest() *Manifest {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	manifest := &Manifest{
+		SchemaVersion: d.manifest.SchemaVersion,
+		LayoutVersion: d.manifest.LayoutVersion,
+		Entries:       make(map[string]*ManifestEntry, len(d.manifest.Entries)),
+	}
+	for path, entry := range d.manifest.Entries {
+		e := *entry
+		manifest.Entries[path] = &e
+	}
+	return manifest
+}
+
+// Refresh updates the manifest from the current data directory content.
+//
+// Content hashes of entries already in the manifest are retained so that modifications can be
+// detected by Verify.
+func (d *DataDir) Refresh() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries := make(map[string]*ManifestEntry)
+	for _, spec := range d.layout.Entries {
+		fi, err := os.Lstat(filepath.Join(d.path, spec.Path))
+		switch {
+		case err == nil:
+		case errors.Is(err, os.ErrNotExist):
+			continue
+		default:
+			return fmt.Errorf("datadir: failed to stat '%s': %w", spec.Path, err)
+		}
+
+		entry := &ManifestEntry{
+			Owner: spec.Owner,
+			Dir:   fi.IsDir(),
+		}
+		if spec.Hashed && fi.Mode().IsRegular() {
+			if old, ok := d.manifest.Entries[spec.Path]; ok && old.ContentHash != nil {
+				entry.ContentHash = old.ContentHash
+			} else if entry.ContentHash, err = d.contentHash(spec.Path); err != nil {
+				return err
+			}
+		}
+		entries[spec.Path] = entry
+	}
+
+	d.manifest.SchemaVersion = ManifestSchemaVersion
+	d.manifest.Entries = entries
+	return d.persistManifestLocked()
+}
+
+// Verify verifies the data directory against the layout and the manifest.
+func (d *DataDir) Verify() (*VerifyResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := &VerifyResult{
+		LayoutVersion: d.manifest.LayoutVersion,
+	}
+
+	obsolete := make(map[string]struct{})
+	for _, m := range d.layout.Migrations {
+		for _, p := range m.Obsolete {
+			obsolete[p] = struct{}{}
+		}
+	}
+
+	dirEntries, err := os.ReadDir(d.path)
+	if err != nil {
+		return nil, fmt.Errorf("datadir: failed to read data directory: %w", err)
+	}
+	present := make(map[string]struct{}, len(dirEntries))
+	for _, de := range dirEntries {
+		name := de.Name()
+		present[name] = struct{}{}
+
+		_, isObsolete := obsolete[name]
+		switch {
+		case d.layout.entry(name) != nil:
+		case isObsolete, strings.HasSuffix(name, tmpSuffix):
+			result.Stale = append(result.Stale, name)
+		default:
+			result.Unknown = append(result.Unknown, name)
+		}
+	}
+	// Obsolete paths may also be nested within known entries.
+	for p := range obsolete {
+		if !strings.ContainsRune(p, filepath.Separator) {
+			continue
+		}
+		if _, err = os.Lstat(filepath.Join(d.path, p)); err == nil {
+			result.Stale = append(result.Stale, p)
+		}
+	}
+
+	for path, entry := range d.manifest.Entries {
+		if _, ok := present[path]; !ok {
+			result.Missing = append(result.Missing, path)
+			continue
+		}
+		if entry.ContentHash == nil {
+			continue
+		}
+		h, err := d.contentHash(path)
+		if err != nil {
+			return nil, err
+		}
+		if !h.Equal(entry.ContentHash) {
+			result.Modified = append(result.Modified, path)
+		}
+	}
+
+	sort.Strings(result.Unknown)
+	sort.Strings(result.Stale)
+	sort.Strings(result.Missing)
+	sort.Strings(result.Modified)
+
+	return result, nil
+}
+
+func (d *DataDir) contentHash(path string) (*hash.Hash, error) {
+	data, err := os.ReadFile(filepath.Join(d.path, path))
+	if err != nil {
+		return nil, fmt.Errorf("datadir: failed to hash '%s': %w", path, err)
+	}
+	h := hash.NewFromBytes(data)
+	return &h, nil
+}
+
+func (d *DataDir) persistManifestLocked() error {
+	data, err := json.MarshalIndent(d.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("datadir: failed to marshal manifest: %w", err)
+	}
+	if err = writeFileAtomic(filepath.Join(d.path, ManifestFilename), data); err != nil {
+		return fmt.Errorf("datadir: failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+func loadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(path, ManifestFilename))
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("datadir: failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("datadir: malformed manifest: %w", err)
+	}
+	if manifest.SchemaVersion > ManifestSchemaVersion {
+		return nil, fmt.Errorf("datadir: unsupported manifest schema version %d", manifest.SchemaVersion)
+	}
+	return &manifest, nil
+}
+
+// writeFileAtomic writes the given file so that either the old or the new content is present
+// after a crash.
+func writeFileAtomic(fn string, data []byte) error {
+	tmpFn := fn + tmpSuffix
+	f, err := os.OpenFile(tmpFn, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmpFn, fn); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(fn))
+}
+
+func syncDir(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return f.Sync()
+}
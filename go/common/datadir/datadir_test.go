@@ -0,0 +1,135 @@
+package datadir
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayoutMigration(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	writeFile := func(path, content string) {
+		fn := filepath.Join(dir, path)
+		require.NoError(os.MkdirAll(filepath.Dir(fn), 0o700))
+		require.NoError(os.WriteFile(fn, []byte(content), 0o600))
+	}
+	requireExists := func(path string, expected bool) {
+		ok, err := exists(filepath.Join(dir, path))
+		require.NoError(err)
+		require.Equal(expected, ok, "existence of '%s'", path)
+	}
+
+	layoutV1 := &Layout{
+		Version: 1,
+		Entries: []EntrySpec{
+			{Path: "identity.pem", Owner: "identity", Hashed: true},
+			{Path: "tendermint", Owner: "consensus"},
+			{Path: "bundles", Owner: "runtime"},
+			{Path: ManifestFilename, Owner: "datadir"},
+			{Path: JournalFilename, Owner: "datadir"},
+		},
+		Migrations: []Migration{
+			{Version: 1, Name: "initial"},
+		},
+	}
+	layoutV2 := &Layout{
+		Version: 2,
+		Entries: []EntrySpec{
+			{Path: "identity.pem", Owner: "identity", Hashed: true},
+			{Path: "consensus", Owner: "consensus"},
+			{Path: "runtimes", Owner: "runtime"},
+			{Path: ManifestFilename, Owner: "datadir"},
+			{Path: JournalFilename, Owner: "datadir"},
+		},
+		Migrations: []Migration{
+			{Version: 1, Name: "initial"},
+			{
+				Version: 2,
+				Name:    "unified-state-dirs",
+				Renames: []Rename{
+					{From: "tendermint", To: "consensus"},
+					{From: "bundles", To: filepath.Join("runtimes", "bundles")},
+				},
+				Obsolete: []string{"bundles.old"},
+			},
+		},
+	}
+
+	// Create a data directory with the old layout.
+	writeFile("identity.pem", "identity")
+	writeFile(filepath.Join("tendermint", "state.db"), "consensus state")
+	writeFile(filepath.Join("bundles", "runtime.orc"), "bundle")
+
+	d, err := Open(dir, layoutV1)
+	require.NoError(err, "Open(layoutV1)")
+	manifest := d.Manifest()
+	require.EqualValues(1, manifest.LayoutVersion)
+	require.Equal("consensus", manifest.Entries["tendermint"].Owner)
+	require.True(manifest.Entries["tendermint"].Dir)
+	require.NotNil(manifest.Entries["identity.pem"].ContentHash)
+
+	// Crash after the first rename.
+	errCrash := errors.New("crash")
+	testHookAfterRename = func(Rename) error {
+		return errCrash
+	}
+	defer func() {
+		testHookAfterRename = nil
+	}()
+
+	_, err = Open(dir, layoutV2)
+	require.ErrorIs(err, errCrash, "Open(layoutV2) should crash")
+	requireExists("consensus", true)
+	requireExists("bundles", true)
+	requireExists(JournalFilename, true)
+
+	loaded, err := loadManifest(dir)
+	require.NoError(err, "loadManifest")
+	require.EqualValues(1, loaded.LayoutVersion, "layout version should not be updated")
+
+	// Opening with the old layout should refuse to proceed with an unknown migration.
+	_, err = Open(dir, layoutV1)
+	require.Error(err, "Open(layoutV1) should fail with an interrupted migration")
+
+	// Recover.
+	testHookAfterRename = nil
+	d, err = Open(dir, layoutV2)
+	require.NoError(err, "Open(layoutV2)")
+	requireExists("tendermint", false)
+	requireExists(filepath.Join("consensus", "state.db"), true)
+	requireExists("bundles", false)
+	requireExists(filepath.Join("runtimes", "bundles", "runtime.orc"), true)
+	requireExists(JournalFilename, false)
+
+	manifest = d.Manifest()
+	require.EqualValues(2, manifest.LayoutVersion)
+	require.Contains(manifest.Entries, "consensus")
+	require.Contains(manifest.Entries, "runtimes")
+	require.NotContains(manifest.Entries, "tendermint")
+
+	result, err := d.Verify()
+	require.NoError(err, "Verify")
+	require.True(result.IsClean(), "data directory should be clean after migration")
+
+	// Downgrades are not supported.
+	_, err = Open(dir, layoutV1)
+	require.Error(err, "Open(layoutV1) should fail after upgrade")
+
+	// Stale, unknown, missing and modified entries should be reported.
+	writeFile("bundles.old", "old bundle")
+	writeFile("node.log", "log")
+	writeFile("identity.pem", "modified identity")
+	require.NoError(os.RemoveAll(filepath.Join(dir, "consensus")))
+
+	result, err = d.Verify()
+	require.NoError(err, "Verify")
+	require.Equal([]string{"bundles.old"}, result.Stale)
+	require.Equal([]string{"node.log"}, result.Unknown)
+	require.Equal([]string{"consensus"}, result.Missing)
+	require.Equal([]string{"identity.pem"}, result.Modified)
+}
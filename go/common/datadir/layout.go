@@ -0,0 +1,167 @@
+package datadir
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// EntrySpec describes a top-level data directory entry owned by a node component.
+type EntrySpec struct {
+	// Path is the path of the entry relative to the data directory.
+	Path string
+
+	// Owner is the name of the component owning the entry.
+	Owner string
+
+	// Hashed specifies whether the content hash of the entry should be tracked in the manifest.
+	//
+	// This should only be enabled for small regular files that are not expected to change (e.g.,
+	// identity keys).
+	Hashed bool
+}
+
+// Rename is a directory or file move performed by a layout migration.
+type Rename struct {
+	// From is the old path relative to the data directory.
+	From string
+
+	// To is the new path relative to the data directory.
+	To string
+}
+
+// Migration is a data directory layout migration.
+type Migration struct {
+	// Version is the layout version after the migration has been applied.
+	Version uint64
+
+	// Name is a short descriptive name of the migration.
+	Name string
+
+	// Renames are the moves performed by the migration, in order. Moves of non-existent paths are
+	// skipped so that migrations are idempotent and can be safely replayed after a crash.
+	Renames []Rename
+
+	// Obsolete are paths relative to the data directory that are no longer used as of this
+	// layout version and are safe to delete.
+	Obsolete []string
+}
+
+// Layout is a data directory layout.
+type Layout struct {
+	// Version is the layout version.
+	Version uint64
+
+	// Entries are the entries of the layout.
+	Entries []EntrySpec
+
+	// Migrations are the migrations from earlier layout versions, ordered by version.
+	Migrations []Migration
+}
+
+// Validate validates the layout.
+func (l *Layout) Validate() error {
+	entries := make(map[string]struct{})
+	for _, e := range l.Entries {
+		if err := validatePath(e.Path); err != nil {
+			return err
+		}
+		if strings.ContainsRune(e.Path, filepath.Separator) {
+			return fmt.Errorf("datadir: entry '%s' is not a top-level entry", e.Path)
+		}
+		if e.Owner == "" {
+			return fmt.Errorf("datadir: entry '%s' has no owner", e.Path)
+		}
+		if _, ok := entries[e.Path]; ok {
+			return fmt.Errorf("datadir: duplicate entry '%s'", e.Path)
+		}
+		entries[e.Path] = struct{}{}
+	}
+
+	var version uint64
+	for _, m := range l.Migrations {
+		if m.Version <= version {
+			return fmt.Errorf("datadir: migration '%s' out of order (version: %d)", m.Name, m.Version)
+		}
+		if m.Version > l.Version {
+			return fmt.Errorf("datadir: migration '%s' targets unsupported version %d", m.Name, m.Version)
+		}
+		version = m.Version
+
+		for _, r := range m.Renames {
+			if err := validatePath(r.From); err != nil {
+				return err
+			}
+			if err := validatePath(r.To); err != nil {
+				return err
+			}
+		}
+		for _, p := range m.Obsolete {
+			if err := validatePath(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Layout) entry(path string) *EntrySpec {
+	for i := range l.Entries {
+		if l.Entries[i].Path == path {
+			return &l.Entries[i]
+		}
+	}
+	return nil
+}
+
+func (l *Layout) migration(version uint64) *Migration {
+	for i := range l.Migrations {
+		if l.Migrations[i].Version == version {
+			return &l.Migrations[i]
+		}
+	}
+	return nil
+}
+
+func validatePath(path string) error {
+	if path == "" || filepath.IsAbs(path) || filepath.Clean(path) != path || strings.HasPrefix(path, "..") {
+		return fmt.Errorf("datadir: malformed path '%s'", path)
+	}
+	return nil
+}
+
+// DefaultLayout is the current node data directory layout.
+var DefaultLayout = &Layout{
+	Version: 1,
+	Entries: []EntrySpec{
+		// Node identity.
+		{Path: "identity.pem", Owner: "identity", Hashed: true},
+		{Path: "identity_pub.pem", Owner: "identity", Hashed: true},
+		{Path: "p2p.pem", Owner: "identity", Hashed: true},
+		{Path: "consensus.pem", Owner: "identity", Hashed: true},
+		{Path: "consensus_pub.pem", Owner: "identity", Hashed: true},
+		{Path: "tls_identity.pem", Owner: "identity"},
+		{Path: "tls_identity_cert.pem", Owner: "identity"},
+		{Path: "sentry_client_tls_identity.pem", Owner: "identity"},
+		{Path: "sentry_client_tls_identity_cert.pem", Owner: "identity"},
+		// Consensus and runtime state.
+		{Path: "consensus", Owner: "consensus"},
+		{Path: "runtimes", Owner: "runtime"},
+		{Path: "persistent-store.badger.db", Owner: "persistent"},
+		// Node control.
+		{Path: "internal.sock", Owner: "control"},
+		{Path: "shutdown-reason.json", Owner: "control"},
+		{Path: "bundle-uploads", Owner: "control"},
+		// Data directory management.
+		{Path: ManifestFilename, Owner: "datadir"},
+		{Path: JournalFilename, Owner: "datadir"},
+	},
+	Migrations: []Migration{
+		{
+			Version: 1,
+			Name:    "initial",
+			// Consensus state directory used before the switch to CometBFT.
+			Obsolete: []string{"tendermint"},
+		},
+	},
+}
@@ -0,0 +1,152 @@
+package datadir
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// journal is the migration journal written before a migration is started and removed after the
+// new layout version has been recorded in the manifest.
+type journal struct {
+	// Version is the layout version after the migration.
+	Version uint64 `json:"version"`
+
+	// Name is the name of the migration.
+	Name string `json:"name"`
+}
+
+// testHookAfterRename is called after each rename performed by a migration. It is used in tests
+// to simulate a crash in the middle of a migration.
+var testHookAfterRename func(r Rename) error
+
+// recoverMigration completes a migration that was interrupted by a crash.
+func (d *DataDir) recoverMigration() error {
+	data, err := os.ReadFile(filepath.Join(d.path, JournalFilename))
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	default:
+		return fmt.Errorf("datadir: failed to read migration journal: %w", err)
+	}
+
+	var j journal
+	if err = json.Unmarshal(data, &j); err != nil {
+		return fmt.Errorf("datadir: malformed migration journal: %w", err)
+	}
+
+	if j.Version <= d.manifest.LayoutVersion {
+		// The migration completed, but the journal has not been removed.
+		return d.removeJournal()
+	}
+
+	m := d.layout.migration(j.Version)
+	if m == nil {
+		return fmt.Errorf("datadir: interrupted migration '%s' (version: %d) is not supported", j.Name, j.Version)
+	}
+
+	d.logger.Warn("resuming interrupted data directory migration",
+		"migration", m.Name,
+		"version", m.Version,
+	)
+
+	return d.migrate(m)
+}
+
+// migrate applies the given migration.
+//
+// Renames are replayed from the start in case of a crash as renames of paths that were already
+// moved are skipped.
+func (d *DataDir) migrate(m *Migration) error {
+	d.logger.Info("migrating data directory layout",
+		"migration", m.Name,
+		"from", d.manifest.LayoutVersion,
+		"to", m.Version,
+	)
+
+	data, err := json.Marshal(&journal{
+		Version: m.Version,
+		Name:    m.Name,
+	})
+	if err != nil {
+		return fmt.Errorf("datadir: failed to marshal migration journal: %w", err)
+	}
+	if err = writeFileAtomic(filepath.Join(d.path, JournalFilename), data); err != nil {
+		return fmt.Errorf("datadir: failed to write migration journal: %w", err)
+	}
+
+	for _, r := range m.Renames {
+		if err = d.rename(r); err != nil {
+			return fmt.Errorf("datadir: migration '%s' failed: %w", m.Name, err)
+		}
+		if testHookAfterRename != nil {
+			if err = testHookAfterRename(r); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.mu.Lock()
+	d.manifest.LayoutVersion = m.Version
+	err = d.persistManifestLocked()
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return d.removeJournal()
+}
+
+func (d *DataDir) rename(r Rename) error {
+	from := filepath.Join(d.path, r.From)
+	to := filepath.Join(d.path, r.To)
+
+	fromExists, err := exists(from)
+	if err != nil {
+		return err
+	}
+	if !fromExists {
+		// Nothing to move or already moved before a crash.
+		return nil
+	}
+	toExists, err := exists(to)
+	if err != nil {
+		return err
+	}
+	if toExists {
+		return fmt.Errorf("cannot move '%s': destination '%s' already exists", r.From, r.To)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(to), 0o700); err != nil {
+		return err
+	}
+	if err = os.Rename(from, to); err != nil {
+		return err
+	}
+	if err = syncDir(filepath.Dir(to)); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(from))
+}
+
+func (d *DataDir) removeJournal() error {
+	if err := os.Remove(filepath.Join(d.path, JournalFilename)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("datadir: failed to remove migration journal: %w", err)
+	}
+	return nil
+}
+
+func exists(path string) (bool, error) {
+	_, err := os.Lstat(path)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, os.ErrNotExist):
+		return false, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,261 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+)
+
+const (
+	// DefaultSlowCallLatency is the default handler latency above which calls are logged.
+	DefaultSlowCallLatency = 5 * time.Second
+
+	// DefaultSlowCallSize is the default message size (in bytes) above which calls are logged.
+	DefaultSlowCallSize = 4 * 1024 * 1024
+
+	// unknownMethod is the method label used for calls to methods that are not implemented.
+	unknownMethod = "unknown"
+)
+
+var (
+	callDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "oasis_grpc_server_call_duration_seconds",
+			Help:    "gRPC call duration by method.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method"},
+	)
+	callMessageSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "oasis_grpc_server_message_size_bytes",
+			Help:    "gRPC message size by method and direction.",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 10),
+		},
+		[]string{"method", "direction"},
+	)
+	callMessages = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_grpc_server_messages",
+			Help: "Number of gRPC messages by method and direction.",
+		},
+		[]string{"method", "direction"},
+	)
+	slowCalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_grpc_server_slow_calls",
+			Help: "Number of gRPC calls exceeding the slow call thresholds by method.",
+		},
+		[]string{"method"},
+	)
+
+	callCollectors = []prometheus.Collector{
+		callDuration,
+		callMessageSize,
+		callMessages,
+		slowCalls,
+	}
+
+	callMetricsOnce sync.Once
+
+	_ stats.Handler = (*CallMonitor)(nil)
+)
+
+// SlowCallThresholds are the thresholds above which calls are logged as slow.
+type SlowCallThresholds struct {
+	// Latency is the call duration above which the call is logged. Zero disables the check.
+	Latency time.Duration `json:"latency"`
+
+	// Size is the size of any single request or response message (in bytes) above which the
+	// call is logged. Zero disables the check.
+	Size uint64 `json:"size"`
+}
+
+// SlowCallInfo is information about a call that exceeded the slow call thresholds.
+type SlowCallInfo struct {
+	// Method is the full method name.
+	Method string
+
+	// Duration is the duration of the call.
+	Duration time.Duration
+
+	// BytesIn is the number of payload bytes received during the call.
+	BytesIn uint64
+
+	// BytesOut is the number of payload bytes sent during the call.
+	BytesOut uint64
+
+	// MessagesIn is the number of messages received during the call.
+	MessagesIn uint64
+
+	// MessagesOut is the number of messages sent during the call.
+	MessagesOut uint64
+
+	// PeerAddress is the address of the remote peer.
+	PeerAddress string
+
+	// TLSPublicKey is the authenticated TLS public key of the peer (if any).
+	TLSPublicKey *signature.PublicKey
+
+	// Err is the error returned by the call (if any).
+	Err error
+}
+
+type callCtxKey struct{}
+
+type call struct {
+	method  string
+	startAt time.Time
+
+	bytesIn     atomic.Uint64
+	bytesOut    atomic.Uint64
+	messagesIn  atomic.Uint64
+	messagesOut atomic.Uint64
+	oversized   atomic.Bool
+}
+
+// CallMonitor is a gRPC stats handler that records per-method call durations and message sizes
+// and logs calls exceeding the configured slow call thresholds.
+//
+// Streaming calls record the size of each message and the number of messages in each direction.
+type CallMonitor struct {
+	thresholds atomic.Pointer[SlowCallThresholds]
+
+	logger     *logging.Logger
+	slowCallFn func(*SlowCallInfo)
+	nowFn      func() time.Time
+}
+
+// NewCallMonitor creates a new call monitor with the default slow call thresholds.
+func NewCallMonitor() *CallMonitor {
+	callMetricsOnce.Do(func() {
+		prometheus.MustRegister(callCollectors...)
+	})
+
+	m := &CallMonitor{
+		logger: logging.GetLogger("common/grpc/calls"),
+		nowFn:  time.Now,
+	}
+	m.slowCallFn = m.logSlowCall
+	m.SetSlowCallThresholds(&SlowCallThresholds{
+		Latency: DefaultSlowCallLatency,
+		Size:    DefaultSlowCallSize,
+	})
+	return m
+}
+
+// SlowCallThresholds returns the current slow call thresholds.
+func (m *CallMonitor) SlowCallThresholds() *SlowCallThresholds {
+	thresholds := *m.thresholds.Load()
+	return &thresholds
+}
+
+// SetSlowCallThresholds updates the slow call thresholds.
+func (m *CallMonitor) SetSlowCallThresholds(thresholds *SlowCallThresholds) {
+	t := *thresholds
+	m.thresholds.Store(&t)
+}
+
+func (m *CallMonitor) logSlowCall(info *SlowCallInfo) {
+	m.logger.Warn("slow gRPC call",
+		"method", info.Method,
+		"duration", info.Duration,
+		"bytes_in", info.BytesIn,
+		"bytes_out", info.BytesOut,
+		"messages_in", info.MessagesIn,
+		"messages_out", info.MessagesOut,
+		"peer_address", info.PeerAddress,
+		"peer_tls_public_key", info.TLSPublicKey,
+		"err", info.Err,
+	)
+}
+
+// TagConn implements stats.Handler.
+func (m *CallMonitor) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+// HandleConn implements stats.Handler.
+func (m *CallMonitor) HandleConn(context.Context, stats.ConnStats) {
+}
+
+// TagRPC implements stats.Handler.
+func (m *CallMonitor) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, callCtxKey{}, &call{
+		method:  info.FullMethodName,
+		startAt: m.nowFn(),
+	})
+}
+
+// HandleRPC implements stats.Handler.
+func (m *CallMonitor) HandleRPC(ctx context.Context, rs stats.RPCStats) {
+	c, ok := ctx.Value(callCtxKey{}).(*call)
+	if !ok {
+		return
+	}
+
+	switch st := rs.(type) {
+	case *stats.InPayload:
+		m.recordMessage(c, "in", st.WireLength, &c.bytesIn, &c.messagesIn)
+	case *stats.OutPayload:
+		m.recordMessage(c, "out", st.WireLength, &c.bytesOut, &c.messagesOut)
+	case *stats.End:
+		m.recordEnd(ctx, c, st)
+	}
+}
+
+func (m *CallMonitor) recordMessage(c *call, direction string, size int, bytes, messages *atomic.Uint64) {
+	labels := prometheus.Labels{"method": c.method, "direction": direction}
+	callMessageSize.With(labels).Observe(float64(size))
+	callMessages.With(labels).Inc()
+
+	bytes.Add(uint64(size)) //nolint:gosec
+	messages.Add(1)
+
+	if limit := m.thresholds.Load().Size; limit > 0 && uint64(size) > limit { //nolint:gosec
+		c.oversized.Store(true)
+	}
+}
+
+func (m *CallMonitor) recordEnd(ctx context.Context, c *call, st *stats.End) {
+	duration := st.EndTime.Sub(c.startAt)
+
+	// Avoid unbounded label cardinality due to clients calling arbitrary methods.
+	method := c.method
+	if status.Code(st.Error) == codes.Unimplemented {
+		method = unknownMethod
+	}
+	callDuration.With(prometheus.Labels{"method": method}).Observe(duration.Seconds())
+
+	thresholds := m.thresholds.Load()
+	slow := thresholds.Latency > 0 && duration > thresholds.Latency
+	if !slow && !c.oversized.Load() {
+		return
+	}
+	slowCalls.With(prometheus.Labels{"method": method}).Inc()
+
+	info := &SlowCallInfo{
+		Method:       c.method,
+		Duration:     duration,
+		BytesIn:      c.bytesIn.Load(),
+		BytesOut:     c.bytesOut.Load(),
+		MessagesIn:   c.messagesIn.Load(),
+		MessagesOut:  c.messagesOut.Load(),
+		TLSPublicKey: peerTLSPublicKey(ctx),
+		Err:          st.Error,
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		info.PeerAddress = p.Addr.String()
+	}
+	m.slowCallFn(info)
+}
@@ -0,0 +1,132 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	testCallsService = "oasis-core.TestCalls"
+	testCallsEcho    = "/" + testCallsService + "/Echo"
+	testCallsSleep   = "/" + testCallsService + "/Sleep"
+)
+
+func testCallsHandler(delay time.Duration) grpc.MethodHandler {
+	return func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+		var msg []byte
+		if err := dec(&msg); err != nil {
+			return nil, err
+		}
+		time.Sleep(delay)
+		return &msg, nil
+	}
+}
+
+func TestCallMonitor(t *testing.T) {
+	require := require.New(t)
+
+	monitor := NewCallMonitor()
+	slowCallCh := make(chan *SlowCallInfo, 10)
+	monitor.slowCallFn = func(info *SlowCallInfo) {
+		slowCallCh <- info
+	}
+	monitor.SetSlowCallThresholds(&SlowCallThresholds{
+		Latency: 200 * time.Millisecond,
+		Size:    1024,
+	})
+	require.Equal(&SlowCallThresholds{Latency: 200 * time.Millisecond, Size: 1024}, monitor.SlowCallThresholds())
+
+	server := grpc.NewServer(
+		grpc.StatsHandler(monitor),
+		grpc.ForceServerCodec(testBytesCodec{}),
+	)
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: testCallsService,
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{
+			{MethodName: "Echo", Handler: testCallsHandler(0)},
+			{MethodName: "Sleep", Handler: testCallsHandler(300 * time.Millisecond)},
+		},
+	}, nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err, "Listen")
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///"+listener.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(testBytesCodec{})),
+	)
+	require.NoError(err, "NewClient")
+	defer conn.Close()
+
+	invoke := func(method string, size int) {
+		msg := make([]byte, size)
+		var rsp []byte
+		require.NoError(conn.Invoke(ctx, method, &msg, &rsp), "Invoke")
+		require.Equal(msg, rsp)
+	}
+	noSlowCall := func() {
+		select {
+		case info := <-slowCallCh:
+			require.Fail("unexpected slow call", "method: %s", info.Method)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	waitSlowCall := func() *SlowCallInfo {
+		select {
+		case info := <-slowCallCh:
+			return info
+		case <-ctx.Done():
+			require.FailNow("slow call not reported")
+			return nil
+		}
+	}
+
+	// Calls below the thresholds should only be recorded.
+	invoke(testCallsEcho, 16)
+	noSlowCall()
+
+	inLabels := prometheus.Labels{"method": testCallsEcho, "direction": "in"}
+	outLabels := prometheus.Labels{"method": testCallsEcho, "direction": "out"}
+	require.EqualValues(1, testutil.ToFloat64(callMessages.With(inLabels)))
+	require.EqualValues(1, testutil.ToFloat64(callMessages.With(outLabels)))
+	require.NotZero(testutil.CollectAndCount(callMessageSize), "message size metrics should exist")
+	require.NotZero(testutil.CollectAndCount(callDuration), "call duration metrics should exist")
+
+	// Calls with oversized messages should be reported.
+	invoke(testCallsEcho, 2048)
+	info := waitSlowCall()
+	require.Equal(testCallsEcho, info.Method)
+	require.EqualValues(1, info.MessagesIn)
+	require.EqualValues(1, info.MessagesOut)
+	require.GreaterOrEqual(info.BytesIn, uint64(2048))
+	require.NotEmpty(info.PeerAddress)
+	require.Nil(info.TLSPublicKey)
+	require.NoError(info.Err)
+
+	// Calls exceeding the latency threshold should be reported.
+	invoke(testCallsSleep, 16)
+	info = waitSlowCall()
+	require.Equal(testCallsSleep, info.Method)
+	require.Greater(info.Duration, 200*time.Millisecond)
+	require.EqualValues(1, testutil.ToFloat64(slowCalls.With(prometheus.Labels{"method": testCallsSleep})))
+
+	// Thresholds should be adjustable at runtime.
+	monitor.SetSlowCallThresholds(&SlowCallThresholds{})
+	invoke(testCallsSleep, 2048)
+	noSlowCall()
+}
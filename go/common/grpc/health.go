@@ -0,0 +1,40 @@
+package grpc
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// HealthServer is a server implementing the standard gRPC health checking protocol
+// (grpc.health.v1.Health), so that load balancers can probe the node without decoding any node
+// specific responses.
+//
+// The overall health is reported under the empty service name, while the health of individual
+// components is reported under their own service names. Everything is reported as not serving
+// until marked otherwise.
+type HealthServer struct {
+	*health.Server
+}
+
+// NewHealthServer creates a new health server.
+func NewHealthServer() *HealthServer {
+	srv := health.NewServer()
+	srv.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return &HealthServer{srv}
+}
+
+// SetServing updates whether the given service is serving and notifies all watchers in case
+// it has changed.
+func (h *HealthServer) SetServing(service string, serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	h.SetServingStatus(service, status)
+}
+
+// Register registers the health service with the given gRPC server.
+func (h *HealthServer) Register(server *grpc.Server) {
+	healthpb.RegisterHealthServer(server, h.Server)
+}
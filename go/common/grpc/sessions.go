@@ -0,0 +1,344 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/stats"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+var (
+	// ErrSessionNotFound is the error returned when a session with the given identifier does
+	// not exist.
+	ErrSessionNotFound = errors.New("grpc: session not found")
+
+	// ErrSessionNotClosable is the error returned when a session cannot be forcibly closed as
+	// its connection has not been accepted via a tracked listener.
+	ErrSessionNotClosable = errors.New("grpc: session not closable")
+
+	_ stats.Handler = (*SessionTracker)(nil)
+)
+
+// StreamInfo is information about an active stream (or unary call) of a session.
+type StreamInfo struct {
+	// Method is the full method name.
+	Method string `json:"method"`
+
+	// StartedAt is the time at which the stream has been started.
+	StartedAt time.Time `json:"started_at"`
+}
+
+// SessionInfo is information about an active gRPC connection.
+type SessionInfo struct {
+	// ID is the session identifier.
+	ID uint64 `json:"id"`
+
+	// Listener is the name of the listener that accepted the connection.
+	Listener string `json:"listener"`
+
+	// PeerAddress is the address of the remote peer.
+	PeerAddress string `json:"peer_address"`
+
+	// TLSPublicKey is the authenticated TLS public key of the peer (if any).
+	TLSPublicKey *signature.PublicKey `json:"tls_public_key,omitempty"`
+
+	// ConnectedAt is the time at which the connection has been established.
+	ConnectedAt time.Time `json:"connected_at"`
+
+	// Streams are the active streams.
+	Streams []StreamInfo `json:"streams,omitempty"`
+
+	// BytesIn is the number of payload bytes received over the connection.
+	BytesIn uint64 `json:"bytes_in"`
+
+	// BytesOut is the number of payload bytes sent over the connection.
+	BytesOut uint64 `json:"bytes_out"`
+
+	// MessagesIn is the number of messages received over the connection.
+	MessagesIn uint64 `json:"messages_in"`
+
+	// MessagesOut is the number of messages sent over the connection.
+	MessagesOut uint64 `json:"messages_out"`
+}
+
+type sessionCtxKey struct{}
+
+type streamCtxKey struct{}
+
+type session struct {
+	info    SessionInfo
+	streams map[uint64]*StreamInfo
+	conn    net.Conn
+}
+
+// trackedAddr is the remote address of a connection accepted via a tracked listener. It carries
+// the session identifier so that connections can be matched to sessions even when the remote
+// address itself is not unique (e.g., for UNIX sockets).
+type trackedAddr struct {
+	net.Addr
+
+	id uint64
+}
+
+type trackedConn struct {
+	net.Conn
+
+	addr    *trackedAddr
+	tracker *SessionTracker
+}
+
+func (c *trackedConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func (c *trackedConn) Close() error {
+	c.tracker.removeSession(c.addr.id)
+	return c.Conn.Close()
+}
+
+type trackedListener struct {
+	net.Listener
+
+	tracker *SessionTracker
+}
+
+func (l *trackedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	t := l.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := t.allocIDLocked()
+	tc := &trackedConn{
+		Conn:    conn,
+		addr:    &trackedAddr{Addr: conn.RemoteAddr(), id: id},
+		tracker: t,
+	}
+	t.sessions[id] = t.newSessionLocked(id, tc.addr.Addr, tc)
+	return tc, nil
+}
+
+// SessionTracker is a gRPC stats handler that tracks active connections and their streams.
+type SessionTracker struct {
+	mu sync.Mutex
+
+	listener     string
+	lastID       uint64
+	lastStreamID uint64
+	sessions     map[uint64]*session
+
+	nowFn func() time.Time
+}
+
+// NewSessionTracker creates a new session tracker for the listener with the given name.
+func NewSessionTracker(listener string) *SessionTracker {
+	return &SessionTracker{
+		listener: listener,
+		sessions: make(map[uint64]*session),
+		nowFn:    time.Now,
+	}
+}
+
+// WrapListener wraps the given listener so that accepted connections can be forcibly closed.
+func (t *SessionTracker) WrapListener(l net.Listener) net.Listener {
+	return &trackedListener{
+		Listener: l,
+		tracker:  t,
+	}
+}
+
+func (t *SessionTracker) allocIDLocked() uint64 {
+	t.lastID++
+	return t.lastID
+}
+
+func (t *SessionTracker) newSessionLocked(id uint64, addr net.Addr, conn net.Conn) *session {
+	var peerAddr string
+	if addr != nil {
+		peerAddr = addr.String()
+	}
+	return &session{
+		info: SessionInfo{
+			ID:          id,
+			Listener:    t.listener,
+			PeerAddress: peerAddr,
+			ConnectedAt: t.nowFn(),
+		},
+		streams: make(map[uint64]*StreamInfo),
+		conn:    conn,
+	}
+}
+
+func (t *SessionTracker) removeSession(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.sessions, id)
+}
+
+// Sessions returns information about all active sessions, ordered by session identifier.
+func (t *SessionTracker) Sessions() []*SessionInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sessions := make([]*SessionInfo, 0, len(t.sessions))
+	for _, s := range t.sessions {
+		info := s.info
+		info.Streams = make([]StreamInfo, 0, len(s.streams))
+		for _, st := range s.streams {
+			info.Streams = append(info.Streams, *st)
+		}
+		sort.Slice(info.Streams, func(i, j int) bool {
+			if !info.Streams[i].StartedAt.Equal(info.Streams[j].StartedAt) {
+				return info.Streams[i].StartedAt.Before(info.Streams[j].StartedAt)
+			}
+			return info.Streams[i].Method < info.Streams[j].Method
+		})
+		sessions = append(sessions, &info)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
+
+// Close forcibly closes the connection of the given session.
+func (t *SessionTracker) Close(id uint64) error {
+	t.mu.Lock()
+	s, ok := t.sessions[id]
+	t.mu.Unlock()
+
+	switch {
+	case !ok:
+		return ErrSessionNotFound
+	case s.conn == nil:
+		return ErrSessionNotClosable
+	default:
+		return s.conn.Close()
+	}
+}
+
+// TagConn implements stats.Handler.
+func (t *SessionTracker) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if addr, ok := info.RemoteAddr.(*trackedAddr); ok {
+		if _, exists := t.sessions[addr.id]; exists {
+			return context.WithValue(ctx, sessionCtxKey{}, addr.id)
+		}
+		// Connection has already been closed.
+		return ctx
+	}
+
+	id := t.allocIDLocked()
+	t.sessions[id] = t.newSessionLocked(id, info.RemoteAddr, nil)
+	return context.WithValue(ctx, sessionCtxKey{}, id)
+}
+
+// HandleConn implements stats.Handler.
+func (t *SessionTracker) HandleConn(ctx context.Context, s stats.ConnStats) {
+	if _, ok := s.(*stats.ConnEnd); !ok {
+		return
+	}
+	if id, ok := ctx.Value(sessionCtxKey{}).(uint64); ok {
+		t.removeSession(id)
+	}
+}
+
+// TagRPC implements stats.Handler.
+func (t *SessionTracker) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	id, ok := ctx.Value(sessionCtxKey{}).(uint64)
+	if !ok {
+		return ctx
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.sessions[id]
+	if !ok {
+		return ctx
+	}
+	if s.info.TLSPublicKey == nil {
+		s.info.TLSPublicKey = peerTLSPublicKey(ctx)
+	}
+
+	t.lastStreamID++
+	streamID := t.lastStreamID
+	s.streams[streamID] = &StreamInfo{
+		Method:    info.FullMethodName,
+		StartedAt: t.nowFn(),
+	}
+	return context.WithValue(ctx, streamCtxKey{}, streamID)
+}
+
+// HandleRPC implements stats.Handler.
+func (t *SessionTracker) HandleRPC(ctx context.Context, rs stats.RPCStats) {
+	id, ok := ctx.Value(sessionCtxKey{}).(uint64)
+	if !ok {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.sessions[id]
+	if !ok {
+		return
+	}
+
+	switch st := rs.(type) {
+	case *stats.InPayload:
+		s.info.BytesIn += uint64(st.WireLength) //nolint:gosec
+		s.info.MessagesIn++
+	case *stats.OutPayload:
+		s.info.BytesOut += uint64(st.WireLength) //nolint:gosec
+		s.info.MessagesOut++
+	case *stats.End:
+		if streamID, ok := ctx.Value(streamCtxKey{}).(uint64); ok {
+			delete(s.streams, streamID)
+		}
+	}
+}
+
+func peerTLSPublicKey(ctx context.Context) *signature.PublicKey {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.AuthInfo == nil {
+		return nil
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return tlsStatePublicKey(&tlsInfo.State)
+}
+
+func tlsStatePublicKey(state *tls.ConnectionState) *signature.PublicKey {
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	edPk, ok := state.PeerCertificates[0].PublicKey.(ed25519.PublicKey)
+	if !ok {
+		return nil
+	}
+	var pk signature.PublicKey
+	if err := pk.UnmarshalBinary(edPk); err != nil {
+		return nil
+	}
+	return &pk
+}
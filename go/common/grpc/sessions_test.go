@@ -0,0 +1,159 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	testSessionsService = "oasis-core.TestSessions"
+	testSessionsWait    = "/" + testSessionsService + "/Wait"
+)
+
+type testBytesCodec struct{}
+
+func (testBytesCodec) Marshal(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case *[]byte:
+		return *v, nil
+	case []byte:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+func (testBytesCodec) Unmarshal(data []byte, v any) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (testBytesCodec) Name() string {
+	return "test-bytes"
+}
+
+func TestSessionTracker(t *testing.T) {
+	require := require.New(t)
+
+	tracker := NewSessionTracker("test")
+
+	// The Wait stream echoes the first message and then blocks until the client goes away.
+	server := grpc.NewServer(
+		grpc.StatsHandler(tracker),
+		grpc.ForceServerCodec(testBytesCodec{}),
+	)
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: testSessionsService,
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName: "Wait",
+				Handler: func(_ any, stream grpc.ServerStream) error {
+					var msg []byte
+					if err := stream.RecvMsg(&msg); err != nil {
+						return err
+					}
+					if err := stream.SendMsg(&msg); err != nil {
+						return err
+					}
+					<-stream.Context().Done()
+					return nil
+				},
+				ServerStreams: true,
+				ClientStreams: true,
+			},
+		},
+	}, nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err, "Listen")
+	go func() { _ = server.Serve(tracker.WrapListener(listener)) }()
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dial := func() *grpc.ClientConn {
+		conn, err := grpc.NewClient(
+			"passthrough:///"+listener.Addr().String(),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultCallOptions(grpc.ForceCodec(testBytesCodec{})),
+		)
+		require.NoError(err, "NewClient")
+		return conn
+	}
+	openStream := func(conn *grpc.ClientConn) grpc.ClientStream {
+		stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, testSessionsWait)
+		require.NoError(err, "NewStream")
+		msg := []byte("hello")
+		require.NoError(stream.SendMsg(&msg), "SendMsg")
+		var rsp []byte
+		require.NoError(stream.RecvMsg(&rsp), "RecvMsg")
+		require.Equal(msg, rsp)
+		return stream
+	}
+
+	conn1 := dial()
+	defer conn1.Close()
+	conn2 := dial()
+	defer conn2.Close()
+
+	// Two streams on the first connection and one on the second.
+	openStream(conn1)
+	openStream(conn1)
+	openStream(conn2)
+
+	// Outgoing payload stats are recorded after the message has been sent.
+	var sessions []*SessionInfo
+	require.Eventually(func() bool {
+		sessions = tracker.Sessions()
+		for _, s := range sessions {
+			if s.MessagesOut != uint64(len(s.Streams)) {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(sessions, 2, "there should be two sessions")
+	streamCounts := make(map[int]int)
+	for _, s := range sessions {
+		require.Equal("test", s.Listener)
+		require.NotEmpty(s.PeerAddress)
+		require.Nil(s.TLSPublicKey, "insecure connections should not have a TLS public key")
+		require.EqualValues(len(s.Streams), s.MessagesIn)
+		require.EqualValues(len(s.Streams), s.MessagesOut)
+		require.NotZero(s.BytesIn)
+		require.NotZero(s.BytesOut)
+		for _, st := range s.Streams {
+			require.Equal(testSessionsWait, st.Method)
+		}
+		streamCounts[len(s.Streams)]++
+	}
+	require.Equal(map[int]int{1: 1, 2: 1}, streamCounts)
+
+	// Forcibly close the session with two streams.
+	var target *SessionInfo
+	for _, s := range sessions {
+		if len(s.Streams) == 2 {
+			target = s
+		}
+	}
+	require.NoError(tracker.Close(target.ID), "Close")
+	require.ErrorIs(tracker.Close(target.ID+1000), ErrSessionNotFound)
+
+	require.Eventually(func() bool {
+		sessions := tracker.Sessions()
+		return len(sessions) == 1 && sessions[0].ID != target.ID
+	}, 5*time.Second, 10*time.Millisecond, "closed session should be removed")
+}
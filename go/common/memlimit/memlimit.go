@@ -0,0 +1,497 @@
+// Package memlimit implements a soft memory limit manager.
+//
+// The manager periodically samples the memory used by the Go runtime and by the node's cgroup,
+// and compares it against a soft limit. When the usage crosses one of the pressure thresholds, the
+// resize callbacks registered by cache owners are invoked so that caches can shrink in a
+// coordinated way, and GOGC is optionally lowered. Once the pressure subsides, budgets and GOGC
+// are restored.
+package memlimit
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+)
+
+const (
+	// DefaultModerateThreshold is the default fraction of the limit above which memory pressure
+	// is moderate.
+	DefaultModerateThreshold = 0.70
+	// DefaultHighThreshold is the default fraction of the limit above which memory pressure is
+	// high.
+	DefaultHighThreshold = 0.85
+	// DefaultCriticalThreshold is the default fraction of the limit above which memory pressure
+	// is critical.
+	DefaultCriticalThreshold = 0.95
+	// DefaultHysteresis is the default fraction of the limit by which the usage needs to fall
+	// below a threshold before the pressure level is lowered.
+	DefaultHysteresis = 0.05
+	// DefaultInterval is the default interval at which memory usage is sampled.
+	DefaultInterval = 5 * time.Second
+
+	// minGCPercent is the lowest GOGC value set under memory pressure.
+	minGCPercent = 10
+)
+
+// Level is the memory pressure level.
+type Level uint8
+
+const (
+	// LevelNone means that there is no memory pressure and all budgets are at their full size.
+	LevelNone Level = iota
+	// LevelModerate means that memory pressure is moderate.
+	LevelModerate
+	// LevelHigh means that memory pressure is high.
+	LevelHigh
+	// LevelCritical means that memory pressure is critical and the node is about to run out of
+	// memory.
+	LevelCritical
+)
+
+// String returns a string representation of the pressure level.
+func (l Level) String() string {
+	switch l {
+	case LevelNone:
+		return "none"
+	case LevelModerate:
+		return "moderate"
+	case LevelHigh:
+		return "high"
+	case LevelCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("[unknown level: %d]", uint8(l))
+	}
+}
+
+// BudgetFraction returns the fraction of their full budget that memory consumers should use at
+// the given pressure level.
+func (l Level) BudgetFraction() float64 {
+	switch l {
+	case LevelNone:
+		return 1
+	case LevelModerate:
+		return 0.75
+	case LevelHigh:
+		return 0.5
+	default:
+		return 0.25
+	}
+}
+
+// ScaleBudget scales the given full budget to the given pressure level.
+func ScaleBudget(budget uint64, level Level) uint64 {
+	return uint64(float64(budget) * level.BudgetFraction())
+}
+
+// ResizeFunc is a callback invoked when the pressure level changes. Memory consumers should
+// resize their budgets for the given level, e.g., using ScaleBudget.
+type ResizeFunc func(level Level)
+
+// Config is the memory limit manager configuration.
+type Config struct {
+	// SoftLimit is the soft memory limit in bytes. If zero, the cgroup memory limit is used. If
+	// there is no cgroup memory limit either, the manager does nothing.
+	SoftLimit uint64
+
+	// ModerateThreshold, HighThreshold and CriticalThreshold are the fractions of the limit above
+	// which memory pressure is moderate, high and critical. If zero, the defaults are used.
+	ModerateThreshold float64
+	HighThreshold     float64
+	CriticalThreshold float64
+
+	// Hysteresis is the fraction of the limit by which the usage needs to fall below a threshold
+	// before the pressure level is lowered. If zero, DefaultHysteresis is used.
+	Hysteresis float64
+
+	// Interval is the interval at which memory usage is sampled. If zero, DefaultInterval is used.
+	Interval time.Duration
+
+	// AdjustGC specifies whether GOGC should be lowered under memory pressure.
+	AdjustGC bool
+}
+
+func (cfg *Config) applyDefaults() {
+	if cfg.ModerateThreshold == 0 {
+		cfg.ModerateThreshold = DefaultModerateThreshold
+	}
+	if cfg.HighThreshold == 0 {
+		cfg.HighThreshold = DefaultHighThreshold
+	}
+	if cfg.CriticalThreshold == 0 {
+		cfg.CriticalThreshold = DefaultCriticalThreshold
+	}
+	if cfg.Hysteresis == 0 {
+		cfg.Hysteresis = DefaultHysteresis
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = DefaultInterval
+	}
+}
+
+// Validate validates the configuration.
+func (cfg *Config) Validate() error {
+	c := *cfg
+	c.applyDefaults()
+
+	if !(c.ModerateThreshold > 0 && c.ModerateThreshold < c.HighThreshold && c.HighThreshold < c.CriticalThreshold && c.CriticalThreshold <= 1) {
+		return fmt.Errorf("memlimit: thresholds must be increasing and in (0, 1] (moderate: %f high: %f critical: %f)",
+			c.ModerateThreshold, c.HighThreshold, c.CriticalThreshold,
+		)
+	}
+	if c.Hysteresis < 0 || c.Hysteresis >= c.ModerateThreshold {
+		return fmt.Errorf("memlimit: hysteresis %f must be in [0, %f)", c.Hysteresis, c.ModerateThreshold)
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("memlimit: invalid interval %s", c.Interval)
+	}
+	return nil
+}
+
+// threshold returns the fraction of the limit above which the given level is entered.
+func (cfg *Config) threshold(level Level) float64 {
+	switch level {
+	case LevelModerate:
+		return cfg.ModerateThreshold
+	case LevelHigh:
+		return cfg.HighThreshold
+	case LevelCritical:
+		return cfg.CriticalThreshold
+	default:
+		return 0
+	}
+}
+
+// ConsumerStatus is the status of a registered memory consumer.
+type ConsumerStatus struct {
+	// Name is the name of the consumer.
+	Name string `json:"name"`
+	// Priority is the consumer priority. Consumers with lower priorities are shrunk first and
+	// restored last.
+	Priority int `json:"priority"`
+	// Level is the pressure level the consumer has last been resized for.
+	Level Level `json:"level"`
+}
+
+// Status is the memory limit manager status.
+type Status struct {
+	// Level is the current pressure level.
+	Level Level `json:"level"`
+	// Limit is the effective soft memory limit in bytes. Zero means that there is no limit.
+	Limit uint64 `json:"limit"`
+	// Usage is the last memory usage sample.
+	Usage Usage `json:"usage"`
+	// GCPercent is the GOGC value set due to memory pressure. Zero means that GOGC is not
+	// adjusted.
+	GCPercent int `json:"gc_percent,omitempty"`
+	// LastChange is the time of the last pressure level change.
+	LastChange time.Time `json:"last_change,omitempty"`
+	// Consumers are the registered memory consumers.
+	Consumers []ConsumerStatus `json:"consumers,omitempty"`
+}
+
+var (
+	pressureLevelGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_node_memlimit_pressure_level",
+			Help: "Current memory pressure level (0 = none, 1 = moderate, 2 = high, 3 = critical).",
+		},
+	)
+	usageGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_node_memlimit_usage_bytes",
+			Help: "Memory usage considered by the memory limit manager by source (bytes).",
+		},
+		[]string{"source"},
+	)
+	limitGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_node_memlimit_limit_bytes",
+			Help: "Effective soft memory limit (bytes).",
+		},
+	)
+	gcPercentGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_node_memlimit_gc_percent",
+			Help: "GOGC value set due to memory pressure (0 if not adjusted).",
+		},
+	)
+	resizeCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_node_memlimit_resizes",
+			Help: "Number of memory consumer resizes by consumer and target pressure level.",
+		},
+		[]string{"consumer", "level"},
+	)
+
+	memlimitCollectors = []prometheus.Collector{
+		pressureLevelGauge,
+		usageGauge,
+		limitGauge,
+		gcPercentGauge,
+		resizeCount,
+	}
+
+	metricsOnce sync.Once
+)
+
+type consumer struct {
+	name     string
+	priority int
+	fn       ResizeFunc
+	level    Level
+}
+
+// Manager is the soft memory limit manager.
+type Manager struct {
+	cfg Config
+
+	readUsage    func() (*Usage, error)
+	setGCPercent func(int) int
+	nowFn        func() time.Time
+
+	// updateLock serializes pressure level changes and consumer registrations so that callbacks
+	// are invoked without holding mu.
+	updateLock sync.Mutex
+
+	mu            sync.Mutex
+	consumers     []*consumer
+	level         Level
+	limit         uint64
+	usage         Usage
+	gcPercent     int
+	origGCPercent int
+	lastChange    time.Time
+
+	logger *logging.Logger
+}
+
+// New creates a new memory limit manager.
+func New(cfg Config) (*Manager, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.applyDefaults()
+
+	metricsOnce.Do(func() {
+		prometheus.MustRegister(memlimitCollectors...)
+	})
+
+	return &Manager{
+		cfg:          cfg,
+		readUsage:    readUsage,
+		setGCPercent: debug.SetGCPercent,
+		nowFn:        time.Now,
+		logger:       logging.GetLogger("common/memlimit"),
+	}, nil
+}
+
+// Register registers a memory consumer with the given name and priority. Consumers with lower
+// priorities are shrunk first and restored last.
+//
+// In case there is memory pressure, the callback is invoked immediately with the current level.
+// The callback must not call Register.
+func (m *Manager) Register(name string, priority int, fn ResizeFunc) {
+	m.updateLock.Lock()
+	defer m.updateLock.Unlock()
+
+	c := &consumer{
+		name:     name,
+		priority: priority,
+		fn:       fn,
+	}
+
+	m.mu.Lock()
+	m.consumers = append(m.consumers, c)
+	sort.SliceStable(m.consumers, func(i, j int) bool {
+		return m.consumers[i].priority < m.consumers[j].priority
+	})
+	level := m.level
+	m.mu.Unlock()
+
+	if level != LevelNone {
+		m.resize(c, level)
+	}
+}
+
+// Level returns the current pressure level.
+func (m *Manager) Level() Level {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.level
+}
+
+// Status returns the memory limit manager status.
+func (m *Manager) Status() *Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := &Status{
+		Level:      m.level,
+		Limit:      m.limit,
+		Usage:      m.usage,
+		GCPercent:  m.gcPercent,
+		LastChange: m.lastChange,
+	}
+	for _, c := range m.consumers {
+		status.Consumers = append(status.Consumers, ConsumerStatus{
+			Name:     c.name,
+			Priority: c.priority,
+			Level:    c.level,
+		})
+	}
+	return status
+}
+
+// Run samples memory usage and updates the pressure level until the given context is canceled.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Update(); err != nil {
+			m.logger.Warn("failed to update memory pressure",
+				"err", err,
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update samples memory usage once and updates the pressure level.
+func (m *Manager) Update() error {
+	usage, err := m.readUsage()
+	if err != nil {
+		return fmt.Errorf("memlimit: failed to read memory usage: %w", err)
+	}
+
+	m.updateLock.Lock()
+	defer m.updateLock.Unlock()
+
+	limit := m.cfg.SoftLimit
+	if limit == 0 {
+		limit = usage.CgroupLimit
+	}
+
+	m.mu.Lock()
+	prevLevel := m.level
+	level := m.levelFor(prevLevel, usage.Total(), limit)
+	m.limit = limit
+	m.usage = *usage
+	consumers := append([]*consumer{}, m.consumers...)
+	m.mu.Unlock()
+
+	usageGauge.WithLabelValues("go").Set(float64(usage.Go))
+	usageGauge.WithLabelValues("cgroup").Set(float64(usage.Cgroup))
+	limitGauge.Set(float64(limit))
+
+	if level == prevLevel {
+		return nil
+	}
+
+	m.logger.Info("memory pressure level changed",
+		"level", level,
+		"previous_level", prevLevel,
+		"usage", usage.Total(),
+		"limit", limit,
+	)
+
+	m.mu.Lock()
+	m.level = level
+	m.lastChange = m.nowFn()
+	m.mu.Unlock()
+	pressureLevelGauge.Set(float64(level))
+
+	// Shrink consumers with lower priorities first and restore them last, adjusting GOGC after
+	// consumers have been shrunk and before they are restored.
+	if level > prevLevel {
+		for _, c := range consumers {
+			m.resize(c, level)
+		}
+		m.adjustGC(level)
+	} else {
+		m.adjustGC(level)
+		for i := len(consumers) - 1; i >= 0; i-- {
+			m.resize(consumers[i], level)
+		}
+	}
+	return nil
+}
+
+// levelFor returns the pressure level for the given usage and limit, given the current level.
+func (m *Manager) levelFor(current Level, usage, limit uint64) Level {
+	if limit == 0 {
+		return LevelNone
+	}
+	ratio := float64(usage) / float64(limit)
+
+	level := LevelNone
+	for l := LevelModerate; l <= LevelCritical; l++ {
+		threshold := m.cfg.threshold(l)
+		if l <= current {
+			// Levels that have already been entered are only left once the usage falls below the
+			// threshold by more than the hysteresis.
+			threshold -= m.cfg.Hysteresis
+		}
+		if ratio < threshold {
+			break
+		}
+		level = l
+	}
+	return level
+}
+
+func (m *Manager) resize(c *consumer, level Level) {
+	c.fn(level)
+
+	m.mu.Lock()
+	c.level = level
+	m.mu.Unlock()
+
+	resizeCount.WithLabelValues(c.name, level.String()).Inc()
+}
+
+func (m *Manager) adjustGC(level Level) {
+	if !m.cfg.AdjustGC {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case level == LevelNone:
+		if m.gcPercent == 0 {
+			return
+		}
+		m.setGCPercent(m.origGCPercent)
+		m.gcPercent = 0
+	default:
+		if m.gcPercent == 0 {
+			// Remember the original value the first time GOGC is adjusted.
+			orig := m.setGCPercent(100)
+			m.setGCPercent(orig)
+			if orig < 0 {
+				// Garbage collection is disabled, leave it alone.
+				return
+			}
+			m.origGCPercent = orig
+		}
+		m.gcPercent = max(int(float64(m.origGCPercent)*level.BudgetFraction()), minGCPercent)
+		m.setGCPercent(m.gcPercent)
+	}
+	gcPercentGauge.Set(float64(m.gcPercent))
+}
@@ -0,0 +1,225 @@
+package memlimit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testEnv struct {
+	usage     Usage
+	gcPercent int
+	calls     []string
+}
+
+func newTestManager(t *testing.T, cfg Config) (*Manager, *testEnv) {
+	m, err := New(cfg)
+	require.NoError(t, err, "New")
+
+	env := &testEnv{gcPercent: 100}
+	m.readUsage = func() (*Usage, error) {
+		u := env.usage
+		return &u, nil
+	}
+	m.setGCPercent = func(v int) int {
+		prev := env.gcPercent
+		env.gcPercent = v
+		return prev
+	}
+	m.nowFn = func() time.Time {
+		return time.Unix(1700000000, 0)
+	}
+	return m, env
+}
+
+func (env *testEnv) register(m *Manager, name string, priority int) {
+	m.Register(name, priority, func(level Level) {
+		env.calls = append(env.calls, name+":"+level.String())
+	})
+}
+
+func TestManager(t *testing.T) {
+	require := require.New(t)
+
+	m, env := newTestManager(t, Config{
+		SoftLimit: 1000,
+		AdjustGC:  true,
+	})
+	env.register(m, "important", 10)
+	env.register(m, "cache-b", 0)
+	env.register(m, "cache-a", 0)
+	require.Empty(env.calls, "registering without pressure should not resize")
+
+	env.usage.Go = 500
+	require.NoError(m.Update())
+	require.Equal(LevelNone, m.Level())
+	require.Empty(env.calls)
+	require.Equal(100, env.gcPercent)
+
+	// Consumers with lower priorities should be shrunk first, in registration order.
+	env.usage.Go = 860
+	require.NoError(m.Update())
+	require.Equal(LevelHigh, m.Level())
+	require.Equal([]string{"cache-b:high", "cache-a:high", "important:high"}, env.calls)
+	require.Equal(50, env.gcPercent)
+
+	// Usage below the threshold but within the hysteresis should not change the level.
+	env.calls = nil
+	env.usage.Go = 820
+	require.NoError(m.Update())
+	require.Equal(LevelHigh, m.Level())
+	require.Empty(env.calls)
+
+	// The cgroup usage should be considered as well.
+	env.usage.Cgroup = 990
+	require.NoError(m.Update())
+	require.Equal(LevelCritical, m.Level())
+	require.Equal([]string{"cache-b:critical", "cache-a:critical", "important:critical"}, env.calls)
+	require.Equal(25, env.gcPercent)
+
+	status := m.Status()
+	require.Equal(LevelCritical, status.Level)
+	require.EqualValues(1000, status.Limit)
+	require.EqualValues(990, status.Usage.Total())
+	require.Equal(25, status.GCPercent)
+	require.Equal(time.Unix(1700000000, 0), status.LastChange)
+	require.Len(status.Consumers, 3)
+	require.Equal("cache-b", status.Consumers[0].Name)
+	require.Equal("important", status.Consumers[2].Name)
+	for _, c := range status.Consumers {
+		require.Equal(LevelCritical, c.Level)
+	}
+
+	// Consumers registered under pressure should be resized immediately.
+	env.calls = nil
+	env.register(m, "late", 5)
+	require.Equal([]string{"late:critical"}, env.calls)
+
+	// Consumers should be restored in reverse order, after GOGC has been restored.
+	env.calls = nil
+	env.usage.Go = 100
+	env.usage.Cgroup = 100
+	require.NoError(m.Update())
+	require.Equal(LevelNone, m.Level())
+	require.Equal([]string{"important:none", "late:none", "cache-a:none", "cache-b:none"}, env.calls)
+	require.Equal(100, env.gcPercent)
+	require.Zero(m.Status().GCPercent)
+}
+
+func TestManagerCgroupLimit(t *testing.T) {
+	require := require.New(t)
+
+	m, env := newTestManager(t, Config{})
+	env.register(m, "cache", 0)
+
+	// Without a soft limit and without a cgroup limit, the manager should do nothing.
+	env.usage.Go = 1 << 40
+	require.NoError(m.Update())
+	require.Equal(LevelNone, m.Level())
+
+	env.usage.CgroupLimit = 1 << 40
+	require.NoError(m.Update())
+	require.Equal(LevelCritical, m.Level())
+	require.EqualValues(1<<40, m.Status().Limit)
+	require.Equal(100, env.gcPercent, "GOGC should not be adjusted unless configured")
+
+	env.usage.Go = 1 << 39
+	require.NoError(m.Update())
+	require.Equal(LevelNone, m.Level())
+	require.Equal([]string{"cache:critical", "cache:none"}, env.calls)
+}
+
+func TestManagerGCDisabled(t *testing.T) {
+	require := require.New(t)
+
+	m, env := newTestManager(t, Config{
+		SoftLimit: 1000,
+		AdjustGC:  true,
+	})
+	env.gcPercent = -1
+
+	env.usage.Go = 1000
+	require.NoError(m.Update())
+	require.Equal(LevelCritical, m.Level())
+	require.Equal(-1, env.gcPercent, "disabled GC should be left alone")
+
+	env.usage.Go = 0
+	require.NoError(m.Update())
+	require.Equal(-1, env.gcPercent)
+}
+
+func TestConfigValidate(t *testing.T) {
+	require := require.New(t)
+
+	require.NoError((&Config{}).Validate())
+	require.NoError((&Config{ModerateThreshold: 0.5, HighThreshold: 0.6, CriticalThreshold: 1}).Validate())
+	require.Error((&Config{ModerateThreshold: 0.9}).Validate(), "thresholds must be increasing")
+	require.Error((&Config{CriticalThreshold: 1.5}).Validate(), "thresholds must be at most 1")
+	require.Error((&Config{Hysteresis: 0.8}).Validate(), "hysteresis must be below the lowest threshold")
+	require.Error((&Config{Interval: -time.Second}).Validate())
+}
+
+func TestScaleBudget(t *testing.T) {
+	require := require.New(t)
+
+	require.EqualValues(1000, ScaleBudget(1000, LevelNone))
+	require.EqualValues(750, ScaleBudget(1000, LevelModerate))
+	require.EqualValues(500, ScaleBudget(1000, LevelHigh))
+	require.EqualValues(250, ScaleBudget(1000, LevelCritical))
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		fn := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fn), 0o700))
+		require.NoError(t, os.WriteFile(fn, []byte(content), 0o600))
+	}
+}
+
+func TestCgroup(t *testing.T) {
+	require := require.New(t)
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"self-v2":                           "0::/node.slice\n",
+		"node.slice/memory.current":         "1000\n",
+		"node.slice/memory.max":             "4000\n",
+		"node.slice/memory.stat":            "anon 600\nfile 400\ninactive_file 300\n",
+		"unlimited/memory.current":          "1000\n",
+		"unlimited/memory.max":              "max\n",
+		"self-v1":                           "12:cpu,cpuacct:/node\n11:memory:/node\n0::/\n",
+		"memory/node/memory.usage_in_bytes": "2000\n",
+		"memory/node/memory.limit_in_bytes": "9223372036854771712\n",
+		"memory/node/memory.stat":           "cache 800\ntotal_inactive_file 500\n",
+		"self-none":                         "",
+	})
+
+	dir, v2, err := cgroupMemoryDir(filepath.Join(root, "self-v2"), root)
+	require.NoError(err, "cgroupMemoryDir")
+	require.True(v2)
+	require.Equal(filepath.Join(root, "node.slice"), dir)
+	usage, limit, err := readCgroupV2(dir)
+	require.NoError(err, "readCgroupV2")
+	require.EqualValues(700, usage)
+	require.EqualValues(4000, limit)
+
+	usage, limit, err = readCgroupV2(filepath.Join(root, "unlimited"))
+	require.NoError(err, "readCgroupV2")
+	require.EqualValues(1000, usage, "missing memory.stat should be ignored")
+	require.Zero(limit)
+
+	dir, v2, err = cgroupMemoryDir(filepath.Join(root, "self-v1"), root)
+	require.NoError(err, "cgroupMemoryDir")
+	require.False(v2)
+	require.Equal(filepath.Join(root, "memory", "node"), dir)
+	usage, limit, err = readCgroupV1(dir)
+	require.NoError(err, "readCgroupV1")
+	require.EqualValues(1500, usage)
+	require.Zero(limit, "huge cgroup v1 limit should mean no limit")
+
+	_, _, err = cgroupMemoryDir(filepath.Join(root, "self-none"), root)
+	require.ErrorIs(err, os.ErrNotExist)
+}
@@ -0,0 +1,210 @@
+package memlimit
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// cgroupRoot is the mount point of the cgroup filesystem.
+	cgroupRoot = "/sys/fs/cgroup"
+	// procSelfCgroup is the file listing the cgroups of the current process.
+	procSelfCgroup = "/proc/self/cgroup"
+
+	// cgroupV1Unlimited is the threshold above which cgroup v1 memory limits mean that there is
+	// no limit, as the kernel reports a page-aligned maximum value.
+	cgroupV1Unlimited = 1 << 62
+)
+
+// Usage is a memory usage sample.
+type Usage struct {
+	// Go is the memory obtained from the OS by the Go runtime that has not been released back.
+	Go uint64 `json:"go"`
+	// Cgroup is the working set of the node's cgroup, i.e. the memory usage without inactive file
+	// pages that can be reclaimed by the kernel. Zero if not available.
+	Cgroup uint64 `json:"cgroup,omitempty"`
+	// CgroupLimit is the memory limit of the node's cgroup. Zero means that there is no limit.
+	CgroupLimit uint64 `json:"cgroup_limit,omitempty"`
+}
+
+// Total returns the memory usage compared against the limit.
+func (u *Usage) Total() uint64 {
+	return max(u.Go, u.Cgroup)
+}
+
+// readUsage samples the memory usage of the Go runtime and of the node's cgroup.
+func readUsage() (*Usage, error) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	usage := Usage{
+		Go: ms.Sys - ms.HeapReleased,
+	}
+
+	dir, v2, err := cgroupMemoryDir(procSelfCgroup, cgroupRoot)
+	switch {
+	case err == nil:
+	case errors.Is(err, fs.ErrNotExist):
+		// Not running in a cgroup, only the Go runtime usage is available.
+		return &usage, nil
+	default:
+		return nil, err
+	}
+
+	if v2 {
+		usage.Cgroup, usage.CgroupLimit, err = readCgroupV2(dir)
+	} else {
+		usage.Cgroup, usage.CgroupLimit, err = readCgroupV1(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// cgroupMemoryDir returns the directory of the memory controller of the current process's cgroup
+// and whether it is a cgroup v2 directory.
+func cgroupMemoryDir(selfCgroup, root string) (string, bool, error) {
+	data, err := os.ReadFile(selfCgroup)
+	if err != nil {
+		return "", false, err
+	}
+
+	var v1Path, v2Path string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// Each line is of the form hierarchy-ID:controller-list:cgroup-path.
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			v2Path = parts[2]
+		case strings.Contains(","+parts[1]+",", ",memory,"):
+			v1Path = parts[2]
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", false, fmt.Errorf("memlimit: malformed %s: %w", selfCgroup, err)
+	}
+
+	if v1Path != "" {
+		dir := filepath.Join(root, "memory", v1Path)
+		if _, err = os.Stat(dir); err == nil {
+			return dir, false, nil
+		}
+		// Inside a container with its own cgroup namespace the path is not visible.
+		return filepath.Join(root, "memory"), false, nil
+	}
+	if v2Path != "" {
+		dir := filepath.Join(root, v2Path)
+		if _, err = os.Stat(filepath.Join(dir, "memory.current")); err == nil {
+			return dir, true, nil
+		}
+		if _, err = os.Stat(filepath.Join(root, "memory.current")); err == nil {
+			return root, true, nil
+		}
+	}
+	return "", false, fs.ErrNotExist
+}
+
+// readCgroupV2 reads the working set and the memory limit from a cgroup v2 directory.
+func readCgroupV2(dir string) (uint64, uint64, error) {
+	current, err := readCgroupValue(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return 0, 0, err
+	}
+	var limit uint64
+	switch data, err := os.ReadFile(filepath.Join(dir, "memory.max")); {
+	case err == nil && strings.TrimSpace(string(data)) == "max":
+	case err == nil:
+		if limit, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64); err != nil {
+			return 0, 0, fmt.Errorf("memlimit: malformed cgroup memory limit: %w", err)
+		}
+	case errors.Is(err, fs.ErrNotExist):
+		// The root cgroup has no limit.
+	default:
+		return 0, 0, err
+	}
+	inactiveFile, err := readCgroupStat(filepath.Join(dir, "memory.stat"), "inactive_file")
+	if err != nil {
+		return 0, 0, err
+	}
+	return workingSet(current, inactiveFile), limit, nil
+}
+
+// readCgroupV1 reads the working set and the memory limit from a cgroup v1 memory controller
+// directory.
+func readCgroupV1(dir string) (uint64, uint64, error) {
+	usage, err := readCgroupValue(filepath.Join(dir, "memory.usage_in_bytes"))
+	if err != nil {
+		return 0, 0, err
+	}
+	limit, err := readCgroupValue(filepath.Join(dir, "memory.limit_in_bytes"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if limit >= cgroupV1Unlimited {
+		limit = 0
+	}
+	inactiveFile, err := readCgroupStat(filepath.Join(dir, "memory.stat"), "total_inactive_file")
+	if err != nil {
+		return 0, 0, err
+	}
+	return workingSet(usage, inactiveFile), limit, nil
+}
+
+func workingSet(usage, inactiveFile uint64) uint64 {
+	if inactiveFile > usage {
+		return 0
+	}
+	return usage - inactiveFile
+}
+
+func readCgroupValue(fn string) (uint64, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("memlimit: malformed %s: %w", fn, err)
+	}
+	return v, nil
+}
+
+// readCgroupStat returns the value of the given key in a cgroup memory.stat file. In case the
+// key is missing, zero is returned.
+func readCgroupStat(fn string, key string) (uint64, error) {
+	data, err := os.ReadFile(fn)
+	switch {
+	case err == nil:
+	case errors.Is(err, fs.ErrNotExist):
+		return 0, nil
+	default:
+		return 0, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || k != key {
+			continue
+		}
+		value, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("memlimit: malformed %s: %w", fn, err)
+		}
+		return value, nil
+	}
+	return 0, scanner.Err()
+}
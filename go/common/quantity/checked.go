@@ -0,0 +1,99 @@
+package quantity
+
+import (
+	"errors"
+	"math/big"
+)
+
+// BasisPointsDenominator is the denominator used for basis points.
+const BasisPointsDenominator uint64 = 10_000
+
+var (
+	// ErrUnderflow is the error returned when an operation would result in a negative quantity.
+	ErrUnderflow = errors.New("quantity: underflow")
+	// ErrZeroDenominator is the error returned when dividing by zero.
+	ErrZeroDenominator = errors.New("quantity: zero denominator")
+	// ErrInvalidBasisPoints is the error returned when basis points exceed 100%.
+	ErrInvalidBasisPoints = errors.New("quantity: invalid basis points")
+)
+
+// AddNew returns a new quantity equal to q + n without modifying either operand.
+func (q *Quantity) AddNew(n *Quantity) (*Quantity, error) {
+	r := q.Clone()
+	if err := r.Add(n); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SubNew returns a new quantity equal to q - n without modifying either operand.
+//
+// Returns ErrUnderflow in case n is greater than q.
+func (q *Quantity) SubNew(n *Quantity) (*Quantity, error) {
+	if q.Cmp(n) < 0 {
+		return nil, ErrUnderflow
+	}
+	r := q.Clone()
+	if err := r.Sub(n); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SaturatingSubNew returns a new quantity equal to q - n without modifying either operand.
+//
+// In case n is greater than q, zero is returned.
+func (q *Quantity) SaturatingSubNew(n *Quantity) *Quantity {
+	r, err := q.SubNew(n)
+	if err != nil {
+		return NewQuantity()
+	}
+	return r
+}
+
+// MulFrac returns a new quantity equal to floor(q * numerator / denominator) without modifying
+// the receiver.
+func (q *Quantity) MulFrac(numerator, denominator uint64) (*Quantity, error) {
+	return q.mulQuo(new(big.Int).SetUint64(numerator), new(big.Int).SetUint64(denominator))
+}
+
+// MulQuo returns a new quantity equal to floor(q * numerator / denominator) without modifying
+// any of the operands.
+//
+// The multiplication is performed first so the result is identical to cloning the receiver and
+// calling Mul followed by Quo.
+func (q *Quantity) MulQuo(numerator, denominator *Quantity) (*Quantity, error) {
+	return q.mulQuo(numerator.ToBigInt(), denominator.ToBigInt())
+}
+
+func (q *Quantity) mulQuo(numerator, denominator *big.Int) (*Quantity, error) {
+	if denominator.Sign() == 0 {
+		return nil, ErrZeroDenominator
+	}
+
+	var v big.Int
+	v.Mul(q.ToBigInt(), numerator)
+	v.Quo(&v, denominator)
+
+	r := NewQuantity()
+	if err := r.FromBigInt(&v); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// PercentOf returns a new quantity equal to floor(q * basisPoints / 10000) without modifying
+// the receiver.
+//
+// Returns ErrInvalidBasisPoints in case basis points exceed 100%.
+func (q *Quantity) PercentOf(basisPoints uint64) (*Quantity, error) {
+	if basisPoints > BasisPointsDenominator {
+		return nil, ErrInvalidBasisPoints
+	}
+	return q.MulFrac(basisPoints, BasisPointsDenominator)
+}
+
+// SaturatingPercentOf is like PercentOf, but clamps basis points to 100%.
+func (q *Quantity) SaturatingPercentOf(basisPoints uint64) (*Quantity, error) {
+	return q.PercentOf(min(basisPoints, BasisPointsDenominator))
+}
@@ -0,0 +1,142 @@
+package quantity
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func randomBigInt(rng *rand.Rand) *big.Int {
+	// Mix small values with large ones to hit edge cases.
+	switch rng.Intn(4) {
+	case 0:
+		return big.NewInt(rng.Int63n(4))
+	case 1:
+		return new(big.Int).SetUint64(rng.Uint64())
+	default:
+		b := make([]byte, 1+rng.Intn(40))
+		_, _ = rng.Read(b)
+		return new(big.Int).SetBytes(b)
+	}
+}
+
+func mustFromBigInt(t *testing.T, v *big.Int) *Quantity {
+	q := NewQuantity()
+	require.NoError(t, q.FromBigInt(v), "FromBigInt")
+	return q
+}
+
+func TestCheckedArithmeticProperties(t *testing.T) {
+	require := require.New(t)
+
+	rng := rand.New(rand.NewSource(42)) // nolint: gosec
+	for i := 0; i < 10_000; i++ {
+		a, b := randomBigInt(rng), randomBigInt(rng)
+		qa, qb := mustFromBigInt(t, a), mustFromBigInt(t, b)
+		qaOrig, qbOrig := qa.Clone(), qb.Clone()
+
+		// AddNew.
+		sum, err := qa.AddNew(qb)
+		require.NoError(err, "AddNew")
+		require.Zero(new(big.Int).Add(a, b).Cmp(sum.ToBigInt()), "AddNew(%s, %s)", a, b)
+
+		// SubNew and SaturatingSubNew.
+		expectedDiff := new(big.Int).Sub(a, b)
+		diff, err := qa.SubNew(qb)
+		switch expectedDiff.Sign() {
+		case -1:
+			require.ErrorIs(err, ErrUnderflow, "SubNew(%s, %s)", a, b)
+			require.True(qa.SaturatingSubNew(qb).IsZero(), "SaturatingSubNew(%s, %s)", a, b)
+		default:
+			require.NoError(err, "SubNew(%s, %s)", a, b)
+			require.Zero(expectedDiff.Cmp(diff.ToBigInt()), "SubNew(%s, %s)", a, b)
+			require.Zero(expectedDiff.Cmp(qa.SaturatingSubNew(qb).ToBigInt()), "SaturatingSubNew(%s, %s)", a, b)
+		}
+
+		// MulFrac.
+		n, d := rng.Uint64(), rng.Uint64()
+		frac, err := qa.MulFrac(n, d)
+		switch d {
+		case 0:
+			require.ErrorIs(err, ErrZeroDenominator)
+		default:
+			require.NoError(err, "MulFrac")
+			expected := new(big.Int).Mul(a, new(big.Int).SetUint64(n))
+			expected.Quo(expected, new(big.Int).SetUint64(d))
+			require.Zero(expected.Cmp(frac.ToBigInt()), "MulFrac(%s, %d, %d)", a, n, d)
+		}
+
+		// MulQuo must be bit-identical to the Clone/Mul/Quo sequence.
+		c := randomBigInt(rng)
+		qc := mustFromBigInt(t, c)
+		mq, err := qa.MulQuo(qb, qc)
+		switch c.Sign() {
+		case 0:
+			require.ErrorIs(err, ErrZeroDenominator)
+		default:
+			require.NoError(err, "MulQuo")
+			legacy := qa.Clone()
+			require.NoError(legacy.Mul(qb))
+			require.NoError(legacy.Quo(qc))
+			require.Zero(legacy.Cmp(mq), "MulQuo(%s, %s, %s)", a, b, c)
+		}
+
+		// PercentOf.
+		bp := uint64(rng.Intn(12_000))
+		pct, err := qa.PercentOf(bp)
+		if bp > BasisPointsDenominator {
+			require.ErrorIs(err, ErrInvalidBasisPoints)
+			sat, err := qa.SaturatingPercentOf(bp)
+			require.NoError(err, "SaturatingPercentOf")
+			require.Zero(qa.Cmp(sat), "SaturatingPercentOf should clamp to 100%%")
+		} else {
+			require.NoError(err, "PercentOf")
+			expected := new(big.Int).Mul(a, new(big.Int).SetUint64(bp))
+			expected.Quo(expected, big.NewInt(10_000))
+			require.Zero(expected.Cmp(pct.ToBigInt()), "PercentOf(%s, %d)", a, bp)
+		}
+
+		// Operands must never be modified.
+		require.Zero(qaOrig.Cmp(qa), "receiver must not be modified")
+		require.Zero(qbOrig.Cmp(qb), "argument must not be modified")
+	}
+}
+
+func TestCheckedArithmeticEdgeCases(t *testing.T) {
+	require := require.New(t)
+
+	zero := NewQuantity()
+	one := NewFromUint64(1)
+
+	_, err := zero.SubNew(one)
+	require.ErrorIs(err, ErrUnderflow, "0 - 1 should underflow")
+	require.True(zero.SaturatingSubNew(one).IsZero(), "0 - 1 should saturate to 0")
+
+	diff, err := one.SubNew(one)
+	require.NoError(err)
+	require.True(diff.IsZero(), "1 - 1 should be 0")
+
+	_, err = one.MulFrac(1, 0)
+	require.ErrorIs(err, ErrZeroDenominator)
+	_, err = one.MulQuo(one, zero)
+	require.ErrorIs(err, ErrZeroDenominator)
+
+	r, err := NewFromUint64(999).MulFrac(1, 1000)
+	require.NoError(err)
+	require.True(r.IsZero(), "result should be rounded down")
+
+	// Intermediate results larger than 64 bits.
+	maxU64 := NewFromUint64(^uint64(0))
+	r, err = maxU64.MulFrac(^uint64(0), ^uint64(0))
+	require.NoError(err)
+	require.Zero(maxU64.Cmp(r), "MulFrac should not overflow intermediate results")
+
+	r, err = NewFromUint64(12_345).PercentOf(BasisPointsDenominator)
+	require.NoError(err)
+	require.Zero(NewFromUint64(12_345).Cmp(r), "100%% of a quantity should be the quantity")
+	r, err = NewFromUint64(12_345).PercentOf(0)
+	require.NoError(err)
+	require.True(r.IsZero(), "0%% of a quantity should be zero")
+}
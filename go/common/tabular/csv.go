@@ -0,0 +1,66 @@
+package tabular
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type syncer interface {
+	Sync() error
+}
+
+type csvWriter[R Row] struct {
+	out io.Writer
+	w   *csv.Writer
+
+	writeHeader bool
+}
+
+// NewCSVWriter creates a new CSV writer. If writeHeader is set, the column names are written
+// before the first row.
+//
+// In case the output supports syncing (e.g., is an *os.File), it is synced on flush.
+func NewCSVWriter[R Row](out io.Writer, writeHeader bool) Writer[R] {
+	return &csvWriter[R]{
+		out:         out,
+		w:           csv.NewWriter(out),
+		writeHeader: writeHeader,
+	}
+}
+
+func (w *csvWriter[R]) Write(rows []R) error {
+	if w.writeHeader {
+		var zero R
+		if err := w.w.Write(zero.Columns()); err != nil {
+			return err
+		}
+		w.writeHeader = false
+	}
+	for _, row := range rows {
+		if err := w.w.Write(row.Values()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter[R]) Flush() error {
+	w.w.Flush()
+	if err := w.w.Error(); err != nil {
+		return err
+	}
+	if s, ok := w.out.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func (w *csvWriter[R]) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if c, ok := w.out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package tabular
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type parquetWriter[R Row] struct {
+	open func() (io.WriteCloser, error)
+
+	out io.WriteCloser
+	w   *parquet.GenericWriter[R]
+}
+
+// NewParquetWriter creates a new Parquet writer. The schema is derived from the row type which
+// must be a struct with parquet field tags.
+//
+// As a Parquet file is only valid once it is closed, each flush completes the current file and
+// the next write opens a new one via the given open function.
+func NewParquetWriter[R Row](open func() (io.WriteCloser, error)) Writer[R] {
+	return &parquetWriter[R]{
+		open: open,
+	}
+}
+
+func (w *parquetWriter[R]) Write(rows []R) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if w.w == nil {
+		out, err := w.open()
+		if err != nil {
+			return err
+		}
+		w.out = out
+		w.w = parquet.NewGenericWriter[R](out)
+	}
+	_, err := w.w.Write(rows)
+	return err
+}
+
+func (w *parquetWriter[R]) Flush() error {
+	if w.w == nil {
+		return nil
+	}
+	if err := w.w.Close(); err != nil {
+		return err
+	}
+	if s, ok := w.out.(syncer); ok {
+		if err := s.Sync(); err != nil {
+			return err
+		}
+	}
+	err := w.out.Close()
+	w.w, w.out = nil, nil
+	return err
+}
+
+func (w *parquetWriter[R]) Close() error {
+	return w.Flush()
+}
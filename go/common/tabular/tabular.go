@@ -0,0 +1,100 @@
+// Package tabular implements writers for exporting data in tabular formats.
+package tabular
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Format is a tabular output format.
+type Format string
+
+const (
+	// FormatCSV is the CSV format.
+	FormatCSV Format = "csv"
+	// FormatParquet is the Parquet format.
+	FormatParquet Format = "parquet"
+)
+
+// Row is a row with a stable column schema.
+type Row interface {
+	// Columns returns the names of the columns.
+	Columns() []string
+
+	// Values returns the values of the columns in text form.
+	Values() []string
+}
+
+// Writer is a tabular data writer.
+type Writer[R Row] interface {
+	// Write writes the given rows.
+	Write(rows []R) error
+
+	// Flush makes all rows written so far durable.
+	Flush() error
+
+	// Close flushes and closes the writer.
+	Close() error
+}
+
+// Cursor is the position of a resumable export.
+type Cursor struct {
+	// Next is the next position (e.g., height) to export.
+	Next int64 `json:"next"`
+
+	// Offset is the size of the output at the time the cursor was saved. It is only used by
+	// formats that append to a single output file.
+	Offset int64 `json:"offset,omitempty"`
+}
+
+// LoadCursor loads the cursor from the given file. In case the file does not exist, nil is
+// returned.
+func LoadCursor(path string) (*Cursor, error) {
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("tabular: failed to read cursor: %w", err)
+	}
+
+	var c Cursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("tabular: malformed cursor: %w", err)
+	}
+	return &c, nil
+}
+
+// Save atomically saves the cursor to the given file.
+func (c *Cursor) Save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("tabular: failed to create cursor: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("tabular: failed to write cursor: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("tabular: failed to sync cursor: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("tabular: failed to close cursor: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("tabular: failed to save cursor: %w", err)
+	}
+	return nil
+}
@@ -5,11 +5,13 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/a8m/envsubst"
 	"gopkg.in/yaml.v3"
 
 	tm "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/config"
+	invariants "github.com/oasisprotocol/oasis-core/go/consensus/cometbft/invariants/config"
 	genesis "github.com/oasisprotocol/oasis-core/go/genesis/config"
 	ias "github.com/oasisprotocol/oasis-core/go/ias/config"
 	common "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/config"
@@ -17,6 +19,7 @@ import (
 	pprof "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/pprof/config"
 	p2p "github.com/oasisprotocol/oasis-core/go/p2p/config"
 	runtime "github.com/oasisprotocol/oasis-core/go/runtime/config"
+	upgrade "github.com/oasisprotocol/oasis-core/go/upgrade/config"
 	workerKM "github.com/oasisprotocol/oasis-core/go/worker/keymanager/config"
 	workerRegistration "github.com/oasisprotocol/oasis-core/go/worker/registration/config"
 	workerSentry "github.com/oasisprotocol/oasis-core/go/worker/sentry/config"
@@ -67,6 +70,22 @@ func (m NodeMode) HasLocalStorage() bool {
 	return false
 }
 
+// Validate validates the node mode.
+func (m NodeMode) Validate() error {
+	switch m {
+	case ModeValidator:
+	case ModeCompute:
+	case ModeKeyManager:
+	case ModeClient:
+	case ModeStatelessClient:
+	case ModeSeed:
+	case ModeArchive:
+	default:
+		return fmt.Errorf("unknown node mode: %s", m)
+	}
+	return nil
+}
+
 // GlobalConfig holds the global configuration options.
 var GlobalConfig Config
 
@@ -83,6 +102,9 @@ type Config struct {
 	IAS       ias.Config     `yaml:"ias,omitempty"`
 	Pprof     pprof.Config   `yaml:"pprof,omitempty"`
 	Metrics   metrics.Config `yaml:"metrics,omitempty"`
+	Upgrade   upgrade.Config `yaml:"upgrade,omitempty"`
+
+	Invariants invariants.Config `yaml:"invariants,omitempty"`
 
 	Registration workerRegistration.Config `yaml:"registration,omitempty"`
 	Keymanager   workerKM.Config           `yaml:"keymanager,omitempty"`
@@ -94,16 +116,8 @@ type Config struct {
 func (c *Config) Validate() error {
 	var err error
 
-	switch c.Mode {
-	case ModeValidator:
-	case ModeCompute:
-	case ModeKeyManager:
-	case ModeClient:
-	case ModeStatelessClient:
-	case ModeSeed:
-	case ModeArchive:
-	default:
-		return fmt.Errorf("unknown node mode: %s", c.Mode)
+	if err = c.Mode.Validate(); err != nil {
+		return err
 	}
 
 	if err = c.Common.Validate(); err != nil {
@@ -130,6 +144,9 @@ func (c *Config) Validate() error {
 	if err = c.Storage.Validate(); err != nil {
 		return fmt.Errorf("storage: %w", err)
 	}
+	if len(c.Storage.Mirror.Runtimes) > 0 && c.Mode != ModeClient && c.Mode != ModeArchive {
+		return fmt.Errorf("storage: mirrored runtimes are only supported in client and archive modes")
+	}
 	if err = c.Sentry.Validate(); err != nil {
 		return fmt.Errorf("sentry: %w", err)
 	}
@@ -142,6 +159,15 @@ func (c *Config) Validate() error {
 	if err = c.Metrics.Validate(); err != nil {
 		return fmt.Errorf("metrics: %w", err)
 	}
+	if err = c.Upgrade.Validate(); err != nil {
+		return fmt.Errorf("upgrade: %w", err)
+	}
+	if err = c.Invariants.Validate(); err != nil {
+		return fmt.Errorf("invariants: %w", err)
+	}
+	if c.Mode == ModeValidator && c.Invariants.CheckInterval > 0 {
+		return fmt.Errorf("invariants: periodic checks are not supported in validator mode")
+	}
 
 	return nil
 }
@@ -162,26 +188,44 @@ func DefaultConfig() Config {
 		IAS:          ias.DefaultConfig(),
 		Pprof:        pprof.DefaultConfig(),
 		Metrics:      metrics.DefaultConfig(),
+		Upgrade:      upgrade.DefaultConfig(),
+		Invariants:   invariants.DefaultConfig(),
+	}
+}
+
+// Parse parses the given configuration file contents on top of the default configuration
+// settings, after substituting environment variables.
+//
+// An error is reported if any of the fields from the input are unknown. The parsed configuration
+// is not validated.
+func Parse(raw []byte) (*Config, error) {
+	raw, err := envsubst.Bytes(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to substitute environment variables: %w", err)
 	}
+
+	cfg := DefaultConfig()
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err = dec.Decode(&cfg); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &cfg, nil
 }
 
 // InitConfig initializes the global configuration from the given file.
 func InitConfig(cfgFile string) error {
-	// Read the specified config file and substitute environment variables.
-	cfg, err := envsubst.ReadFile(cfgFile)
+	raw, err := os.ReadFile(cfgFile)
 	if err != nil {
 		return fmt.Errorf("unable to read config file '%s': %w", cfgFile, err)
 	}
 
 	// Reset the global config and apply changes from the config file.
-	// Report error if any of the fields from the input file are unknown.
-	GlobalConfig = DefaultConfig()
-	dec := yaml.NewDecoder(bytes.NewReader(cfg))
-	dec.KnownFields(true)
-	err = dec.Decode(&GlobalConfig)
-	if err != nil && err != io.EOF {
+	cfg, err := Parse(raw)
+	if err != nil {
 		return fmt.Errorf("failed to load config file '%s': %w", cfgFile, err)
 	}
+	GlobalConfig = *cfg
 
 	// Validate config file.
 	return GlobalConfig.Validate()
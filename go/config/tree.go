@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tree returns the flattened configuration tree, mapping dotted paths of all set fields (as used
+// in the configuration file, e.g., "common.log.level.default") to their values.
+func (c *Config) Tree() (map[string]string, error) {
+	raw, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	var doc yaml.Node
+	if err = yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	tree := make(map[string]string)
+	flattenNode(tree, "", &doc)
+	return tree, nil
+}
+
+func flattenNode(tree map[string]string, path string, n *yaml.Node) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			flattenNode(tree, path, c)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			flattenNode(tree, joinPath(path, n.Content[i].Value), n.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		for i, c := range n.Content {
+			flattenNode(tree, joinPath(path, strconv.Itoa(i)), c)
+		}
+	case yaml.AliasNode:
+		flattenNode(tree, path, n.Alias)
+	default:
+		tree[path] = n.Value
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Section returns the top-level section of the given configuration tree path.
+func Section(path string) string {
+	section, _, _ := strings.Cut(path, ".")
+	return section
+}
+
+// ValidateSection validates the settings of the given top-level section in isolation.
+//
+// Checks spanning multiple sections are only performed by Validate.
+func (c *Config) ValidateSection(section string) error {
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		if name != section {
+			continue
+		}
+		validator, ok := v.Field(i).Addr().Interface().(interface{ Validate() error })
+		if !ok {
+			return nil
+		}
+		if err := validator.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", section, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown config section: %s", section)
+}
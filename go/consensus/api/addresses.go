@@ -0,0 +1,16 @@
+package api
+
+import (
+	"context"
+
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+)
+
+// AddressWatcher is implemented by consensus services that can notify about changes of the
+// consensus addresses of the local node.
+type AddressWatcher interface {
+	// WatchAddresses returns a channel that produces the consensus addresses of the local node
+	// each time they change.
+	WatchAddresses(ctx context.Context) (<-chan []node.ConsensusAddress, pubsub.ClosableSubscription, error)
+}
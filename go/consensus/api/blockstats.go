@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+
+	"github.com/oasisprotocol/oasis-core/go/common/errors"
+)
+
+// blockStatsModuleName is the module name used for block statistics errors.
+const blockStatsModuleName = "consensus/blockstats"
+
+// MaxBlockStatsRange is the maximum number of heights that can be queried in a single block
+// statistics range query.
+const MaxBlockStatsRange = 1000
+
+var (
+	// ErrBlockStatsNotFound is the error returned when block statistics for the given height are
+	// not available, either because the height has not been executed yet or because its
+	// statistics are no longer retained.
+	ErrBlockStatsNotFound = errors.New(blockStatsModuleName, 1, "consensus: block statistics not found")
+
+	// ErrInvalidBlockStatsRange is the error returned when a block statistics range query is
+	// malformed or exceeds MaxBlockStatsRange.
+	ErrInvalidBlockStatsRange = errors.New(blockStatsModuleName, 2, "consensus: invalid block statistics range")
+)
+
+// BlockStats are the resource usage statistics of a single executed block.
+type BlockStats struct {
+	// Height is the block height.
+	Height int64 `json:"height"`
+
+	// GasUsed is the total amount of gas used by all transactions in the block.
+	GasUsed uint64 `json:"gas_used"`
+
+	// TxCount is the number of transactions in the block.
+	TxCount uint64 `json:"tx_count"`
+
+	// EventCount is the number of events emitted during block execution.
+	EventCount uint64 `json:"event_count"`
+
+	// StateGrowth is the number of bytes written to consensus state storage when committing the
+	// block.
+	StateGrowth int64 `json:"state_growth"`
+}
+
+// BlockStatsRangeQuery is a block statistics range query.
+type BlockStatsRangeQuery struct {
+	// StartHeight is the first height (inclusive).
+	StartHeight int64 `json:"start_height"`
+
+	// EndHeight is the last height (inclusive).
+	EndHeight int64 `json:"end_height"`
+}
+
+// Validate validates the range query.
+func (q *BlockStatsRangeQuery) Validate() error {
+	if q.StartHeight <= 0 || q.EndHeight < q.StartHeight {
+		return ErrInvalidBlockStatsRange
+	}
+	if q.EndHeight-q.StartHeight >= MaxBlockStatsRange {
+		return ErrInvalidBlockStatsRange
+	}
+	return nil
+}
+
+// BlockStatsBackend is a backend that provides per-block resource usage statistics.
+type BlockStatsBackend interface {
+	// GetBlockStats returns the resource usage statistics of the block at the given height.
+	//
+	// In case the statistics are not available, ErrBlockStatsNotFound is returned.
+	GetBlockStats(ctx context.Context, height int64) (*BlockStats, error)
+
+	// GetBlockStatsRange returns the resource usage statistics of all retained blocks in the
+	// given height range, ordered by height.
+	GetBlockStatsRange(ctx context.Context, query *BlockStatsRangeQuery) ([]*BlockStats, error)
+}
@@ -0,0 +1,204 @@
+// Package eventfilter implements typed event filters that are evaluated server-side.
+package eventfilter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common/errors"
+)
+
+// ModuleName is the event filter module name.
+const ModuleName = "consensus/eventfilter"
+
+const (
+	// MaxDepth is the maximum nesting depth of a filter.
+	MaxDepth = 4
+
+	// MaxConditions is the maximum total number of conditions in a filter.
+	MaxConditions = 32
+
+	// MaxScanHeights is the maximum number of heights scanned by a single filtered events query.
+	MaxScanHeights = 100
+)
+
+var (
+	// ErrInvalidFilter is the error returned when an event filter is malformed or exceeds the
+	// depth or condition limits.
+	ErrInvalidFilter = errors.New(ModuleName, 1, "eventfilter: invalid filter")
+
+	// ErrInvalidRange is the error returned when the height range of a filtered events query is
+	// malformed or exceeds MaxScanHeights.
+	ErrInvalidRange = errors.New(ModuleName, 2, "eventfilter: invalid height range")
+)
+
+// Comparator is a condition comparator.
+type Comparator string
+
+const (
+	// Equal matches values equal to the condition value.
+	Equal Comparator = "eq"
+	// NotEqual matches values not equal to the condition value.
+	NotEqual Comparator = "ne"
+	// Less matches values less than the condition value.
+	Less Comparator = "lt"
+	// LessOrEqual matches values less than or equal to the condition value.
+	LessOrEqual Comparator = "lte"
+	// Greater matches values greater than the condition value.
+	Greater Comparator = "gt"
+	// GreaterOrEqual matches values greater than or equal to the condition value.
+	GreaterOrEqual Comparator = "gte"
+)
+
+// IsEquality returns true iff the comparator only tests for (in)equality.
+func (c Comparator) IsEquality() bool {
+	return c == Equal || c == NotEqual
+}
+
+// Validate checks that the comparator is valid.
+func (c Comparator) Validate() error {
+	switch c {
+	case Equal, NotEqual, Less, LessOrEqual, Greater, GreaterOrEqual:
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown comparator '%s'", ErrInvalidFilter, c)
+	}
+}
+
+// Compare returns true iff the result of a three-way comparison of a value against the condition
+// value satisfies the comparator.
+func (c Comparator) Compare(cmp int) bool {
+	switch c {
+	case Equal:
+		return cmp == 0
+	case NotEqual:
+		return cmp != 0
+	case Less:
+		return cmp < 0
+	case LessOrEqual:
+		return cmp <= 0
+	case Greater:
+		return cmp > 0
+	case GreaterOrEqual:
+		return cmp >= 0
+	default:
+		return false
+	}
+}
+
+// Condition is a single typed condition on events of type E.
+type Condition[E any] interface {
+	// Validate checks that the condition is well-formed.
+	Validate() error
+
+	// Matches returns true iff the given event satisfies the condition.
+	Matches(ev E) bool
+}
+
+// Filter is a typed event filter matching events that satisfy all of its conditions and all of
+// its nested filters.
+type Filter[E any, C Condition[E]] struct {
+	// Conditions are the conditions that must all be satisfied.
+	Conditions []C `json:"conditions,omitempty"`
+
+	// And are the nested filters that must all be satisfied.
+	And []*Filter[E, C] `json:"and,omitempty"`
+}
+
+// Match creates a new filter matching events that satisfy all of the given conditions.
+func Match[E any, C Condition[E]](conditions ...C) *Filter[E, C] {
+	return &Filter[E, C]{Conditions: conditions}
+}
+
+// And creates a new filter matching events that satisfy all of the given filters.
+func And[E any, C Condition[E]](filters ...*Filter[E, C]) *Filter[E, C] {
+	return &Filter[E, C]{And: filters}
+}
+
+// Validate checks that the filter is well-formed and within the depth and condition limits.
+func (f *Filter[E, C]) Validate() error {
+	var conditions int
+	return f.validate(1, &conditions)
+}
+
+func (f *Filter[E, C]) validate(depth int, conditions *int) error {
+	if f == nil {
+		return fmt.Errorf("%w: missing filter", ErrInvalidFilter)
+	}
+	if depth > MaxDepth {
+		return fmt.Errorf("%w: filter nested deeper than %d", ErrInvalidFilter, MaxDepth)
+	}
+
+	*conditions += len(f.Conditions)
+	if *conditions > MaxConditions {
+		return fmt.Errorf("%w: more than %d conditions", ErrInvalidFilter, MaxConditions)
+	}
+	for _, cond := range f.Conditions {
+		if err := cond.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, nested := range f.And {
+		if err := nested.validate(depth+1, conditions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches returns true iff the given event satisfies the filter. A nil filter matches all events.
+func (f *Filter[E, C]) Matches(ev E) bool {
+	if f == nil {
+		return true
+	}
+	for _, cond := range f.Conditions {
+		if !cond.Matches(ev) {
+			return false
+		}
+	}
+	for _, nested := range f.And {
+		if !nested.Matches(ev) {
+			return false
+		}
+	}
+	return true
+}
+
+// Scan validates the given filter and height range, then returns the events at heights in the
+// range that match the filter.
+//
+// Events are fetched one height at a time using the given function, so only matching events are
+// retained. The range is inclusive and may span at most MaxScanHeights heights.
+func Scan[E any, C Condition[E]](
+	ctx context.Context,
+	startHeight int64,
+	endHeight int64,
+	filter *Filter[E, C],
+	getEvents func(ctx context.Context, height int64) ([]E, error),
+) ([]E, error) {
+	if startHeight <= 0 || endHeight < startHeight {
+		return nil, fmt.Errorf("%w: [%d, %d]", ErrInvalidRange, startHeight, endHeight)
+	}
+	if endHeight-startHeight >= MaxScanHeights {
+		return nil, fmt.Errorf("%w: more than %d heights", ErrInvalidRange, MaxScanHeights)
+	}
+	if filter != nil {
+		if err := filter.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var matching []E
+	for height := startHeight; height <= endHeight; height++ {
+		evs, err := getEvents(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		for _, ev := range evs {
+			if filter.Matches(ev) {
+				matching = append(matching, ev)
+			}
+		}
+	}
+	return matching, nil
+}
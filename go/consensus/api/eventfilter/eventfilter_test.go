@@ -0,0 +1,101 @@
+package eventfilter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// intCondition is a condition on integer events.
+type intCondition struct {
+	Comparator Comparator
+	Value      int
+}
+
+func (c intCondition) Validate() error {
+	return c.Comparator.Validate()
+}
+
+func (c intCondition) Matches(ev int) bool {
+	switch {
+	case ev < c.Value:
+		return c.Comparator.Compare(-1)
+	case ev > c.Value:
+		return c.Comparator.Compare(1)
+	default:
+		return c.Comparator.Compare(0)
+	}
+}
+
+type intFilter = Filter[int, intCondition]
+
+func TestFilterValidate(t *testing.T) {
+	require := require.New(t)
+
+	cond := intCondition{Comparator: Equal, Value: 1}
+
+	// Nesting limits.
+	filter := Match[int](cond)
+	for range MaxDepth - 1 {
+		filter = And(filter)
+	}
+	require.NoError(filter.Validate(), "filter at maximum depth should be valid")
+	require.ErrorIs(And(filter).Validate(), ErrInvalidFilter, "filter exceeding maximum depth should be invalid")
+
+	// Condition limits, counted across nested filters.
+	conds := make([]intCondition, MaxConditions)
+	for i := range conds {
+		conds[i] = cond
+	}
+	require.NoError(Match[int](conds...).Validate(), "filter with maximum conditions should be valid")
+	require.ErrorIs(And(Match[int](conds...), Match[int](cond)).Validate(), ErrInvalidFilter,
+		"filter exceeding maximum conditions should be invalid",
+	)
+
+	// Invalid conditions and missing nested filters.
+	require.ErrorIs(Match[int](intCondition{Comparator: "like"}).Validate(), ErrInvalidFilter)
+	require.ErrorIs(And[int, intCondition](nil).Validate(), ErrInvalidFilter)
+}
+
+func TestScan(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// Events at each height are the height and its multiple of ten.
+	var scanned []int64
+	getEvents := func(_ context.Context, height int64) ([]int, error) {
+		scanned = append(scanned, height)
+		return []int{int(height), int(height) * 10}, nil
+	}
+
+	filter := And(
+		Match[int](intCondition{Comparator: GreaterOrEqual, Value: 3}),
+		Match[int](intCondition{Comparator: Less, Value: 40}),
+	)
+	evs, err := Scan(ctx, 1, 5, filter, getEvents)
+	require.NoError(err, "Scan")
+	require.Equal([]int{10, 20, 3, 30, 4, 5}, evs, "matching events should be returned in order")
+	require.Equal([]int64{1, 2, 3, 4, 5}, scanned)
+
+	evs, err = Scan[int, intCondition](ctx, 2, 2, nil, getEvents)
+	require.NoError(err, "Scan without filter")
+	require.Equal([]int{2, 20}, evs)
+
+	for _, tc := range []struct {
+		start, end int64
+	}{
+		{0, 1},
+		{5, 4},
+		{1, MaxScanHeights + 1},
+	} {
+		_, err = Scan(ctx, tc.start, tc.end, filter, getEvents)
+		require.ErrorIs(err, ErrInvalidRange, fmt.Sprintf("range [%d, %d] should be invalid", tc.start, tc.end))
+	}
+	_, err = Scan(ctx, 1, MaxScanHeights, filter, getEvents)
+	require.NoError(err, "range spanning maximum heights should be valid")
+
+	_, err = Scan(ctx, 1, 1, &intFilter{Conditions: []intCondition{{Comparator: "like"}}}, getEvents)
+	require.ErrorIs(err, ErrInvalidFilter)
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+)
+
+var (
+	// blockStatsServiceName is the gRPC service name.
+	blockStatsServiceName = cmnGrpc.NewServiceName("Consensus.BlockStats")
+
+	// methodGetBlockStats is the GetBlockStats method.
+	methodGetBlockStats = blockStatsServiceName.NewMethod("GetBlockStats", int64(0))
+	// methodGetBlockStatsRange is the GetBlockStatsRange method.
+	methodGetBlockStatsRange = blockStatsServiceName.NewMethod("GetBlockStatsRange", BlockStatsRangeQuery{})
+
+	// blockStatsServiceDesc is the gRPC service descriptor.
+	blockStatsServiceDesc = grpc.ServiceDesc{
+		ServiceName: string(blockStatsServiceName),
+		HandlerType: (*BlockStatsBackend)(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: methodGetBlockStats.ShortName(),
+				Handler:    handlerGetBlockStats,
+			},
+			{
+				MethodName: methodGetBlockStatsRange.ShortName(),
+				Handler:    handlerGetBlockStatsRange,
+			},
+		},
+		Streams: []grpc.StreamDesc{},
+	}
+)
+
+func handlerGetBlockStats(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var height int64
+	if err := dec(&height); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockStatsBackend).GetBlockStats(ctx, height)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetBlockStats.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(BlockStatsBackend).GetBlockStats(ctx, req.(int64))
+	}
+	return interceptor(ctx, height, info, handler)
+}
+
+func handlerGetBlockStatsRange(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var query BlockStatsRangeQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockStatsBackend).GetBlockStatsRange(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetBlockStatsRange.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(BlockStatsBackend).GetBlockStatsRange(ctx, req.(*BlockStatsRangeQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
+// RegisterBlockStatsService registers a new block statistics service with the given gRPC server.
+func RegisterBlockStatsService(server *grpc.Server, service BlockStatsBackend) {
+	server.RegisterService(&blockStatsServiceDesc, service)
+}
+
+// BlockStatsClient is a gRPC block statistics client.
+type BlockStatsClient struct {
+	conn *grpc.ClientConn
+}
+
+// NewBlockStatsClient creates a new gRPC block statistics client.
+func NewBlockStatsClient(c *grpc.ClientConn) *BlockStatsClient {
+	return &BlockStatsClient{c}
+}
+
+func (c *BlockStatsClient) GetBlockStats(ctx context.Context, height int64) (*BlockStats, error) {
+	var rsp BlockStats
+	if err := c.conn.Invoke(ctx, methodGetBlockStats.FullName(), height, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *BlockStatsClient) GetBlockStatsRange(ctx context.Context, query *BlockStatsRangeQuery) ([]*BlockStats, error) {
+	var rsp []*BlockStats
+	if err := c.conn.Invoke(ctx, methodGetBlockStatsRange.FullName(), query, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
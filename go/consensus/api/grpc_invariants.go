@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+)
+
+var (
+	// invariantsServiceName is the gRPC service name.
+	invariantsServiceName = cmnGrpc.NewServiceName("Consensus.Invariants")
+
+	// methodRunInvariantChecks is the RunInvariantChecks method.
+	methodRunInvariantChecks = invariantsServiceName.NewMethod("RunInvariantChecks", RunInvariantChecksRequest{})
+
+	// invariantsServiceDesc is the gRPC service descriptor.
+	invariantsServiceDesc = grpc.ServiceDesc{
+		ServiceName: string(invariantsServiceName),
+		HandlerType: (*InvariantsBackend)(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: methodRunInvariantChecks.ShortName(),
+				Handler:    handlerRunInvariantChecks,
+			},
+		},
+		Streams: []grpc.StreamDesc{},
+	}
+)
+
+func handlerRunInvariantChecks(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var request RunInvariantChecksRequest
+	if err := dec(&request); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(InvariantsBackend).RunInvariantChecks(ctx, &request)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodRunInvariantChecks.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(InvariantsBackend).RunInvariantChecks(ctx, req.(*RunInvariantChecksRequest))
+	}
+	return interceptor(ctx, &request, info, handler)
+}
+
+// RegisterInvariantsService registers a new invariant checker service with the given gRPC server.
+func RegisterInvariantsService(server *grpc.Server, service InvariantsBackend) {
+	server.RegisterService(&invariantsServiceDesc, service)
+}
+
+// InvariantsClient is a gRPC invariant checker client.
+type InvariantsClient struct {
+	conn *grpc.ClientConn
+}
+
+// NewInvariantsClient creates a new gRPC invariant checker client.
+func NewInvariantsClient(c *grpc.ClientConn) *InvariantsClient {
+	return &InvariantsClient{c}
+}
+
+func (c *InvariantsClient) RunInvariantChecks(ctx context.Context, request *RunInvariantChecksRequest) (*InvariantCheckResults, error) {
+	var rsp InvariantCheckResults
+	if err := c.conn.Invoke(ctx, methodRunInvariantChecks.FullName(), request, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
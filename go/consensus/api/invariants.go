@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+
+	"github.com/oasisprotocol/oasis-core/go/common/errors"
+)
+
+// invariantsModuleName is the module name used for invariant checker errors.
+const invariantsModuleName = "consensus/invariants"
+
+var (
+	// ErrInvariantChecksDisabled is the error returned when on-demand invariant checks are
+	// requested on a node where they are not enabled.
+	ErrInvariantChecksDisabled = errors.New(invariantsModuleName, 1, "consensus: invariant checks disabled")
+
+	// ErrUnknownInvariantModule is the error returned when invariant checks are requested for a
+	// module that has no registered invariants.
+	ErrUnknownInvariantModule = errors.New(invariantsModuleName, 2, "consensus: unknown invariant module")
+)
+
+// RunInvariantChecksRequest is a RunInvariantChecks request.
+type RunInvariantChecksRequest struct {
+	// Height is the consensus height at which the invariants are checked.
+	Height int64 `json:"height"`
+
+	// Modules are the consensus modules whose invariants are checked. If empty, the invariants
+	// of all modules are checked.
+	Modules []string `json:"modules,omitempty"`
+}
+
+// InvariantResult is the result of checking a single invariant.
+type InvariantResult struct {
+	// Module is the name of the consensus module.
+	Module string `json:"module"`
+
+	// Name is the name of the invariant.
+	Name string `json:"name"`
+
+	// Passed is true iff the invariant holds.
+	Passed bool `json:"passed"`
+
+	// Details describes the violation in case the invariant does not hold.
+	Details string `json:"details,omitempty"`
+}
+
+// InvariantCheckResults are the results of checking invariants at a given height.
+type InvariantCheckResults struct {
+	// Height is the consensus height at which the invariants have been checked.
+	Height int64 `json:"height"`
+
+	// Results are the results of individual invariants, ordered by module and invariant name.
+	Results []InvariantResult `json:"results"`
+}
+
+// Failed returns the results of all invariants that do not hold.
+func (r *InvariantCheckResults) Failed() []InvariantResult {
+	var failed []InvariantResult
+	for _, res := range r.Results {
+		if !res.Passed {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// InvariantsBackend is a backend that checks invariants of consensus module state.
+type InvariantsBackend interface {
+	// RunInvariantChecks checks the invariants of the requested modules against the consensus
+	// state at the given height without modifying it.
+	//
+	// This is a debug-only query and ErrInvariantChecksDisabled is returned unless it has been
+	// explicitly enabled on the node.
+	RunInvariantChecks(ctx context.Context, request *RunInvariantChecksRequest) (*InvariantCheckResults, error)
+}
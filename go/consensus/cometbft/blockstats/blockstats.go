@@ -0,0 +1,237 @@
+// Package blockstats implements per-block resource usage accounting.
+package blockstats
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+)
+
+// DefaultRetention is the default number of most recent heights for which block statistics are
+// retained.
+const DefaultRetention = 100_000
+
+// keyPrefix is the prefix of block statistics keys in the database.
+var keyPrefix = []byte("blockstats/")
+
+var (
+	blockGasUsed = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_consensus_block_gas_used",
+			Help: "Gas used by transactions in the latest block.",
+		},
+	)
+	blockTxs = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_consensus_block_txs",
+			Help: "Number of transactions in the latest block.",
+		},
+	)
+	blockEvents = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_consensus_block_events",
+			Help: "Number of events emitted in the latest block.",
+		},
+	)
+	blockStateGrowth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_consensus_block_state_growth_bytes",
+			Help: "Bytes written to consensus state storage by the latest block.",
+		},
+	)
+
+	blockStatsCollectors = []prometheus.Collector{
+		blockGasUsed,
+		blockTxs,
+		blockEvents,
+		blockStateGrowth,
+	}
+
+	metricsOnce sync.Once
+
+	_ consensus.BlockStatsBackend = (*Store)(nil)
+)
+
+// Accumulator accumulates resource usage of a block while it is being executed.
+type Accumulator struct {
+	sync.Mutex
+
+	stats consensus.BlockStats
+}
+
+// RecordTx records an executed transaction that used the given amount of gas.
+func (a *Accumulator) RecordTx(gasUsed uint64) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.stats.TxCount++
+	a.stats.GasUsed += gasUsed
+}
+
+// RecordEvents records the given number of emitted events.
+func (a *Accumulator) RecordEvents(n int) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.stats.EventCount += uint64(n) // nolint: gosec
+}
+
+// RecordStateGrowth records the given number of bytes written to consensus state storage, as
+// reported by the size of the committed storage batch.
+func (a *Accumulator) RecordStateGrowth(bytes int64) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.stats.StateGrowth += bytes
+}
+
+// Finish returns the statistics accumulated for the block at the given height and resets the
+// accumulator for the next block.
+func (a *Accumulator) Finish(height int64) *consensus.BlockStats {
+	a.Lock()
+	defer a.Unlock()
+
+	stats := a.stats
+	stats.Height = height
+	a.stats = consensus.BlockStats{}
+	return &stats
+}
+
+// record is the compact serialized form of block statistics.
+type record struct {
+	_ struct{} `cbor:",toarray"` // nolint
+
+	GasUsed     uint64
+	TxCount     uint64
+	EventCount  uint64
+	StateGrowth int64
+}
+
+// Store is a block statistics store which retains the statistics of a bounded number of most
+// recent heights.
+type Store struct {
+	db        dbm.DB
+	retention int64
+}
+
+// NewStore creates a new block statistics store backed by the given database, retaining the
+// statistics of the given number of most recent heights. If zero, DefaultRetention is used.
+func NewStore(db dbm.DB, retention uint64) *Store {
+	metricsOnce.Do(func() {
+		prometheus.MustRegister(blockStatsCollectors...)
+	})
+
+	if retention == 0 {
+		retention = DefaultRetention
+	}
+	return &Store{
+		db:        db,
+		retention: int64(retention), // nolint: gosec
+	}
+}
+
+func heightKey(height int64) []byte {
+	key := make([]byte, len(keyPrefix)+8)
+	copy(key, keyPrefix)
+	binary.BigEndian.PutUint64(key[len(keyPrefix):], uint64(height)) // nolint: gosec
+	return key
+}
+
+func decodeRecord(height int64, data []byte) (*consensus.BlockStats, error) {
+	var rec record
+	if err := cbor.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("blockstats: corrupted record at height %d: %w", height, err)
+	}
+	return &consensus.BlockStats{
+		Height:      height,
+		GasUsed:     rec.GasUsed,
+		TxCount:     rec.TxCount,
+		EventCount:  rec.EventCount,
+		StateGrowth: rec.StateGrowth,
+	}, nil
+}
+
+// Put stores the statistics of an executed block, removes statistics that are no longer retained
+// and updates the latest block metrics.
+func (s *Store) Put(stats *consensus.BlockStats) error {
+	batch := s.db.NewBatch()
+	defer batch.Close()
+
+	rec := record{
+		GasUsed:     stats.GasUsed,
+		TxCount:     stats.TxCount,
+		EventCount:  stats.EventCount,
+		StateGrowth: stats.StateGrowth,
+	}
+	if err := batch.Set(heightKey(stats.Height), cbor.Marshal(&rec)); err != nil {
+		return fmt.Errorf("blockstats: failed to store record: %w", err)
+	}
+	if expired := stats.Height - s.retention; expired > 0 {
+		if err := batch.Delete(heightKey(expired)); err != nil {
+			return fmt.Errorf("blockstats: failed to remove expired record: %w", err)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return fmt.Errorf("blockstats: failed to write batch: %w", err)
+	}
+
+	blockGasUsed.Set(float64(stats.GasUsed))
+	blockTxs.Set(float64(stats.TxCount))
+	blockEvents.Set(float64(stats.EventCount))
+	blockStateGrowth.Set(float64(stats.StateGrowth))
+
+	return nil
+}
+
+// GetBlockStats implements consensus.BlockStatsBackend.
+func (s *Store) GetBlockStats(_ context.Context, height int64) (*consensus.BlockStats, error) {
+	if height <= 0 {
+		return nil, consensus.ErrBlockStatsNotFound
+	}
+	data, err := s.db.Get(heightKey(height))
+	if err != nil {
+		return nil, fmt.Errorf("blockstats: failed to get record: %w", err)
+	}
+	if data == nil {
+		return nil, consensus.ErrBlockStatsNotFound
+	}
+	return decodeRecord(height, data)
+}
+
+// GetBlockStatsRange implements consensus.BlockStatsBackend.
+func (s *Store) GetBlockStatsRange(ctx context.Context, query *consensus.BlockStatsRangeQuery) ([]*consensus.BlockStats, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	it, err := s.db.Iterator(heightKey(query.StartHeight), heightKey(query.EndHeight+1))
+	if err != nil {
+		return nil, fmt.Errorf("blockstats: failed to create iterator: %w", err)
+	}
+	defer it.Close()
+
+	var stats []*consensus.BlockStats
+	for ; it.Valid(); it.Next() {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		height := int64(binary.BigEndian.Uint64(it.Key()[len(keyPrefix):])) // nolint: gosec
+		bs, err := decodeRecord(height, it.Value())
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, bs)
+	}
+	if err = it.Error(); err != nil {
+		return nil, fmt.Errorf("blockstats: iterator failed: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,74 @@
+package blockstats
+
+import (
+	"context"
+	"testing"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+)
+
+func TestBlockStats(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	store := NewStore(dbm.NewMemDB(), 3)
+	var acc Accumulator
+
+	// Execute synthetic blocks with an increasing number of transactions.
+	for height := int64(1); height <= 5; height++ {
+		for tx := int64(0); tx < height; tx++ {
+			acc.RecordTx(100)
+			acc.RecordEvents(2)
+		}
+		acc.RecordStateGrowth(height * 1024)
+
+		err := store.Put(acc.Finish(height))
+		require.NoError(err, "Put")
+	}
+
+	// Accumulator should be reset after each block.
+	require.Equal(&consensus.BlockStats{Height: 6}, acc.Finish(6))
+
+	stats, err := store.GetBlockStats(ctx, 5)
+	require.NoError(err, "GetBlockStats")
+	require.Equal(&consensus.BlockStats{
+		Height:      5,
+		GasUsed:     500,
+		TxCount:     5,
+		EventCount:  10,
+		StateGrowth: 5 * 1024,
+	}, stats)
+
+	// Latest block metrics should be updated.
+	require.EqualValues(500, testutil.ToFloat64(blockGasUsed))
+	require.EqualValues(5, testutil.ToFloat64(blockTxs))
+	require.EqualValues(10, testutil.ToFloat64(blockEvents))
+	require.EqualValues(5*1024, testutil.ToFloat64(blockStateGrowth))
+
+	// Only the most recent heights should be retained.
+	_, err = store.GetBlockStats(ctx, 2)
+	require.ErrorIs(err, consensus.ErrBlockStatsNotFound)
+	_, err = store.GetBlockStats(ctx, 6)
+	require.ErrorIs(err, consensus.ErrBlockStatsNotFound)
+
+	rangeStats, err := store.GetBlockStatsRange(ctx, &consensus.BlockStatsRangeQuery{StartHeight: 1, EndHeight: 10})
+	require.NoError(err, "GetBlockStatsRange")
+	require.Len(rangeStats, 3)
+	for i, bs := range rangeStats {
+		height := int64(i + 3)
+		require.Equal(height, bs.Height)
+		require.EqualValues(height, bs.TxCount)
+		require.EqualValues(height*100, bs.GasUsed)
+		require.EqualValues(height*2, bs.EventCount)
+		require.EqualValues(height*1024, bs.StateGrowth)
+	}
+
+	_, err = store.GetBlockStatsRange(ctx, &consensus.BlockStatsRangeQuery{StartHeight: 5, EndHeight: 4})
+	require.ErrorIs(err, consensus.ErrInvalidBlockStatsRange)
+	_, err = store.GetBlockStatsRange(ctx, &consensus.BlockStatsRangeQuery{StartHeight: 1, EndHeight: consensus.MaxBlockStatsRange + 1})
+	require.ErrorIs(err, consensus.ErrInvalidBlockStatsRange)
+}
@@ -0,0 +1,23 @@
+// Package config implements global configuration options.
+package config
+
+// Config is the consensus state invariant checker configuration structure.
+type Config struct {
+	// CheckInterval is the number of blocks between periodic checks of all consensus state
+	// invariants (zero means that periodic checks are disabled).
+	//
+	// Periodic checks are only supported on non-validator nodes.
+	CheckInterval uint64 `yaml:"check_interval,omitempty"`
+}
+
+// Validate validates the configuration settings.
+func (c *Config) Validate() error {
+	return nil
+}
+
+// DefaultConfig returns the default configuration settings.
+func DefaultConfig() Config {
+	return Config{
+		CheckInterval: 0,
+	}
+}
@@ -0,0 +1,250 @@
+// Package invariants implements checking of consensus module state invariants.
+package invariants
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+var (
+	invariantViolations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_consensus_invariant_violations",
+			Help: "Number of consensus state invariant violations detected by periodic checks.",
+		},
+		[]string{"module", "invariant"},
+	)
+	invariantCheckHeight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_consensus_invariant_check_height",
+			Help: "Height of the latest periodic consensus state invariant check.",
+		},
+	)
+
+	invariantCollectors = []prometheus.Collector{
+		invariantViolations,
+		invariantCheckHeight,
+	}
+
+	metricsOnce sync.Once
+
+	_ consensus.InvariantsBackend = (*Checker)(nil)
+)
+
+// Invariant is a named invariant over consensus module state of type S.
+type Invariant[S any] struct {
+	// Name is the name of the invariant.
+	Name string
+
+	// Check returns an error describing the violation in case the invariant does not hold.
+	Check func(state S) error
+}
+
+// module is a consensus module with registered invariants.
+type module struct {
+	check func(ctx context.Context, height int64) ([]consensus.InvariantResult, error)
+}
+
+// Checker checks registered invariants of consensus module state.
+type Checker struct {
+	sync.RWMutex
+
+	logger *logging.Logger
+
+	queriesEnabled bool
+	modules        map[string]*module
+}
+
+// New creates a new invariant checker.
+//
+// On-demand invariant checks via RunInvariantChecks are only served when queriesEnabled is set,
+// while periodic checks via Monitor are always available.
+func New(queriesEnabled bool) *Checker {
+	metricsOnce.Do(func() {
+		prometheus.MustRegister(invariantCollectors...)
+	})
+
+	return &Checker{
+		logger:         logging.GetLogger("consensus/cometbft/invariants"),
+		queriesEnabled: queriesEnabled,
+		modules:        make(map[string]*module),
+	}
+}
+
+// Register registers the given invariants of a consensus module. The module state is loaded
+// read-only at the checked height via the given function once per check.
+//
+// Registering invariants of an already registered module replaces them.
+func Register[S any](
+	c *Checker,
+	name string,
+	load func(ctx context.Context, height int64) (S, error),
+	invariants ...Invariant[S],
+) {
+	invariants = slices.Clone(invariants)
+	slices.SortFunc(invariants, func(a, b Invariant[S]) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	m := &module{
+		check: func(ctx context.Context, height int64) ([]consensus.InvariantResult, error) {
+			state, err := load(ctx, height)
+			if err != nil {
+				return nil, fmt.Errorf("invariants: failed to load %s state at height %d: %w", name, height, err)
+			}
+
+			results := make([]consensus.InvariantResult, 0, len(invariants))
+			for _, inv := range invariants {
+				res := consensus.InvariantResult{
+					Module: name,
+					Name:   inv.Name,
+					Passed: true,
+				}
+				if err = inv.Check(state); err != nil {
+					res.Passed = false
+					res.Details = err.Error()
+				}
+				results = append(results, res)
+			}
+			return results, nil
+		},
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.modules[name] = m
+}
+
+// RegisterStaking registers the staking invariants over the state returned by the given
+// function, usually the staking backend's StateToGenesis.
+func RegisterStaking(c *Checker, load func(ctx context.Context, height int64) (*staking.Genesis, error)) {
+	Register(c, staking.ModuleName, load,
+		Invariant[*staking.Genesis]{Name: "total_supply", Check: (*staking.Genesis).CheckSupplyInvariant},
+		Invariant[*staking.Genesis]{Name: "escrow_shares", Check: (*staking.Genesis).CheckEscrowSharesInvariant},
+	)
+}
+
+// RegisterRegistry registers the registry invariants over the state returned by the given
+// function, usually the registry backend's StateToGenesis.
+func RegisterRegistry(c *Checker, load func(ctx context.Context, height int64) (*registry.Genesis, error)) {
+	Register(c, registry.ModuleName, load,
+		Invariant[*registry.Genesis]{Name: "referential_integrity", Check: (*registry.Genesis).CheckReferentialIntegrity},
+	)
+}
+
+// Modules returns the sorted names of all modules with registered invariants.
+func (c *Checker) Modules() []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	names := make([]string, 0, len(c.modules))
+	for name := range c.modules {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Check checks the invariants of the given modules (or all modules if none are given) against
+// the consensus state at the given height.
+func (c *Checker) Check(ctx context.Context, height int64, modules []string) (*consensus.InvariantCheckResults, error) {
+	if len(modules) == 0 {
+		modules = c.Modules()
+	} else {
+		modules = slices.Clone(modules)
+		slices.Sort(modules)
+		modules = slices.Compact(modules)
+	}
+
+	c.RLock()
+	checks := make([]*module, 0, len(modules))
+	for _, name := range modules {
+		m, ok := c.modules[name]
+		if !ok {
+			c.RUnlock()
+			return nil, fmt.Errorf("%w: %s", consensus.ErrUnknownInvariantModule, name)
+		}
+		checks = append(checks, m)
+	}
+	c.RUnlock()
+
+	results := &consensus.InvariantCheckResults{
+		Height:  height,
+		Results: []consensus.InvariantResult{},
+	}
+	for _, m := range checks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		res, err := m.check(ctx, height)
+		if err != nil {
+			return nil, err
+		}
+		results.Results = append(results.Results, res...)
+	}
+	return results, nil
+}
+
+// RunInvariantChecks implements consensus.InvariantsBackend.
+func (c *Checker) RunInvariantChecks(ctx context.Context, request *consensus.RunInvariantChecksRequest) (*consensus.InvariantCheckResults, error) {
+	if !c.queriesEnabled {
+		return nil, consensus.ErrInvariantChecksDisabled
+	}
+	return c.Check(ctx, request.Height, request.Modules)
+}
+
+// Monitor checks the invariants of all modules at every interval-th height received from the
+// given channel and raises alerts on violations, until the context is canceled or the channel
+// is closed.
+//
+// Violations are reported via error logs and the oasis_consensus_invariant_violations metric.
+func (c *Checker) Monitor(ctx context.Context, interval uint64, heights <-chan int64) {
+	if interval == 0 {
+		return
+	}
+
+	for {
+		var height int64
+		select {
+		case <-ctx.Done():
+			return
+		case h, ok := <-heights:
+			if !ok {
+				return
+			}
+			height = h
+		}
+		if height <= 0 || uint64(height)%interval != 0 { // nolint: gosec
+			continue
+		}
+
+		results, err := c.Check(ctx, height, nil)
+		if err != nil {
+			c.logger.Error("failed to check invariants",
+				"err", err,
+				"height", height,
+			)
+			continue
+		}
+		invariantCheckHeight.Set(float64(height))
+
+		for _, res := range results.Failed() {
+			c.logger.Error("consensus state invariant violated",
+				"height", height,
+				"module", res.Module,
+				"invariant", res.Name,
+				"details", res.Details,
+			)
+			invariantViolations.WithLabelValues(res.Module, res.Name).Inc()
+		}
+	}
+}
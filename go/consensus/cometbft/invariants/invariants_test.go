@@ -0,0 +1,110 @@
+package invariants
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+func newStakingState() *staking.Genesis {
+	delegator := staking.NewModuleAddress("test", "delegator")
+	escrow := staking.NewModuleAddress("test", "escrow")
+
+	var delegatorAcct, escrowAcct staking.Account
+	delegatorAcct.General.Balance = *quantity.NewFromUint64(600)
+	escrowAcct.Escrow.Active.Balance = *quantity.NewFromUint64(300)
+	escrowAcct.Escrow.Active.TotalShares = *quantity.NewFromUint64(30)
+
+	return &staking.Genesis{
+		TotalSupply: *quantity.NewFromUint64(1000),
+		CommonPool:  *quantity.NewFromUint64(100),
+		Ledger: map[staking.Address]*staking.Account{
+			delegator: &delegatorAcct,
+			escrow:    &escrowAcct,
+		},
+		Delegations: map[staking.Address]map[staking.Address]*staking.Delegation{
+			escrow: {
+				delegator: {Shares: *quantity.NewFromUint64(30)},
+			},
+		},
+	}
+}
+
+func TestInvariants(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	stakingState := newStakingState()
+	var loadedHeight int64
+	c := New(true)
+	RegisterStaking(c, func(_ context.Context, height int64) (*staking.Genesis, error) {
+		loadedHeight = height
+		return stakingState, nil
+	})
+	RegisterRegistry(c, func(context.Context, int64) (*registry.Genesis, error) {
+		return &registry.Genesis{}, nil
+	})
+	require.Equal([]string{registry.ModuleName, staking.ModuleName}, c.Modules())
+
+	// Consistent state passes all invariants.
+	results, err := c.RunInvariantChecks(ctx, &consensus.RunInvariantChecksRequest{Height: 10})
+	require.NoError(err, "RunInvariantChecks")
+	require.EqualValues(10, results.Height)
+	require.EqualValues(10, loadedHeight, "state should be loaded at the requested height")
+	require.Equal([]consensus.InvariantResult{
+		{Module: registry.ModuleName, Name: "referential_integrity", Passed: true},
+		{Module: staking.ModuleName, Name: "escrow_shares", Passed: true},
+		{Module: staking.ModuleName, Name: "total_supply", Passed: true},
+	}, results.Results)
+	require.Empty(results.Failed())
+
+	// Corrupting the total supply trips exactly one invariant.
+	stakingState.TotalSupply = *quantity.NewFromUint64(999)
+	results, err = c.RunInvariantChecks(ctx, &consensus.RunInvariantChecksRequest{
+		Height:  11,
+		Modules: []string{staking.ModuleName},
+	})
+	require.NoError(err, "RunInvariantChecks")
+	require.Len(results.Results, 2)
+	failed := results.Failed()
+	require.Len(failed, 1)
+	require.Equal(staking.ModuleName, failed[0].Module)
+	require.Equal("total_supply", failed[0].Name)
+	require.NotEmpty(failed[0].Details)
+
+	_, err = c.RunInvariantChecks(ctx, &consensus.RunInvariantChecksRequest{
+		Height:  11,
+		Modules: []string{"unknown"},
+	})
+	require.ErrorIs(err, consensus.ErrUnknownInvariantModule)
+
+	// On-demand checks can be disabled.
+	_, err = New(false).RunInvariantChecks(ctx, &consensus.RunInvariantChecksRequest{Height: 10})
+	require.ErrorIs(err, consensus.ErrInvariantChecksDisabled)
+
+	// Periodic checks only run at every interval-th height and report violations.
+	violations := invariantViolations.WithLabelValues(staking.ModuleName, "total_supply")
+	before := testutil.ToFloat64(violations)
+
+	heights := make(chan int64)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Monitor(ctx, 2, heights)
+	}()
+	for height := int64(1); height <= 5; height++ {
+		heights <- height
+	}
+	close(heights)
+	<-done
+
+	require.EqualValues(before+2, testutil.ToFloat64(violations))
+	require.EqualValues(4, testutil.ToFloat64(invariantCheckHeight))
+}
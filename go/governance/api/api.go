@@ -38,6 +38,9 @@ var (
 	ErrNotEligible = errors.New(ModuleName, 6, "governance: not eligible")
 	// ErrVotingIsClosed is the error returned when a vote is cast for a non-active proposal.
 	ErrVotingIsClosed = errors.New(ModuleName, 7, "governance: voting is closed")
+	// ErrParameterChangeNotActive is the error returned when a parameter change is executed
+	// before its activation epoch.
+	ErrParameterChangeNotActive = errors.New(ModuleName, 8, "governance: parameter change not yet active")
 
 	// MethodSubmitProposal submits a new consensus layer governance proposal.
 	MethodSubmitProposal = transaction.NewMethodName(ModuleName, "SubmitProposal", ProposalContent{})
@@ -54,6 +57,7 @@ var (
 	_ prettyprint.PrettyPrinter = (*UpgradeProposal)(nil)
 	_ prettyprint.PrettyPrinter = (*CancelUpgradeProposal)(nil)
 	_ prettyprint.PrettyPrinter = (*ChangeParametersProposal)(nil)
+	_ prettyprint.PrettyPrinter = (*AdminActionProposal)(nil)
 	_ prettyprint.PrettyPrinter = (*ProposalVote)(nil)
 )
 
@@ -65,6 +69,7 @@ type ProposalContent struct {
 	Upgrade          *UpgradeProposal          `json:"upgrade,omitempty"`
 	CancelUpgrade    *CancelUpgradeProposal    `json:"cancel_upgrade,omitempty"`
 	ChangeParameters *ChangeParametersProposal `json:"change_parameters,omitempty"`
+	AdminAction      *AdminActionProposal      `json:"admin_action,omitempty"`
 }
 
 // ValidateBasic performs basic proposal content validity checks.
@@ -95,6 +100,9 @@ func (p *ProposalContent) ValidateBasic(params *ConsensusParameters) error {
 	if p.ChangeParameters != nil {
 		numProposals++
 	}
+	if p.AdminAction != nil {
+		numProposals++
+	}
 
 	switch {
 	case numProposals > 1:
@@ -109,6 +117,10 @@ func (p *ProposalContent) ValidateBasic(params *ConsensusParameters) error {
 		if err := p.ChangeParameters.ValidateBasic(); err != nil {
 			return fmt.Errorf("change parameters proposal validation failed: %w", err)
 		}
+	case p.AdminAction != nil:
+		if err := p.AdminAction.ValidateBasic(); err != nil {
+			return fmt.Errorf("admin action proposal validation failed: %w", err)
+		}
 	default:
 		return fmt.Errorf("proposal content has no fields set")
 	}
@@ -136,6 +148,9 @@ func (p *ProposalContent) Equals(other *ProposalContent) bool {
 	if !p.ChangeParameters.Equals(other.ChangeParameters) {
 		return false
 	}
+	if !p.AdminAction.Equals(other.AdminAction) {
+		return false
+	}
 	return true
 }
 
@@ -157,6 +172,10 @@ func (p ProposalContent) PrettyPrint(ctx context.Context, prefix string, w io.Wr
 		fmt.Fprintf(w, "%sChange Parameters:\n", prefix)
 		p.ChangeParameters.PrettyPrint(ctx, prefix+"  ", w)
 	}
+	if p.AdminAction != nil {
+		fmt.Fprintf(w, "%sAdmin Action:\n", prefix)
+		p.AdminAction.PrettyPrint(ctx, prefix+"  ", w)
+	}
 }
 
 // PrettyType returns a representation of ProposalContent that can be used for
@@ -332,6 +351,69 @@ func (p *ChangeParametersProposal) ValidateBasic() error {
 	return nil
 }
 
+// AdminActionProposal is a consensus administrative action proposal.
+type AdminActionProposal struct {
+	// Module identifies the consensus backend module which should execute the action.
+	Module string `json:"module"`
+	// Method identifies the administrative action within the module.
+	Method string `json:"method"`
+	// Action is the module-specific administrative action.
+	Action cbor.RawMessage `json:"action"`
+}
+
+// Equals checks if admin action proposals are equal.
+func (p *AdminActionProposal) Equals(other *AdminActionProposal) bool {
+	if p == other {
+		return true
+	}
+	if p == nil || other == nil {
+		return false
+	}
+	if p.Module != other.Module || p.Method != other.Method {
+		return false
+	}
+	if !bytes.Equal(p.Action, other.Action) {
+		return false
+	}
+	return true
+}
+
+// PrettyPrint writes a pretty-printed representation of AdminActionProposal to the given writer.
+func (p *AdminActionProposal) PrettyPrint(_ context.Context, prefix string, w io.Writer) {
+	fmt.Fprintf(w, "%sModule: %s\n", prefix, p.Module)
+	fmt.Fprintf(w, "%sMethod: %s\n", prefix, p.Method)
+	var action map[string]any
+	if err := cbor.Unmarshal(p.Action, &action); err != nil {
+		fmt.Fprintf(w, "%s  <error: %s>\n", prefix, err)
+		fmt.Fprintf(w, "%s  <malformed: %s>\n", prefix, base64.StdEncoding.EncodeToString(p.Action))
+		return
+	}
+	fmt.Fprintf(w, "%sAction: \n", prefix)
+	for field, value := range action {
+		fmt.Fprintf(w, "%s  - %s: %v\n", prefix, field, value)
+	}
+}
+
+// PrettyType returns a representation of AdminActionProposal that can be used for pretty
+// printing.
+func (p *AdminActionProposal) PrettyType() (any, error) {
+	return p, nil
+}
+
+// ValidateBasic performs a basic validation on the admin action proposal.
+func (p *AdminActionProposal) ValidateBasic() error {
+	if len(p.Module) == 0 {
+		return fmt.Errorf("invalid module name: name should not be empty")
+	}
+	if len(p.Method) == 0 {
+		return fmt.Errorf("invalid method name: name should not be empty")
+	}
+	if len(p.Action) == 0 {
+		return fmt.Errorf("invalid action: action should not be empty")
+	}
+	return nil
+}
+
 // ProposalVote is a vote for a proposal.
 type ProposalVote struct {
 	// ID is the unique identifier of a proposal.
@@ -381,6 +463,11 @@ type Backend interface {
 
 	// WatchEvents returns a channel that produces a stream of Events.
 	WatchEvents(ctx context.Context) (<-chan *Event, pubsub.ClosableSubscription, error)
+
+	// PreviewParameterChange validates the given change parameters proposal against the
+	// consensus parameters at the specified block height and returns the resulting effective
+	// consensus parameters without changing anything.
+	PreviewParameterChange(ctx context.Context, query *PreviewParameterChangeQuery) (*ParameterChangePreview, error)
 }
 
 // ProposalQuery is a proposal query.
@@ -507,6 +594,7 @@ type Event struct {
 	ProposalExecuted  *ProposalExecutedEvent  `json:"proposal_executed,omitempty"`
 	ProposalFinalized *ProposalFinalizedEvent `json:"proposal_finalized,omitempty"`
 	Vote              *VoteEvent              `json:"vote,omitempty"`
+	ParametersChanged *ParametersChangedEvent `json:"parameters_changed,omitempty"`
 }
 
 // ProposalSubmittedEvent is the event emitted when a new proposal is submitted.
@@ -561,6 +649,23 @@ func (e *VoteEvent) EventKind() string {
 	return "vote"
 }
 
+// ParametersChangedEvent is the event emitted when consensus parameter changes take effect.
+type ParametersChangedEvent struct {
+	// ID is the unique identifier of the executed proposal.
+	ID uint64 `json:"id"`
+	// Module is the consensus module whose parameters changed.
+	Module string `json:"module"`
+	// Epoch is the epoch at which the changes took effect.
+	Epoch beacon.EpochTime `json:"epoch"`
+	// Changes are the changed parameters with their old and new values.
+	Changes []*ParameterChange `json:"changes,omitempty"`
+}
+
+// EventKind returns a string representation of this event's kind.
+func (e *ParametersChangedEvent) EventKind() string {
+	return "parameters_changed"
+}
+
 // NewSubmitProposalTx creates a new submit proposal transaction.
 func NewSubmitProposalTx(nonce uint64, fee *transaction.Fee, proposal *ProposalContent) *transaction.Transaction {
 	return transaction.NewTransaction(nonce, fee, MethodSubmitProposal, proposal)
@@ -197,6 +197,18 @@ func TestProposalContentPrettyPrint(t *testing.T) {
 				},
 			},
 		},
+		{
+			expRegex: "^Admin Action:",
+			p: &ProposalContent{
+				AdminAction: &AdminActionProposal{
+					Module: "test-module",
+					Method: "TestAction",
+					Action: cbor.Marshal(map[string]string{
+						"test-field": "test-value",
+					}),
+				},
+			},
+		},
 	} {
 		var actualPrettyPrint bytes.Buffer
 		tc.p.PrettyPrint(context.Background(), "", &actualPrettyPrint)
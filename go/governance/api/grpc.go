@@ -30,6 +30,8 @@ var (
 	methodConsensusParameters = serviceName.NewMethod("ConsensusParameters", int64(0))
 	// methodGetEvents is the GetEvents method.
 	methodGetEvents = serviceName.NewMethod("GetEvents", int64(0))
+	// methodPreviewParameterChange is the PreviewParameterChange method.
+	methodPreviewParameterChange = serviceName.NewMethod("PreviewParameterChange", PreviewParameterChangeQuery{})
 
 	// methodWatchEvents is the WatchEvents method.
 	methodWatchEvents = serviceName.NewMethod("WatchEvents", nil)
@@ -71,6 +73,10 @@ var (
 				MethodName: methodGetEvents.ShortName(),
 				Handler:    handlerGetEvents,
 			},
+			{
+				MethodName: methodPreviewParameterChange.ShortName(),
+				Handler:    handlerPreviewParameterChange,
+			},
 		},
 		Streams: []grpc.StreamDesc{
 			{
@@ -266,6 +272,29 @@ func handlerGetEvents(
 	return interceptor(ctx, height, info, handler)
 }
 
+func handlerPreviewParameterChange(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var query PreviewParameterChangeQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).PreviewParameterChange(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodPreviewParameterChange.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(Backend).PreviewParameterChange(ctx, req.(*PreviewParameterChangeQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
 func handlerWatchEvents(srv any, stream grpc.ServerStream) error {
 	if err := stream.RecvMsg(nil); err != nil {
 		return err
@@ -374,6 +403,14 @@ func (c *Client) GetEvents(ctx context.Context, height int64) ([]*Event, error)
 	return rsp, nil
 }
 
+func (c *Client) PreviewParameterChange(ctx context.Context, query *PreviewParameterChangeQuery) (*ParameterChangePreview, error) {
+	var rsp ParameterChangePreview
+	if err := c.conn.Invoke(ctx, methodPreviewParameterChange.FullName(), query, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
 func (c *Client) WatchEvents(ctx context.Context) (<-chan *Event, pubsub.ClosableSubscription, error) {
 	ctx, sub := pubsub.NewContextSubscription(ctx)
 
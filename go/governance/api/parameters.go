@@ -0,0 +1,177 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+// ParameterChanges are typed consensus parameter changes of a consensus module whose consensus
+// parameters are of type P.
+type ParameterChanges[P any] interface {
+	// SanityCheck performs a sanity check on the consensus parameter changes.
+	SanityCheck() error
+
+	// Apply applies changes to the given consensus parameters.
+	Apply(params *P) error
+}
+
+// Parameters are consensus parameters of a consensus module.
+type Parameters[P any] interface {
+	*P
+
+	// CheckInvariants checks that the consensus parameters are sane and satisfy the invariants
+	// that consensus parameter changes must preserve.
+	CheckInvariants() error
+}
+
+// ParameterChange is a change of a single consensus parameter.
+type ParameterChange struct {
+	// Parameter is the name of the changed parameter.
+	Parameter string `json:"parameter"`
+	// OldValue is the CBOR-encoded value of the parameter before the change.
+	OldValue cbor.RawMessage `json:"old_value,omitempty"`
+	// NewValue is the CBOR-encoded value of the parameter after the change.
+	NewValue cbor.RawMessage `json:"new_value,omitempty"`
+}
+
+// ParameterChangePreview is the outcome of a change parameters proposal if it were executed.
+type ParameterChangePreview struct {
+	// Module is the consensus module the changes apply to.
+	Module string `json:"module"`
+	// Parameters are the CBOR-encoded effective consensus parameters after the changes.
+	Parameters cbor.RawMessage `json:"parameters"`
+	// Changes are the parameters that would change.
+	Changes []*ParameterChange `json:"changes,omitempty"`
+}
+
+// PreviewParameterChangeQuery is a change parameters proposal preview query.
+type PreviewParameterChangeQuery struct {
+	Height   int64                    `json:"height"`
+	Proposal ChangeParametersProposal `json:"proposal"`
+}
+
+// NewChangeParametersProposal creates a new change parameters proposal for the given module from
+// typed consensus parameter changes.
+func NewChangeParametersProposal(module string, changes any) *ChangeParametersProposal {
+	return &ChangeParametersProposal{
+		Module:  module,
+		Changes: cbor.Marshal(changes),
+	}
+}
+
+// PreviewParameterChange decodes the typed consensus parameter changes of the given change
+// parameters proposal, validates them and applies them to a copy of the given consensus
+// parameters of the given module.
+//
+// The changes are rejected if the resulting consensus parameters violate any of the module's
+// invariants. The given consensus parameters are not modified. This is used to validate change
+// parameters proposals at submission time and to serve parameter change previews.
+func PreviewParameterChange[P, C any, PP Parameters[P], PC interface {
+	*C
+	ParameterChanges[P]
+}](module string, proposal *ChangeParametersProposal, params *P) (*P, *ParameterChangePreview, error) {
+	if proposal.Module != module {
+		return nil, nil, fmt.Errorf("%w: changes are for module '%s', not '%s'", ErrInvalidArgument, proposal.Module, module)
+	}
+
+	var changes C
+	if err := cbor.Unmarshal(proposal.Changes, &changes); err != nil {
+		return nil, nil, fmt.Errorf("%w: malformed %s parameter changes: %w", ErrInvalidArgument, module, err)
+	}
+	if err := PC(&changes).SanityCheck(); err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid %s parameter changes: %w", ErrInvalidArgument, module, err)
+	}
+
+	newParams := *params
+	if err := PC(&changes).Apply(&newParams); err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to apply %s parameter changes: %w", ErrInvalidArgument, module, err)
+	}
+	if err := PP(&newParams).CheckInvariants(); err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid %s parameters after changes: %w", ErrInvalidArgument, module, err)
+	}
+
+	parameterChanges, err := diffParameters(params, &newParams)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &newParams, &ParameterChangePreview{
+		Module:     module,
+		Parameters: cbor.Marshal(&newParams),
+		Changes:    parameterChanges,
+	}, nil
+}
+
+// ExecuteParameterChange executes a passed change parameters proposal of the given module at the
+// given epoch, returning the new effective consensus parameters and the event recording the
+// changed parameters.
+//
+// The changes take effect at the epoch at which the proposal closes. They are validated against
+// the consensus parameters in effect at that time, as those may differ from the ones at
+// submission time.
+func ExecuteParameterChange[P, C any, PP Parameters[P], PC interface {
+	*C
+	ParameterChanges[P]
+}](module string, proposal *Proposal, epoch beacon.EpochTime, params *P) (*P, *ParametersChangedEvent, error) {
+	if proposal.State != StatePassed {
+		return nil, nil, fmt.Errorf("%w: proposal %d not passed", ErrInvalidArgument, proposal.ID)
+	}
+	if proposal.Content.ChangeParameters == nil {
+		return nil, nil, fmt.Errorf("%w: proposal %d is not a change parameters proposal", ErrInvalidArgument, proposal.ID)
+	}
+	if epoch < proposal.ClosesAt {
+		return nil, nil, fmt.Errorf("%w: proposal %d activates at epoch %d", ErrParameterChangeNotActive, proposal.ID, proposal.ClosesAt)
+	}
+
+	newParams, preview, err := PreviewParameterChange[P, C, PP, PC](module, proposal.Content.ChangeParameters, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newParams, &ParametersChangedEvent{
+		ID:      proposal.ID,
+		Module:  module,
+		Epoch:   epoch,
+		Changes: preview.Changes,
+	}, nil
+}
+
+// diffParameters returns the top-level parameters that differ between the given consensus
+// parameters, ordered by parameter name.
+func diffParameters(oldParams, newParams any) ([]*ParameterChange, error) {
+	var oldFields, newFields map[string]cbor.RawMessage
+	if err := cbor.Unmarshal(cbor.Marshal(oldParams), &oldFields); err != nil {
+		return nil, fmt.Errorf("failed to decode old parameters: %w", err)
+	}
+	if err := cbor.Unmarshal(cbor.Marshal(newParams), &newFields); err != nil {
+		return nil, fmt.Errorf("failed to decode new parameters: %w", err)
+	}
+
+	var changes []*ParameterChange
+	for name, newValue := range newFields {
+		if oldValue := oldFields[name]; !bytes.Equal(oldValue, newValue) {
+			changes = append(changes, &ParameterChange{
+				Parameter: name,
+				OldValue:  oldValue,
+				NewValue:  newValue,
+			})
+		}
+	}
+	for name, oldValue := range oldFields {
+		// Parameters omitted after the change were reset to their zero value.
+		if _, ok := newFields[name]; !ok {
+			changes = append(changes, &ParameterChange{
+				Parameter: name,
+				OldValue:  oldValue,
+			})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Parameter < changes[j].Parameter
+	})
+	return changes, nil
+}
@@ -212,6 +212,23 @@ type Backend interface {
 
 	// WatchEphemeralSecrets returns a channel that produces a stream of ephemeral secrets.
 	WatchEphemeralSecrets(context.Context) (<-chan *SignedEncryptedEphemeralSecret, pubsub.ClosableSubscription, error)
+
+	// LintPolicy checks the given policy for likely mistakes against the registry state at the
+	// given block height.
+	LintPolicy(context.Context, *PolicyQuery) ([]Finding, error)
+
+	// DiffPolicy returns the changes that the given policy makes to the current policy of its
+	// key manager at the given block height.
+	DiffPolicy(context.Context, *PolicyQuery) ([]Change, error)
+}
+
+// PolicyQuery is a key manager policy review query.
+type PolicyQuery struct {
+	// Height is the block height at which the registry state and the current policy are queried.
+	Height int64 `json:"height"`
+
+	// Policy is the policy under review.
+	Policy PolicySGX `json:"policy"`
 }
 
 // NewUpdatePolicyTx creates a new policy update transaction.
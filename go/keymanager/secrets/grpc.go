@@ -24,6 +24,10 @@ var (
 	methodGetMasterSecret = serviceName.NewMethod("GetMasterSecret", registry.NamespaceQuery{})
 	// methodGetEphemeralSecret is the GetEphemeralSecret method.
 	methodGetEphemeralSecret = serviceName.NewMethod("GetEphemeralSecret", registry.NamespaceQuery{})
+	// methodLintPolicy is the LintPolicy method.
+	methodLintPolicy = serviceName.NewMethod("LintPolicy", PolicyQuery{})
+	// methodDiffPolicy is the DiffPolicy method.
+	methodDiffPolicy = serviceName.NewMethod("DiffPolicy", PolicyQuery{})
 
 	// methodWatchStatuses is the WatchStatuses method.
 	methodWatchStatuses = serviceName.NewMethod("WatchStatuses", nil)
@@ -57,6 +61,14 @@ var (
 				MethodName: methodGetEphemeralSecret.ShortName(),
 				Handler:    handlerGetEphemeralSecret,
 			},
+			{
+				MethodName: methodLintPolicy.ShortName(),
+				Handler:    handlerLintPolicy,
+			},
+			{
+				MethodName: methodDiffPolicy.ShortName(),
+				Handler:    handlerDiffPolicy,
+			},
 		},
 		Streams: []grpc.StreamDesc{
 			{
@@ -193,6 +205,52 @@ func handlerGetEphemeralSecret(
 	return interceptor(ctx, &query, info, handler)
 }
 
+func handlerLintPolicy(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var query PolicyQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).LintPolicy(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodLintPolicy.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(Backend).LintPolicy(ctx, req.(*PolicyQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
+func handlerDiffPolicy(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var query PolicyQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).DiffPolicy(ctx, &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodDiffPolicy.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(Backend).DiffPolicy(ctx, req.(*PolicyQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
 func handlerWatchStatuses(srv any, stream grpc.ServerStream) error {
 	if err := stream.RecvMsg(nil); err != nil {
 		return err
@@ -333,6 +391,22 @@ func (c *Client) GetEphemeralSecret(ctx context.Context, query *registry.Namespa
 	return resp, nil
 }
 
+func (c *Client) LintPolicy(ctx context.Context, query *PolicyQuery) ([]Finding, error) {
+	var resp []Finding
+	if err := c.conn.Invoke(ctx, methodLintPolicy.FullName(), query, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) DiffPolicy(ctx context.Context, query *PolicyQuery) ([]Change, error) {
+	var resp []Change
+	if err := c.conn.Invoke(ctx, methodDiffPolicy.FullName(), query, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (c *Client) WatchStatuses(ctx context.Context) (<-chan *Status, pubsub.ClosableSubscription, error) {
 	ctx, sub := pubsub.NewContextSubscription(ctx)
 
@@ -0,0 +1,453 @@
+package secrets
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/sgx"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+// FindingSeverity is the severity of a policy lint finding.
+type FindingSeverity string
+
+const (
+	// FindingWarning is a finding that most likely indicates a mistake, but does not grant access
+	// to key material beyond what was intended.
+	FindingWarning FindingSeverity = "warning"
+	// FindingCritical is a finding that may grant access to key material to enclaves that should
+	// not have it.
+	FindingCritical FindingSeverity = "critical"
+)
+
+// Policy lint finding codes.
+const (
+	// FindingNoEnclaves is reported when the policy does not authorize any key manager enclave.
+	FindingNoEnclaves = "no_enclaves"
+	// FindingUnknownKeyManager is reported when the key manager runtime is not registered.
+	FindingUnknownKeyManager = "unknown_key_manager"
+	// FindingMissingEnclave is reported when an enclave of a current deployment is not covered by
+	// the policy.
+	FindingMissingEnclave = "missing_enclave"
+	// FindingUnknownEnclave is reported when an enclave is not part of any deployment of the
+	// runtime it is associated with.
+	FindingUnknownEnclave = "unknown_enclave"
+	// FindingExpiredIdentity is reported when an enclave is only part of deployments that have
+	// been superseded.
+	FindingExpiredIdentity = "expired_identity"
+	// FindingBroadReplication is reported when an enclave that is not a key manager enclave may
+	// replicate the master secret.
+	FindingBroadReplication = "broad_replication"
+	// FindingUnknownRuntime is reported when query access is granted to a runtime that is not
+	// registered.
+	FindingUnknownRuntime = "unknown_runtime"
+	// FindingForeignRuntime is reported when query access is granted to a runtime that does not
+	// use the key manager.
+	FindingForeignRuntime = "foreign_runtime"
+	// FindingBroadGrant is reported when query access is granted to enclaves that are not part
+	// of any deployment of the runtime.
+	FindingBroadGrant = "broad_grant"
+	// FindingEmptyGrant is reported when query access is granted to a runtime without any
+	// enclaves.
+	FindingEmptyGrant = "empty_grant"
+)
+
+// Finding is a policy lint finding.
+type Finding struct {
+	// Severity is the severity of the finding.
+	Severity FindingSeverity `json:"severity"`
+
+	// Code is the machine-readable code of the finding.
+	Code string `json:"code"`
+
+	// Enclave is the key manager enclave whose policy the finding refers to (if any).
+	Enclave *sgx.EnclaveIdentity `json:"enclave,omitempty"`
+
+	// Runtime is the runtime the finding refers to (if any).
+	Runtime *common.Namespace `json:"runtime,omitempty"`
+
+	// Identity is the enclave identity the finding refers to (if any).
+	Identity *sgx.EnclaveIdentity `json:"identity,omitempty"`
+
+	// Message is the human-readable description of the finding.
+	Message string `json:"message"`
+}
+
+// String returns a string representation of the finding.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s [%s]: %s", f.Severity, f.Code, f.Message)
+}
+
+// PolicyRegistryState is the registry state against which policies are linted.
+type PolicyRegistryState struct {
+	// Epoch is the current epoch.
+	Epoch beacon.EpochTime
+
+	// Runtimes are the registered runtimes.
+	Runtimes []*registry.Runtime
+}
+
+func (s *PolicyRegistryState) runtime(id common.Namespace) *registry.Runtime {
+	if s == nil {
+		return nil
+	}
+	for _, rt := range s.Runtimes {
+		if rt.ID.Equal(&id) {
+			return rt
+		}
+	}
+	return nil
+}
+
+// enclaveSet is the set of enclave identities of a runtime, split into enclaves of the current
+// and future deployments and enclaves of superseded deployments.
+type enclaveSet struct {
+	current map[sgx.EnclaveIdentity]bool
+	expired map[sgx.EnclaveIdentity]bool
+}
+
+func newEnclaveSet(rt *registry.Runtime, epoch beacon.EpochTime) *enclaveSet {
+	es := &enclaveSet{
+		current: make(map[sgx.EnclaveIdentity]bool),
+		expired: make(map[sgx.EnclaveIdentity]bool),
+	}
+	if rt.TEEHardware != node.TEEHardwareIntelSGX {
+		return es
+	}
+
+	active := rt.ActiveDeployment(epoch)
+	for _, deployment := range rt.Deployments {
+		var cs node.SGXConstraints
+		if err := cbor.Unmarshal(deployment.TEE, &cs); err != nil {
+			continue
+		}
+
+		set := es.expired
+		if deployment.ValidFrom > epoch || deployment == active {
+			set = es.current
+		}
+		for _, enclave := range cs.Enclaves {
+			set[enclave] = true
+		}
+	}
+	for enclave := range es.current {
+		delete(es.expired, enclave)
+	}
+	return es
+}
+
+func (es *enclaveSet) sortedCurrent() []sgx.EnclaveIdentity {
+	return sortedEnclaves(es.current)
+}
+
+func sortedEnclaves(set map[sgx.EnclaveIdentity]bool) []sgx.EnclaveIdentity {
+	enclaves := make([]sgx.EnclaveIdentity, 0, len(set))
+	for enclave := range set {
+		enclaves = append(enclaves, enclave)
+	}
+	slices.SortFunc(enclaves, func(a, b sgx.EnclaveIdentity) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return enclaves
+}
+
+func sortedPolicyEnclaves[V any](m map[sgx.EnclaveIdentity]V) []sgx.EnclaveIdentity {
+	set := make(map[sgx.EnclaveIdentity]bool, len(m))
+	for enclave := range m {
+		set[enclave] = true
+	}
+	return sortedEnclaves(set)
+}
+
+func sortedRuntimes[V any](m map[common.Namespace]V) []common.Namespace {
+	ids := make([]common.Namespace, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b common.Namespace) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return ids
+}
+
+// LintPolicy checks the given key manager policy for likely mistakes.
+//
+// In case the registry state is nil, only checks that do not depend on it are performed. The
+// returned findings are ordered deterministically.
+func LintPolicy(policy *PolicySGX, state *PolicyRegistryState) []Finding {
+	var findings []Finding
+	report := func(severity FindingSeverity, code string, enclave *sgx.EnclaveIdentity, runtime *common.Namespace, identity *sgx.EnclaveIdentity, format string, args ...any) {
+		findings = append(findings, Finding{
+			Severity: severity,
+			Code:     code,
+			Enclave:  enclave,
+			Runtime:  runtime,
+			Identity: identity,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if len(policy.Enclaves) == 0 {
+		report(FindingCritical, FindingNoEnclaves, nil, nil, nil,
+			"policy does not authorize any key manager enclave",
+		)
+	}
+
+	// Key manager enclaves.
+	var kmEnclaves *enclaveSet
+	if state != nil {
+		kmID := policy.ID
+		switch km := state.runtime(kmID); km {
+		case nil:
+			report(FindingWarning, FindingUnknownKeyManager, nil, &kmID, nil,
+				"key manager runtime %s is not registered", kmID,
+			)
+		default:
+			kmEnclaves = newEnclaveSet(km, state.Epoch)
+			for _, enclave := range kmEnclaves.sortedCurrent() {
+				if _, ok := policy.Enclaves[enclave]; !ok {
+					report(FindingWarning, FindingMissingEnclave, nil, &kmID, &enclave,
+						"key manager enclave %s of a current deployment is not authorized", enclave,
+					)
+				}
+			}
+		}
+	}
+	checkIdentity := func(es *enclaveSet, severity FindingSeverity, code string, enclave *sgx.EnclaveIdentity, runtime *common.Namespace, identity sgx.EnclaveIdentity, what string) {
+		switch {
+		case es == nil:
+		case es.current[identity]:
+		case es.expired[identity]:
+			report(FindingWarning, FindingExpiredIdentity, enclave, runtime, &identity,
+				"%s %s is only part of superseded deployments", what, identity,
+			)
+		default:
+			report(severity, code, enclave, runtime, &identity,
+				"%s %s is not part of any deployment", what, identity,
+			)
+		}
+	}
+
+	for _, enclave := range sortedPolicyEnclaves(policy.Enclaves) {
+		kmID := policy.ID
+		checkIdentity(kmEnclaves, FindingWarning, FindingUnknownEnclave, nil, &kmID, enclave, "key manager enclave")
+
+		ep := policy.Enclaves[enclave]
+		if ep == nil {
+			continue
+		}
+
+		// Replication grants access to the master secret.
+		for _, identity := range ep.MayReplicate {
+			checkIdentity(kmEnclaves, FindingCritical, FindingBroadReplication, &enclave, &kmID, identity, "replicating enclave")
+		}
+
+		// Query grants.
+		for _, rtID := range sortedRuntimes(ep.MayQuery) {
+			identities := ep.MayQuery[rtID]
+			if len(identities) == 0 {
+				report(FindingWarning, FindingEmptyGrant, &enclave, &rtID, nil,
+					"runtime %s is granted query access without any enclaves", rtID,
+				)
+			}
+			if state == nil {
+				continue
+			}
+
+			rt := state.runtime(rtID)
+			if rt == nil {
+				report(FindingCritical, FindingUnknownRuntime, &enclave, &rtID, nil,
+					"runtime %s granted query access is not registered", rtID,
+				)
+				continue
+			}
+			if rt.KeyManager == nil || !rt.KeyManager.Equal(&policy.ID) {
+				report(FindingCritical, FindingForeignRuntime, &enclave, &rtID, nil,
+					"runtime %s granted query access does not use key manager %s", rtID, policy.ID,
+				)
+			}
+
+			rtEnclaves := newEnclaveSet(rt, state.Epoch)
+			granted := make(map[sgx.EnclaveIdentity]bool, len(identities))
+			for _, identity := range identities {
+				granted[identity] = true
+				checkIdentity(rtEnclaves, FindingCritical, FindingBroadGrant, &enclave, &rtID, identity, "querying enclave")
+			}
+			for _, identity := range rtEnclaves.sortedCurrent() {
+				if !granted[identity] {
+					report(FindingWarning, FindingMissingEnclave, &enclave, &rtID, &identity,
+						"enclave %s of a current deployment of runtime %s is not granted query access", identity, rtID,
+					)
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+// ChangeKind is the kind of a policy change.
+type ChangeKind string
+
+const (
+	// ChangeAdded is an addition.
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved is a removal.
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeModified is a modification of a value.
+	ChangeModified ChangeKind = "modified"
+)
+
+// Policy fields that can change.
+const (
+	PolicyFieldSerial                       = "serial"
+	PolicyFieldID                           = "id"
+	PolicyFieldMasterSecretRotationInterval = "master_secret_rotation_interval"
+	PolicyFieldMaxEphemeralSecretAge        = "max_ephemeral_secret_age"
+	PolicyFieldEnclaves                     = "enclaves"
+	PolicyFieldMayReplicate                 = "may_replicate"
+	PolicyFieldMayQuery                     = "may_query"
+)
+
+// Change is a single change between two key manager policies.
+type Change struct {
+	// Kind is the kind of the change.
+	Kind ChangeKind `json:"kind"`
+
+	// Field is the policy field that changed.
+	Field string `json:"field"`
+
+	// Enclave is the key manager enclave whose policy changed (if any).
+	Enclave *sgx.EnclaveIdentity `json:"enclave,omitempty"`
+
+	// Runtime is the runtime whose query access changed (if any).
+	Runtime *common.Namespace `json:"runtime,omitempty"`
+
+	// Identity is the enclave identity that was added or removed (if any).
+	Identity *sgx.EnclaveIdentity `json:"identity,omitempty"`
+
+	// Old is the old value of a modified field.
+	Old string `json:"old,omitempty"`
+
+	// New is the new value of a modified field.
+	New string `json:"new,omitempty"`
+}
+
+// String returns a string representation of the change.
+func (c Change) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", c.Kind, c.Field)
+	if c.Enclave != nil {
+		fmt.Fprintf(&b, " enclave=%s", c.Enclave)
+	}
+	if c.Runtime != nil {
+		fmt.Fprintf(&b, " runtime=%s", c.Runtime)
+	}
+	if c.Identity != nil {
+		fmt.Fprintf(&b, " identity=%s", c.Identity)
+	}
+	if c.Kind == ChangeModified {
+		fmt.Fprintf(&b, ": %s -> %s", c.Old, c.New)
+	}
+	return b.String()
+}
+
+// DiffPolicies returns the list of changes that turn the old policy into the new one.
+//
+// A nil old policy is treated as an empty policy. The returned changes are ordered
+// deterministically.
+func DiffPolicies(oldPol, newPol *PolicySGX) []Change {
+	if oldPol == nil {
+		oldPol = &PolicySGX{}
+	}
+
+	var changes []Change
+	modified := func(field string, o, n any) {
+		if o == n {
+			return
+		}
+		changes = append(changes, Change{
+			Kind:  ChangeModified,
+			Field: field,
+			Old:   fmt.Sprintf("%v", o),
+			New:   fmt.Sprintf("%v", n),
+		})
+	}
+	modified(PolicyFieldSerial, oldPol.Serial, newPol.Serial)
+	modified(PolicyFieldID, oldPol.ID.String(), newPol.ID.String())
+	modified(PolicyFieldMasterSecretRotationInterval, oldPol.MasterSecretRotationInterval, newPol.MasterSecretRotationInterval)
+	modified(PolicyFieldMaxEphemeralSecretAge, oldPol.MaxEphemeralSecretAge, newPol.MaxEphemeralSecretAge)
+
+	diffIdentities := func(field string, enclave *sgx.EnclaveIdentity, runtime *common.Namespace, o, n []sgx.EnclaveIdentity) {
+		oldSet := make(map[sgx.EnclaveIdentity]bool, len(o))
+		for _, identity := range o {
+			oldSet[identity] = true
+		}
+		newSet := make(map[sgx.EnclaveIdentity]bool, len(n))
+		for _, identity := range n {
+			newSet[identity] = true
+		}
+		for _, identity := range sortedEnclaves(oldSet) {
+			if !newSet[identity] {
+				changes = append(changes, Change{Kind: ChangeRemoved, Field: field, Enclave: enclave, Runtime: runtime, Identity: &identity})
+			}
+		}
+		for _, identity := range sortedEnclaves(newSet) {
+			if !oldSet[identity] {
+				changes = append(changes, Change{Kind: ChangeAdded, Field: field, Enclave: enclave, Runtime: runtime, Identity: &identity})
+			}
+		}
+	}
+
+	enclaves := make(map[sgx.EnclaveIdentity]bool)
+	for enclave := range oldPol.Enclaves {
+		enclaves[enclave] = true
+	}
+	for enclave := range newPol.Enclaves {
+		enclaves[enclave] = true
+	}
+	for _, enclave := range sortedEnclaves(enclaves) {
+		oldEp, oldOk := oldPol.Enclaves[enclave]
+		newEp, newOk := newPol.Enclaves[enclave]
+		switch {
+		case !oldOk:
+			changes = append(changes, Change{Kind: ChangeAdded, Field: PolicyFieldEnclaves, Identity: &enclave})
+		case !newOk:
+			changes = append(changes, Change{Kind: ChangeRemoved, Field: PolicyFieldEnclaves, Identity: &enclave})
+		}
+		if oldEp == nil {
+			oldEp = &EnclavePolicySGX{}
+		}
+		if newEp == nil {
+			newEp = &EnclavePolicySGX{}
+		}
+
+		diffIdentities(PolicyFieldMayReplicate, &enclave, nil, oldEp.MayReplicate, newEp.MayReplicate)
+
+		runtimes := make(map[common.Namespace]bool)
+		for rtID := range oldEp.MayQuery {
+			runtimes[rtID] = true
+		}
+		for rtID := range newEp.MayQuery {
+			runtimes[rtID] = true
+		}
+		for _, rtID := range sortedRuntimes(runtimes) {
+			oldIDs, oldOk := oldEp.MayQuery[rtID]
+			newIDs, newOk := newEp.MayQuery[rtID]
+			switch {
+			case !oldOk:
+				changes = append(changes, Change{Kind: ChangeAdded, Field: PolicyFieldMayQuery, Enclave: &enclave, Runtime: &rtID})
+			case !newOk:
+				changes = append(changes, Change{Kind: ChangeRemoved, Field: PolicyFieldMayQuery, Enclave: &enclave, Runtime: &rtID})
+			}
+			diffIdentities(PolicyFieldMayQuery, &enclave, &rtID, oldIDs, newIDs)
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,197 @@
+package secrets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/sgx"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+func testDeployment(validFrom beacon.EpochTime, enclaves ...sgx.EnclaveIdentity) *registry.VersionInfo {
+	return &registry.VersionInfo{
+		Version:   version.Version{Major: uint16(validFrom)}, // nolint: gosec
+		ValidFrom: validFrom,
+		TEE: cbor.Marshal(node.SGXConstraints{
+			Enclaves: enclaves,
+		}),
+	}
+}
+
+func testEnclave(b byte) sgx.EnclaveIdentity {
+	return sgx.EnclaveIdentity{MrEnclave: sgx.MrEnclave{b}, MrSigner: sgx.MrSigner{b}}
+}
+
+func findingCodes(findings []Finding) map[string]FindingSeverity {
+	codes := make(map[string]FindingSeverity)
+	for _, f := range findings {
+		codes[f.Code] = f.Severity
+	}
+	return codes
+}
+
+func TestLintPolicy(t *testing.T) {
+	require := require.New(t)
+
+	kmID := common.NewTestNamespaceFromSeed([]byte("km runtime"), common.NamespaceKeyManager)
+	rtID := common.NewTestNamespaceFromSeed([]byte("compute runtime"), 0)
+	otherID := common.NewTestNamespaceFromSeed([]byte("other runtime"), 0)
+	unknownID := common.NewTestNamespaceFromSeed([]byte("unknown runtime"), 0)
+
+	kmOld, kmNew := testEnclave(1), testEnclave(2)
+	rtOld, rtNew := testEnclave(3), testEnclave(4)
+	otherEnclave, rogueEnclave := testEnclave(5), testEnclave(6)
+
+	state := &PolicyRegistryState{
+		Epoch: 10,
+		Runtimes: []*registry.Runtime{
+			{
+				ID:          kmID,
+				Kind:        registry.KindKeyManager,
+				TEEHardware: node.TEEHardwareIntelSGX,
+				Deployments: []*registry.VersionInfo{testDeployment(1, kmOld), testDeployment(5, kmNew)},
+			},
+			{
+				ID:          rtID,
+				Kind:        registry.KindCompute,
+				TEEHardware: node.TEEHardwareIntelSGX,
+				KeyManager:  &kmID,
+				Deployments: []*registry.VersionInfo{testDeployment(1, rtOld), testDeployment(5, rtNew)},
+			},
+			{
+				ID:          otherID,
+				Kind:        registry.KindCompute,
+				TEEHardware: node.TEEHardwareIntelSGX,
+				Deployments: []*registry.VersionInfo{testDeployment(1, otherEnclave)},
+			},
+		},
+	}
+
+	policy := &PolicySGX{
+		Serial: 1,
+		ID:     kmID,
+		Enclaves: map[sgx.EnclaveIdentity]*EnclavePolicySGX{
+			kmNew: {
+				MayQuery: map[common.Namespace][]sgx.EnclaveIdentity{
+					rtID: {rtNew},
+				},
+				MayReplicate: []sgx.EnclaveIdentity{kmNew},
+			},
+		},
+	}
+	require.Empty(LintPolicy(policy, state), "policy matching the registry state should be clean")
+
+	// A policy that silently widens access to long-term keys.
+	widened := &PolicySGX{
+		Serial: 2,
+		ID:     kmID,
+		Enclaves: map[sgx.EnclaveIdentity]*EnclavePolicySGX{
+			kmNew: {
+				MayQuery: map[common.Namespace][]sgx.EnclaveIdentity{
+					rtID:    {rtNew, rogueEnclave},
+					otherID: {otherEnclave},
+				},
+				MayReplicate: []sgx.EnclaveIdentity{kmNew},
+			},
+		},
+	}
+	findings := LintPolicy(widened, state)
+	codes := findingCodes(findings)
+	require.Equal(FindingCritical, codes[FindingBroadGrant], "unknown enclave granted access should be reported")
+	require.Equal(FindingCritical, codes[FindingForeignRuntime], "runtime not using the key manager should be reported")
+	for _, f := range findings {
+		if f.Code == FindingBroadGrant {
+			require.Equal(rtID, *f.Runtime)
+			require.Equal(rogueEnclave, *f.Identity)
+			require.Equal(kmNew, *f.Enclave)
+		}
+	}
+	require.Equal(findings, LintPolicy(widened, state), "findings should be deterministic")
+
+	// Replication, expired and missing identities.
+	broken := &PolicySGX{
+		Serial: 3,
+		ID:     kmID,
+		Enclaves: map[sgx.EnclaveIdentity]*EnclavePolicySGX{
+			kmOld: {
+				MayQuery: map[common.Namespace][]sgx.EnclaveIdentity{
+					rtID:      {rtOld},
+					unknownID: {},
+				},
+				MayReplicate: []sgx.EnclaveIdentity{rogueEnclave},
+			},
+		},
+	}
+	codes = findingCodes(LintPolicy(broken, state))
+	require.Equal(FindingCritical, codes[FindingBroadReplication])
+	require.Equal(FindingWarning, codes[FindingExpiredIdentity])
+	require.Equal(FindingWarning, codes[FindingMissingEnclave])
+	require.Equal(FindingCritical, codes[FindingUnknownRuntime])
+	require.Equal(FindingWarning, codes[FindingEmptyGrant])
+
+	// Without registry state only structural checks are performed.
+	codes = findingCodes(LintPolicy(&PolicySGX{ID: kmID}, nil))
+	require.Equal(map[string]FindingSeverity{FindingNoEnclaves: FindingCritical}, codes)
+}
+
+func TestDiffPolicies(t *testing.T) {
+	require := require.New(t)
+
+	kmID := common.NewTestNamespaceFromSeed([]byte("km runtime"), common.NamespaceKeyManager)
+	rtID := common.NewTestNamespaceFromSeed([]byte("compute runtime"), 0)
+	otherID := common.NewTestNamespaceFromSeed([]byte("other runtime"), 0)
+	kmEnclave, rtEnclave, otherEnclave, newKmEnclave := testEnclave(1), testEnclave(2), testEnclave(3), testEnclave(4)
+
+	oldPol := &PolicySGX{
+		Serial: 1,
+		ID:     kmID,
+		Enclaves: map[sgx.EnclaveIdentity]*EnclavePolicySGX{
+			kmEnclave: {
+				MayQuery: map[common.Namespace][]sgx.EnclaveIdentity{
+					rtID: {rtEnclave},
+				},
+			},
+		},
+	}
+	newPol := &PolicySGX{
+		Serial:                       2,
+		ID:                           kmID,
+		MasterSecretRotationInterval: 10,
+		Enclaves: map[sgx.EnclaveIdentity]*EnclavePolicySGX{
+			kmEnclave: {
+				MayQuery: map[common.Namespace][]sgx.EnclaveIdentity{
+					rtID:    {rtEnclave},
+					otherID: {otherEnclave},
+				},
+				MayReplicate: []sgx.EnclaveIdentity{newKmEnclave},
+			},
+			newKmEnclave: {},
+		},
+	}
+
+	require.Empty(DiffPolicies(oldPol, oldPol), "identical policies should have no changes")
+
+	changes := DiffPolicies(oldPol, newPol)
+	require.Contains(changes, Change{Kind: ChangeModified, Field: PolicyFieldSerial, Old: "1", New: "2"})
+	require.Contains(changes, Change{Kind: ChangeModified, Field: PolicyFieldMasterSecretRotationInterval, Old: "0", New: "10"})
+	require.Contains(changes, Change{Kind: ChangeAdded, Field: PolicyFieldEnclaves, Identity: &newKmEnclave})
+	require.Contains(changes, Change{Kind: ChangeAdded, Field: PolicyFieldMayReplicate, Enclave: &kmEnclave, Identity: &newKmEnclave})
+	require.Contains(changes, Change{Kind: ChangeAdded, Field: PolicyFieldMayQuery, Enclave: &kmEnclave, Runtime: &otherID})
+	require.Contains(changes, Change{Kind: ChangeAdded, Field: PolicyFieldMayQuery, Enclave: &kmEnclave, Runtime: &otherID, Identity: &otherEnclave})
+	require.Len(changes, 6)
+	require.Equal(changes, DiffPolicies(oldPol, newPol), "changes should be deterministic")
+
+	// Reverse diff should report removals.
+	changes = DiffPolicies(newPol, oldPol)
+	require.Contains(changes, Change{Kind: ChangeRemoved, Field: PolicyFieldMayQuery, Enclave: &kmEnclave, Runtime: &otherID, Identity: &otherEnclave})
+	require.Contains(changes, Change{Kind: ChangeRemoved, Field: PolicyFieldEnclaves, Identity: &newKmEnclave})
+
+	// Changes should be human-readable.
+	require.Equal("modified serial: 2 -> 1", changes[0].String())
+}
@@ -1,6 +1,11 @@
 // Package config implements global configuration options.
 package config
 
+import (
+	"fmt"
+	"time"
+)
+
 // Config is the common configuration structure.
 type Config struct {
 	// Node's data directory.
@@ -9,6 +14,10 @@ type Config struct {
 	InternalSocketPath string `yaml:"internal_socket_path,omitempty"`
 	// Logging configuration options.
 	Log LogConfig `yaml:"log,omitempty"`
+	// Node control API configuration options.
+	Control ControlConfig `yaml:"control,omitempty"`
+	// Memory limit configuration options.
+	MemoryLimit MemoryLimitConfig `yaml:"memory_limit,omitempty"`
 	// Debug configuration options (do not use).
 	Debug DebugConfig `yaml:"debug,omitempty"`
 }
@@ -23,6 +32,28 @@ type LogConfig struct {
 	Level map[string]string `yaml:"level,omitempty"`
 }
 
+// ControlConfig is the node control API configuration structure.
+type ControlConfig struct {
+	// Maximum size of bundles uploaded via the control API (e.g., 512mb). If empty, the default
+	// maximum size is used.
+	MaxBundleUploadSize string `yaml:"max_bundle_upload_size,omitempty"`
+	// Maximum rate per second at which storage backups are streamed via the control API (e.g.,
+	// 64mb). If empty, the default maximum rate is used.
+	MaxStorageBackupRate string `yaml:"max_storage_backup_rate,omitempty"`
+}
+
+// MemoryLimitConfig is the soft memory limit configuration structure.
+type MemoryLimitConfig struct {
+	// Enable the soft memory limit manager which shrinks caches under memory pressure.
+	Enabled bool `yaml:"enabled,omitempty"`
+	// Soft memory limit (e.g., 8gb). If empty, the cgroup memory limit is used.
+	SoftLimit string `yaml:"soft_limit,omitempty"`
+	// Lower GOGC under memory pressure and restore it once the pressure subsides.
+	AdjustGC bool `yaml:"adjust_gc,omitempty"`
+	// Interval at which memory usage is sampled (0 means the default interval).
+	Interval time.Duration `yaml:"interval,omitempty"`
+}
+
 // DebugConfig is the common debug configuration structure.
 type DebugConfig struct {
 	// Allow running the node as root.
@@ -33,6 +64,9 @@ type DebugConfig struct {
 
 // Validate validates the configuration settings.
 func (c *Config) Validate() error {
+	if c.MemoryLimit.Interval < 0 {
+		return fmt.Errorf("memory_limit.interval must be non-negative")
+	}
 	return nil
 }
 
@@ -50,6 +84,16 @@ func DefaultConfig() Config {
 				"mkvs/db":           "info",  // Debug logs are too verbose and not very useful.
 			},
 		},
+		Control: ControlConfig{
+			MaxBundleUploadSize:  "",
+			MaxStorageBackupRate: "",
+		},
+		MemoryLimit: MemoryLimitConfig{
+			Enabled:   false,
+			SoftLimit: "",
+			AdjustGC:  false,
+			Interval:  0,
+		},
 		Debug: DebugConfig{
 			AllowRoot: false,
 			Rlimit:    0,
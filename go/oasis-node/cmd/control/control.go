@@ -6,12 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
 
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/common/persistent"
+	"github.com/oasisprotocol/oasis-core/go/config"
 	control "github.com/oasisprotocol/oasis-core/go/control/api"
 	cmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
 	cmdGrpc "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/grpc"
@@ -20,7 +26,24 @@ import (
 )
 
 var (
-	shutdownWait = false
+	shutdownWait        = false
+	shutdownAnnotation  string
+	shutdownReason      string
+	shutdownGracePeriod time.Duration
+	gcDiscardRatio      float64
+	backupSinceVersion  uint64
+	backupMaxRate       string
+	backupOutput        string
+	checkpointVersion   uint64
+	pruneEarliest       uint64
+	pruneKeep           uint64
+	rotateTLS           bool
+	rotateP2P           bool
+	rotateGracePeriod   time.Duration
+	logLevelModule      string
+	statusSections      []string
+	cancelByName        bool
+	restartForce        bool
 
 	controlCmd = &cobra.Command{
 		Use:   "control",
@@ -59,11 +82,92 @@ var (
 	}
 
 	controlCancelUpgradeCmd = &cobra.Command{
-		Use:   "cancel-upgrade <upgrade-name>",
+		Use:   "cancel-upgrade <upgrade-descriptor|handler-name>",
 		Short: "cancel a pending upgrade unless it is already in progress",
+		Args:  cobra.ExactArgs(1),
 		Run:   doCancelUpgrade,
 	}
 
+	controlSetHaltEpochCmd = &cobra.Command{
+		Use:   "set-halt-epoch <epoch|clear>",
+		Short: "halt consensus block processing at the given epoch or clear the halt epoch",
+		Args:  cobra.ExactArgs(1),
+		Run:   doSetHaltEpoch,
+	}
+
+	controlVerifyDataDirCmd = &cobra.Command{
+		Use:   "verify-data-dir",
+		Short: "verify the node data directory and report unknown and stale entries",
+		Run:   doVerifyDataDir,
+	}
+
+	controlTriggerStorageGCCmd = &cobra.Command{
+		Use:   "trigger-storage-gc <runtime-id>",
+		Short: "garbage collect the runtime storage, e.g., after pruning",
+		Args:  cobra.ExactArgs(1),
+		Run:   doTriggerStorageGC,
+	}
+
+	controlBackupStorageCmd = &cobra.Command{
+		Use:   "backup-storage <runtime-id>",
+		Short: "back up the runtime storage while the node keeps running",
+		Args:  cobra.ExactArgs(1),
+		Run:   doBackupStorage,
+	}
+
+	controlPruneStorageCmd = &cobra.Command{
+		Use:   "prune-storage <runtime-id>",
+		Short: "prune old versions of the runtime storage",
+		Args:  cobra.ExactArgs(1),
+		Run:   doPruneStorage,
+	}
+
+	controlCreateCheckpointCmd = &cobra.Command{
+		Use:   "create-checkpoint <runtime-id>",
+		Short: "create a runtime storage checkpoint outside the regular checkpoint schedule",
+		Args:  cobra.ExactArgs(1),
+		Run:   doCreateCheckpoint,
+	}
+
+	controlRestartRuntimeCmd = &cobra.Command{
+		Use:   "restart-runtime <runtime-id>",
+		Short: "restart a runtime's host without restarting the node",
+		Args:  cobra.ExactArgs(1),
+		Run:   doRestartRuntime,
+	}
+
+	controlPublicKeysCmd = &cobra.Command{
+		Use:   "public-keys",
+		Short: "list the node's public keys and their roles",
+		Run:   doPublicKeys,
+	}
+
+	controlRotateKeysCmd = &cobra.Command{
+		Use:   "rotate-keys",
+		Short: "rotate the node's TLS and/or P2P keys without a restart",
+		Run:   doRotateKeys,
+	}
+
+	controlSelfTestCmd = &cobra.Command{
+		Use:   "self-test",
+		Short: "check that the addresses the node advertises are reachable from its self-check peers",
+		Run:   doSelfTest,
+	}
+
+	controlValidateConfigCmd = &cobra.Command{
+		Use:   "validate-config <path>",
+		Short: "validate a config file against the running node's config",
+		Args:  cobra.ExactArgs(1),
+		Run:   doValidateConfig,
+	}
+
+	controlApplyConfigCmd = &cobra.Command{
+		Use:   "apply-config <path>",
+		Short: "apply the hot-applicable changes of a config file to the running node",
+		Args:  cobra.ExactArgs(1),
+		Run:   doApplyConfig,
+	}
+
 	controlStatusCmd = &cobra.Command{
 		Use:   "status",
 		Short: "show node status",
@@ -83,6 +187,32 @@ var (
 		Run:   doAddBundle,
 	}
 
+	controlListBundlesCmd = &cobra.Command{
+		Use:   "list-bundles",
+		Short: "list the bundles registered with the node",
+		Run:   doListBundles,
+	}
+
+	controlSetLogLevelCmd = &cobra.Command{
+		Use:   "set-log-level <level>",
+		Short: "change the log level of the running node",
+		Args:  cobra.ExactArgs(1),
+		Run:   doSetLogLevel,
+	}
+
+	controlGetLogLevelsCmd = &cobra.Command{
+		Use:   "log-levels",
+		Short: "show the log levels of the running node",
+		Run:   doGetLogLevels,
+	}
+
+	controlRemoveBundleCmd = &cobra.Command{
+		Use:   "remove-bundle <manifest-hash>",
+		Short: "remove a bundle not required by the active runtime descriptor",
+		Args:  cobra.ExactArgs(1),
+		Run:   doRemoveBundle,
+	}
+
 	logger = logging.GetLogger("cmd/control")
 )
 
@@ -152,7 +282,27 @@ func doShutdown(cmd *cobra.Command, _ []string) {
 	conn, client := DoConnect(cmd)
 	defer conn.Close()
 
-	err := client.RequestShutdown(context.Background(), shutdownWait)
+	req := control.ShutdownRequest{
+		Wait:        shutdownWait,
+		Annotation:  shutdownAnnotation,
+		GracePeriod: shutdownGracePeriod,
+		Reason:      shutdownReason,
+	}
+	if err := req.ValidateBasic(); err != nil {
+		logger.Error("invalid shutdown request",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	var err error
+	switch req {
+	case control.ShutdownRequest{Wait: shutdownWait}:
+		// Use the old method when possible so that older nodes are supported.
+		err = client.RequestShutdown(context.Background(), shutdownWait)
+	default:
+		err = client.RequestShutdownEx(context.Background(), &req)
+	}
 	if err != nil {
 		logger.Error("failed to send shutdown request",
 			"err", err,
@@ -244,9 +394,14 @@ func doCancelUpgrade(cmd *cobra.Command, args []string) {
 	conn, client := DoConnect(cmd)
 	defer conn.Close()
 
-	if len(args) == 0 {
-		logger.Error("expected descriptor path")
-		os.Exit(1)
+	if cancelByName {
+		if err := client.CancelUpgradeByName(context.Background(), args[0]); err != nil {
+			logger.Error("failed to send upgrade cancellation request",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+		return
 	}
 
 	descriptorBytes, err := os.ReadFile(args[0])
@@ -274,13 +429,394 @@ func doCancelUpgrade(cmd *cobra.Command, args []string) {
 	}
 }
 
+func doSetHaltEpoch(cmd *cobra.Command, args []string) {
+	epoch := beacon.EpochInvalid
+	if args[0] != "clear" {
+		v, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil || beacon.EpochTime(v) == beacon.EpochInvalid {
+			logger.Error("malformed halt epoch",
+				"epoch", args[0],
+			)
+			os.Exit(1)
+		}
+		epoch = beacon.EpochTime(v)
+	}
+
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	if err := client.SetHaltEpoch(context.Background(), epoch); err != nil {
+		logger.Error("failed to set halt epoch",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+func doVerifyDataDir(cmd *cobra.Command, _ []string) {
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	result, err := client.VerifyDataDir(context.Background())
+	if err != nil {
+		logger.Error("failed to verify data directory",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	prettyResult, err := cmdCommon.PrettyJSONMarshal(result)
+	if err != nil {
+		logger.Error("failed to get pretty JSON of data directory verification result",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Println(string(prettyResult))
+}
+
+func doTriggerStorageGC(cmd *cobra.Command, args []string) {
+	var runtimeID common.Namespace
+	if err := runtimeID.UnmarshalText([]byte(args[0])); err != nil {
+		logger.Error("malformed runtime ID",
+			"err", err,
+			"arg", args[0],
+		)
+		os.Exit(1)
+	}
+
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	// Use background context to block until the garbage collection completes.
+	stats, err := client.TriggerStorageGC(context.Background(), &control.TriggerStorageGCRequest{
+		RuntimeID:    runtimeID,
+		DiscardRatio: gcDiscardRatio,
+	})
+	if err != nil {
+		logger.Error("failed to garbage collect runtime storage",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	prettyStats, err := cmdCommon.PrettyJSONMarshal(stats)
+	if err != nil {
+		logger.Error("failed to get pretty JSON of garbage collection statistics",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Println(string(prettyStats))
+}
+
+func doBackupStorage(cmd *cobra.Command, args []string) {
+	var runtimeID common.Namespace
+	if err := runtimeID.UnmarshalText([]byte(args[0])); err != nil {
+		logger.Error("malformed runtime ID",
+			"err", err,
+			"arg", args[0],
+		)
+		os.Exit(1)
+	}
+
+	var maxRate uint64
+	if backupMaxRate != "" {
+		maxRate = uint64(config.ParseSizeInBytes(backupMaxRate))
+	}
+
+	f, err := os.OpenFile(backupOutput, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		logger.Error("failed to create backup file",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	// Use background context to block until the backup completes.
+	nextSinceVersion, err := client.BackupStorage(context.Background(), &control.BackupStorageRequest{
+		RuntimeID:    runtimeID,
+		SinceVersion: backupSinceVersion,
+		MaxRate:      maxRate,
+	}, f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		logger.Error("failed to back up runtime storage",
+			"err", err,
+		)
+		_ = os.Remove(backupOutput)
+		os.Exit(1)
+	}
+	fmt.Printf("next since version: %d\n", nextSinceVersion)
+}
+
+func doPruneStorage(cmd *cobra.Command, args []string) {
+	var runtimeID common.Namespace
+	if err := runtimeID.UnmarshalText([]byte(args[0])); err != nil {
+		logger.Error("malformed runtime ID",
+			"err", err,
+			"arg", args[0],
+		)
+		os.Exit(1)
+	}
+
+	req := control.PruneStorageRequest{
+		RuntimeID:       runtimeID,
+		EarliestVersion: pruneEarliest,
+		KeepVersions:    pruneKeep,
+	}
+	if err := req.ValidateBasic(); err != nil {
+		logger.Error("invalid storage pruning request",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	// Use background context to block until pruning completes.
+	rsp, err := client.PruneStorage(context.Background(), &req)
+	if err != nil {
+		logger.Error("failed to prune runtime storage",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	prettyRsp, err := cmdCommon.PrettyJSONMarshal(rsp)
+	if err != nil {
+		logger.Error("failed to get pretty JSON of storage pruning response",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Println(string(prettyRsp))
+}
+
+func doCreateCheckpoint(cmd *cobra.Command, args []string) {
+	var runtimeID common.Namespace
+	if err := runtimeID.UnmarshalText([]byte(args[0])); err != nil {
+		logger.Error("malformed runtime ID",
+			"err", err,
+			"arg", args[0],
+		)
+		os.Exit(1)
+	}
+
+	req := control.CreateCheckpointRequest{
+		RuntimeID: runtimeID,
+	}
+	if cmd.Flags().Changed("version") {
+		req.Version = &checkpointVersion
+	}
+
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	// Use background context to block until the checkpoint is created.
+	info, err := client.CreateCheckpoint(context.Background(), &req)
+	if err != nil {
+		logger.Error("failed to create runtime storage checkpoint",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	prettyInfo, err := cmdCommon.PrettyJSONMarshal(info)
+	if err != nil {
+		logger.Error("failed to get pretty JSON of checkpoint info",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Println(string(prettyInfo))
+}
+
+func doRestartRuntime(cmd *cobra.Command, args []string) {
+	var runtimeID common.Namespace
+	if err := runtimeID.UnmarshalText([]byte(args[0])); err != nil {
+		logger.Error("malformed runtime ID",
+			"err", err,
+			"arg", args[0],
+		)
+		os.Exit(1)
+	}
+
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	// Use background context to block until the runtime is restarted.
+	if err := client.RestartRuntime(context.Background(), runtimeID, restartForce); err != nil {
+		logger.Error("failed to restart runtime",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+func doPublicKeys(cmd *cobra.Command, _ []string) {
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	keys, err := client.GetPublicKeys(context.Background())
+	if err != nil {
+		logger.Error("failed to get public keys",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	prettyKeys, err := cmdCommon.PrettyJSONMarshal(keys)
+	if err != nil {
+		logger.Error("failed to get pretty JSON of public keys",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Println(string(prettyKeys))
+}
+
+func doRotateKeys(cmd *cobra.Command, _ []string) {
+	req := control.RotateKeysRequest{
+		TLS:         rotateTLS,
+		P2P:         rotateP2P,
+		GracePeriod: rotateGracePeriod,
+	}
+	if err := req.ValidateBasic(); err != nil {
+		logger.Error("invalid key rotation request",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	rsp, err := client.RotateKeys(context.Background(), &req)
+	if err != nil {
+		logger.Error("failed to rotate keys",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	prettyRsp, err := cmdCommon.PrettyJSONMarshal(rsp)
+	if err != nil {
+		logger.Error("failed to get pretty JSON of key rotation response",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Println(string(prettyRsp))
+}
+
+func doSelfTest(cmd *cobra.Command, _ []string) {
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	// Use background context to block until all peers have probed the addresses.
+	report, err := client.RunSelfTest(context.Background())
+	if err != nil {
+		logger.Error("failed to run self-test",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	prettyReport, err := cmdCommon.PrettyJSONMarshal(report)
+	if err != nil {
+		logger.Error("failed to get pretty JSON of self-test report",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Println(string(prettyReport))
+
+	if err = report.Err(); err != nil {
+		logger.Error("self-test failed",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+func doValidateConfig(cmd *cobra.Command, args []string) {
+	doConfig(cmd, args[0], false)
+}
+
+func doApplyConfig(cmd *cobra.Command, args []string) {
+	doConfig(cmd, args[0], true)
+}
+
+func doConfig(cmd *cobra.Command, path string, apply bool) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		logger.Error("failed to read config file",
+			"err", err,
+			"path", path,
+		)
+		os.Exit(1)
+	}
+
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	var res *control.ConfigValidationResult
+	if apply {
+		res, err = client.ApplyConfig(context.Background(), raw)
+	} else {
+		res, err = client.ValidateConfig(context.Background(), raw)
+	}
+	if err != nil {
+		logger.Error("failed to validate config",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	prettyRes, err := cmdCommon.PrettyJSONMarshal(res)
+	if err != nil {
+		logger.Error("failed to get pretty JSON of config validation result",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Println(string(prettyRes))
+
+	if !res.Valid {
+		os.Exit(1)
+	}
+}
+
 // DoFetchStatus connects to the node's gRPC server and fetches its status.
 func DoFetchStatus(cmd *cobra.Command) *control.Status {
 	conn, client := DoConnect(cmd)
 	defer conn.Close()
 
+	filter, err := control.ParseStatusFilter(statusSections)
+	if err != nil {
+		logger.Error("malformed status sections",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
 	// Use background context to block until the result comes in.
-	status, err := client.GetStatus(context.Background())
+	var status *control.Status
+	switch filter {
+	case nil:
+		// Use the original method when not filtering to support older nodes.
+		status, err = client.GetStatus(context.Background())
+	default:
+		status, err = client.GetStatusEx(context.Background(), filter)
+	}
 	if err != nil {
 		logger.Error("failed to query status",
 			"err", err,
@@ -320,11 +856,118 @@ func doAddBundle(cmd *cobra.Command, args []string) {
 	}
 }
 
+func doListBundles(cmd *cobra.Command, _ []string) {
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	bundles, err := client.ListBundles(context.Background())
+	if err != nil {
+		logger.Error("failed to list bundles",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	prettyBundles, err := cmdCommon.PrettyJSONMarshal(bundles)
+	if err != nil {
+		logger.Error("failed to get pretty JSON of bundles",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Println(string(prettyBundles))
+}
+
+func doRemoveBundle(cmd *cobra.Command, args []string) {
+	var manifestHash hash.Hash
+	if err := manifestHash.UnmarshalHex(args[0]); err != nil {
+		logger.Error("malformed manifest hash",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	if err := client.RemoveBundle(context.Background(), manifestHash); err != nil {
+		logger.Error("failed to remove bundle",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+func doSetLogLevel(cmd *cobra.Command, args []string) {
+	req := control.LogLevelRequest{
+		Module: logLevelModule,
+		Level:  args[0],
+	}
+	if err := req.ValidateBasic(); err != nil {
+		logger.Error("invalid log level request",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	affected, err := client.SetLogLevel(context.Background(), &req)
+	if err != nil {
+		logger.Error("failed to set log level",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Printf("log level changed for %d logger(s)\n", affected)
+}
+
+func doGetLogLevels(cmd *cobra.Command, _ []string) {
+	conn, client := DoConnect(cmd)
+	defer conn.Close()
+
+	levels, err := client.GetLogLevels(context.Background())
+	if err != nil {
+		logger.Error("failed to get log levels",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	prettyLevels, err := cmdCommon.PrettyJSONMarshal(levels)
+	if err != nil {
+		logger.Error("failed to get pretty JSON of log levels",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+	fmt.Println(string(prettyLevels))
+}
+
 // Register registers the client sub-command and all of it's children.
 func Register(parentCmd *cobra.Command) {
 	controlCmd.PersistentFlags().AddFlagSet(cmdGrpc.ClientFlags)
 
 	controlShutdownCmd.Flags().BoolVarP(&shutdownWait, "wait", "w", false, "wait for the node to finish shutdown")
+	controlShutdownCmd.Flags().StringVar(&shutdownAnnotation, "annotation", "", "annotation recorded together with the shutdown reason")
+	controlShutdownCmd.Flags().StringVar(&shutdownReason, "reason", "", "description of why the shutdown is requested")
+	controlShutdownCmd.Flags().DurationVar(&shutdownGracePeriod, "grace-period", 0, "time after which the node exits even if workers have not drained (0 means no limit)")
+	controlCancelUpgradeCmd.Flags().BoolVar(&cancelByName, "by-name", false, "cancel the pending upgrade with the given handler name instead of reading a descriptor")
+	controlStatusCmd.Flags().StringSliceVar(&statusSections, "sections", nil, "comma-separated status sections to gather (all if empty)")
+	controlSetLogLevelCmd.Flags().StringVar(&logLevelModule, "module", "", "glob matching the logger modules to change (default level if empty)")
+	controlTriggerStorageGCCmd.Flags().Float64Var(&gcDiscardRatio, "discard-ratio", control.DefaultStorageGCDiscardRatio, "ratio of discardable data required for a file to be rewritten")
+	controlBackupStorageCmd.Flags().Uint64Var(&backupSinceVersion, "since", 0, "first version to back up, as returned by the previous backup (full backup if zero)")
+	controlBackupStorageCmd.Flags().StringVar(&backupMaxRate, "max-rate", "", "maximum backup rate per second (e.g., 16mb), can only lower the node's limit")
+	controlBackupStorageCmd.Flags().StringVarP(&backupOutput, "output", "o", "", "file to write the backup into")
+	_ = controlBackupStorageCmd.MarkFlagRequired("output")
+	controlRotateKeysCmd.Flags().BoolVar(&rotateTLS, "tls", false, "rotate the TLS key")
+	controlRotateKeysCmd.Flags().BoolVar(&rotateP2P, "p2p", false, "rotate the P2P key")
+	controlRotateKeysCmd.Flags().DurationVar(&rotateGracePeriod, "grace-period", control.DefaultKeyRotationGracePeriod, "time during which the rotated out keys remain valid")
+	controlPruneStorageCmd.Flags().Uint64Var(&pruneEarliest, "earliest-version", 0, "earliest version to keep")
+	controlPruneStorageCmd.Flags().Uint64Var(&pruneKeep, "keep", 0, "number of latest versions to keep")
+	controlCreateCheckpointCmd.Flags().Uint64Var(&checkpointVersion, "version", 0, "finalized version to checkpoint (latest finalized version if not set)")
+	controlRestartRuntimeCmd.Flags().BoolVar(&restartForce, "force", false, "restart immediately without waiting for in-flight host calls")
 
 	controlCmd.AddCommand(controlIsSyncedCmd)
 	controlCmd.AddCommand(controlWaitSyncCmd)
@@ -332,8 +975,24 @@ func Register(parentCmd *cobra.Command) {
 	controlCmd.AddCommand(controlClearDeregisterCmd)
 	controlCmd.AddCommand(controlUpgradeBinaryCmd)
 	controlCmd.AddCommand(controlCancelUpgradeCmd)
+	controlCmd.AddCommand(controlSetHaltEpochCmd)
+	controlCmd.AddCommand(controlVerifyDataDirCmd)
+	controlCmd.AddCommand(controlTriggerStorageGCCmd)
+	controlCmd.AddCommand(controlBackupStorageCmd)
+	controlCmd.AddCommand(controlPruneStorageCmd)
+	controlCmd.AddCommand(controlCreateCheckpointCmd)
+	controlCmd.AddCommand(controlRestartRuntimeCmd)
+	controlCmd.AddCommand(controlPublicKeysCmd)
+	controlCmd.AddCommand(controlRotateKeysCmd)
+	controlCmd.AddCommand(controlSelfTestCmd)
+	controlCmd.AddCommand(controlValidateConfigCmd)
+	controlCmd.AddCommand(controlApplyConfigCmd)
 	controlCmd.AddCommand(controlStatusCmd)
 	controlCmd.AddCommand(controlRuntimeStatsCmd)
 	controlCmd.AddCommand(controlAddBundleCmd)
+	controlCmd.AddCommand(controlListBundlesCmd)
+	controlCmd.AddCommand(controlRemoveBundleCmd)
+	controlCmd.AddCommand(controlSetLogLevelCmd)
+	controlCmd.AddCommand(controlGetLogLevelsCmd)
 	parentCmd.AddCommand(controlCmd)
 }
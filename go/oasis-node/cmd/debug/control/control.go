@@ -9,6 +9,7 @@ import (
 	"google.golang.org/grpc"
 
 	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	control "github.com/oasisprotocol/oasis-core/go/control/api"
 	cmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
@@ -17,8 +18,9 @@ import (
 )
 
 var (
-	epoch uint64
-	nodes int
+	epoch     uint64
+	nodes     int
+	runtimeID string
 
 	controlCmd = &cobra.Command{
 		Use:   "control",
@@ -41,7 +43,8 @@ var (
 		Use:   "wait-ready",
 		Short: "wait for node to become ready",
 		Long: "Wait for the consensus backend to be synced and runtimes being registered, " +
-			"initialized, and ready to accept the workload.",
+			"initialized, and ready to accept the workload. If a runtime is given, only wait " +
+			"for that runtime to become ready.",
 		Run: doWaitReady,
 	}
 
@@ -103,10 +106,28 @@ func doWaitReady(cmd *cobra.Command, _ []string) {
 	conn, client := cmdControl.DoConnect(cmd)
 	defer conn.Close()
 
-	logger.Debug("waiting for ready status")
-
 	// Use background context to block until the result comes in.
-	err := client.WaitReady(context.Background())
+	var err error
+	switch runtimeID {
+	case "":
+		logger.Debug("waiting for ready status")
+
+		err = client.WaitReady(context.Background())
+	default:
+		var id common.Namespace
+		if err = id.UnmarshalHex(runtimeID); err != nil {
+			logger.Error("malformed runtime identifier",
+				"err", err,
+			)
+			os.Exit(1)
+		}
+
+		logger.Debug("waiting for runtime ready status",
+			"runtime_id", id,
+		)
+
+		err = client.WaitRuntimeReady(context.Background(), id)
+	}
 	if err != nil {
 		logger.Error("failed to wait for ready status",
 			"err", err,
@@ -120,6 +141,7 @@ func Register(parentCmd *cobra.Command) {
 	controlCmd.PersistentFlags().AddFlagSet(cmdGrpc.ClientFlags)
 	controlSetEpochCmd.Flags().Uint64VarP(&epoch, "epoch", "e", 0, "set epoch to given value")
 	controlWaitNodesCmd.Flags().IntVarP(&nodes, "nodes", "n", 1, "number of nodes to wait for")
+	controlWaitReadyCmd.Flags().StringVar(&runtimeID, "runtime", "", "only wait for the given runtime to become ready")
 
 	controlCmd.AddCommand(controlSetEpochCmd)
 	controlCmd.AddCommand(controlWaitNodesCmd)
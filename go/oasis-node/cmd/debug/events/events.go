@@ -0,0 +1,219 @@
+// Package events implements the event export debug sub-commands.
+package events
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/tabular"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	cmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+	cmdGrpc "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/grpc"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+	stakingExport "github.com/oasisprotocol/oasis-core/go/staking/export"
+)
+
+const (
+	cfgStartHeight = "events.start_height"
+	cfgEndHeight   = "events.end_height"
+	cfgFormat      = "events.format"
+	cfgOutput      = "events.output"
+	cfgCursor      = "events.cursor"
+	cfgBatchSize   = "events.batch_size"
+)
+
+var (
+	eventsCmd = &cobra.Command{
+		Use:   "events",
+		Short: "export consensus events",
+	}
+
+	eventsStakingCmd = &cobra.Command{
+		Use:   "staking",
+		Short: "export staking events for a height range",
+		Long: "Export staking events for a height range in CSV or Parquet format. " +
+			"The export can be resumed from the cursor file in case it is interrupted. " +
+			"Parquet output is written as a series of files, one per batch.",
+		Run: doExportStaking,
+	}
+
+	eventsFlags = flag.NewFlagSet("", flag.ContinueOnError)
+
+	logger = logging.GetLogger("cmd/debug/events")
+)
+
+type consensusClient interface {
+	GetStatus(ctx context.Context) (*consensus.Status, error)
+	GetBlock(ctx context.Context, height int64) (*consensus.Block, error)
+}
+
+type stakingSource struct {
+	consensus consensusClient
+	staking   *staking.Client
+}
+
+func (s *stakingSource) GetStatus(ctx context.Context) (*consensus.Status, error) {
+	return s.consensus.GetStatus(ctx)
+}
+
+func (s *stakingSource) GetBlock(ctx context.Context, height int64) (*consensus.Block, error) {
+	return s.consensus.GetBlock(ctx, height)
+}
+
+func (s *stakingSource) GetEvents(ctx context.Context, height int64) ([]*staking.Event, error) {
+	return s.staking.GetEvents(ctx, height)
+}
+
+func doExportStaking(cmd *cobra.Command, _ []string) {
+	if err := cmdCommon.Init(); err != nil {
+		cmdCommon.EarlyLogAndExit(err)
+	}
+
+	if err := exportStaking(cmd); err != nil {
+		logger.Error("failed to export staking events",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
+
+func exportStaking(cmd *cobra.Command) error {
+	ctx := context.Background()
+
+	output := viper.GetString(cfgOutput)
+	if output == "" {
+		return fmt.Errorf("output path must be set")
+	}
+	cursorPath := viper.GetString(cfgCursor)
+	if cursorPath == "" {
+		cursorPath = output + ".cursor"
+	}
+
+	conn, err := cmdGrpc.NewClient(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to establish connection with node: %w", err)
+	}
+	defer conn.Close()
+
+	src := &stakingSource{
+		consensus: consensus.NewClient(conn),
+		staking:   staking.NewClient(conn),
+	}
+
+	cfg := &stakingExport.Config{
+		StartHeight: viper.GetInt64(cfgStartHeight),
+		EndHeight:   viper.GetInt64(cfgEndHeight),
+		BatchSize:   viper.GetInt64(cfgBatchSize),
+	}
+	if cfg.EndHeight == 0 {
+		blk, err := src.GetBlock(ctx, consensus.HeightLatest)
+		if err != nil {
+			return fmt.Errorf("failed to get latest block: %w", err)
+		}
+		cfg.EndHeight = blk.Height
+	}
+
+	cursor, err := tabular.LoadCursor(cursorPath)
+	if err != nil {
+		return err
+	}
+	if cursor != nil {
+		logger.Info("resuming export from cursor",
+			"cursor", cursorPath,
+			"next_height", cursor.Next,
+		)
+		cfg.StartHeight = cursor.Next
+	}
+	if cfg.StartHeight > cfg.EndHeight {
+		logger.Info("nothing to export")
+		return nil
+	}
+
+	var (
+		w      tabular.Writer[stakingExport.EventRow]
+		offset func() (int64, error)
+	)
+	switch format := tabular.Format(viper.GetString(cfgFormat)); format {
+	case tabular.FormatCSV:
+		f, err := os.OpenFile(output, os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open output: %w", err)
+		}
+		// Discard anything written after the last saved cursor.
+		var size int64
+		if cursor != nil {
+			size = cursor.Offset
+		}
+		if err = f.Truncate(size); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to truncate output: %w", err)
+		}
+		if _, err = f.Seek(size, io.SeekStart); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to seek output: %w", err)
+		}
+		w = tabular.NewCSVWriter[stakingExport.EventRow](f, size == 0)
+		offset = func() (int64, error) {
+			return f.Seek(0, io.SeekCurrent)
+		}
+	case tabular.FormatParquet:
+		dir, base := filepath.Split(output)
+		w = tabular.NewParquetWriter[stakingExport.EventRow](func() (io.WriteCloser, error) {
+			return os.CreateTemp(dir, base+"-*.parquet")
+		})
+	default:
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+	defer w.Close()
+
+	cfg.OnFlush = func(nextHeight int64) error {
+		c := tabular.Cursor{Next: nextHeight}
+		if offset != nil {
+			var err error
+			if c.Offset, err = offset(); err != nil {
+				return err
+			}
+		}
+		logger.Debug("exported staking events",
+			"next_height", nextHeight,
+		)
+		return c.Save(cursorPath)
+	}
+
+	logger.Info("exporting staking events",
+		"start_height", cfg.StartHeight,
+		"end_height", cfg.EndHeight,
+	)
+
+	if err = stakingExport.Export(ctx, src, w, cfg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// Register registers the events sub-command and all of its children.
+func Register(parentCmd *cobra.Command) {
+	eventsCmd.PersistentFlags().AddFlagSet(cmdGrpc.ClientFlags)
+
+	eventsStakingCmd.Flags().AddFlagSet(eventsFlags)
+	eventsCmd.AddCommand(eventsStakingCmd)
+	parentCmd.AddCommand(eventsCmd)
+}
+
+func init() {
+	eventsFlags.Int64(cfgStartHeight, 1, "first height to export")
+	eventsFlags.Int64(cfgEndHeight, 0, "last height to export (0 = latest)")
+	eventsFlags.String(cfgFormat, string(tabular.FormatCSV), "output format (csv, parquet)")
+	eventsFlags.String(cfgOutput, "", "output path (prefix of output files for parquet)")
+	eventsFlags.String(cfgCursor, "", "cursor file path (default: <output>.cursor)")
+	eventsFlags.Int64(cfgBatchSize, stakingExport.DefaultBatchSize, "number of heights exported between flushes")
+	_ = viper.BindPFlags(eventsFlags)
+}
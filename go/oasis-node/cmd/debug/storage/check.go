@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/oasis-core/go/config"
+	cmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+	runtimeConfig "github.com/oasisprotocol/oasis-core/go/runtime/config"
+	storageAPI "github.com/oasisprotocol/oasis-core/go/storage/api"
+	storageDatabase "github.com/oasisprotocol/oasis-core/go/storage/database"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/badger"
+)
+
+const (
+	cfgCheckRuntimeID = "storage.check.runtime_id"
+	cfgCheckVersion   = "storage.check.version"
+	cfgCheckRoot      = "storage.check.root"
+)
+
+var (
+	storageCheckCmd = &cobra.Command{
+		Use:   "check",
+		Short: "check consistency of a single version of a runtime state database",
+		Run:   doCheck,
+	}
+
+	storageCheckFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func doCheck(*cobra.Command, []string) {
+	var ok bool
+	defer func() {
+		if !ok {
+			os.Exit(1)
+		}
+	}()
+
+	if err := cmdCommon.Init(); err != nil {
+		cmdCommon.EarlyLogAndExit(err)
+	}
+
+	dataDir := cmdCommon.DataDir()
+	if dataDir == "" {
+		logger.Error("data directory must be set")
+		return
+	}
+
+	runtimeID, version, err := runtimeAndVersion(
+		viper.GetString(cfgCheckRuntimeID),
+		viper.GetUint64(cfgCheckVersion),
+		viper.GetString(cfgCheckRoot),
+	)
+	if err != nil {
+		logger.Error("failed to determine the version to check",
+			"err", err,
+		)
+		return
+	}
+
+	// Only the badger backend supports consistency checks.
+	dbDir := filepath.Join(
+		runtimeConfig.GetRuntimeStateDir(dataDir, runtimeID),
+		storageDatabase.DefaultFileName(storageDatabase.BackendNameBadgerDB),
+	)
+	if _, err := os.Stat(dbDir); err != nil {
+		logger.Error("badger runtime state database not found",
+			"err", err,
+			"dir", dbDir,
+		)
+		return
+	}
+
+	cfg := (&storageAPI.Config{
+		DB:           dbDir,
+		Namespace:    runtimeID,
+		MaxCacheSize: int64(config.ParseSizeInBytes(config.GlobalConfig.Storage.MaxCacheSize)),
+		ReadOnly:     true,
+	}).ToNodeDB()
+	err = badger.CheckVersion(context.Background(), cfg, version)
+	switch {
+	case err == nil:
+	case errors.Is(err, badger.ErrInconsistent):
+		fmt.Fprintf(os.Stderr, "version %d is inconsistent:\n%v\n", version, err)
+		return
+	default:
+		logger.Error("failed to check consistency",
+			"err", err,
+			"version", version,
+		)
+		return
+	}
+
+	fmt.Printf("version %d is consistent\n", version)
+	ok = true
+}
+
+func init() {
+	storageCheckFlags.String(cfgCheckRuntimeID, "", "the runtime identifier (hex) of the database to check")
+	storageCheckFlags.Uint64(cfgCheckVersion, 0, "the version to check")
+	storageCheckFlags.String(cfgCheckRoot, "", rootFlagHelp)
+	_ = viper.BindPFlags(storageCheckFlags)
+}
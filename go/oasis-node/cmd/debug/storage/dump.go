@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/oasis-core/go/config"
+	cmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+	runtimeConfig "github.com/oasisprotocol/oasis-core/go/runtime/config"
+	storageAPI "github.com/oasisprotocol/oasis-core/go/storage/api"
+	storageDatabase "github.com/oasisprotocol/oasis-core/go/storage/database"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/badger"
+)
+
+const (
+	cfgDumpRuntimeID = "storage.dump.runtime_id"
+	cfgDumpVersion   = "storage.dump.version"
+	cfgDumpRoot      = "storage.dump.root"
+	cfgDumpKeyspace  = "storage.dump.keyspace"
+	cfgDumpValues    = "storage.dump.values"
+	cfgDumpLimit     = "storage.dump.limit"
+)
+
+var (
+	storageDumpCmd = &cobra.Command{
+		Use:   "dump",
+		Short: "dump the raw keys of a keyspace of a runtime state database at a given version",
+		Run:   doDump,
+	}
+
+	storageDumpFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func doDump(*cobra.Command, []string) {
+	var ok bool
+	defer func() {
+		if !ok {
+			os.Exit(1)
+		}
+	}()
+
+	if err := cmdCommon.Init(); err != nil {
+		cmdCommon.EarlyLogAndExit(err)
+	}
+
+	dataDir := cmdCommon.DataDir()
+	if dataDir == "" {
+		logger.Error("data directory must be set")
+		return
+	}
+
+	runtimeID, version, err := runtimeAndVersion(
+		viper.GetString(cfgDumpRuntimeID),
+		viper.GetUint64(cfgDumpVersion),
+		viper.GetString(cfgDumpRoot),
+	)
+	if err != nil {
+		logger.Error("failed to determine the version to dump",
+			"err", err,
+		)
+		return
+	}
+	var keyspace badger.KeyspacePrefix
+	if err := keyspace.UnmarshalText([]byte(viper.GetString(cfgDumpKeyspace))); err != nil {
+		logger.Error("malformed keyspace",
+			"err", err,
+			"keyspaces", badger.KeyspaceNames(),
+		)
+		return
+	}
+	withValues := viper.GetBool(cfgDumpValues)
+	limit := viper.GetUint64(cfgDumpLimit)
+
+	// Only the badger backend supports raw iteration.
+	dbDir := filepath.Join(
+		runtimeConfig.GetRuntimeStateDir(dataDir, runtimeID),
+		storageDatabase.DefaultFileName(storageDatabase.BackendNameBadgerDB),
+	)
+	if _, err := os.Stat(dbDir); err != nil {
+		logger.Error("badger runtime state database not found",
+			"err", err,
+			"dir", dbDir,
+		)
+		return
+	}
+
+	cfg := (&storageAPI.Config{
+		DB:           dbDir,
+		Namespace:    runtimeID,
+		MaxCacheSize: int64(config.ParseSizeInBytes(config.GlobalConfig.Storage.MaxCacheSize)),
+		ReadOnly:     true,
+	}).ToNodeDB()
+	cfg.BadgerOptions = &api.BadgerOptions{
+		DebugIterate: true,
+	}
+	ndb, err := badger.New(cfg)
+	if err != nil {
+		logger.Error("failed to open runtime state database",
+			"err", err,
+			"dir", dbDir,
+		)
+		return
+	}
+	defer ndb.Close()
+
+	w := bufio.NewWriter(os.Stdout)
+	var count uint64
+	err = ndb.(badger.DebugIterator).DebugIterate(context.Background(), version, keyspace, func(key, value []byte) bool {
+		line := keyspace.FormatKey(key)
+		if withValues {
+			line = fmt.Sprintf("%s %x", line, value)
+		}
+		fmt.Fprintln(w, line)
+
+		count++
+		return limit == 0 || count < limit
+	})
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		logger.Error("failed to dump keyspace",
+			"err", err,
+			"keyspace", keyspace,
+			"version", version,
+		)
+		return
+	}
+
+	ok = true
+}
+
+func init() {
+	storageDumpFlags.String(cfgDumpRuntimeID, "", "the runtime identifier (hex) of the database")
+	storageDumpFlags.Uint64(cfgDumpVersion, 0, "the version to dump (ignored for unversioned keyspaces)")
+	storageDumpFlags.String(cfgDumpRoot, "", rootFlagHelp)
+	storageDumpFlags.String(cfgDumpKeyspace, "", "the keyspace to dump ("+strings.Join(badger.KeyspaceNames(), ", ")+")")
+	storageDumpFlags.Bool(cfgDumpValues, false, "also dump raw values (hex)")
+	storageDumpFlags.Uint64(cfgDumpLimit, 0, "the maximum number of entries to dump (0 means no limit)")
+	_ = viper.BindPFlags(storageDumpFlags)
+
+	storageDumpCmd.Flags().AddFlagSet(storageDumpFlags)
+}
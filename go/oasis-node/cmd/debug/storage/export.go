@@ -103,6 +103,18 @@ func exportRuntime(dataDir, destDir string, id common.Namespace, rtg *roothash.G
 		Type:      storageAPI.RootTypeState,
 		Hash:      rtg.StateRoot,
 	}
+
+	// Make sure that the version is not pruned while it is being exported.
+	unpin, err := storageBackend.NodeDB().Pin(root.Version)
+	if err != nil {
+		logger.Error("failed to pin version",
+			"err", err,
+			"version", root.Version,
+		)
+		return err
+	}
+	defer unpin()
+
 	tree := mkvs.NewWithRoot(storageBackend, nil, root)
 	it := tree.NewIterator(context.Background(), mkvs.IteratorPrefetch(10_000))
 	defer it.Close()
@@ -154,7 +166,7 @@ func exportIterator(fn string, root *storageAPI.Root, it mkvs.Iterator) error {
 	return nil
 }
 
-func newDirectStorageBackend(dataDir string, namespace common.Namespace) (storageAPI.Backend, error) {
+func newDirectStorageBackend(dataDir string, namespace common.Namespace) (storageAPI.LocalBackend, error) {
 	// The right thing to do will be to use storage.New, but the backend config
 	// assumes that identity is valid, and we don't have one.
 	cfg := &storageAPI.Config{
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	flag "github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/config"
+	cmdCommon "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common"
+	runtimeConfig "github.com/oasisprotocol/oasis-core/go/runtime/config"
+	storageAPI "github.com/oasisprotocol/oasis-core/go/storage/api"
+	storageDatabase "github.com/oasisprotocol/oasis-core/go/storage/database"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/badger"
+)
+
+const (
+	cfgMetadataRuntimeID       = "storage.metadata.runtime_id"
+	cfgMetadataFile            = "storage.metadata.file"
+	cfgMetadataEarliestVersion = "storage.metadata.earliest_version"
+)
+
+var (
+	storageMetadataCmd = &cobra.Command{
+		Use:   "metadata",
+		Short: "back up and recover the metadata of a runtime state database",
+	}
+
+	storageMetadataExportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "export the metadata of a runtime state database into a file",
+		Run:   doMetadataExport,
+	}
+
+	storageMetadataRepairCmd = &cobra.Command{
+		Use:   "repair",
+		Short: "restore the metadata of a runtime state database from an exported file",
+		Run:   doMetadataRepair,
+	}
+
+	storageMetadataReconstructCmd = &cobra.Command{
+		Use:   "reconstruct",
+		Short: "reconstruct the metadata of a runtime state database from its roots and write logs",
+		Run:   doMetadataReconstruct,
+	}
+
+	storageMetadataFlags = flag.NewFlagSet("", flag.ContinueOnError)
+)
+
+func doMetadataExport(*cobra.Command, []string) {
+	var ok bool
+	defer func() {
+		if !ok {
+			os.Exit(1)
+		}
+	}()
+
+	cfg := metadataNodeDBConfig(true)
+	if cfg == nil {
+		return
+	}
+
+	fn := viper.GetString(cfgMetadataFile)
+	if fn == "" {
+		logger.Error("metadata dump file must be set")
+		return
+	}
+	f, err := os.Create(fn)
+	if err != nil {
+		logger.Error("failed to create metadata dump file",
+			"err", err,
+			"fn", fn,
+		)
+		return
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err = badger.ExportMetadata(cfg, w); err != nil {
+		logger.Error("failed to export metadata",
+			"err", err,
+		)
+		return
+	}
+	if err = w.Flush(); err != nil {
+		logger.Error("failed to write metadata dump file",
+			"err", err,
+			"fn", fn,
+		)
+		return
+	}
+	if err = f.Sync(); err != nil {
+		logger.Error("failed to sync metadata dump file",
+			"err", err,
+			"fn", fn,
+		)
+		return
+	}
+
+	ok = true
+}
+
+func doMetadataRepair(*cobra.Command, []string) {
+	var ok bool
+	defer func() {
+		if !ok {
+			os.Exit(1)
+		}
+	}()
+
+	cfg := metadataNodeDBConfig(false)
+	if cfg == nil {
+		return
+	}
+
+	fn := viper.GetString(cfgMetadataFile)
+	if fn == "" {
+		logger.Error("metadata dump file must be set")
+		return
+	}
+	f, err := os.Open(fn)
+	if err != nil {
+		logger.Error("failed to open metadata dump file",
+			"err", err,
+			"fn", fn,
+		)
+		return
+	}
+	defer f.Close()
+
+	if err = badger.RepairMetadata(cfg, bufio.NewReader(f)); err != nil {
+		logger.Error("failed to repair metadata",
+			"err", err,
+		)
+		return
+	}
+
+	ok = true
+}
+
+func doMetadataReconstruct(*cobra.Command, []string) {
+	var ok bool
+	defer func() {
+		if !ok {
+			os.Exit(1)
+		}
+	}()
+
+	cfg := metadataNodeDBConfig(false)
+	if cfg == nil {
+		return
+	}
+
+	if err := badger.ReconstructMetadata(cfg, viper.GetUint64(cfgMetadataEarliestVersion)); err != nil {
+		logger.Error("failed to reconstruct metadata",
+			"err", err,
+		)
+		return
+	}
+
+	ok = true
+}
+
+// metadataNodeDBConfig returns the node database configuration of the runtime state database
+// selected by the flags, or nil in case of errors.
+func metadataNodeDBConfig(readOnly bool) *api.Config {
+	if err := cmdCommon.Init(); err != nil {
+		cmdCommon.EarlyLogAndExit(err)
+	}
+
+	dataDir := cmdCommon.DataDir()
+	if dataDir == "" {
+		logger.Error("data directory must be set")
+		return nil
+	}
+
+	var runtimeID common.Namespace
+	if err := runtimeID.UnmarshalHex(viper.GetString(cfgMetadataRuntimeID)); err != nil {
+		logger.Error("malformed runtime identifier",
+			"err", err,
+		)
+		return nil
+	}
+
+	// Only the badger backend supports metadata recovery.
+	dbDir := filepath.Join(
+		runtimeConfig.GetRuntimeStateDir(dataDir, runtimeID),
+		storageDatabase.DefaultFileName(storageDatabase.BackendNameBadgerDB),
+	)
+	if _, err := os.Stat(dbDir); err != nil {
+		logger.Error("badger runtime state database not found",
+			"err", err,
+			"dir", dbDir,
+		)
+		return nil
+	}
+
+	return (&storageAPI.Config{
+		DB:           dbDir,
+		Namespace:    runtimeID,
+		MaxCacheSize: int64(config.ParseSizeInBytes(config.GlobalConfig.Storage.MaxCacheSize)),
+		ReadOnly:     readOnly,
+	}).ToNodeDB()
+}
+
+func init() {
+	storageMetadataFlags.String(cfgMetadataRuntimeID, "", "the runtime identifier (hex) of the database")
+	storageMetadataFlags.String(cfgMetadataFile, "", "the metadata dump file")
+	storageMetadataFlags.Uint64(cfgMetadataEarliestVersion, 0, "the earliest version to reconstruct metadata for")
+	_ = viper.BindPFlags(storageMetadataFlags)
+
+	storageMetadataExportCmd.Flags().AddFlagSet(storageMetadataFlags)
+	storageMetadataRepairCmd.Flags().AddFlagSet(storageMetadataFlags)
+	storageMetadataReconstructCmd.Flags().AddFlagSet(storageMetadataFlags)
+	storageMetadataCmd.AddCommand(
+		storageMetadataExportCmd,
+		storageMetadataRepairCmd,
+		storageMetadataReconstructCmd,
+	)
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// rootFlagHelp is the help text of the root flags.
+const rootFlagHelp = "the root (<runtime-id>:<version>:<type>:<hash>, or <version>:<type>:<hash> together with the runtime identifier) selecting the runtime and version instead"
+
+// runtimeAndVersion returns the runtime identifier and the version selected by the given flag
+// values. In case a root is given, the runtime identifier and the version are taken from the root
+// and the runtime identifier is only needed for the short root form.
+func runtimeAndVersion(rawRuntimeID string, version uint64, rawRoot string) (common.Namespace, uint64, error) {
+	var runtimeID common.Namespace
+	if rawRoot == "" {
+		if err := runtimeID.UnmarshalHex(rawRuntimeID); err != nil {
+			return runtimeID, 0, fmt.Errorf("malformed runtime identifier: %w", err)
+		}
+		return runtimeID, version, nil
+	}
+
+	var (
+		root node.Root
+		err  error
+	)
+	switch rawRuntimeID {
+	case "":
+		root, err = node.ParseRoot(rawRoot)
+	default:
+		if err = runtimeID.UnmarshalHex(rawRuntimeID); err != nil {
+			return runtimeID, 0, fmt.Errorf("malformed runtime identifier: %w", err)
+		}
+		root, err = node.ParseRootInNamespace(runtimeID, rawRoot)
+	}
+	if err != nil {
+		return runtimeID, 0, err
+	}
+	return root.Namespace, root.Version, nil
+}
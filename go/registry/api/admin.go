@@ -0,0 +1,213 @@
+package api
+
+import (
+	"fmt"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	governance "github.com/oasisprotocol/oasis-core/go/governance/api"
+)
+
+// MaxAdminFreezeReasonLength is the maximum length of the reason of an administrative freeze.
+const MaxAdminFreezeReasonLength = 256
+
+// AdminFreezeNodeSignatureContext is the context used for signing administrative node freezes
+// when multisig administrative authorization is used.
+var AdminFreezeNodeSignatureContext = signature.NewContext("oasis-core/registry: admin freeze node")
+
+// AdminAuthorization specifies how registry administrative actions are authorized.
+type AdminAuthorization uint8
+
+const (
+	// AdminAuthorizationGovernance requires administrative actions to be submitted as consensus
+	// governance proposals and executed once the proposal passes.
+	AdminAuthorizationGovernance AdminAuthorization = 0
+	// AdminAuthorizationMultisig requires administrative actions to be signed by a threshold of
+	// the configured administrator keys. This is meant for private networks.
+	AdminAuthorizationMultisig AdminAuthorization = 1
+
+	aaGovernance = "governance"
+	aaMultisig   = "multisig"
+)
+
+// String returns a string representation of an administrative authorization.
+func (aa AdminAuthorization) String() string {
+	kind, err := aa.MarshalText()
+	if err != nil {
+		return "[unsupported administrative authorization]"
+	}
+	return string(kind)
+}
+
+func (aa AdminAuthorization) MarshalText() ([]byte, error) {
+	switch aa {
+	case AdminAuthorizationGovernance:
+		return []byte(aaGovernance), nil
+	case AdminAuthorizationMultisig:
+		return []byte(aaMultisig), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported administrative authorization", ErrInvalidArgument)
+	}
+}
+
+func (aa *AdminAuthorization) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case aaGovernance:
+		*aa = AdminAuthorizationGovernance
+	case aaMultisig:
+		*aa = AdminAuthorizationMultisig
+	default:
+		return fmt.Errorf("%w: unsupported administrative authorization: '%s'", ErrInvalidArgument, string(text))
+	}
+	return nil
+}
+
+// AdminFreezeNode is an administrative action that freezes or unfreezes a node.
+//
+// When governance authorization is used, the action is the content of an admin action proposal
+// for the registry module. When multisig authorization is used, it is submitted as a
+// MultiSignedAdminFreezeNode in an AdminFreezeNode transaction.
+type AdminFreezeNode struct {
+	// NodeID is the identifier of the node.
+	NodeID signature.PublicKey `json:"node_id"`
+
+	// Unfreeze is true iff the node should be unfrozen instead of frozen.
+	Unfreeze bool `json:"unfreeze,omitempty"`
+
+	// Reason is the reason for the action, recorded in the node status when freezing.
+	Reason string `json:"reason"`
+
+	// Expiry is the epoch at which the freeze expires and the node is unfrozen automatically.
+	// Only valid when freezing, use FreezeForever to freeze the node indefinitely.
+	Expiry beacon.EpochTime `json:"expiry,omitempty"`
+
+	// Epoch is the epoch in which a multisig-authorized action is valid in order to prevent
+	// replays. It is ignored for governance-authorized actions.
+	Epoch beacon.EpochTime `json:"epoch,omitempty"`
+}
+
+// ValidateBasic performs basic administrative freeze validity checks.
+func (a *AdminFreezeNode) ValidateBasic() error {
+	if !a.NodeID.IsValid() {
+		return fmt.Errorf("%w: invalid node identifier", ErrInvalidArgument)
+	}
+	switch {
+	case len(a.Reason) == 0:
+		return fmt.Errorf("%w: missing reason", ErrInvalidArgument)
+	case len(a.Reason) > MaxAdminFreezeReasonLength:
+		return fmt.Errorf("%w: reason too long", ErrInvalidArgument)
+	}
+	switch a.Unfreeze {
+	case true:
+		if a.Expiry != 0 {
+			return fmt.Errorf("%w: expiry set when unfreezing", ErrInvalidArgument)
+		}
+	case false:
+		if a.Expiry == 0 {
+			return fmt.Errorf("%w: missing expiry", ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
+// MultiSignedAdminFreezeNode is an administrative freeze signed by the administrators.
+type MultiSignedAdminFreezeNode struct {
+	signature.MultiSigned
+}
+
+// Open verifies that the administrative freeze has been authorized by a threshold of the
+// administrator keys and is valid in the given epoch, and returns it.
+func (s *MultiSignedAdminFreezeNode) Open(params *ConsensusParameters, epoch beacon.EpochTime) (*AdminFreezeNode, error) {
+	if params.AdminAuthorization != AdminAuthorizationMultisig {
+		return nil, fmt.Errorf("%w: multisig administrative authorization not enabled", ErrForbidden)
+	}
+
+	var action AdminFreezeNode
+	if err := s.MultiSigned.Open(AdminFreezeNodeSignatureContext, &action); err != nil {
+		return nil, ErrInvalidSignature
+	}
+	var signers int
+	for _, pk := range params.AdminKeys {
+		if s.MultiSigned.IsSignedBy(pk) {
+			signers++
+		}
+	}
+	if signers < int(params.AdminThreshold) {
+		return nil, fmt.Errorf("%w: insufficient administrator signatures (%d of %d)", ErrForbidden, signers, params.AdminThreshold)
+	}
+
+	if err := action.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	if action.Epoch != epoch {
+		return nil, fmt.Errorf("%w: action not valid in epoch %d", ErrInvalidArgument, epoch)
+	}
+	if err := action.validateExpiry(epoch); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+func (a *AdminFreezeNode) validateExpiry(epoch beacon.EpochTime) error {
+	if !a.Unfreeze && a.Expiry <= epoch {
+		return fmt.Errorf("%w: expiry in the past", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// NewAdminFreezeNodeProposal creates a new governance admin action proposal for the given
+// administrative freeze.
+func NewAdminFreezeNodeProposal(action *AdminFreezeNode) *governance.AdminActionProposal {
+	return &governance.AdminActionProposal{
+		Module: ModuleName,
+		Method: string(MethodAdminFreezeNode),
+		Action: cbor.Marshal(action),
+	}
+}
+
+// OpenAdminFreezeNodeProposal decodes the administrative freeze from a passed governance admin
+// action proposal and verifies that it is valid for execution in the given epoch.
+func OpenAdminFreezeNodeProposal(params *ConsensusParameters, proposal *governance.AdminActionProposal, epoch beacon.EpochTime) (*AdminFreezeNode, error) {
+	if params.AdminAuthorization != AdminAuthorizationGovernance {
+		return nil, fmt.Errorf("%w: governance administrative authorization not enabled", ErrForbidden)
+	}
+	if proposal.Module != ModuleName || proposal.Method != string(MethodAdminFreezeNode) {
+		return nil, fmt.Errorf("%w: unsupported admin action: %s/%s", ErrInvalidArgument, proposal.Module, proposal.Method)
+	}
+
+	var action AdminFreezeNode
+	if err := cbor.Unmarshal(proposal.Action, &action); err != nil {
+		return nil, fmt.Errorf("%w: malformed admin action: %w", ErrInvalidArgument, err)
+	}
+	if err := action.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	if err := action.validateExpiry(epoch); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// AdminFreeze is an administrative freeze of a node.
+type AdminFreeze struct {
+	// Reason is the reason for the freeze.
+	Reason string `json:"reason"`
+
+	// Expiry is the epoch at which the freeze expires.
+	Expiry beacon.EpochTime `json:"expiry"`
+}
+
+// NodeAdminFreezeEvent signifies an administrative freeze or unfreeze of a node.
+type NodeAdminFreezeEvent struct {
+	// NodeID is the identifier of the node.
+	NodeID signature.PublicKey `json:"node_id"`
+
+	// Freeze is the administrative freeze, nil if the node has been unfrozen.
+	Freeze *AdminFreeze `json:"freeze,omitempty"`
+}
+
+// EventKind returns a string representation of this event's kind.
+func (e *NodeAdminFreezeEvent) EventKind() string {
+	return "node_admin_freeze"
+}
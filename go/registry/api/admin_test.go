@@ -0,0 +1,60 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+func TestAdminFreezeNodeProposal(t *testing.T) {
+	require := require.New(t)
+
+	nodeID := signature.NewPublicKey("0000000000000000000000000000000000000000000000000000000000000001")
+	govParams := &ConsensusParameters{AdminAuthorization: AdminAuthorizationGovernance}
+	multisigParams := &ConsensusParameters{AdminAuthorization: AdminAuthorizationMultisig}
+
+	action := &AdminFreezeNode{NodeID: nodeID, Reason: "misbehaving", Expiry: 20}
+	proposal := NewAdminFreezeNodeProposal(action)
+	require.NoError(proposal.ValidateBasic())
+
+	opened, err := OpenAdminFreezeNodeProposal(govParams, proposal, 10)
+	require.NoError(err, "OpenAdminFreezeNodeProposal")
+	require.EqualValues(action, opened)
+
+	_, err = OpenAdminFreezeNodeProposal(multisigParams, proposal, 10)
+	require.ErrorIs(err, ErrForbidden, "governance proposals should be rejected with multisig authorization")
+
+	_, err = OpenAdminFreezeNodeProposal(govParams, proposal, 20)
+	require.ErrorIs(err, ErrInvalidArgument, "expired freezes should be rejected")
+
+	unfreeze := NewAdminFreezeNodeProposal(&AdminFreezeNode{NodeID: nodeID, Reason: "resolved", Unfreeze: true})
+	_, err = OpenAdminFreezeNodeProposal(govParams, unfreeze, 20)
+	require.NoError(err, "unfreezing should not require an expiry")
+
+	wrongMethod := *proposal
+	wrongMethod.Method = string(MethodUnfreezeNode)
+	_, err = OpenAdminFreezeNodeProposal(govParams, &wrongMethod, 10)
+	require.ErrorIs(err, ErrInvalidArgument, "other admin actions should be rejected")
+
+	for _, tc := range []struct {
+		action AdminFreezeNode
+		valid  bool
+	}{
+		{AdminFreezeNode{NodeID: nodeID, Reason: "test", Expiry: 1}, true},
+		{AdminFreezeNode{NodeID: nodeID, Reason: "test", Unfreeze: true}, true},
+		{AdminFreezeNode{Reason: "test", Expiry: 1}, false},
+		{AdminFreezeNode{NodeID: nodeID, Expiry: 1}, false},
+		{AdminFreezeNode{NodeID: nodeID, Reason: "test"}, false},
+		{AdminFreezeNode{NodeID: nodeID, Reason: "test", Unfreeze: true, Expiry: 1}, false},
+	} {
+		err = tc.action.ValidateBasic()
+		switch tc.valid {
+		case true:
+			require.NoError(err, "ValidateBasic(%+v)", tc.action)
+		case false:
+			require.ErrorIs(err, ErrInvalidArgument, "ValidateBasic(%+v)", tc.action)
+		}
+	}
+}
@@ -132,6 +132,9 @@ var (
 	MethodRegisterRuntime = transaction.NewMethodName(ModuleName, "RegisterRuntime", Runtime{})
 	// MethodProveFreshness is the method name for freshness proofs.
 	MethodProveFreshness = transaction.NewMethodName(ModuleName, "ProveFreshness", [32]byte{})
+	// MethodAdminFreezeNode is the method name for multisig-authorized administrative node
+	// freezes.
+	MethodAdminFreezeNode = transaction.NewMethodName(ModuleName, "AdminFreezeNode", MultiSignedAdminFreezeNode{})
 
 	// Methods is the list of all methods supported by the registry backend.
 	Methods = []transaction.MethodName{
@@ -141,6 +144,7 @@ var (
 		MethodUnfreezeNode,
 		MethodRegisterRuntime,
 		MethodProveFreshness,
+		MethodAdminFreezeNode,
 	}
 
 	// RuntimesRequiredRoles are the Node roles that require runtimes.
@@ -292,6 +296,11 @@ func NewProveFreshnessTx(nonce uint64, fee *transaction.Fee, blob [32]byte) *tra
 	return transaction.NewTransaction(nonce, fee, MethodProveFreshness, blob)
 }
 
+// NewAdminFreezeNodeTx creates a new administrative node freeze transaction.
+func NewAdminFreezeNodeTx(nonce uint64, fee *transaction.Fee, sigAction *MultiSignedAdminFreezeNode) *transaction.Transaction {
+	return transaction.NewTransaction(nonce, fee, MethodAdminFreezeNode, sigAction)
+}
+
 // EntityEvent is the event that is returned via WatchEntities to signify
 // entity registration changes and updates.
 type EntityEvent struct {
@@ -379,6 +388,7 @@ type Event struct {
 	EntityEvent           *EntityEvent           `json:"entity,omitempty"`
 	NodeEvent             *NodeEvent             `json:"node,omitempty"`
 	NodeUnfrozenEvent     *NodeUnfrozenEvent     `json:"node_unfrozen,omitempty"`
+	NodeAdminFreezeEvent  *NodeAdminFreezeEvent  `json:"node_admin_freeze,omitempty"`
 }
 
 // NodeList is a per-epoch immutable node list.
@@ -1416,6 +1426,17 @@ type ConsensusParameters struct {
 
 	// MaxRuntimeDeployments is the maximum number of runtime deployments.
 	MaxRuntimeDeployments uint8 `json:"max_runtime_deployments,omitempty"`
+
+	// AdminAuthorization specifies how administrative actions (e.g., freezing nodes) are
+	// authorized.
+	AdminAuthorization AdminAuthorization `json:"admin_authorization,omitempty"`
+
+	// AdminKeys are the administrator keys used with multisig administrative authorization.
+	AdminKeys []signature.PublicKey `json:"admin_keys,omitempty"`
+
+	// AdminThreshold is the number of administrator signatures required with multisig
+	// administrative authorization.
+	AdminThreshold uint8 `json:"admin_threshold,omitempty"`
 }
 
 // ConsensusParameterChanges are allowed registry consensus parameter changes.
@@ -1484,6 +1505,8 @@ const (
 	GasOpRuntimeEpochMaintenance transaction.Op = "runtime_epoch_maintenance"
 	// GasOpProveFreshness is the gas operation identifier for freshness proofs.
 	GasOpProveFreshness transaction.Op = "prove_freshness"
+	// GasOpAdminFreezeNode is the gas operation identifier for administrative node freezes.
+	GasOpAdminFreezeNode transaction.Op = "admin_freeze_node"
 )
 
 // XXX: Define reasonable default gas costs.
@@ -1497,6 +1520,7 @@ var DefaultGasCosts = transaction.Costs{
 	GasOpRegisterRuntime:         1000,
 	GasOpRuntimeEpochMaintenance: 1000,
 	GasOpProveFreshness:          1000,
+	GasOpAdminFreezeNode:         1000,
 }
 
 const (
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"time"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// committeeRequiredRoles are the node roles required for each committee kind.
+var committeeRequiredRoles = map[scheduler.CommitteeKind]node.RolesMask{
+	scheduler.KindComputeExecutor: node.RoleComputeWorker,
+}
+
+// ElectionContext is the state needed to evaluate committee election predicates.
+type ElectionContext struct {
+	// Logger is the logger used for attestation verification.
+	Logger *logging.Logger
+
+	// Epoch is the epoch for which the election is being performed.
+	Epoch beacon.EpochTime
+	// Now is the current consensus time.
+	Now time.Time
+	// Height is the current consensus height.
+	Height uint64
+
+	// Params are the registry consensus parameters.
+	Params *ConsensusParameters
+	// DebugBypassStake is true iff all staking related checks should be skipped.
+	DebugBypassStake bool
+	// StakeThresholds are the global staking thresholds.
+	StakeThresholds map[staking.ThresholdKind]quantity.Quantity
+}
+
+// ElectionCandidate is a node considered in a committee election.
+type ElectionCandidate struct {
+	// Node is the node descriptor.
+	Node *node.Node
+	// Status is the node status.
+	Status *NodeStatus
+	// Escrow is the escrow account of the node's entity. It may be nil when stake is bypassed.
+	Escrow *staking.EscrowAccount
+	// EntityHasValidator is true iff the node's entity has a node in the current validator set.
+	EntityHasValidator bool
+}
+
+// NodeRuntimeIneligibility returns the reasons why the candidate cannot be elected into any
+// committee for the given runtime. An empty result means the candidate is suitable.
+//
+// The result is meant for diagnostics and mirrors the checks performed by committee elections.
+func NodeRuntimeIneligibility(
+	ec *ElectionContext,
+	c *ElectionCandidate,
+	rt *Runtime,
+	runtimeSuspended bool,
+) []scheduler.IneligibilityReason {
+	var reasons []scheduler.IneligibilityReason
+
+	if runtimeSuspended {
+		reasons = append(reasons, scheduler.IneligibleRuntimeSuspended)
+	}
+
+	switch {
+	case c.Status.IsFrozen(), c.Status.IsAdminFrozen(ec.Epoch):
+		reasons = append(reasons, scheduler.IneligibleFrozen)
+	case c.Status.IsSuspended(rt.ID, ec.Epoch):
+		reasons = append(reasons, scheduler.IneligibleFaulty)
+	}
+	if c.Status.ElectionEligibleAfter == 0 || ec.Epoch <= c.Status.ElectionEligibleAfter {
+		reasons = append(reasons, scheduler.IneligibleNotYetEligible)
+	}
+
+	if !ec.DebugBypassStake && c.Escrow != nil {
+		if err := c.Escrow.CheckStakeClaims(ec.StakeThresholds); err != nil {
+			reasons = append(reasons, scheduler.IneligibleInsufficientStake)
+		}
+	}
+
+	activeDeployment := rt.ActiveDeployment(ec.Epoch)
+	if activeDeployment == nil {
+		return append(reasons, scheduler.IneligibleNoActiveDeployment)
+	}
+
+	var nrt *node.Runtime
+	for _, r := range c.Node.Runtimes {
+		if r.ID.Equal(&rt.ID) && r.Version.ToU64() == activeDeployment.Version.ToU64() {
+			nrt = r
+			break
+		}
+	}
+	if nrt == nil {
+		return append(reasons, scheduler.IneligibleVersionMismatch)
+	}
+
+	switch rt.TEEHardware {
+	case node.TEEHardwareInvalid:
+		if nrt.Capabilities.TEE != nil {
+			reasons = append(reasons, scheduler.IneligibleStaleAttestation)
+		}
+	default:
+		if nrt.Capabilities.TEE == nil {
+			reasons = append(reasons, scheduler.IneligibleStaleAttestation)
+			break
+		}
+		if err := VerifyNodeRuntimeEnclaveIDs(ec.Logger, c.Node.ID, nrt, rt, ec.Params.TEEFeatures, ec.Now, ec.Height); err != nil {
+			reasons = append(reasons, scheduler.IneligibleStaleAttestation)
+		}
+	}
+
+	return reasons
+}
+
+// CommitteeIneligibility returns the reasons why the candidate cannot be elected into the given
+// committee kind with the given role. An empty result means the candidate is suitable.
+func CommitteeIneligibility(
+	ec *ElectionContext,
+	c *ElectionCandidate,
+	rt *Runtime,
+	runtimeSuspended bool,
+	kind scheduler.CommitteeKind,
+	role scheduler.Role,
+) []scheduler.IneligibilityReason {
+	var reasons []scheduler.IneligibilityReason
+	if !c.Node.HasRoles(committeeRequiredRoles[kind]) {
+		reasons = append(reasons, scheduler.IneligibleMissingRole)
+	}
+	if cs, ok := rt.Constraints[kind][role]; ok && cs.ValidatorSet != nil && !c.EntityHasValidator {
+		reasons = append(reasons, scheduler.IneligibleNotValidator)
+	}
+	return append(reasons, NodeRuntimeIneligibility(ec, c, rt, runtimeSuspended)...)
+}
+
+// EvaluateElectionEligibility evaluates the election eligibility matrix of the candidate for
+// every committee kind and role of the given runtimes.
+//
+// The suspended map specifies which of the given runtimes are suspended.
+func EvaluateElectionEligibility(
+	ec *ElectionContext,
+	c *ElectionCandidate,
+	runtimes []*Runtime,
+	suspended map[common.Namespace]bool,
+) *scheduler.ElectionEligibility {
+	result := &scheduler.ElectionEligibility{
+		NodeID: c.Node.ID,
+		Height: int64(ec.Height), //nolint:gosec
+		Epoch:  ec.Epoch,
+	}
+	for _, rt := range runtimes {
+		rtElig := scheduler.RuntimeEligibility{
+			RuntimeID: rt.ID,
+		}
+		for kind := scheduler.KindComputeExecutor; kind < scheduler.MaxCommitteeKind; kind++ {
+			for _, role := range []scheduler.Role{scheduler.RoleWorker, scheduler.RoleBackupWorker} {
+				reasons := CommitteeIneligibility(ec, c, rt, suspended[rt.ID], kind, role)
+				rtElig.Committees = append(rtElig.Committees, scheduler.CommitteeEligibility{
+					Kind:     kind,
+					Role:     role,
+					Eligible: len(reasons) == 0,
+					Reasons:  reasons,
+				})
+			}
+		}
+		result.Runtimes = append(result.Runtimes, rtElig)
+	}
+	return result
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+func TestElectionEligibility(t *testing.T) {
+	require := require.New(t)
+
+	rtID := common.NewTestNamespaceFromSeed([]byte("eligibility runtime"), 0)
+	nodeID := signature.NewPublicKey("0000000000000000000000000000000000000000000000000000000000000001")
+	rtVersion := version.Version{Major: 1}
+
+	ec := &ElectionContext{
+		Logger: logging.GetLogger("registry/api/tests"),
+		Epoch:  10,
+		Now:    time.Now(),
+		Height: 100,
+		Params: &ConsensusParameters{},
+		StakeThresholds: map[staking.ThresholdKind]quantity.Quantity{
+			staking.KindNodeCompute: *quantity.NewFromUint64(1000),
+		},
+	}
+	rt := &Runtime{
+		ID:          rtID,
+		Kind:        KindCompute,
+		TEEHardware: node.TEEHardwareInvalid,
+		Deployments: []*VersionInfo{
+			{Version: rtVersion, ValidFrom: 0},
+		},
+	}
+	newCandidate := func() *ElectionCandidate {
+		var escrow staking.EscrowAccount
+		escrow.Active.Balance = *quantity.NewFromUint64(1000)
+		escrow.StakeAccumulator.AddClaimUnchecked("node", staking.GlobalStakeThresholds(staking.KindNodeCompute))
+
+		return &ElectionCandidate{
+			Node: &node.Node{
+				ID:    nodeID,
+				Roles: node.RoleComputeWorker,
+				Runtimes: []*node.Runtime{
+					{ID: rtID, Version: rtVersion},
+				},
+			},
+			Status: &NodeStatus{ElectionEligibleAfter: 1},
+			Escrow: &escrow,
+		}
+	}
+	executorWorker := func(e *scheduler.ElectionEligibility) scheduler.CommitteeEligibility {
+		require.Len(e.Runtimes, 1)
+		require.Equal(rtID, e.Runtimes[0].RuntimeID)
+		for _, c := range e.Runtimes[0].Committees {
+			if c.Kind == scheduler.KindComputeExecutor && c.Role == scheduler.RoleWorker {
+				return c
+			}
+		}
+		require.FailNow("missing executor worker eligibility")
+		return scheduler.CommitteeEligibility{}
+	}
+
+	// Eligible node.
+	c := newCandidate()
+	e := EvaluateElectionEligibility(ec, c, []*Runtime{rt}, nil)
+	require.Equal(nodeID, e.NodeID)
+	require.True(e.IsEligible())
+	require.Len(e.Runtimes[0].Committees, 2, "executor worker and backup worker should be evaluated")
+	for _, ce := range e.Runtimes[0].Committees {
+		require.True(ce.Eligible)
+		require.Empty(ce.Reasons)
+	}
+
+	// Missing role.
+	c = newCandidate()
+	c.Node.Roles = node.RoleObserver
+	ce := executorWorker(EvaluateElectionEligibility(ec, c, []*Runtime{rt}, nil))
+	require.False(ce.Eligible)
+	require.Equal([]scheduler.IneligibilityReason{scheduler.IneligibleMissingRole}, ce.Reasons)
+
+	// Insufficient stake.
+	c = newCandidate()
+	c.Escrow.Active.Balance = *quantity.NewFromUint64(999)
+	ce = executorWorker(EvaluateElectionEligibility(ec, c, []*Runtime{rt}, nil))
+	require.False(ce.Eligible)
+	require.Equal([]scheduler.IneligibilityReason{scheduler.IneligibleInsufficientStake}, ce.Reasons)
+
+	// Insufficient stake is ignored when stake is bypassed.
+	bypassEc := *ec
+	bypassEc.DebugBypassStake = true
+	ce = executorWorker(EvaluateElectionEligibility(&bypassEc, c, []*Runtime{rt}, nil))
+	require.True(ce.Eligible)
+
+	// Stale (missing) attestation.
+	teeRt := *rt
+	teeRt.TEEHardware = node.TEEHardwareIntelSGX
+	c = newCandidate()
+	ce = executorWorker(EvaluateElectionEligibility(ec, c, []*Runtime{&teeRt}, nil))
+	require.False(ce.Eligible)
+	require.Equal([]scheduler.IneligibilityReason{scheduler.IneligibleStaleAttestation}, ce.Reasons)
+
+	// Suspended runtime and faults over threshold.
+	c = newCandidate()
+	c.Status.RecordFailure(rtID, ec.Epoch)
+	ce = executorWorker(EvaluateElectionEligibility(ec, c, []*Runtime{rt}, map[common.Namespace]bool{rtID: true}))
+	require.False(ce.Eligible)
+	require.Equal([]scheduler.IneligibilityReason{
+		scheduler.IneligibleRuntimeSuspended,
+		scheduler.IneligibleFaulty,
+	}, ce.Reasons)
+
+	// Administratively frozen node.
+	c = newCandidate()
+	c.Status.ApplyAdminFreeze(&AdminFreezeNode{NodeID: nodeID, Reason: "test", Expiry: ec.Epoch + 1})
+	ce = executorWorker(EvaluateElectionEligibility(ec, c, []*Runtime{rt}, nil))
+	require.False(ce.Eligible)
+	require.Equal([]scheduler.IneligibilityReason{scheduler.IneligibleFrozen}, ce.Reasons)
+
+	// Expired administrative freeze.
+	c.Status.ApplyAdminFreeze(&AdminFreezeNode{NodeID: nodeID, Reason: "test", Expiry: ec.Epoch})
+	ce = executorWorker(EvaluateElectionEligibility(ec, c, []*Runtime{rt}, nil))
+	require.True(ce.Eligible)
+
+	// Outdated runtime version.
+	c = newCandidate()
+	c.Node.Runtimes[0].Version = version.Version{Major: 0, Minor: 9}
+	ce = executorWorker(EvaluateElectionEligibility(ec, c, []*Runtime{rt}, nil))
+	require.False(ce.Eligible)
+	require.Equal([]scheduler.IneligibilityReason{scheduler.IneligibleVersionMismatch}, ce.Reasons)
+
+	// Validator set constraint only applies to the constrained role.
+	constrainedRt := *rt
+	constrainedRt.Constraints = map[scheduler.CommitteeKind]map[scheduler.Role]SchedulingConstraints{
+		scheduler.KindComputeExecutor: {
+			scheduler.RoleWorker: {ValidatorSet: &ValidatorSetConstraint{}},
+		},
+	}
+	c = newCandidate()
+	e = EvaluateElectionEligibility(ec, c, []*Runtime{&constrainedRt}, nil)
+	require.True(e.IsEligible(), "backup worker role should still be eligible")
+	ce = executorWorker(e)
+	require.False(ce.Eligible)
+	require.Equal([]scheduler.IneligibilityReason{scheduler.IneligibleNotValidator}, ce.Reasons)
+
+	// Recently registered node.
+	c = newCandidate()
+	c.Status.ElectionEligibleAfter = ec.Epoch
+	ce = executorWorker(EvaluateElectionEligibility(ec, c, []*Runtime{rt}, nil))
+	require.False(ce.Eligible)
+	require.Equal([]scheduler.IneligibilityReason{scheduler.IneligibleNotYetEligible}, ce.Reasons)
+}
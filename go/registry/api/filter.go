@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/consensus/api/eventfilter"
+)
+
+// EventField is a registry event field that can be used in event filter conditions.
+type EventField string
+
+const (
+	// EventFieldKind is the event kind (e.g., "node" or "runtime_started").
+	EventFieldKind EventField = "kind"
+	// EventFieldEntity is the entity of entity and node events.
+	EventFieldEntity EventField = "entity"
+	// EventFieldNode is the node of node and node unfrozen events.
+	EventFieldNode EventField = "node"
+	// EventFieldRuntime is the runtime of runtime started and runtime suspended events.
+	EventFieldRuntime EventField = "runtime"
+)
+
+// EventCondition is a registry event filter condition.
+//
+// Exactly the value matching the field's type must be set. Conditions on fields that an event does
+// not have never match that event. All fields only support (in)equality.
+type EventCondition struct {
+	// Field is the event field the condition applies to.
+	Field EventField `json:"field"`
+	// Comparator is the comparator.
+	Comparator eventfilter.Comparator `json:"comparator"`
+
+	// Kind is the event kind for kind conditions.
+	Kind string `json:"kind,omitempty"`
+	// ID is the entity or node identifier for entity and node conditions.
+	ID *signature.PublicKey `json:"id,omitempty"`
+	// RuntimeID is the runtime identifier for runtime conditions.
+	RuntimeID *common.Namespace `json:"runtime_id,omitempty"`
+}
+
+// EventFilter is a registry event filter.
+type EventFilter = eventfilter.Filter[*Event, EventCondition]
+
+// EventsQuery is a filtered registry events query.
+type EventsQuery struct {
+	// StartHeight is the first height to scan (inclusive).
+	StartHeight int64 `json:"start_height"`
+	// EndHeight is the last height to scan (inclusive).
+	EndHeight int64 `json:"end_height"`
+	// Filter is the filter events must match. If nil, all events match.
+	Filter *EventFilter `json:"filter,omitempty"`
+}
+
+// NewKindCondition creates a new condition matching events of the given kind.
+func NewKindCondition(kind string) EventCondition {
+	return EventCondition{Field: EventFieldKind, Comparator: eventfilter.Equal, Kind: kind}
+}
+
+// NewEntityCondition creates a new condition matching events of the given entity.
+func NewEntityCondition(id signature.PublicKey) EventCondition {
+	return EventCondition{Field: EventFieldEntity, Comparator: eventfilter.Equal, ID: &id}
+}
+
+// NewNodeCondition creates a new condition matching events of the given node.
+func NewNodeCondition(id signature.PublicKey) EventCondition {
+	return EventCondition{Field: EventFieldNode, Comparator: eventfilter.Equal, ID: &id}
+}
+
+// NewRuntimeCondition creates a new condition matching events of the given runtime.
+func NewRuntimeCondition(id common.Namespace) EventCondition {
+	return EventCondition{Field: EventFieldRuntime, Comparator: eventfilter.Equal, RuntimeID: &id}
+}
+
+// Validate checks that the condition is well-formed.
+func (c EventCondition) Validate() error {
+	if err := c.Comparator.Validate(); err != nil {
+		return err
+	}
+	if !c.Comparator.IsEquality() {
+		return fmt.Errorf("%w: registry event fields only support (in)equality", eventfilter.ErrInvalidFilter)
+	}
+
+	var valid bool
+	switch c.Field {
+	case EventFieldKind:
+		valid = c.Kind != "" && c.ID == nil && c.RuntimeID == nil
+	case EventFieldEntity, EventFieldNode:
+		valid = c.Kind == "" && c.ID != nil && c.RuntimeID == nil
+	case EventFieldRuntime:
+		valid = c.Kind == "" && c.ID == nil && c.RuntimeID != nil
+	default:
+		return fmt.Errorf("%w: unknown registry event field '%s'", eventfilter.ErrInvalidFilter, c.Field)
+	}
+	if !valid {
+		return fmt.Errorf("%w: malformed condition on registry event field '%s'", eventfilter.ErrInvalidFilter, c.Field)
+	}
+	return nil
+}
+
+// Matches returns true iff the given event satisfies the condition.
+func (c EventCondition) Matches(ev *Event) bool {
+	fields := ev.filterFields()
+	if fields == nil {
+		return false
+	}
+
+	var equal bool
+	switch c.Field {
+	case EventFieldKind:
+		equal = strings.Compare(fields.kind, c.Kind) == 0
+	case EventFieldEntity:
+		if fields.entity == nil {
+			return false
+		}
+		equal = fields.entity.Equal(*c.ID)
+	case EventFieldNode:
+		if fields.node == nil {
+			return false
+		}
+		equal = fields.node.Equal(*c.ID)
+	case EventFieldRuntime:
+		if fields.runtime == nil {
+			return false
+		}
+		equal = fields.runtime.Equal(c.RuntimeID)
+	default:
+		return false
+	}
+	return equal == (c.Comparator == eventfilter.Equal)
+}
+
+// eventFilterFields are the fields of a registry event that can be filtered on.
+type eventFilterFields struct {
+	kind    string
+	entity  *signature.PublicKey
+	node    *signature.PublicKey
+	runtime *common.Namespace
+}
+
+func (e *Event) filterFields() *eventFilterFields {
+	switch {
+	case e.EntityEvent != nil && e.EntityEvent.Entity != nil:
+		ev := e.EntityEvent
+		return &eventFilterFields{kind: ev.EventKind(), entity: &ev.Entity.ID}
+	case e.NodeEvent != nil && e.NodeEvent.Node != nil:
+		ev := e.NodeEvent
+		return &eventFilterFields{kind: ev.EventKind(), entity: &ev.Node.EntityID, node: &ev.Node.ID}
+	case e.NodeUnfrozenEvent != nil:
+		ev := e.NodeUnfrozenEvent
+		return &eventFilterFields{kind: ev.EventKind(), node: &ev.NodeID}
+	case e.NodeAdminFreezeEvent != nil:
+		ev := e.NodeAdminFreezeEvent
+		return &eventFilterFields{kind: ev.EventKind(), node: &ev.NodeID}
+	case e.RuntimeStartedEvent != nil && e.RuntimeStartedEvent.Runtime != nil:
+		ev := e.RuntimeStartedEvent
+		return &eventFilterFields{kind: ev.EventKind(), runtime: &ev.Runtime.ID}
+	case e.RuntimeSuspendedEvent != nil:
+		ev := e.RuntimeSuspendedEvent
+		return &eventFilterFields{kind: ev.EventKind(), runtime: &ev.RuntimeID}
+	default:
+		return nil
+	}
+}
+
+// GetFilteredEvents returns the registry events at the queried heights that match the query
+// filter.
+//
+// The filter is evaluated while scanning the events of each height so that only matching events
+// are returned.
+func GetFilteredEvents(ctx context.Context, backend Backend, query *EventsQuery) ([]*Event, error) {
+	return eventfilter.Scan(ctx, query.StartHeight, query.EndHeight, query.Filter, backend.GetEvents)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/consensus/api/eventfilter"
+)
+
+func TestEventFilter(t *testing.T) {
+	require := require.New(t)
+
+	entityID := signature.NewPublicKey("aaafffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	nodeID := signature.NewPublicKey("bbbfffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	rtID := common.NewTestNamespaceFromSeed([]byte("event filter runtime"), 0)
+
+	events := []*Event{
+		{EntityEvent: &EntityEvent{Entity: &entity.Entity{ID: entityID}, IsRegistration: true}},
+		{NodeEvent: &NodeEvent{Node: &node.Node{ID: nodeID, EntityID: entityID}, IsRegistration: true}},
+		{NodeUnfrozenEvent: &NodeUnfrozenEvent{NodeID: nodeID}},
+		{RuntimeStartedEvent: &RuntimeStartedEvent{Runtime: &Runtime{ID: rtID}}},
+		{RuntimeSuspendedEvent: &RuntimeSuspendedEvent{RuntimeID: rtID}},
+	}
+
+	matching := func(filter *EventFilter) []int {
+		var indices []int
+		for i, ev := range events {
+			if filter.Matches(ev) {
+				indices = append(indices, i)
+			}
+		}
+		return indices
+	}
+
+	for _, tc := range []struct {
+		name     string
+		filter   *EventFilter
+		expected []int
+	}{
+		{"Entity", eventfilter.Match[*Event](NewEntityCondition(entityID)), []int{0, 1}},
+		{"Node", eventfilter.Match[*Event](NewNodeCondition(nodeID)), []int{1, 2}},
+		{"Runtime", eventfilter.Match[*Event](NewRuntimeCondition(rtID)), []int{3, 4}},
+		{"KindAndNode", eventfilter.Match[*Event](
+			NewKindCondition((&NodeUnfrozenEvent{}).EventKind()),
+			NewNodeCondition(nodeID),
+		), []int{2}},
+		{"NotKind", eventfilter.Match[*Event](
+			EventCondition{Field: EventFieldKind, Comparator: eventfilter.NotEqual, Kind: (&NodeEvent{}).EventKind()},
+		), []int{0, 2, 3, 4}},
+	} {
+		require.NoError(tc.filter.Validate(), tc.name)
+		require.Equal(tc.expected, matching(tc.filter), tc.name)
+	}
+
+	err := eventfilter.Match[*Event](
+		EventCondition{Field: EventFieldRuntime, Comparator: eventfilter.Greater, RuntimeID: &rtID},
+	).Validate()
+	require.ErrorIs(err, eventfilter.ErrInvalidFilter, "ordering comparators should be rejected")
+}
@@ -35,6 +35,8 @@ var (
 	methodStateToGenesis = serviceName.NewMethod("StateToGenesis", int64(0))
 	// methodGetEvents is the GetEvents method.
 	methodGetEvents = serviceName.NewMethod("GetEvents", int64(0))
+	// methodGetFilteredEvents is the GetFilteredEvents method.
+	methodGetFilteredEvents = serviceName.NewMethod("GetFilteredEvents", EventsQuery{})
 	// methodConsensusParameters is the ConsensusParameters method.
 	methodConsensusParameters = serviceName.NewMethod("ConsensusParameters", int64(0))
 
@@ -94,6 +96,10 @@ var (
 				MethodName: methodGetEvents.ShortName(),
 				Handler:    handlerGetEvents,
 			},
+			{
+				MethodName: methodGetFilteredEvents.ShortName(),
+				Handler:    handlerGetFilteredEvents,
+			},
 			{
 				MethodName: methodConsensusParameters.ShortName(),
 				Handler:    handlerConsensusParameters,
@@ -359,6 +365,29 @@ func handlerGetEvents(
 	return interceptor(ctx, height, info, handler)
 }
 
+func handlerGetFilteredEvents(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var query EventsQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return GetFilteredEvents(ctx, srv.(Backend), &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetFilteredEvents.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return GetFilteredEvents(ctx, srv.(Backend), req.(*EventsQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
 func handlerConsensusParameters(
 	srv any,
 	ctx context.Context,
@@ -758,6 +787,14 @@ func (c *Client) GetEvents(ctx context.Context, height int64) ([]*Event, error)
 	return rsp, nil
 }
 
+func (c *Client) GetFilteredEvents(ctx context.Context, query *EventsQuery) ([]*Event, error) {
+	var rsp []*Event
+	if err := c.conn.Invoke(ctx, methodGetFilteredEvents.FullName(), query, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
 func (c *Client) WatchEvents(ctx context.Context) (<-chan *Event, pubsub.ClosableSubscription, error) {
 	ctx, sub := pubsub.NewContextSubscription(ctx)
 
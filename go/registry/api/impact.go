@@ -0,0 +1,194 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	governance "github.com/oasisprotocol/oasis-core/go/governance/api"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// ElectionState is the election state against which the impact of a parameter change is
+// analyzed.
+type ElectionState struct {
+	// Candidates are all registered nodes.
+	Candidates []*ElectionCandidate
+	// Runtimes are all registered runtimes.
+	Runtimes []*Runtime
+	// Suspended specifies which of the runtimes are suspended.
+	Suspended map[common.Namespace]bool
+	// Committees are the currently elected committees.
+	Committees []*scheduler.Committee
+}
+
+// ChangedElectionContext returns the election context that would result from executing the given
+// proposal.
+//
+// Only proposals changing the staking thresholds are supported, as these are the only consensus
+// parameters that committee eligibility depends on.
+func ChangedElectionContext(ec *ElectionContext, content *governance.ProposalContent) (*ElectionContext, error) {
+	cp := content.ChangeParameters
+	if cp == nil {
+		return nil, fmt.Errorf("%w: not a change parameters proposal", scheduler.ErrUnsupportedProposal)
+	}
+	if cp.Module != staking.ModuleName {
+		return nil, fmt.Errorf("%w: unsupported module: %s", scheduler.ErrUnsupportedProposal, cp.Module)
+	}
+
+	var changes staking.ConsensusParameterChanges
+	if err := cbor.Unmarshal(cp.Changes, &changes); err != nil {
+		return nil, fmt.Errorf("%w: malformed changes: %w", scheduler.ErrUnsupportedProposal, err)
+	}
+	if changes.Thresholds == nil {
+		return nil, fmt.Errorf("%w: staking thresholds not changed", scheduler.ErrUnsupportedProposal)
+	}
+
+	params := staking.ConsensusParameters{Thresholds: ec.StakeThresholds}
+	if err := changes.Apply(&params); err != nil {
+		return nil, err
+	}
+
+	changed := *ec
+	changed.StakeThresholds = params.Thresholds
+	return &changed, nil
+}
+
+// AnalyzeParameterChange evaluates the impact that executing the given proposal would have on
+// the elections of all committees of all runtimes in the given state.
+//
+// The result only depends on the given state, and the number of evaluated node-runtime pairs is
+// bounded by MaxParameterChangeImpactEvaluations.
+func AnalyzeParameterChange(
+	ec *ElectionContext,
+	content *governance.ProposalContent,
+	state *ElectionState,
+) (*scheduler.ParameterChangeImpact, error) {
+	if n := len(state.Candidates) * len(state.Runtimes); n > scheduler.MaxParameterChangeImpactEvaluations {
+		return nil, fmt.Errorf("%w: %d node-runtime pairs", scheduler.ErrAnalysisTooExpensive, n)
+	}
+
+	changed, err := ChangedElectionContext(ec, content)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := slices.Clone(state.Candidates)
+	slices.SortFunc(candidates, func(a, b *ElectionCandidate) int {
+		if c := bytes.Compare(a.Node.EntityID[:], b.Node.EntityID[:]); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.Node.ID[:], b.Node.ID[:])
+	})
+	runtimes := slices.Clone(state.Runtimes)
+	slices.SortFunc(runtimes, func(a, b *Runtime) int {
+		return bytes.Compare(a.ID[:], b.ID[:])
+	})
+
+	result := &scheduler.ParameterChangeImpact{
+		Height: int64(ec.Height), //nolint:gosec
+		Epoch:  ec.Epoch,
+	}
+	for _, rt := range runtimes {
+		rtImpact := scheduler.RuntimeParameterChangeImpact{
+			RuntimeID: rt.ID,
+		}
+		var affected bool
+		for kind := scheduler.KindComputeExecutor; kind < scheduler.MaxCommitteeKind; kind++ {
+			members := committeeMembers(state.Committees, rt.ID, kind)
+			for _, role := range []scheduler.Role{scheduler.RoleWorker, scheduler.RoleBackupWorker} {
+				impact := analyzeCommittee(ec, changed, candidates, rt, state.Suspended[rt.ID], kind, role, members)
+				affected = affected || impact.IsAffected()
+				rtImpact.Committees = append(rtImpact.Committees, impact)
+			}
+		}
+		if affected {
+			result.Runtimes = append(result.Runtimes, rtImpact)
+		}
+	}
+	return result, nil
+}
+
+func analyzeCommittee(
+	before *ElectionContext,
+	after *ElectionContext,
+	candidates []*ElectionCandidate,
+	rt *Runtime,
+	suspended bool,
+	kind scheduler.CommitteeKind,
+	role scheduler.Role,
+	members map[signature.PublicKey]scheduler.Role,
+) scheduler.CommitteeParameterChangeImpact {
+	impact := scheduler.CommitteeParameterChangeImpact{
+		Kind: kind,
+		Role: role,
+		Size: committeeSize(rt, kind, role),
+	}
+	for _, c := range candidates {
+		eligibleBefore := len(CommitteeIneligibility(before, c, rt, suspended, kind, role)) == 0
+		reasons := CommitteeIneligibility(after, c, rt, suspended, kind, role)
+		eligibleAfter := len(reasons) == 0
+		elected := members[c.Node.ID] == role
+
+		if eligibleBefore {
+			impact.CandidatesBefore++
+		}
+		if eligibleAfter {
+			impact.CandidatesAfter++
+		}
+		if elected && eligibleAfter {
+			impact.RetainedMembers++
+		}
+		if eligibleBefore && !eligibleAfter {
+			impact.Losses = append(impact.Losses, scheduler.EligibilityLoss{
+				EntityID: c.Node.EntityID,
+				NodeID:   c.Node.ID,
+				Elected:  elected,
+				Reasons:  reasons,
+			})
+		}
+	}
+
+	// Each remaining candidate is elected with probability size/candidates.
+	switch {
+	case impact.CandidatesAfter == 0:
+	case uint64(impact.Size) >= impact.CandidatesAfter:
+		impact.ExpectedOverlap = impact.RetainedMembers
+	default:
+		impact.ExpectedOverlap = impact.RetainedMembers * uint64(impact.Size) / impact.CandidatesAfter
+	}
+	return impact
+}
+
+// committeeMembers returns the roles of the members of the given committee.
+func committeeMembers(committees []*scheduler.Committee, runtimeID common.Namespace, kind scheduler.CommitteeKind) map[signature.PublicKey]scheduler.Role {
+	members := make(map[signature.PublicKey]scheduler.Role)
+	for _, c := range committees {
+		if c.Kind != kind || !c.RuntimeID.Equal(&runtimeID) {
+			continue
+		}
+		for _, m := range c.Members {
+			members[m.PublicKey] = m.Role
+		}
+	}
+	return members
+}
+
+// committeeSize returns the number of nodes elected into the given committee with the given role.
+func committeeSize(rt *Runtime, kind scheduler.CommitteeKind, role scheduler.Role) uint16 {
+	if kind != scheduler.KindComputeExecutor {
+		return 0
+	}
+	switch role {
+	case scheduler.RoleWorker:
+		return rt.Executor.GroupSize
+	case scheduler.RoleBackupWorker:
+		return rt.Executor.GroupBackupSize
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	governance "github.com/oasisprotocol/oasis-core/go/governance/api"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+func TestAnalyzeParameterChange(t *testing.T) {
+	require := require.New(t)
+
+	rtID := common.NewTestNamespaceFromSeed([]byte("impact runtime"), 0)
+	rtVersion := version.Version{Major: 1}
+
+	ec := &ElectionContext{
+		Logger: logging.GetLogger("registry/api/tests"),
+		Epoch:  10,
+		Now:    time.Now(),
+		Height: 100,
+		Params: &ConsensusParameters{},
+		StakeThresholds: map[staking.ThresholdKind]quantity.Quantity{
+			staking.KindNodeCompute: *quantity.NewFromUint64(1000),
+		},
+	}
+	rt := &Runtime{
+		ID:          rtID,
+		Kind:        KindCompute,
+		TEEHardware: node.TEEHardwareInvalid,
+		Executor:    ExecutorParameters{GroupSize: 2, GroupBackupSize: 1},
+		Deployments: []*VersionInfo{
+			{Version: rtVersion, ValidFrom: 0},
+		},
+	}
+	newCandidate := func(id byte, balance uint64) *ElectionCandidate {
+		var escrow staking.EscrowAccount
+		escrow.Active.Balance = *quantity.NewFromUint64(balance)
+		escrow.StakeAccumulator.AddClaimUnchecked("node", staking.GlobalStakeThresholds(staking.KindNodeCompute))
+
+		var nodeID, entityID signature.PublicKey
+		nodeID[0], entityID[1] = id, id
+		return &ElectionCandidate{
+			Node: &node.Node{
+				ID:       nodeID,
+				EntityID: entityID,
+				Roles:    node.RoleComputeWorker,
+				Runtimes: []*node.Runtime{
+					{ID: rtID, Version: rtVersion},
+				},
+			},
+			Status: &NodeStatus{ElectionEligibleAfter: 1},
+			Escrow: &escrow,
+		}
+	}
+
+	// Node 2 only satisfies the current threshold and node 3 is not eligible at all.
+	candidates := []*ElectionCandidate{
+		newCandidate(1, 5000),
+		newCandidate(2, 1000),
+		newCandidate(3, 10),
+		newCandidate(4, 5000),
+	}
+	state := &ElectionState{
+		Candidates: candidates,
+		Runtimes:   []*Runtime{rt},
+		Committees: []*scheduler.Committee{
+			{
+				Kind:      scheduler.KindComputeExecutor,
+				RuntimeID: rtID,
+				Members: []*scheduler.CommitteeNode{
+					{Role: scheduler.RoleWorker, PublicKey: candidates[0].Node.ID},
+					{Role: scheduler.RoleWorker, PublicKey: candidates[1].Node.ID},
+					{Role: scheduler.RoleBackupWorker, PublicKey: candidates[3].Node.ID},
+				},
+			},
+		},
+	}
+	thresholdProposal := func(threshold uint64) *governance.ProposalContent {
+		return &governance.ProposalContent{
+			ChangeParameters: &governance.ChangeParametersProposal{
+				Module: staking.ModuleName,
+				Changes: cbor.Marshal(staking.ConsensusParameterChanges{
+					Thresholds: map[staking.ThresholdKind]quantity.Quantity{
+						staking.KindNodeCompute: *quantity.NewFromUint64(threshold),
+					},
+				}),
+			},
+		}
+	}
+
+	// Threshold increase disqualifying node 2.
+	impact, err := AnalyzeParameterChange(ec, thresholdProposal(2000), state)
+	require.NoError(err, "AnalyzeParameterChange")
+	require.EqualValues(ec.Height, impact.Height)
+	require.Equal(ec.Epoch, impact.Epoch)
+	require.Equal([]signature.PublicKey{candidates[1].Node.EntityID}, impact.LostEntities())
+	require.Len(impact.Runtimes, 1)
+	require.Equal(rtID, impact.Runtimes[0].RuntimeID)
+	require.Len(impact.Runtimes[0].Committees, 2)
+
+	worker := impact.Runtimes[0].Committees[0]
+	require.Equal(scheduler.RoleWorker, worker.Role)
+	require.EqualValues(2, worker.Size)
+	require.EqualValues(3, worker.CandidatesBefore)
+	require.EqualValues(2, worker.CandidatesAfter)
+	require.Equal([]scheduler.EligibilityLoss{{
+		EntityID: candidates[1].Node.EntityID,
+		NodeID:   candidates[1].Node.ID,
+		Elected:  true,
+		Reasons:  []scheduler.IneligibilityReason{scheduler.IneligibleInsufficientStake},
+	}}, worker.Losses)
+	require.EqualValues(1, worker.RetainedMembers)
+	require.EqualValues(1, worker.ExpectedOverlap)
+
+	backup := impact.Runtimes[0].Committees[1]
+	require.Equal(scheduler.RoleBackupWorker, backup.Role)
+	require.EqualValues(1, backup.Size)
+	require.EqualValues(2, backup.CandidatesAfter)
+	require.Len(backup.Losses, 1)
+	require.False(backup.Losses[0].Elected, "node should not be reported as elected as a backup worker")
+	require.EqualValues(1, backup.RetainedMembers)
+	require.EqualValues(0, backup.ExpectedOverlap)
+
+	// The analysis should not depend on the order of the state.
+	reversed := *state
+	reversed.Candidates = []*ElectionCandidate{candidates[3], candidates[2], candidates[1], candidates[0]}
+	reversedImpact, err := AnalyzeParameterChange(ec, thresholdProposal(2000), &reversed)
+	require.NoError(err, "AnalyzeParameterChange")
+	require.Equal(impact, reversedImpact, "analysis should be deterministic")
+
+	// Threshold decrease only grows the candidate pools.
+	impact, err = AnalyzeParameterChange(ec, thresholdProposal(10), state)
+	require.NoError(err, "AnalyzeParameterChange")
+	require.Empty(impact.LostEntities())
+	require.Len(impact.Runtimes, 1)
+	require.EqualValues(4, impact.Runtimes[0].Committees[0].CandidatesAfter)
+
+	// Unchanged thresholds do not affect any runtime.
+	impact, err = AnalyzeParameterChange(ec, thresholdProposal(1000), state)
+	require.NoError(err, "AnalyzeParameterChange")
+	require.Empty(impact.Runtimes)
+
+	// Unsupported proposals.
+	_, err = AnalyzeParameterChange(ec, &governance.ProposalContent{
+		CancelUpgrade: &governance.CancelUpgradeProposal{},
+	}, state)
+	require.ErrorIs(err, scheduler.ErrUnsupportedProposal)
+	_, err = AnalyzeParameterChange(ec, &governance.ProposalContent{
+		ChangeParameters: &governance.ChangeParametersProposal{
+			Module:  scheduler.ModuleName,
+			Changes: cbor.Marshal(scheduler.ConsensusParameterChanges{}),
+		},
+	}, state)
+	require.ErrorIs(err, scheduler.ErrUnsupportedProposal)
+
+	// The cost of the analysis is bounded.
+	large := *state
+	large.Candidates = make([]*ElectionCandidate, scheduler.MaxParameterChangeImpactEvaluations+1)
+	_, err = AnalyzeParameterChange(ec, thresholdProposal(2000), &large)
+	require.ErrorIs(err, scheduler.ErrAnalysisTooExpensive)
+}
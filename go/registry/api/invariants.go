@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/entity"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+)
+
+// CheckReferentialIntegrity checks that all registry records only reference other registered
+// records:
+//
+//   - nodes reference registered entities and (possibly suspended) runtimes,
+//   - entity-governed runtimes reference registered entities,
+//   - compute runtimes reference registered key manager runtimes,
+//   - node statuses reference registered nodes.
+func (g *Genesis) CheckReferentialIntegrity() error {
+	entities := make(map[signature.PublicKey]struct{})
+	for _, sigEnt := range g.Entities {
+		var ent entity.Entity
+		if err := sigEnt.Open(RegisterEntitySignatureContext, &ent); err != nil {
+			return fmt.Errorf("registry: unable to open signed entity: %w", err)
+		}
+		entities[ent.ID] = struct{}{}
+	}
+
+	runtimes := make(map[common.Namespace]*Runtime)
+	for _, rts := range [][]*Runtime{g.Runtimes, g.SuspendedRuntimes} {
+		for _, rt := range rts {
+			runtimes[rt.ID] = rt
+		}
+	}
+	for id, rt := range runtimes {
+		if rt.GovernanceModel == GovernanceEntity {
+			if _, ok := entities[rt.EntityID]; !ok {
+				return fmt.Errorf("registry: runtime %s references missing entity %s", id, rt.EntityID)
+			}
+		}
+		if rt.Kind == KindCompute && rt.KeyManager != nil {
+			km, ok := runtimes[*rt.KeyManager]
+			if !ok || km.Kind != KindKeyManager {
+				return fmt.Errorf("registry: runtime %s references missing key manager runtime %s", id, rt.KeyManager)
+			}
+		}
+	}
+
+	nodes := make(map[signature.PublicKey]struct{})
+	for _, sigNode := range g.Nodes {
+		var n node.Node
+		if err := sigNode.Open(RegisterNodeSignatureContext, &n); err != nil {
+			return fmt.Errorf("registry: unable to open signed node: %w", err)
+		}
+		if _, ok := entities[n.EntityID]; !ok {
+			return fmt.Errorf("registry: node %s references missing entity %s", n.ID, n.EntityID)
+		}
+		for _, nrt := range n.Runtimes {
+			if _, ok := runtimes[nrt.ID]; !ok {
+				return fmt.Errorf("registry: node %s references missing runtime %s", n.ID, nrt.ID)
+			}
+		}
+		nodes[n.ID] = struct{}{}
+	}
+
+	for id := range g.NodeStatuses {
+		if _, ok := nodes[id]; !ok {
+			return fmt.Errorf("registry: status of missing node %s", id)
+		}
+	}
+	return nil
+}
@@ -25,6 +25,29 @@ func (p *ConsensusParameters) SanityCheck() error {
 			return fmt.Errorf("maximum node expiration not specified")
 		}
 	}
+
+	switch p.AdminAuthorization {
+	case AdminAuthorizationGovernance:
+		if len(p.AdminKeys) > 0 || p.AdminThreshold > 0 {
+			return fmt.Errorf("administrator keys set with governance administrative authorization")
+		}
+	case AdminAuthorizationMultisig:
+		if p.AdminThreshold == 0 || int(p.AdminThreshold) > len(p.AdminKeys) {
+			return fmt.Errorf("invalid administrator threshold: %d of %d keys", p.AdminThreshold, len(p.AdminKeys))
+		}
+		seen := make(map[signature.PublicKey]struct{})
+		for _, pk := range p.AdminKeys {
+			if !pk.IsValid() {
+				return fmt.Errorf("invalid administrator key: %s", pk)
+			}
+			if _, ok := seen[pk]; ok {
+				return fmt.Errorf("duplicate administrator key: %s", pk)
+			}
+			seen[pk] = struct{}{}
+		}
+	default:
+		return fmt.Errorf("unsupported administrative authorization: %d", p.AdminAuthorization)
+	}
 	return nil
 }
 
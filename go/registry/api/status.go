@@ -31,6 +31,8 @@ type NodeStatus struct {
 	// Faults is a set of fault records for nodes that are experiencing
 	// liveness failures when participating in specific committees.
 	Faults map[common.Namespace]*Fault `json:"faults,omitempty"`
+	// AdminFreeze is the administrative freeze of the node, if any.
+	AdminFreeze *AdminFreeze `json:"admin_freeze,omitempty"`
 }
 
 // IsFrozen returns true if the node is currently frozen (prevented
@@ -44,6 +46,35 @@ func (ns *NodeStatus) Unfreeze() {
 	ns.FreezeEndTime = 0
 }
 
+// IsAdminFrozen returns true if the node is administratively frozen in the given epoch.
+func (ns NodeStatus) IsAdminFrozen(epoch beacon.EpochTime) bool {
+	return ns.AdminFreeze != nil && epoch < ns.AdminFreeze.Expiry
+}
+
+// ApplyAdminFreeze applies the given administrative freeze action.
+func (ns *NodeStatus) ApplyAdminFreeze(action *AdminFreezeNode) {
+	if action.Unfreeze {
+		ns.AdminFreeze = nil
+		return
+	}
+	ns.AdminFreeze = &AdminFreeze{
+		Reason: action.Reason,
+		Expiry: action.Expiry,
+	}
+}
+
+// ClearExpiredAdminFreeze clears the administrative freeze in case it has expired by the given
+// epoch.
+//
+// Returns true iff the administrative freeze has been cleared.
+func (ns *NodeStatus) ClearExpiredAdminFreeze(epoch beacon.EpochTime) bool {
+	if ns.AdminFreeze == nil || ns.IsAdminFrozen(epoch) {
+		return false
+	}
+	ns.AdminFreeze = nil
+	return true
+}
+
 // RecordFailure records a liveness failure in the epoch preceding the specified epoch.
 func (ns *NodeStatus) RecordFailure(runtimeID common.Namespace, epoch beacon.EpochTime) {
 	if ns.Faults == nil {
@@ -78,7 +109,7 @@ func (ns *NodeStatus) RecordSuccess(runtimeID common.Namespace, epoch beacon.Epo
 // IsSuspended checks whether the node is suspended in the given epoch.
 func (ns *NodeStatus) IsSuspended(runtimeID common.Namespace, epoch beacon.EpochTime) bool {
 	// If a node is frozen it is also suspended.
-	if ns.IsFrozen() {
+	if ns.IsFrozen() || ns.IsAdminFrozen(epoch) {
 		return true
 	}
 
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 )
 
 func TestStatusFaults(t *testing.T) {
@@ -66,3 +67,38 @@ func TestStatusFaults(t *testing.T) {
 	require.False(ns.IsSuspended(testRuntimeID, 26), "should not be suspended in epoch 26")
 	require.Len(ns.Faults, 0, "faults set should be cleared")
 }
+
+func TestStatusAdminFreeze(t *testing.T) {
+	require := require.New(t)
+
+	var testRuntimeID common.Namespace
+	nodeID := signature.NewPublicKey("0000000000000000000000000000000000000000000000000000000000000001")
+
+	var ns NodeStatus
+	require.False(ns.IsAdminFrozen(10), "default node status should not be administratively frozen")
+
+	// Freeze in epoch 10 until epoch 15.
+	ns.ApplyAdminFreeze(&AdminFreezeNode{NodeID: nodeID, Reason: "misbehaving", Expiry: 15})
+	require.NotNil(ns.AdminFreeze)
+	require.Equal("misbehaving", ns.AdminFreeze.Reason)
+	require.True(ns.IsAdminFrozen(10), "should be frozen in epoch 10")
+	require.True(ns.IsAdminFrozen(14), "should be frozen in epoch 14")
+	require.False(ns.IsAdminFrozen(15), "should not be frozen in epoch 15")
+	require.False(ns.IsFrozen(), "administrative freeze should not affect the regular freeze")
+	require.True(ns.IsSuspended(testRuntimeID, 14), "should be suspended while frozen")
+	require.False(ns.IsSuspended(testRuntimeID, 15), "should not be suspended after expiry")
+
+	// Expiry-based unfreeze.
+	require.False(ns.ClearExpiredAdminFreeze(14), "freeze should not be cleared before expiry")
+	require.NotNil(ns.AdminFreeze)
+	require.True(ns.ClearExpiredAdminFreeze(15), "freeze should be cleared on expiry")
+	require.Nil(ns.AdminFreeze)
+	require.False(ns.ClearExpiredAdminFreeze(16), "nothing to clear")
+
+	// Explicit unfreeze.
+	ns.ApplyAdminFreeze(&AdminFreezeNode{NodeID: nodeID, Reason: "misbehaving", Expiry: FreezeForever})
+	require.True(ns.IsAdminFrozen(1000), "should be frozen indefinitely")
+	ns.ApplyAdminFreeze(&AdminFreezeNode{NodeID: nodeID, Reason: "resolved", Unfreeze: true})
+	require.Nil(ns.AdminFreeze)
+	require.False(ns.IsAdminFrozen(1000), "should no longer be frozen")
+}
@@ -34,6 +34,10 @@ const (
 	// TimeoutNever is the timeout value that never expires.
 	TimeoutNever int64 = 0
 
+	// MaxRuntimeMessagesLimit is the upper bound of the maximum number of emitted and queued
+	// incoming runtime messages allowed by the consensus parameters.
+	MaxRuntimeMessagesLimit = 1024
+
 	// LogEventExecutionDiscrepancyDetected is a log event value that signals
 	// an execution discrepancy has been detected.
 	LogEventExecutionDiscrepancyDetected = "roothash/execution_discrepancy_detected"
@@ -13,6 +13,7 @@ import (
 	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
 	"github.com/oasisprotocol/oasis-core/go/consensus/api/events"
 	genesisTestHelpers "github.com/oasisprotocol/oasis-core/go/genesis/tests"
+	governance "github.com/oasisprotocol/oasis-core/go/governance/api"
 	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
 	"github.com/oasisprotocol/oasis-core/go/roothash/api/commitment"
 )
@@ -725,3 +726,39 @@ func TestRoundRootsSerialization(t *testing.T) {
 		require.EqualValues(tc.rr, dec, "Runtime serialization should round-trip")
 	}
 }
+
+func TestParameterChange(t *testing.T) {
+	require := require.New(t)
+
+	params := &ConsensusParameters{
+		MaxRuntimeMessages:   32,
+		MaxInRuntimeMessages: 32,
+	}
+
+	maxMessages := uint32(64)
+	newParams, preview, err := governance.PreviewParameterChange[ConsensusParameters, ConsensusParameterChanges](
+		ModuleName,
+		governance.NewChangeParametersProposal(ModuleName, &ConsensusParameterChanges{
+			MaxRuntimeMessages: &maxMessages,
+		}),
+		params,
+	)
+	require.NoError(err, "PreviewParameterChange")
+	require.EqualValues(64, newParams.MaxRuntimeMessages)
+	require.Len(preview.Changes, 1)
+	require.Equal("max_runtime_messages", preview.Changes[0].Parameter)
+
+	var previewParams ConsensusParameters
+	require.NoError(cbor.Unmarshal(preview.Parameters, &previewParams))
+	require.Equal(*newParams, previewParams, "preview should contain the effective parameters")
+
+	maxMessages = MaxRuntimeMessagesLimit + 1
+	_, _, err = governance.PreviewParameterChange[ConsensusParameters, ConsensusParameterChanges](
+		ModuleName,
+		governance.NewChangeParametersProposal(ModuleName, &ConsensusParameterChanges{
+			MaxInRuntimeMessages: &maxMessages,
+		}),
+		params,
+	)
+	require.ErrorIs(err, governance.ErrInvalidArgument, "out-of-bounds max incoming messages should be rejected")
+}
@@ -45,6 +45,21 @@ func (p *ConsensusParameters) SanityCheck() error {
 	return nil
 }
 
+// CheckInvariants checks that the consensus parameters are sane and satisfy the invariants that
+// consensus parameter changes must preserve.
+func (p *ConsensusParameters) CheckInvariants() error {
+	if err := p.SanityCheck(); err != nil {
+		return err
+	}
+	if p.MaxRuntimeMessages > MaxRuntimeMessagesLimit {
+		return fmt.Errorf("max_runtime_messages must be at most %d", MaxRuntimeMessagesLimit)
+	}
+	if p.MaxInRuntimeMessages > MaxRuntimeMessagesLimit {
+		return fmt.Errorf("max_in_runtime_messages must be at most %d", MaxRuntimeMessagesLimit)
+	}
+	return nil
+}
+
 // SanityCheck performs a sanity check on the consensus parameter changes.
 func (c *ConsensusParameterChanges) SanityCheck() error {
 	if c.GasCosts == nil &&
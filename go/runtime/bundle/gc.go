@@ -0,0 +1,291 @@
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+const (
+	// DefaultGCRetention is the default time unreferenced bundles are kept in the trash directory
+	// before they are permanently deleted.
+	DefaultGCRetention = 7 * 24 * time.Hour
+
+	// DefaultGCMinAge is the default minimum age of a bundle before it can be collected.
+	DefaultGCMinAge = 24 * time.Hour
+)
+
+// GCConfig is the bundle garbage collector configuration.
+type GCConfig struct {
+	// Dir is the directory containing exploded bundles, one subdirectory per manifest hash.
+	Dir string
+
+	// TrashDir is the directory unreferenced bundles are moved to before they are deleted.
+	TrashDir string
+
+	// Retention is the time unreferenced bundles are kept in the trash directory before they are
+	// permanently deleted. If zero, DefaultGCRetention is used.
+	Retention time.Duration
+
+	// MinAge is the minimum time since a bundle has been added before it can be collected. If
+	// zero, DefaultGCMinAge is used.
+	MinAge time.Duration
+
+	// UpgradeHorizon is the number of epochs ahead of the current epoch within which bundles of
+	// pending upgrades are considered referenced.
+	UpgradeHorizon beacon.EpochTime
+}
+
+// GCBundle describes an exploded bundle known to the runtime registry.
+type GCBundle struct {
+	// ManifestHash is the hash of the bundle manifest.
+	ManifestHash hash.Hash
+
+	// RuntimeID is the identifier of the runtime the bundle belongs to.
+	RuntimeID common.Namespace
+
+	// Version is the runtime version of the bundle. Detached bundles use the version of the
+	// runtime their components belong to.
+	Version version.Version
+}
+
+// GCReferences are the sources of bundle references.
+type GCReferences struct {
+	// Epoch is the current epoch.
+	Epoch beacon.EpochTime
+
+	// Deployments are the registered deployments of each runtime.
+	Deployments map[common.Namespace][]*registry.VersionInfo
+
+	// Pinned are the runtime versions that are explicitly pinned by the node operator.
+	Pinned map[common.Namespace][]version.Version
+}
+
+// GCReport is the report of a bundle garbage collection run.
+type GCReport struct {
+	// DryRun specifies whether the run did not modify anything.
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Referenced are the bundles that are kept as they are referenced.
+	Referenced []hash.Hash `json:"referenced,omitempty"`
+
+	// Recent are the unreferenced bundles that are kept as they have been added recently.
+	Recent []hash.Hash `json:"recent,omitempty"`
+
+	// Trashed are the unreferenced bundles that have been moved to the trash directory.
+	Trashed []hash.Hash `json:"trashed,omitempty"`
+
+	// Deleted are the bundles that have been permanently deleted from the trash directory.
+	Deleted []hash.Hash `json:"deleted,omitempty"`
+}
+
+// GC is a garbage collector of exploded bundles that are no longer referenced.
+type GC struct {
+	logger *logging.Logger
+
+	cfg GCConfig
+
+	nowFn func() time.Time
+}
+
+// NewGC creates a new bundle garbage collector.
+func NewGC(cfg *GCConfig) (*GC, error) {
+	if cfg.Dir == "" || cfg.TrashDir == "" {
+		return nil, fmt.Errorf("bundle/gc: bundle and trash directories must be configured")
+	}
+	if filepath.Clean(cfg.Dir) == filepath.Clean(cfg.TrashDir) {
+		return nil, fmt.Errorf("bundle/gc: trash directory must differ from bundle directory")
+	}
+
+	gcCfg := *cfg
+	if gcCfg.Retention == 0 {
+		gcCfg.Retention = DefaultGCRetention
+	}
+	if gcCfg.MinAge == 0 {
+		gcCfg.MinAge = DefaultGCMinAge
+	}
+
+	return &GC{
+		logger: logging.GetLogger("runtime/bundle/gc"),
+		cfg:    gcCfg,
+		nowFn:  time.Now,
+	}, nil
+}
+
+// Referenced returns the manifest hashes of the given bundles that are referenced by an active
+// deployment, a pending upgrade within the upgrade horizon or a pinned version.
+func (gc *GC) Referenced(bundles []*GCBundle, refs *GCReferences) map[hash.Hash]bool {
+	versions := make(map[common.Namespace]map[version.Version]bool)
+	addVersion := func(id common.Namespace, v version.Version) {
+		if versions[id] == nil {
+			versions[id] = make(map[version.Version]bool)
+		}
+		versions[id][v] = true
+	}
+
+	for id, deployments := range refs.Deployments {
+		// The active deployment is the one with the latest activation epoch that is not in the
+		// future. Earlier deployments are superseded.
+		var active *registry.VersionInfo
+		for _, vi := range deployments {
+			switch {
+			case vi.ValidFrom <= refs.Epoch:
+				if active == nil || vi.ValidFrom > active.ValidFrom {
+					active = vi
+				}
+			case vi.ValidFrom-refs.Epoch <= gc.cfg.UpgradeHorizon:
+				addVersion(id, vi.Version)
+			}
+		}
+		if active != nil {
+			addVersion(id, active.Version)
+		}
+	}
+	for id, pinned := range refs.Pinned {
+		for _, v := range pinned {
+			addVersion(id, v)
+		}
+	}
+
+	referenced := make(map[hash.Hash]bool)
+	for _, b := range bundles {
+		if versions[b.RuntimeID][b.Version] {
+			referenced[b.ManifestHash] = true
+		}
+	}
+	return referenced
+}
+
+// Collect moves unreferenced bundles to the trash directory and permanently deletes trashed
+// bundles once their retention period expires.
+//
+// Bundles added less than the configured minimum age ago are never collected. In dry-run mode,
+// the report describes what would be done without modifying anything.
+func (gc *GC) Collect(bundles []*GCBundle, refs *GCReferences, dryRun bool) (*GCReport, error) {
+	now := gc.nowFn()
+	report := &GCReport{DryRun: dryRun}
+
+	if !dryRun {
+		if err := common.Mkdir(gc.cfg.TrashDir); err != nil {
+			return nil, fmt.Errorf("bundle/gc: failed to create trash directory: %w", err)
+		}
+	}
+
+	// Permanently delete expired trashed bundles first so that bundles trashed in this run are
+	// always retained for the full retention period.
+	deleted, err := gc.expireTrash(now, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	report.Deleted = deleted
+
+	referenced := gc.Referenced(bundles, refs)
+	for _, b := range bundles {
+		if referenced[b.ManifestHash] {
+			report.Referenced = append(report.Referenced, b.ManifestHash)
+			continue
+		}
+
+		path := filepath.Join(gc.cfg.Dir, b.ManifestHash.Hex())
+		fi, err := os.Stat(path)
+		switch {
+		case err == nil:
+		case errors.Is(err, os.ErrNotExist):
+			continue
+		default:
+			return nil, fmt.Errorf("bundle/gc: failed to stat bundle: %w", err)
+		}
+		if now.Sub(fi.ModTime()) < gc.cfg.MinAge {
+			report.Recent = append(report.Recent, b.ManifestHash)
+			continue
+		}
+
+		report.Trashed = append(report.Trashed, b.ManifestHash)
+		if dryRun {
+			continue
+		}
+
+		gc.logger.Info("moving unreferenced bundle to trash",
+			"manifest_hash", b.ManifestHash,
+			"runtime_id", b.RuntimeID,
+			"version", b.Version,
+		)
+		trashPath := filepath.Join(gc.cfg.TrashDir, b.ManifestHash.Hex())
+		if err = os.RemoveAll(trashPath); err != nil {
+			return nil, fmt.Errorf("bundle/gc: failed to remove stale trashed bundle: %w", err)
+		}
+		if err = os.Rename(path, trashPath); err != nil {
+			return nil, fmt.Errorf("bundle/gc: failed to move bundle to trash: %w", err)
+		}
+		// The modification time of a trashed bundle records when it has been trashed.
+		if err = os.Chtimes(trashPath, now, now); err != nil {
+			return nil, fmt.Errorf("bundle/gc: failed to update trashed bundle: %w", err)
+		}
+	}
+
+	sortHashes(report.Referenced)
+	sortHashes(report.Recent)
+	sortHashes(report.Trashed)
+
+	return report, nil
+}
+
+// expireTrash permanently deletes trashed bundles whose retention period has expired.
+func (gc *GC) expireTrash(now time.Time, dryRun bool) ([]hash.Hash, error) {
+	entries, err := os.ReadDir(gc.cfg.TrashDir)
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("bundle/gc: failed to read trash directory: %w", err)
+	}
+
+	var deleted []hash.Hash
+	for _, entry := range entries {
+		var h hash.Hash
+		if err = h.UnmarshalHex(entry.Name()); err != nil {
+			// Ignore anything not placed there by the garbage collector.
+			continue
+		}
+
+		fi, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("bundle/gc: failed to stat trashed bundle: %w", err)
+		}
+		if now.Sub(fi.ModTime()) < gc.cfg.Retention {
+			continue
+		}
+
+		deleted = append(deleted, h)
+		if dryRun {
+			continue
+		}
+
+		gc.logger.Info("deleting expired trashed bundle",
+			"manifest_hash", h,
+		)
+		if err = os.RemoveAll(filepath.Join(gc.cfg.TrashDir, entry.Name())); err != nil {
+			return nil, fmt.Errorf("bundle/gc: failed to delete trashed bundle: %w", err)
+		}
+	}
+	sortHashes(deleted)
+
+	return deleted, nil
+}
+
+func sortHashes(hashes []hash.Hash) {
+	sort.Slice(hashes, func(i, j int) bool {
+		return hashes[i].Hex() < hashes[j].Hex()
+	})
+}
@@ -0,0 +1,133 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+func TestGC(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	bundleDir := filepath.Join(dir, "bundles")
+	trashDir := filepath.Join(dir, "trash")
+
+	gc, err := NewGC(&GCConfig{
+		Dir:            bundleDir,
+		TrashDir:       trashDir,
+		Retention:      48 * time.Hour,
+		MinAge:         time.Hour,
+		UpgradeHorizon: 10,
+	})
+	require.NoError(err, "NewGC")
+	now := time.Now()
+	gc.nowFn = func() time.Time { return now }
+
+	runtimeID := common.NewTestNamespaceFromSeed([]byte("bundle gc test"), 0)
+	newBundle := func(major uint16, age time.Duration) *GCBundle {
+		b := &GCBundle{
+			ManifestHash: hash.NewFromBytes([]byte{byte(major)}),
+			RuntimeID:    runtimeID,
+			Version:      version.Version{Major: major},
+		}
+		path := filepath.Join(bundleDir, b.ManifestHash.Hex())
+		require.NoError(os.MkdirAll(path, 0o700))
+		require.NoError(os.Chtimes(path, now.Add(-age), now.Add(-age)))
+		return b
+	}
+
+	var (
+		superseded = newBundle(1, 24*time.Hour) // Stale, superseded by the active deployment.
+		active     = newBundle(2, 24*time.Hour) // Active deployment.
+		pending    = newBundle(3, 24*time.Hour) // Pending upgrade within the horizon.
+		distant    = newBundle(4, 24*time.Hour) // Pending upgrade beyond the horizon.
+		pinned     = newBundle(5, 24*time.Hour) // Pinned by the operator.
+		recent     = newBundle(6, time.Minute)  // Unreferenced, but recently added.
+		bundles    = []*GCBundle{superseded, active, pending, distant, pinned, recent}
+	)
+
+	deployment := func(v *GCBundle, validFrom beacon.EpochTime) *registry.VersionInfo {
+		return &registry.VersionInfo{Version: v.Version, ValidFrom: validFrom}
+	}
+	refs := &GCReferences{
+		Epoch: 100,
+		Deployments: map[common.Namespace][]*registry.VersionInfo{
+			runtimeID: {
+				deployment(superseded, 10),
+				deployment(active, 50),
+				deployment(pending, 105),
+				deployment(distant, 200),
+			},
+		},
+		Pinned: map[common.Namespace][]version.Version{
+			runtimeID: {pinned.Version},
+		},
+	}
+
+	referenced := gc.Referenced(bundles, refs)
+	require.Equal(map[hash.Hash]bool{
+		active.ManifestHash:  true,
+		pending.ManifestHash: true,
+		pinned.ManifestHash:  true,
+	}, referenced)
+
+	// A dry run should not modify anything.
+	report, err := gc.Collect(bundles, refs, true)
+	require.NoError(err, "Collect(dry run)")
+	require.True(report.DryRun)
+	require.ElementsMatch([]hash.Hash{superseded.ManifestHash, distant.ManifestHash}, report.Trashed)
+	require.Equal([]hash.Hash{recent.ManifestHash}, report.Recent)
+	require.Len(report.Referenced, 3)
+	require.DirExists(filepath.Join(bundleDir, superseded.ManifestHash.Hex()))
+	require.NoDirExists(trashDir)
+
+	// Unreferenced stale bundles should be moved to the trash.
+	report, err = gc.Collect(bundles, refs, false)
+	require.NoError(err, "Collect")
+	require.ElementsMatch([]hash.Hash{superseded.ManifestHash, distant.ManifestHash}, report.Trashed)
+	require.Empty(report.Deleted)
+	for _, b := range bundles {
+		path := filepath.Join(bundleDir, b.ManifestHash.Hex())
+		trashPath := filepath.Join(trashDir, b.ManifestHash.Hex())
+		switch b {
+		case superseded, distant:
+			require.NoDirExists(path)
+			require.DirExists(trashPath)
+		default:
+			require.DirExists(path)
+			require.NoDirExists(trashPath)
+		}
+	}
+
+	// Trashed bundles should be retained until the retention period expires.
+	remaining := []*GCBundle{active, pending, pinned, recent}
+	now = now.Add(24 * time.Hour)
+	report, err = gc.Collect(remaining, refs, false)
+	require.NoError(err, "Collect")
+	require.Empty(report.Deleted)
+	require.Empty(report.Recent, "bundle should no longer be recent")
+	require.Equal([]hash.Hash{recent.ManifestHash}, report.Trashed)
+
+	now = now.Add(24 * time.Hour)
+	report, err = gc.Collect(remaining[:3], refs, true)
+	require.NoError(err, "Collect(dry run)")
+	require.ElementsMatch([]hash.Hash{superseded.ManifestHash, distant.ManifestHash}, report.Deleted)
+	require.DirExists(filepath.Join(trashDir, superseded.ManifestHash.Hex()))
+
+	report, err = gc.Collect(remaining[:3], refs, false)
+	require.NoError(err, "Collect")
+	require.ElementsMatch([]hash.Hash{superseded.ManifestHash, distant.ManifestHash}, report.Deleted)
+	require.NoDirExists(filepath.Join(trashDir, superseded.ManifestHash.Hex()))
+	require.NoDirExists(filepath.Join(trashDir, distant.ManifestHash.Hex()))
+	require.DirExists(filepath.Join(trashDir, recent.ManifestHash.Hex()), "recently trashed bundle should be retained")
+}
@@ -0,0 +1,193 @@
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	"github.com/oasisprotocol/oasis-core/go/runtime/bundle/component"
+)
+
+const (
+	// RequirementTEE is the name of the TEE requirement.
+	RequirementTEE = "tee"
+	// RequirementMinHostProtocol is the name of the minimum host protocol version requirement.
+	RequirementMinHostProtocol = "min_host_protocol"
+	// RequirementCapability is the name of a host capability requirement.
+	RequirementCapability = "capability"
+	// RequirementFeature is the name of a node feature requirement.
+	RequirementFeature = "feature"
+)
+
+// Requirements are the node capabilities required by a component.
+type Requirements struct {
+	// TEE is the kind of TEE the component requires (e.g. "sgx" or "tdx").
+	TEE string `json:"tee,omitempty"`
+
+	// MinHostProtocol is the minimum runtime host protocol version the component requires.
+	MinHostProtocol *version.Version `json:"min_host_protocol,omitempty"`
+
+	// Capabilities are the host capabilities the component requires.
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// Features are the node features that must be enabled for the component.
+	Features []string `json:"features,omitempty"`
+
+	// Unknown are the names of requirements not recognized by this node version. They are
+	// reported but never prevent provisioning so that newer manifests remain usable.
+	Unknown []string `json:"-"`
+}
+
+// UnmarshalJSON decodes the requirements and records any unknown requirements.
+func (r *Requirements) UnmarshalJSON(data []byte) error {
+	type requirements Requirements
+	var decoded requirements
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for name := range fields {
+		switch name {
+		case RequirementTEE, RequirementMinHostProtocol, "capabilities", "features":
+		default:
+			decoded.Unknown = append(decoded.Unknown, name)
+		}
+	}
+	sort.Strings(decoded.Unknown)
+
+	*r = Requirements(decoded)
+	return nil
+}
+
+// HostCapabilities are the capabilities of the node that components are provisioned on.
+type HostCapabilities struct {
+	// TEEs are the kinds of TEEs supported by the provisioner.
+	TEEs []string
+
+	// HostProtocol is the runtime host protocol version supported by the node.
+	HostProtocol version.Version
+
+	// Capabilities are the capabilities of the runtime host.
+	Capabilities []string
+
+	// Features are the enabled node features.
+	Features []string
+}
+
+// RequirementStatus is the status of a single component requirement.
+type RequirementStatus struct {
+	// Name is the name of the requirement.
+	Name string `json:"name"`
+
+	// Value is the required value.
+	Value string `json:"value,omitempty"`
+
+	// Satisfied specifies whether the node satisfies the requirement.
+	Satisfied bool `json:"satisfied"`
+
+	// Unknown specifies whether the requirement is not recognized by this node version.
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Check checks the requirements against the given host capabilities and returns the status of
+// each requirement.
+func (r *Requirements) Check(caps *HostCapabilities) []RequirementStatus {
+	if r == nil {
+		return nil
+	}
+
+	var statuses []RequirementStatus
+	if r.TEE != "" {
+		statuses = append(statuses, RequirementStatus{
+			Name:      RequirementTEE,
+			Value:     r.TEE,
+			Satisfied: slices.Contains(caps.TEEs, r.TEE),
+		})
+	}
+	if r.MinHostProtocol != nil {
+		statuses = append(statuses, RequirementStatus{
+			Name:      RequirementMinHostProtocol,
+			Value:     r.MinHostProtocol.String(),
+			Satisfied: caps.HostProtocol.ToU64() >= r.MinHostProtocol.ToU64(),
+		})
+	}
+	for _, capability := range r.Capabilities {
+		statuses = append(statuses, RequirementStatus{
+			Name:      RequirementCapability,
+			Value:     capability,
+			Satisfied: slices.Contains(caps.Capabilities, capability),
+		})
+	}
+	for _, feature := range r.Features {
+		statuses = append(statuses, RequirementStatus{
+			Name:      RequirementFeature,
+			Value:     feature,
+			Satisfied: slices.Contains(caps.Features, feature),
+		})
+	}
+	for _, name := range r.Unknown {
+		statuses = append(statuses, RequirementStatus{
+			Name:    name,
+			Unknown: true,
+		})
+	}
+	return statuses
+}
+
+// UnmetRequirementsError is the error returned when a component requires capabilities that the
+// node does not have.
+type UnmetRequirementsError struct {
+	// Component is the identifier of the component.
+	Component component.ID
+
+	// Unmet are the requirements that are not satisfied.
+	Unmet []RequirementStatus
+}
+
+// Error implements error.
+func (e *UnmetRequirementsError) Error() string {
+	unmet := make([]string, 0, len(e.Unmet))
+	for _, st := range e.Unmet {
+		unmet = append(unmet, fmt.Sprintf("%s=%s", st.Name, st.Value))
+	}
+	return fmt.Sprintf("component '%s' has unmet requirements: %s", e.Component, strings.Join(unmet, ", "))
+}
+
+// VerifyRequirements verifies that the node satisfies the requirements of the given component.
+//
+// Unknown requirements are logged and ignored. In case any known requirement is not satisfied,
+// an UnmetRequirementsError is returned.
+func VerifyRequirements(
+	logger *logging.Logger,
+	id component.ID,
+	reqs *Requirements,
+	caps *HostCapabilities,
+) error {
+	var unmet []RequirementStatus
+	for _, st := range reqs.Check(caps) {
+		switch {
+		case st.Unknown:
+			logger.Warn("ignoring unknown component requirement",
+				"component", id,
+				"requirement", st.Name,
+			)
+		case !st.Satisfied:
+			unmet = append(unmet, st)
+		}
+	}
+	if len(unmet) > 0 {
+		return &UnmetRequirementsError{
+			Component: id,
+			Unmet:     unmet,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package bundle
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	"github.com/oasisprotocol/oasis-core/go/runtime/bundle/component"
+)
+
+func TestRequirements(t *testing.T) {
+	require := require.New(t)
+
+	logger := logging.GetLogger("runtime/bundle/test")
+	caps := &HostCapabilities{
+		TEEs:         []string{"sgx"},
+		HostProtocol: version.Version{Major: 5, Minor: 1},
+		Capabilities: []string{"query"},
+		Features:     []string{"rofl"},
+	}
+
+	var reqs Requirements
+	err := json.Unmarshal([]byte(`{
+		"tee": "sgx",
+		"min_host_protocol": {"major": 5},
+		"capabilities": ["query"],
+		"features": ["rofl"]
+	}`), &reqs)
+	require.NoError(err, "Unmarshal")
+	require.Empty(reqs.Unknown)
+
+	// Met requirements.
+	statuses := reqs.Check(caps)
+	require.Len(statuses, 4)
+	for _, st := range statuses {
+		require.True(st.Satisfied, "requirement %s should be satisfied", st.Name)
+	}
+	err = VerifyRequirements(logger, component.ID_RONL, &reqs, caps)
+	require.NoError(err, "VerifyRequirements")
+
+	// Unmet requirements.
+	reqs.TEE = "tdx"
+	reqs.MinHostProtocol = &version.Version{Major: 6}
+	err = VerifyRequirements(logger, component.ID_RONL, &reqs, caps)
+	require.Error(err, "VerifyRequirements should fail")
+	var unmetErr *UnmetRequirementsError
+	require.True(errors.As(err, &unmetErr))
+	require.Equal([]RequirementStatus{
+		{Name: RequirementTEE, Value: "tdx"},
+		{Name: RequirementMinHostProtocol, Value: "6.0.0"},
+	}, unmetErr.Unmet)
+
+	// Unknown requirements should not fail verification.
+	err = json.Unmarshal([]byte(`{"features": ["rofl"], "gpu": "a100"}`), &reqs)
+	require.NoError(err, "Unmarshal")
+	require.Equal([]string{"gpu"}, reqs.Unknown)
+	statuses = reqs.Check(caps)
+	require.Equal([]RequirementStatus{
+		{Name: RequirementFeature, Value: "rofl", Satisfied: true},
+		{Name: "gpu", Unknown: true},
+	}, statuses)
+	err = VerifyRequirements(logger, component.ID_RONL, &reqs, caps)
+	require.NoError(err, "VerifyRequirements")
+
+	// No requirements.
+	var none *Requirements
+	require.Empty(none.Check(caps))
+}
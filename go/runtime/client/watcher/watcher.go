@@ -0,0 +1,281 @@
+// Package watcher implements a runtime block watcher that delivers contiguous rounds.
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	commonErrors "github.com/oasisprotocol/oasis-core/go/common/errors"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	"github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+)
+
+// ModuleName is the block watcher module name.
+const ModuleName = "runtime/client/watcher"
+
+// ErrGapPruned is the error returned when rounds missing from the block stream can no longer be
+// backfilled as they have been pruned.
+var ErrGapPruned = commonErrors.New(ModuleName, 1, "watcher: missing rounds have been pruned")
+
+// BlockSource is the part of the runtime client API used to watch blocks.
+type BlockSource interface {
+	// WatchBlocks returns a stream of annotated blocks of the given runtime.
+	WatchBlocks(ctx context.Context, runtimeID common.Namespace) (<-chan *roothash.AnnotatedBlock, pubsub.ClosableSubscription, error)
+
+	// GetBlock returns the block at the given round.
+	GetBlock(ctx context.Context, request *api.GetBlockRequest) (*block.Block, error)
+
+	// GetLastRetainedBlock returns the oldest retained block.
+	GetLastRetainedBlock(ctx context.Context, runtimeID common.Namespace) (*block.Block, error)
+}
+
+// Options are the block watcher options.
+type Options struct {
+	// HeadersOnly specifies whether only block headers should be delivered, without the
+	// consensus height annotations.
+	HeadersOnly bool
+
+	// DetectGaps specifies whether non-contiguous rounds should be detected and the missing
+	// blocks backfilled via point queries before delivering any later blocks. When enabled,
+	// the watcher also resubscribes in case the block stream is interrupted.
+	DetectGaps bool
+}
+
+// Block is a block delivered by the block watcher.
+type Block struct {
+	// Round is the block's round.
+	Round uint64
+
+	// Header is the block header.
+	Header *block.Header
+
+	// Block is the annotated block. It is nil in header-only mode and for backfilled blocks, as
+	// point queries do not return the consensus height at which the block was finalized.
+	Block *roothash.AnnotatedBlock
+
+	// Backfilled is true iff the block was fetched to repair a gap in the block stream.
+	Backfilled bool
+}
+
+// Subscription is a block watcher subscription.
+type Subscription struct {
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	err error
+}
+
+// Close cancels the subscription.
+func (s *Subscription) Close() {
+	s.cancel()
+}
+
+// Err returns the error that terminated the block stream, if any.
+//
+// It should only be called after the block channel has been closed.
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+func (s *Subscription) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+}
+
+// WatchBlocks starts watching blocks of the given runtime.
+//
+// The returned channel is closed when the subscription is closed, the context is canceled or an
+// error occurs, in which case the error is available via the subscription's Err method. In case
+// the missing rounds of a gap have already been pruned, the error is ErrGapPruned.
+func WatchBlocks(ctx context.Context, src BlockSource, runtimeID common.Namespace, opts Options) (<-chan *Block, *Subscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	blkCh, blkSub, err := src.WatchBlocks(ctx, runtimeID)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	w := &watcher{
+		src:       src,
+		runtimeID: runtimeID,
+		opts:      opts,
+		ch:        make(chan *Block),
+		sub:       &Subscription{cancel: cancel},
+		logger:    logging.GetLogger("runtime/client/watcher").With("runtime_id", runtimeID),
+	}
+	go w.run(ctx, blkCh, blkSub)
+
+	return w.ch, w.sub, nil
+}
+
+type watcher struct {
+	src       BlockSource
+	runtimeID common.Namespace
+	opts      Options
+
+	ch  chan *Block
+	sub *Subscription
+
+	// lastRound is the last delivered round, valid iff started is true.
+	lastRound uint64
+	started   bool
+
+	logger *logging.Logger
+}
+
+func (w *watcher) run(ctx context.Context, blkCh <-chan *roothash.AnnotatedBlock, blkSub pubsub.ClosableSubscription) {
+	defer close(w.ch)
+	defer func() {
+		if blkSub != nil {
+			blkSub.Close()
+		}
+	}()
+
+	for {
+		var (
+			blk *roothash.AnnotatedBlock
+			ok  bool
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case blk, ok = <-blkCh:
+		}
+
+		if !ok {
+			if !w.opts.DetectGaps || ctx.Err() != nil {
+				return
+			}
+
+			// The block stream has been interrupted, resubscribe and repair any gap.
+			w.logger.Warn("block stream interrupted, resubscribing")
+
+			blkSub.Close()
+			blkSub = nil
+
+			var err error
+			if blkCh, blkSub, err = w.resubscribe(ctx); err != nil {
+				if ctx.Err() == nil {
+					w.sub.setErr(err)
+				}
+				return
+			}
+			continue
+		}
+
+		if err := w.handleBlock(ctx, blk); err != nil {
+			if ctx.Err() == nil {
+				w.sub.setErr(err)
+			}
+			return
+		}
+	}
+}
+
+func (w *watcher) resubscribe(ctx context.Context) (<-chan *roothash.AnnotatedBlock, pubsub.ClosableSubscription, error) {
+	var (
+		blkCh  <-chan *roothash.AnnotatedBlock
+		blkSub pubsub.ClosableSubscription
+	)
+	err := backoff.Retry(func() error {
+		var err error
+		blkCh, blkSub, err = w.src.WatchBlocks(ctx, w.runtimeID)
+		return err
+	}, backoff.WithContext(backoff.NewExponentialBackOff(), ctx))
+	if err != nil {
+		return nil, nil, fmt.Errorf("watcher: failed to resubscribe: %w", err)
+	}
+	return blkCh, blkSub, nil
+}
+
+func (w *watcher) handleBlock(ctx context.Context, blk *roothash.AnnotatedBlock) error {
+	round := blk.Block.Header.Round
+
+	if w.opts.DetectGaps && w.started {
+		switch {
+		case round <= w.lastRound:
+			// Already delivered, e.g., the latest block is sent again after resubscribing.
+			return nil
+		case round > w.lastRound+1:
+			w.logger.Info("detected gap in block stream",
+				"last_round", w.lastRound,
+				"round", round,
+			)
+
+			if err := w.backfill(ctx, w.lastRound+1, round); err != nil {
+				return err
+			}
+		}
+	}
+
+	return w.deliver(ctx, &Block{
+		Round:  round,
+		Header: &blk.Block.Header,
+		Block:  blk,
+	})
+}
+
+// backfill delivers the blocks of rounds in the range [from, to) using point queries.
+func (w *watcher) backfill(ctx context.Context, from, to uint64) error {
+	lastRetained, err := w.src.GetLastRetainedBlock(ctx, w.runtimeID)
+	if err != nil {
+		return fmt.Errorf("watcher: failed to get last retained block: %w", err)
+	}
+	if from < lastRetained.Header.Round {
+		return fmt.Errorf("%w: rounds %d-%d (last retained round: %d)", ErrGapPruned, from, to-1, lastRetained.Header.Round)
+	}
+
+	for round := from; round < to; round++ {
+		blk, err := w.src.GetBlock(ctx, &api.GetBlockRequest{
+			RuntimeID: w.runtimeID,
+			Round:     round,
+		})
+		switch {
+		case err == nil:
+		case errors.Is(err, roothash.ErrNotFound):
+			// The block may have been pruned after the retention check.
+			return fmt.Errorf("%w: round %d", ErrGapPruned, round)
+		default:
+			return fmt.Errorf("watcher: failed to backfill round %d: %w", round, err)
+		}
+
+		if err = w.deliver(ctx, &Block{
+			Round:      round,
+			Header:     &blk.Header,
+			Backfilled: true,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *watcher) deliver(ctx context.Context, blk *Block) error {
+	if w.opts.HeadersOnly {
+		blk.Block = nil
+	}
+
+	select {
+	case w.ch <- blk:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	w.lastRound = blk.Round
+	w.started = true
+	return nil
+}
@@ -0,0 +1,204 @@
+package watcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	"github.com/oasisprotocol/oasis-core/go/runtime/client/api"
+)
+
+const recvTimeout = 5 * time.Second
+
+var testRuntimeID = common.NewTestNamespaceFromSeed([]byte("runtime client watcher test"), 0)
+
+// fakeSource is a block source whose streams are fed by the test.
+type fakeSource struct {
+	sync.Mutex
+
+	blocks       map[uint64]*block.Block
+	lastRetained uint64
+	streams      chan chan *roothash.AnnotatedBlock
+}
+
+func newFakeSource(rounds uint64) *fakeSource {
+	src := &fakeSource{
+		blocks:  make(map[uint64]*block.Block),
+		streams: make(chan chan *roothash.AnnotatedBlock, 16),
+	}
+	for round := range rounds {
+		blk := &block.Block{}
+		blk.Header.Namespace = testRuntimeID
+		blk.Header.Round = round
+		src.blocks[round] = blk
+	}
+	return src
+}
+
+func (s *fakeSource) WatchBlocks(ctx context.Context, _ common.Namespace) (<-chan *roothash.AnnotatedBlock, pubsub.ClosableSubscription, error) {
+	_, sub := pubsub.NewContextSubscription(ctx)
+	// Buffer blocks so that tests can feed a stream before consuming the watcher output.
+	ch := make(chan *roothash.AnnotatedBlock, 16)
+	s.streams <- ch
+	return ch, sub, nil
+}
+
+func (s *fakeSource) GetBlock(_ context.Context, request *api.GetBlockRequest) (*block.Block, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	blk, ok := s.blocks[request.Round]
+	if !ok || request.Round < s.lastRetained {
+		return nil, roothash.ErrNotFound
+	}
+	return blk, nil
+}
+
+func (s *fakeSource) GetLastRetainedBlock(_ context.Context, _ common.Namespace) (*block.Block, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.blocks[s.lastRetained], nil
+}
+
+func (s *fakeSource) nextStream(t *testing.T) chan *roothash.AnnotatedBlock {
+	select {
+	case ch := <-s.streams:
+		return ch
+	case <-time.After(recvTimeout):
+		t.Fatal("timed out waiting for subscription")
+		return nil
+	}
+}
+
+func (s *fakeSource) send(t *testing.T, ch chan<- *roothash.AnnotatedBlock, round uint64) {
+	select {
+	case ch <- &roothash.AnnotatedBlock{Height: int64(round) + 100, Block: s.blocks[round]}: // nolint: gosec
+	case <-time.After(recvTimeout):
+		t.Fatalf("timed out sending round %d", round)
+	}
+}
+
+func recvBlock(t *testing.T, ch <-chan *Block) *Block {
+	select {
+	case blk, ok := <-ch:
+		require.True(t, ok, "block channel should not be closed")
+		return blk
+	case <-time.After(recvTimeout):
+		t.Fatal("timed out waiting for block")
+		return nil
+	}
+}
+
+func requireClosed(t *testing.T, ch <-chan *Block) {
+	select {
+	case _, ok := <-ch:
+		require.False(t, ok, "block channel should be closed")
+	case <-time.After(recvTimeout):
+		t.Fatal("timed out waiting for block channel to close")
+	}
+}
+
+func TestWatchBlocks(t *testing.T) {
+	require := require.New(t)
+
+	src := newFakeSource(10)
+	ch, sub, err := WatchBlocks(context.Background(), src, testRuntimeID, Options{})
+	require.NoError(err, "WatchBlocks")
+	defer sub.Close()
+
+	// Without gap detection, blocks should be passed through as-is.
+	stream := src.nextStream(t)
+	for _, round := range []uint64{1, 3} {
+		src.send(t, stream, round)
+		blk := recvBlock(t, ch)
+		require.Equal(round, blk.Round)
+		require.Equal(round, blk.Header.Round)
+		require.NotNil(blk.Block, "full blocks should be delivered")
+		require.EqualValues(round+100, blk.Block.Height)
+		require.False(blk.Backfilled)
+	}
+
+	// The stream should end when the source stream ends.
+	close(stream)
+	requireClosed(t, ch)
+	require.NoError(sub.Err())
+}
+
+func TestWatchBlocksGapRepair(t *testing.T) {
+	require := require.New(t)
+
+	src := newFakeSource(10)
+	ch, sub, err := WatchBlocks(context.Background(), src, testRuntimeID, Options{
+		HeadersOnly: true,
+		DetectGaps:  true,
+	})
+	require.NoError(err, "WatchBlocks")
+	defer sub.Close()
+
+	stream := src.nextStream(t)
+	src.send(t, stream, 1)
+	src.send(t, stream, 2)
+
+	// Drop round 3 from the stream.
+	src.send(t, stream, 4)
+
+	// Drop the stream, missing rounds 5 and 6, and resend the latest block after resubscribing.
+	close(stream)
+	stream = src.nextStream(t)
+	src.send(t, stream, 4)
+	src.send(t, stream, 7)
+
+	for _, expected := range []struct {
+		round      uint64
+		backfilled bool
+	}{
+		{1, false},
+		{2, false},
+		{3, true},
+		{4, false},
+		{5, true},
+		{6, true},
+		{7, false},
+	} {
+		blk := recvBlock(t, ch)
+		require.Equal(expected.round, blk.Round, "blocks should be delivered in order")
+		require.Equal(expected.round, blk.Header.Round)
+		require.Equal(expected.backfilled, blk.Backfilled)
+		require.Nil(blk.Block, "only headers should be delivered")
+	}
+
+	sub.Close()
+	requireClosed(t, ch)
+	require.NoError(sub.Err())
+}
+
+func TestWatchBlocksGapPruned(t *testing.T) {
+	require := require.New(t)
+
+	src := newFakeSource(10)
+	ch, sub, err := WatchBlocks(context.Background(), src, testRuntimeID, Options{DetectGaps: true})
+	require.NoError(err, "WatchBlocks")
+	defer sub.Close()
+
+	stream := src.nextStream(t)
+	src.send(t, stream, 1)
+	blk := recvBlock(t, ch)
+	require.EqualValues(1, blk.Round)
+
+	// Prune the missing rounds before the gap is detected.
+	src.Lock()
+	src.lastRetained = 3
+	src.Unlock()
+
+	src.send(t, stream, 5)
+	requireClosed(t, ch)
+	require.ErrorIs(sub.Err(), ErrGapPruned)
+}
@@ -0,0 +1,307 @@
+// Package kvstore implements an untrusted node-local key-value store for runtimes.
+//
+// The store is meant for data that is local to the node (e.g., caches and local indices) and is
+// explicitly untrusted and not replicated. Data survives runtime restarts.
+package kvstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/options"
+
+	cmnBadger "github.com/oasisprotocol/oasis-core/go/common/badger"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+)
+
+// DBFilename is the name of the key-value store database directory within the runtime's data
+// directory.
+const DBFilename = "local-kv.badger.db"
+
+var (
+	// ErrNotFound is the error returned when a key does not exist.
+	ErrNotFound = errors.New("kvstore: key not found")
+	// ErrQuotaExceeded is the error returned when a write would exceed the configured quota.
+	ErrQuotaExceeded = errors.New("kvstore: quota exceeded")
+	// ErrInvalidArgument is the error returned on malformed requests.
+	ErrInvalidArgument = errors.New("kvstore: invalid argument")
+)
+
+// Config is the key-value store configuration.
+type Config struct {
+	// MaxKeySize is the maximum size of a key in bytes.
+	MaxKeySize uint64 `yaml:"max_key_size"`
+	// MaxValueSize is the maximum size of a value in bytes.
+	MaxValueSize uint64 `yaml:"max_value_size"`
+	// MaxTotalSize is the maximum total size of all keys and values in bytes.
+	MaxTotalSize uint64 `yaml:"max_total_size"`
+}
+
+// DefaultConfig returns the default key-value store configuration.
+func DefaultConfig() Config {
+	return Config{
+		MaxKeySize:   1024,
+		MaxValueSize: 1024 * 1024,
+		MaxTotalSize: 128 * 1024 * 1024,
+	}
+}
+
+// Entry is a key-value store entry.
+type Entry struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Store is a namespaced key-value store backed by Badger.
+type Store struct {
+	sync.Mutex
+
+	logger *logging.Logger
+	cfg    Config
+
+	db   *badger.DB
+	size uint64
+}
+
+// New opens (or creates) the key-value store in the given runtime data directory.
+func New(dataDir string, cfg Config) (*Store, error) {
+	logger := logging.GetLogger("runtime/host/kvstore")
+
+	opts := badger.DefaultOptions(filepath.Join(dataDir, DBFilename))
+	opts = opts.WithLogger(cmnBadger.NewLogAdapter(logger))
+	opts = opts.WithSyncWrites(true)
+	opts = opts.WithCompression(options.Snappy)
+	opts = opts.WithBlockCacheSize(8 * 1024 * 1024)
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("kvstore: failed to open database: %w", err)
+	}
+
+	s := &Store{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+
+	// Compute the current size so that quotas are enforced across restarts.
+	err = db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.IteratorOptions{})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			s.size += uint64(len(item.Key())) + uint64(item.ValueSize())
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("kvstore: failed to compute size: %w", err)
+	}
+
+	return s, nil
+}
+
+// encodeKey prefixes the key with the length-prefixed namespace.
+func encodeKey(namespace string, key []byte) []byte {
+	data := make([]byte, 0, 2+len(namespace)+len(key))
+	data = binary.BigEndian.AppendUint16(data, uint16(len(namespace)))
+	data = append(data, namespace...)
+	data = append(data, key...)
+	return data
+}
+
+func (s *Store) checkKey(namespace string, key []byte) error {
+	if len(namespace) > 0xffff {
+		return fmt.Errorf("%w: namespace too long", ErrInvalidArgument)
+	}
+	if uint64(len(key)) > s.cfg.MaxKeySize {
+		return fmt.Errorf("%w: key too long", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// Size returns the total size of all keys and values in bytes.
+func (s *Store) Size() uint64 {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.size
+}
+
+// Get returns the value stored under the given key.
+func (s *Store) Get(namespace string, key []byte) ([]byte, error) {
+	if err := s.checkKey(namespace, key); err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	err := s.db.View(func(tx *badger.Txn) error {
+		item, err := tx.Get(encodeKey(namespace, key))
+		switch err {
+		case nil:
+		case badger.ErrKeyNotFound:
+			return ErrNotFound
+		default:
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Put stores the value under the given key.
+func (s *Store) Put(namespace string, key, value []byte) error {
+	if err := s.checkKey(namespace, key); err != nil {
+		return err
+	}
+	if uint64(len(value)) > s.cfg.MaxValueSize {
+		return fmt.Errorf("%w: value too large", ErrQuotaExceeded)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	// The size is only updated once the transaction has been committed so that it does not drift
+	// in case the commit fails.
+	var newSize uint64
+	dbKey := encodeKey(namespace, key)
+	err := s.db.Update(func(tx *badger.Txn) error {
+		var oldSize uint64
+		item, err := tx.Get(dbKey)
+		switch err {
+		case nil:
+			oldSize = uint64(len(dbKey)) + uint64(item.ValueSize())
+		case badger.ErrKeyNotFound:
+		default:
+			return err
+		}
+
+		newSize = s.size - oldSize + uint64(len(dbKey)) + uint64(len(value))
+		if newSize > s.cfg.MaxTotalSize {
+			return fmt.Errorf("%w: total size limit reached", ErrQuotaExceeded)
+		}
+		return tx.Set(dbKey, value)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.size = newSize
+	return nil
+}
+
+// Delete removes the given key. Deleting a non-existent key is not an error.
+func (s *Store) Delete(namespace string, key []byte) error {
+	if err := s.checkKey(namespace, key); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	// The size is only updated once the transaction has been committed so that it does not drift
+	// in case the commit fails.
+	var size uint64
+	dbKey := encodeKey(namespace, key)
+	err := s.db.Update(func(tx *badger.Txn) error {
+		item, err := tx.Get(dbKey)
+		switch err {
+		case nil:
+		case badger.ErrKeyNotFound:
+			return nil
+		default:
+			return err
+		}
+		size = uint64(len(dbKey)) + uint64(item.ValueSize())
+
+		return tx.Delete(dbKey)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.size -= size
+	return nil
+}
+
+// Iterate returns up to limit entries in the given namespace with keys that have the given
+// prefix and are greater than or equal to the start key, in key order.
+//
+// A limit of zero means no limit.
+func (s *Store) Iterate(namespace string, prefix, start []byte, limit uint64) ([]*Entry, error) {
+	if err := s.checkKey(namespace, prefix); err != nil {
+		return nil, err
+	}
+
+	nsPrefix := encodeKey(namespace, nil)
+	dbPrefix := encodeKey(namespace, prefix)
+	seekKey := dbPrefix
+	if sk := encodeKey(namespace, start); string(sk) > string(seekKey) {
+		seekKey = sk
+	}
+
+	var entries []*Entry
+	err := s.db.View(func(tx *badger.Txn) error {
+		it := tx.NewIterator(badger.IteratorOptions{
+			PrefetchValues: true,
+			PrefetchSize:   100,
+			Prefix:         dbPrefix,
+		})
+		defer it.Close()
+
+		for it.Seek(seekKey); it.Valid(); it.Next() {
+			if limit > 0 && uint64(len(entries)) >= limit {
+				break
+			}
+
+			item := it.Item()
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, &Entry{
+				Key:   item.KeyCopy(nil)[len(nsPrefix):],
+				Value: value,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Wipe removes all data from the store.
+func (s *Store) Wipe() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.db.DropAll(); err != nil {
+		return fmt.Errorf("kvstore: failed to wipe: %w", err)
+	}
+	s.size = 0
+
+	s.logger.Info("local key-value store wiped")
+
+	return nil
+}
+
+// Close closes the store.
+func (s *Store) Close() {
+	if err := s.db.Close(); err != nil {
+		s.logger.Error("failed to close database",
+			"err", err,
+		)
+	}
+}
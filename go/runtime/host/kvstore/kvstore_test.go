@@ -0,0 +1,88 @@
+package kvstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	require := require.New(t)
+
+	dataDir := t.TempDir()
+	cfg := Config{
+		MaxKeySize:   16,
+		MaxValueSize: 64,
+		MaxTotalSize: 128,
+	}
+
+	s, err := New(dataDir, cfg)
+	require.NoError(err, "New")
+
+	// Basic operations.
+	_, err = s.Get("cache", []byte("missing"))
+	require.ErrorIs(err, ErrNotFound)
+
+	require.NoError(s.Put("cache", []byte("b"), []byte("value b")))
+	require.NoError(s.Put("cache", []byte("a"), []byte("value a")))
+	require.NoError(s.Put("cache", []byte("c"), []byte("value c")))
+	require.NoError(s.Put("index", []byte("a"), []byte("other namespace")))
+
+	value, err := s.Get("cache", []byte("a"))
+	require.NoError(err, "Get")
+	require.Equal([]byte("value a"), value)
+	value, err = s.Get("index", []byte("a"))
+	require.NoError(err, "Get")
+	require.Equal([]byte("other namespace"), value, "namespaces should be isolated")
+
+	// Iteration ordering.
+	entries, err := s.Iterate("cache", nil, nil, 0)
+	require.NoError(err, "Iterate")
+	require.Len(entries, 3)
+	require.Equal([]byte("a"), entries[0].Key)
+	require.Equal([]byte("b"), entries[1].Key)
+	require.Equal([]byte("c"), entries[2].Key)
+
+	entries, err = s.Iterate("cache", nil, []byte("b"), 1)
+	require.NoError(err, "Iterate")
+	require.Len(entries, 1)
+	require.Equal([]byte("b"), entries[0].Key)
+	require.Equal([]byte("value b"), entries[0].Value)
+
+	// Quota rejection.
+	err = s.Put("cache", []byte("this key is way too long"), []byte("v"))
+	require.ErrorIs(err, ErrInvalidArgument, "oversized keys should be rejected")
+	err = s.Put("cache", []byte("d"), make([]byte, 65))
+	require.ErrorIs(err, ErrQuotaExceeded, "oversized values should be rejected")
+	err = s.Put("cache", []byte("d"), make([]byte, 64))
+	require.ErrorIs(err, ErrQuotaExceeded, "writes over the total size limit should be rejected")
+	_, err = s.Get("cache", []byte("d"))
+	require.ErrorIs(err, ErrNotFound, "rejected writes should not be stored")
+
+	// Overwriting and deleting should free up space.
+	sizeBefore := s.Size()
+	require.NoError(s.Put("cache", []byte("a"), []byte("x")))
+	require.Less(s.Size(), sizeBefore)
+	require.NoError(s.Delete("cache", []byte("b")))
+	require.NoError(s.Delete("cache", []byte("b")), "deleting a missing key should not fail")
+
+	// Data should survive restarts.
+	size := s.Size()
+	s.Close()
+	s, err = New(dataDir, cfg)
+	require.NoError(err, "New")
+	defer s.Close()
+	require.Equal(size, s.Size(), "size should be recomputed after restart")
+	value, err = s.Get("cache", []byte("a"))
+	require.NoError(err, "Get after restart")
+	require.Equal([]byte("x"), value)
+
+	// Wipe.
+	require.NoError(s.Wipe(), "Wipe")
+	require.Zero(s.Size())
+	entries, err = s.Iterate("cache", nil, nil, 0)
+	require.NoError(err, "Iterate")
+	require.Empty(entries)
+	_, err = s.Get("index", []byte("a"))
+	require.ErrorIs(err, ErrNotFound)
+}
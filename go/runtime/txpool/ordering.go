@@ -0,0 +1,370 @@
+package txpool
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+)
+
+// OrderingComparatorVersion is the version of the comparator used to order scheduled batches.
+//
+// Version 1 orders transactions by descending priority and, within the same priority class, by
+// ascending arrival sequence number.
+const OrderingComparatorVersion = 1
+
+const (
+	orderingResultValid     = "valid"
+	orderingResultInvalid   = "invalid"
+	orderingResultViolation = "violation"
+)
+
+var (
+	// OrderingProofSignatureContext is the context used for signing batch ordering proofs.
+	OrderingProofSignatureContext = signature.NewContext(
+		"oasis-core/txpool: ordering proof",
+		signature.WithChainSeparation(),
+		signature.WithDynamicSuffix(" for runtime ", common.NamespaceHexSize),
+	)
+
+	// ErrInvalidOrderingProof is the error returned when an ordering proof is malformed, is not
+	// properly signed or does not match the batch.
+	ErrInvalidOrderingProof = errors.New("txpool: invalid ordering proof")
+
+	// ErrOrderingViolation is the error returned when a batch does not respect the declared
+	// ordering policy.
+	ErrOrderingViolation = errors.New("txpool: ordering violation")
+
+	orderingVerifications = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_txpool_ordering_verifications",
+			Help: "Number of verified batch ordering proofs by result.",
+		},
+		[]string{"runtime", "result"},
+	)
+
+	orderingMetricsOnce sync.Once
+)
+
+// ArrivalSequencer assigns arrival sequence numbers to transactions at pool admission.
+//
+// Sequence numbers are strictly increasing in admission order and are never reused while the
+// sequencer exists, so they can be used to prove that transactions of the same priority class
+// were scheduled in the order they arrived.
+type ArrivalSequencer struct {
+	l sync.Mutex
+
+	next uint64
+	seqs map[hash.Hash]uint64
+}
+
+// NewArrivalSequencer creates a new arrival sequencer.
+func NewArrivalSequencer() *ArrivalSequencer {
+	return &ArrivalSequencer{
+		seqs: make(map[hash.Hash]uint64),
+	}
+}
+
+// Admit assigns the next arrival sequence number to the given transaction. In case the
+// transaction has already been admitted, its existing sequence number is returned.
+func (s *ArrivalSequencer) Admit(txHash hash.Hash) uint64 {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if seq, ok := s.seqs[txHash]; ok {
+		return seq
+	}
+	seq := s.next
+	s.next++
+	s.seqs[txHash] = seq
+	return seq
+}
+
+// Sequence returns the arrival sequence number of the given transaction.
+func (s *ArrivalSequencer) Sequence(txHash hash.Hash) (uint64, bool) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	seq, ok := s.seqs[txHash]
+	return seq, ok
+}
+
+// Remove forgets the sequence numbers of the given transactions after they have been removed
+// from the pool.
+func (s *ArrivalSequencer) Remove(txHashes []hash.Hash) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	for _, txHash := range txHashes {
+		delete(s.seqs, txHash)
+	}
+}
+
+// OrderedTx is a scheduled transaction together with the data that determines its order.
+type OrderedTx struct {
+	// Hash is the transaction hash.
+	Hash hash.Hash
+	// Priority is the transaction priority.
+	Priority uint64
+	// Seq is the arrival sequence number assigned at pool admission.
+	Seq uint64
+}
+
+// SortOrdered sorts the given transactions according to the current ordering comparator.
+func SortOrdered(txs []OrderedTx) {
+	sort.SliceStable(txs, func(i, j int) bool {
+		return compareOrdering(txs[i].Priority, txs[i].Seq, txs[j].Priority, txs[j].Seq) < 0
+	})
+}
+
+// compareOrdering compares two transactions according to the current ordering comparator and
+// returns a negative number iff the first one should be scheduled before the second one.
+func compareOrdering(priorityA, seqA, priorityB, seqB uint64) int {
+	switch {
+	case priorityA > priorityB:
+		return -1
+	case priorityA < priorityB:
+		return 1
+	case seqA < seqB:
+		return -1
+	case seqA > seqB:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// OrderingEntry is the ordering data of a single transaction in an ordering proof.
+type OrderingEntry struct {
+	_ struct{} `cbor:",toarray"` // nolint
+
+	// Priority is the transaction priority.
+	Priority uint64
+	// Seq is the arrival sequence number assigned at pool admission.
+	Seq uint64
+}
+
+// OrderingProofHeader is the signed part of an ordering proof.
+type OrderingProofHeader struct {
+	// Round is the round of the proposal the batch belongs to.
+	Round uint64 `json:"round"`
+
+	// BatchHash is the hash of the ordered list of transaction hashes in the batch.
+	BatchHash hash.Hash `json:"batch_hash"`
+
+	// ComparatorVersion is the version of the comparator used to order the batch.
+	ComparatorVersion uint16 `json:"comparator_version"`
+
+	// Entries are the ordering entries for all transactions in the batch, in batch order.
+	Entries []OrderingEntry `json:"entries"`
+}
+
+// OrderingProof is a compact proof of the order in which the scheduler assembled a batch. It is
+// distributed alongside the batch proposal so that other committee members and external auditors
+// can verify that the batch respects the declared ordering policy.
+//
+// Ordering proofs are advisory and do not affect consensus.
+type OrderingProof struct {
+	// NodeID is the public key of the node that assembled the batch.
+	NodeID signature.PublicKey `json:"node_id"`
+
+	// Header is the ordering proof header.
+	Header OrderingProofHeader `json:"header"`
+
+	// Signature is the ordering proof header signature.
+	Signature signature.RawSignature `json:"sig"`
+}
+
+// NewOrderingProof creates a new signed ordering proof for the given batch, in the order in
+// which it has been assembled.
+func NewOrderingProof(signer signature.Signer, runtimeID common.Namespace, round uint64, batch []OrderedTx) (*OrderingProof, error) {
+	txHashes := make([]hash.Hash, 0, len(batch))
+	entries := make([]OrderingEntry, 0, len(batch))
+	for _, tx := range batch {
+		txHashes = append(txHashes, tx.Hash)
+		entries = append(entries, OrderingEntry{
+			Priority: tx.Priority,
+			Seq:      tx.Seq,
+		})
+	}
+
+	p := OrderingProof{
+		NodeID: signer.Public(),
+		Header: OrderingProofHeader{
+			Round:             round,
+			BatchHash:         orderingBatchHash(txHashes),
+			ComparatorVersion: OrderingComparatorVersion,
+			Entries:           entries,
+		},
+	}
+
+	sigCtx, err := OrderingProofSignatureContext.WithSuffix(runtimeID.String())
+	if err != nil {
+		return nil, fmt.Errorf("txpool: signature context error: %w", err)
+	}
+	sig, err := signature.Sign(signer, sigCtx, cbor.Marshal(p.Header))
+	if err != nil {
+		return nil, fmt.Errorf("txpool: failed to sign ordering proof: %w", err)
+	}
+	p.Signature = sig.Signature
+	return &p, nil
+}
+
+// Verify verifies that the ordering proof is properly signed, matches the given batch and that
+// the batch respects the declared ordering policy.
+//
+// In case the proof itself is not valid, an error wrapping ErrInvalidOrderingProof is returned.
+// In case the batch does not respect the ordering policy, an OrderingViolationError is returned.
+func (p *OrderingProof) Verify(runtimeID common.Namespace, batch []hash.Hash) error {
+	sigCtx, err := OrderingProofSignatureContext.WithSuffix(runtimeID.String())
+	if err != nil {
+		return fmt.Errorf("%w: signature context error: %w", ErrInvalidOrderingProof, err)
+	}
+	if !p.NodeID.Verify(sigCtx, cbor.Marshal(p.Header), p.Signature[:]) {
+		return fmt.Errorf("%w: signature verification failed", ErrInvalidOrderingProof)
+	}
+	if p.Header.ComparatorVersion != OrderingComparatorVersion {
+		return fmt.Errorf("%w: unsupported comparator version (expected: %d got: %d)",
+			ErrInvalidOrderingProof, OrderingComparatorVersion, p.Header.ComparatorVersion,
+		)
+	}
+	if len(p.Header.Entries) != len(batch) {
+		return fmt.Errorf("%w: entry count mismatch (expected: %d got: %d)",
+			ErrInvalidOrderingProof, len(batch), len(p.Header.Entries),
+		)
+	}
+	if batchHash := orderingBatchHash(batch); !p.Header.BatchHash.Equal(&batchHash) {
+		return fmt.Errorf("%w: batch hash mismatch", ErrInvalidOrderingProof)
+	}
+
+	for i := 1; i < len(p.Header.Entries); i++ {
+		prev, cur := &p.Header.Entries[i-1], &p.Header.Entries[i]
+		if compareOrdering(prev.Priority, prev.Seq, cur.Priority, cur.Seq) < 0 {
+			continue
+		}
+		return &OrderingViolationError{
+			Index:  i,
+			TxHash: batch[i],
+			Reason: fmt.Sprintf("transaction (priority: %d seq: %d) scheduled after transaction (priority: %d seq: %d)",
+				cur.Priority, cur.Seq, prev.Priority, prev.Seq,
+			),
+		}
+	}
+	return nil
+}
+
+// orderingBatchHash computes the hash of the given ordered list of transaction hashes.
+func orderingBatchHash(batch []hash.Hash) hash.Hash {
+	if batch == nil {
+		batch = []hash.Hash{}
+	}
+	return hash.NewFrom(batch)
+}
+
+// OrderingViolationError is the error returned when a batch does not respect the declared
+// ordering policy.
+type OrderingViolationError struct {
+	// Index is the index of the first transaction in the batch that is out of order.
+	Index int
+
+	// TxHash is the hash of the first transaction in the batch that is out of order.
+	TxHash hash.Hash
+
+	// Reason describes the violation.
+	Reason string
+}
+
+// Error implements error.
+func (e *OrderingViolationError) Error() string {
+	return fmt.Sprintf("%s: batch index %d (tx: %s): %s", ErrOrderingViolation, e.Index, e.TxHash, e.Reason)
+}
+
+// Is returns true iff the target is ErrOrderingViolation.
+func (e *OrderingViolationError) Is(target error) bool {
+	return target == ErrOrderingViolation
+}
+
+// OrderingViolation is an event emitted when a verified batch ordering proof is invalid or shows
+// that a batch does not respect the declared ordering policy.
+type OrderingViolation struct {
+	// Round is the round of the proposal the batch belongs to.
+	Round uint64
+	// NodeID is the public key of the node that assembled the batch.
+	NodeID signature.PublicKey
+	// Err is the verification error.
+	Err error
+}
+
+// OrderingVerifier verifies batch ordering proofs of a runtime.
+//
+// Verification is advisory: failures do not affect the handling of the batch, but are reported
+// via metrics and OrderingViolation events.
+type OrderingVerifier struct {
+	runtimeID common.Namespace
+
+	notifier *pubsub.Broker
+	logger   *logging.Logger
+}
+
+// NewOrderingVerifier creates a new ordering proof verifier for the given runtime.
+func NewOrderingVerifier(runtimeID common.Namespace) *OrderingVerifier {
+	orderingMetricsOnce.Do(func() {
+		prometheus.MustRegister(orderingVerifications)
+	})
+
+	return &OrderingVerifier{
+		runtimeID: runtimeID,
+		notifier:  pubsub.NewBroker(false),
+		logger:    logging.GetLogger("runtime/txpool/ordering").With("runtime_id", runtimeID),
+	}
+}
+
+// Verify verifies the given ordering proof against the given batch, records the result and
+// emits an OrderingViolation event in case verification fails.
+func (v *OrderingVerifier) Verify(proof *OrderingProof, batch []hash.Hash) error {
+	err := proof.Verify(v.runtimeID, batch)
+
+	result := orderingResultValid
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrOrderingViolation):
+		result = orderingResultViolation
+	default:
+		result = orderingResultInvalid
+	}
+	orderingVerifications.With(prometheus.Labels{
+		"runtime": v.runtimeID.String(),
+		"result":  result,
+	}).Inc()
+
+	if err != nil {
+		v.logger.Warn("batch ordering proof verification failed",
+			"round", proof.Header.Round,
+			"node_id", proof.NodeID,
+			"err", err,
+		)
+		v.notifier.Broadcast(&OrderingViolation{
+			Round:  proof.Header.Round,
+			NodeID: proof.NodeID,
+			Err:    err,
+		})
+	}
+	return err
+}
+
+// WatchViolations returns a channel that produces a stream of ordering violations.
+func (v *OrderingVerifier) WatchViolations() (<-chan *OrderingViolation, pubsub.ClosableSubscription) {
+	ch := make(chan *OrderingViolation)
+	sub := v.notifier.Subscribe()
+	sub.Unwrap(ch)
+	return ch, sub
+}
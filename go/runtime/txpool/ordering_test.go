@@ -0,0 +1,144 @@
+package txpool
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	genesisTestHelpers "github.com/oasisprotocol/oasis-core/go/genesis/tests"
+)
+
+func TestArrivalSequencer(t *testing.T) {
+	require := require.New(t)
+
+	s := NewArrivalSequencer()
+	tx1 := hash.NewFromBytes([]byte("tx 1"))
+	tx2 := hash.NewFromBytes([]byte("tx 2"))
+
+	require.EqualValues(0, s.Admit(tx1))
+	require.EqualValues(1, s.Admit(tx2))
+	require.EqualValues(0, s.Admit(tx1), "readmission should keep the sequence number")
+
+	seq, ok := s.Sequence(tx2)
+	require.True(ok)
+	require.EqualValues(1, seq)
+
+	s.Remove([]hash.Hash{tx1})
+	_, ok = s.Sequence(tx1)
+	require.False(ok, "removed transactions should be forgotten")
+	require.EqualValues(2, s.Admit(tx1), "sequence numbers should never be reused")
+}
+
+func TestOrderingProof(t *testing.T) {
+	require := require.New(t)
+
+	genesisTestHelpers.SetTestChainContext()
+
+	rtID := common.NewTestNamespaceFromSeed([]byte("txpool ordering proof"), 0)
+	signer := memorySigner.NewTestSigner("txpool ordering proof: node")
+	v := NewOrderingVerifier(rtID)
+
+	ch, sub := v.WatchViolations()
+	defer sub.Close()
+
+	verifications := func(result string) float64 {
+		return testutil.ToFloat64(orderingVerifications.With(prometheus.Labels{
+			"runtime": rtID.String(),
+			"result":  result,
+		}))
+	}
+	requireViolationEvent := func(round uint64, target error) {
+		select {
+		case ev := <-ch:
+			require.EqualValues(round, ev.Round)
+			require.Equal(signer.Public(), ev.NodeID)
+			require.ErrorIs(ev.Err, target)
+		case <-time.After(time.Second):
+			require.FailNow("timed out waiting for ordering violation")
+		}
+	}
+
+	// Admit transactions in an order that does not match their priorities.
+	s := NewArrivalSequencer()
+	var txs []OrderedTx
+	for i, priority := range []uint64{1, 5, 1, 5, 3} {
+		h := hash.NewFromBytes([]byte(fmt.Sprintf("tx %d", i)))
+		txs = append(txs, OrderedTx{
+			Hash:     h,
+			Priority: priority,
+			Seq:      s.Admit(h),
+		})
+	}
+	batchHashes := func(txs []OrderedTx) []hash.Hash {
+		var hashes []hash.Hash
+		for _, tx := range txs {
+			hashes = append(hashes, tx.Hash)
+		}
+		return hashes
+	}
+
+	// Compliant ordering.
+	SortOrdered(txs)
+	var order []uint64
+	for _, tx := range txs {
+		order = append(order, tx.Seq)
+	}
+	require.Equal([]uint64{1, 3, 4, 0, 2}, order, "arrival order within priority classes")
+
+	proof, err := NewOrderingProof(signer, rtID, 42, txs)
+	require.NoError(err, "NewOrderingProof")
+	require.EqualValues(OrderingComparatorVersion, proof.Header.ComparatorVersion)
+	require.Len(proof.Header.Entries, len(txs))
+	require.NoError(v.Verify(proof, batchHashes(txs)), "compliant ordering should verify")
+	require.EqualValues(1, verifications(orderingResultValid))
+
+	empty, err := NewOrderingProof(signer, rtID, 43, nil)
+	require.NoError(err, "NewOrderingProof")
+	require.NoError(v.Verify(empty, nil), "empty batches should verify")
+
+	// Violating ordering: arrival order swapped within a priority class.
+	violating := append([]OrderedTx{}, txs...)
+	violating[0], violating[1] = violating[1], violating[0]
+	proof, err = NewOrderingProof(signer, rtID, 44, violating)
+	require.NoError(err, "NewOrderingProof")
+	err = v.Verify(proof, batchHashes(violating))
+	require.ErrorIs(err, ErrOrderingViolation)
+	var verr *OrderingViolationError
+	require.True(errors.As(err, &verr))
+	require.Equal(1, verr.Index)
+	require.Equal(violating[1].Hash, verr.TxHash)
+	require.EqualValues(1, verifications(orderingResultViolation))
+	requireViolationEvent(44, ErrOrderingViolation)
+
+	// Violating ordering: lower priority scheduled first.
+	violating = append([]OrderedTx{}, txs...)
+	violating[2], violating[3] = violating[3], violating[2]
+	proof, err = NewOrderingProof(signer, rtID, 45, violating)
+	require.NoError(err, "NewOrderingProof")
+	err = v.Verify(proof, batchHashes(violating))
+	require.True(errors.As(err, &verr))
+	require.Equal(3, verr.Index)
+	requireViolationEvent(45, ErrOrderingViolation)
+
+	// Proofs not matching the batch.
+	proof, err = NewOrderingProof(signer, rtID, 46, txs)
+	require.NoError(err, "NewOrderingProof")
+	require.ErrorIs(v.Verify(proof, batchHashes(violating)), ErrInvalidOrderingProof, "batch hash mismatch")
+	requireViolationEvent(46, ErrInvalidOrderingProof)
+	require.ErrorIs(v.Verify(proof, batchHashes(txs[:4])), ErrInvalidOrderingProof, "entry count mismatch")
+	requireViolationEvent(46, ErrInvalidOrderingProof)
+	otherRtID := common.NewTestNamespaceFromSeed([]byte("txpool ordering proof"), 1)
+	require.ErrorIs(proof.Verify(otherRtID, batchHashes(txs)), ErrInvalidOrderingProof, "signature from another runtime")
+
+	proof.Header.ComparatorVersion++
+	require.ErrorIs(proof.Verify(rtID, batchHashes(txs)), ErrInvalidOrderingProof, "tampered header")
+	require.EqualValues(2, verifications(orderingResultInvalid))
+}
@@ -0,0 +1,316 @@
+package txpool
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+const (
+	// StatelessValidatorSize is the name of the built-in validator that rejects empty and
+	// oversized transactions. It is always enabled.
+	StatelessValidatorSize = "size"
+	// StatelessValidatorEnvelope is the name of the built-in validator that rejects transactions
+	// that are not well-formed standard envelopes of a supported version.
+	StatelessValidatorEnvelope = "envelope"
+	// StatelessValidatorSignature is the name of the built-in validator that rejects transactions
+	// whose authentication proofs contain malformed signatures.
+	StatelessValidatorSignature = "signature"
+
+	// EnvelopeVersion is the supported standard transaction envelope version.
+	EnvelopeVersion = 1
+
+	// maxSignatureSize is the maximum size of an encoded signature in an authentication proof.
+	maxSignatureSize = 128
+)
+
+var (
+	// ErrMalformedTransaction is the error returned when a transaction is rejected by a stateless
+	// validator.
+	ErrMalformedTransaction = errors.New("txpool: malformed transaction")
+
+	statelessRejectedTransactions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_txpool_stateless_rejected_transactions",
+			Help: "Number of transactions rejected by stateless validators.",
+		},
+		[]string{"runtime", "validator"},
+	)
+
+	statelessMetricsOnce sync.Once
+
+	statelessValidatorsLock sync.RWMutex
+	statelessValidators     = map[string]StatelessValidatorFactory{
+		StatelessValidatorEnvelope:  func() StatelessValidator { return envelopeValidator{} },
+		StatelessValidatorSignature: func() StatelessValidator { return signatureValidator{} },
+	}
+)
+
+// StatelessValidator is a transaction validator that does not require any runtime state and can
+// therefore reject malformed transactions before they are queued for checking by the runtime.
+type StatelessValidator interface {
+	// Name returns the validator name.
+	Name() string
+
+	// ValidateTx returns an error in case the given transaction is malformed.
+	ValidateTx(tx []byte) error
+}
+
+// StatelessValidatorFactory creates a new stateless validator instance.
+type StatelessValidatorFactory func() StatelessValidator
+
+// RegisterStatelessValidator registers a new stateless validator that runtimes can enable by name.
+func RegisterStatelessValidator(name string, factory StatelessValidatorFactory) error {
+	statelessValidatorsLock.Lock()
+	defer statelessValidatorsLock.Unlock()
+
+	if name == StatelessValidatorSize {
+		return fmt.Errorf("txpool: stateless validator '%s' is always enabled", name)
+	}
+	if _, exists := statelessValidators[name]; exists {
+		return fmt.Errorf("txpool: stateless validator '%s' already registered", name)
+	}
+	statelessValidators[name] = factory
+	return nil
+}
+
+// StatelessValidationError is the error returned when a transaction is rejected by a stateless
+// validator.
+type StatelessValidationError struct {
+	// Validator is the name of the validator that rejected the transaction.
+	Validator string
+
+	// Err is the rejection reason.
+	Err error
+}
+
+// Error implements error.
+func (e *StatelessValidationError) Error() string {
+	return fmt.Sprintf("%s: rejected by '%s' validator: %s", ErrMalformedTransaction, e.Validator, e.Err)
+}
+
+// Is returns true iff the target is ErrMalformedTransaction.
+func (e *StatelessValidationError) Is(target error) bool {
+	return target == ErrMalformedTransaction
+}
+
+// Unwrap returns the rejection reason.
+func (e *StatelessValidationError) Unwrap() error {
+	return e.Err
+}
+
+// StatelessValidators is the set of stateless validators used for a runtime.
+type StatelessValidators struct {
+	runtimeID common.Namespace
+
+	size       sizeValidator
+	validators atomic.Pointer[[]StatelessValidator]
+}
+
+// NewStatelessValidators creates a new set of stateless validators for the given runtime with
+// only the built-in size validator enabled.
+func NewStatelessValidators(runtimeID common.Namespace) *StatelessValidators {
+	statelessMetricsOnce.Do(func() {
+		prometheus.MustRegister(statelessRejectedTransactions)
+	})
+
+	v := &StatelessValidators{
+		runtimeID: runtimeID,
+	}
+	v.validators.Store(&[]StatelessValidator{})
+	return v
+}
+
+// SetMaxTxSize sets the maximum size of a transaction. Zero means that there is no limit.
+func (v *StatelessValidators) SetMaxTxSize(maxTxSize uint64) {
+	v.size.maxTxSize.Store(maxTxSize)
+}
+
+// Enable replaces the set of additional validators with the named ones. It is called with the
+// validators requested by the runtime's bundle metadata.
+func (v *StatelessValidators) Enable(names []string) error {
+	statelessValidatorsLock.RLock()
+	defer statelessValidatorsLock.RUnlock()
+
+	names = append([]string{}, names...)
+	sort.Strings(names)
+
+	validators := make([]StatelessValidator, 0, len(names))
+	for i, name := range names {
+		if name == StatelessValidatorSize || (i > 0 && names[i-1] == name) {
+			continue
+		}
+		factory, ok := statelessValidators[name]
+		if !ok {
+			return fmt.Errorf("txpool: unknown stateless validator '%s'", name)
+		}
+		validators = append(validators, factory())
+	}
+	v.validators.Store(&validators)
+	return nil
+}
+
+// Enabled returns the names of all enabled validators.
+func (v *StatelessValidators) Enabled() []string {
+	names := []string{StatelessValidatorSize}
+	for _, sv := range *v.validators.Load() {
+		names = append(names, sv.Name())
+	}
+	return names
+}
+
+// ValidateTx runs all enabled validators on the given transaction. In case the transaction is
+// rejected, a StatelessValidationError is returned.
+func (v *StatelessValidators) ValidateTx(tx []byte) error {
+	if err := v.validate(&v.size, tx); err != nil {
+		return err
+	}
+	for _, sv := range *v.validators.Load() {
+		if err := v.validate(sv, tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *StatelessValidators) validate(sv StatelessValidator, tx []byte) error {
+	if err := sv.ValidateTx(tx); err != nil {
+		statelessRejectedTransactions.With(prometheus.Labels{
+			"runtime":   v.runtimeID.String(),
+			"validator": sv.Name(),
+		}).Inc()
+		return &StatelessValidationError{
+			Validator: sv.Name(),
+			Err:       err,
+		}
+	}
+	return nil
+}
+
+type sizeValidator struct {
+	maxTxSize atomic.Uint64
+}
+
+func (sv *sizeValidator) Name() string {
+	return StatelessValidatorSize
+}
+
+func (sv *sizeValidator) ValidateTx(tx []byte) error {
+	if len(tx) == 0 {
+		return fmt.Errorf("empty transaction")
+	}
+	if maxTxSize := sv.maxTxSize.Load(); maxTxSize > 0 && uint64(len(tx)) > maxTxSize {
+		return fmt.Errorf("transaction too large (size: %d max: %d)", len(tx), maxTxSize)
+	}
+	return nil
+}
+
+// envelope is the standard runtime transaction envelope.
+type envelope struct {
+	_ struct{} `cbor:",toarray"`
+
+	// Body is the CBOR-encoded transaction body.
+	Body []byte
+	// AuthProofs are the authentication proofs for the transaction.
+	AuthProofs []envelopeAuthProof
+}
+
+// envelopeBody is the part of the transaction body that is relevant for stateless validation.
+type envelopeBody struct {
+	// Version is the envelope version.
+	Version uint16 `json:"v"`
+}
+
+// envelopeAuthProof is an authentication proof in the standard transaction envelope.
+type envelopeAuthProof struct {
+	// Signature is a single signature.
+	Signature []byte `json:"signature,omitempty"`
+	// Multisig are signatures of a multisig proof where missing signatures are nil.
+	Multisig [][]byte `json:"multisig,omitempty"`
+	// Module is a module-specific proof.
+	Module string `json:"module,omitempty"`
+}
+
+func decodeEnvelope(tx []byte) (*envelope, error) {
+	var env envelope
+	if err := cbor.Unmarshal(tx, &env); err != nil {
+		return nil, fmt.Errorf("malformed envelope: %w", err)
+	}
+	return &env, nil
+}
+
+type envelopeValidator struct{}
+
+func (envelopeValidator) Name() string {
+	return StatelessValidatorEnvelope
+}
+
+func (envelopeValidator) ValidateTx(tx []byte) error {
+	env, err := decodeEnvelope(tx)
+	if err != nil {
+		return err
+	}
+
+	var body envelopeBody
+	if err = cbor.Unmarshal(env.Body, &body); err != nil {
+		return fmt.Errorf("malformed envelope body: %w", err)
+	}
+	if body.Version != EnvelopeVersion {
+		return fmt.Errorf("unsupported envelope version (expected: %d got: %d)", EnvelopeVersion, body.Version)
+	}
+	return nil
+}
+
+type signatureValidator struct{}
+
+func (signatureValidator) Name() string {
+	return StatelessValidatorSignature
+}
+
+func (signatureValidator) ValidateTx(tx []byte) error {
+	env, err := decodeEnvelope(tx)
+	if err != nil {
+		return err
+	}
+
+	checkSignature := func(sig []byte) error {
+		if len(sig) == 0 || len(sig) > maxSignatureSize {
+			return fmt.Errorf("malformed signature (size: %d)", len(sig))
+		}
+		return nil
+	}
+
+	for i, proof := range env.AuthProofs {
+		switch {
+		case proof.Signature != nil:
+			if err = checkSignature(proof.Signature); err != nil {
+				return fmt.Errorf("auth proof %d: %w", i, err)
+			}
+		case proof.Multisig != nil:
+			var present int
+			for _, sig := range proof.Multisig {
+				if sig == nil {
+					continue
+				}
+				if err = checkSignature(sig); err != nil {
+					return fmt.Errorf("auth proof %d: %w", i, err)
+				}
+				present++
+			}
+			if present == 0 {
+				return fmt.Errorf("auth proof %d: multisig without signatures", i)
+			}
+		case proof.Module != "":
+		default:
+			return fmt.Errorf("auth proof %d: empty proof", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package txpool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+type prefixValidator struct{}
+
+func (prefixValidator) Name() string {
+	return "test_prefix"
+}
+
+func (prefixValidator) ValidateTx(tx []byte) error {
+	if bytes.HasPrefix(tx, []byte("bad")) {
+		return fmt.Errorf("bad prefix")
+	}
+	return nil
+}
+
+func TestStatelessValidators(t *testing.T) {
+	require := require.New(t)
+
+	rtID := common.NewTestNamespaceFromSeed([]byte("txpool stateless validators"), 0)
+	v := NewStatelessValidators(rtID)
+	require.Equal([]string{StatelessValidatorSize}, v.Enabled())
+
+	requireRejected := func(tx []byte, validator string) {
+		err := v.ValidateTx(tx)
+		require.ErrorIs(err, ErrMalformedTransaction)
+		var verr *StatelessValidationError
+		require.True(errors.As(err, &verr))
+		require.Equal(validator, verr.Validator)
+	}
+	rejected := func(validator string) float64 {
+		return testutil.ToFloat64(statelessRejectedTransactions.With(prometheus.Labels{
+			"runtime":   rtID.String(),
+			"validator": validator,
+		}))
+	}
+
+	// Only the size validator is enabled by default.
+	require.NoError(v.ValidateTx([]byte("opaque")), "opaque transactions should be accepted")
+	requireRejected(nil, StatelessValidatorSize)
+	v.SetMaxTxSize(4)
+	requireRejected([]byte("opaque"), StatelessValidatorSize)
+	v.SetMaxTxSize(0)
+	require.EqualValues(2, rejected(StatelessValidatorSize))
+
+	// Enable the envelope validators.
+	err := v.Enable([]string{"unknown"})
+	require.Error(err, "enabling unknown validators should fail")
+	err = v.Enable([]string{StatelessValidatorSignature, StatelessValidatorEnvelope, StatelessValidatorEnvelope})
+	require.NoError(err, "Enable")
+	require.Equal([]string{StatelessValidatorSize, StatelessValidatorEnvelope, StatelessValidatorSignature}, v.Enabled())
+
+	newTx := func(version uint16, proofs ...envelopeAuthProof) []byte {
+		return cbor.Marshal(&envelope{
+			Body:       cbor.Marshal(&envelopeBody{Version: version}),
+			AuthProofs: proofs,
+		})
+	}
+	sig := make([]byte, 64)
+
+	require.NoError(v.ValidateTx(newTx(EnvelopeVersion, envelopeAuthProof{Signature: sig})))
+	require.NoError(v.ValidateTx(newTx(EnvelopeVersion, envelopeAuthProof{Multisig: [][]byte{nil, sig}})))
+	require.NoError(v.ValidateTx(newTx(EnvelopeVersion, envelopeAuthProof{Module: "test"})))
+
+	for _, tc := range []struct {
+		name      string
+		tx        []byte
+		validator string
+	}{
+		{"NotAnEnvelope", []byte("opaque"), StatelessValidatorEnvelope},
+		{"UnsupportedVersion", newTx(EnvelopeVersion+1, envelopeAuthProof{Signature: sig}), StatelessValidatorEnvelope},
+		{"EmptyProof", newTx(EnvelopeVersion, envelopeAuthProof{}), StatelessValidatorSignature},
+		{"OversizedSignature", newTx(EnvelopeVersion, envelopeAuthProof{Signature: make([]byte, maxSignatureSize+1)}), StatelessValidatorSignature},
+		{"EmptyMultisig", newTx(EnvelopeVersion, envelopeAuthProof{Multisig: [][]byte{nil, nil}}), StatelessValidatorSignature},
+	} {
+		t.Run(tc.name, func(_ *testing.T) {
+			requireRejected(tc.tx, tc.validator)
+		})
+	}
+	require.EqualValues(2, rejected(StatelessValidatorEnvelope))
+	require.EqualValues(3, rejected(StatelessValidatorSignature))
+
+	// Register an additional validator.
+	err = RegisterStatelessValidator(StatelessValidatorSize, func() StatelessValidator { return prefixValidator{} })
+	require.Error(err, "registering the size validator should fail")
+	err = RegisterStatelessValidator("test_prefix", func() StatelessValidator { return prefixValidator{} })
+	require.NoError(err, "RegisterStatelessValidator")
+	err = RegisterStatelessValidator("test_prefix", func() StatelessValidator { return prefixValidator{} })
+	require.Error(err, "registering a validator twice should fail")
+
+	err = v.Enable([]string{"test_prefix"})
+	require.NoError(err, "Enable")
+	require.NoError(v.ValidateTx([]byte("opaque")))
+	requireRejected([]byte("bad transaction"), "test_prefix")
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/errors"
 	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
 	"github.com/oasisprotocol/oasis-core/go/common/quantity"
 )
@@ -17,6 +18,18 @@ import (
 // ModuleName is a unique module name for the scheduler module.
 const ModuleName = "scheduler"
 
+// ErrNotModified is the error returned by the gRPC service when the requested committees match
+// the response identified by GetCommitteesRequest.IfNoneMatch.
+var ErrNotModified = errors.New(ModuleName, 1, "scheduler: not modified")
+
+// ErrUnsupportedProposal is the error returned when analyzing the impact of a proposal that does
+// not change any parameters affecting committee elections.
+var ErrUnsupportedProposal = errors.New(ModuleName, 2, "scheduler: unsupported proposal")
+
+// ErrAnalysisTooExpensive is the error returned when the impact analysis of a proposal would
+// exceed the cost bound.
+var ErrAnalysisTooExpensive = errors.New(ModuleName, 3, "scheduler: analysis too expensive")
+
 // Role is the role a given node plays in a committee.
 type Role uint8
 
@@ -368,12 +381,27 @@ type Backend interface {
 
 	// ConsensusParameters returns the scheduler consensus parameters.
 	ConsensusParameters(ctx context.Context, height int64) (*ConsensusParameters, error)
+
+	// GetElectionEligibility evaluates whether the given node currently satisfies the
+	// requirements to be elected into each committee of every runtime it registered for.
+	GetElectionEligibility(ctx context.Context, request *GetElectionEligibilityRequest) (*ElectionEligibility, error)
+
+	// AnalyzeParameterChange evaluates the impact the given proposal would have on committee
+	// elections if it were executed at the given height, without modifying any state.
+	AnalyzeParameterChange(ctx context.Context, request *AnalyzeParameterChangeRequest) (*ParameterChangeImpact, error)
 }
 
 // GetCommitteesRequest is a GetCommittees request.
 type GetCommitteesRequest struct {
 	Height    int64            `json:"height"`
 	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// IfNoneMatch is the optional hash of a previously received response for the same height and
+	// runtime. In case the response has not changed, the gRPC service replies with ErrNotModified
+	// instead of sending the committees again.
+	//
+	// This field is ignored by backends and only handled by the gRPC layer.
+	IfNoneMatch *hash.Hash `json:"if_none_match,omitempty"`
 }
 
 // Genesis is the committee scheduler genesis state.
@@ -425,6 +453,9 @@ type ConsensusParameterChanges struct {
 	// MaxValidators is the new maximum number of validators.
 	MaxValidators *int `json:"max_validators"`
 
+	// MaxValidatorsPerEntity is the new maximum number of validators per entity.
+	MaxValidatorsPerEntity *int `json:"max_validators_per_entity,omitempty"`
+
 	// VotingPowerDistribution is the new voting power distribution.
 	VotingPowerDistribution *VotingPowerDistribution `json:"voting_power_distribution,omitempty"`
 }
@@ -437,6 +468,9 @@ func (c *ConsensusParameterChanges) Apply(params *ConsensusParameters) error {
 	if c.MaxValidators != nil {
 		params.MaxValidators = *c.MaxValidators
 	}
+	if c.MaxValidatorsPerEntity != nil {
+		params.MaxValidatorsPerEntity = *c.MaxValidatorsPerEntity
+	}
 	if c.VotingPowerDistribution != nil {
 		params.VotingPowerDistribution = *c.VotingPowerDistribution
 	}
@@ -5,7 +5,9 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	governance "github.com/oasisprotocol/oasis-core/go/governance/api"
 )
 
 func TestSanityCheck(t *testing.T) {
@@ -35,3 +37,69 @@ func TestVotingPowerDistribution(t *testing.T) {
 	require.True(t, powerS > 0, "sqrt should be greater than 0")
 	require.True(t, powerL > powerS, "linear should be greater than sqrt")
 }
+
+func TestParameterChange(t *testing.T) {
+	require := require.New(t)
+
+	params := &ConsensusParameters{
+		MinValidators:          1,
+		MaxValidators:          100,
+		MaxValidatorsPerEntity: 1,
+	}
+
+	maxValidators := 120
+	proposal := &governance.Proposal{
+		ID:       1,
+		State:    governance.StatePassed,
+		ClosesAt: 10,
+		Content: governance.ProposalContent{
+			ChangeParameters: governance.NewChangeParametersProposal(ModuleName, &ConsensusParameterChanges{
+				MaxValidators: &maxValidators,
+			}),
+		},
+	}
+
+	// The change should not take effect before the activation epoch.
+	_, _, err := governance.ExecuteParameterChange[ConsensusParameters, ConsensusParameterChanges](ModuleName, proposal, 9, params)
+	require.ErrorIs(err, governance.ErrParameterChangeNotActive, "change should not take effect before activation epoch")
+
+	// The change should take effect at the activation epoch.
+	newParams, ev, err := governance.ExecuteParameterChange[ConsensusParameters, ConsensusParameterChanges](ModuleName, proposal, 10, params)
+	require.NoError(err, "ExecuteParameterChange")
+	require.Equal(120, newParams.MaxValidators, "max validators should be changed")
+	require.Equal(100, params.MaxValidators, "current parameters should not be modified")
+	require.EqualValues(1, ev.ID)
+	require.Equal(ModuleName, ev.Module)
+	require.EqualValues(10, ev.Epoch)
+	require.Len(ev.Changes, 1, "only the max validators parameter should change")
+	require.Equal("max_validators", ev.Changes[0].Parameter)
+	require.Equal(cbor.Marshal(100), []byte(ev.Changes[0].OldValue))
+	require.Equal(cbor.Marshal(120), []byte(ev.Changes[0].NewValue))
+
+	// Out-of-bounds values should be rejected.
+	minValidators := 101
+	_, _, err = governance.PreviewParameterChange[ConsensusParameters, ConsensusParameterChanges](
+		ModuleName,
+		governance.NewChangeParametersProposal(ModuleName, &ConsensusParameterChanges{
+			MinValidators: &minValidators,
+		}),
+		params,
+	)
+	require.ErrorIs(err, governance.ErrInvalidArgument, "min validators above max validators should be rejected")
+
+	// Empty changes and changes for a different module should be rejected.
+	_, _, err = governance.PreviewParameterChange[ConsensusParameters, ConsensusParameterChanges](
+		ModuleName,
+		governance.NewChangeParametersProposal(ModuleName, &ConsensusParameterChanges{}),
+		params,
+	)
+	require.ErrorIs(err, governance.ErrInvalidArgument, "empty changes should be rejected")
+	_, _, err = governance.PreviewParameterChange[ConsensusParameters, ConsensusParameterChanges](
+		ModuleName,
+		governance.NewChangeParametersProposal("staking", &ConsensusParameterChanges{
+			MaxValidators: &maxValidators,
+		}),
+		params,
+	)
+	require.ErrorIs(err, governance.ErrInvalidArgument, "changes for another module should be rejected")
+}
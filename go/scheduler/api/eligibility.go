@@ -0,0 +1,99 @@
+package api
+
+import (
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+)
+
+// IneligibilityReason is a machine-readable reason why a node is not eligible to be elected
+// into a committee.
+type IneligibilityReason string
+
+const (
+	// IneligibleMissingRole means that the node is not registered with the role required by
+	// the committee.
+	IneligibleMissingRole IneligibilityReason = "missing_role"
+	// IneligibleFrozen means that the node is frozen.
+	IneligibleFrozen IneligibilityReason = "frozen"
+	// IneligibleNotYetEligible means that the node has registered too recently to be considered
+	// in elections.
+	IneligibleNotYetEligible IneligibilityReason = "not_yet_eligible"
+	// IneligibleRuntimeSuspended means that the runtime is suspended.
+	IneligibleRuntimeSuspended IneligibilityReason = "runtime_suspended"
+	// IneligibleFaulty means that the node is suspended for the runtime because it has
+	// accumulated too many liveness faults.
+	IneligibleFaulty IneligibilityReason = "faulty"
+	// IneligibleNoActiveDeployment means that the runtime has no active deployment.
+	IneligibleNoActiveDeployment IneligibilityReason = "no_active_deployment"
+	// IneligibleVersionMismatch means that the node is not registered for the active runtime
+	// deployment version.
+	IneligibleVersionMismatch IneligibilityReason = "version_mismatch"
+	// IneligibleStaleAttestation means that the node's TEE attestation for the runtime is
+	// missing, stale or otherwise fails verification.
+	IneligibleStaleAttestation IneligibilityReason = "stale_attestation"
+	// IneligibleInsufficientStake means that the node's entity does not have enough stake in
+	// escrow to satisfy its stake claims.
+	IneligibleInsufficientStake IneligibilityReason = "insufficient_stake"
+	// IneligibleNotValidator means that the committee requires the node's entity to have a
+	// node in the validator set, but it has none.
+	IneligibleNotValidator IneligibilityReason = "not_validator"
+)
+
+// CommitteeEligibility is the eligibility of a node for a given committee kind and role.
+type CommitteeEligibility struct {
+	// Kind is the committee kind.
+	Kind CommitteeKind `json:"kind"`
+
+	// Role is the role in the committee.
+	Role Role `json:"role"`
+
+	// Eligible is true iff the node is eligible to be elected into the committee with the
+	// given role.
+	Eligible bool `json:"eligible"`
+
+	// Reasons are the reasons why the node is not eligible.
+	Reasons []IneligibilityReason `json:"reasons,omitempty"`
+}
+
+// RuntimeEligibility is the eligibility of a node for all committees of a given runtime.
+type RuntimeEligibility struct {
+	// RuntimeID is the runtime identifier.
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// Committees is the eligibility for each committee kind and role.
+	Committees []CommitteeEligibility `json:"committees"`
+}
+
+// ElectionEligibility is the election eligibility matrix of a node.
+type ElectionEligibility struct {
+	// NodeID is the node identifier.
+	NodeID signature.PublicKey `json:"node_id"`
+
+	// Height is the consensus height at which the eligibility has been evaluated.
+	Height int64 `json:"height"`
+
+	// Epoch is the epoch for which the eligibility has been evaluated.
+	Epoch beacon.EpochTime `json:"epoch"`
+
+	// Runtimes is the eligibility for each runtime the node registered for.
+	Runtimes []RuntimeEligibility `json:"runtimes,omitempty"`
+}
+
+// IsEligible returns true iff the node is eligible for at least one committee.
+func (e *ElectionEligibility) IsEligible() bool {
+	for _, rt := range e.Runtimes {
+		for _, c := range rt.Committees {
+			if c.Eligible {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetElectionEligibilityRequest is a GetElectionEligibility request.
+type GetElectionEligibilityRequest struct {
+	Height int64               `json:"height"`
+	NodeID signature.PublicKey `json:"node_id"`
+}
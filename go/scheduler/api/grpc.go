@@ -21,6 +21,10 @@ var (
 	methodStateToGenesis = serviceName.NewMethod("StateToGenesis", int64(0))
 	// methodConsensusParameters is the ConsensusParameters method.
 	methodConsensusParameters = serviceName.NewMethod("ConsensusParameters", int64(0))
+	// methodGetElectionEligibility is the GetElectionEligibility method.
+	methodGetElectionEligibility = serviceName.NewMethod("GetElectionEligibility", GetElectionEligibilityRequest{})
+	// methodAnalyzeParameterChange is the AnalyzeParameterChange method.
+	methodAnalyzeParameterChange = serviceName.NewMethod("AnalyzeParameterChange", AnalyzeParameterChangeRequest{})
 
 	// methodWatchCommittees is the WatchCommittees method.
 	methodWatchCommittees = serviceName.NewMethod("WatchCommittees", nil)
@@ -46,6 +50,14 @@ var (
 				MethodName: methodConsensusParameters.ShortName(),
 				Handler:    handlerConsensusParameters,
 			},
+			{
+				MethodName: methodGetElectionEligibility.ShortName(),
+				Handler:    handlerGetElectionEligibility,
+			},
+			{
+				MethodName: methodAnalyzeParameterChange.ShortName(),
+				Handler:    handlerAnalyzeParameterChange,
+			},
 		},
 		Streams: []grpc.StreamDesc{
 			{
@@ -149,6 +161,52 @@ func handlerConsensusParameters(
 	return interceptor(ctx, height, info, handler)
 }
 
+func handlerGetElectionEligibility(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var req GetElectionEligibilityRequest
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetElectionEligibility(ctx, &req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetElectionEligibility.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(Backend).GetElectionEligibility(ctx, req.(*GetElectionEligibilityRequest))
+	}
+	return interceptor(ctx, &req, info, handler)
+}
+
+func handlerAnalyzeParameterChange(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var req AnalyzeParameterChangeRequest
+	if err := dec(&req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).AnalyzeParameterChange(ctx, &req)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodAnalyzeParameterChange.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(Backend).AnalyzeParameterChange(ctx, req.(*AnalyzeParameterChangeRequest))
+	}
+	return interceptor(ctx, &req, info, handler)
+}
+
 func handlerWatchCommittees(srv any, stream grpc.ServerStream) error {
 	if err := stream.RecvMsg(nil); err != nil {
 		return err
@@ -185,12 +243,15 @@ func RegisterService(server *grpc.Server, service Backend) {
 // Client is a gRPC scheduler client.
 type Client struct {
 	conn *grpc.ClientConn
+
+	committees *committeesClientCache
 }
 
 // NewClient creates a new gRPC scheduler client.
 func NewClient(c *grpc.ClientConn) *Client {
 	return &Client{
-		conn: c,
+		conn:       c,
+		committees: newCommitteesClientCache(),
 	}
 }
 
@@ -203,11 +264,13 @@ func (c *Client) GetValidators(ctx context.Context, height int64) ([]*Validator,
 }
 
 func (c *Client) GetCommittees(ctx context.Context, request *GetCommitteesRequest) ([]*Committee, error) {
-	var rsp []*Committee
-	if err := c.conn.Invoke(ctx, methodGetCommittees.FullName(), request, &rsp); err != nil {
-		return nil, err
-	}
-	return rsp, nil
+	return c.committees.getCommittees(ctx, request, func(ctx context.Context, request *GetCommitteesRequest) ([]*Committee, error) {
+		var rsp []*Committee
+		if err := c.conn.Invoke(ctx, methodGetCommittees.FullName(), request, &rsp); err != nil {
+			return nil, err
+		}
+		return rsp, nil
+	})
 }
 
 func (c *Client) StateToGenesis(ctx context.Context, height int64) (*Genesis, error) {
@@ -226,6 +289,22 @@ func (c *Client) ConsensusParameters(ctx context.Context, height int64) (*Consen
 	return &rsp, nil
 }
 
+func (c *Client) GetElectionEligibility(ctx context.Context, request *GetElectionEligibilityRequest) (*ElectionEligibility, error) {
+	var rsp ElectionEligibility
+	if err := c.conn.Invoke(ctx, methodGetElectionEligibility.FullName(), request, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *Client) AnalyzeParameterChange(ctx context.Context, request *AnalyzeParameterChangeRequest) (*ParameterChangeImpact, error) {
+	var rsp ParameterChangeImpact
+	if err := c.conn.Invoke(ctx, methodAnalyzeParameterChange.FullName(), request, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
 func (c *Client) WatchCommittees(ctx context.Context) (<-chan *Committee, pubsub.ClosableSubscription, error) {
 	ctx, sub := pubsub.NewContextSubscription(ctx)
 
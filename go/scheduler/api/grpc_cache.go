@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cache/lru"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+)
+
+const (
+	// DefaultCommitteesCacheSize is the default number of GetCommittees responses cached by the
+	// gRPC service.
+	DefaultCommitteesCacheSize = 1024
+
+	// clientCommitteesCacheSize is the number of GetCommittees responses kept by the gRPC client
+	// to handle not modified replies.
+	clientCommitteesCacheSize = 128
+)
+
+var (
+	committeesCacheLookups = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_scheduler_committees_cache_lookups",
+			Help: "Number of GetCommittees response cache lookups by result.",
+		},
+		[]string{"result"},
+	)
+	committeesCacheNotModified = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "oasis_scheduler_committees_cache_not_modified",
+			Help: "Number of GetCommittees requests answered with a not modified reply.",
+		},
+	)
+	committeesCacheEntries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "oasis_scheduler_committees_cache_entries",
+			Help: "Number of cached GetCommittees responses.",
+		},
+	)
+
+	committeesCacheCollectors = []prometheus.Collector{
+		committeesCacheLookups,
+		committeesCacheNotModified,
+		committeesCacheEntries,
+	}
+
+	labelCacheHit    = prometheus.Labels{"result": "hit"}
+	labelCacheMiss   = prometheus.Labels{"result": "miss"}
+	labelCacheBypass = prometheus.Labels{"result": "bypass"}
+
+	committeesCacheMetricsOnce sync.Once
+)
+
+// LatestHeightProvider provides the latest finalized consensus height.
+type LatestHeightProvider interface {
+	// GetLatestHeight returns the consensus height of the latest finalized block.
+	GetLatestHeight(ctx context.Context) (int64, error)
+}
+
+// RegisterServiceWithCache registers a new scheduler service with the given gRPC server, caching
+// GetCommittees responses for finalized heights.
+//
+// Responses for heights that are not yet finalized (including the latest height) are never
+// cached. Requests carrying the hash of the current response are answered with ErrNotModified.
+func RegisterServiceWithCache(server *grpc.Server, service Backend, heights LatestHeightProvider, size uint64) {
+	server.RegisterService(&serviceDesc, newCachingBackend(service, heights, size))
+}
+
+type committeesCacheKey struct {
+	height    int64
+	runtimeID common.Namespace
+}
+
+type committeesCacheEntry struct {
+	committees []*Committee
+	hash       hash.Hash
+}
+
+func newCommitteesCacheEntry(committees []*Committee) *committeesCacheEntry {
+	return &committeesCacheEntry{
+		committees: committees,
+		hash:       hash.NewFrom(committees),
+	}
+}
+
+// cachingBackend is a scheduler backend wrapper that caches GetCommittees responses for finalized
+// heights and handles not modified replies.
+type cachingBackend struct {
+	Backend
+
+	heights LatestHeightProvider
+	cache   *lru.Cache
+}
+
+func newCachingBackend(backend Backend, heights LatestHeightProvider, size uint64) *cachingBackend {
+	committeesCacheMetricsOnce.Do(func() {
+		prometheus.MustRegister(committeesCacheCollectors...)
+	})
+
+	if size == 0 {
+		size = DefaultCommitteesCacheSize
+	}
+
+	return &cachingBackend{
+		Backend: backend,
+		heights: heights,
+		cache:   lru.New(lru.Capacity(size, false)),
+	}
+}
+
+func (b *cachingBackend) isFinalized(ctx context.Context, height int64) (bool, error) {
+	// Height zero refers to the latest height which changes with every block.
+	if height <= 0 {
+		return false, nil
+	}
+	latestHeight, err := b.heights.GetLatestHeight(ctx)
+	if err != nil {
+		return false, err
+	}
+	return height <= latestHeight, nil
+}
+
+func (b *cachingBackend) GetCommittees(ctx context.Context, request *GetCommitteesRequest) ([]*Committee, error) {
+	key := committeesCacheKey{
+		height:    request.Height,
+		runtimeID: request.RuntimeID,
+	}
+
+	var entry *committeesCacheEntry
+	if cached, ok := b.cache.Get(key); ok {
+		committeesCacheLookups.With(labelCacheHit).Inc()
+		entry = cached.(*committeesCacheEntry)
+	} else {
+		finalized, err := b.isFinalized(ctx, request.Height)
+		if err != nil {
+			return nil, err
+		}
+
+		committees, err := b.Backend.GetCommittees(ctx, &GetCommitteesRequest{
+			Height:    request.Height,
+			RuntimeID: request.RuntimeID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		entry = newCommitteesCacheEntry(committees)
+
+		if finalized {
+			committeesCacheLookups.With(labelCacheMiss).Inc()
+			_ = b.cache.Put(key, entry)
+			committeesCacheEntries.Set(float64(b.cache.Len()))
+		} else {
+			committeesCacheLookups.With(labelCacheBypass).Inc()
+		}
+	}
+
+	if request.IfNoneMatch != nil && request.IfNoneMatch.Equal(&entry.hash) {
+		committeesCacheNotModified.Inc()
+		return nil, ErrNotModified
+	}
+	return entry.committees, nil
+}
+
+// committeesClientCache keeps GetCommittees responses received by the gRPC client so that not
+// modified replies can be served from the local copy.
+type committeesClientCache struct {
+	cache *lru.Cache
+}
+
+func newCommitteesClientCache() *committeesClientCache {
+	return &committeesClientCache{
+		cache: lru.New(lru.Capacity(clientCommitteesCacheSize, false)),
+	}
+}
+
+func (cc *committeesClientCache) getCommittees(
+	ctx context.Context,
+	request *GetCommitteesRequest,
+	invoke func(context.Context, *GetCommitteesRequest) ([]*Committee, error),
+) ([]*Committee, error) {
+	// Responses for the latest height are not stable, so there is no point in keeping them.
+	if request.Height <= 0 {
+		return invoke(ctx, request)
+	}
+
+	key := committeesCacheKey{
+		height:    request.Height,
+		runtimeID: request.RuntimeID,
+	}
+
+	rq := *request
+	var local *committeesCacheEntry
+	if cached, ok := cc.cache.Get(key); ok {
+		local = cached.(*committeesCacheEntry)
+		rq.IfNoneMatch = &local.hash
+	}
+
+	committees, err := invoke(ctx, &rq)
+	switch {
+	case err == nil:
+	case local != nil && errors.Is(err, ErrNotModified):
+		return local.committees, nil
+	default:
+		return nil, err
+	}
+
+	_ = cc.cache.Put(key, newCommitteesCacheEntry(committees))
+	return committees, nil
+}
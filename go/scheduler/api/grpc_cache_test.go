@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+)
+
+type testCommitteesBackend struct {
+	Backend
+
+	calls int
+}
+
+func (b *testCommitteesBackend) GetCommittees(_ context.Context, request *GetCommitteesRequest) ([]*Committee, error) {
+	b.calls++
+	return []*Committee{
+		{
+			Kind:      KindComputeExecutor,
+			Members:   []*CommitteeNode{{Role: RoleWorker}},
+			RuntimeID: request.RuntimeID,
+			ValidFor:  1,
+		},
+	}, nil
+}
+
+type testLatestHeight int64
+
+func (h testLatestHeight) GetLatestHeight(context.Context) (int64, error) {
+	return int64(h), nil
+}
+
+func TestCommitteesCache(t *testing.T) {
+	require := require.New(t)
+
+	ctx := context.Background()
+	var runtimeID common.Namespace
+	require.NoError(runtimeID.UnmarshalHex("8000000000000000000000000000000000000000000000000000000000000000"))
+
+	backend := &testCommitteesBackend{}
+	server := newCachingBackend(backend, testLatestHeight(10), 2)
+	client := newCommitteesClientCache()
+	var notModified int
+	invoke := func(ctx context.Context, request *GetCommitteesRequest) ([]*Committee, error) {
+		rsp, err := server.GetCommittees(ctx, request)
+		if err == ErrNotModified {
+			notModified++
+		}
+		return rsp, err
+	}
+
+	// Finalized heights should be cached.
+	committees, err := server.GetCommittees(ctx, &GetCommitteesRequest{Height: 5, RuntimeID: runtimeID})
+	require.NoError(err, "GetCommittees")
+	require.Len(committees, 1)
+	require.Equal(1, backend.calls)
+
+	committees, err = server.GetCommittees(ctx, &GetCommitteesRequest{Height: 5, RuntimeID: runtimeID})
+	require.NoError(err, "GetCommittees")
+	require.Len(committees, 1)
+	require.Equal(1, backend.calls, "cached response should be used")
+
+	// Latest and non-finalized heights should bypass the cache.
+	for _, height := range []int64{0, 11} {
+		_, err = server.GetCommittees(ctx, &GetCommitteesRequest{Height: height, RuntimeID: runtimeID})
+		require.NoError(err, "GetCommittees")
+		_, err = server.GetCommittees(ctx, &GetCommitteesRequest{Height: height, RuntimeID: runtimeID})
+		require.NoError(err, "GetCommittees")
+	}
+	require.Equal(5, backend.calls, "non-finalized heights should not be cached")
+
+	// The client should transparently handle not modified replies.
+	committees, err = client.getCommittees(ctx, &GetCommitteesRequest{Height: 5, RuntimeID: runtimeID}, invoke)
+	require.NoError(err, "GetCommittees")
+	require.Len(committees, 1)
+	require.Equal(0, notModified)
+
+	request := &GetCommitteesRequest{Height: 5, RuntimeID: runtimeID}
+	cachedCommittees, err := client.getCommittees(ctx, request, invoke)
+	require.NoError(err, "GetCommittees")
+	require.Equal(committees, cachedCommittees)
+	require.Equal(1, notModified, "second request should get a not modified reply")
+	require.Nil(request.IfNoneMatch, "request should not be modified")
+	require.Equal(1, backend.calls)
+
+	// Mismatched hashes should result in a full response.
+	h := hash.NewFromBytes([]byte("stale response"))
+	committees, err = server.GetCommittees(ctx, &GetCommitteesRequest{Height: 5, RuntimeID: runtimeID, IfNoneMatch: &h})
+	require.NoError(err, "GetCommittees")
+	require.Len(committees, 1)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"slices"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	governance "github.com/oasisprotocol/oasis-core/go/governance/api"
+)
+
+// MaxParameterChangeImpactEvaluations is the maximum number of node-runtime pairs that are
+// evaluated by a single parameter change impact analysis.
+const MaxParameterChangeImpactEvaluations = 100_000
+
+// AnalyzeParameterChangeRequest is an AnalyzeParameterChange request.
+type AnalyzeParameterChangeRequest struct {
+	// Height is the consensus height at which the analysis is performed.
+	Height int64 `json:"height"`
+
+	// Content is the content of the proposal that should be analyzed.
+	Content governance.ProposalContent `json:"content"`
+}
+
+// EligibilityLoss is a node that would lose eligibility for a committee.
+type EligibilityLoss struct {
+	// EntityID is the identifier of the node's entity.
+	EntityID signature.PublicKey `json:"entity_id"`
+
+	// NodeID is the node identifier.
+	NodeID signature.PublicKey `json:"node_id"`
+
+	// Elected is true iff the node is currently elected into the committee with the given role.
+	Elected bool `json:"elected,omitempty"`
+
+	// Reasons are the reasons why the node would not be eligible after the change.
+	Reasons []IneligibilityReason `json:"reasons"`
+}
+
+// CommitteeParameterChangeImpact is the impact of a parameter change on a committee kind and
+// role of a runtime.
+type CommitteeParameterChangeImpact struct {
+	// Kind is the committee kind.
+	Kind CommitteeKind `json:"kind"`
+
+	// Role is the role in the committee.
+	Role Role `json:"role"`
+
+	// Size is the number of nodes elected with the given role.
+	Size uint16 `json:"size"`
+
+	// CandidatesBefore is the number of eligible nodes before the change.
+	CandidatesBefore uint64 `json:"candidates_before"`
+
+	// CandidatesAfter is the number of eligible nodes after the change.
+	CandidatesAfter uint64 `json:"candidates_after"`
+
+	// Losses are the nodes that would lose eligibility, ordered by entity and node identifier.
+	Losses []EligibilityLoss `json:"losses,omitempty"`
+
+	// RetainedMembers is the number of currently elected nodes that would remain eligible.
+	RetainedMembers uint64 `json:"retained_members"`
+
+	// ExpectedOverlap is the expected number of currently elected nodes that would be elected
+	// again in the next election, assuming uniform selection among the remaining candidates.
+	ExpectedOverlap uint64 `json:"expected_overlap"`
+}
+
+// IsAffected returns true iff the change affects the committee.
+func (c *CommitteeParameterChangeImpact) IsAffected() bool {
+	return c.CandidatesBefore != c.CandidatesAfter || len(c.Losses) > 0
+}
+
+// RuntimeParameterChangeImpact is the impact of a parameter change on a runtime.
+type RuntimeParameterChangeImpact struct {
+	// RuntimeID is the runtime identifier.
+	RuntimeID common.Namespace `json:"runtime_id"`
+
+	// Committees is the impact on each committee kind and role.
+	Committees []CommitteeParameterChangeImpact `json:"committees"`
+}
+
+// ParameterChangeImpact is the impact of a parameter change on committee elections.
+type ParameterChangeImpact struct {
+	// Height is the consensus height at which the impact has been analyzed.
+	Height int64 `json:"height"`
+
+	// Epoch is the epoch for which the impact has been analyzed.
+	Epoch beacon.EpochTime `json:"epoch"`
+
+	// Runtimes is the impact on each affected runtime, ordered by runtime identifier.
+	Runtimes []RuntimeParameterChangeImpact `json:"runtimes,omitempty"`
+}
+
+// LostEntities returns the sorted set of entities with at least one node losing eligibility for
+// any committee of any runtime.
+func (p *ParameterChangeImpact) LostEntities() []signature.PublicKey {
+	seen := make(map[signature.PublicKey]struct{})
+	var entities []signature.PublicKey
+	for _, rt := range p.Runtimes {
+		for _, c := range rt.Committees {
+			for _, l := range c.Losses {
+				if _, ok := seen[l.EntityID]; ok {
+					continue
+				}
+				seen[l.EntityID] = struct{}{}
+				entities = append(entities, l.EntityID)
+			}
+		}
+	}
+	slices.SortFunc(entities, func(a, b signature.PublicKey) int {
+		return bytes.Compare(a[:], b[:])
+	})
+	return entities
+}
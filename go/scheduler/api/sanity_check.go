@@ -39,10 +39,29 @@ func (p *ConsensusParameters) SanityCheck() error {
 	return nil
 }
 
+// CheckInvariants checks that the consensus parameters are sane and satisfy the invariants that
+// consensus parameter changes must preserve.
+func (p *ConsensusParameters) CheckInvariants() error {
+	if err := p.SanityCheck(); err != nil {
+		return err
+	}
+	if p.MinValidators < 1 {
+		return fmt.Errorf("min_validators must be at least 1")
+	}
+	if p.MaxValidators < p.MinValidators {
+		return fmt.Errorf("max_validators must be at least min_validators")
+	}
+	if p.MaxValidatorsPerEntity < 1 {
+		return fmt.Errorf("max_validators_per_entity must be at least 1")
+	}
+	return nil
+}
+
 // SanityCheck performs a sanity check on the consensus parameter changes.
 func (c *ConsensusParameterChanges) SanityCheck() error {
 	if c.MinValidators == nil &&
 		c.MaxValidators == nil &&
+		c.MaxValidatorsPerEntity == nil &&
 		c.VotingPowerDistribution == nil {
 		return fmt.Errorf("consensus parameter changes should not be empty")
 	}
@@ -3,11 +3,14 @@ package api
 
 import (
 	"context"
+	"time"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/accessctl"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	"github.com/oasisprotocol/oasis-core/go/common/grpc"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
 )
 
 // SentryAddresses contains sentry node consensus and TLS addresses.
@@ -15,6 +18,21 @@ type SentryAddresses struct {
 	Consensus []node.ConsensusAddress `json:"consensus"`
 }
 
+// Status is the sentry node status.
+type Status struct {
+	// Addresses are the cached sentry addresses (if any).
+	Addresses *SentryAddresses `json:"addresses,omitempty"`
+
+	// LastRefresh is the time when the cached addresses were last refreshed.
+	LastRefresh time.Time `json:"last_refresh"`
+
+	// CacheAge is the age of the cached addresses.
+	CacheAge time.Duration `json:"cache_age"`
+
+	// UpstreamTLSPubKeys are the TLS public keys last pushed by the upstream node (if any).
+	UpstreamTLSPubKeys []signature.PublicKey `json:"upstream_tls_pub_keys,omitempty"`
+}
+
 // ServicePolicies contains policies for a GRPC service.
 type ServicePolicies struct {
 	Service        grpc.ServiceName                      `json:"service"`
@@ -25,4 +43,16 @@ type ServicePolicies struct {
 type Backend interface {
 	// Get addresses returns the list of consensus and TLS addresses of the sentry node.
 	GetAddresses(context.Context) (*SentryAddresses, error)
+
+	// WatchAddresses returns a channel that produces the addresses of the sentry node each time
+	// they change. The current addresses (if known) are sent immediately after subscribing.
+	WatchAddresses(context.Context) (<-chan *SentryAddresses, pubsub.ClosableSubscription, error)
+
+	// GetStatus returns the status of the sentry node.
+	GetStatus(context.Context) (*Status, error)
+
+	// SetUpstreamTLSPubKeys notifies the sentry node of the upstream node's currently valid TLS
+	// public keys, e.g., after the upstream node rotated its TLS key. The given keys replace any
+	// previously pushed keys.
+	SetUpstreamTLSPubKeys(context.Context, []signature.PublicKey) error
 }
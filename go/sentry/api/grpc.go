@@ -5,7 +5,9 @@ import (
 
 	"google.golang.org/grpc"
 
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
 )
 
 var (
@@ -14,6 +16,13 @@ var (
 
 	// methodGetAddresses is the GetAddresses method.
 	methodGetAddresses = serviceName.NewMethod("GetAddresses", nil)
+	// methodGetStatus is the GetStatus method.
+	methodGetStatus = serviceName.NewMethod("GetStatus", nil)
+	// methodSetUpstreamTLSPubKeys is the SetUpstreamTLSPubKeys method.
+	methodSetUpstreamTLSPubKeys = serviceName.NewMethod("SetUpstreamTLSPubKeys", []signature.PublicKey{})
+
+	// methodWatchAddresses is the WatchAddresses method.
+	methodWatchAddresses = serviceName.NewMethod("WatchAddresses", nil)
 
 	// serviceDesc is the gRPC service descriptor.
 	serviceDesc = grpc.ServiceDesc{
@@ -24,8 +33,22 @@ var (
 				MethodName: methodGetAddresses.ShortName(),
 				Handler:    handlerGetAddresses,
 			},
+			{
+				MethodName: methodGetStatus.ShortName(),
+				Handler:    handlerGetStatus,
+			},
+			{
+				MethodName: methodSetUpstreamTLSPubKeys.ShortName(),
+				Handler:    handlerSetUpstreamTLSPubKeys,
+			},
+		},
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    methodWatchAddresses.ShortName(),
+				Handler:       handlerWatchAddresses,
+				ServerStreams: true,
+			},
 		},
-		Streams: []grpc.StreamDesc{},
 	}
 )
 
@@ -48,6 +71,75 @@ func handlerGetAddresses(
 	return interceptor(ctx, nil, info, handler)
 }
 
+func handlerGetStatus(
+	srv any,
+	ctx context.Context,
+	_ func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	if interceptor == nil {
+		return srv.(Backend).GetStatus(ctx)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetStatus.FullName(),
+	}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return srv.(Backend).GetStatus(ctx)
+	}
+	return interceptor(ctx, nil, info, handler)
+}
+
+func handlerSetUpstreamTLSPubKeys(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var pubKeys []signature.PublicKey
+	if err := dec(&pubKeys); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return nil, srv.(Backend).SetUpstreamTLSPubKeys(ctx, pubKeys)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodSetUpstreamTLSPubKeys.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, srv.(Backend).SetUpstreamTLSPubKeys(ctx, req.([]signature.PublicKey))
+	}
+	return interceptor(ctx, pubKeys, info, handler)
+}
+
+func handlerWatchAddresses(srv any, stream grpc.ServerStream) error {
+	if err := stream.RecvMsg(nil); err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	ch, sub, err := srv.(Backend).WatchAddresses(ctx)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case addrs, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := stream.SendMsg(addrs); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 // RegisterService registers a new sentry service with the given gRPC server.
 func RegisterService(server *grpc.Server, service Backend) {
 	server.RegisterService(&serviceDesc, service)
@@ -72,3 +164,50 @@ func (c *Client) GetAddresses(ctx context.Context) (*SentryAddresses, error) {
 	}
 	return &rsp, nil
 }
+
+func (c *Client) WatchAddresses(ctx context.Context) (<-chan *SentryAddresses, pubsub.ClosableSubscription, error) {
+	ctx, sub := pubsub.NewContextSubscription(ctx)
+
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], methodWatchAddresses.FullName())
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = stream.SendMsg(nil); err != nil {
+		return nil, nil, err
+	}
+	if err = stream.CloseSend(); err != nil {
+		return nil, nil, err
+	}
+
+	ch := make(chan *SentryAddresses)
+	go func() {
+		defer close(ch)
+
+		for {
+			var addrs SentryAddresses
+			if serr := stream.RecvMsg(&addrs); serr != nil {
+				return
+			}
+
+			select {
+			case ch <- &addrs:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, sub, nil
+}
+
+func (c *Client) GetStatus(ctx context.Context) (*Status, error) {
+	var rsp Status
+	if err := c.conn.Invoke(ctx, methodGetStatus.FullName(), nil, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *Client) SetUpstreamTLSPubKeys(ctx context.Context, pubKeys []signature.PublicKey) error {
+	return c.conn.Invoke(ctx, methodSetUpstreamTLSPubKeys.FullName(), pubKeys, nil)
+}
@@ -4,27 +4,103 @@ package sentry
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"slices"
 	"sync"
+	"time"
 
+	"github.com/eapache/channels"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	"github.com/oasisprotocol/oasis-core/go/common/identity"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
 	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
 	"github.com/oasisprotocol/oasis-core/go/sentry/api"
 )
 
+// DefaultAddressCacheTTL is the default time for which consensus addresses are cached before
+// they are obtained from the consensus backend again.
+const DefaultAddressCacheTTL = 30 * time.Second
+
 var _ api.Backend = (*backend)(nil)
 
+// addressProvider is the part of the consensus backend used by the sentry backend.
+type addressProvider interface {
+	// GetAddresses returns the consensus addresses of the local node.
+	GetAddresses() ([]node.ConsensusAddress, error)
+}
+
 type backend struct {
 	sync.RWMutex
 
 	logger *logging.Logger
 
-	consensus consensus.Service
+	consensus addressProvider
 	identity  *identity.Identity
+
+	cacheTTL    time.Duration
+	cached      *api.SentryAddresses
+	lastRefresh time.Time
+	notifier    *pubsub.Broker
+
+	upstreamTLSPubKeys []signature.PublicKey
+
+	nowFn func() time.Time
 }
 
 func (b *backend) GetAddresses(context.Context) (*api.SentryAddresses, error) {
-	// Consensus addresses.
+	b.RLock()
+	cached := b.cached
+	fresh := cached != nil && b.nowFn().Sub(b.lastRefresh) < b.cacheTTL
+	b.RUnlock()
+
+	if fresh {
+		return cached, nil
+	}
+	return b.refresh()
+}
+
+func (b *backend) WatchAddresses(context.Context) (<-chan *api.SentryAddresses, pubsub.ClosableSubscription, error) {
+	ch := make(chan *api.SentryAddresses)
+	sub := b.notifier.Subscribe()
+	sub.Unwrap(ch)
+
+	return ch, sub, nil
+}
+
+func (b *backend) GetStatus(context.Context) (*api.Status, error) {
+	b.RLock()
+	defer b.RUnlock()
+
+	if b.cached == nil {
+		return &api.Status{
+			UpstreamTLSPubKeys: b.upstreamTLSPubKeys,
+		}, nil
+	}
+	return &api.Status{
+		Addresses:          b.cached,
+		LastRefresh:        b.lastRefresh,
+		CacheAge:           b.nowFn().Sub(b.lastRefresh),
+		UpstreamTLSPubKeys: b.upstreamTLSPubKeys,
+	}, nil
+}
+
+func (b *backend) SetUpstreamTLSPubKeys(_ context.Context, pubKeys []signature.PublicKey) error {
+	b.Lock()
+	defer b.Unlock()
+
+	b.upstreamTLSPubKeys = slices.Clone(pubKeys)
+
+	b.logger.Info("upstream TLS public keys updated",
+		"pub_keys", pubKeys,
+	)
+	return nil
+}
+
+// refresh obtains the consensus addresses from the consensus backend and updates the cache.
+func (b *backend) refresh() (*api.SentryAddresses, error) {
 	consensusAddrs, err := b.consensus.GetAddresses()
 	if err != nil {
 		return nil, fmt.Errorf("sentry: error obtaining consensus addresses: %w", err)
@@ -33,9 +109,91 @@ func (b *backend) GetAddresses(context.Context) (*api.SentryAddresses, error) {
 		"addresses", consensusAddrs,
 	)
 
-	return &api.SentryAddresses{
+	return b.update(consensusAddrs), nil
+}
+
+// update updates the cached addresses and notifies watchers in case the addresses changed.
+func (b *backend) update(consensusAddrs []node.ConsensusAddress) *api.SentryAddresses {
+	addrs := &api.SentryAddresses{
 		Consensus: consensusAddrs,
-	}, nil
+	}
+
+	b.Lock()
+	changed := b.cached == nil || !reflect.DeepEqual(b.cached.Consensus, consensusAddrs)
+	b.cached = addrs
+	b.lastRefresh = b.nowFn()
+	b.Unlock()
+
+	if changed {
+		b.logger.Info("consensus addresses changed",
+			"addresses", consensusAddrs,
+		)
+		b.notifier.Broadcast(addrs)
+	}
+	return addrs
+}
+
+// worker keeps the cached addresses up to date so that changes are propagated to watchers.
+//
+// In case the consensus backend supports address change notifications, these are used to update
+// the cache. Otherwise the addresses are refreshed each time the cache expires.
+func (b *backend) worker(ctx context.Context) {
+	if w, ok := b.consensus.(consensus.AddressWatcher); ok {
+		ch, sub, err := w.WatchAddresses(ctx)
+		if err == nil {
+			defer sub.Close()
+
+			for {
+				select {
+				case addrs, ok := <-ch:
+					if !ok {
+						return
+					}
+					b.update(addrs)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+		b.logger.Warn("failed to watch consensus addresses, falling back to polling",
+			"err", err,
+		)
+	}
+
+	ticker := time.NewTicker(b.cacheTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := b.refresh(); err != nil {
+				b.logger.Error("failed to refresh consensus addresses",
+					"err", err,
+				)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func newBackend(consensus addressProvider, identity *identity.Identity, cacheTTL time.Duration) *backend {
+	b := &backend{
+		logger:    logging.GetLogger("sentry"),
+		consensus: consensus,
+		identity:  identity,
+		cacheTTL:  cacheTTL,
+		nowFn:     time.Now,
+	}
+	b.notifier = pubsub.NewBrokerEx(func(ch channels.Channel) {
+		b.RLock()
+		defer b.RUnlock()
+
+		if b.cached != nil {
+			ch.In() <- b.cached
+		}
+	})
+	return b
 }
 
 // New constructs a new sentry backend instance.
@@ -47,11 +205,8 @@ func New(
 		return nil, fmt.Errorf("sentry: consensus backend is nil")
 	}
 
-	b := &backend{
-		logger:    logging.GetLogger("sentry"),
-		consensus: consensus,
-		identity:  identity,
-	}
+	b := newBackend(consensus, identity, DefaultAddressCacheTTL)
+	go b.worker(context.Background())
 
 	return b, nil
 }
@@ -0,0 +1,181 @@
+package sentry
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	"github.com/oasisprotocol/oasis-core/go/sentry/api"
+)
+
+type testProvider struct {
+	sync.Mutex
+
+	addrs []node.ConsensusAddress
+	calls int
+}
+
+func (p *testProvider) GetAddresses() ([]node.ConsensusAddress, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.calls++
+	return p.addrs, nil
+}
+
+func (p *testProvider) setAddresses(addrs []node.ConsensusAddress) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.addrs = addrs
+}
+
+func (p *testProvider) numCalls() int {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.calls
+}
+
+type testWatchingProvider struct {
+	testProvider
+
+	ch chan []node.ConsensusAddress
+}
+
+func (p *testWatchingProvider) WatchAddresses(ctx context.Context) (<-chan []node.ConsensusAddress, pubsub.ClosableSubscription, error) {
+	_, sub := pubsub.NewContextSubscription(ctx)
+	return p.ch, sub, nil
+}
+
+func testAddresses(port int64) []node.ConsensusAddress {
+	return []node.ConsensusAddress{
+		{Address: node.Address{IP: net.IPv4(127, 0, 0, 1), Port: port}},
+	}
+}
+
+func TestAddressCache(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	provider := &testProvider{addrs: testAddresses(26656)}
+	b := newBackend(provider, nil, time.Minute)
+	now := time.Now()
+	b.nowFn = func() time.Time { return now }
+
+	status, err := b.GetStatus(ctx)
+	require.NoError(err, "GetStatus")
+	require.Nil(status.Addresses, "nothing should be cached initially")
+
+	addrs, err := b.GetAddresses(ctx)
+	require.NoError(err, "GetAddresses")
+	require.Equal(testAddresses(26656), addrs.Consensus)
+	require.Equal(1, provider.numCalls())
+
+	// Addresses should be served from the cache.
+	provider.setAddresses(testAddresses(26657))
+	now = now.Add(30 * time.Second)
+	addrs, err = b.GetAddresses(ctx)
+	require.NoError(err, "GetAddresses")
+	require.Equal(testAddresses(26656), addrs.Consensus, "cached addresses should be returned")
+	require.Equal(1, provider.numCalls())
+
+	status, err = b.GetStatus(ctx)
+	require.NoError(err, "GetStatus")
+	require.Equal(addrs, status.Addresses)
+	require.Equal(30*time.Second, status.CacheAge)
+
+	// Once the cache expires, addresses should be obtained again.
+	now = now.Add(30 * time.Second)
+	addrs, err = b.GetAddresses(ctx)
+	require.NoError(err, "GetAddresses")
+	require.Equal(testAddresses(26657), addrs.Consensus, "addresses should be refreshed after expiry")
+	require.Equal(2, provider.numCalls())
+
+	status, err = b.GetStatus(ctx)
+	require.NoError(err, "GetStatus")
+	require.Zero(status.CacheAge)
+}
+
+func TestAddressInvalidation(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	provider := &testWatchingProvider{
+		testProvider: testProvider{addrs: testAddresses(26656)},
+		ch:           make(chan []node.ConsensusAddress),
+	}
+	b := newBackend(provider, nil, time.Hour)
+	go b.worker(ctx)
+
+	addrs, err := b.GetAddresses(ctx)
+	require.NoError(err, "GetAddresses")
+	require.Equal(testAddresses(26656), addrs.Consensus)
+
+	ch, sub, err := b.WatchAddresses(ctx)
+	require.NoError(err, "WatchAddresses")
+	defer sub.Close()
+
+	// The current addresses should be delivered immediately.
+	recvAddresses := func() *api.SentryAddresses {
+		select {
+		case addrs := <-ch:
+			return addrs
+		case <-time.After(5 * time.Second):
+			require.FailNow("timed out waiting for addresses")
+			return nil
+		}
+	}
+	require.Equal(testAddresses(26656), recvAddresses().Consensus)
+
+	// A change notification from the consensus backend should update the cache and be
+	// delivered to watchers.
+	provider.ch <- testAddresses(26657)
+	require.Equal(testAddresses(26657), recvAddresses().Consensus)
+
+	addrs, err = b.GetAddresses(ctx)
+	require.NoError(err, "GetAddresses")
+	require.Equal(testAddresses(26657), addrs.Consensus, "cache should be updated on change")
+	require.Equal(1, provider.numCalls(), "consensus backend should not be queried again")
+
+	// Notifications without any change should not be delivered.
+	provider.ch <- testAddresses(26657)
+	provider.ch <- testAddresses(26658)
+	require.Equal(testAddresses(26658), recvAddresses().Consensus)
+}
+
+func TestUpstreamTLSPubKeys(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	b := newBackend(&testProvider{}, nil, time.Minute)
+
+	status, err := b.GetStatus(ctx)
+	require.NoError(err, "GetStatus")
+	require.Empty(status.UpstreamTLSPubKeys, "no upstream keys should be known initially")
+
+	oldKey := memorySigner.NewTestSigner("sentry test upstream TLS key 1").Public()
+	newKey := memorySigner.NewTestSigner("sentry test upstream TLS key 2").Public()
+
+	err = b.SetUpstreamTLSPubKeys(ctx, []signature.PublicKey{newKey, oldKey})
+	require.NoError(err, "SetUpstreamTLSPubKeys")
+	status, err = b.GetStatus(ctx)
+	require.NoError(err, "GetStatus")
+	require.Equal([]signature.PublicKey{newKey, oldKey}, status.UpstreamTLSPubKeys)
+
+	// Pushed keys should replace previously pushed keys.
+	err = b.SetUpstreamTLSPubKeys(ctx, []signature.PublicKey{newKey})
+	require.NoError(err, "SetUpstreamTLSPubKeys")
+	status, err = b.GetStatus(ctx)
+	require.NoError(err, "GetStatus")
+	require.Equal([]signature.PublicKey{newKey}, status.UpstreamTLSPubKeys)
+}
@@ -70,6 +70,11 @@ var (
 	// total supply value.
 	ErrAllowanceGreaterThanSupply = errors.New(ModuleName, 11, "staking: allowance greater than total supply")
 
+	// ErrEpochRewardsNotAvailable is the error returned when the rewards distributed at the given
+	// epoch are not available, either because the epoch has not been reached yet or because they
+	// are outside the retention window.
+	ErrEpochRewardsNotAvailable = errors.New(ModuleName, 12, "staking: epoch rewards not available")
+
 	// MethodTransfer is the method name for transfers.
 	MethodTransfer = transaction.NewMethodName(ModuleName, "Transfer", Transfer{})
 	// MethodBurn is the method name for burns.
@@ -179,6 +184,13 @@ type Backend interface {
 	// ConsensusParameters returns the staking consensus parameters.
 	ConsensusParameters(ctx context.Context, height int64) (*ConsensusParameters, error)
 
+	// GetRewardSchedule returns the active reward schedule together with a projection of the
+	// epoch rewards for the next RewardProjectionEpochs epochs.
+	GetRewardSchedule(ctx context.Context, height int64) (*RewardSchedule, error)
+
+	// GetEpochRewards returns the rewards distributed at the given epoch.
+	GetEpochRewards(ctx context.Context, epoch beacon.EpochTime) (*EpochRewards, error)
+
 	// GetEvents returns the events at specified block height.
 	GetEvents(ctx context.Context, height int64) ([]*Event, error)
 
@@ -639,16 +651,7 @@ func (p *SharePool) sharesForStake(amount *quantity.Quantity) (*quantity.Quantit
 	//
 	//     shares = amount * total_shares / balance
 	//
-	q := amount.Clone()
-	// Multiply first.
-	if err := q.Mul(&p.TotalShares); err != nil {
-		return nil, err
-	}
-	if err := q.Quo(&p.Balance); err != nil {
-		return nil, err
-	}
-
-	return q, nil
+	return amount.MulQuo(&p.TotalShares, &p.Balance)
 }
 
 // Deposit moves stake into the combined balance, raising the shares.
@@ -688,16 +691,7 @@ func (p *SharePool) StakeForShares(amount *quantity.Quantity) (*quantity.Quantit
 	//
 	//     base_units = shares * balance / total_shares
 	//
-	q := amount.Clone()
-	// Multiply first.
-	if err := q.Mul(&p.Balance); err != nil {
-		return nil, err
-	}
-	if err := q.Quo(&p.TotalShares); err != nil {
-		return nil, err
-	}
-
-	return q, nil
+	return amount.MulQuo(&p.Balance, &p.TotalShares)
 }
 
 // Withdraw moves stake out of the combined balance, reducing the shares.
@@ -1318,6 +1312,9 @@ type ConsensusParameterChanges struct {
 	RewardFactorEpochSigned *quantity.Quantity `json:"reward_factor_epoch_signed"`
 	// RewardFactorBlockProposed is the new block proposed reward factor.
 	RewardFactorBlockProposed *quantity.Quantity `json:"reward_factor_block_proposed"`
+
+	// Thresholds are the new staking thresholds. Only the given threshold kinds are changed.
+	Thresholds map[ThresholdKind]quantity.Quantity `json:"thresholds,omitempty"`
 }
 
 // Apply applies changes to the given consensus parameters.
@@ -1370,6 +1367,16 @@ func (c *ConsensusParameterChanges) Apply(params *ConsensusParameters) error {
 	if c.RewardFactorBlockProposed != nil {
 		params.RewardFactorBlockProposed = *c.RewardFactorBlockProposed
 	}
+	if c.Thresholds != nil {
+		thresholds := make(map[ThresholdKind]quantity.Quantity, len(params.Thresholds))
+		for kind, q := range params.Thresholds {
+			thresholds[kind] = q
+		}
+		for kind, q := range c.Thresholds {
+			thresholds[kind] = q
+		}
+		params.Thresholds = thresholds
+	}
 	return nil
 }
 
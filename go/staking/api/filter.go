@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	"github.com/oasisprotocol/oasis-core/go/consensus/api/eventfilter"
+)
+
+// EventField is a staking event field that can be used in event filter conditions.
+type EventField string
+
+const (
+	// EventFieldKind is the event kind (e.g., "transfer" or "add_escrow").
+	EventFieldKind EventField = "kind"
+	// EventFieldAddress is any address involved in the event.
+	EventFieldAddress EventField = "address"
+	// EventFieldFrom is the source address of a transfer.
+	EventFieldFrom EventField = "from"
+	// EventFieldTo is the destination address of a transfer.
+	EventFieldTo EventField = "to"
+	// EventFieldOwner is the owner address of burn, escrow and allowance change events.
+	EventFieldOwner EventField = "owner"
+	// EventFieldEscrow is the escrow address of escrow events.
+	EventFieldEscrow EventField = "escrow"
+	// EventFieldBeneficiary is the beneficiary address of allowance change events.
+	EventFieldBeneficiary EventField = "beneficiary"
+	// EventFieldAmount is the amount of the event. For allowance change events it is the amount
+	// by which the allowance changed.
+	EventFieldAmount EventField = "amount"
+)
+
+// EventCondition is a staking event filter condition.
+//
+// Exactly the value matching the field's type must be set. Conditions on fields that an event does
+// not have never match that event.
+type EventCondition struct {
+	// Field is the event field the condition applies to.
+	Field EventField `json:"field"`
+	// Comparator is the comparator. Kind and address fields only support (in)equality.
+	Comparator eventfilter.Comparator `json:"comparator"`
+
+	// Kind is the event kind for kind conditions.
+	Kind string `json:"kind,omitempty"`
+	// Address is the address for address conditions.
+	Address *Address `json:"address,omitempty"`
+	// Amount is the amount for amount conditions.
+	Amount *quantity.Quantity `json:"amount,omitempty"`
+}
+
+// EventFilter is a staking event filter.
+type EventFilter = eventfilter.Filter[*Event, EventCondition]
+
+// EventsQuery is a filtered staking events query.
+type EventsQuery struct {
+	// StartHeight is the first height to scan (inclusive).
+	StartHeight int64 `json:"start_height"`
+	// EndHeight is the last height to scan (inclusive).
+	EndHeight int64 `json:"end_height"`
+	// Filter is the filter events must match. If nil, all events match.
+	Filter *EventFilter `json:"filter,omitempty"`
+}
+
+// NewKindCondition creates a new condition matching events of the given kind.
+func NewKindCondition(kind string) EventCondition {
+	return EventCondition{Field: EventFieldKind, Comparator: eventfilter.Equal, Kind: kind}
+}
+
+// NewAddressCondition creates a new condition matching events where the given address field
+// equals the given address.
+func NewAddressCondition(field EventField, addr Address) EventCondition {
+	return EventCondition{Field: field, Comparator: eventfilter.Equal, Address: &addr}
+}
+
+// NewAmountCondition creates a new condition comparing event amounts against the given amount.
+func NewAmountCondition(cmp eventfilter.Comparator, amount quantity.Quantity) EventCondition {
+	return EventCondition{Field: EventFieldAmount, Comparator: cmp, Amount: &amount}
+}
+
+// Validate checks that the condition is well-formed.
+func (c EventCondition) Validate() error {
+	if err := c.Comparator.Validate(); err != nil {
+		return err
+	}
+
+	var valid bool
+	switch c.Field {
+	case EventFieldKind:
+		valid = c.Kind != "" && c.Address == nil && c.Amount == nil && c.Comparator.IsEquality()
+	case EventFieldAddress, EventFieldFrom, EventFieldTo, EventFieldOwner, EventFieldEscrow, EventFieldBeneficiary:
+		valid = c.Kind == "" && c.Address != nil && c.Amount == nil && c.Comparator.IsEquality()
+	case EventFieldAmount:
+		valid = c.Kind == "" && c.Address == nil && c.Amount != nil
+	default:
+		return fmt.Errorf("%w: unknown staking event field '%s'", eventfilter.ErrInvalidFilter, c.Field)
+	}
+	if !valid {
+		return fmt.Errorf("%w: malformed condition on staking event field '%s'", eventfilter.ErrInvalidFilter, c.Field)
+	}
+	return nil
+}
+
+// Matches returns true iff the given event satisfies the condition.
+func (c EventCondition) Matches(ev *Event) bool {
+	fields := ev.filterFields()
+	if fields == nil {
+		return false
+	}
+
+	switch c.Field {
+	case EventFieldKind:
+		return c.Comparator.Compare(strings.Compare(fields.kind, c.Kind))
+	case EventFieldAddress:
+		// Any involved address must be equal, or none for inequality.
+		involved := false
+		for _, addr := range []*Address{fields.from, fields.to, fields.owner, fields.escrow, fields.beneficiary} {
+			if addr != nil && addr.Equal(*c.Address) {
+				involved = true
+				break
+			}
+		}
+		return involved == (c.Comparator == eventfilter.Equal)
+	case EventFieldAmount:
+		if fields.amount == nil {
+			return false
+		}
+		return c.Comparator.Compare(fields.amount.Cmp(c.Amount))
+	}
+
+	var addr *Address
+	switch c.Field {
+	case EventFieldFrom:
+		addr = fields.from
+	case EventFieldTo:
+		addr = fields.to
+	case EventFieldOwner:
+		addr = fields.owner
+	case EventFieldEscrow:
+		addr = fields.escrow
+	case EventFieldBeneficiary:
+		addr = fields.beneficiary
+	}
+	if addr == nil {
+		return false
+	}
+	return addr.Equal(*c.Address) == (c.Comparator == eventfilter.Equal)
+}
+
+// eventFilterFields are the fields of a staking event that can be filtered on.
+type eventFilterFields struct {
+	kind        string
+	from        *Address
+	to          *Address
+	owner       *Address
+	escrow      *Address
+	beneficiary *Address
+	amount      *quantity.Quantity
+}
+
+func (e *Event) filterFields() *eventFilterFields {
+	switch {
+	case e.Transfer != nil:
+		ev := e.Transfer
+		return &eventFilterFields{kind: ev.EventKind(), from: &ev.From, to: &ev.To, amount: &ev.Amount}
+	case e.Burn != nil:
+		ev := e.Burn
+		return &eventFilterFields{kind: ev.EventKind(), owner: &ev.Owner, amount: &ev.Amount}
+	case e.Escrow != nil && e.Escrow.Add != nil:
+		ev := e.Escrow.Add
+		return &eventFilterFields{kind: ev.EventKind(), owner: &ev.Owner, escrow: &ev.Escrow, amount: &ev.Amount}
+	case e.Escrow != nil && e.Escrow.Take != nil:
+		ev := e.Escrow.Take
+		return &eventFilterFields{kind: ev.EventKind(), owner: &ev.Owner, amount: &ev.Amount}
+	case e.Escrow != nil && e.Escrow.DebondingStart != nil:
+		ev := e.Escrow.DebondingStart
+		return &eventFilterFields{kind: ev.EventKind(), owner: &ev.Owner, escrow: &ev.Escrow, amount: &ev.Amount}
+	case e.Escrow != nil && e.Escrow.Reclaim != nil:
+		ev := e.Escrow.Reclaim
+		return &eventFilterFields{kind: ev.EventKind(), owner: &ev.Owner, escrow: &ev.Escrow, amount: &ev.Amount}
+	case e.AllowanceChange != nil:
+		ev := e.AllowanceChange
+		return &eventFilterFields{kind: ev.EventKind(), owner: &ev.Owner, beneficiary: &ev.Beneficiary, amount: &ev.AmountChange}
+	default:
+		return nil
+	}
+}
+
+// GetFilteredEvents returns the staking events at the queried heights that match the query filter.
+//
+// The filter is evaluated while scanning the events of each height so that only matching events
+// are returned.
+func GetFilteredEvents(ctx context.Context, backend Backend, query *EventsQuery) ([]*Event, error) {
+	return eventfilter.Scan(ctx, query.StartHeight, query.EndHeight, query.Filter, backend.GetEvents)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	"github.com/oasisprotocol/oasis-core/go/consensus/api/eventfilter"
+)
+
+func TestEventFilter(t *testing.T) {
+	require := require.New(t)
+
+	addr1 := NewAddress(signature.NewPublicKey("aaafffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"))
+	addr2 := NewAddress(signature.NewPublicKey("bbbfffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"))
+	addr3 := NewAddress(signature.NewPublicKey("cccfffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"))
+
+	transfer := func(from, to Address, amount uint64) *Event {
+		return &Event{Transfer: &TransferEvent{From: from, To: to, Amount: *quantity.NewFromUint64(amount)}}
+	}
+	events := []*Event{
+		transfer(addr1, addr2, 50),
+		transfer(addr1, addr2, 500),
+		transfer(addr2, addr1, 1000),
+		transfer(addr3, addr2, 1000),
+		{Burn: &BurnEvent{Owner: addr1, Amount: *quantity.NewFromUint64(1000)}},
+		{Escrow: &EscrowEvent{Add: &AddEscrowEvent{Owner: addr1, Escrow: addr3, Amount: *quantity.NewFromUint64(1000)}}},
+	}
+
+	matching := func(filter *EventFilter) []int {
+		var indices []int
+		for i, ev := range events {
+			if filter.Matches(ev) {
+				indices = append(indices, i)
+			}
+		}
+		return indices
+	}
+
+	for _, tc := range []struct {
+		name     string
+		filter   *EventFilter
+		expected []int
+	}{
+		{"Nil", nil, []int{0, 1, 2, 3, 4, 5}},
+		{"Kind", eventfilter.Match[*Event](NewKindCondition((&TransferEvent{}).EventKind())), []int{0, 1, 2, 3}},
+		{"From", eventfilter.Match[*Event](NewAddressCondition(EventFieldFrom, addr1)), []int{0, 1}},
+		{"Address", eventfilter.Match[*Event](NewAddressCondition(EventFieldAddress, addr1)), []int{0, 1, 2, 4, 5}},
+		{"AmountAndAddress", eventfilter.Match[*Event](
+			NewAmountCondition(eventfilter.GreaterOrEqual, *quantity.NewFromUint64(500)),
+			NewAddressCondition(EventFieldAddress, addr1),
+		), []int{1, 2, 4, 5}},
+		{"Nested", eventfilter.And(
+			eventfilter.Match[*Event](NewAmountCondition(eventfilter.Greater, *quantity.NewFromUint64(100))),
+			eventfilter.Match[*Event](
+				NewKindCondition((&TransferEvent{}).EventKind()),
+				NewAddressCondition(EventFieldTo, addr2),
+			),
+		), []int{1, 3}},
+		{"NotEqual", eventfilter.Match[*Event](
+			EventCondition{Field: EventFieldEscrow, Comparator: eventfilter.NotEqual, Address: &addr2},
+		), []int{5}},
+	} {
+		if tc.filter != nil {
+			require.NoError(tc.filter.Validate(), tc.name)
+		}
+		require.Equal(tc.expected, matching(tc.filter), tc.name)
+	}
+}
+
+func TestEventConditionValidate(t *testing.T) {
+	require := require.New(t)
+
+	addr := NewAddress(signature.NewPublicKey("aaafffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"))
+	amount := quantity.NewFromUint64(1)
+
+	for _, cond := range []EventCondition{
+		{Field: "unknown", Comparator: eventfilter.Equal, Kind: "transfer"},
+		{Field: EventFieldKind, Comparator: "like", Kind: "transfer"},
+		{Field: EventFieldKind, Comparator: eventfilter.Equal},
+		{Field: EventFieldKind, Comparator: eventfilter.Less, Kind: "transfer"},
+		{Field: EventFieldFrom, Comparator: eventfilter.Greater, Address: &addr},
+		{Field: EventFieldFrom, Comparator: eventfilter.Equal, Amount: amount},
+		{Field: EventFieldAmount, Comparator: eventfilter.Less},
+		{Field: EventFieldAmount, Comparator: eventfilter.Less, Address: &addr, Amount: amount},
+	} {
+		err := cond.Validate()
+		require.ErrorIs(err, eventfilter.ErrInvalidFilter, "condition %+v should be invalid", cond)
+	}
+}
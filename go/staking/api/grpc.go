@@ -5,6 +5,7 @@ import (
 
 	"google.golang.org/grpc"
 
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
 	cmnGrpc "github.com/oasisprotocol/oasis-core/go/common/grpc"
 	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
 	"github.com/oasisprotocol/oasis-core/go/common/quantity"
@@ -52,8 +53,14 @@ var (
 	methodStateToGenesis = serviceName.NewMethod("StateToGenesis", int64(0))
 	// methodConsensusParameters is the ConsensusParameters method.
 	methodConsensusParameters = serviceName.NewMethod("ConsensusParameters", int64(0))
+	// methodGetRewardSchedule is the GetRewardSchedule method.
+	methodGetRewardSchedule = serviceName.NewMethod("GetRewardSchedule", int64(0))
+	// methodGetEpochRewards is the GetEpochRewards method.
+	methodGetEpochRewards = serviceName.NewMethod("GetEpochRewards", beacon.EpochTime(0))
 	// methodGetEvents is the GetEvents method.
 	methodGetEvents = serviceName.NewMethod("GetEvents", int64(0))
+	// methodGetFilteredEvents is the GetFilteredEvents method.
+	methodGetFilteredEvents = serviceName.NewMethod("GetFilteredEvents", EventsQuery{})
 
 	// methodWatchEvents is the WatchEvents method.
 	methodWatchEvents = serviceName.NewMethod("WatchEvents", nil)
@@ -139,10 +146,22 @@ var (
 				MethodName: methodConsensusParameters.ShortName(),
 				Handler:    handlerConsensusParameters,
 			},
+			{
+				MethodName: methodGetRewardSchedule.ShortName(),
+				Handler:    handlerGetRewardSchedule,
+			},
+			{
+				MethodName: methodGetEpochRewards.ShortName(),
+				Handler:    handlerGetEpochRewards,
+			},
 			{
 				MethodName: methodGetEvents.ShortName(),
 				Handler:    handlerGetEvents,
 			},
+			{
+				MethodName: methodGetFilteredEvents.ShortName(),
+				Handler:    handlerGetFilteredEvents,
+			},
 		},
 		Streams: []grpc.StreamDesc{
 			{
@@ -591,6 +610,52 @@ func handlerConsensusParameters(
 	return interceptor(ctx, height, info, handler)
 }
 
+func handlerGetRewardSchedule(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var height int64
+	if err := dec(&height); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetRewardSchedule(ctx, height)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetRewardSchedule.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(Backend).GetRewardSchedule(ctx, req.(int64))
+	}
+	return interceptor(ctx, height, info, handler)
+}
+
+func handlerGetEpochRewards(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var epoch beacon.EpochTime
+	if err := dec(&epoch); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(Backend).GetEpochRewards(ctx, epoch)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetEpochRewards.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(Backend).GetEpochRewards(ctx, req.(beacon.EpochTime))
+	}
+	return interceptor(ctx, epoch, info, handler)
+}
+
 func handlerGetEvents(
 	srv any,
 	ctx context.Context,
@@ -614,6 +679,29 @@ func handlerGetEvents(
 	return interceptor(ctx, height, info, handler)
 }
 
+func handlerGetFilteredEvents(
+	srv any,
+	ctx context.Context,
+	dec func(any) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (any, error) {
+	var query EventsQuery
+	if err := dec(&query); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return GetFilteredEvents(ctx, srv.(Backend), &query)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: methodGetFilteredEvents.FullName(),
+	}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return GetFilteredEvents(ctx, srv.(Backend), req.(*EventsQuery))
+	}
+	return interceptor(ctx, &query, info, handler)
+}
+
 func handlerWatchEvents(srv any, stream grpc.ServerStream) error {
 	if err := stream.RecvMsg(nil); err != nil {
 		return err
@@ -811,6 +899,22 @@ func (c *Client) ConsensusParameters(ctx context.Context, height int64) (*Consen
 	return &rsp, nil
 }
 
+func (c *Client) GetRewardSchedule(ctx context.Context, height int64) (*RewardSchedule, error) {
+	var rsp RewardSchedule
+	if err := c.conn.Invoke(ctx, methodGetRewardSchedule.FullName(), height, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+func (c *Client) GetEpochRewards(ctx context.Context, epoch beacon.EpochTime) (*EpochRewards, error) {
+	var rsp EpochRewards
+	if err := c.conn.Invoke(ctx, methodGetEpochRewards.FullName(), epoch, &rsp); err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
 func (c *Client) GetEvents(ctx context.Context, height int64) ([]*Event, error) {
 	var rsp []*Event
 	if err := c.conn.Invoke(ctx, methodGetEvents.FullName(), height, &rsp); err != nil {
@@ -819,6 +923,14 @@ func (c *Client) GetEvents(ctx context.Context, height int64) ([]*Event, error)
 	return rsp, nil
 }
 
+func (c *Client) GetFilteredEvents(ctx context.Context, query *EventsQuery) ([]*Event, error) {
+	var rsp []*Event
+	if err := c.conn.Invoke(ctx, methodGetFilteredEvents.FullName(), query, &rsp); err != nil {
+		return nil, err
+	}
+	return rsp, nil
+}
+
 func (c *Client) WatchEvents(ctx context.Context) (<-chan *Event, pubsub.ClosableSubscription, error) {
 	ctx, sub := pubsub.NewContextSubscription(ctx)
 
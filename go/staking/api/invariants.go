@@ -0,0 +1,51 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+)
+
+// CheckSupplyInvariant checks that the balances of all accounts, the governance deposits, the
+// common pool and the last block fees add up to the total supply.
+func (g *Genesis) CheckSupplyInvariant() error {
+	var total quantity.Quantity
+	for _, acct := range g.Ledger {
+		_ = total.Add(&acct.General.Balance)
+		_ = total.Add(&acct.Escrow.Active.Balance)
+		_ = total.Add(&acct.Escrow.Debonding.Balance)
+	}
+	_ = total.Add(&g.GovernanceDeposits)
+	_ = total.Add(&g.CommonPool)
+	_ = total.Add(&g.LastBlockFees)
+
+	if total.Cmp(&g.TotalSupply) != 0 {
+		return fmt.Errorf(
+			"staking: balances in accounts, plus governance deposits, plus common pool, plus last block fees (%s), do not add up to total supply (%s)",
+			total, g.TotalSupply,
+		)
+	}
+	return nil
+}
+
+// CheckEscrowSharesInvariant checks that all delegations reference existing accounts and that
+// the shares of all delegations and debonding delegations to each account add up to the total
+// shares of the account's escrow pools.
+func (g *Genesis) CheckEscrowSharesInvariant() error {
+	for addr := range g.Delegations {
+		if g.Ledger[addr] == nil {
+			return fmt.Errorf("staking: delegations to nonexisting account %s", addr)
+		}
+	}
+	for addr := range g.DebondingDelegations {
+		if g.Ledger[addr] == nil {
+			return fmt.Errorf("staking: debonding delegations to nonexisting account %s", addr)
+		}
+	}
+	for addr, acct := range g.Ledger {
+		if err := SanityCheckAccountShares(addr, acct, g.Delegations[addr], g.DebondingDelegations[addr]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
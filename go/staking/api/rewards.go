@@ -1,12 +1,23 @@
 package api
 
 import (
+	"fmt"
 	"math/big"
 
 	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
 	"github.com/oasisprotocol/oasis-core/go/common/quantity"
 )
 
+const (
+	// RewardProjectionEpochs is the number of future epochs covered by the reward schedule
+	// projection.
+	RewardProjectionEpochs = 24
+
+	// EpochRewardsRetention is the number of most recent epochs for which the distributed epoch
+	// rewards are retained.
+	EpochRewardsRetention beacon.EpochTime = 720
+)
+
 // RewardAmountDenominator is the denominator for the reward rate.
 var RewardAmountDenominator *quantity.Quantity
 
@@ -16,6 +27,208 @@ type RewardStep struct {
 	Scale quantity.Quantity `json:"scale"`
 }
 
+// RewardProjection is the projected epoch reward distribution at a future epoch.
+type RewardProjection struct {
+	// Epoch is the epoch at which the rewards are distributed.
+	Epoch beacon.EpochTime `json:"epoch"`
+	// Scale is the reward schedule scale active at the epoch.
+	Scale quantity.Quantity `json:"scale"`
+	// Escrow is the projected total escrow at the start of the epoch.
+	Escrow quantity.Quantity `json:"escrow"`
+	// Amount is the projected total amount of distributed rewards.
+	Amount quantity.Quantity `json:"amount"`
+}
+
+// RewardSchedule is the active reward schedule together with a projection of the epoch rewards.
+type RewardSchedule struct {
+	// Height is the height at which the schedule was queried.
+	Height int64 `json:"height"`
+	// Epoch is the epoch at the queried height.
+	Epoch beacon.EpochTime `json:"epoch"`
+
+	// Schedule is the reward schedule.
+	Schedule []RewardStep `json:"schedule,omitempty"`
+	// RewardFactorEpochSigned is the factor for a reward distributed per epoch to entities that
+	// have signed at least a threshold fraction of the blocks.
+	RewardFactorEpochSigned quantity.Quantity `json:"reward_factor_epoch_signed"`
+	// RewardFactorBlockProposed is the factor for a reward distributed per block to the entity
+	// that proposed the block.
+	RewardFactorBlockProposed quantity.Quantity `json:"reward_factor_block_proposed"`
+
+	// TotalEscrow is the total active escrow balance at the queried height.
+	TotalEscrow quantity.Quantity `json:"total_escrow"`
+	// CommonPool is the common pool balance at the queried height.
+	CommonPool quantity.Quantity `json:"common_pool"`
+
+	// Projection is the projection of the epoch rewards for the next epochs.
+	//
+	// The projection assumes that all escrow belongs to entities that sign enough blocks and
+	// that distributed rewards remain escrowed, so it is an upper bound on the actual rewards.
+	// Block proposer rewards are not included.
+	Projection []RewardProjection `json:"projection,omitempty"`
+}
+
+// NewRewardSchedule creates a new reward schedule with a projection of the epoch rewards for the
+// given number of epochs following the given epoch.
+func NewRewardSchedule(
+	params *ConsensusParameters,
+	height int64,
+	epoch beacon.EpochTime,
+	totalEscrow *quantity.Quantity,
+	commonPool *quantity.Quantity,
+	numEpochs int,
+) (*RewardSchedule, error) {
+	rs := RewardSchedule{
+		Height:                    height,
+		Epoch:                     epoch,
+		Schedule:                  params.RewardSchedule,
+		RewardFactorEpochSigned:   params.RewardFactorEpochSigned,
+		RewardFactorBlockProposed: params.RewardFactorBlockProposed,
+		TotalEscrow:               *totalEscrow.Clone(),
+		CommonPool:                *commonPool.Clone(),
+	}
+
+	escrow := totalEscrow.Clone()
+	pool := commonPool.Clone()
+	for i := 1; i <= numEpochs; i++ {
+		projection := RewardProjection{
+			Epoch:  epoch + beacon.EpochTime(i),
+			Escrow: *escrow.Clone(),
+		}
+		step := params.ActiveRewardStep(projection.Epoch)
+		if step == nil {
+			// The schedule has ended, no more rewards will be distributed.
+			break
+		}
+		projection.Scale = step.Scale
+
+		amount, err := RewardAmount(escrow, &params.RewardFactorEpochSigned, &step.Scale)
+		if err != nil {
+			return nil, err
+		}
+		// Rewards are limited by the common pool balance.
+		moved, err := quantity.MoveUpTo(escrow, pool, amount)
+		if err != nil {
+			return nil, fmt.Errorf("staking: failed to move projected rewards: %w", err)
+		}
+		projection.Amount = *moved
+
+		rs.Projection = append(rs.Projection, projection)
+	}
+
+	return &rs, nil
+}
+
+// ActiveRewardStep returns the reward schedule step active at the given epoch or nil if the
+// reward schedule has ended.
+func (p *ConsensusParameters) ActiveRewardStep(epoch beacon.EpochTime) *RewardStep {
+	for i := range p.RewardSchedule {
+		if epoch < p.RewardSchedule[i].Until {
+			return &p.RewardSchedule[i]
+		}
+	}
+	return nil
+}
+
+// RewardAmount computes the reward for the given escrow balance using the given reward factor and
+// reward schedule scale.
+//
+// This is the amount the reward distribution adds to an escrow account before the commission is
+// split off and before it is limited by the common pool balance.
+func RewardAmount(escrow, factor, scale *quantity.Quantity) (*quantity.Quantity, error) {
+	numerator := factor.Clone()
+	if err := numerator.Mul(scale); err != nil {
+		return nil, fmt.Errorf("staking: failed multiplying by reward scale: %w", err)
+	}
+	amount, err := escrow.MulQuo(numerator, RewardAmountDenominator)
+	if err != nil {
+		return nil, fmt.Errorf("staking: failed computing reward amount: %w", err)
+	}
+	return amount, nil
+}
+
+// RewardCommission computes the part of the given reward that goes to the account owner as
+// commission under the given commission rate. A nil rate means no commission.
+func RewardCommission(reward, rate *quantity.Quantity) (*quantity.Quantity, error) {
+	if rate == nil {
+		return quantity.NewQuantity(), nil
+	}
+	commission, err := reward.MulQuo(rate, CommissionRateDenominator)
+	if err != nil {
+		return nil, fmt.Errorf("staking: failed computing commission: %w", err)
+	}
+	return commission, nil
+}
+
+// EpochReward is the reward distributed to an escrow account at an epoch.
+type EpochReward struct {
+	// Amount is the total amount added to the escrow account, including the commission.
+	Amount quantity.Quantity `json:"amount"`
+	// Commission is the part of the amount that went to the account owner as commission.
+	Commission quantity.Quantity `json:"commission"`
+}
+
+// EpochRewards are the rewards distributed at an epoch.
+//
+// They are recorded by the reward distribution and retained for EpochRewardsRetention epochs.
+type EpochRewards struct {
+	// Epoch is the epoch at which the rewards were distributed.
+	Epoch beacon.EpochTime `json:"epoch"`
+	// Rewards are the rewards distributed to each escrow account.
+	Rewards map[Address]*EpochReward `json:"rewards,omitempty"`
+}
+
+// NewEpochRewards creates new empty epoch rewards for the given epoch.
+func NewEpochRewards(epoch beacon.EpochTime) *EpochRewards {
+	return &EpochRewards{
+		Epoch:   epoch,
+		Rewards: make(map[Address]*EpochReward),
+	}
+}
+
+// Add records a reward distributed to the given escrow account.
+func (r *EpochRewards) Add(addr Address, amount, commission *quantity.Quantity) error {
+	if commission.Cmp(amount) > 0 {
+		return fmt.Errorf("staking: commission exceeds reward amount")
+	}
+	if amount.IsZero() {
+		return nil
+	}
+
+	reward, ok := r.Rewards[addr]
+	if !ok {
+		reward = &EpochReward{}
+		r.Rewards[addr] = reward
+	}
+	if err := reward.Amount.Add(amount); err != nil {
+		return fmt.Errorf("staking: failed to add reward amount: %w", err)
+	}
+	if err := reward.Commission.Add(commission); err != nil {
+		return fmt.Errorf("staking: failed to add reward commission: %w", err)
+	}
+	return nil
+}
+
+// Total returns the total amount of distributed rewards.
+func (r *EpochRewards) Total() (*quantity.Quantity, error) {
+	total := quantity.NewQuantity()
+	for _, reward := range r.Rewards {
+		if err := total.Add(&reward.Amount); err != nil {
+			return nil, fmt.Errorf("staking: failed to add reward amount: %w", err)
+		}
+	}
+	return total, nil
+}
+
+// EpochRewardsRetained returns true iff the rewards distributed at the given epoch are still
+// retained at the current epoch.
+func EpochRewardsRetained(current, epoch beacon.EpochTime) bool {
+	if epoch > current {
+		return false
+	}
+	return current-epoch < EpochRewardsRetention
+}
+
 func init() {
 	// Denominated in one millionth of a percent.
 	RewardAmountDenominator = quantity.NewQuantity()
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+)
+
+// distributeEpochRewards distributes epoch rewards to the given escrow accounts the same way as
+// the reward distribution does and records them.
+func distributeEpochRewards(
+	t *testing.T,
+	params *ConsensusParameters,
+	epoch beacon.EpochTime,
+	accounts map[Address]*Account,
+	commonPool *quantity.Quantity,
+) *EpochRewards {
+	require := require.New(t)
+
+	rewards := NewEpochRewards(epoch)
+	step := params.ActiveRewardStep(epoch)
+	if step == nil {
+		return rewards
+	}
+	for addr, acct := range accounts {
+		amount, err := RewardAmount(&acct.Escrow.Active.Balance, &params.RewardFactorEpochSigned, &step.Scale)
+		require.NoError(err, "RewardAmount")
+		commission, err := RewardCommission(amount, acct.Escrow.CommissionSchedule.CurrentRate(epoch))
+		require.NoError(err, "RewardCommission")
+
+		moved, err := quantity.MoveUpTo(&acct.Escrow.Active.Balance, commonPool, amount)
+		require.NoError(err, "MoveUpTo")
+		if moved.Cmp(commission) < 0 {
+			commission = moved.Clone()
+		}
+		require.NoError(rewards.Add(addr, moved, commission), "Add")
+	}
+	return rewards
+}
+
+func TestRewardSchedule(t *testing.T) {
+	require := require.New(t)
+
+	params := ConsensusParameters{
+		RewardSchedule: []RewardStep{
+			{Until: 12, Scale: mustInitQuantity(t, 2_000_000)},
+			{Until: 13, Scale: mustInitQuantity(t, 1_000_000)},
+		},
+		RewardFactorEpochSigned:   mustInitQuantity(t, 1),
+		RewardFactorBlockProposed: mustInitQuantity(t, 0),
+	}
+
+	require.Equal(&params.RewardSchedule[0], params.ActiveRewardStep(0), "first step")
+	require.Equal(&params.RewardSchedule[0], params.ActiveRewardStep(11), "first step end")
+	require.Equal(&params.RewardSchedule[1], params.ActiveRewardStep(12), "second step")
+	require.Nil(params.ActiveRewardStep(13), "schedule end")
+
+	addr1 := NewAddress(signature.NewPublicKey("aaafffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"))
+	addr2 := NewAddress(signature.NewPublicKey("bbbfffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"))
+	newAccounts := func() map[Address]*Account {
+		acct1 := &Account{}
+		acct1.Escrow.Active.Balance = mustInitQuantity(t, 1_000_000_000)
+		acct1.Escrow.CommissionSchedule.Rates = []CommissionRateStep{
+			{Start: 0, Rate: mustInitQuantity(t, 10_000)},
+		}
+		acct2 := &Account{}
+		acct2.Escrow.Active.Balance = mustInitQuantity(t, 500_000_000)
+		return map[Address]*Account{addr1: acct1, addr2: acct2}
+	}
+
+	// Projection with a sufficient common pool.
+	rs, err := NewRewardSchedule(&params, 42, 10, mustInitQuantityP(t, 1_500_000_000), mustInitQuantityP(t, 1_000_000_000), RewardProjectionEpochs)
+	require.NoError(err, "NewRewardSchedule")
+	require.EqualValues(42, rs.Height)
+	require.EqualValues(10, rs.Epoch)
+	require.Equal(params.RewardSchedule, rs.Schedule)
+	require.Len(rs.Projection, 2, "projection should stop at the schedule end")
+	require.EqualValues(11, rs.Projection[0].Epoch)
+	require.Equal(mustInitQuantity(t, 2_000_000), rs.Projection[0].Scale)
+	require.Equal(mustInitQuantity(t, 1_500_000_000), rs.Projection[0].Escrow)
+	require.Equal(mustInitQuantity(t, 30_000_000), rs.Projection[0].Amount)
+	require.EqualValues(12, rs.Projection[1].Epoch)
+	require.Equal(mustInitQuantity(t, 1_530_000_000), rs.Projection[1].Escrow, "rewards should compound")
+	require.Equal(mustInitQuantity(t, 15_300_000), rs.Projection[1].Amount)
+
+	// The projection should match the actual distribution.
+	accounts := newAccounts()
+	commonPool := mustInitQuantityP(t, 1_000_000_000)
+	for _, projection := range rs.Projection {
+		rewards := distributeEpochRewards(t, &params, projection.Epoch, accounts, commonPool)
+		require.EqualValues(projection.Epoch, rewards.Epoch)
+		total, err := rewards.Total()
+		require.NoError(err, "Total")
+		require.Equal(projection.Amount, *total, "projected rewards at epoch %d", projection.Epoch)
+	}
+
+	// Recorded per-entity values.
+	accounts = newAccounts()
+	rewards := distributeEpochRewards(t, &params, 11, accounts, mustInitQuantityP(t, 1_000_000_000))
+	require.Len(rewards.Rewards, 2)
+	require.Equal(mustInitQuantity(t, 20_000_000), rewards.Rewards[addr1].Amount)
+	require.Equal(mustInitQuantity(t, 2_000_000), rewards.Rewards[addr1].Commission)
+	require.Equal(mustInitQuantity(t, 10_000_000), rewards.Rewards[addr2].Amount)
+	require.True(rewards.Rewards[addr2].Commission.IsZero())
+	require.Empty(distributeEpochRewards(t, &params, 13, newAccounts(), mustInitQuantityP(t, 1_000_000_000)).Rewards,
+		"no rewards after the schedule end")
+
+	// Projection limited by the common pool.
+	rs, err = NewRewardSchedule(&params, 42, 10, mustInitQuantityP(t, 1_500_000_000), mustInitQuantityP(t, 40_000_000), RewardProjectionEpochs)
+	require.NoError(err, "NewRewardSchedule")
+	require.Len(rs.Projection, 2)
+	require.Equal(mustInitQuantity(t, 30_000_000), rs.Projection[0].Amount)
+	require.Equal(mustInitQuantity(t, 10_000_000), rs.Projection[1].Amount, "rewards should be limited by the common pool")
+
+	// The projection is an upper bound when per-account rewards are rounded down.
+	accounts = make(map[Address]*Account)
+	for _, pk := range []string{
+		"aaafffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"bbbfffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"cccfffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+	} {
+		acct := &Account{}
+		acct.Escrow.Active.Balance = mustInitQuantity(t, 99)
+		accounts[NewAddress(signature.NewPublicKey(pk))] = acct
+	}
+	rs, err = NewRewardSchedule(&params, 42, 11, mustInitQuantityP(t, 297), mustInitQuantityP(t, 1_000), 1)
+	require.NoError(err, "NewRewardSchedule")
+	require.Equal(mustInitQuantity(t, 2), rs.Projection[0].Amount)
+	rewards = distributeEpochRewards(t, &params, 12, accounts, mustInitQuantityP(t, 1_000))
+	total, err := rewards.Total()
+	require.NoError(err, "Total")
+	require.True(total.Cmp(&rs.Projection[0].Amount) <= 0, "projection should be an upper bound")
+}
+
+func TestEpochRewards(t *testing.T) {
+	require := require.New(t)
+
+	addr := NewAddress(signature.NewPublicKey("aaafffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"))
+	rewards := NewEpochRewards(5)
+	require.Error(rewards.Add(addr, mustInitQuantityP(t, 10), mustInitQuantityP(t, 11)), "commission exceeding amount")
+	require.NoError(rewards.Add(addr, mustInitQuantityP(t, 0), mustInitQuantityP(t, 0)))
+	require.Empty(rewards.Rewards, "zero rewards should not be recorded")
+
+	require.NoError(rewards.Add(addr, mustInitQuantityP(t, 10), mustInitQuantityP(t, 1)))
+	require.NoError(rewards.Add(addr, mustInitQuantityP(t, 5), mustInitQuantityP(t, 2)))
+	require.Equal(mustInitQuantity(t, 15), rewards.Rewards[addr].Amount)
+	require.Equal(mustInitQuantity(t, 3), rewards.Rewards[addr].Commission)
+
+	require.True(EpochRewardsRetained(10, 10))
+	require.True(EpochRewardsRetained(EpochRewardsRetention, 1))
+	require.False(EpochRewardsRetained(EpochRewardsRetention, 0))
+	require.False(EpochRewardsRetained(10, 11), "future epochs are not retained")
+}
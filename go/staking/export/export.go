@@ -0,0 +1,234 @@
+// Package export implements exporting of staking events in tabular formats.
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/tabular"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+// DefaultBatchSize is the default number of heights exported between flushes.
+const DefaultBatchSize = 100
+
+// ErrNotRetained is the error returned when the requested heights are no longer retained by the
+// node.
+var ErrNotRetained = errors.New("export: height not retained")
+
+// EventRow is a staking event row.
+//
+// NOTE: The columns are a stable schema, only append new columns.
+type EventRow struct {
+	Height int64     `parquet:"height"`
+	Time   time.Time `parquet:"time"`
+	Kind   string    `parquet:"kind"`
+	From   string    `parquet:"from"`
+	To     string    `parquet:"to,optional"`
+	Amount string    `parquet:"amount"`
+	Shares string    `parquet:"shares,optional"`
+	TxHash string    `parquet:"tx_hash,optional"`
+}
+
+// Columns implements tabular.Row.
+func (r EventRow) Columns() []string {
+	return []string{"height", "time", "kind", "from", "to", "amount", "shares", "tx_hash"}
+}
+
+// Values implements tabular.Row.
+func (r EventRow) Values() []string {
+	return []string{
+		strconv.FormatInt(r.Height, 10),
+		r.Time.UTC().Format(time.RFC3339),
+		r.Kind,
+		r.From,
+		r.To,
+		r.Amount,
+		r.Shares,
+		r.TxHash,
+	}
+}
+
+// NewEventRow converts a staking event emitted in a block with the given time to a row.
+func NewEventRow(ev *staking.Event, blockTime time.Time) (*EventRow, error) {
+	row := EventRow{
+		Height: ev.Height,
+		Time:   blockTime,
+	}
+	if !ev.TxHash.Equal(&hash.Hash{}) {
+		row.TxHash = ev.TxHash.String()
+	}
+
+	switch {
+	case ev.Transfer != nil:
+		row.Kind = ev.Transfer.EventKind()
+		row.From = ev.Transfer.From.String()
+		row.To = ev.Transfer.To.String()
+		row.Amount = ev.Transfer.Amount.String()
+	case ev.Burn != nil:
+		row.Kind = ev.Burn.EventKind()
+		row.From = ev.Burn.Owner.String()
+		row.Amount = ev.Burn.Amount.String()
+	case ev.Escrow != nil && ev.Escrow.Add != nil:
+		e := ev.Escrow.Add
+		row.Kind = e.EventKind()
+		row.From = e.Owner.String()
+		row.To = e.Escrow.String()
+		row.Amount = e.Amount.String()
+		row.Shares = e.NewShares.String()
+	case ev.Escrow != nil && ev.Escrow.Take != nil:
+		e := ev.Escrow.Take
+		row.Kind = e.EventKind()
+		row.From = e.Owner.String()
+		row.Amount = e.Amount.String()
+	case ev.Escrow != nil && ev.Escrow.DebondingStart != nil:
+		e := ev.Escrow.DebondingStart
+		row.Kind = e.EventKind()
+		row.From = e.Escrow.String()
+		row.To = e.Owner.String()
+		row.Amount = e.Amount.String()
+		row.Shares = e.ActiveShares.String()
+	case ev.Escrow != nil && ev.Escrow.Reclaim != nil:
+		e := ev.Escrow.Reclaim
+		row.Kind = e.EventKind()
+		row.From = e.Escrow.String()
+		row.To = e.Owner.String()
+		row.Amount = e.Amount.String()
+		row.Shares = e.Shares.String()
+	case ev.AllowanceChange != nil:
+		e := ev.AllowanceChange
+		row.Kind = e.EventKind()
+		row.From = e.Owner.String()
+		row.To = e.Beneficiary.String()
+		row.Amount = e.AmountChange.String()
+		if e.Negative {
+			row.Amount = "-" + row.Amount
+		}
+	default:
+		return nil, fmt.Errorf("export: unsupported staking event at height %d", ev.Height)
+	}
+	return &row, nil
+}
+
+// Source is the source of staking events.
+type Source interface {
+	// GetStatus returns the consensus status.
+	GetStatus(ctx context.Context) (*consensus.Status, error)
+
+	// GetBlock returns the consensus block at the given height.
+	GetBlock(ctx context.Context, height int64) (*consensus.Block, error)
+
+	// GetEvents returns the staking events at the given height.
+	GetEvents(ctx context.Context, height int64) ([]*staking.Event, error)
+}
+
+// Config is the export configuration.
+type Config struct {
+	// StartHeight is the first height to export.
+	StartHeight int64
+
+	// EndHeight is the last height to export.
+	EndHeight int64
+
+	// BatchSize is the number of heights exported between flushes. If zero, DefaultBatchSize is
+	// used.
+	BatchSize int64
+
+	// OnFlush is called after the writer is flushed with the next height to export. It can be
+	// used to persist a cursor for resuming the export.
+	OnFlush func(nextHeight int64) error
+}
+
+// Export exports staking events in the configured height range to the given writer.
+func Export(ctx context.Context, src Source, w tabular.Writer[EventRow], cfg *Config) error {
+	if cfg.EndHeight < cfg.StartHeight {
+		return fmt.Errorf("export: end height %d is before start height %d", cfg.EndHeight, cfg.StartHeight)
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if err := checkRetained(ctx, src, cfg.StartHeight); err != nil {
+		return err
+	}
+
+	flush := func(nextHeight int64) error {
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("export: failed to flush: %w", err)
+		}
+		if cfg.OnFlush != nil {
+			return cfg.OnFlush(nextHeight)
+		}
+		return nil
+	}
+
+	var rows []EventRow
+	for height := cfg.StartHeight; height <= cfg.EndHeight; height++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		heightRows, err := exportHeight(ctx, src, height)
+		if err != nil {
+			// The height may have been pruned while exporting.
+			if rerr := checkRetained(ctx, src, height); rerr != nil {
+				return rerr
+			}
+			return err
+		}
+		rows = append(rows, heightRows...)
+
+		if (height-cfg.StartHeight+1)%batchSize != 0 && height != cfg.EndHeight {
+			continue
+		}
+		if err = w.Write(rows); err != nil {
+			return fmt.Errorf("export: failed to write rows: %w", err)
+		}
+		if err = flush(height + 1); err != nil {
+			return err
+		}
+		rows = rows[:0]
+	}
+	return nil
+}
+
+func exportHeight(ctx context.Context, src Source, height int64) ([]EventRow, error) {
+	events, err := src.GetEvents(ctx, height)
+	if err != nil {
+		return nil, fmt.Errorf("export: failed to get events at height %d: %w", height, err)
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	blk, err := src.GetBlock(ctx, height)
+	if err != nil {
+		return nil, fmt.Errorf("export: failed to get block at height %d: %w", height, err)
+	}
+
+	rows := make([]EventRow, 0, len(events))
+	for _, ev := range events {
+		row, err := NewEventRow(ev, blk.Time)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, *row)
+	}
+	return rows, nil
+}
+
+func checkRetained(ctx context.Context, src Source, height int64) error {
+	status, err := src.GetStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("export: failed to get consensus status: %w", err)
+	}
+	if height < status.LastRetainedHeight {
+		return fmt.Errorf("%w: height %d (last retained height: %d)", ErrNotRetained, height, status.LastRetainedHeight)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/quantity"
+	"github.com/oasisprotocol/oasis-core/go/common/tabular"
+	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	staking "github.com/oasisprotocol/oasis-core/go/staking/api"
+)
+
+var (
+	testAddrA = staking.NewModuleAddress("test", "a")
+	testAddrB = staking.NewModuleAddress("test", "b")
+
+	testGenesisTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+type testSource struct {
+	lastRetainedHeight int64
+	failHeight         int64
+}
+
+func (s *testSource) GetStatus(context.Context) (*consensus.Status, error) {
+	return &consensus.Status{LastRetainedHeight: s.lastRetainedHeight}, nil
+}
+
+func (s *testSource) GetBlock(_ context.Context, height int64) (*consensus.Block, error) {
+	return &consensus.Block{
+		Height: height,
+		Time:   testGenesisTime.Add(time.Duration(height) * time.Minute),
+	}, nil
+}
+
+func (s *testSource) GetEvents(_ context.Context, height int64) ([]*staking.Event, error) {
+	if height == s.failHeight {
+		return nil, fmt.Errorf("injected failure")
+	}
+
+	// Emit events at even heights only.
+	if height%2 != 0 {
+		return nil, nil
+	}
+	return []*staking.Event{
+		{
+			Height: height,
+			Transfer: &staking.TransferEvent{
+				From:   testAddrA,
+				To:     testAddrB,
+				Amount: *quantity.NewFromUint64(uint64(height)),
+			},
+		},
+		{
+			Height: height,
+			Escrow: &staking.EscrowEvent{
+				Add: &staking.AddEscrowEvent{
+					Owner:     testAddrA,
+					Escrow:    testAddrB,
+					Amount:    *quantity.NewFromUint64(100),
+					NewShares: *quantity.NewFromUint64(10),
+				},
+			},
+		},
+	}, nil
+}
+
+func TestExport(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	// Export the full range.
+	src := &testSource{lastRetainedHeight: 1}
+	var full bytes.Buffer
+	err := Export(ctx, src, tabular.NewCSVWriter[EventRow](&full, true), &Config{
+		StartHeight: 1,
+		EndHeight:   10,
+		BatchSize:   3,
+	})
+	require.NoError(err, "Export")
+
+	records, err := csv.NewReader(bytes.NewReader(full.Bytes())).ReadAll()
+	require.NoError(err, "ReadAll")
+	require.Len(records, 11, "header and two rows for each even height")
+	require.Equal([]string{"height", "time", "kind", "from", "to", "amount", "shares", "tx_hash"}, records[0])
+	require.Equal([]string{
+		"2", "2024-01-01T00:02:00Z", "transfer", testAddrA.String(), testAddrB.String(), "2", "", "",
+	}, records[1])
+	require.Equal([]string{
+		"2", "2024-01-01T00:02:00Z", "add_escrow", testAddrA.String(), testAddrB.String(), "100", "10", "",
+	}, records[2])
+
+	// Interrupt an export and resume it from the cursor.
+	var (
+		resumed bytes.Buffer
+		cursor  *tabular.Cursor
+	)
+	onFlush := func(nextHeight int64) error {
+		cursor = &tabular.Cursor{Next: nextHeight, Offset: int64(resumed.Len())}
+		return nil
+	}
+
+	src.failHeight = 8
+	err = Export(ctx, src, tabular.NewCSVWriter[EventRow](&resumed, true), &Config{
+		StartHeight: 1,
+		EndHeight:   10,
+		BatchSize:   3,
+		OnFlush:     onFlush,
+	})
+	require.Error(err, "Export should fail")
+	require.NotNil(cursor, "cursor should be saved")
+	require.EqualValues(7, cursor.Next)
+
+	// Discard anything written after the last flush.
+	resumed.Truncate(int(cursor.Offset))
+
+	src.failHeight = 0
+	err = Export(ctx, src, tabular.NewCSVWriter[EventRow](&resumed, false), &Config{
+		StartHeight: cursor.Next,
+		EndHeight:   10,
+		BatchSize:   3,
+		OnFlush:     onFlush,
+	})
+	require.NoError(err, "Export (resumed)")
+	require.EqualValues(11, cursor.Next)
+	require.Equal(full.String(), resumed.String(), "resumed export should match full export")
+
+	// Heights that are no longer retained should be rejected.
+	src.lastRetainedHeight = 5
+	err = Export(ctx, src, tabular.NewCSVWriter[EventRow](&bytes.Buffer{}, true), &Config{
+		StartHeight: 1,
+		EndHeight:   10,
+	})
+	require.True(errors.Is(err, ErrNotRetained), "Export should fail with ErrNotRetained")
+}
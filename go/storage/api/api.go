@@ -3,6 +3,7 @@ package api
 
 import (
 	"context"
+	"time"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
@@ -94,18 +95,66 @@ type Config struct { // nolint: maligned
 
 	// ReadOnly will make the storage read-only.
 	ReadOnly bool
+
+	// RootCacheVersions is the number of most recent versions for which the set of existing roots
+	// is kept in memory (if the backend supports it).
+	RootCacheVersions uint64
+
+	// NodeCacheSize is the maximum size (in bytes) of the in-memory cache of unmarshalled nodes
+	// (if the backend supports it).
+	NodeCacheSize uint64
+
+	// AllowResumeMultipart will keep the state of an interrupted multipart restore on open so
+	// that the restore can be resumed (if the backend supports it).
+	AllowResumeMultipart bool
+
+	// MetricsEnabled will make the node database report metrics (if the backend supports it).
+	MetricsEnabled bool
+
+	// TombstoneRetentionVersions is the number of versions for which tombstones of deleted keys
+	// are retained (if the backend supports it).
+	TombstoneRetentionVersions uint64
+
+	// BadgerOptions are optional tuning options (if the backend is based on badger).
+	BadgerOptions *nodedb.BadgerOptions
+
+	// VerifyNodeHashes will make the node database verify the hashes of nodes read from and
+	// written to disk (if the backend supports it).
+	VerifyNodeHashes bool
+
+	// CorruptionErrorThreshold is the number of corruption-class errors within the corruption
+	// error window after which the node database reports itself as unhealthy (if the backend
+	// supports it). Zero disables health reporting based on errors.
+	CorruptionErrorThreshold uint64
+
+	// CorruptionErrorWindow is the window over which corruption-class errors are counted.
+	CorruptionErrorWindow time.Duration
+
+	// AllowRepair will allow inserting roots into already finalized versions (if the backend
+	// supports it).
+	AllowRepair bool
 }
 
 // ToNodeDB converts from a Config to a node DB Config.
 func (cfg *Config) ToNodeDB() *nodedb.Config {
 	return &nodedb.Config{
-		DB:               cfg.DB,
-		Namespace:        cfg.Namespace,
-		MaxCacheSize:     cfg.MaxCacheSize,
-		NoFsync:          cfg.NoFsync,
-		MemoryOnly:       cfg.MemoryOnly,
-		ReadOnly:         cfg.ReadOnly,
-		DiscardWriteLogs: cfg.DiscardWriteLogs,
+		DB:                         cfg.DB,
+		Namespace:                  cfg.Namespace,
+		MaxCacheSize:               cfg.MaxCacheSize,
+		NoFsync:                    cfg.NoFsync,
+		MemoryOnly:                 cfg.MemoryOnly,
+		ReadOnly:                   cfg.ReadOnly,
+		DiscardWriteLogs:           cfg.DiscardWriteLogs,
+		RootCacheVersions:          cfg.RootCacheVersions,
+		NodeCacheSize:              cfg.NodeCacheSize,
+		AllowResumeMultipart:       cfg.AllowResumeMultipart,
+		MetricsEnabled:             cfg.MetricsEnabled,
+		TombstoneRetentionVersions: cfg.TombstoneRetentionVersions,
+		BadgerOptions:              cfg.BadgerOptions,
+		VerifyNodeHashes:           cfg.VerifyNodeHashes,
+		CorruptionErrorThreshold:   cfg.CorruptionErrorThreshold,
+		CorruptionErrorWindow:      cfg.CorruptionErrorWindow,
+		AllowRepair:                cfg.AllowRepair,
 	}
 }
 
@@ -186,6 +235,30 @@ type ApplyRequest struct {
 	DstRound  uint64           `json:"dst_round"`
 	DstRoot   hash.Hash        `json:"dst_root"`
 	WriteLog  WriteLog         `json:"writelog"`
+
+	// WriteLogAnnotations are optional write log annotations carrying per-entry checksums that
+	// are verified before the write log is applied.
+	WriteLogAnnotations writelog.Annotations `json:"-"`
+}
+
+// ApplyBatchRequest is an ApplyBatch request.
+type ApplyBatchRequest struct {
+	Namespace common.Namespace `json:"namespace"`
+	DstRound  uint64           `json:"dst_round"`
+	Ops       []ApplyOp        `json:"ops"`
+}
+
+// ApplyOp is an apply operation within an ApplyBatch request.
+type ApplyOp struct {
+	RootType RootType  `json:"root_type"`
+	SrcRound uint64    `json:"src_round"`
+	SrcRoot  hash.Hash `json:"src_root"`
+	DstRoot  hash.Hash `json:"dst_root"`
+	WriteLog WriteLog  `json:"writelog"`
+
+	// WriteLogAnnotations are optional write log annotations carrying per-entry checksums that
+	// are verified before the write log is applied.
+	WriteLogAnnotations writelog.Annotations `json:"-"`
 }
 
 // SyncOptions are the sync options.
@@ -235,6 +308,11 @@ type LocalBackend interface {
 	// Apply is ignored.
 	Apply(ctx context.Context, request *ApplyRequest) error
 
+	// ApplyBatch applies multiple sets of operations against the MKVS, one for each root (e.g.,
+	// the I/O and state roots of a round). Either all of the new roots are committed or none of
+	// them is. The new roots are returned in the same order as the operations.
+	ApplyBatch(ctx context.Context, request *ApplyBatchRequest) ([]hash.Hash, error)
+
 	// Checkpointer returns the checkpoint creator/restorer for this storage backend.
 	Checkpointer() checkpoint.CreateRestorer
 
@@ -7,6 +7,7 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
 )
@@ -49,6 +50,7 @@ var (
 	}
 
 	labelApply           = prometheus.Labels{"call": "apply"}
+	labelApplyBatch      = prometheus.Labels{"call": "apply_batch"}
 	labelSyncGet         = prometheus.Labels{"call": "sync_get"}
 	labelSyncGetPrefixes = prometheus.Labels{"call": "sync_get_prefixes"}
 	labelSyncIterate     = prometheus.Labels{"call": "sync_iterate"}
@@ -136,6 +138,27 @@ func (w *metricsWrapper) Apply(ctx context.Context, request *ApplyRequest) error
 	return nil
 }
 
+func (w *metricsWrapper) ApplyBatch(ctx context.Context, request *ApplyBatchRequest) ([]hash.Hash, error) {
+	start := time.Now()
+	newRoots, err := w.Backend.(LocalBackend).ApplyBatch(ctx, request)
+	storageLatency.With(labelApplyBatch).Observe(time.Since(start).Seconds())
+
+	var size int
+	for _, op := range request.Ops {
+		for _, entry := range op.WriteLog {
+			size += len(entry.Key) + len(entry.Value)
+		}
+	}
+	storageValueSize.With(labelApplyBatch).Observe(float64(size))
+	if err != nil {
+		storageFailures.With(labelApplyBatch).Inc()
+		return nil, err
+	}
+
+	storageCalls.With(labelApplyBatch).Inc()
+	return newRoots, nil
+}
+
 func (w *localMetricsWrapper) Checkpointer() checkpoint.CreateRestorer {
 	return w.Backend.(LocalBackend).Checkpointer()
 }
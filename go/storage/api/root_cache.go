@@ -2,6 +2,8 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
@@ -58,6 +60,96 @@ func (rc *RootCache) Apply(
 	return &r, nil
 }
 
+// ApplyBatch applies multiple write logs so that either all of the expected new roots are
+// committed to the node database or none of them is. Applying write logs whose expected new root
+// already is in the node database is bypassed.
+//
+// In case more than one root needs to be committed, the node database must support committing
+// multiple roots together as otherwise ErrUnsupported is returned. All of the roots are then
+// persisted by a single commit, which only takes effect after all of them have been computed and
+// verified.
+func (rc *RootCache) ApplyBatch(
+	ctx context.Context,
+	roots []Root,
+	expectedNewRoots []Root,
+	writeLogs []WriteLog,
+) ([]hash.Hash, error) {
+	if len(roots) != len(expectedNewRoots) || len(roots) != len(writeLogs) {
+		return nil, fmt.Errorf("storage: malformed batch (roots: %d expected: %d write logs: %d)",
+			len(roots), len(expectedNewRoots), len(writeLogs),
+		)
+	}
+	if !sameVersion(expectedNewRoots) {
+		return nil, fmt.Errorf("storage: malformed batch (new roots of different versions)")
+	}
+
+	newRoots := make([]hash.Hash, 0, len(roots))
+	var toApply []int
+	for i := range roots {
+		// Sanity check the expected new root.
+		if !expectedNewRoots[i].Follows(&roots[i]) {
+			return nil, ErrRootMustFollowOld
+		}
+		newRoots = append(newRoots, expectedNewRoots[i].Hash)
+
+		// Check if we already have the expected new root in our local DB.
+		if rc.localDB.HasRoot(expectedNewRoots[i]) {
+			continue
+		}
+		toApply = append(toApply, i)
+	}
+
+	// Defer the commits of all trees so that the roots are committed together once all of them
+	// have been verified.
+	treeDB := rc.localDB
+	var deferredDB *nodedb.DeferredCommitNodeDB
+	if len(toApply) > 1 {
+		mdb, ok := rc.localDB.(nodedb.MultiCommitNodeDB)
+		if !ok {
+			return nil, ErrUnsupported
+		}
+		deferredDB = nodedb.NewDeferredCommitNodeDB(mdb)
+		defer deferredDB.Discard()
+		treeDB = deferredDB
+	}
+
+	// Apply operations for the roots that we don't have yet.
+	for _, i := range toApply {
+		tree := mkvs.NewWithRoot(nil, treeDB, roots[i])
+		defer tree.Close()
+
+		if err := tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(writeLogs[i])); err != nil {
+			return nil, err
+		}
+
+		_, err := tree.CommitKnown(ctx, expectedNewRoots[i])
+		switch {
+		case err == nil:
+		case errors.Is(err, mkvs.ErrKnownRootMismatch):
+			return nil, ErrExpectedRootMismatch
+		default:
+			return nil, err
+		}
+	}
+	if deferredDB != nil {
+		if err := deferredDB.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	return newRoots, nil
+}
+
+// sameVersion returns true iff all of the given roots are of the same version.
+func sameVersion(roots []Root) bool {
+	for _, root := range roots {
+		if root.Version != roots[0].Version {
+			return false
+		}
+	}
+	return true
+}
+
 func (rc *RootCache) HasRoot(root Root) bool {
 	return rc.localDB.HasRoot(root)
 }
@@ -10,10 +10,12 @@ import (
 	"slices"
 	"time"
 
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/storage/api"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db"
 	dbApi "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
 )
 
 const (
@@ -23,6 +25,8 @@ const (
 	BackendNameBadgerDB = "badger"
 	// BackendNamePathBadger is the name of the PathBadger database backend.
 	BackendNamePathBadger = "pathbadger"
+	// BackendNamePebble is the name of the Pebble database backend.
+	BackendNamePebble = "pebble"
 
 	// defaultBackendName is the default backend in case automatic backend detection is enabled and
 	// no previous backend exists.
@@ -143,6 +147,9 @@ func (ba *databaseBackend) Apply(ctx context.Context, request *api.ApplyRequest)
 	if ba.readOnly {
 		return fmt.Errorf("storage/database: failed to Apply: %w", api.ErrReadOnly)
 	}
+	if err := writelog.VerifyChecksums(request.WriteLog, request.WriteLogAnnotations); err != nil {
+		return fmt.Errorf("storage/database: failed to Apply: %w", err)
+	}
 
 	oldRoot := api.Root{
 		Namespace: request.Namespace,
@@ -168,6 +175,47 @@ func (ba *databaseBackend) Apply(ctx context.Context, request *api.ApplyRequest)
 	return nil
 }
 
+// Implements api.LocalBackend.
+func (ba *databaseBackend) ApplyBatch(ctx context.Context, request *api.ApplyBatchRequest) ([]hash.Hash, error) {
+	if ba.readOnly {
+		return nil, fmt.Errorf("storage/database: failed to ApplyBatch: %w", api.ErrReadOnly)
+	}
+
+	oldRoots := make([]api.Root, 0, len(request.Ops))
+	expectedNewRoots := make([]api.Root, 0, len(request.Ops))
+	writeLogs := make([]api.WriteLog, 0, len(request.Ops))
+	for _, op := range request.Ops {
+		if err := writelog.VerifyChecksums(op.WriteLog, op.WriteLogAnnotations); err != nil {
+			return nil, fmt.Errorf("storage/database: failed to ApplyBatch: %w", err)
+		}
+
+		oldRoots = append(oldRoots, api.Root{
+			Namespace: request.Namespace,
+			Version:   op.SrcRound,
+			Type:      op.RootType,
+			Hash:      op.SrcRoot,
+		})
+		expectedNewRoots = append(expectedNewRoots, api.Root{
+			Namespace: request.Namespace,
+			Version:   request.DstRound,
+			Type:      op.RootType,
+			Hash:      op.DstRoot,
+		})
+		writeLogs = append(writeLogs, op.WriteLog)
+	}
+
+	newRoots, err := ba.rootCache.ApplyBatch(
+		ctx,
+		oldRoots,
+		expectedNewRoots,
+		writeLogs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("storage/database: failed to ApplyBatch: %w", err)
+	}
+	return newRoots, nil
+}
+
 // Implements api.LocalBackend.
 func (ba *databaseBackend) Checkpointer() checkpoint.CreateRestorer {
 	return ba.checkpointer
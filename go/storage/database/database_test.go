@@ -1,6 +1,8 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -8,8 +10,13 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	genesisTestHelpers "github.com/oasisprotocol/oasis-core/go/genesis/tests"
 	"github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
+	dbApi "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
 	"github.com/oasisprotocol/oasis-core/go/storage/tests"
 )
 
@@ -140,3 +147,198 @@ func TestAutoBackend(t *testing.T) {
 		require.Equal(filepath.Join(tmpDir, DefaultFileName("pathbadger")), cfg.DB)
 	})
 }
+
+func TestApplyWriteLogChecksums(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	testNs := common.NewTestNamespaceFromSeed([]byte("database backend checksum test ns"), 0)
+	cfg := api.Config{
+		Backend:      BackendNameBadgerDB,
+		DB:           filepath.Join(t.TempDir(), DefaultFileName(BackendNameBadgerDB)),
+		Namespace:    testNs,
+		MaxCacheSize: 16 * 1024 * 1024,
+		NoFsync:      true,
+	}
+	impl, err := New(&cfg)
+	require.NoError(err, "New()")
+	defer impl.Cleanup()
+
+	wl := writelog.WriteLog{
+		{Key: []byte("key 1"), Value: []byte("value 1")},
+		{Key: []byte("key 2"), Value: []byte("value 2")},
+		{Key: []byte("key 3"), Value: []byte("value 3")},
+	}
+
+	// Compute the expected root.
+	tree := mkvs.New(nil, nil, api.RootTypeState)
+	defer tree.Close()
+	err = tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(wl))
+	require.NoError(err, "ApplyWriteLog()")
+	_, dstRoot, err := tree.Commit(ctx, testNs, 0)
+	require.NoError(err, "Commit()")
+
+	// Checksums are computed at the boundary.
+	anns := writelog.NewChecksumAnnotations(wl)
+
+	// Flip a byte between the boundary and apply.
+	corrupted := make(writelog.WriteLog, len(wl))
+	copy(corrupted, wl)
+	corrupted[1].Value = append([]byte{}, wl[1].Value...)
+	corrupted[1].Value[0] ^= 0xff
+
+	request := &api.ApplyRequest{
+		Namespace:           testNs,
+		RootType:            api.RootTypeState,
+		DstRoot:             dstRoot,
+		WriteLog:            corrupted,
+		WriteLogAnnotations: anns,
+	}
+	request.SrcRoot.Empty()
+
+	err = impl.Apply(ctx, request)
+	require.ErrorIs(err, writelog.ErrChecksumMismatch, "Apply() should fail on corrupted write log")
+	var mismatchErr *writelog.ChecksumMismatchError
+	require.True(errors.As(err, &mismatchErr))
+	require.Equal(1, mismatchErr.Index)
+	require.Equal([]byte("key 2"), mismatchErr.Key, "offending key should be reported")
+	require.False(impl.NodeDB().HasRoot(api.Root{
+		Namespace: testNs,
+		Type:      api.RootTypeState,
+		Hash:      dstRoot,
+	}), "corrupted write log should not be applied")
+
+	// Intact write log.
+	request.WriteLog = wl
+	err = impl.Apply(ctx, request)
+	require.NoError(err, "Apply()")
+
+	// Write logs without annotations are not verified.
+	request.WriteLogAnnotations = nil
+	err = impl.Apply(ctx, request)
+	require.NoError(err, "Apply() without annotations")
+}
+
+// failingCommitNodeDB is a node database whose multi-root commits fail with the given error.
+type failingCommitNodeDB struct {
+	dbApi.MultiCommitNodeDB
+
+	err error
+}
+
+func (d *failingCommitNodeDB) CommitMulti([]dbApi.Batch, []node.Root) error {
+	return d.err
+}
+
+func TestApplyBatch(t *testing.T) {
+	for _, v := range []string{
+		BackendNameBadgerDB,
+		BackendNamePathBadger,
+	} {
+		t.Run(v, func(t *testing.T) {
+			doTestApplyBatch(t, v)
+		})
+	}
+}
+
+func doTestApplyBatch(t *testing.T, backend string) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	testNs := common.NewTestNamespaceFromSeed([]byte("database backend apply batch test ns"), 0)
+	cfg := api.Config{
+		Backend:      backend,
+		DB:           filepath.Join(t.TempDir(), DefaultFileName(backend)),
+		Namespace:    testNs,
+		MaxCacheSize: 16 * 1024 * 1024,
+		NoFsync:      true,
+	}
+	impl, err := New(&cfg)
+	require.NoError(err, "New()")
+	defer impl.Cleanup()
+
+	ioWl := writelog.WriteLog{
+		{Key: []byte("io key"), Value: []byte("io value")},
+	}
+	stateWl := writelog.WriteLog{
+		{Key: []byte("state key 1"), Value: []byte("state value 1")},
+		{Key: []byte("state key 2"), Value: []byte("state value 2")},
+	}
+	ioRoot := tests.CalculateExpectedNewRoot(t, ioWl, testNs, 0)
+	stateRoot := tests.CalculateExpectedNewRoot(t, stateWl, testNs, 0)
+
+	newRequest := func(stateDstRoot hash.Hash) *api.ApplyBatchRequest {
+		request := &api.ApplyBatchRequest{
+			Namespace: testNs,
+			Ops: []api.ApplyOp{
+				{
+					RootType: api.RootTypeIO,
+					DstRoot:  ioRoot,
+					WriteLog: ioWl,
+				},
+				{
+					RootType: api.RootTypeState,
+					DstRoot:  stateDstRoot,
+					WriteLog: stateWl,
+				},
+			},
+		}
+		request.Ops[0].SrcRoot.Empty()
+		request.Ops[1].SrcRoot.Empty()
+		return request
+	}
+	hasRoot := func(rootType api.RootType, rootHash hash.Hash) bool {
+		return impl.NodeDB().HasRoot(api.Root{
+			Namespace: testNs,
+			Type:      rootType,
+			Hash:      rootHash,
+		})
+	}
+
+	// Node databases that cannot commit multiple roots together should refuse the batch.
+	mdb, ok := impl.NodeDB().(dbApi.MultiCommitNodeDB)
+	if !ok {
+		_, err = impl.ApplyBatch(ctx, newRequest(stateRoot))
+		require.ErrorIs(err, api.ErrUnsupported, "ApplyBatch() should fail without multi-root commits")
+		require.False(hasRoot(api.RootTypeIO, ioRoot), "I/O root should not be committed")
+		require.False(hasRoot(api.RootTypeState, stateRoot), "state root should not be committed")
+		return
+	}
+
+	// Fail the application of the second root, nothing should be committed.
+	_, err = impl.ApplyBatch(ctx, newRequest(hash.NewFromBytes([]byte("bogus state root"))))
+	require.ErrorIs(err, api.ErrExpectedRootMismatch, "ApplyBatch() should fail on mismatched root")
+	require.False(hasRoot(api.RootTypeIO, ioRoot), "I/O root should not be committed")
+
+	// Corrupt the second write log, nothing should be committed.
+	request := newRequest(stateRoot)
+	request.Ops[1].WriteLog = writelog.WriteLog{stateWl[0], {Key: stateWl[1].Key, Value: []byte("corrupted")}}
+	request.Ops[1].WriteLogAnnotations = writelog.NewChecksumAnnotations(stateWl)
+	_, err = impl.ApplyBatch(ctx, request)
+	require.ErrorIs(err, writelog.ErrChecksumMismatch, "ApplyBatch() should fail on corrupted write log")
+	require.False(hasRoot(api.RootTypeIO, ioRoot), "I/O root should not be committed")
+
+	// Fail persisting the roots after both have been verified, nothing should be committed.
+	backend := impl.(*databaseBackend)
+	rootCache := backend.rootCache
+	errPersist := errors.New("injected persist failure")
+	backend.rootCache, err = api.NewRootCache(&failingCommitNodeDB{MultiCommitNodeDB: mdb, err: errPersist})
+	require.NoError(err, "NewRootCache()")
+	_, err = impl.ApplyBatch(ctx, newRequest(stateRoot))
+	require.ErrorIs(err, errPersist, "ApplyBatch() should fail when persisting fails")
+	require.False(hasRoot(api.RootTypeIO, ioRoot), "I/O root should not be committed")
+	require.False(hasRoot(api.RootTypeState, stateRoot), "state root should not be committed")
+	backend.rootCache = rootCache
+
+	// Both roots should be committed.
+	newRoots, err := impl.ApplyBatch(ctx, newRequest(stateRoot))
+	require.NoError(err, "ApplyBatch()")
+	require.Equal([]hash.Hash{ioRoot, stateRoot}, newRoots)
+	require.True(hasRoot(api.RootTypeIO, ioRoot), "I/O root should be committed")
+	require.True(hasRoot(api.RootTypeState, stateRoot), "state root should be committed")
+
+	// Applying the same batch again should be a no-op.
+	newRoots, err = impl.ApplyBatch(ctx, newRequest(stateRoot))
+	require.NoError(err, "ApplyBatch() again")
+	require.Equal([]hash.Hash{ioRoot, stateRoot}, newRoots)
+}
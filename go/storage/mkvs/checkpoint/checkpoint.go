@@ -35,6 +35,14 @@ var (
 
 	// ErrChunkCorrupted is the error when a chunk is corrupted.
 	ErrChunkCorrupted = errors.New(moduleName, 7, "chunk: corrupted chunk")
+
+	// ErrVersionNotFinalized is the error when a checkpoint of a version that has not yet been
+	// finalized is requested.
+	ErrVersionNotFinalized = errors.New(moduleName, 8, "checkpoint: version not finalized")
+
+	// ErrCheckpointAlreadyExists is the error when a checkpoint that already exists is requested
+	// to be created.
+	ErrCheckpointAlreadyExists = errors.New(moduleName, 9, "checkpoint: already exists")
 )
 
 // ChunkProvider is a chunk provider.
@@ -151,3 +159,28 @@ func (m Metadata) GetChunkMetadata(idx uint64) (*ChunkMetadata, error) {
 		Digest:  m.Chunks[int(idx)],
 	}, nil
 }
+
+// GetCheckpointSize returns the total size of all chunks of the given checkpoint.
+func GetCheckpointSize(ctx context.Context, provider ChunkProvider, cp *Metadata) (uint64, error) {
+	var w countingWriter
+	for idx := range uint64(len(cp.Chunks)) {
+		chunk, err := cp.GetChunkMetadata(idx)
+		if err != nil {
+			return 0, err
+		}
+		if err = provider.GetCheckpointChunk(ctx, chunk, &w); err != nil {
+			return 0, err
+		}
+	}
+	return w.n, nil
+}
+
+// countingWriter is a writer which discards all data and only counts the written bytes.
+type countingWriter struct {
+	n uint64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += uint64(len(p))
+	return len(p), nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/eapache/channels"
@@ -79,6 +80,14 @@ type Checkpointer interface {
 	// The checkpoint will be created asynchronously.
 	ForceCheckpoint(version uint64)
 
+	// CreateCheckpoint synchronously creates a checkpoint of the given version, or of the latest
+	// finalized version in case no version is given, even if it is outside the regular checkpoint
+	// schedule and returns the metadata of the created checkpoints (one per root).
+	//
+	// In case the version has not yet been finalized, ErrVersionNotFinalized is returned. In case
+	// the checkpoint at that version already exists, ErrCheckpointAlreadyExists is returned.
+	CreateCheckpoint(ctx context.Context, version *uint64) ([]*Metadata, error)
+
 	// WatchCheckpoints returns a channel that produces a stream of checkpointed versions. The
 	// versions are emitted before the checkpointing process starts.
 	WatchCheckpoints() (<-chan uint64, pubsub.ClosableSubscription, error)
@@ -104,6 +113,9 @@ type checkpointer struct {
 	pausedCh   chan bool
 	cpNotifier *pubsub.Broker
 
+	// cpLock serializes checkpoint creation between the worker and synchronous requests.
+	cpLock sync.Mutex
+
 	logger *logging.Logger
 }
 
@@ -117,6 +129,51 @@ func (c *checkpointer) ForceCheckpoint(version uint64) {
 	c.forceCh.In() <- version
 }
 
+// Implements Checkpointer.
+func (c *checkpointer) CreateCheckpoint(ctx context.Context, version *uint64) ([]*Metadata, error) {
+	c.cpLock.Lock()
+	defer c.cpLock.Unlock()
+
+	latestVersion, ok := c.ndb.GetLatestVersion()
+	if !ok {
+		return nil, ErrVersionNotFinalized
+	}
+	switch {
+	case version == nil:
+		version = &latestVersion
+	case *version > latestVersion:
+		return nil, ErrVersionNotFinalized
+	default:
+	}
+
+	// Check whether the checkpoint already exists.
+	cps, err := c.creator.GetCheckpoints(ctx, &GetCheckpointsRequest{
+		Version:     checkpointVersion,
+		Namespace:   c.cfg.Namespace,
+		RootVersion: version,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("checkpointer: failed to get existing checkpoints: %w", err)
+	}
+	if len(cps) >= c.cfg.RootsPerVersion {
+		return nil, ErrCheckpointAlreadyExists
+	}
+
+	params, err := c.getParameters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.checkpoint(ctx, *version, params); err != nil {
+		return nil, err
+	}
+
+	return c.creator.GetCheckpoints(ctx, &GetCheckpointsRequest{
+		Version:     checkpointVersion,
+		Namespace:   c.cfg.Namespace,
+		RootVersion: version,
+	})
+}
+
 // Implements Checkpointer.
 func (c *checkpointer) WatchCheckpoints() (<-chan uint64, pubsub.ClosableSubscription, error) {
 	ch := make(chan uint64)
@@ -135,7 +192,29 @@ func (c *checkpointer) Pause(pause bool) {
 	c.pausedCh <- pause
 }
 
+func (c *checkpointer) getParameters(ctx context.Context) (*CreationParameters, error) {
+	params := c.cfg.Parameters
+	if params == nil && c.cfg.GetParameters != nil {
+		var err error
+		params, err = c.cfg.GetParameters(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("checkpointer: failed to get checkpoint parameters: %w", err)
+		}
+	}
+	if params == nil {
+		return nil, fmt.Errorf("checkpointer: no checkpoint parameters")
+	}
+	return params, nil
+}
+
 func (c *checkpointer) checkpoint(ctx context.Context, version uint64, params *CreationParameters) (err error) {
+	// Make sure that the version is not pruned while the checkpoint is being created.
+	unpin, err := c.ndb.Pin(version)
+	if err != nil {
+		return fmt.Errorf("checkpointer: failed to pin version: %w", err)
+	}
+	defer unpin()
+
 	// Notify watchers about the checkpoint we are about to make.
 	c.cpNotifier.Broadcast(version)
 
@@ -327,20 +406,12 @@ func (c *checkpointer) worker(ctx context.Context) {
 		}
 
 		// Fetch current checkpoint parameters.
-		params := c.cfg.Parameters
-		if params == nil && c.cfg.GetParameters != nil {
-			var err error
-			params, err = c.cfg.GetParameters(ctx)
-			if err != nil {
-				c.logger.Error("failed to get checkpoint parameters",
-					"err", err,
-					"version", version,
-				)
-				continue
-			}
-		}
-		if params == nil {
-			c.logger.Error("no checkpoint parameters")
+		params, err := c.getParameters(ctx)
+		if err != nil {
+			c.logger.Error("failed to get checkpoint parameters",
+				"err", err,
+				"version", version,
+			)
 			continue
 		}
 
@@ -357,13 +428,14 @@ func (c *checkpointer) worker(ctx context.Context) {
 		default:
 		}
 
-		var err error
+		c.cpLock.Lock()
 		switch force {
 		case false:
 			err = c.maybeCheckpoint(ctx, version, params)
 		case true:
 			err = c.checkpoint(ctx, version, params)
 		}
+		c.cpLock.Unlock()
 		if err != nil {
 			c.logger.Error("failed to checkpoint",
 				"version", version,
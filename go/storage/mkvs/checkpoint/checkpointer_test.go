@@ -167,6 +167,90 @@ func testCheckpointer(t *testing.T, factory dbApi.Factory, earliestVersion, inte
 	}
 }
 
+func testCreateCheckpoint(t *testing.T, factory dbApi.Factory) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "mkvs.checkpointer")
+	require.NoError(err, "TempDir")
+	defer os.RemoveAll(dir)
+
+	ndb, err := factory.New(&dbApi.Config{
+		DB:           filepath.Join(dir, "db"),
+		Namespace:    testNs,
+		MaxCacheSize: 16 * 1024 * 1024,
+	})
+	require.NoError(err, "New")
+
+	fc, err := NewFileCreator(filepath.Join(dir, "checkpoints"), ndb)
+	require.NoError(err, "NewFileCreator")
+
+	// Create a checkpointer that never checkpoints on its own.
+	cp, err := NewCheckpointer(ctx, ndb, fc, CheckpointerConfig{
+		Name:            "test",
+		Namespace:       testNs,
+		CheckInterval:   CheckIntervalDisabled,
+		RootsPerVersion: 1,
+		Parameters: &CreationParameters{
+			Interval:  10,
+			NumKept:   testNumKept,
+			ChunkSize: 16 * 1024,
+		},
+	})
+	require.NoError(err, "NewCheckpointer")
+
+	// Nothing has been finalized yet.
+	_, err = cp.CreateCheckpoint(ctx, nil)
+	require.ErrorIs(err, ErrVersionNotFinalized, "CreateCheckpoint should fail without finalized versions")
+
+	var root node.Root
+	root.Empty()
+	root.Namespace = testNs
+	root.Type = node.RootTypeState
+
+	for round := uint64(0); round < 3; round++ {
+		tree := mkvs.NewWithRoot(nil, ndb, root)
+		err = tree.Insert(ctx, []byte(fmt.Sprintf("round %d", round)), []byte(fmt.Sprintf("value %d", round)))
+		require.NoError(err, "Insert")
+
+		var rootHash hash.Hash
+		_, rootHash, err = tree.Commit(ctx, testNs, round)
+		require.NoError(err, "Commit")
+
+		root.Version = round
+		root.Hash = rootHash
+
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(err, "Finalize")
+	}
+
+	// Versions past the latest finalized version cannot be checkpointed.
+	version := uint64(3)
+	_, err = cp.CreateCheckpoint(ctx, &version)
+	require.ErrorIs(err, ErrVersionNotFinalized, "CreateCheckpoint should fail for non-finalized versions")
+
+	// Checkpoint the latest finalized version by default.
+	cps, err := cp.CreateCheckpoint(ctx, nil)
+	require.NoError(err, "CreateCheckpoint")
+	require.Len(cps, 1, "CreateCheckpoint should return a checkpoint per root")
+	require.Equal(root, cps[0].Root, "CreateCheckpoint should checkpoint the latest finalized version")
+	require.NotEmpty(cps[0].Chunks, "checkpoint should have chunks")
+
+	size, err := GetCheckpointSize(ctx, fc, cps[0])
+	require.NoError(err, "GetCheckpointSize")
+	require.NotZero(size, "checkpoint size should not be zero")
+
+	_, err = cp.CreateCheckpoint(ctx, nil)
+	require.ErrorIs(err, ErrCheckpointAlreadyExists, "CreateCheckpoint should fail for existing checkpoints")
+
+	// Checkpoint an explicit earlier version.
+	version = 1
+	cps, err = cp.CreateCheckpoint(ctx, &version)
+	require.NoError(err, "CreateCheckpoint")
+	require.Len(cps, 1, "CreateCheckpoint should return a checkpoint per root")
+	require.EqualValues(1, cps[0].Root.Version, "CreateCheckpoint should checkpoint the given version")
+}
+
 func TestCheckpointer(t *testing.T) {
 	dbTesting.TestMultipleBackends(t, db.Backends, testCheckpointerWithBackend)
 }
@@ -187,4 +271,7 @@ func testCheckpointerWithBackend(t *testing.T, factory dbApi.Factory) {
 	t.Run("ForceCheckpoint", func(t *testing.T) {
 		testCheckpointer(t, factory, 0, 10, false)
 	})
+	t.Run("CreateCheckpoint", func(t *testing.T) {
+		testCreateCheckpoint(t, factory)
+	})
 }
@@ -77,7 +77,7 @@ func createChunk(
 	return
 }
 
-func restoreChunk(ctx context.Context, ndb db.NodeDB, chunk *ChunkMetadata, r io.Reader) error {
+func restoreChunk(ctx context.Context, ndb db.NodeDB, chunk *ChunkMetadata, r io.Reader, skipExisting bool) error {
 	hb := hash.NewBuilder()
 	tr := io.TeeReader(r, hb)
 	sr := snappy.NewReader(tr)
@@ -131,6 +131,11 @@ func restoreChunk(ctx context.Context, ndb db.NodeDB, chunk *ChunkMetadata, r io
 		return fmt.Errorf("%w: %s", ErrChunkProofVerificationFailed, err.Error())
 	}
 
+	// When resuming an interrupted restore, skip chunks that have already been imported.
+	if skipExisting && chunkExists(ndb, chunk.Root, ptr) {
+		return nil
+	}
+
 	// Import chunk into the node database.
 	emptyRoot := node.Root{
 		Namespace: chunk.Root.Namespace,
@@ -143,7 +148,7 @@ func restoreChunk(ctx context.Context, ndb db.NodeDB, chunk *ChunkMetadata, r io
 	if err != nil {
 		return fmt.Errorf("chunk: failed to create batch: %w", err)
 	}
-	defer batch.Reset()
+	defer batch.Close()
 
 	if err = doRestoreChunk(ctx, batch, ptr, nil); err != nil {
 		return fmt.Errorf("chunk: node import failed: %w", err)
@@ -155,6 +160,30 @@ func restoreChunk(ctx context.Context, ndb db.NodeDB, chunk *ChunkMetadata, r io
 	return nil
 }
 
+// chunkExists returns true iff all nodes of the given verified chunk are already present in the
+// node database under the given root. Any failure to look up a node is treated as the node not
+// being present, in which case the chunk is simply imported again.
+func chunkExists(ndb db.NodeDB, root node.Root, ptr *node.Pointer) bool {
+	if ptr == nil || ptr.Node == nil {
+		// Nodes not included in the chunk belong to other chunks.
+		return true
+	}
+	if _, err := ndb.GetNode(root, &node.Pointer{Clean: true, Hash: ptr.Hash}); err != nil {
+		return false
+	}
+
+	n, ok := ptr.Node.(*node.InternalNode)
+	if !ok {
+		return true
+	}
+	for _, subNode := range []*node.Pointer{n.LeafNode, n.Left, n.Right} {
+		if !chunkExists(ndb, root, subNode) {
+			return false
+		}
+	}
+	return true
+}
+
 func doRestoreChunk(
 	ctx context.Context,
 	batch db.Batch,
@@ -20,6 +20,8 @@ type restorer struct {
 	currentCheckpoint *Metadata
 	// pendingChunks is a set of pending chunks.
 	pendingChunks map[uint64]bool
+	// resumed is true iff an interrupted restore of the current checkpoint is being resumed.
+	resumed bool
 }
 
 // Implements Restorer.
@@ -33,6 +35,9 @@ func (rs *restorer) StartRestore(_ context.Context, checkpoint *Metadata) error
 
 	rs.currentCheckpoint = checkpoint
 	rs.pendingChunks = make(map[uint64]bool)
+	// In case the node database kept an interrupted multipart restore of the same root, chunks
+	// that have already been imported can be skipped.
+	rs.resumed = rs.ndb.GetMultipartVersion() == checkpoint.Root.Version && rs.ndb.HasRoot(checkpoint.Root)
 	for idx := range checkpoint.Chunks {
 		rs.pendingChunks[uint64(idx)] = true
 	}
@@ -46,6 +51,7 @@ func (rs *restorer) AbortRestore(context.Context) error {
 
 	rs.pendingChunks = nil
 	rs.currentCheckpoint = nil
+	rs.resumed = false
 
 	return nil
 }
@@ -64,26 +70,27 @@ func (rs *restorer) GetCurrentCheckpoint() *Metadata {
 
 // Implements Restorer.
 func (rs *restorer) RestoreChunk(ctx context.Context, idx uint64, r io.Reader) (bool, error) {
-	chunk, err := func() (*ChunkMetadata, error) {
+	chunk, resumed, err := func() (*ChunkMetadata, bool, error) {
 		rs.Lock()
 		defer rs.Unlock()
 
 		if rs.currentCheckpoint == nil {
-			return nil, ErrNoRestoreInProgress
+			return nil, false, ErrNoRestoreInProgress
 		}
 
 		// Check if the given chunk is still pending.
 		if !rs.pendingChunks[idx] {
-			return nil, ErrChunkAlreadyRestored
+			return nil, false, ErrChunkAlreadyRestored
 		}
 
-		return rs.currentCheckpoint.GetChunkMetadata(idx)
+		chunk, err := rs.currentCheckpoint.GetChunkMetadata(idx)
+		return chunk, rs.resumed, err
 	}()
 	if err != nil {
 		return false, err
 	}
 
-	err = restoreChunk(ctx, rs.ndb, chunk, r)
+	err = restoreChunk(ctx, rs.ndb, chunk, r, resumed)
 	switch {
 	case err == nil:
 	case errors.Is(err, ErrChunkProofVerificationFailed):
@@ -105,6 +112,7 @@ func (rs *restorer) RestoreChunk(ctx context.Context, idx uint64, r io.Reader) (
 	if len(rs.pendingChunks) == 0 {
 		rs.pendingChunks = nil
 		rs.currentCheckpoint = nil
+		rs.resumed = false
 		return true, nil
 	}
 
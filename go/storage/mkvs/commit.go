@@ -26,14 +26,14 @@ type commitOptions struct {
 }
 
 // Implements Tree.
-func (t *tree) CommitKnown(ctx context.Context, root node.Root) (writelog.WriteLog, error) {
+func (t *tree) CommitKnown(ctx context.Context, root node.Root, options ...CommitOption) (writelog.WriteLog, error) {
 	writeLog, _, err := t.commitWithHooks(ctx, root.Namespace, root.Version, func(rootHash hash.Hash) error {
 		if !rootHash.Equal(&root.Hash) {
 			return ErrKnownRootMismatch
 		}
 
 		return nil
-	})
+	}, options...)
 	return writeLog, err
 }
 
@@ -81,7 +81,7 @@ func (t *tree) commitWithHooks(
 	if err != nil {
 		return nil, hash.Hash{}, err
 	}
-	defer batch.Reset()
+	defer batch.Close()
 
 	rootHash, err := doCommit(ctx, t.cache, batch, t.cache.pendingRoot, nil)
 	if err != nil {
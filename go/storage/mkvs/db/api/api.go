@@ -3,9 +3,13 @@ package api
 
 import (
 	"context"
+	stdErrors "errors"
+	"fmt"
+	"time"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/errors"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
 )
@@ -56,6 +60,34 @@ var (
 	// ErrCannotPruneLatestVersion indicates that the caller attempted to prune the latest finalized
 	// version which would leave the database without any finalized versions.
 	ErrCannotPruneLatestVersion = errors.New(ModuleName, 16, "mkvs: cannot prune latest version")
+	// ErrExportCorrupted indicates that an exported root cannot be imported as it is malformed or
+	// its nodes do not match the root hash in the export header.
+	ErrExportCorrupted = errors.New(ModuleName, 17, "mkvs: corrupted root export")
+	// ErrTombstoneNotFound indicates that no tombstone is retained for the given key and version,
+	// either because the key was not deleted or because the tombstone has already been pruned.
+	ErrTombstoneNotFound = errors.New(ModuleName, 18, "mkvs: tombstone not found")
+	// ErrRepairNotAllowed indicates that repairing finalized versions has not been enabled in the
+	// node database configuration.
+	ErrRepairNotAllowed = errors.New(ModuleName, 19, "mkvs: repair not allowed")
+	// ErrRootNotTrusted indicates that a root being repaired is not among the trusted roots.
+	ErrRootNotTrusted = errors.New(ModuleName, 20, "mkvs: root not trusted")
+	// ErrCorruptedDB indicates that the database contains corrupted or inconsistent data.
+	ErrCorruptedDB = errors.New(ModuleName, 21, "mkvs: corrupted database")
+	// ErrNodeCorrupted indicates that the hash of a node read from the database does not match
+	// the hash it was requested by.
+	ErrNodeCorrupted = errors.New(ModuleName, 22, "mkvs: node corrupted")
+	// ErrVersionPinned indicates that the caller attempted to prune a version that is pinned.
+	ErrVersionPinned = errors.New(ModuleName, 23, "mkvs: version is pinned")
+	// ErrGCInProgress indicates that a manually triggered garbage collection is already running.
+	ErrGCInProgress = errors.New(ModuleName, 24, "mkvs: garbage collection already in progress")
+	// ErrBatchClosed indicates that the caller attempted to commit a batch that has already been
+	// committed, reset or closed.
+	ErrBatchClosed = errors.New(ModuleName, 25, "mkvs: batch closed")
+	// ErrBackupCorrupted indicates that a node database backup is corrupted.
+	ErrBackupCorrupted = errors.New(ModuleName, 26, "mkvs: corrupted backup")
+	// ErrBackupOutOfOrder indicates that an incremental backup does not follow the versions that
+	// are already in the node database.
+	ErrBackupOutOfOrder = errors.New(ModuleName, 27, "mkvs: backup out of order")
 )
 
 // Config is the node database backend configuration.
@@ -67,6 +99,9 @@ type Config struct { // nolint: maligned
 	NoFsync bool
 
 	// MemoryOnly will make the storage memory-only (if the backend supports it).
+	//
+	// Memory-only databases start empty and lose all data on close, so they cannot be combined
+	// with ReadOnly or AllowResumeMultipart. They are mostly useful for tests.
 	MemoryOnly bool
 
 	// ReadOnly will make the storage read-only.
@@ -80,6 +115,97 @@ type Config struct { // nolint: maligned
 
 	// DiscardWriteLogs will cause all write logs to be discarded.
 	DiscardWriteLogs bool
+
+	// MaxWriteLogHops is the maximum number of hops that will be traversed when searching for
+	// a write log between two roots. If zero, DefaultMaxWriteLogHops is used.
+	MaxWriteLogHops uint8
+
+	// RootCacheVersions is the number of most recent versions for which the set of existing roots
+	// is kept in memory (if the backend supports it). If zero, roots are not cached.
+	RootCacheVersions uint64
+
+	// NodeCacheSize is the maximum size (in bytes) of the in-memory cache of unmarshalled nodes
+	// (if the backend supports it). If zero, nodes are not cached.
+	NodeCacheSize uint64
+
+	// AllowResumeMultipart will keep the state of an interrupted multipart restore on open so
+	// that the restore can be resumed (if the backend supports it). Otherwise any multipart
+	// restore remnants are removed.
+	AllowResumeMultipart bool
+
+	// MetricsEnabled will make the database register and report Prometheus metrics (if the
+	// backend supports it).
+	MetricsEnabled bool
+
+	// TombstoneRetentionVersions is the number of versions for which tombstones of deleted keys
+	// are retained (if the backend supports it). Tombstones are retained independently of node
+	// pruning. If zero, tombstones are not recorded.
+	TombstoneRetentionVersions uint64
+
+	// PruneInterval is the interval at which the database is checked for versions to prune in
+	// the background (if the backend supports it). Pruning is additionally triggered after each
+	// finalization. If zero, background pruning is disabled.
+	PruneInterval time.Duration
+
+	// KeepVersions is the number of versions before the last finalized version that are kept
+	// when pruning in the background.
+	KeepVersions uint64
+
+	// AllowRepair will permit inserting trusted roots into already finalized versions via
+	// RepairNodeDB (if the backend supports it).
+	AllowRepair bool
+
+	// BadgerOptions are optional tuning options applied on top of the defaults (if the backend is
+	// based on badger).
+	BadgerOptions *BadgerOptions
+
+	// VerifyNodeHashes will make the database recompute the hash of each node read from and
+	// written to disk and compare it with the expected hash (if the backend supports it).
+	VerifyNodeHashes bool
+
+	// CorruptionErrorThreshold is the number of corruption-class errors within the corruption
+	// error window after which the database reports itself as unhealthy (if the backend supports
+	// it). Zero disables health reporting based on errors.
+	CorruptionErrorThreshold uint64
+
+	// CorruptionErrorWindow is the window over which corruption-class errors are counted. If
+	// zero, DefaultCorruptionErrorWindow is used.
+	CorruptionErrorWindow time.Duration
+}
+
+const (
+	// DefaultMaxWriteLogHops is the default maximum number of write log hops.
+	DefaultMaxWriteLogHops = 2
+	// MaxWriteLogHopsLimit is the upper bound on the configurable maximum number of write log hops.
+	MaxWriteLogHopsLimit = 16
+)
+
+// WriteLogHops returns the configured maximum number of write log hops, applying the default.
+func (cfg *Config) WriteLogHops() (uint8, error) {
+	switch {
+	case cfg.MaxWriteLogHops == 0:
+		return DefaultMaxWriteLogHops, nil
+	case cfg.MaxWriteLogHops > MaxWriteLogHopsLimit:
+		return 0, fmt.Errorf("mkvs: max write log hops %d exceeds limit %d", cfg.MaxWriteLogHops, MaxWriteLogHopsLimit)
+	default:
+		return cfg.MaxWriteLogHops, nil
+	}
+}
+
+// ValidateMemoryOnly checks that the memory-only mode is not combined with options that require
+// the database to persist across opens.
+func (cfg *Config) ValidateMemoryOnly() error {
+	if !cfg.MemoryOnly {
+		return nil
+	}
+	switch {
+	case cfg.ReadOnly:
+		return fmt.Errorf("mkvs: memory-only database cannot be read-only")
+	case cfg.AllowResumeMultipart:
+		return fmt.Errorf("mkvs: memory-only database cannot resume multipart restores")
+	default:
+		return nil
+	}
 }
 
 // Factory is a node database factory interface that can create new databases.
@@ -146,6 +272,13 @@ type NodeDB interface {
 	// GetRootsForVersion returns a list of roots stored under the given version.
 	GetRootsForVersion(version uint64) ([]node.Root, error)
 
+	// Roots streams the roots of all versions in the given (inclusive) range that have any roots
+	// stored, in ascending version order.
+	//
+	// The returned channel is closed once all versions have been sent, the context is canceled
+	// or an error occurs while reading the database.
+	Roots(ctx context.Context, startVersion, endVersion uint64) (<-chan VersionRoots, error)
+
 	// StartMultipartInsert prepares the database for a batch insert job from multiple chunks.
 	// Batches from this call onwards will keep track of inserted nodes so that they can be
 	// deleted if the job fails for any reason.
@@ -158,6 +291,15 @@ type NodeDB interface {
 	// It is not an error to call this method more than once.
 	AbortMultipartInsert() error
 
+	// GetMultipartVersion returns the version of the multipart insert that is in progress or
+	// zero in case there is none. A non-zero version right after opening the database means
+	// that an interrupted multipart restore is being resumed.
+	GetMultipartVersion() uint64
+
+	// MultipartProgress returns the progress of the multipart insert that is in progress or nil
+	// in case there is none.
+	MultipartProgress() *MultipartProgress
+
 	// NewBatch starts a new batch.
 	//
 	// The chunk argument specifies whether the given batch is being used to import a chunk of an
@@ -169,18 +311,56 @@ type NodeDB interface {
 	// HasRoot checks whether the given root exists.
 	HasRoot(root node.Root) bool
 
+	// HasRoots checks whether the given roots exist.
+	//
+	// The returned slice is positionally aligned with the given roots.
+	HasRoots(roots []node.Root) ([]bool, error)
+
 	// Finalize finalizes the version comprising the passed list of finalized roots.
 	// All non-finalized roots can be discarded.
 	Finalize(roots []node.Root) error
 
+	// Pin prevents the given finalized version from being pruned until the returned function is
+	// called, so that long-running reads of the version cannot race with pruning.
+	//
+	// Pins are reference-counted and the version remains pinned until all of its pins have been
+	// released. Releasing a pin more than once has no effect. All pins are released on Close.
+	Pin(version uint64) (unpin func(), err error)
+
 	// Prune removes all roots recorded under the given version.
 	//
 	// Only the earliest version can be pruned, passing any other version will result in an error.
+	// In case the version is pinned, ErrVersionPinned is returned.
 	Prune(version uint64) error
 
+	// PruneRange removes all roots recorded under versions in the given (inclusive) range.
+	//
+	// The start version must be the earliest version. Versions that cannot be pruned as they are
+	// not finalized, are the latest finalized version or follow a pinned version are skipped. In
+	// case the start version is pinned, ErrVersionPinned is returned. The operation can be
+	// canceled via the context between versions.
+	//
+	// Returns the number of versions that have been pruned.
+	PruneRange(ctx context.Context, startVersion, endVersion uint64) (int, error)
+
+	// PruneWriteLogs removes all write logs of versions before the given version, e.g., those
+	// stored before write logs were configured to be discarded. Roots and nodes are kept.
+	//
+	// All versions before the given version must be finalized. The operation can be canceled via
+	// the context in which case the write logs removed so far stay removed.
+	//
+	// Returns the number of write logs that have been removed.
+	PruneWriteLogs(ctx context.Context, beforeVersion uint64) (int, error)
+
 	// Size returns the size of the database in bytes.
 	Size() (int64, error)
 
+	// Stats returns a breakdown of the database size and contents.
+	//
+	// Counting the stored roots may require iterating over all of them, so they are only counted
+	// when countRoots is set.
+	Stats(countRoots bool) (*Stats, error)
+
 	// Sync syncs the database to disk. This is useful if the NoFsync option is used to explicitly
 	// perform a sync.
 	Sync() error
@@ -189,6 +369,55 @@ type NodeDB interface {
 	Close()
 }
 
+// VersionRoots are the roots stored under a given version.
+type VersionRoots struct {
+	// Version is the version.
+	Version uint64
+	// Roots are the roots stored under the version.
+	Roots []node.Root
+}
+
+// MultipartProgress is the progress of a multipart insert.
+type MultipartProgress struct {
+	// Version is the version of the multipart insert.
+	Version uint64 `json:"version"`
+
+	// NodesWritten is the number of nodes recorded in the multipart insert log so far. Backends
+	// that do not track progress report zero.
+	NodesWritten uint64 `json:"nodes_written"`
+
+	// BytesWritten is the number of bytes written since the multipart insert was started or
+	// resumed. Backends that do not track progress report zero.
+	BytesWritten uint64 `json:"bytes_written"`
+}
+
+// Stats are node database statistics.
+type Stats struct {
+	// LSMSize is the size of the LSM tree in bytes.
+	LSMSize int64 `json:"lsm_size"`
+
+	// ValueLogSize is the size of the value log in bytes.
+	ValueLogSize int64 `json:"value_log_size"`
+
+	// NumRoots is the number of stored roots. It is only set when explicitly requested.
+	NumRoots *uint64 `json:"num_roots,omitempty"`
+
+	// EarliestVersion is the earliest version stored in the database.
+	EarliestVersion uint64 `json:"earliest_version"`
+
+	// LatestVersion is the latest finalized version (if any).
+	LatestVersion *uint64 `json:"latest_version,omitempty"`
+
+	// PendingGarbage is an estimate of the number of bytes of stale data that is waiting to be
+	// reclaimed by garbage collection.
+	PendingGarbage int64 `json:"pending_garbage"`
+}
+
+// Size returns the total size of the database in bytes.
+func (s *Stats) Size() int64 {
+	return s.LSMSize + s.ValueLogSize
+}
+
 // Batch is a NodeDB-specific batch implementation.
 type Batch interface {
 	// PutNode persists a node in the NodeDB.
@@ -201,6 +430,9 @@ type Batch interface {
 	RemoveNodes(nodes []*node.Pointer) error
 
 	// Commit commits the batch.
+	//
+	// A batch can only be committed once. Committing it again returns ErrBatchClosed in case the
+	// batch released its resources on commit.
 	Commit(root node.Root) error
 
 	// OnCommit registers a hook to run after a successful commit.
@@ -218,8 +450,136 @@ type Batch interface {
 	// The specific NodeDB implementation may wish to do further processing.
 	VisitDirtyNode(ptr *node.Pointer, parent *node.Pointer) error
 
-	// Reset resets the batch for another use.
+	// Size returns the number of bytes and the number of entries pending in the batch.
+	//
+	// The size is maintained as the batch is being assembled so that callers can pace their own
+	// writes. It is reset on Reset and after a successful Commit.
+	Size() (bytes int64, entries int)
+
+	// Reset discards anything that has not been committed.
+	//
+	// Backends may also release any resources held by the batch, in which case the batch cannot
+	// be used afterwards and Commit returns ErrBatchClosed.
 	Reset()
+
+	// Close releases any resources held by the batch, discarding anything that has not been
+	// committed. Callers should defer Close right after creating the batch.
+	//
+	// It is safe to call Close multiple times and after Commit or Reset.
+	Close()
+}
+
+// HasRoots is a HasRoots implementation for node databases that have no more efficient way of
+// checking the existence of multiple roots than calling HasRoot for each one.
+func HasRoots(db NodeDB, roots []node.Root) ([]bool, error) {
+	exists := make([]bool, len(roots))
+	for i, root := range roots {
+		exists[i] = db.HasRoot(root)
+	}
+	return exists, nil
+}
+
+// UntrackedMultipartProgress is a MultipartProgress implementation for node databases that do not
+// track the progress of multipart inserts. It only reports the multipart version.
+func UntrackedMultipartProgress(db NodeDB) *MultipartProgress {
+	version := db.GetMultipartVersion()
+	if version == 0 {
+		return nil
+	}
+	return &MultipartProgress{Version: version}
+}
+
+// Roots is a Roots implementation for node databases that have no more efficient way of
+// enumerating roots than calling GetRootsForVersion for each version.
+//
+// Only versions up to the latest finalized version are considered.
+func Roots(ctx context.Context, db NodeDB, logger *logging.Logger, startVersion, endVersion uint64) (<-chan VersionRoots, error) {
+	if startVersion > endVersion {
+		return nil, fmt.Errorf("mkvs: invalid version range [%d, %d]", startVersion, endVersion)
+	}
+
+	ch := make(chan VersionRoots)
+	go func() {
+		defer close(ch)
+
+		lastFinalizedVersion, exists := db.GetLatestVersion()
+		if !exists {
+			return
+		}
+		endVersion = min(endVersion, lastFinalizedVersion)
+
+		for version := max(startVersion, db.GetEarliestVersion()); version <= endVersion; version++ {
+			roots, err := db.GetRootsForVersion(version)
+			if err != nil {
+				logger.Error("failed to get roots for version",
+					"err", err,
+					"version", version,
+				)
+				return
+			}
+			if len(roots) == 0 {
+				continue
+			}
+
+			select {
+			case ch <- VersionRoots{Version: version, Roots: roots}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// CheckPruneWriteLogs checks that the write logs of versions before the given version may be
+// pruned, which requires all of those versions to be finalized.
+func CheckPruneWriteLogs(db NodeDB, beforeVersion uint64) error {
+	if beforeVersion == 0 {
+		return nil
+	}
+	lastFinalizedVersion, exists := db.GetLatestVersion()
+	if !exists || beforeVersion-1 > lastFinalizedVersion {
+		return ErrNotFinalized
+	}
+	return nil
+}
+
+// PruneRange is a PruneRange implementation for node databases that have no more efficient way
+// of pruning multiple versions than calling Prune for each version.
+func PruneRange(ctx context.Context, db NodeDB, startVersion, endVersion uint64) (int, error) {
+	if startVersion > endVersion {
+		return 0, fmt.Errorf("mkvs: invalid prune range [%d, %d]", startVersion, endVersion)
+	}
+	if startVersion != db.GetEarliestVersion() {
+		return 0, ErrNotEarliest
+	}
+	lastFinalizedVersion, exists := db.GetLatestVersion()
+	switch {
+	case !exists || lastFinalizedVersion < startVersion:
+		return 0, ErrNotFinalized
+	case lastFinalizedVersion == startVersion:
+		return 0, ErrCannotPruneLatestVersion
+	case endVersion >= lastFinalizedVersion:
+		endVersion = lastFinalizedVersion - 1
+	}
+
+	var pruned int
+	for version := startVersion; version <= endVersion; version++ {
+		if err := ctx.Err(); err != nil {
+			return pruned, err
+		}
+		err := db.Prune(version)
+		switch {
+		case err == nil:
+		case stdErrors.Is(err, ErrVersionPinned) && pruned > 0:
+			// Versions following a pinned version cannot be pruned either.
+			return pruned, nil
+		default:
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
 }
 
 // BaseBatch encapsulates basic functionality of a batch so it doesn't need
@@ -268,10 +628,20 @@ func (d *nopNodeDB) GetRootsForVersion(uint64) ([]node.Root, error) {
 	return nil, nil
 }
 
+func (d *nopNodeDB) Roots(context.Context, uint64, uint64) (<-chan VersionRoots, error) {
+	ch := make(chan VersionRoots)
+	close(ch)
+	return ch, nil
+}
+
 func (d *nopNodeDB) HasRoot(node.Root) bool {
 	return false
 }
 
+func (d *nopNodeDB) HasRoots(roots []node.Root) ([]bool, error) {
+	return HasRoots(d, roots)
+}
+
 func (d *nopNodeDB) StartMultipartInsert(uint64) error {
 	return nil
 }
@@ -280,14 +650,38 @@ func (d *nopNodeDB) AbortMultipartInsert() error {
 	return nil
 }
 
+func (d *nopNodeDB) GetMultipartVersion() uint64 {
+	return 0
+}
+
+func (d *nopNodeDB) MultipartProgress() *MultipartProgress {
+	return nil
+}
+
 func (d *nopNodeDB) Finalize([]node.Root) error {
 	return nil
 }
 
+func (d *nopNodeDB) Pin(uint64) (func(), error) {
+	return func() {}, nil
+}
+
 func (d *nopNodeDB) Prune(uint64) error {
 	return nil
 }
 
+func (d *nopNodeDB) PruneRange(context.Context, uint64, uint64) (int, error) {
+	return 0, nil
+}
+
+func (d *nopNodeDB) PruneWriteLogs(context.Context, uint64) (int, error) {
+	return 0, nil
+}
+
+func (d *nopNodeDB) Stats(bool) (*Stats, error) {
+	return &Stats{}, nil
+}
+
 func (d *nopNodeDB) Size() (int64, error) {
 	return 0, nil
 }
@@ -320,6 +714,10 @@ func (b *nopBatch) RemoveNodes([]*node.Pointer) error {
 	return nil
 }
 
+func (b *nopBatch) Size() (int64, int) {
+	return 0, 0
+}
+
 func (b *nopBatch) VisitCleanNode(*node.Pointer, *node.Pointer) error {
 	return nil
 }
@@ -330,3 +728,6 @@ func (b *nopBatch) VisitDirtyNode(*node.Pointer, *node.Pointer) error {
 
 func (b *nopBatch) Reset() {
 }
+
+func (b *nopBatch) Close() {
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"context"
+	"io"
+)
+
+// BackupNodeDB is a node database that supports online incremental backups.
+type BackupNodeDB interface {
+	NodeDB
+
+	// Backup writes a backup of all finalized versions starting at the given version into the
+	// given writer while the database remains in use, and returns the version at which the next
+	// incremental backup should start.
+	//
+	// A since version of zero produces a full backup.
+	Backup(ctx context.Context, w io.Writer, sinceVersion uint64) (uint64, error)
+
+	// RestoreFromBackup restores a backup produced by Backup.
+	//
+	// A full backup may only be restored into an empty database. An incremental backup may only
+	// be restored into a database that holds all versions preceding its since version, otherwise
+	// ErrBackupOutOfOrder is returned.
+	RestoreFromBackup(r io.Reader) error
+}
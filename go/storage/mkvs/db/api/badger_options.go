@@ -0,0 +1,78 @@
+package api
+
+import "fmt"
+
+// MaxBadgerValueThreshold is the maximum value threshold supported by badger.
+const MaxBadgerValueThreshold = 1 << 20
+
+// BadgerOptions are tuning options for badger-based node database backends that are applied on
+// top of the backend defaults. Zero values keep the defaults.
+type BadgerOptions struct {
+	// MemTableSize is the size of each memtable in bytes.
+	MemTableSize int64
+
+	// BaseTableSize is the size of each table in the LSM tree base level in bytes.
+	BaseTableSize int64
+
+	// BlockCacheSize is the size of the block cache in bytes. If set, it takes precedence over
+	// Config.MaxCacheSize.
+	BlockCacheSize int64
+
+	// NumCompactors is the number of concurrent compaction workers.
+	NumCompactors int
+
+	// CompactL0OnClose determines whether level zero should be compacted when the database is
+	// closed.
+	CompactL0OnClose *bool
+
+	// ValueThreshold is the size in bytes above which values are stored in the value log instead
+	// of the LSM tree.
+	ValueThreshold int64
+
+	// SyncWrites determines whether writes are synced to disk. If set, it takes precedence over
+	// Config.NoFsync.
+	SyncWrites *bool
+
+	// UpdatedNodesChunkSize is the maximum size in bytes of a single record of nodes updated by a
+	// root that are kept until the root's version is finalized. Larger sets of updated nodes are
+	// split across multiple records.
+	UpdatedNodesChunkSize int64
+
+	// MultipartNodeFilterLimit is the maximum number of nodes already in the database for which
+	// an in-memory filter is built when a multipart insert is started, so that inserted nodes
+	// only need to be looked up when the filter cannot rule out that they existed before. If
+	// negative, the filter is disabled and all inserted nodes are looked up.
+	MultipartNodeFilterLimit int64
+
+	// SplitMetadata determines whether root metadata should be stored in a separate small
+	// database instance instead of alongside the nodes. Existing databases are migrated when
+	// opened with a different setting. Only supported by the badger backend.
+	SplitMetadata bool
+
+	// DebugIterate enables raw key/value iteration of the database keyspaces for offline
+	// inspection. Only supported by the badger backend.
+	DebugIterate bool
+}
+
+// Validate validates the badger options.
+func (o *BadgerOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	switch {
+	case o.MemTableSize < 0:
+		return fmt.Errorf("mkvs: invalid memtable size %d", o.MemTableSize)
+	case o.BaseTableSize < 0:
+		return fmt.Errorf("mkvs: invalid base table size %d", o.BaseTableSize)
+	case o.BlockCacheSize < 0:
+		return fmt.Errorf("mkvs: invalid block cache size %d", o.BlockCacheSize)
+	case o.NumCompactors < 0 || o.NumCompactors == 1:
+		// Badger requires at least two compactors when compaction is enabled.
+		return fmt.Errorf("mkvs: invalid number of compactors %d", o.NumCompactors)
+	case o.ValueThreshold < 0 || o.ValueThreshold > MaxBadgerValueThreshold:
+		return fmt.Errorf("mkvs: value threshold %d not in range [0, %d]", o.ValueThreshold, MaxBadgerValueThreshold)
+	case o.UpdatedNodesChunkSize < 0:
+		return fmt.Errorf("mkvs: invalid updated nodes chunk size %d", o.UpdatedNodesChunkSize)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"errors"
+)
+
+// ErrorCategory is a category of failures internal to a node database backend.
+type ErrorCategory string
+
+const (
+	// ErrorCategoryUnknown is the category of errors that could not be classified.
+	ErrorCategoryUnknown ErrorCategory = "unknown"
+	// ErrorCategoryCorruption is the category of errors caused by corrupted or inconsistent data.
+	ErrorCategoryCorruption ErrorCategory = "corruption"
+	// ErrorCategoryResourceExhausted is the category of errors caused by exhausted resources,
+	// e.g., disk space, memory or transaction size limits.
+	ErrorCategoryResourceExhausted ErrorCategory = "resource_exhausted"
+	// ErrorCategoryConflict is the category of errors caused by conflicting concurrent
+	// transactions. Such operations may be retried.
+	ErrorCategoryConflict ErrorCategory = "conflict"
+	// ErrorCategoryIO is the category of errors caused by failed I/O operations.
+	ErrorCategoryIO ErrorCategory = "io"
+)
+
+// ErrorCategories are all error categories that can be reported by backends.
+var ErrorCategories = []ErrorCategory{
+	ErrorCategoryUnknown,
+	ErrorCategoryCorruption,
+	ErrorCategoryResourceExhausted,
+	ErrorCategoryConflict,
+	ErrorCategoryIO,
+}
+
+// CategorizedError is a backend error that has been classified into an error category.
+//
+// Errors in the corruption category also match ErrCorruptedDB.
+type CategorizedError struct {
+	// Category is the category of the error.
+	Category ErrorCategory
+	// Err is the underlying error.
+	Err error
+}
+
+// NewCategorizedError wraps the given error with the given category. In case the error is nil or
+// is already categorized, it is returned unchanged.
+func NewCategorizedError(category ErrorCategory, err error) error {
+	if err == nil {
+		return nil
+	}
+	var ce *CategorizedError
+	if errors.As(err, &ce) {
+		return err
+	}
+	return &CategorizedError{Category: category, Err: err}
+}
+
+// Error implements the error interface.
+func (e *CategorizedError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *CategorizedError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true iff the target is ErrCorruptedDB and the error is in the corruption category.
+func (e *CategorizedError) Is(target error) bool {
+	return e.Category == ErrorCategoryCorruption && target == ErrCorruptedDB
+}
+
+// ErrorCategoryOf returns the category of the given error.
+//
+// Errors that were not categorized by the backend are reported as corruption in case they wrap
+// ErrCorruptedDB or ErrNodeCorrupted and as unknown otherwise.
+func ErrorCategoryOf(err error) ErrorCategory {
+	var ce *CategorizedError
+	switch {
+	case errors.As(err, &ce):
+		return ce.Category
+	case errors.Is(err, ErrCorruptedDB), errors.Is(err, ErrNodeCorrupted):
+		return ErrorCategoryCorruption
+	default:
+		return ErrorCategoryUnknown
+	}
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// exportFormatVersion is the version of the root export format.
+const exportFormatVersion = 1
+
+// exportHeader is the header of an exported root.
+type exportHeader struct {
+	// Version is the export format version.
+	Version uint16 `json:"v"`
+
+	// Namespace is the namespace of the exported root.
+	Namespace common.Namespace `json:"namespace"`
+
+	// RootVersion is the version of the exported root.
+	RootVersion uint64 `json:"version"`
+
+	// Root is the typed hash of the exported root.
+	Root TypedHash `json:"root"`
+}
+
+// ExportRoot serializes all nodes of the given root into the given writer.
+//
+// The export consists of a CBOR-encoded header followed by the binary-serialized nodes in the
+// order in which they are visited by Visit.
+func ExportRoot(ctx context.Context, ndb NodeDB, root node.Root, w io.Writer) error {
+	enc := cbor.NewEncoder(w)
+	if err := enc.Encode(&exportHeader{
+		Version:     exportFormatVersion,
+		Namespace:   root.Namespace,
+		RootVersion: root.Version,
+		Root:        TypedHashFromRoot(root),
+	}); err != nil {
+		return fmt.Errorf("mkvs: failed to encode export header: %w", err)
+	}
+
+	// An empty root has no nodes.
+	if root.Hash.IsEmpty() {
+		return nil
+	}
+
+	var encErr error
+	err := Visit(ctx, ndb, root, func(_ context.Context, n node.Node) bool {
+		var data []byte
+		if data, encErr = n.MarshalBinary(); encErr != nil {
+			return false
+		}
+		encErr = enc.Encode(data)
+		return encErr == nil
+	})
+	if encErr != nil {
+		return fmt.Errorf("mkvs: failed to encode node: %w", encErr)
+	}
+	if err != nil {
+		return fmt.Errorf("mkvs: failed to traverse root: %w", err)
+	}
+	return nil
+}
+
+// ImportRoot imports a root exported by ExportRoot from the given reader into the node database
+// and returns the imported root.
+//
+// The root hash is recomputed from the imported nodes and must match the root in the header. The
+// version of the root must not yet be finalized. The caller is responsible for finalizing it.
+func ImportRoot(ctx context.Context, ndb NodeDB, r io.Reader) (node.Root, error) {
+	dec := cbor.NewDecoder(r)
+
+	var hdr exportHeader
+	if err := dec.Decode(&hdr); err != nil {
+		return node.Root{}, fmt.Errorf("%w: failed to decode header: %w", ErrExportCorrupted, err)
+	}
+	if hdr.Version != exportFormatVersion {
+		return node.Root{}, fmt.Errorf("mkvs: unsupported export format version: %d", hdr.Version)
+	}
+
+	root := node.Root{
+		Namespace: hdr.Namespace,
+		Version:   hdr.RootVersion,
+		Type:      hdr.Root.Type(),
+		Hash:      hdr.Root.Hash(),
+	}
+	if latestVersion, exists := ndb.GetLatestVersion(); exists && root.Version <= latestVersion {
+		return node.Root{}, ErrAlreadyFinalized
+	}
+
+	// An empty root has no nodes.
+	if root.Hash.IsEmpty() {
+		return root, nil
+	}
+
+	// Reconstruct the tree.
+	im := importer{dec: dec}
+	ptr := &node.Pointer{Clean: true}
+	if err := im.readNode(ctx, ptr); err != nil {
+		return node.Root{}, err
+	}
+	if !ptr.Hash.Equal(&root.Hash) {
+		return node.Root{}, fmt.Errorf("%w: root hash mismatch (expected: %s got: %s)",
+			ErrExportCorrupted,
+			root.Hash,
+			ptr.Hash,
+		)
+	}
+	var trailing []byte
+	if err := dec.Decode(&trailing); !errors.Is(err, io.EOF) {
+		return node.Root{}, fmt.Errorf("%w: trailing data", ErrExportCorrupted)
+	}
+
+	// Import the tree into the node database.
+	emptyRoot := node.Root{
+		Namespace: root.Namespace,
+		Version:   root.Version,
+		Type:      root.Type,
+	}
+	emptyRoot.Hash.Empty()
+
+	batch, err := ndb.NewBatch(emptyRoot, root.Version, false)
+	if err != nil {
+		return node.Root{}, fmt.Errorf("mkvs: failed to create batch: %w", err)
+	}
+	defer batch.Close()
+
+	if err = putNodes(batch, ptr, nil); err != nil {
+		return node.Root{}, fmt.Errorf("mkvs: node import failed: %w", err)
+	}
+	if err = batch.Commit(root); err != nil {
+		return node.Root{}, fmt.Errorf("mkvs: node import failed: %w", err)
+	}
+	return root, nil
+}
+
+type importer struct {
+	dec *cbor.Decoder
+}
+
+func (im *importer) next() (node.Node, error) {
+	var data []byte
+	if err := im.dec.Decode(&data); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: unexpected end of export", ErrExportCorrupted)
+		}
+		return nil, fmt.Errorf("%w: failed to decode node: %w", ErrExportCorrupted, err)
+	}
+	n, err := node.UnmarshalBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrExportCorrupted, err)
+	}
+	return n, nil
+}
+
+// readNode reads the next node and all of its children, updating the given pointer with the node
+// and its recomputed hash.
+func (im *importer) readNode(ctx context.Context, ptr *node.Pointer) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	n, err := im.next()
+	if err != nil {
+		return err
+	}
+
+	if n, ok := n.(*node.InternalNode); ok {
+		if n.LeafNode != nil {
+			// The leaf node is part of the internal node, but it is also visited separately.
+			var leaf node.Node
+			if leaf, err = im.next(); err != nil {
+				return err
+			}
+			if h := leaf.GetHash(); !h.Equal(&n.LeafNode.Hash) {
+				return fmt.Errorf("%w: leaf node mismatch", ErrExportCorrupted)
+			}
+		}
+		for _, child := range []*node.Pointer{n.Left, n.Right} {
+			if child == nil {
+				continue
+			}
+			if err = im.readNode(ctx, child); err != nil {
+				return err
+			}
+		}
+		n.UpdateHash()
+	}
+
+	ptr.Node = n
+	ptr.Hash = n.GetHash()
+	return nil
+}
+
+// putNodes adds the given subtree to the batch, children first.
+func putNodes(batch Batch, ptr *node.Pointer, parent *node.Pointer) error {
+	if ptr == nil {
+		return nil
+	}
+	if err := batch.VisitDirtyNode(ptr, parent); err != nil {
+		return err
+	}
+	if n, ok := ptr.Node.(*node.InternalNode); ok {
+		for _, child := range []*node.Pointer{n.LeafNode, n.Left, n.Right} {
+			if err := putNodes(batch, child, ptr); err != nil {
+				return err
+			}
+		}
+	}
+	return batch.PutNode(ptr)
+}
@@ -0,0 +1,26 @@
+package api
+
+import "context"
+
+// GCStats are the statistics of a manually triggered garbage collection.
+type GCStats struct {
+	// FilesRewritten is the number of value log files that have been rewritten.
+	FilesRewritten uint64 `json:"files_rewritten"`
+
+	// ReclaimedBytesEstimate is a lower bound estimate of the number of bytes reclaimed.
+	ReclaimedBytesEstimate uint64 `json:"reclaimed_bytes_estimate"`
+}
+
+// GCNodeDB is a node database that supports manually triggering garbage collection, e.g., to
+// reclaim space after a big prune instead of waiting for the background garbage collection.
+type GCNodeDB interface {
+	NodeDB
+
+	// TriggerGC runs garbage collection until there is nothing left to collect or the context is
+	// cancelled. Only files in which at least the given ratio of the data can be discarded are
+	// rewritten.
+	//
+	// Only one manually triggered garbage collection may run at a time, otherwise
+	// ErrGCInProgress is returned.
+	TriggerGC(ctx context.Context, discardRatio float64) (*GCStats, error)
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
@@ -78,6 +79,29 @@ func (h TypedHash) String() string {
 	return fmt.Sprintf("%v:%s", node.RootType(h[0]), hex.EncodeToString(h[1:]))
 }
 
+// ParseTypedHash parses the canonical string representation of a typed hash, as returned by
+// String, i.e. "<type>:<hash>".
+func ParseTypedHash(s string) (TypedHash, error) {
+	typ, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return TypedHash{}, fmt.Errorf("malformed typed hash: expected <type>:<hash>")
+	}
+	rt, err := node.ParseRootType(typ)
+	if err != nil {
+		return TypedHash{}, fmt.Errorf("malformed typed hash: %w", err)
+	}
+	var h hash.Hash
+	if err = h.UnmarshalHex(rest); err != nil {
+		return TypedHash{}, fmt.Errorf("malformed typed hash: bad hash: %w", err)
+	}
+
+	th := TypedHashFromParts(rt, h)
+	if th.String() != s {
+		return TypedHash{}, fmt.Errorf("malformed typed hash: non-canonical form")
+	}
+	return th, nil
+}
+
 // FromParts returns the typed hash composed of the given type and hash.
 func (h *TypedHash) FromParts(typ node.RootType, hash hash.Hash) {
 	h[0] = byte(typ)
@@ -0,0 +1,39 @@
+package api
+
+import "time"
+
+// DefaultCorruptionErrorWindow is the default window over which corruption-class errors are
+// counted when determining the health of a node database.
+const DefaultCorruptionErrorWindow = 10 * time.Minute
+
+// HealthNodeDB is a node database that can report errors encountered by operations that are not
+// able to return them, e.g., HasRoot.
+type HealthNodeDB interface {
+	NodeDB
+
+	// LastError returns the error encountered by the last such operation or nil in case the last
+	// operation succeeded.
+	//
+	// This makes it possible to distinguish a root that is genuinely absent from a database that
+	// is unhealthy. Errors wrapping ErrCorruptedDB indicate corrupted data, while other errors
+	// may be transient.
+	LastError() error
+
+	// Health returns the health of the node database based on the rate of corruption-class
+	// errors encountered by all operations.
+	Health() *Health
+}
+
+// Health is the health of a node database.
+type Health struct {
+	// Healthy is true iff the number of corruption-class errors in the current window is below
+	// the configured threshold.
+	Healthy bool `json:"healthy"`
+
+	// CorruptionErrors is the number of corruption-class errors in the current window. It is
+	// capped at the threshold and is only tracked when a threshold is configured.
+	CorruptionErrors uint64 `json:"corruption_errors,omitempty"`
+
+	// LastCorruptionError is the last corruption-class error (if any).
+	LastCorruptionError string `json:"last_corruption_error,omitempty"`
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"sync"
 
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
@@ -116,6 +117,125 @@ func Visit(ctx context.Context, ndb NodeDB, root node.Root, visitor NodeVisitor)
 	return doVisit(ctx, ndb, root, visitor, ptr)
 }
 
+// VisitOptions are the options for VisitWithOptions.
+type VisitOptions struct {
+	// Concurrency is the maximum number of goroutines used to traverse the tree. If zero or one,
+	// the tree is traversed sequentially in the same order as by Visit.
+	Concurrency int
+}
+
+// VisitWithOptions traverses the tree like Visit, but allows sibling subtrees to be traversed
+// concurrently.
+//
+// When traversing concurrently, the visitor may be called from multiple goroutines at the same
+// time and the order in which subtrees are visited is not defined. A node is still visited before
+// any of its children and a visitor returning false stops descent into that subtree.
+func VisitWithOptions(ctx context.Context, ndb NodeDB, root node.Root, visitor NodeVisitor, opts VisitOptions) error {
+	if opts.Concurrency <= 1 {
+		return Visit(ctx, ndb, root, visitor)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cv := &concurrentVisitor{
+		ndb:     ndb,
+		root:    root,
+		visitor: visitor,
+		slots:   make(chan struct{}, opts.Concurrency-1),
+		cancel:  cancel,
+	}
+	ptr := &node.Pointer{
+		Clean: true,
+		Hash:  root.Hash,
+	}
+	if err := cv.visit(ctx, ptr); err != nil {
+		cv.fail(err)
+	}
+	cv.wg.Wait()
+
+	return cv.err
+}
+
+type concurrentVisitor struct {
+	ndb     NodeDB
+	root    node.Root
+	visitor NodeVisitor
+
+	// slots limits the number of additional goroutines traversing subtrees.
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+	cancel  context.CancelFunc
+}
+
+func (cv *concurrentVisitor) fail(err error) {
+	cv.errOnce.Do(func() {
+		cv.err = err
+		cv.cancel()
+	})
+}
+
+func (cv *concurrentVisitor) visit(ctx context.Context, ptr *node.Pointer) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	nd := ptr.Node
+	if nd == nil {
+		var err error
+		if nd, err = cv.ndb.GetNode(cv.root, ptr); err != nil {
+			return err
+		}
+	}
+
+	if !cv.visitor(ctx, nd) {
+		return nil
+	}
+
+	n, ok := nd.(*node.InternalNode)
+	if !ok {
+		return nil
+	}
+	if n.LeafNode != nil {
+		if err := cv.visit(ctx, n.LeafNode); err != nil {
+			return err
+		}
+	}
+	left := n.Left
+	if left != nil && n.Right != nil {
+		// Hand the left subtree to another goroutine if one is available.
+		select {
+		case cv.slots <- struct{}{}:
+			cv.wg.Add(1)
+			go func(ptr *node.Pointer) {
+				defer cv.wg.Done()
+				defer func() { <-cv.slots }()
+
+				if err := cv.visit(ctx, ptr); err != nil {
+					cv.fail(err)
+				}
+			}(left)
+			left = nil
+		default:
+		}
+	}
+	for _, child := range []*node.Pointer{left, n.Right} {
+		if child == nil {
+			continue
+		}
+		if err := cv.visit(ctx, child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func doVisit(ctx context.Context, ndb NodeDB, root node.Root, visitor NodeVisitor, ptr *node.Pointer) error {
 	select {
 	case <-ctx.Done():
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// LineageNodeDB is a node database that can report the lineage of roots as recorded when new
+// roots are committed on top of existing ones.
+type LineageNodeDB interface {
+	NodeDB
+
+	// GetDerivedRoots returns the roots directly derived from the given root, either in the same
+	// or in the next version.
+	//
+	// Derived roots that have been discarded during finalization are not reported. In case the
+	// root does not exist, ErrRootNotFound is returned.
+	GetDerivedRoots(root node.Root) ([]node.Root, error)
+
+	// GetRootLineage returns the roots derived from the given root by following the derived root
+	// links forward across versions. The element at index i contains the roots at derivation
+	// depth i+1. At most maxDepth levels are returned, zero means no limit.
+	//
+	// In case the root does not exist, ErrRootNotFound is returned.
+	GetRootLineage(root node.Root, maxDepth int) ([][]node.Root, error)
+}
+
+// ResolveDerivedRoots resolves the versions of roots derived from the given root, given the roots
+// metadata (root to derived roots) of the root's version and of the next version.
+//
+// Derived roots present in neither version have been discarded and are skipped.
+func ResolveDerivedRoots(
+	root node.Root,
+	derivedRoots []TypedHash,
+	sameVersion map[TypedHash][]TypedHash,
+	nextVersion map[TypedHash][]TypedHash,
+) []node.Root {
+	rootHash := TypedHashFromRoot(root)
+
+	var roots []node.Root
+	for _, derivedRoot := range derivedRoots {
+		version := root.Version
+		if _, ok := sameVersion[derivedRoot]; !ok || derivedRoot == rootHash {
+			if _, ok = nextVersion[derivedRoot]; !ok {
+				continue
+			}
+			version++
+		}
+
+		roots = append(roots, node.Root{
+			Namespace: root.Namespace,
+			Version:   version,
+			Type:      derivedRoot.Type(),
+			Hash:      derivedRoot.Hash(),
+		})
+	}
+	return roots
+}
+
+// GetRootLineage is a helper that walks the derived root links of the given root using
+// GetDerivedRoots of the given node database.
+func GetRootLineage(ndb LineageNodeDB, root node.Root, maxDepth int) ([][]node.Root, error) {
+	type versionedHash struct {
+		version uint64
+		hash    TypedHash
+	}
+	seen := make(map[versionedHash]struct{})
+
+	var lineage [][]node.Root
+	current := []node.Root{root}
+	for depth := 0; maxDepth <= 0 || depth < maxDepth; depth++ {
+		var next []node.Root
+		for _, r := range current {
+			derivedRoots, err := ndb.GetDerivedRoots(r)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, derivedRoot := range derivedRoots {
+				vh := versionedHash{derivedRoot.Version, TypedHashFromRoot(derivedRoot)}
+				if _, ok := seen[vh]; ok {
+					continue
+				}
+				seen[vh] = struct{}{}
+				next = append(next, derivedRoot)
+			}
+		}
+		if len(next) == 0 {
+			break
+		}
+
+		lineage = append(lineage, next)
+		current = next
+	}
+	return lineage, nil
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// MultiCommitNodeDB is a node database that supports committing the roots of multiple batches
+// together, e.g. all roots of a single round.
+type MultiCommitNodeDB interface {
+	NodeDB
+
+	// CommitMulti commits the given batches with their corresponding roots, as if Commit was
+	// called on each batch in order, but loading and saving the roots metadata of each affected
+	// version only once.
+	//
+	// All roots must be of the same version and the batches must have been created by this node
+	// database. In case of an error none of the roots are committed.
+	CommitMulti(batches []Batch, roots []node.Root) error
+}
+
+// DeferredCommitNodeDB is a node database that defers committing batches created through it
+// until Commit is called, at which point the roots of all deferred batches are committed
+// together via CommitMulti.
+//
+// This makes it possible to coalesce the commits of multiple trees without changing how the
+// trees themselves are committed. It is not safe for concurrent use.
+type DeferredCommitNodeDB struct {
+	MultiCommitNodeDB
+
+	batches []Batch
+	roots   []node.Root
+}
+
+// NewDeferredCommitNodeDB creates a new node database that defers commits to the given node
+// database.
+func NewDeferredCommitNodeDB(db MultiCommitNodeDB) *DeferredCommitNodeDB {
+	return &DeferredCommitNodeDB{
+		MultiCommitNodeDB: db,
+	}
+}
+
+// Implements NodeDB.
+func (d *DeferredCommitNodeDB) NewBatch(oldRoot node.Root, version uint64, chunk bool) (Batch, error) {
+	batch, err := d.MultiCommitNodeDB.NewBatch(oldRoot, version, chunk)
+	if err != nil {
+		return nil, err
+	}
+	return &deferredBatch{Batch: batch, db: d}, nil
+}
+
+// Commit commits the roots of all deferred batches.
+func (d *DeferredCommitNodeDB) Commit() error {
+	batches, roots := d.batches, d.roots
+	d.batches, d.roots = nil, nil
+	if len(batches) == 0 {
+		return nil
+	}
+
+	err := d.MultiCommitNodeDB.CommitMulti(batches, roots)
+	for _, batch := range batches {
+		batch.Close()
+	}
+	return err
+}
+
+// Discard closes all deferred batches without committing them.
+func (d *DeferredCommitNodeDB) Discard() {
+	for _, batch := range d.batches {
+		batch.Close()
+	}
+	d.batches, d.roots = nil, nil
+}
+
+type deferredBatch struct {
+	Batch
+
+	db       *DeferredCommitNodeDB
+	deferred bool
+}
+
+// Implements Batch.
+func (b *deferredBatch) Commit(root node.Root) error {
+	if b.deferred {
+		return nil
+	}
+	b.deferred = true
+	b.db.batches = append(b.db.batches, b.Batch)
+	b.db.roots = append(b.db.roots, root)
+	return nil
+}
+
+// Implements Batch.
+func (b *deferredBatch) Reset() {
+	// Deferred batches are reset once they are committed.
+	if b.deferred {
+		return
+	}
+	b.Batch.Reset()
+}
+
+// Implements Batch.
+func (b *deferredBatch) Close() {
+	// Deferred batches are closed once they are committed.
+	if b.deferred {
+		return
+	}
+	b.Batch.Close()
+}
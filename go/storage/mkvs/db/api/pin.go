@@ -0,0 +1,100 @@
+package api
+
+import "sync"
+
+// VersionPins is a set of reference-counted version pins.
+//
+// Node database implementations must pin versions and check pins while holding the lock that
+// serializes pruning so that a version cannot be pinned while it is being pruned.
+type VersionPins struct {
+	mu     sync.Mutex
+	pins   map[uint64]uint64
+	closed bool
+}
+
+// Pin pins the given version and returns a function that releases the pin.
+//
+// The caller is responsible for checking that the version can be pinned, see CheckPinVersion.
+func (p *VersionPins) Pin(version uint64) (func(), error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, ErrVersionNotFound
+	}
+	if p.pins == nil {
+		p.pins = make(map[uint64]uint64)
+	}
+	p.pins[version]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.unpin(version)
+		})
+	}, nil
+}
+
+func (p *VersionPins) unpin(version uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Pins may have already been released on close.
+	if p.pins[version] == 0 {
+		return
+	}
+	p.pins[version]--
+	if p.pins[version] == 0 {
+		delete(p.pins, version)
+	}
+}
+
+// IsPinned returns true iff the given version is pinned.
+func (p *VersionPins) IsPinned(version uint64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.pins[version] > 0
+}
+
+// PrunableEnd returns the last version in the given (inclusive) range that may be pruned, given
+// that versions following a pinned version cannot be pruned either.
+//
+// In case the start version is pinned, ErrVersionPinned is returned.
+func (p *VersionPins) PrunableEnd(startVersion, endVersion uint64) (uint64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for version := range p.pins {
+		if version < startVersion || version > endVersion {
+			continue
+		}
+		if version == startVersion {
+			return 0, ErrVersionPinned
+		}
+		endVersion = version - 1
+	}
+	return endVersion, nil
+}
+
+// Close releases all pins and prevents any further versions from being pinned.
+func (p *VersionPins) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pins = nil
+	p.closed = true
+}
+
+// CheckPinVersion checks that the given version may be pinned, which requires it to be finalized
+// and not yet pruned.
+func CheckPinVersion(db NodeDB, version uint64) error {
+	lastFinalizedVersion, exists := db.GetLatestVersion()
+	if !exists || version > lastFinalizedVersion {
+		return ErrNotFinalized
+	}
+	if version < db.GetEarliestVersion() {
+		return ErrVersionNotFound
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// RepairNodeDB is a node database that supports inserting missing roots into already finalized
+// versions, e.g. when backfilling historic state of an archive node from a verified source.
+//
+// Repairs are only permitted when enabled via Config.AllowRepair.
+type RepairNodeDB interface {
+	NodeDB
+
+	// NewBatchForRepair starts a new batch that inserts a root into the given already finalized
+	// version.
+	//
+	// The committed root must be one of the given trusted roots, which the caller must have
+	// verified against an authoritative source. Otherwise ErrRootNotTrusted is returned on
+	// commit. No finalization bookkeeping is done for the inserted root.
+	NewBatchForRepair(oldRoot node.Root, version uint64, trustedRoots []node.Root) (Batch, error)
+}
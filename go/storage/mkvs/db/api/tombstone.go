@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/syncer"
+)
+
+// TombstoneNodeDB is a node database that retains tombstones of deleted keys so that their
+// deletion can be proven even after the version of the deletion has been pruned.
+//
+// Tombstones are only retained when enabled via Config.TombstoneRetentionVersions.
+type TombstoneNodeDB interface {
+	NodeDB
+
+	// GetDeletionProof returns a proof that the given key is absent in the given root as it has
+	// been deleted at an earlier (or the same) version.
+	//
+	// In case there is no retained tombstone for the key, ErrTombstoneNotFound is returned.
+	GetDeletionProof(ctx context.Context, root node.Root, key []byte) (*DeletionProof, error)
+
+	// GetEarliestTombstoneVersion returns the earliest deletion version for which tombstones are
+	// retained.
+	GetEarliestTombstoneVersion() uint64
+}
+
+// DeletionProof is a proof that a key is absent at a given version as it has been deleted at an
+// earlier (or the same) version.
+type DeletionProof struct {
+	// Key is the deleted key.
+	Key []byte `json:"key"`
+
+	// Version is the version at which the key is absent.
+	Version uint64 `json:"version"`
+
+	// DeletedVersion is the version in which the key was deleted.
+	DeletedVersion uint64 `json:"deleted_version"`
+
+	// Root is the hash of the finalized root at the deletion version.
+	Root hash.Hash `json:"root"`
+
+	// Proof is the proof of the key being absent in the root at the deletion version.
+	Proof syncer.Proof `json:"proof"`
+}
+
+// Verify verifies the deletion proof against the trusted root hash at the deletion version.
+//
+// Note that the absence of re-insertions between the deletion version and the queried version is
+// attested by the node database and is not covered by the proof.
+func (p *DeletionProof) Verify(ctx context.Context, trustedRoot hash.Hash) error {
+	if p.DeletedVersion > p.Version {
+		return fmt.Errorf("mkvs: deletion version %d is after version %d", p.DeletedVersion, p.Version)
+	}
+	if !p.Root.Equal(&trustedRoot) {
+		return fmt.Errorf("mkvs: deletion proof root mismatch (expected: %s got: %s)", trustedRoot, p.Root)
+	}
+	return VerifyKeyAbsent(ctx, trustedRoot, &p.Proof, p.Key)
+}
+
+// ProveKey builds a proof of the lookup path of the given key in the given root and returns
+// whether the key is present. Depending on that, the proof can be used to prove either presence
+// or absence of the key.
+func ProveKey(ctx context.Context, ndb NodeDB, root node.Root, key []byte) (*syncer.Proof, bool, error) {
+	var present bool
+	pb := syncer.NewProofBuilder(root.Hash, root.Hash)
+	if !root.Hash.IsEmpty() {
+		var err error
+		ptr := &node.Pointer{Clean: true, Hash: root.Hash}
+		if present, err = doProveKey(ctx, ndb, root, pb, ptr, 0, key); err != nil {
+			return nil, false, err
+		}
+	}
+	proof, err := pb.Build(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	return proof, present, nil
+}
+
+func doProveKey(
+	ctx context.Context,
+	ndb NodeDB,
+	root node.Root,
+	pb *syncer.ProofBuilder,
+	ptr *node.Pointer,
+	bitDepth node.Depth,
+	key node.Key,
+) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if ptr == nil {
+		return false, nil
+	}
+
+	nd := ptr.Node
+	if nd == nil {
+		var err error
+		if nd, err = ndb.GetNode(root, ptr); err != nil {
+			return false, err
+		}
+	}
+	pb.Include(nd)
+
+	switch n := nd.(type) {
+	case *node.InternalNode:
+		bitLength := bitDepth + n.LabelBitLength
+		switch {
+		case key.BitLength() == bitLength:
+			return doProveKey(ctx, ndb, root, pb, n.LeafNode, bitLength, key)
+		case key.BitLength() < bitLength:
+			return false, nil
+		case key.GetBit(bitLength):
+			return doProveKey(ctx, ndb, root, pb, n.Right, bitLength, key)
+		default:
+			return doProveKey(ctx, ndb, root, pb, n.Left, bitLength, key)
+		}
+	case *node.LeafNode:
+		return n.Key.Equal(key), nil
+	default:
+		return false, fmt.Errorf("mkvs: unknown node type: %T", n)
+	}
+}
+
+// VerifyKeyAbsent verifies the given proof against the trusted root hash and checks that it
+// proves the absence of the given key.
+func VerifyKeyAbsent(ctx context.Context, trustedRoot hash.Hash, proof *syncer.Proof, key []byte) error {
+	var pv syncer.ProofVerifier
+	ptr, err := pv.VerifyProof(ctx, trustedRoot, proof)
+	if err != nil {
+		return fmt.Errorf("mkvs: invalid proof: %w", err)
+	}
+
+	present, err := lookupVerified(ptr, 0, key)
+	if err != nil {
+		return err
+	}
+	if present {
+		return fmt.Errorf("mkvs: key is present")
+	}
+	return nil
+}
+
+func lookupVerified(ptr *node.Pointer, bitDepth node.Depth, key node.Key) (bool, error) {
+	if ptr == nil {
+		return false, nil
+	}
+	if ptr.Node == nil {
+		if ptr.Hash.IsEmpty() {
+			return false, nil
+		}
+		return false, fmt.Errorf("mkvs: incomplete proof")
+	}
+
+	switch n := ptr.Node.(type) {
+	case *node.InternalNode:
+		bitLength := bitDepth + n.LabelBitLength
+		switch {
+		case key.BitLength() == bitLength:
+			return lookupVerified(n.LeafNode, bitLength, key)
+		case key.BitLength() < bitLength:
+			return false, nil
+		case key.GetBit(bitLength):
+			return lookupVerified(n.Right, bitLength, key)
+		default:
+			return lookupVerified(n.Left, bitLength, key)
+		}
+	case *node.LeafNode:
+		return n.Key.Equal(key), nil
+	default:
+		return false, fmt.Errorf("mkvs: unknown node type: %T", n)
+	}
+}
@@ -0,0 +1,15 @@
+package api
+
+import "github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+
+// UpdatedNodesNodeDB is a node database that can return the nodes added by a root before its
+// version is finalized, e.g., so that only those nodes need to be replicated.
+type UpdatedNodesNodeDB interface {
+	NodeDB
+
+	// GetUpdatedNodes returns the nodes added by the given root that has not yet been finalized.
+	//
+	// The nodes added by a root are only tracked until its version is finalized, after which
+	// ErrAlreadyFinalized is returned.
+	GetUpdatedNodes(root node.Root) ([]node.Node, error)
+}
@@ -0,0 +1,15 @@
+package api
+
+// VersionStatsNodeDB is a node database that accounts for the nodes written under each version,
+// e.g., for capacity planning.
+type VersionStatsNodeDB interface {
+	NodeDB
+
+	// VersionStats returns the number of nodes and the total size in bytes of the serialized
+	// nodes written by batches committed under the given version.
+	//
+	// A node written by multiple batches is accounted for each time. The contribution of roots
+	// discarded during finalization is subtracted and the statistics are removed together with
+	// the version when it is pruned, in which case ErrVersionNotFound is returned.
+	VersionStats(version uint64) (nodes uint64, bytes uint64, err error)
+}
@@ -0,0 +1,338 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+const (
+	// backupFormatVersion is the version of the backup format.
+	backupFormatVersion = 1
+
+	// backupMaxHeaderSize is the maximum size of an encoded backup header.
+	backupMaxHeaderSize = 64 * 1024
+
+	// backupLoadMaxPendingWrites is the maximum number of pending writes when restoring a backup.
+	backupLoadMaxPendingWrites = 256
+)
+
+var _ api.BackupNodeDB = (*badgerNodeDB)(nil)
+
+// backupHeader is the header of a backup.
+type backupHeader struct {
+	// Version is the backup format version.
+	Version uint16 `json:"v"`
+
+	// Metadata is the database metadata as of the last finalized version in the backup.
+	Metadata serializedMetadata `json:"metadata"`
+
+	// SinceVersion is the first version included in the backup.
+	SinceVersion uint64 `json:"since_version"`
+
+	// MetadataSize is the size of the metadata section following the header.
+	MetadataSize uint64 `json:"metadata_size"`
+}
+
+// Implements api.BackupNodeDB.
+//
+// The backup consists of a length-prefixed CBOR-encoded header, followed by a metadata section
+// and a data section. Both sections use badger's backup format, so they can also be inspected
+// with badger's tooling. The metadata section holds all metadata of finalized versions, while the
+// data section only holds the entries written by versions starting at the since version, read at
+// the timestamp of the last finalized version.
+//
+// Pruning is not propagated by incremental backups, versions pruned in the meantime can be pruned
+// again once the backups have been restored.
+func (d *badgerNodeDB) Backup(ctx context.Context, w io.Writer, sinceVersion uint64) (uint64, error) {
+	meta, unpin, err := d.prepareBackup(sinceVersion)
+	if err != nil {
+		return 0, err
+	}
+	defer unpin()
+	lastFinalizedVersion := *meta.LastFinalizedVersion
+
+	// Metadata is small, so it is buffered in order to record its size in the header.
+	var metaSection bytes.Buffer
+	metaStream := d.metaDB.NewStreamAt(tsMetadata)
+	metaStream.LogPrefix = "mkvs/badger: metadata backup"
+	metaStream.ChooseKey = func(item *badger.Item) bool {
+		return item.Version() == tsMetadata && isBackupMetadataKey(item.Key(), lastFinalizedVersion)
+	}
+	if _, err = metaStream.Backup(&metaSection, 0); err != nil {
+		return 0, fmt.Errorf("mkvs/badger: failed to back up metadata: %w", err)
+	}
+
+	hdr := cbor.Marshal(&backupHeader{
+		Version:      backupFormatVersion,
+		Metadata:     meta,
+		SinceVersion: sinceVersion,
+		MetadataSize: uint64(metaSection.Len()),
+	})
+	if err = binary.Write(w, binary.LittleEndian, uint32(len(hdr))); err != nil { //nolint:gosec
+		return 0, fmt.Errorf("mkvs/badger: failed to write backup header: %w", err)
+	}
+	if _, err = w.Write(hdr); err != nil {
+		return 0, fmt.Errorf("mkvs/badger: failed to write backup header: %w", err)
+	}
+	if _, err = metaSection.WriteTo(w); err != nil {
+		return 0, fmt.Errorf("mkvs/badger: failed to write metadata: %w", err)
+	}
+
+	if sinceVersion <= lastFinalizedVersion {
+		// Entries older than the since timestamp are skipped by the stream, so that the backup
+		// only contains entries written by the versions being backed up.
+		dataStream := d.db.NewStreamAt(versionToTs(lastFinalizedVersion))
+		dataStream.LogPrefix = "mkvs/badger: backup"
+		dataStream.SinceTs = versionToTs(sinceVersion) - 1
+		if _, err = dataStream.Backup(&contextWriter{ctx: ctx, w: w}, versionToTs(sinceVersion)); err != nil {
+			return 0, fmt.Errorf("mkvs/badger: failed to back up data: %w", err)
+		}
+	}
+
+	d.logger.Info("backup completed",
+		"since_version", sinceVersion,
+		"last_finalized_version", lastFinalizedVersion,
+	)
+	return lastFinalizedVersion + 1, nil
+}
+
+// prepareBackup returns a snapshot of the database metadata for a backup starting at the given
+// version and pins the versions being backed up so that they cannot be pruned while the backup is
+// in progress.
+func (d *badgerNodeDB) prepareBackup(sinceVersion uint64) (serializedMetadata, func(), error) {
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	d.meta.RLock()
+	meta := d.meta.value
+	d.meta.RUnlock()
+
+	if meta.LastFinalizedVersion == nil {
+		return meta, nil, api.ErrNotFinalized
+	}
+	lastFinalizedVersion := *meta.LastFinalizedVersion
+	if sinceVersion > lastFinalizedVersion+1 {
+		return meta, nil, fmt.Errorf("%w: since version %d is after the next version %d",
+			api.ErrBackupOutOfOrder,
+			sinceVersion,
+			lastFinalizedVersion+1,
+		)
+	}
+	meta.LastFinalizedVersion = &lastFinalizedVersion
+	// Versions that are not finalized are never backed up.
+	meta.MultipartVersion = multipartVersionNone
+
+	pinVersion := max(sinceVersion, meta.EarliestVersion)
+	if pinVersion > lastFinalizedVersion {
+		return meta, func() {}, nil
+	}
+	if err := api.CheckPinVersion(d, pinVersion); err != nil {
+		return meta, nil, err
+	}
+	unpin, err := d.pins.Pin(pinVersion)
+	if err != nil {
+		return meta, nil, err
+	}
+	return meta, unpin, nil
+}
+
+// Implements api.BackupNodeDB.
+func (d *badgerNodeDB) RestoreFromBackup(r io.Reader) error {
+	if d.readOnly {
+		return api.ErrReadOnly
+	}
+
+	var hdrSize uint32
+	if err := binary.Read(r, binary.LittleEndian, &hdrSize); err != nil {
+		return fmt.Errorf("%w: failed to read header: %w", api.ErrBackupCorrupted, err)
+	}
+	if hdrSize > backupMaxHeaderSize {
+		return fmt.Errorf("%w: header too large", api.ErrBackupCorrupted)
+	}
+	rawHdr := make([]byte, hdrSize)
+	if _, err := io.ReadFull(r, rawHdr); err != nil {
+		return fmt.Errorf("%w: failed to read header: %w", api.ErrBackupCorrupted, err)
+	}
+	var hdr backupHeader
+	if err := cbor.Unmarshal(rawHdr, &hdr); err != nil {
+		return fmt.Errorf("%w: failed to decode header: %w", api.ErrBackupCorrupted, err)
+	}
+	if hdr.Version != backupFormatVersion {
+		return fmt.Errorf("mkvs/badger: unsupported backup format version: %d", hdr.Version)
+	}
+	if err := checkMetadataCompatible(&hdr.Metadata, d.namespace); err != nil {
+		return fmt.Errorf("mkvs/badger: %w", err)
+	}
+	if hdr.Metadata.LastFinalizedVersion == nil {
+		return fmt.Errorf("%w: no finalized version", api.ErrBackupCorrupted)
+	}
+	lastFinalizedVersion := *hdr.Metadata.LastFinalizedVersion
+
+	// The metadata section is written last, so that the database only refers to the restored data
+	// once all of it is there.
+	metaSection, err := io.ReadAll(io.LimitReader(r, int64(hdr.MetadataSize))) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("%w: failed to read metadata: %w", api.ErrBackupCorrupted, err)
+	}
+	if uint64(len(metaSection)) != hdr.MetadataSize {
+		return fmt.Errorf("%w: truncated metadata", api.ErrBackupCorrupted)
+	}
+
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	if d.multipartVersion != multipartVersionNone {
+		return api.ErrMultipartInProgress
+	}
+
+	meta := hdr.Metadata
+	switch hdr.SinceVersion {
+	case 0:
+		var empty bool
+		if empty, err = d.isEmptyLocked(); err != nil {
+			return err
+		}
+		if !empty {
+			return fmt.Errorf("mkvs/badger: full backups can only be restored into an empty database")
+		}
+	default:
+		latestVersion, exists := d.meta.getLastFinalizedVersion()
+		if !exists || latestVersion+1 != hdr.SinceVersion {
+			return fmt.Errorf("%w: backup starts at version %d", api.ErrBackupOutOfOrder, hdr.SinceVersion)
+		}
+		// Versions pruned since the previous backup are still there and can be pruned again.
+		meta.EarliestVersion = d.meta.getEarliestVersion()
+	}
+
+	if err = d.db.Load(r, backupLoadMaxPendingWrites); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to restore data: %w", err)
+	}
+	if err = syncStore(d.db); err != nil {
+		return err
+	}
+	if err = d.metaDB.Load(bytes.NewReader(metaSection), backupLoadMaxPendingWrites); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to restore metadata: %w", err)
+	}
+	if err = syncStore(d.metaDB); err != nil {
+		return err
+	}
+
+	tx := d.metaDB.NewTransactionAt(tsMetadata, true)
+	defer tx.Discard()
+
+	d.meta.Lock()
+	d.meta.value = meta
+	err = d.meta.save(tx)
+	d.meta.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = tx.CommitAt(tsMetadata, nil); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to commit database metadata: %w", err)
+	}
+	if err = syncStore(d.metaDB); err != nil {
+		return err
+	}
+
+	d.rootsMetaCache.invalidate(hdr.SinceVersion, lastFinalizedVersion)
+	if err = d.warmUpRootCache(); err != nil {
+		return err
+	}
+	d.metrics.versions(&d.meta)
+
+	d.logger.Info("backup restored",
+		"since_version", hdr.SinceVersion,
+		"last_finalized_version", lastFinalizedVersion,
+	)
+	return nil
+}
+
+// isEmptyLocked returns true iff there is nothing but the database metadata in the database.
+//
+// Must be called while holding metaUpdateLock.
+func (d *badgerNodeDB) isEmptyLocked() (bool, error) {
+	if _, exists := d.meta.getLastFinalizedVersion(); exists {
+		return false, nil
+	}
+
+	metadataKey := metadataKeyFmt.Encode()
+	for _, store := range d.stores() {
+		empty := func() bool {
+			tx := store.NewTransactionAt(math.MaxUint64, false)
+			defer tx.Discard()
+
+			it := tx.NewIterator(badger.IteratorOptions{AllVersions: true})
+			defer it.Close()
+
+			for it.Rewind(); it.Valid(); it.Next() {
+				if !bytes.Equal(it.Item().Key(), metadataKey) {
+					return false
+				}
+			}
+			return true
+		}()
+		if !empty {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// isBackupMetadataKey returns true iff the given metadata key belongs into a backup of all versions
+// up to the given last finalized version.
+//
+// The database metadata is restored from the backup header, while records of operations in
+// progress and of versions that are not finalized are never backed up.
+func isBackupMetadataKey(key []byte, lastFinalizedVersion uint64) bool {
+	for _, fixed := range [][]byte{
+		metadataKeyFmt.Encode(),
+		finalizeIntentKeyFmt.Encode(),
+		pruneIntentKeyFmt.Encode(),
+	} {
+		if bytes.Equal(key, fixed) {
+			return false
+		}
+	}
+
+	var (
+		version  uint64
+		chunk    uint64
+		rootHash api.TypedHash
+	)
+	switch {
+	case multipartRestoreNodeLogKeyFmt.Decode(key, &rootHash),
+		rootUpdatedNodesKeyFmt.Decode(key, &version, &rootHash),
+		rootUpdatedNodesChunkKeyFmt.Decode(key, &version, &rootHash, &chunk),
+		rootPendingKeysKeyFmt.Decode(key, &version, &rootHash):
+		return false
+	case rootsMetadataKeyFmt.Decode(key, &version),
+		rootStatsKeyFmt.Decode(key, &version, &rootHash),
+		tombstoneKeyFmt.Decode(key, &rootHash, &version),
+		tombstoneVersionKeyFmt.Decode(key, &version, &rootHash):
+		return version <= lastFinalizedVersion
+	default:
+		return true
+	}
+}
+
+// contextWriter is a writer that fails once the context is canceled.
+type contextWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw *contextWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
@@ -0,0 +1,137 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+func TestBackup(t *testing.T) {
+	for _, split := range []bool{false, true} {
+		t.Run(fmt.Sprintf("SplitMetadata=%t", split), func(t *testing.T) {
+			testBackup(t, split)
+		})
+	}
+}
+
+func testBackup(t *testing.T, split bool) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "oasis-storage-database-test")
+	require.NoError(err, "TempDir()")
+	defer os.RemoveAll(dir)
+
+	srcCfg := splitTestConfig(filepath.Join(dir, "src"), split)
+	src, err := New(srcCfg)
+	require.NoError(err, "New()")
+	defer src.Close()
+	bsrc := src.(api.BackupNodeDB)
+
+	// Take a full backup while versions 0-2 are finalized.
+	roots := fillVersions(ctx, require, src, 4)
+	var full bytes.Buffer
+	cursor, err := bsrc.Backup(ctx, &full, 0)
+	require.NoError(err, "Backup(0)")
+	require.EqualValues(3, cursor, "cursor should follow the last finalized version")
+
+	// Make progress and take an incremental backup.
+	err = src.Finalize([]node.Root{roots[3]})
+	require.NoError(err, "Finalize({root3})")
+	root := fillDB(ctx, require, testValues, &roots[3], 4, 4, src)
+	root.Version = 4
+	err = src.Finalize([]node.Root{root})
+	require.NoError(err, "Finalize({root4})")
+	roots = append(roots, root)
+
+	var incremental bytes.Buffer
+	cursor, err = bsrc.Backup(ctx, &incremental, cursor)
+	require.NoError(err, "Backup(3)")
+	require.EqualValues(5, cursor)
+
+	_, err = bsrc.Backup(ctx, &bytes.Buffer{}, cursor+1)
+	require.ErrorIs(err, api.ErrBackupOutOfOrder, "Backup() should reject future since versions")
+
+	// Restore the chain into an empty database.
+	dstCfg := splitTestConfig(filepath.Join(dir, "dst"), split)
+	dst, err := New(dstCfg)
+	require.NoError(err, "New()")
+	bdst := dst.(api.BackupNodeDB)
+
+	err = bdst.RestoreFromBackup(bytes.NewReader(incremental.Bytes()))
+	require.ErrorIs(err, api.ErrBackupOutOfOrder, "incremental backups should require preceding versions")
+
+	err = bdst.RestoreFromBackup(bytes.NewReader(full.Bytes()))
+	require.NoError(err, "RestoreFromBackup(full)")
+	latestVersion, exists := dst.GetLatestVersion()
+	require.True(exists)
+	require.EqualValues(2, latestVersion)
+	require.False(dst.HasRoot(roots[3]), "versions that were not finalized should not be backed up")
+
+	err = bdst.RestoreFromBackup(bytes.NewReader(full.Bytes()))
+	require.Error(err, "full backups should require an empty database")
+
+	err = bdst.RestoreFromBackup(bytes.NewReader(incremental.Bytes()))
+	require.NoError(err, "RestoreFromBackup(incremental)")
+	err = bdst.RestoreFromBackup(bytes.NewReader(incremental.Bytes()))
+	require.ErrorIs(err, api.ErrBackupOutOfOrder, "backups should not be restored twice")
+
+	verify := func(ndb api.NodeDB) {
+		version, ok := ndb.GetLatestVersion()
+		require.True(ok)
+		require.EqualValues(4, version)
+		require.EqualValues(0, ndb.GetEarliestVersion())
+		for _, r := range roots {
+			require.True(ndb.HasRoot(r), "root %d should exist", r.Version)
+		}
+
+		tree := mkvs.NewWithRoot(nil, ndb, roots[4])
+		defer tree.Close()
+		for i, value := range testValues {
+			v, err := tree.Get(ctx, []byte(strconv.Itoa(i)))
+			require.NoError(err, "Get()")
+			require.Equal(value, v)
+		}
+	}
+	verify(dst)
+
+	// The restored database should survive a restart.
+	dst.Close()
+	dst, err = New(dstCfg)
+	require.NoError(err, "New()")
+	verify(dst)
+
+	// Backups can be taken from read-only databases, but not restored into them.
+	dst.Close()
+	roCfg := *dstCfg
+	roCfg.ReadOnly = true
+	dst, err = New(&roCfg)
+	require.NoError(err, "New()")
+	defer dst.Close()
+
+	_, err = dst.(api.BackupNodeDB).Backup(ctx, &bytes.Buffer{}, 0)
+	require.NoError(err, "Backup() on a read-only database")
+	err = dst.(api.BackupNodeDB).RestoreFromBackup(bytes.NewReader(full.Bytes()))
+	require.ErrorIs(err, api.ErrReadOnly)
+
+	// The namespace must match.
+	otherCfg := *dbCfg
+	otherCfg.Namespace = common.NewTestNamespaceFromSeed([]byte("badger node db other ns"), 0)
+	other, err := New(&otherCfg)
+	require.NoError(err, "New()")
+	defer other.Close()
+
+	err = other.(api.BackupNodeDB).RestoreFromBackup(bytes.NewReader(full.Bytes()))
+	require.Error(err, "backups of other namespaces should be rejected")
+}
@@ -5,7 +5,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/dgraph-io/badger/v4"
 
@@ -26,6 +30,16 @@ const (
 	// multipartVersionNone is the value used for the multipart version in metadata
 	// when no multipart restore is in progress.
 	multipartVersionNone uint64 = 0
+
+	// maxWriteLogVisited is the maximum number of write logs visited during a single write log
+	// path search.
+	maxWriteLogVisited = 1024
+
+	// pruneRangeChunkSize is the maximum number of versions pruned using a single write batch.
+	pruneRangeChunkSize = 128
+
+	// pruneVisitConcurrency is the number of goroutines used to traverse a root being pruned.
+	pruneVisitConcurrency = 4
 )
 
 var (
@@ -66,19 +80,99 @@ var (
 	//
 	// Value is empty.
 	rootNodeKeyFmt = keyFormat.New(0x06, &api.TypedHash{})
+	// tombstoneKeyFmt is the key format for tombstones of deleted keys (typed key hash, deletion
+	// version). The type of the typed key hash is the type of the root the key was deleted from.
+	//
+	// Value is CBOR-serialized tombstone.
+	tombstoneKeyFmt = keyFormat.New(0x07, &api.TypedHash{}, uint64(0))
+	// tombstoneVersionKeyFmt is the key format for the index of tombstones by deletion version
+	// (deletion version, typed key hash). It is used for pruning tombstones beyond the retention
+	// horizon.
+	//
+	// Value is empty.
+	tombstoneVersionKeyFmt = keyFormat.New(0x08, uint64(0), &api.TypedHash{})
+	// rootPendingKeysKeyFmt is the key format for the keys deleted and inserted by the given root
+	// that are turned into tombstones once the version is finalized (version, root).
+	//
+	// Value is CBOR-serialized pendingKeys.
+	rootPendingKeysKeyFmt = keyFormat.New(0x09, uint64(0), &api.TypedHash{})
+	// finalizeIntentKeyFmt is the key format for the record of a finalization in progress. It is
+	// written before any data is removed and deleted atomically with the metadata update.
+	//
+	// Value is CBOR-serialized finalizeIntent.
+	finalizeIntentKeyFmt = keyFormat.New(0x0a)
+	// rootUpdatedNodesChunkKeyFmt is the key format for additional chunks of the pending updated
+	// nodes for the given root in case they do not fit into a single root updated nodes record
+	// (version, root, chunk index). Chunk indices start at one.
+	//
+	// Value is CBOR-serialized []updatedNode.
+	rootUpdatedNodesChunkKeyFmt = keyFormat.New(0x0b, uint64(0), &api.TypedHash{}, uint64(0))
+	// rootStatsKeyFmt is the key format for the statistics of nodes written by batches committing
+	// the given root (version, root).
+	//
+	// Value is CBOR-serialized rootStats.
+	rootStatsKeyFmt = keyFormat.New(0x0c, uint64(0), &api.TypedHash{})
+	// pruneIntentKeyFmt is the key format for the record of a pruning in progress. It is written
+	// before any data is removed and deleted atomically with the metadata update.
+	//
+	// Value is CBOR-serialized pruneIntent.
+	pruneIntentKeyFmt = keyFormat.New(0x0d)
 )
 
 // New creates a new BadgerDB-backed node database.
 func New(cfg *api.Config) (api.NodeDB, error) {
+	maxWriteLogHops, err := cfg.WriteLogHops()
+	if err != nil {
+		return nil, fmt.Errorf("mkvs/badger: invalid configuration: %w", err)
+	}
+	if err = cfg.BadgerOptions.Validate(); err != nil {
+		return nil, fmt.Errorf("mkvs/badger: invalid configuration: %w", err)
+	}
+	if err = cfg.ValidateMemoryOnly(); err != nil {
+		return nil, fmt.Errorf("mkvs/badger: invalid configuration: %w", err)
+	}
+
 	db := &badgerNodeDB{
 		logger:           logging.GetLogger("mkvs/db/badger"),
 		namespace:        cfg.Namespace,
 		readOnly:         cfg.ReadOnly,
+		memoryOnly:       cfg.MemoryOnly,
 		discardWriteLogs: cfg.DiscardWriteLogs,
+		maxWriteLogHops:  maxWriteLogHops,
+		rootCache:        newRootCache(cfg.RootCacheVersions),
+		rootsMetaCache:   newRootsMetadataCache(rootsMetadataCacheVersions),
+		metrics:          newDBMetrics(cfg),
+		corruption:       newCorruptionTracker(cfg),
+
+		tombstoneRetention: cfg.TombstoneRetentionVersions,
+		allowRepair:        cfg.AllowRepair,
+		verifyNodeHashes:   cfg.VerifyNodeHashes,
+
+		updatedNodesChunkSize:    defaultUpdatedNodesChunkSize,
+		multipartNodeFilterLimit: defaultMultipartNodeFilterLimit,
+	}
+	if cfg.BadgerOptions != nil {
+		db.debugIterate = cfg.BadgerOptions.DebugIterate
+	}
+	if cfg.BadgerOptions != nil && cfg.BadgerOptions.UpdatedNodesChunkSize > 0 {
+		db.updatedNodesChunkSize = int(cfg.BadgerOptions.UpdatedNodesChunkSize)
 	}
+	if cfg.BadgerOptions != nil && cfg.BadgerOptions.MultipartNodeFilterLimit != 0 {
+		db.multipartNodeFilterLimit = cfg.BadgerOptions.MultipartNodeFilterLimit
+	}
+	db.nodeCache = newNodeCache(cfg.NodeCacheSize, db.metrics)
 	opts := commonConfigToBadgerOptions(cfg, db)
+	db.logger.Info("using badger options",
+		"mem_table_size", opts.MemTableSize,
+		"base_table_size", opts.BaseTableSize,
+		"block_cache_size", opts.BlockCacheSize,
+		"num_compactors", opts.NumCompactors,
+		"compact_l0_on_close", opts.CompactL0OnClose,
+		"value_threshold", opts.ValueThreshold,
+		"sync_writes", opts.SyncWrites,
+		"split_metadata", isSplitMetadata(cfg),
+	)
 
-	var err error
 	if db.db, err = badger.OpenManaged(opts); err != nil {
 		return nil, fmt.Errorf("mkvs/badger: failed to open database: %w", err)
 	}
@@ -86,20 +180,69 @@ func New(cfg *api.Config) (api.NodeDB, error) {
 	// Make sure that we can discard any deleted/invalid metadata.
 	db.db.SetDiscardTs(tsMetadata)
 
+	// Open the metadata store, migrating metadata between stores if needed.
+	if err = db.openMetadataStore(cfg, opts, isSplitMetadata(cfg)); err != nil {
+		_ = db.db.Close()
+		return nil, fmt.Errorf("mkvs/badger: %w", err)
+	}
+
 	// Load database metadata.
 	if err = db.load(); err != nil {
-		_ = db.db.Close()
+		_ = db.closeStores()
 		return nil, fmt.Errorf("mkvs/badger: failed to load metadata: %w", err)
 	}
 
-	// Cleanup any multipart restore remnants, since they can't be used anymore.
-	if err = db.cleanMultipartLocked(true); err != nil {
-		_ = db.db.Close()
-		return nil, fmt.Errorf("mkvs/badger: failed to clean leftovers from multipart restore: %w", err)
+	// Complete any finalization interrupted before its metadata was committed.
+	if err = db.recoverFinalize(); err != nil {
+		_ = db.closeStores()
+		return nil, fmt.Errorf("mkvs/badger: failed to recover interrupted finalization: %w", err)
+	}
+
+	// Complete the metadata updates of any pruning interrupted before they were committed.
+	if err = db.recoverPrune(); err != nil {
+		_ = db.closeStores()
+		return nil, fmt.Errorf("mkvs/badger: failed to recover interrupted pruning: %w", err)
+	}
+
+	// Cleanup any multipart restore remnants, unless the restore should be resumed.
+	switch version := db.meta.getMultipartVersion(); {
+	case version != multipartVersionNone && cfg.AllowResumeMultipart:
+		db.logger.Info("keeping interrupted multipart restore for resumption",
+			"version", version,
+		)
+		db.multipartVersion = version
+		if db.multipartNodeFilter, err = db.newMultipartNodeFilter(version); err != nil {
+			_ = db.closeStores()
+			return nil, err
+		}
+	default:
+		if err = db.cleanMultipartLocked(true); err != nil {
+			_ = db.closeStores()
+			return nil, fmt.Errorf("mkvs/badger: failed to clean leftovers from multipart restore: %w", err)
+		}
 	}
 
-	db.gc = cmnBadger.NewGCWorker(db.logger, db.db)
-	db.gc.Start()
+	// Warm up the root cache so that the first rounds don't need to hit the database.
+	if err = db.warmUpRootCache(); err != nil {
+		_ = db.closeStores()
+		return nil, fmt.Errorf("mkvs/badger: failed to warm up root cache: %w", err)
+	}
+
+	db.metrics.versions(&db.meta)
+	db.metrics.multipart(db.multipartVersion)
+
+	// There is no value log to garbage collect in memory-only mode.
+	if !db.memoryOnly {
+		db.gc = cmnBadger.NewGCWorker(db.logger, db.db)
+		db.gc.Start()
+		if db.metaDB != db.db {
+			db.metaGC = cmnBadger.NewGCWorker(db.logger, db.metaDB)
+			db.metaGC.Start()
+		}
+	}
+
+	db.pruner = newPruner(db, cfg)
+	db.pruner.start()
 
 	return db, nil
 }
@@ -110,24 +253,93 @@ type badgerNodeDB struct { // nolint: maligned
 	namespace common.Namespace
 
 	readOnly         bool
+	memoryOnly       bool
 	discardWriteLogs bool
+	maxWriteLogHops  uint8
+
+	// rootCache is an optional cache of roots known to exist in recent versions.
+	rootCache *rootCache
+	// rootsMetaCache is a cache of decoded roots metadata of recent versions.
+	rootsMetaCache *rootsMetadataCache
+	// nodeCache is an optional cache of unmarshalled nodes.
+	nodeCache *nodeCache
+	// metrics is the optional metrics reporter.
+	metrics *dbMetrics
+	// corruption tracks corruption-class errors to determine the health of the database.
+	corruption *corruptionTracker
+	// tombstoneRetention is the number of versions for which tombstones are retained. If zero,
+	// tombstones are not recorded.
+	tombstoneRetention uint64
+	// allowRepair specifies whether roots may be inserted into already finalized versions.
+	allowRepair bool
+	// verifyNodeHashes specifies whether hashes of nodes are verified when reading and writing.
+	verifyNodeHashes bool
+	// updatedNodesChunkSize is the maximum size in bytes of a single root updated nodes record.
+	updatedNodesChunkSize int
+	// multipartNodeFilterLimit is the maximum number of existing nodes for which a multipart node
+	// filter is built. If negative, the filter is disabled.
+	multipartNodeFilterLimit int64
+	// debugIterate specifies whether raw key/value iteration via DebugIterate is enabled.
+	debugIterate bool
+	// finalizeInterruptFn is called after the removals of a finalization are flushed but before
+	// its metadata is committed. It is only used in tests to simulate a crash.
+	finalizeInterruptFn func() error
+	// pruneInterruptFn is called after the removals of a pruning are flushed but before its
+	// metadata is committed. It is only used in tests to simulate a crash.
+	pruneInterruptFn func() error
+	// pruneVersionFn is called after all removals of a version being pruned have been staged. It
+	// is only used in tests to simulate a failure while pruning a version.
+	pruneVersionFn func(version uint64) error
+	// commitMultiInterruptFn is called after the batches of a multi-root commit are flushed but
+	// before the roots metadata is committed. It is only used in tests to simulate a crash.
+	commitMultiInterruptFn func() error
+	// pruner is the optional background pruner.
+	pruner *pruner
 
 	multipartVersion uint64
+	// multipartNodeFilter is the filter over nodes that existed when the multipart insert was
+	// started or resumed. If nil, all inserted nodes are looked up.
+	multipartNodeFilter *nodeFilter
+	// multipartNodesWritten is the number of nodes recorded in the multipart insert log since the
+	// multipart insert was started or resumed.
+	multipartNodesWritten atomic.Uint64
+	// multipartBytesWritten is the number of bytes written since the multipart insert was started
+	// or resumed.
+	multipartBytesWritten atomic.Uint64
+	// openBatches is the number of batches that still hold badger resources.
+	openBatches atomic.Int64
 
 	db *badger.DB
-	gc *cmnBadger.GCWorker
+	// metaDB is the store holding all data at the metadata timestamp. It is the same as db unless
+	// metadata is split into a separate store.
+	metaDB *badger.DB
+	// syncSplitStores specifies whether writes to split stores need to be explicitly synced to
+	// preserve their order across the stores.
+	syncSplitStores bool
+	gc              *cmnBadger.GCWorker
+	metaGC          *cmnBadger.GCWorker
+	// manualGCLock makes sure that only one manually triggered garbage collection runs at a time.
+	manualGCLock sync.Mutex
 
 	// metaUpdateLock must be held at any point where data at tsMetadata is read and updated. This
 	// is required because all metadata updates happen at the same timestamp and as such conflicts
 	// cannot be detected.
 	metaUpdateLock sync.Mutex
 	meta           metadata
+	// pins are the versions pinned by readers. Versions are pinned and pins are checked while
+	// holding metaUpdateLock.
+	pins api.VersionPins
+
+	// lastErrorLock protects lastError.
+	lastErrorLock sync.RWMutex
+	// lastError is the last error encountered by an operation that cannot report errors.
+	lastError error
 
 	closeOnce sync.Once
 }
 
 func (d *badgerNodeDB) load() error {
-	tx := d.db.NewTransactionAt(tsMetadata, true)
+	tx := d.metaDB.NewTransactionAt(tsMetadata, true)
 	defer tx.Discard()
 
 	// Check first if the database is even usable.
@@ -177,6 +389,85 @@ func (d *badgerNodeDB) load() error {
 	return tx.CommitAt(tsMetadata, nil)
 }
 
+// warmUpRootCache loads the roots of the last finalized versions into the root cache.
+func (d *badgerNodeDB) warmUpRootCache() error {
+	if d.rootCache == nil {
+		return nil
+	}
+
+	lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion()
+	if !exists {
+		return nil
+	}
+	startVersion := d.meta.getEarliestVersion()
+	if maxVersions := uint64(d.rootCache.maxVersions); lastFinalizedVersion-startVersion >= maxVersions { //nolint:gosec
+		startVersion = lastFinalizedVersion - maxVersions + 1
+	}
+
+	tx := d.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	for version := startVersion; version <= lastFinalizedVersion; version++ {
+		if err := d.warmUpRootCacheVersion(tx, version); err != nil {
+			return err
+		}
+	}
+
+	d.logger.Debug("root cache warmed up",
+		"start_version", startVersion,
+		"end_version", lastFinalizedVersion,
+		"num_versions", d.rootCache.len(),
+	)
+	return nil
+}
+
+// warmUpRootCacheVersion loads the roots of the given version into the root cache. Only roots
+// whose root node is present are cached.
+func (d *badgerNodeDB) warmUpRootCacheVersion(tx *badger.Txn, version uint64) error {
+	rootsMeta, err := loadRootsMetadata(tx, version)
+	if err != nil {
+		return err
+	}
+
+	vtx := d.db.NewTransactionAt(versionToTs(version), false)
+	defer vtx.Discard()
+
+	keyBuf := getKeyBuffer()
+	defer putKeyBuffer(keyBuf)
+
+	for rootHash := range rootsMeta.Roots {
+		*keyBuf = appendRootNodeKey((*keyBuf)[:0], &rootHash)
+		_, err = vtx.Get(*keyBuf)
+		switch err {
+		case nil:
+			d.rootCache.add(version, rootHash)
+		case badger.ErrKeyNotFound:
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// getRootsMetadata returns the roots metadata for the given version, using the roots metadata
+// cache if possible. The returned metadata must not be modified.
+func (d *badgerNodeDB) getRootsMetadata(version uint64) (*rootsMetadata, error) {
+	rootsMeta, generation, ok := d.rootsMetaCache.get(version)
+	if ok {
+		return rootsMeta, nil
+	}
+
+	tx := d.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	rootsMeta, err := loadRootsMetadata(tx, version)
+	if err != nil {
+		return nil, err
+	}
+	d.rootsMetaCache.fill(generation, rootsMeta)
+	return rootsMeta, nil
+}
+
 func (d *badgerNodeDB) sanityCheckNamespace(ns common.Namespace) error {
 	if !ns.Equal(&d.namespace) {
 		return api.ErrBadNamespace
@@ -184,9 +475,22 @@ func (d *badgerNodeDB) sanityCheckNamespace(ns common.Namespace) error {
 	return nil
 }
 
-func (d *badgerNodeDB) checkRoot(txn *badger.Txn, root node.Root) error {
+// itemGetter is the part of a badger transaction used for point lookups.
+type itemGetter interface {
+	Get(key []byte) (*badger.Item, error)
+}
+
+func (d *badgerNodeDB) checkRoot(txn itemGetter, root node.Root) error {
 	rootHash := api.TypedHashFromRoot(root)
-	if _, err := txn.Get(rootNodeKeyFmt.Encode(&rootHash)); err != nil {
+	if d.rootCache.has(root.Version, rootHash) {
+		return nil
+	}
+
+	keyBuf := getKeyBuffer()
+	defer putKeyBuffer(keyBuf)
+
+	*keyBuf = appendRootNodeKey(*keyBuf, &rootHash)
+	if _, err := txn.Get(*keyBuf); err != nil {
 		switch err {
 		case badger.ErrKeyNotFound:
 			return api.ErrRootNotFound
@@ -194,7 +498,7 @@ func (d *badgerNodeDB) checkRoot(txn *badger.Txn, root node.Root) error {
 			d.logger.Error("failed to check root existence",
 				"err", err,
 			)
-			return fmt.Errorf("mkvs/badger: failed to check root existence while getting node from backing store: %w", err)
+			return fmt.Errorf("mkvs/badger: failed to check root existence while getting node from backing store: %w", d.failure(metricsOpCheckRoot, err))
 		}
 	}
 	return nil
@@ -214,7 +518,7 @@ func (d *badgerNodeDB) cleanMultipartLocked(removeNodes bool) error {
 		return nil
 	}
 
-	txn := d.db.NewTransactionAt(tsMetadata, false)
+	txn := d.metaDB.NewTransactionAt(tsMetadata, false)
 	defer txn.Discard()
 
 	opts := badger.DefaultIteratorOptions
@@ -224,6 +528,9 @@ func (d *badgerNodeDB) cleanMultipartLocked(removeNodes bool) error {
 
 	batch := d.db.NewWriteBatchAt(versionToTs(version))
 	defer batch.Cancel()
+	// Metadata batch collects removals of the node log entries.
+	metaBatch := d.metaDB.NewWriteBatchAt(tsMetadata)
+	defer metaBatch.Cancel()
 
 	var logged bool
 	for it.Rewind(); it.Valid(); it.Next() {
@@ -243,24 +550,34 @@ func (d *badgerNodeDB) cleanMultipartLocked(removeNodes bool) error {
 				if err := batch.Delete(nodeKeyFmt.Encode(&h)); err != nil {
 					return err
 				}
+				d.nodeCache.remove(h)
 			default:
 				if err := batch.Delete(rootNodeKeyFmt.Encode(&hash)); err != nil {
 					return err
 				}
+				if err := metaBatch.Delete(rootStatsKeyFmt.Encode(version, &hash)); err != nil {
+					return err
+				}
 			}
 		}
-		if err := batch.DeleteAt(key, tsMetadata); err != nil {
+		if err := metaBatch.Delete(it.Item().KeyCopy(nil)); err != nil {
 			return err
 		}
 	}
 
-	// Flush batch first. If anything fails, having corrupt
-	// multipart info in d.meta shouldn't hurt us next run.
+	// Flush batches first, removing nodes before their log entries. If anything fails, having
+	// corrupt multipart info in d.meta shouldn't hurt us next run.
 	if err := batch.Flush(); err != nil {
 		return err
 	}
+	if err := d.syncData(); err != nil {
+		return err
+	}
+	if err := metaBatch.Flush(); err != nil {
+		return err
+	}
 
-	metaTx := d.db.NewTransactionAt(tsMetadata, true)
+	metaTx := d.metaDB.NewTransactionAt(tsMetadata, true)
 	defer metaTx.Discard()
 	if err := d.meta.setMultipartVersion(metaTx, 0); err != nil {
 		return err
@@ -270,6 +587,10 @@ func (d *badgerNodeDB) cleanMultipartLocked(removeNodes bool) error {
 	}
 
 	d.multipartVersion = multipartVersionNone
+	d.multipartNodeFilter = nil
+	d.multipartNodesWritten.Store(0)
+	d.multipartBytesWritten.Store(0)
+	d.metrics.multipart(d.multipartVersion)
 	return nil
 }
 
@@ -286,6 +607,14 @@ func (d *badgerNodeDB) GetNode(root node.Root, ptr *node.Pointer) (node.Node, er
 		return nil, api.ErrNodeNotFound
 	}
 
+	// Avoid the transaction altogether in case both the root and the node are cached.
+	if d.rootCache.has(root.Version, api.TypedHashFromRoot(root)) {
+		if n, ok := d.nodeCache.get(ptr.Hash); ok {
+			d.metrics.getNode(true)
+			return n, nil
+		}
+	}
+
 	tx := d.db.NewTransactionAt(versionToTs(root.Version), false)
 	defer tx.Discard()
 
@@ -293,17 +622,28 @@ func (d *badgerNodeDB) GetNode(root node.Root, ptr *node.Pointer) (node.Node, er
 	if err := d.checkRoot(tx, root); err != nil {
 		return nil, err
 	}
+	if n, ok := d.nodeCache.get(ptr.Hash); ok {
+		d.metrics.getNode(true)
+		return n, nil
+	}
 
-	item, err := tx.Get(nodeKeyFmt.Encode(&ptr.Hash))
+	// The key is only referenced by the item, which does not outlive this call.
+	keyBuf := getKeyBuffer()
+	defer putKeyBuffer(keyBuf)
+
+	*keyBuf = appendNodeKey(*keyBuf, &ptr.Hash)
+	item, err := tx.Get(*keyBuf)
 	switch err {
 	case nil:
+		d.metrics.getNode(true)
 	case badger.ErrKeyNotFound:
+		d.metrics.getNode(false)
 		return nil, api.ErrNodeNotFound
 	default:
 		d.logger.Error("failed to Get node from backing store",
 			"err", err,
 		)
-		return nil, fmt.Errorf("mkvs/badger: failed to Get node from backing store: %w", err)
+		return nil, fmt.Errorf("mkvs/badger: failed to Get node from backing store: %w", d.failure(metricsOpGetNode, err))
 	}
 
 	var n node.Node
@@ -315,13 +655,31 @@ func (d *badgerNodeDB) GetNode(root node.Root, ptr *node.Pointer) (node.Node, er
 		d.logger.Error("failed to unmarshal node",
 			"err", err,
 		)
-		return nil, fmt.Errorf("mkvs/badger: failed to unmarshal node: %w", err)
+		return nil, fmt.Errorf("mkvs/badger: failed to unmarshal node: %w", d.failure(metricsOpGetNode, err))
+	}
+	if d.verifyNodeHashes {
+		n.UpdateHash()
+		if h := n.GetHash(); !h.Equal(&ptr.Hash) {
+			d.logger.Error("node hash mismatch, database is corrupted",
+				"expected_hash", ptr.Hash,
+				"hash", h,
+			)
+			d.metrics.nodeCorrupted()
+			return nil, d.failure(metricsOpGetNode, fmt.Errorf("%w: expected hash %s, got %s", api.ErrNodeCorrupted, ptr.Hash, h))
+		}
 	}
+	d.nodeCache.put(n)
 
 	return n, nil
 }
 
 func (d *badgerNodeDB) GetWriteLog(ctx context.Context, startRoot, endRoot node.Root) (writelog.Iterator, error) {
+	// Number of hops of the found write log path, zero if not found.
+	var hops int
+	defer func() {
+		d.metrics.getWriteLog(hops)
+	}()
+
 	if d.discardWriteLogs {
 		return nil, api.ErrWriteLogNotFound
 	}
@@ -357,8 +715,11 @@ func (d *badgerNodeDB) GetWriteLog(ctx context.Context, startRoot, endRoot node.
 	// - State updates: s -> s' (a single hop)
 	// - I/O updates: empty -> i -> io (two hops)
 	//
-	// For this reason, we currently refuse to traverse more than two hops.
-	const maxAllowedHops = 2
+	// For this reason, by default we refuse to traverse more than two hops. The limit can be
+	// raised via configuration for runtimes that produce longer chains. To bound the amount of
+	// work in case of many forks, the total number of visited write logs is also limited.
+	maxAllowedHops := d.maxWriteLogHops
+	var visited int
 
 	type wlItem struct {
 		depth       uint8
@@ -389,6 +750,16 @@ func (d *badgerNodeDB) GetWriteLog(ctx context.Context, startRoot, endRoot node.
 					return nil, ctx.Err()
 				}
 
+				visited++
+				if visited > maxWriteLogVisited {
+					d.logger.Warn("write log search visited too many write logs",
+						"start_root", startRoot,
+						"end_root", endRoot,
+						"max_hops", maxAllowedHops,
+					)
+					return nil, api.ErrWriteLogNotFound
+				}
+
 				item := it.Item()
 
 				var decVersion uint64
@@ -410,6 +781,7 @@ func (d *badgerNodeDB) GetWriteLog(ctx context.Context, startRoot, endRoot node.
 				}
 				if nextItem.endRootHash.Equal(&startRootHash) {
 					// Path has been found, deserialize and stream write logs.
+					hops = len(nextItem.logKeys)
 					var index int
 					discardTx = false
 					// Close iterator now as ReviveHashedDBWriteLogs can close the txn immediately.
@@ -486,10 +858,7 @@ func (d *badgerNodeDB) GetRootsForVersion(version uint64) (roots []node.Root, er
 		return nil, nil
 	}
 
-	tx := d.db.NewTransactionAt(tsMetadata, false)
-	defer tx.Discard()
-
-	rootsMeta, err := loadRootsMetadata(tx, version)
+	rootsMeta, err := d.getRootsMetadata(version)
 	if err != nil {
 		return nil, err
 	}
@@ -520,18 +889,68 @@ func (d *badgerNodeDB) HasRoot(root node.Root) bool {
 		return false
 	}
 
-	tx := d.db.NewTransactionAt(tsMetadata, false)
-	defer tx.Discard()
+	rootHash := api.TypedHashFromRoot(root)
+	if d.rootCache.has(root.Version, rootHash) {
+		return true
+	}
 
-	rootsMeta, err := loadRootsMetadata(tx, root.Version)
+	rootsMeta, err := d.getRootsMetadata(root.Version)
 	if err != nil {
-		panic(err)
+		d.setLastError(d.failure(metricsOpHasRoot, fmt.Errorf("mkvs/badger: failed to load roots metadata: %w", err)))
+		return false
 	}
+	d.setLastError(nil)
 
-	_, exists := rootsMeta.Roots[api.TypedHashFromRoot(root)]
+	_, exists := rootsMeta.Roots[rootHash]
 	return exists
 }
 
+func (d *badgerNodeDB) HasRoots(roots []node.Root) ([]bool, error) {
+	exists := make([]bool, len(roots))
+
+	// Group roots by version so that roots metadata is loaded only once per version.
+	byVersion := make(map[uint64][]int)
+	earliestVersion := d.meta.getEarliestVersion()
+	for i, root := range roots {
+		if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+			continue
+		}
+
+		// An empty root is always implicitly present.
+		if root.Hash.IsEmpty() {
+			exists[i] = true
+			continue
+		}
+
+		// If the version is earlier than the earliest version, we don't have the root.
+		if root.Version < earliestVersion {
+			continue
+		}
+
+		if d.rootCache.has(root.Version, api.TypedHashFromRoot(root)) {
+			exists[i] = true
+			continue
+		}
+
+		byVersion[root.Version] = append(byVersion[root.Version], i)
+	}
+	if len(byVersion) == 0 {
+		return exists, nil
+	}
+
+	for version, indices := range byVersion {
+		rootsMeta, err := d.getRootsMetadata(version)
+		if err != nil {
+			return nil, fmt.Errorf("mkvs/badger: failed to load roots metadata: %w", err)
+		}
+
+		for _, i := range indices {
+			_, exists[i] = rootsMeta.Roots[api.TypedHashFromRoot(roots[i])]
+		}
+	}
+	return exists, nil
+}
+
 func (d *badgerNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
 	if d.readOnly {
 		return api.ErrReadOnly
@@ -541,6 +960,7 @@ func (d *badgerNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
 		return fmt.Errorf("mkvs/badger: need at least one root to finalize")
 	}
 	version := roots[0].Version
+	start := time.Now()
 
 	d.metaUpdateLock.Lock()
 	defer d.metaUpdateLock.Unlock()
@@ -552,9 +972,12 @@ func (d *badgerNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
 	// Version batch collects removals at the version timestamp.
 	versionBatch := d.db.NewWriteBatchAt(versionToTs(version))
 	defer versionBatch.Cancel()
-	// Transaction is used to read at the version timestamp.
-	tx := d.db.NewTransactionAt(versionToTs(version), true)
+	// Transaction is used to read and update metadata at the version timestamp.
+	tx := d.metaDB.NewTransactionAt(versionToTs(version), true)
 	defer tx.Discard()
+	// Data transaction is used to read data at the version timestamp.
+	dtx := d.db.NewTransactionAt(versionToTs(version), false)
+	defer dtx.Discard()
 
 	// Make sure that the previous version has been finalized (if we are not restoring).
 	lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion()
@@ -577,7 +1000,7 @@ func (d *badgerNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
 	}
 
 	var rootsChanged bool
-	rootsMeta, err := loadRootsMetadata(tx, version)
+	rootsMeta, err := newRootsMetadataSet(tx, d.rootsMetaCache).load(version)
 	if err != nil {
 		return err
 	}
@@ -603,60 +1026,73 @@ func (d *badgerNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
 	for iroot := range finalizedRoots {
 		h := iroot.Hash()
 		if _, ok := rootsMeta.Roots[iroot]; !ok && !h.IsEmpty() {
-			return api.ErrRootNotFound
+			return fmt.Errorf("%w: %s", api.ErrRootNotFound, node.Root{
+				Namespace: d.namespace,
+				Version:   version,
+				Type:      iroot.Type(),
+				Hash:      h,
+			})
 		}
 	}
 
+	// Record the intent before removing anything so that an interrupted finalization can be
+	// completed on the next open.
+	if err = d.putFinalizeIntent(version, finalizedRoots); err != nil {
+		return err
+	}
+
+	// Record tombstones for keys deleted by the finalized roots.
+	tombstoneBatch := d.metaDB.NewWriteBatchAt(tsMetadata)
+	defer tombstoneBatch.Cancel()
+	if err = d.updateTombstonesLocked(tx, tombstoneBatch, version, rootsMeta, finalizedRoots); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to update tombstones: %w", err)
+	}
+
 	// Go through all roots and prune them based on whether they are finalized or not.
 	maybeLoneNodes := make(map[hash.Hash]bool)
 	notLoneNodes := make(map[hash.Hash]bool)
 
 	for rootHash := range rootsMeta.Roots {
 		// TODO: Consider colocating updated nodes with the root metadata.
-		rootUpdatedNodesKey := rootUpdatedNodesKeyFmt.Encode(version, &rootHash)
+		finalized := finalizedRoots[rootHash]
 
 		// Load hashes of nodes added during this version for this root.
-		item, err := tx.Get(rootUpdatedNodesKey)
-		if err != nil {
-			panic(fmt.Errorf("mkvs/badger: corrupted/missing root updated nodes index: %w", err))
-		}
-
-		var updatedNodes []updatedNode
-		err = item.Value(func(data []byte) error {
-			return cbor.UnmarshalTrusted(data, &updatedNodes)
-		})
-		if err != nil {
-			panic(fmt.Errorf("mkvs/badger: corrupted root updated nodes index: %w", err))
-		}
-
-		if finalizedRoots[rootHash] {
-			// Make sure not to remove any nodes shared with finalized roots.
+		updatedNodesKeys, err := visitUpdatedNodes(tx, version, rootHash, func(updatedNodes []updatedNode) {
 			for _, n := range updatedNodes {
-				if n.Removed {
+				switch {
+				case finalized && n.Removed:
 					maybeLoneNodes[n.Hash] = true
-				} else {
+				case finalized:
+					// Make sure not to remove any nodes shared with finalized roots.
 					notLoneNodes[n.Hash] = true
-				}
-			}
-		} else {
-			// Remove any non-finalized roots. It is safe to remove these nodes as Badger's version
-			// control will make sure they are not removed if they are resurrected in any later
-			// version as long as we make sure that these nodes are not shared with any finalized
-			// roots added in the same version.
-			for _, n := range updatedNodes {
-				if !n.Removed {
+				case !n.Removed:
+					// Remove any non-finalized roots. It is safe to remove these nodes as Badger's
+					// version control will make sure they are not removed if they are resurrected
+					// in any later version as long as we make sure that these nodes are not shared
+					// with any finalized roots added in the same version.
 					maybeLoneNodes[n.Hash] = true
 				}
 			}
+		})
+		if err != nil {
+			return err
+		}
 
+		if !finalized {
 			delete(rootsMeta.Roots, rootHash)
+			d.rootCache.remove(version, rootHash)
 			rootsChanged = true
 
+			// Remove the root node entry so that the discarded root is no longer reachable.
+			if err = versionBatch.Delete(rootNodeKeyFmt.Encode(&rootHash)); err != nil {
+				return err
+			}
+
 			// Remove write logs for the non-finalized root.
 			if !d.discardWriteLogs {
 				if err = func() error {
 					rootWriteLogsPrefix := writeLogKeyFmt.Encode(version, &rootHash)
-					wit := tx.NewIterator(badger.IteratorOptions{Prefix: rootWriteLogsPrefix})
+					wit := dtx.NewIterator(badger.IteratorOptions{Prefix: rootWriteLogsPrefix})
 					defer wit.Close()
 
 					for wit.Rewind(); wit.Valid(); wit.Next() {
@@ -672,9 +1108,21 @@ func (d *badgerNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
 		}
 
 		// Set of updated nodes no longer needed after finalization.
-		if err = tx.Delete(rootUpdatedNodesKey); err != nil {
+		for _, key := range updatedNodesKeys {
+			if err = tx.Delete(key); err != nil {
+				return err
+			}
+		}
+		// Same for the set of keys changed by the root.
+		if err = tx.Delete(rootPendingKeysKeyFmt.Encode(version, &rootHash)); err != nil {
 			return err
 		}
+		// Discarded roots no longer contribute to the version statistics.
+		if !finalized {
+			if err = tx.Delete(rootStatsKeyFmt.Encode(version, &rootHash)); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Clean any lone nodes.
@@ -683,16 +1131,27 @@ func (d *badgerNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
 			continue
 		}
 
-		key := nodeKeyFmt.Encode(&h)
-		if err := versionBatch.Delete(key); err != nil {
+		if err := versionBatch.Delete(newNodeKey(&h)); err != nil {
 			return err
 		}
+		d.nodeCache.remove(h)
 	}
 
 	// Commit batch.
 	if err := versionBatch.Flush(); err != nil {
 		return err
 	}
+	if err := tombstoneBatch.Flush(); err != nil {
+		return err
+	}
+	if err := d.syncData(); err != nil {
+		return err
+	}
+	if d.finalizeInterruptFn != nil {
+		if err := d.finalizeInterruptFn(); err != nil {
+			return err
+		}
+	}
 
 	// Save roots metadata if changed.
 	if rootsChanged {
@@ -705,10 +1164,18 @@ func (d *badgerNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
 	if err := d.meta.setLastFinalizedVersion(tx, version); err != nil {
 		return fmt.Errorf("mkvs/badger: failed to set last finalized version: %w", err)
 	}
+	// The finalization is complete once the metadata is committed.
+	if err := tx.Delete(finalizeIntentKeyFmt.Encode()); err != nil {
+		return err
+	}
 
 	if err := tx.CommitAt(tsMetadata, nil); err != nil {
 		return fmt.Errorf("mkvs/badger: failed to commit metadata: %w", err)
 	}
+	d.rootsMetaCache.put(rootsMeta)
+	for rootHash := range rootsMeta.Roots {
+		d.rootCache.add(version, rootHash)
+	}
 
 	// Clean multipart metadata if there is any.
 	if d.multipartVersion != multipartVersionNone {
@@ -716,41 +1183,167 @@ func (d *badgerNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
 			return err
 		}
 	}
+
+	d.metrics.versions(&d.meta)
+	d.metrics.finalize(start)
+	d.pruner.kick()
 	return nil
 }
 
+func (d *badgerNodeDB) Pin(version uint64) (func(), error) {
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	if err := api.CheckPinVersion(d, version); err != nil {
+		return nil, err
+	}
+	return d.pins.Pin(version)
+}
+
 func (d *badgerNodeDB) Prune(version uint64) error {
+	_, err := d.pruneRange(context.Background(), version, version, true)
+	return err
+}
+
+func (d *badgerNodeDB) PruneRange(ctx context.Context, startVersion, endVersion uint64) (int, error) {
+	return d.pruneRange(ctx, startVersion, endVersion, false)
+}
+
+func (d *badgerNodeDB) pruneRange(ctx context.Context, startVersion, endVersion uint64, exact bool) (int, error) {
 	if d.readOnly {
-		return api.ErrReadOnly
+		return 0, api.ErrReadOnly
+	}
+	if startVersion > endVersion {
+		return 0, fmt.Errorf("mkvs/badger: invalid prune range [%d, %d]", startVersion, endVersion)
 	}
 
 	d.metaUpdateLock.Lock()
 	defer d.metaUpdateLock.Unlock()
 
 	if d.multipartVersion != multipartVersionNone {
-		return api.ErrMultipartInProgress
+		return 0, api.ErrMultipartInProgress
 	}
 
-	// Make sure that the version that we try to prune has been finalized.
+	// Make sure that the versions that we try to prune have been finalized.
 	lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion()
-	if !exists || lastFinalizedVersion < version {
-		return api.ErrNotFinalized
+	if !exists || lastFinalizedVersion < startVersion || (exact && lastFinalizedVersion < endVersion) {
+		return 0, api.ErrNotFinalized
 	}
-	// Make sure that the version that we are trying to prune is the earliest version.
-	if version != d.meta.getEarliestVersion() {
-		return api.ErrNotEarliest
+	// Make sure that the first version that we are trying to prune is the earliest version.
+	if startVersion != d.meta.getEarliestVersion() {
+		return 0, api.ErrNotEarliest
 	}
-	// Make sure that the version that we are trying to prune is not the only finalized version.
-	if version == lastFinalizedVersion {
-		return api.ErrCannotPruneLatestVersion
+	// Make sure that we are not trying to prune the only finalized version.
+	if endVersion >= lastFinalizedVersion {
+		if exact || startVersion == lastFinalizedVersion {
+			return 0, api.ErrCannotPruneLatestVersion
+		}
+		endVersion = lastFinalizedVersion - 1
+	}
+	// Make sure that we are not trying to prune any pinned versions.
+	endVersion, err := d.pins.PrunableEnd(startVersion, endVersion)
+	if err != nil {
+		return 0, err
 	}
 
-	// Remove all roots in version.
-	batch := d.db.NewWriteBatchAt(versionToTs(version))
+	start := time.Now()
+	defer func() {
+		d.metrics.versions(&d.meta)
+		d.metrics.prune(start)
+	}()
+
+	var pruned int
+	for chunkStart := startVersion; chunkStart <= endVersion; {
+		chunkEnd := min(endVersion, chunkStart+pruneRangeChunkSize-1)
+		n, err := d.pruneChunkLocked(ctx, chunkStart, chunkEnd)
+		pruned += n
+		if err != nil {
+			return pruned, err
+		}
+		chunkStart = chunkEnd + 1
+	}
+	return pruned, nil
+}
+
+// pruneChunkLocked prunes versions in the given range using a single write batch and metadata
+// update. In case the context is canceled or pruning a version fails, the versions that were
+// completely pruned before are committed while the removals of the failed version are discarded.
+func (d *badgerNodeDB) pruneChunkLocked(ctx context.Context, startVersion, endVersion uint64) (int, error) {
+	batch := d.db.NewManagedWriteBatch()
 	defer batch.Cancel()
-	tx := d.db.NewTransactionAt(versionToTs(version), true)
+	tx := d.metaDB.NewTransactionAt(versionToTs(endVersion), true)
 	defer tx.Discard()
 
+	var (
+		pruned   int
+		pruneErr error
+	)
+	for version := startVersion; version <= endVersion; version++ {
+		if pruneErr = ctx.Err(); pruneErr != nil {
+			break
+		}
+		var stage pruneStage
+		if pruneErr = d.pruneVersionLocked(tx, &stage, version); pruneErr != nil {
+			break
+		}
+		if pruneErr = stage.apply(tx, batch, versionToTs(version)); pruneErr != nil {
+			// A partially applied version cannot be committed.
+			return 0, pruneErr
+		}
+		pruned++
+	}
+	if pruned == 0 {
+		return 0, pruneErr
+	}
+	lastPruned := startVersion + uint64(pruned) - 1 //nolint:gosec
+
+	// Record the intent before removing anything so that an interrupted pruning can be completed
+	// on the next open.
+	if err := d.putPruneIntent(startVersion, lastPruned); err != nil {
+		return 0, err
+	}
+
+	// Commit batch.
+	if err := batch.Flush(); err != nil {
+		return 0, fmt.Errorf("mkvs/badger: failed to flush batch: %w", err)
+	}
+	if err := d.syncData(); err != nil {
+		return 0, err
+	}
+	if d.pruneInterruptFn != nil {
+		if err := d.pruneInterruptFn(); err != nil {
+			return 0, err
+		}
+	}
+
+	// Update metadata. The pruning is complete once the metadata is committed.
+	if err := d.meta.setEarliestVersion(tx, lastPruned+1); err != nil {
+		return 0, fmt.Errorf("mkvs/badger: failed to set earliest version: %w", err)
+	}
+	if err := tx.Delete(pruneIntentKeyFmt.Encode()); err != nil {
+		return 0, err
+	}
+	if err := tx.CommitAt(tsMetadata, nil); err != nil {
+		return 0, fmt.Errorf("mkvs/badger: failed to commit: %w", err)
+	}
+	d.rootsMetaCache.invalidate(startVersion, lastPruned)
+
+	// Discard everything invalidated at or below the last pruned version.
+	d.db.SetDiscardTs(versionToTs(lastPruned + 1))
+
+	return pruned, pruneErr
+}
+
+// pruneVersionLocked stages the removal of all roots, nodes and write logs of the given version.
+// Metadata is read using the given transaction, which is not modified.
+func (d *badgerNodeDB) pruneVersionLocked(tx *badger.Txn, stage *pruneStage, version uint64) error {
+	// Invalidate cached roots before anything is removed so that lookups fall through.
+	d.rootCache.removeVersion(version)
+
+	ts := versionToTs(version)
+	rtx := d.db.NewTransactionAt(ts, false)
+	defer rtx.Discard()
+
 	rootsMeta, err := loadRootsMetadata(tx, version)
 	if err != nil {
 		return err
@@ -769,71 +1362,90 @@ func (d *badgerNodeDB) Prune(version uint64) error {
 			Type:      rootHash.Type(),
 			Hash:      rootHash.Hash(),
 		}
-		var innerErr error
-		err := api.Visit(context.Background(), d, root, func(_ context.Context, n node.Node) bool {
-			h := n.GetHash()
-			var item *badger.Item
-			if item, innerErr = tx.Get(nodeKeyFmt.Encode(&h)); innerErr != nil {
-				return false
-			}
-
-			if tsToVersion(item.Version()) == version {
-				if innerErr = batch.Delete(nodeKeyFmt.Encode(&h)); innerErr != nil {
-					return false
-				}
-			}
-			return true
-		})
-		if innerErr != nil {
-			return innerErr
-		}
-		if err != nil {
+		if err = d.pruneRootNodesLocked(stage, root); err != nil {
 			return err
 		}
 
-		if err = batch.Delete(rootNodeKeyFmt.Encode(&rootHash)); err != nil {
-			return err
-		}
+		stage.deleteVersioned(rootNodeKeyFmt.Encode(&rootHash))
 	}
 
 	// Delete roots metadata.
-	if err := tx.Delete(rootsMetadataKeyFmt.Encode(version)); err != nil {
-		return fmt.Errorf("mkvs/badger: failed to remove roots metadata: %w", err)
+	stage.deleteMeta(rootsMetadataKeyFmt.Encode(version))
+	for _, key := range versionStatsKeys(tx, version) {
+		stage.deleteMeta(key)
 	}
 
 	// Prune all write logs in version.
 	if !d.discardWriteLogs {
-		wtx := d.db.NewTransactionAt(versionToTs(version), false)
-		defer wtx.Discard()
-
 		prefix := writeLogKeyFmt.Encode(version)
-		it := wtx.NewIterator(badger.IteratorOptions{Prefix: prefix})
+		it := rtx.NewIterator(badger.IteratorOptions{Prefix: prefix})
 		defer it.Close()
 
 		for it.Rewind(); it.Valid(); it.Next() {
-			if err := batch.Delete(it.Item().KeyCopy(nil)); err != nil {
-				return err
-			}
+			stage.deleteVersioned(it.Item().KeyCopy(nil))
 		}
 	}
 
-	// Commit batch.
-	if err := batch.Flush(); err != nil {
-		return fmt.Errorf("mkvs/badger: failed to flush batch: %w", err)
+	if d.pruneVersionFn != nil {
+		return d.pruneVersionFn(version)
 	}
+	return nil
+}
 
-	// Update metadata.
-	if err := d.meta.setEarliestVersion(tx, version+1); err != nil {
-		return fmt.Errorf("mkvs/badger: failed to set earliest version: %w", err)
+// pruneRootNodesLocked traverses the given root and stages the removal of all nodes created in the
+// version of the root.
+func (d *badgerNodeDB) pruneRootNodesLocked(stage *pruneStage, root node.Root) error {
+	ts := versionToTs(root.Version)
+
+	// Transactions are not safe for concurrent use, so each traversal goroutine needs its own.
+	txns := make(chan *badger.Txn, pruneVisitConcurrency)
+	for range pruneVisitConcurrency {
+		txns <- d.db.NewTransactionAt(ts, false)
 	}
-	if err := tx.CommitAt(tsMetadata, nil); err != nil {
-		return fmt.Errorf("mkvs/badger: failed to commit: %w", err)
+	defer func() {
+		close(txns)
+		for tx := range txns {
+			tx.Discard()
+		}
+	}()
+
+	var (
+		innerErrLock sync.Mutex
+		innerErr     error
+	)
+	setInnerErr := func(err error) {
+		innerErrLock.Lock()
+		defer innerErrLock.Unlock()
+		if innerErr == nil {
+			innerErr = err
+		}
 	}
 
-	// Discard everything invalidated at or below given version.
-	d.db.SetDiscardTs(versionToTs(version + 1))
+	err := api.VisitWithOptions(context.Background(), d, root, func(_ context.Context, n node.Node) bool {
+		tx := <-txns
+		defer func() { txns <- tx }()
 
-	return nil
+		keyBuf := getKeyBuffer()
+		defer putKeyBuffer(keyBuf)
+
+		h := n.GetHash()
+		*keyBuf = appendNodeKey(*keyBuf, &h)
+		item, err := tx.Get(*keyBuf)
+		if err != nil {
+			setInnerErr(err)
+			return false
+		}
+
+		if tsToVersion(item.Version()) == root.Version {
+			stage.deleteVersioned(newNodeKey(&h))
+			d.nodeCache.remove(h)
+		}
+		return true
+	}, api.VisitOptions{Concurrency: pruneVisitConcurrency})
+	if innerErr != nil {
+		return innerErr
+	}
+	return err
 }
 
 func (d *badgerNodeDB) StartMultipartInsert(version uint64) error {
@@ -853,16 +1465,25 @@ func (d *badgerNodeDB) StartMultipartInsert(version uint64) error {
 		return nil
 	}
 
-	tx := d.db.NewTransactionAt(tsMetadata, true)
+	filter, err := d.newMultipartNodeFilter(version)
+	if err != nil {
+		return err
+	}
+
+	tx := d.metaDB.NewTransactionAt(tsMetadata, true)
 	defer tx.Discard()
-	if err := d.meta.setMultipartVersion(tx, version); err != nil {
+	if err = d.meta.setMultipartVersion(tx, version); err != nil {
 		return err
 	}
-	if err := tx.CommitAt(tsMetadata, nil); err != nil {
+	if err = tx.CommitAt(tsMetadata, nil); err != nil {
 		return err
 	}
 
 	d.multipartVersion = version
+	d.multipartNodeFilter = filter
+	d.multipartNodesWritten.Store(0)
+	d.multipartBytesWritten.Store(0)
+	d.metrics.multipart(d.multipartVersion)
 
 	return nil
 }
@@ -874,6 +1495,26 @@ func (d *badgerNodeDB) AbortMultipartInsert() error {
 	return d.cleanMultipartLocked(true)
 }
 
+func (d *badgerNodeDB) GetMultipartVersion() uint64 {
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	return d.multipartVersion
+}
+
+// Implements api.NodeDB.
+func (d *badgerNodeDB) MultipartProgress() *api.MultipartProgress {
+	version := d.GetMultipartVersion()
+	if version == multipartVersionNone {
+		return nil
+	}
+	return &api.MultipartProgress{
+		Version:      version,
+		NodesWritten: d.multipartNodesWritten.Load(),
+		BytesWritten: d.multipartBytesWritten.Load(),
+	}
+}
+
 func (d *badgerNodeDB) NewBatch(oldRoot node.Root, version uint64, chunk bool) (api.Batch, error) {
 	// WARNING: There is a maximum batch size and maximum batch entry count.
 	// Both of these things are derived from the MaxTableSize option.
@@ -896,51 +1537,143 @@ func (d *badgerNodeDB) NewBatch(oldRoot node.Root, version uint64, chunk bool) (
 		return nil, api.ErrMultipartInProgress
 	}
 
-	var logBatch *badger.WriteBatch
-	var readTxn *badger.Txn
+	res := &batchResources{
+		db:  d,
+		bat: d.db.NewWriteBatchAt(versionToTs(version)),
+	}
 	if d.multipartVersion != multipartVersionNone {
 		// The node log is at a different version than the nodes themselves,
 		// which is awkward.
-		logBatch = d.db.NewWriteBatchAt(tsMetadata)
-		readTxn = d.db.NewTransactionAt(versionToTs(version), false)
-	}
-
-	return &badgerBatch{
-		db:             d,
-		bat:            d.db.NewWriteBatchAt(versionToTs(version)),
-		multipartNodes: logBatch,
-		readTxn:        readTxn,
-		oldRoot:        oldRoot,
-		chunk:          chunk,
-	}, nil
+		res.multipartNodes = d.metaDB.NewWriteBatchAt(tsMetadata)
+		res.readTxn = d.db.NewTransactionAt(versionToTs(version), false)
+	}
+
+	return d.newBatch(&badgerBatch{
+		oldRoot:    oldRoot,
+		version:    version,
+		chunk:      chunk,
+		nodeFilter: d.multipartNodeFilter,
+	}, res), nil
+}
+
+// newBatch attaches the given resources to the batch and makes sure that they are released even
+// in case the batch is abandoned without being committed, reset or closed.
+func (d *badgerNodeDB) newBatch(ba *badgerBatch, res *batchResources) *badgerBatch {
+	d.openBatches.Add(1)
+	ba.db = d
+	ba.batchResources = res
+	ba.cleanup = runtime.AddCleanup(ba, func(res *batchResources) {
+		res.db.logger.Warn("batch abandoned without being closed")
+		res.release()
+	}, res)
+	return ba
 }
 
 func (d *badgerNodeDB) Size() (int64, error) {
-	lsm, vlog := d.db.Size()
-	return lsm + vlog, nil
+	var size int64
+	for _, db := range d.stores() {
+		lsm, vlog := db.Size()
+		size += lsm + vlog
+	}
+	return size, nil
+}
+
+func (d *badgerNodeDB) Stats(countRoots bool) (*api.Stats, error) {
+	var stats api.Stats
+	for _, db := range d.stores() {
+		lsmSize, vlogSize := db.Size()
+		stats.LSMSize += lsmSize
+		stats.ValueLogSize += vlogSize
+
+		// Badger does not expose value log discard statistics, so pending garbage is estimated
+		// from the stale data in LSM tables.
+		for _, level := range db.Levels() {
+			stats.PendingGarbage += level.StaleDatSize
+		}
+	}
+
+	stats.EarliestVersion = d.meta.getEarliestVersion()
+	if version, exists := d.meta.getLastFinalizedVersion(); exists {
+		stats.LatestVersion = &version
+	}
+
+	if !countRoots {
+		return &stats, nil
+	}
+
+	// Count root nodes as these are removed once a root is pruned.
+	tx := d.db.NewTransactionAt(math.MaxUint64, false)
+	defer tx.Discard()
+
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: rootNodeKeyFmt.Encode()})
+	defer it.Close()
+
+	var numRoots uint64
+	for it.Rewind(); it.Valid(); it.Next() {
+		numRoots++
+	}
+	stats.NumRoots = &numRoots
+	return &stats, nil
 }
 
 func (d *badgerNodeDB) Sync() error {
-	return d.db.Sync()
+	if d.memoryOnly {
+		return nil
+	}
+	for _, db := range d.stores() {
+		if err := db.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (d *badgerNodeDB) Close() {
 	d.closeOnce.Do(func() {
+		d.pruner.stop()
+		d.pins.Close()
+
 		if d.gc != nil {
 			d.gc.Stop()
 		}
+		if d.metaGC != nil {
+			d.metaGC.Stop()
+		}
 
-		if err := d.db.Close(); err != nil {
+		if err := d.closeStores(); err != nil {
 			d.logger.Error("close returned error",
 				"err", err,
 			)
+			_ = d.failure(metricsOpClose, err)
 		}
 	})
 }
 
-type badgerBatch struct {
-	api.BaseBatch
+// stores returns the main store followed by the metadata store in case it is split.
+func (d *badgerNodeDB) stores() []*badger.DB {
+	if d.metaDB == nil || d.metaDB == d.db {
+		return []*badger.DB{d.db}
+	}
+	return []*badger.DB{d.db, d.metaDB}
+}
+
+// closeStores closes the main store and the metadata store in case it is split.
+func (d *badgerNodeDB) closeStores() error {
+	var errs []error
+	for _, db := range d.stores() {
+		if err := db.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
 
+// batchResources are the badger resources held by a batch until it is committed, reset or
+// closed.
+//
+// They are kept separate from the batch so that they can be released by a cleanup function once
+// an abandoned batch is garbage collected.
+type batchResources struct {
 	db             *badgerNodeDB
 	bat            *badger.WriteBatch
 	multipartNodes *badger.WriteBatch
@@ -949,12 +1682,61 @@ type badgerBatch struct {
 	// a multipart restore.
 	readTxn *badger.Txn
 
+	released bool
+}
+
+// release cancels the write batches and discards the read transaction. It is safe to call it
+// multiple times.
+func (res *batchResources) release() {
+	if res.released {
+		return
+	}
+	res.released = true
+
+	res.bat.Cancel()
+	if res.multipartNodes != nil {
+		res.multipartNodes.Cancel()
+	}
+	if res.readTxn != nil {
+		res.readTxn.Discard()
+	}
+	res.db.openBatches.Add(-1)
+}
+
+type badgerBatch struct {
+	api.BaseBatch
+	*batchResources
+
+	db      *badgerNodeDB
+	cleanup runtime.Cleanup
+
+	// multipartLogEntries is the number of entries added to the multipart node log by this batch.
+	multipartLogEntries uint64
+
 	oldRoot node.Root
+	version uint64
 	chunk   bool
+	// nodeFilter is the multipart node filter used to avoid looking up inserted nodes that did not
+	// exist before the multipart insert.
+	nodeFilter *nodeFilter
+
+	// trustedRoots are the roots that may be committed in case the batch repairs an already
+	// finalized version. It is nil for regular batches.
+	trustedRoots map[api.TypedHash]bool
 
 	writeLog     writelog.WriteLog
 	annotations  writelog.Annotations
-	updatedNodes []updatedNode
+	updatedNodes updatedNodes
+	pendingKeys  *pendingKeys
+	// writeLogData is the encoded write log, prepared when the write log is put into the batch.
+	writeLogData []byte
+
+	// size is the number of bytes written by the batch.
+	size int64
+	// entries is the number of entries written by the batch.
+	entries int
+	// stats are the statistics of the nodes written by the batch.
+	stats rootStats
 }
 
 // Implements api.Batch.
@@ -962,12 +1744,29 @@ func (ba *badgerBatch) PutWriteLog(writeLog writelog.WriteLog, annotations write
 	if ba.chunk {
 		return fmt.Errorf("mkvs/badger: cannot put write log in chunk mode")
 	}
+	// Deleted keys are needed for tombstones even when write logs are discarded.
+	if ba.db.tombstoneRetention > 0 {
+		ba.pendingKeys = newPendingKeys(writeLog)
+	}
 	if ba.db.discardWriteLogs {
 		return nil
 	}
 
 	ba.writeLog = writeLog
 	ba.annotations = annotations
+
+	// Replace any previously put write log.
+	if ba.writeLogData != nil {
+		ba.size -= int64(writeLogKeyFmt.Size() + len(ba.writeLogData))
+		ba.entries--
+		ba.writeLogData = nil
+	}
+	if writeLog != nil && annotations != nil {
+		log := api.MakeHashedDBWriteLog(writeLog, annotations)
+		ba.writeLogData = cbor.Marshal(log)
+		ba.size += int64(writeLogKeyFmt.Size() + len(ba.writeLogData))
+		ba.entries++
+	}
 	return nil
 }
 
@@ -978,152 +1777,244 @@ func (ba *badgerBatch) RemoveNodes(nodes []*node.Pointer) error {
 	}
 
 	for _, ptr := range nodes {
-		ba.updatedNodes = append(ba.updatedNodes, updatedNode{
-			Removed: true,
-			Hash:    ptr.GetHash(),
-		})
+		// Only removals are accounted for as inserted nodes are accounted for when put.
+		if ba.updatedNodes.add(ptr.GetHash(), true) > 0 {
+			ba.size += int64(updatedNodeSize)
+			ba.entries++
+		}
 	}
 	return nil
 }
 
 // Implements api.Batch.
 func (ba *badgerBatch) Commit(root node.Root) error {
+	// A committed batch has been reset, so it cannot be committed again.
+	if ba.released {
+		return api.ErrBatchClosed
+	}
+
 	ba.db.metaUpdateLock.Lock()
 	defer ba.db.metaUpdateLock.Unlock()
 
+	// Update the set of roots for this version.
+	tx := ba.db.metaDB.NewTransactionAt(versionToTs(root.Version), true)
+	defer tx.Discard()
+
+	metas := newRootsMetadataSet(tx, ba.db.rootsMetaCache)
+	exists, err := ba.prepareCommit(metas, root)
+	if err != nil {
+		return err
+	}
+	if exists {
+		// Root already exists, no need to do anything since if the hash matches, everything will
+		// be identical and we would just be duplicating work.
+		ba.Reset()
+		return ba.BaseBatch.Commit(root)
+	}
+	if err = metas.save(); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to save roots metadata: %w", err)
+	}
+	if err = ba.flush(); err != nil {
+		return err
+	}
+	if err = ba.db.syncData(); err != nil {
+		return err
+	}
+
+	// Commit root metadata updates. This is done last, so in case we fail, we can still retry.
+	if err = tx.CommitAt(tsMetadata, nil); err != nil {
+		return err
+	}
+	metas.committed()
+	return ba.finishCommit(root)
+}
+
+// prepareCommit checks that the given root may be committed and stages all of its metadata
+// updates in the given roots metadata set.
+//
+// It returns true in case the root already exists and nothing else needs to be done. Must be
+// called while holding the metadata update lock.
+func (ba *badgerBatch) prepareCommit(metas *rootsMetadataSet, root node.Root) (bool, error) {
 	if ba.db.multipartVersion != multipartVersionNone && ba.db.multipartVersion != root.Version {
-		return api.ErrInvalidMultipartVersion
+		return false, api.ErrInvalidMultipartVersion
 	}
 
 	if err := ba.db.sanityCheckNamespace(root.Namespace); err != nil {
-		return err
+		return false, err
 	}
 	if !root.Follows(&ba.oldRoot) {
-		return api.ErrRootMustFollowOld
+		return false, api.ErrRootMustFollowOld
 	}
 
-	// Make sure that the version that we try to commit into has not yet been finalized.
-	lastFinalizedVersion, exists := ba.db.meta.getLastFinalizedVersion()
-	if exists && lastFinalizedVersion >= root.Version {
-		return api.ErrAlreadyFinalized
+	switch {
+	case ba.trustedRoots != nil:
+		// Repairs may only insert trusted roots into the already finalized version of the batch.
+		if root.Version != ba.version {
+			return false, fmt.Errorf("mkvs/badger: repair root version mismatch (expected: %d got: %d)",
+				ba.version, root.Version,
+			)
+		}
+		if err := ba.db.checkRepairVersionLocked(root.Version); err != nil {
+			return false, err
+		}
+		if !ba.trustedRoots[api.TypedHashFromRoot(root)] {
+			return false, api.ErrRootNotTrusted
+		}
+	default:
+		// Make sure that the version that we try to commit into has not yet been finalized.
+		lastFinalizedVersion, exists := ba.db.meta.getLastFinalizedVersion()
+		if exists && lastFinalizedVersion >= root.Version {
+			return false, api.ErrAlreadyFinalized
+		}
 	}
 
-	// Update the set of roots for this version.
-	tx := ba.db.db.NewTransactionAt(versionToTs(root.Version), true)
-	defer tx.Discard()
-
-	rootsMeta, err := loadRootsMetadata(tx, root.Version)
+	rootsMeta, err := metas.load(root.Version)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	rootHash := api.TypedHashFromRoot(root)
-	if err = ba.bat.Set(rootNodeKeyFmt.Encode(&rootHash), []byte{}); err != nil {
-		return err
+	if err = ba.bat.Set(newRootNodeKey(&rootHash), []byte{}); err != nil {
+		return false, err
 	}
 	if ba.multipartNodes != nil {
 		if err = ba.multipartNodes.Set(multipartRestoreNodeLogKeyFmt.Encode(&rootHash), []byte{}); err != nil {
-			return err
+			return false, err
+		}
+		// Roots are committed once per restored chunk, but only logged once.
+		if rootsMeta.Roots[rootHash] == nil {
+			ba.multipartLogEntries++
 		}
 	}
 
 	if rootsMeta.Roots[rootHash] != nil {
-		// Root already exists, no need to do anything since if the hash matches, everything will
-		// be identical and we would just be duplicating work.
-		//
 		// If we are importing a chunk, there can be multiple commits for the same root.
 		if !ba.chunk {
-			ba.Reset()
-			return ba.BaseBatch.Commit(root)
+			return true, nil
 		}
 	} else {
 		// Create root with no derived roots.
 		rootsMeta.Roots[rootHash] = []api.TypedHash{}
+		metas.markDirty(root.Version)
+	}
 
-		if err = rootsMeta.save(tx); err != nil {
-			return fmt.Errorf("mkvs/badger: failed to save roots metadata: %w", err)
-		}
+	// Account for the nodes written by the batch. Chunks of the same root are accumulated.
+	if err = addRootStats(metas.tx, root.Version, rootHash, ba.stats); err != nil {
+		return false, err
 	}
 
 	if ba.chunk {
 		// Skip most of metadata updates if we are just importing chunks.
-		key := rootUpdatedNodesKeyFmt.Encode(root.Version, &rootHash)
-		if err = tx.Set(key, cbor.Marshal([]updatedNode{})); err != nil {
-			return fmt.Errorf("mkvs/badger: set returned error: %w", err)
+		return false, ba.db.putUpdatedNodes(metas.tx, root.Version, rootHash, nil)
+	}
+
+	// Update the root link for the old root.
+	oldRootHash := api.TypedHashFromRoot(ba.oldRoot)
+	if !ba.oldRoot.Hash.IsEmpty() {
+		if ba.oldRoot.Version < ba.db.meta.getEarliestVersion() && ba.oldRoot.Version != root.Version {
+			return false, api.ErrPreviousVersionMismatch
 		}
-	} else {
-		// Update the root link for the old root.
-		oldRootHash := api.TypedHashFromRoot(ba.oldRoot)
-		if !ba.oldRoot.Hash.IsEmpty() {
-			if ba.oldRoot.Version < ba.db.meta.getEarliestVersion() && ba.oldRoot.Version != root.Version {
-				return api.ErrPreviousVersionMismatch
-			}
 
-			var oldRootsMeta *rootsMetadata
-			oldRootsMeta, err = loadRootsMetadata(tx, ba.oldRoot.Version)
-			if err != nil {
-				return err
-			}
+		var oldRootsMeta *rootsMetadata
+		oldRootsMeta, err = metas.load(ba.oldRoot.Version)
+		if err != nil {
+			return false, err
+		}
 
-			if _, ok := oldRootsMeta.Roots[oldRootHash]; !ok {
-				return api.ErrRootNotFound
-			}
+		if _, ok := oldRootsMeta.Roots[oldRootHash]; !ok {
+			return false, api.ErrRootNotFound
+		}
 
-			oldRootsMeta.Roots[oldRootHash] = append(oldRootsMeta.Roots[oldRootHash], rootHash)
-			if err = oldRootsMeta.save(tx); err != nil {
-				return fmt.Errorf("mkvs/badger: failed to save old roots metadata: %w", err)
-			}
+		oldRootsMeta.Roots[oldRootHash] = append(oldRootsMeta.Roots[oldRootHash], rootHash)
+		metas.markDirty(ba.oldRoot.Version)
+	}
+
+	// Store updated nodes (only needed until the version is finalized).
+	if ba.trustedRoots == nil {
+		if err = ba.db.putUpdatedNodes(metas.tx, root.Version, rootHash, ba.updatedNodes); err != nil {
+			return false, err
 		}
+	}
 
-		// Store updated nodes (only needed until the version is finalized).
-		key := rootUpdatedNodesKeyFmt.Encode(root.Version, &rootHash)
-		if err = tx.Set(key, cbor.Marshal(ba.updatedNodes)); err != nil {
-			return fmt.Errorf("mkvs/badger: set returned error: %w", err)
+	// Store keys changed by this root (only needed until the version is finalized).
+	if ba.pendingKeys != nil && ba.trustedRoots == nil {
+		key := rootPendingKeysKeyFmt.Encode(root.Version, &rootHash)
+		if err = metas.tx.Set(key, cbor.Marshal(ba.pendingKeys)); err != nil {
+			return false, fmt.Errorf("mkvs/badger: set returned error: %w", err)
 		}
+	}
 
-		// Store write log.
-		if ba.writeLog != nil && ba.annotations != nil {
-			log := api.MakeHashedDBWriteLog(ba.writeLog, ba.annotations)
-			bytes := cbor.Marshal(log)
-			key := writeLogKeyFmt.Encode(root.Version, &rootHash, &oldRootHash)
-			if err = ba.bat.Set(key, bytes); err != nil {
-				return fmt.Errorf("mkvs/badger: set new write log returned error: %w", err)
-			}
+	// Store write log.
+	if ba.writeLogData != nil {
+		key := writeLogKeyFmt.Encode(root.Version, &rootHash, &oldRootHash)
+		if err = ba.bat.Set(key, ba.writeLogData); err != nil {
+			return false, fmt.Errorf("mkvs/badger: set new write log returned error: %w", err)
 		}
 	}
+	return false, nil
+}
 
-	// Flush node updates.
+// flush flushes the node updates of the batch. Inserted nodes are logged before they are written
+// so that they can be removed in case a multipart restore is aborted.
+func (ba *badgerBatch) flush() error {
 	if ba.multipartNodes != nil {
-		if err = ba.multipartNodes.Flush(); err != nil {
+		if err := ba.multipartNodes.Flush(); err != nil {
 			return fmt.Errorf("mkvs/badger: failed to flush node log batch: %w", err)
 		}
+		if err := ba.db.syncMetadata(); err != nil {
+			return err
+		}
 	}
-	if err = ba.bat.Flush(); err != nil {
+	if err := ba.bat.Flush(); err != nil {
 		return fmt.Errorf("mkvs/badger: failed to flush batch: %w", err)
 	}
+	return nil
+}
 
-	// Commit root metadata updates. This is done last, so in case we fail, we can still retry.
-	if err = tx.CommitAt(tsMetadata, nil); err != nil {
-		return err
+// finishCommit updates the caches and metrics after the root metadata updates of the batch have
+// been committed and resets the batch.
+func (ba *badgerBatch) finishCommit(root node.Root) error {
+	// Roots committed during a multipart restore may still be removed in case the restore is
+	// aborted, so only cache them once they are finalized.
+	if ba.db.multipartVersion == multipartVersionNone {
+		ba.db.rootCache.add(root.Version, api.TypedHashFromRoot(root))
+	}
+	if ba.multipartNodes != nil {
+		ba.db.multipartNodesWritten.Add(ba.multipartLogEntries)
+		ba.db.multipartBytesWritten.Add(uint64(ba.size)) // nolint: gosec
 	}
 
-	ba.writeLog = nil
-	ba.annotations = nil
-	ba.updatedNodes = nil
+	ba.db.metrics.batchCommit(ba.size)
+
+	ba.Reset()
 
 	return ba.BaseBatch.Commit(root)
 }
 
 // Implements api.Batch.
 func (ba *badgerBatch) Reset() {
-	ba.bat.Cancel()
-	if ba.multipartNodes != nil {
-		ba.multipartNodes.Cancel()
-		ba.readTxn.Discard()
-	}
+	ba.cleanup.Stop()
+	ba.release()
+
 	ba.writeLog = nil
 	ba.annotations = nil
 	ba.updatedNodes = nil
+	ba.pendingKeys = nil
+	ba.writeLogData = nil
+	ba.size = 0
+	ba.entries = 0
+	ba.multipartLogEntries = 0
+	ba.stats = rootStats{}
+}
+
+// Implements api.Batch.
+func (ba *badgerBatch) Close() {
+	ba.Reset()
+}
+
+// Implements api.Batch.
+func (ba *badgerBatch) Size() (int64, int) {
+	return ba.size, ba.entries
 }
 
 // Implements api.Batch.
@@ -1134,20 +2025,74 @@ func (ba *badgerBatch) PutNode(ptr *node.Pointer) error {
 	}
 
 	h := ptr.Node.GetHash()
-	ba.updatedNodes = append(ba.updatedNodes, updatedNode{Hash: h})
-	nodeKey := nodeKeyFmt.Encode(&h)
+	if ba.db.verifyNodeHashes {
+		if err = verifyNodeData(data, h); err != nil {
+			return err
+		}
+	}
+	if ba.updatedNodes.add(h, false) < 0 {
+		// The insertion cancelled out a removal that was accounted for.
+		ba.size -= int64(updatedNodeSize)
+		ba.entries--
+	}
+	// The key is retained by the write batch, so it must be freshly allocated.
+	nodeKey := newNodeKey(&h)
 	if ba.multipartNodes != nil {
-		if _, err = ba.readTxn.Get(nodeKey); err != nil && errors.Is(err, badger.ErrKeyNotFound) {
+		if !ba.nodeMayExist(ptr.Node, &h, nodeKey) {
 			th := api.TypedHashFromParts(node.RootTypeInvalid, h)
 			if err = ba.multipartNodes.Set(multipartRestoreNodeLogKeyFmt.Encode(&th), []byte{}); err != nil {
 				return err
 			}
+			ba.multipartLogEntries++
 		}
 	}
 
+	ba.size += int64(len(nodeKey) + len(data))
+	ba.entries++
+	ba.stats.Nodes++
+	ba.stats.Bytes += uint64(len(data))
 	return ba.bat.Set(nodeKey, data)
 }
 
+// nodeMayExist returns false iff the given node with the given hash and key does not exist yet.
+func (ba *badgerBatch) nodeMayExist(n node.Node, h *hash.Hash, nodeKey []byte) bool {
+	// Nodes on the path to a chunk reference nodes of other chunks and are inserted by each of
+	// those chunks, so they are always looked up in order to not log them repeatedly.
+	if ba.nodeFilter != nil && !ba.nodeFilter.mayContain(h) && !referencesOtherChunks(n) {
+		return false
+	}
+	_, err := ba.readTxn.Get(nodeKey)
+	return !errors.Is(err, badger.ErrKeyNotFound)
+}
+
+// referencesOtherChunks returns true iff the given node references nodes that are not part of
+// the same chunk.
+func referencesOtherChunks(n node.Node) bool {
+	in, ok := n.(*node.InternalNode)
+	if !ok {
+		return false
+	}
+	for _, ptr := range []*node.Pointer{in.LeafNode, in.Left, in.Right} {
+		if ptr != nil && ptr.Node == nil && !ptr.Hash.IsEmpty() {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyNodeData makes sure that the serialized node unmarshals into a node with the given hash.
+func verifyNodeData(data []byte, h hash.Hash) error {
+	n, err := node.UnmarshalBinary(data)
+	if err != nil {
+		return fmt.Errorf("%w: failed to unmarshal serialized node: %w", api.ErrNodeCorrupted, err)
+	}
+	n.UpdateHash()
+	if nh := n.GetHash(); !nh.Equal(&h) {
+		return fmt.Errorf("%w: serialized node hash %s does not match expected hash %s", api.ErrNodeCorrupted, nh, h)
+	}
+	return nil
+}
+
 // Implements api.Batch.
 func (ba *badgerBatch) VisitCleanNode(*node.Pointer, *node.Pointer) error {
 	return nil
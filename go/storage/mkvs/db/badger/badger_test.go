@@ -3,15 +3,25 @@ package badger
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"runtime"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/dgraph-io/badger/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
@@ -142,6 +152,20 @@ func verifyNodes(require *require.Assertions, badgerdb *badgerNodeDB, keySet key
 	require.Equal(0, len(notVisited), "some nodes not visited")
 }
 
+func countLogKeys(require *require.Assertions, badgerdb *badgerNodeDB) uint64 {
+	var count uint64
+	err := badgerdb.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: logPrefix})
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			count++
+		}
+		return nil
+	})
+	require.NoError(err, "countLogKeys()")
+	return count
+}
+
 func checkNoLogKeys(require *require.Assertions, badgerdb *badgerNodeDB) {
 	err := badgerdb.db.View(func(txn *badger.Txn) error {
 		it := txn.NewIterator(badger.DefaultIteratorOptions)
@@ -223,6 +247,9 @@ func TestMultipartRestore(t *testing.T) {
 	t.Run("Abort", wrap(testAbort, testValues))
 	t.Run("Finalize", wrap(testFinalize, testValues))
 	t.Run("ExistingNodes", wrap(testExistingNodes, testValues[:1]))
+	t.Run("ExistingNodesNoFilter", wrap(testExistingNodesNoFilter, testValues[:1]))
+	t.Run("ExistingNodesFilterLimit", wrap(testExistingNodesFilterLimit, testValues[:1]))
+	t.Run("Progress", wrap(testProgress, testValues))
 }
 
 func testAbort(ctx *test) {
@@ -284,6 +311,21 @@ func testExistingNodes(ctx *test) {
 	ctx.require.NoError(err, "Finalize()")
 	verifyNodes(ctx.require, ctx.badgerdb, ctx.ckNodes)
 
+	// Start the second restore and make sure that the node filter covers the existing nodes.
+	err = ctx.badgerdb.StartMultipartInsert(ckMeta2.Root.Version)
+	ctx.require.NoError(err, "StartMultipartInsert()")
+	if ctx.badgerdb.multipartNodeFilterLimit >= int64(len(ctx.ckNodes)) {
+		filter := ctx.badgerdb.multipartNodeFilter
+		ctx.require.NotNil(filter, "node filter should be used")
+		for key := range ctx.ckNodes {
+			var h hash.Hash
+			ctx.require.True(nodeKeyFmt.Decode([]byte(key), &h), "Decode()")
+			ctx.require.True(filter.mayContain(&h), "node filter should contain existing node %s", h)
+		}
+	} else {
+		ctx.require.Nil(ctx.badgerdb.multipartNodeFilter, "node filter should not be used")
+	}
+
 	// Restore the second checkpoint. One of the nodes from it already exists. After aborting,
 	// exactly the nodes from the first checkpoint should remain.
 	restorer := restoreCheckpoint(ctx, ckMeta2, ckNodes2)
@@ -291,9 +333,44 @@ func testExistingNodes(ctx *test) {
 	ctx.require.NoError(err, "AbortRestore()")
 	err = ctx.badgerdb.AbortMultipartInsert()
 	ctx.require.NoError(err, "AbortMultipartInsert()")
+	ctx.require.Nil(ctx.badgerdb.multipartNodeFilter, "node filter should be dropped on abort")
 	verifyNodes(ctx.require, ctx.badgerdb, ctx.ckNodes)
 }
 
+func testExistingNodesNoFilter(ctx *test) {
+	ctx.badgerdb.multipartNodeFilterLimit = -1
+	testExistingNodes(ctx)
+}
+
+func testExistingNodesFilterLimit(ctx *test) {
+	ctx.badgerdb.multipartNodeFilterLimit = 1
+	testExistingNodes(ctx)
+}
+
+func testProgress(ctx *test) {
+	ctx.require.Nil(ctx.badgerdb.MultipartProgress(), "no progress without multipart restore")
+
+	restoreCheckpoint(ctx, ctx.ckMeta, ctx.ckNodes)
+
+	progress := ctx.badgerdb.MultipartProgress()
+	ctx.require.NotNil(progress, "progress during multipart restore")
+	ctx.require.Equal(ctx.ckMeta.Root.Version, progress.Version)
+	ctx.require.Equal(countLogKeys(ctx.require, ctx.badgerdb), progress.NodesWritten, "all node log entries should be counted")
+	ctx.require.NotZero(progress.BytesWritten, "written bytes should be counted")
+
+	err := ctx.badgerdb.Finalize([]node.Root{ctx.ckMeta.Root})
+	ctx.require.NoError(err, "Finalize()")
+	ctx.require.Nil(ctx.badgerdb.MultipartProgress(), "no progress after multipart restore")
+
+	// A new multipart restore should start counting from zero.
+	err = ctx.badgerdb.StartMultipartInsert(ctx.ckMeta.Root.Version + 1)
+	ctx.require.NoError(err, "StartMultipartInsert()")
+	progress = ctx.badgerdb.MultipartProgress()
+	ctx.require.NotNil(progress, "progress during multipart restore")
+	ctx.require.Zero(progress.NodesWritten)
+	ctx.require.Zero(progress.BytesWritten)
+}
+
 func TestVersionChecks(t *testing.T) {
 	require := require.New(t)
 	ndb, err := New(dbCfg)
@@ -379,3 +456,2269 @@ func TestFinalizeBasic(t *testing.T) {
 	err = ndb.Finalize([]node.Root{root2})
 	require.Errorf(err, "mkvs: root not found", "Finalize({root2-broken})")
 }
+
+func TestWriteLogMaxHops(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	emptyRoot := node.Root{
+		Namespace: testNs,
+		Version:   0,
+		Type:      node.RootTypeState,
+	}
+	emptyRoot.Hash.Empty()
+
+	// Build a three-hop chain within a single version: empty -> r1 -> r2 -> r3.
+	buildChain := func(ndb api.NodeDB) node.Root {
+		tree := mkvs.NewWithRoot(nil, ndb, emptyRoot)
+		defer tree.Close()
+
+		var root node.Root
+		for i, val := range testValues {
+			err := tree.Insert(ctx, []byte(strconv.Itoa(i)), val)
+			require.NoError(err, "Insert()")
+			_, hash, err := tree.Commit(ctx, testNs, 0)
+			require.NoError(err, "Commit()")
+			root = node.Root{
+				Namespace: testNs,
+				Version:   0,
+				Type:      node.RootTypeState,
+				Hash:      hash,
+			}
+		}
+		return root
+	}
+
+	// Default limit.
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	endRoot := buildChain(ndb)
+	_, err = ndb.GetWriteLog(ctx, emptyRoot, endRoot)
+	require.ErrorIs(err, api.ErrWriteLogNotFound, "GetWriteLog() should fail with the default limit")
+
+	// Raised limit.
+	cfg := *dbCfg
+	cfg.MaxWriteLogHops = 3
+	ndb3, err := New(&cfg)
+	require.NoError(err, "New()")
+	defer ndb3.Close()
+
+	endRoot = buildChain(ndb3)
+	wli, err := ndb3.GetWriteLog(ctx, emptyRoot, endRoot)
+	require.NoError(err, "GetWriteLog() should succeed with a raised limit")
+	var entries int
+	for {
+		more, err := wli.Next()
+		require.NoError(err, "Next()")
+		if !more {
+			break
+		}
+		entries++
+	}
+	require.Equal(len(testValues), entries, "write log should contain all entries")
+
+	// Limit above the hard cap.
+	cfg.MaxWriteLogHops = api.MaxWriteLogHopsLimit + 1
+	_, err = New(&cfg)
+	require.Error(err, "New() should fail with a limit above the hard cap")
+}
+
+func TestHasRoots(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	root1 := fillDB(ctx, require, testValues, nil, 1, 2, ndb)
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+	root2 := fillDB(ctx, require, testValues[:1], &root1, 2, 3, ndb)
+
+	emptyRoot := node.Root{
+		Namespace: testNs,
+		Version:   5,
+		Type:      node.RootTypeState,
+	}
+	emptyRoot.Hash.Empty()
+
+	missingRoot := root2
+	missingRoot.Hash[3]++
+
+	otherNsRoot := root1
+	otherNsRoot.Namespace = common.NewTestNamespaceFromSeed([]byte("other ns"), 0)
+
+	roots := []node.Root{root2, emptyRoot, missingRoot, root1, otherNsRoot, root2}
+	exists, err := ndb.HasRoots(roots)
+	require.NoError(err, "HasRoots()")
+	require.Equal([]bool{true, true, false, true, false, true}, exists)
+
+	// Results should match HasRoot.
+	for i, root := range roots {
+		require.Equal(ndb.HasRoot(root), exists[i], "HasRoots() should match HasRoot() for root %d", i)
+	}
+
+	exists, err = ndb.HasRoots(nil)
+	require.NoError(err, "HasRoots(nil)")
+	require.Empty(exists)
+}
+
+func TestCommitMulti(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	// commitRoots commits two state roots derived from the same root and an I/O root in version 3
+	// either one by one or all together.
+	commitRoots := func(ndb api.NodeDB, multi bool) (*badgerNodeDB, []node.Root) {
+		root1 := fillDB(ctx, require, testValues, nil, 1, 2, ndb)
+		err := ndb.Finalize([]node.Root{root1})
+		require.NoError(err, "Finalize({root1})")
+
+		emptyIORoot := node.Root{
+			Namespace: testNs,
+			Version:   3,
+			Type:      node.RootTypeIO,
+		}
+		emptyIORoot.Hash.Empty()
+
+		treeDB := ndb
+		ddb := api.NewDeferredCommitNodeDB(ndb.(api.MultiCommitNodeDB))
+		if multi {
+			treeDB = ddb
+		}
+
+		var roots []node.Root
+		for i, prevRoot := range []node.Root{root1, root1, emptyIORoot} {
+			tree := mkvs.NewWithRoot(nil, treeDB, prevRoot)
+			defer tree.Close()
+
+			err = tree.Insert(ctx, []byte("key"), []byte(fmt.Sprintf("value %d", i)))
+			require.NoError(err, "Insert()")
+			var rootHash hash.Hash
+			_, rootHash, err = tree.Commit(ctx, testNs, 3)
+			require.NoError(err, "Commit()")
+
+			roots = append(roots, node.Root{
+				Namespace: testNs,
+				Version:   3,
+				Type:      prevRoot.Type,
+				Hash:      rootHash,
+			})
+		}
+		if multi {
+			for _, root := range roots {
+				require.False(ndb.HasRoot(root), "roots should not be committed before Commit()")
+			}
+			err = ddb.Commit()
+			require.NoError(err, "Commit()")
+		}
+		return ndb.(*badgerNodeDB), roots
+	}
+
+	ndb1, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb1.Close()
+	ndb2, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb2.Close()
+
+	seqDB, seqRoots := commitRoots(ndb1, false)
+	multiDB, multiRoots := commitRoots(ndb2, true)
+	require.Equal(seqRoots, multiRoots, "roots should be the same")
+
+	// The roots metadata must be the same as when committing the roots one by one.
+	for _, version := range []uint64{2, 3} {
+		seqTx := seqDB.db.NewTransactionAt(versionToTs(version), false)
+		defer seqTx.Discard()
+		seqMeta, err := loadRootsMetadata(seqTx, version)
+		require.NoError(err, "loadRootsMetadata(%d)", version)
+
+		multiTx := multiDB.db.NewTransactionAt(versionToTs(version), false)
+		defer multiTx.Discard()
+		multiMeta, err := loadRootsMetadata(multiTx, version)
+		require.NoError(err, "loadRootsMetadata(%d)", version)
+
+		require.Equal(seqMeta.Roots, multiMeta.Roots, "roots metadata of version %d should match", version)
+	}
+	for _, root := range multiRoots {
+		require.True(multiDB.HasRoot(root), "committed roots should exist")
+	}
+	err = multiDB.Finalize([]node.Root{multiRoots[0], multiRoots[2]})
+	require.NoError(err, "Finalize()")
+
+	// Batches must be of the same version and created by the same database.
+	batch1, err := ndb1.NewBatch(multiRoots[0], 4, false)
+	require.NoError(err, "NewBatch()")
+	defer batch1.Reset()
+	batch2, err := ndb1.NewBatch(multiRoots[0], 5, false)
+	require.NoError(err, "NewBatch()")
+	defer batch2.Reset()
+	root4 := multiRoots[0]
+	root4.Version = 4
+	root5 := multiRoots[0]
+	root5.Version = 5
+	err = ndb1.(api.MultiCommitNodeDB).CommitMulti([]api.Batch{batch1, batch2}, []node.Root{root4, root5})
+	require.Error(err, "CommitMulti() should fail for roots of different versions")
+	err = ndb2.(api.MultiCommitNodeDB).CommitMulti([]api.Batch{batch1}, []node.Root{root4})
+	require.Error(err, "CommitMulti() should fail for batches of other databases")
+}
+
+func TestCommitMultiInterrupted(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerDb := ndb.(*badgerNodeDB)
+
+	root1 := fillDB(ctx, require, testValues, nil, 1, 2, ndb)
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+
+	emptyIORoot := node.Root{
+		Namespace: testNs,
+		Version:   3,
+		Type:      node.RootTypeIO,
+	}
+	emptyIORoot.Hash.Empty()
+
+	// commitRoots commits a state and an I/O root in version 3 together.
+	commitRoots := func() ([]node.Root, error) {
+		ddb := api.NewDeferredCommitNodeDB(badgerDb)
+		defer ddb.Discard()
+
+		var roots []node.Root
+		for i, prevRoot := range []node.Root{root1, emptyIORoot} {
+			tree := mkvs.NewWithRoot(nil, ddb, prevRoot)
+			defer tree.Close()
+
+			err := tree.Insert(ctx, []byte("key"), []byte(fmt.Sprintf("value %d", i)))
+			require.NoError(err, "Insert()")
+			_, rootHash, err := tree.Commit(ctx, testNs, 3)
+			require.NoError(err, "Commit()")
+
+			roots = append(roots, node.Root{
+				Namespace: testNs,
+				Version:   3,
+				Type:      prevRoot.Type,
+				Hash:      rootHash,
+			})
+		}
+		return roots, ddb.Commit()
+	}
+
+	// Fail after the batches have been flushed, none of the roots should be committed.
+	errInterrupted := fmt.Errorf("interrupted")
+	badgerDb.commitMultiInterruptFn = func() error { return errInterrupted }
+	roots, err := commitRoots()
+	require.ErrorIs(err, errInterrupted, "CommitMulti() should fail when interrupted")
+	for _, root := range roots {
+		require.False(ndb.HasRoot(root), "roots should not be committed after an interrupted commit")
+	}
+	rootsForVersion, err := ndb.GetRootsForVersion(3)
+	require.NoError(err, "GetRootsForVersion(3)")
+	require.Empty(rootsForVersion, "no roots should be recorded after an interrupted commit")
+
+	// Retrying should commit all of the roots.
+	badgerDb.commitMultiInterruptFn = nil
+	retried, err := commitRoots()
+	require.NoError(err, "CommitMulti()")
+	require.Equal(roots, retried, "roots should be the same")
+	for _, root := range retried {
+		require.True(ndb.HasRoot(root), "roots should be committed")
+	}
+	err = ndb.Finalize(retried)
+	require.NoError(err, "Finalize()")
+}
+
+func TestBatchSize(t *testing.T) {
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	emptyRoot := node.Root{Namespace: testNs, Type: node.RootTypeState}
+	emptyRoot.Hash.Empty()
+	batch, err := ndb.NewBatch(emptyRoot, 0, false)
+	require.NoError(err, "NewBatch()")
+
+	size, entries := batch.Size()
+	require.Zero(size)
+	require.Zero(entries)
+
+	var (
+		ptrs          []*node.Pointer
+		wl            writelog.WriteLog
+		annotations   writelog.Annotations
+		expectedBytes int64
+	)
+	for i, value := range testValues {
+		leaf := &node.LeafNode{Key: []byte(strconv.Itoa(i)), Value: value}
+		leaf.UpdateHash()
+		ptr := &node.Pointer{Clean: true, Hash: leaf.GetHash(), Node: leaf}
+		ptrs = append(ptrs, ptr)
+		wl = append(wl, writelog.LogEntry{Key: leaf.Key, Value: leaf.Value})
+		annotations = append(annotations, writelog.LogEntryAnnotation{InsertedNode: ptr})
+
+		err = batch.PutNode(ptr)
+		require.NoError(err, "PutNode()")
+
+		data, err := leaf.MarshalBinary()
+		require.NoError(err, "MarshalBinary()")
+		h := leaf.GetHash()
+		expectedBytes += int64(len(nodeKeyFmt.Encode(&h)) + len(data))
+	}
+	size, entries = batch.Size()
+	require.Equal(expectedBytes, size, "size should match encoded nodes")
+	require.Equal(len(testValues), entries)
+
+	// Write logs should be accounted for when put into the batch.
+	err = batch.PutWriteLog(wl, annotations)
+	require.NoError(err, "PutWriteLog()")
+	expectedBytes += int64(writeLogKeyFmt.Size() + len(cbor.Marshal(api.MakeHashedDBWriteLog(wl, annotations))))
+	size, entries = batch.Size()
+	require.Equal(expectedBytes, size, "size should include the encoded write log")
+	require.Equal(len(testValues)+1, entries)
+
+	// Replacing the write log should not count it twice.
+	err = batch.PutWriteLog(wl, annotations)
+	require.NoError(err, "PutWriteLog()")
+	size, entries = batch.Size()
+	require.Equal(expectedBytes, size)
+	require.Equal(len(testValues)+1, entries)
+
+	// Removed nodes should be accounted for as well, but only once.
+	removed := &node.LeafNode{Key: []byte("removed"), Value: []byte("removed")}
+	removed.UpdateHash()
+	removedPtr := &node.Pointer{Clean: true, Hash: removed.GetHash(), Node: removed}
+	err = batch.RemoveNodes([]*node.Pointer{removedPtr, removedPtr})
+	require.NoError(err, "RemoveNodes()")
+	expectedBytes += int64(len(cbor.Marshal(&updatedNode{Removed: true, Hash: removedPtr.Hash})))
+	size, entries = batch.Size()
+	require.Equal(expectedBytes, size, "size should include removed nodes")
+	require.Equal(len(testValues)+2, entries)
+
+	// Removing a node inserted in the same batch should cancel out.
+	err = batch.RemoveNodes(ptrs[:1])
+	require.NoError(err, "RemoveNodes()")
+	size, entries = batch.Size()
+	require.Equal(expectedBytes, size, "size should not include cancelled removals")
+	require.Equal(len(testValues)+2, entries)
+
+	batch.Reset()
+	size, entries = batch.Size()
+	require.Zero(size, "size should be reset")
+	require.Zero(entries, "entries should be reset")
+
+	// Size should also be reset after a successful commit.
+	batch, err = ndb.NewBatch(emptyRoot, 0, false)
+	require.NoError(err, "NewBatch()")
+	err = batch.PutNode(ptrs[0])
+	require.NoError(err, "PutNode()")
+	_, entries = batch.Size()
+	require.Equal(1, entries)
+	root := node.Root{Namespace: testNs, Type: node.RootTypeState, Hash: ptrs[0].Hash}
+	err = batch.Commit(root)
+	require.NoError(err, "Commit()")
+	size, entries = batch.Size()
+	require.Zero(size, "size should be reset after commit")
+	require.Zero(entries, "entries should be reset after commit")
+}
+
+func TestUpdatedNodesChunks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+
+	emptyRoot := node.Root{Namespace: testNs, Type: node.RootTypeState}
+	emptyRoot.Hash.Empty()
+	batch, err := ndb.NewBatch(emptyRoot, 0, false)
+	require.NoError(err, "NewBatch()")
+
+	leaf := &node.LeafNode{Key: []byte("key"), Value: []byte("value")}
+	leaf.UpdateHash()
+	leafPtr := &node.Pointer{Clean: true, Hash: leaf.GetHash(), Node: leaf}
+	err = batch.PutNode(leafPtr)
+	require.NoError(err, "PutNode()")
+
+	// Synthesize a batch removing more than a million nodes, including duplicates.
+	const numRemoved = 1_100_000
+	ptrs := make([]*node.Pointer, 0, numRemoved)
+	for i := range numRemoved {
+		ptrs = append(ptrs, &node.Pointer{Hash: hash.NewFromBytes([]byte(strconv.Itoa(i)))})
+	}
+	err = batch.RemoveNodes(ptrs)
+	require.NoError(err, "RemoveNodes()")
+	err = batch.RemoveNodes(ptrs[:1000])
+	require.NoError(err, "RemoveNodes()")
+	_, entries := batch.Size()
+	require.Equal(numRemoved+1, entries, "duplicate removals should be deduplicated")
+
+	root := node.Root{Namespace: testNs, Version: 0, Type: node.RootTypeState, Hash: leafPtr.Hash}
+	err = batch.Commit(root)
+	require.NoError(err, "Commit()")
+
+	// Updated nodes should be split across multiple records that do not exceed the chunk size.
+	rootHash := api.TypedHashFromRoot(root)
+	var (
+		records int
+		total   int
+	)
+	tx := badgerdb.db.NewTransactionAt(versionToTs(0), false)
+	keys, err := visitUpdatedNodes(tx, 0, rootHash, func(nodes []updatedNode) {
+		records++
+		total += len(nodes)
+		require.LessOrEqual(len(nodes)*updatedNodeSize, defaultUpdatedNodesChunkSize)
+	})
+	tx.Discard()
+	require.NoError(err, "visitUpdatedNodes()")
+	require.Greater(records, 1, "updated nodes should be split across records")
+	require.Len(keys, records)
+	require.Equal(numRemoved, total, "all removed nodes should be recorded")
+
+	// Finalization should reassemble and remove all records.
+	err = ndb.Finalize([]node.Root{root})
+	require.NoError(err, "Finalize()")
+
+	tx = badgerdb.db.NewTransactionAt(versionToTs(0), false)
+	defer tx.Discard()
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: rootUpdatedNodesChunkKeyFmt.Encode()})
+	defer it.Close()
+	it.Rewind()
+	require.False(it.Valid(), "updated nodes chunks should be removed after finalization")
+
+	problems, err := checkVersionInternal(context.Background(), badgerdb, 0)
+	require.NoError(err, "checkVersionInternal()")
+	require.Empty(problems)
+}
+
+func TestPruneRange(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	var (
+		roots []node.Root
+		prev  *node.Root
+	)
+	for version := uint64(0); version < 6; version++ {
+		root := fillDB(ctx, require, testValues[:version+1], prev, version, version, ndb)
+		root.Version = version
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(err, "Finalize({root%d})", version)
+		roots = append(roots, root)
+		prev = &roots[len(roots)-1]
+	}
+
+	_, err = ndb.PruneRange(ctx, 3, 1)
+	require.Error(err, "PruneRange() with an invalid range should fail")
+	_, err = ndb.PruneRange(ctx, 1, 2)
+	require.ErrorIs(err, api.ErrNotEarliest, "PruneRange() should only prune from the earliest version")
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	pruned, err := ndb.PruneRange(canceledCtx, 0, 2)
+	require.ErrorIs(err, context.Canceled, "PruneRange() with a canceled context")
+	require.Zero(pruned)
+	require.EqualValues(0, ndb.GetEarliestVersion())
+
+	pruned, err = ndb.PruneRange(ctx, 0, 2)
+	require.NoError(err, "PruneRange(0, 2)")
+	require.Equal(3, pruned)
+	require.EqualValues(3, ndb.GetEarliestVersion())
+
+	// The end version should be clamped to keep the latest finalized version.
+	pruned, err = ndb.PruneRange(ctx, 3, 100)
+	require.NoError(err, "PruneRange(3, 100)")
+	require.Equal(2, pruned)
+	require.EqualValues(5, ndb.GetEarliestVersion())
+
+	for _, root := range roots[:5] {
+		require.False(ndb.HasRoot(root), "pruned root %d should not exist", root.Version)
+	}
+	require.True(ndb.HasRoot(roots[5]), "latest root should still exist")
+
+	_, err = ndb.PruneRange(ctx, 5, 100)
+	require.ErrorIs(err, api.ErrCannotPruneLatestVersion)
+}
+
+func TestPruneRangeFailure(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+
+	var (
+		roots []node.Root
+		prev  *node.Root
+	)
+	for version := uint64(0); version < 4; version++ {
+		root := fillDB(ctx, require, testValues[:version%3+1], prev, version, version, ndb)
+		root.Version = version
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(err, "Finalize({root%d})", version)
+		roots = append(roots, root)
+		prev = &roots[len(roots)-1]
+	}
+
+	// Fail pruning the second version of the chunk after all of its removals have been staged.
+	errInjected := fmt.Errorf("injected failure")
+	badgerdb.pruneVersionFn = func(version uint64) error {
+		if version == 1 {
+			return errInjected
+		}
+		return nil
+	}
+	pruned, err := ndb.PruneRange(ctx, 0, 2)
+	require.ErrorIs(err, errInjected, "PruneRange() should fail")
+	require.Equal(1, pruned, "only the first version should be pruned")
+	require.EqualValues(1, ndb.GetEarliestVersion())
+
+	// The failed version must be left intact.
+	problems, err := checkVersionInternal(ctx, badgerdb, 1)
+	require.NoError(err, "checkVersionInternal(1)")
+	require.Empty(problems, "the new earliest version should be consistent")
+	require.True(ndb.HasRoot(roots[1]), "root of the failed version should exist")
+	requireRootValues(ctx, require, ndb, roots[1], testValues[:2])
+
+	// Pruning should succeed once the failure is gone.
+	badgerdb.pruneVersionFn = nil
+	pruned, err = ndb.PruneRange(ctx, 1, 2)
+	require.NoError(err, "PruneRange(1, 2)")
+	require.Equal(2, pruned)
+	require.EqualValues(3, ndb.GetEarliestVersion())
+	requireRootValues(ctx, require, ndb, roots[3], testValues[:1])
+}
+
+func TestBackgroundPruner(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	cfg := *dbCfg
+	cfg.PruneInterval = time.Hour
+	cfg.KeepVersions = 2
+	ndb, err := New(&cfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+	require.NotNil(badgerdb.pruner, "pruner should be enabled")
+
+	var prev *node.Root
+	for version := uint64(0); version < 6; version++ {
+		values := [][]byte{[]byte(fmt.Sprintf("value %d", version))}
+		root := fillDB(ctx, require, values, prev, version, version, ndb)
+		root.Version = version
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(err, "Finalize({root%d})", version)
+		prev = &root
+	}
+
+	// Versions before the last finalized version minus the versions to keep should be pruned
+	// in the background after finalization.
+	require.Eventually(func() bool {
+		return ndb.GetEarliestVersion() == 3
+	}, 10*time.Second, 10*time.Millisecond, "background pruning should catch up")
+	version, backlog := badgerdb.pruner.backlog()
+	require.EqualValues(3, version)
+	require.Zero(backlog)
+
+	// Close should stop the pruner.
+	ndb.Close()
+	select {
+	case <-badgerdb.pruner.doneCh:
+	default:
+		require.Fail("pruner should be stopped after Close()")
+	}
+
+	// Background pruning should not be enabled without an interval.
+	ndb2, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb2.Close()
+	require.Nil(ndb2.(*badgerNodeDB).pruner)
+}
+
+func TestPin(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	const numVersions = 32
+	var (
+		roots []node.Root
+		prev  *node.Root
+	)
+	for version := uint64(0); version < numVersions; version++ {
+		values := [][]byte{[]byte(fmt.Sprintf("value %d", version))}
+		root := fillDB(ctx, require, values, prev, version, version, ndb)
+		root.Version = version
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(err, "Finalize({root%d})", version)
+		roots = append(roots, root)
+		prev = &roots[len(roots)-1]
+	}
+
+	_, err = ndb.Pin(numVersions)
+	require.ErrorIs(err, api.ErrNotFinalized, "Pin() should fail for versions that are not finalized")
+
+	// Pins should be reference-counted.
+	unpin1, err := ndb.Pin(0)
+	require.NoError(err, "Pin(0)")
+	unpin2, err := ndb.Pin(0)
+	require.NoError(err, "Pin(0)")
+
+	err = ndb.Prune(0)
+	require.ErrorIs(err, api.ErrVersionPinned, "Prune() should fail for pinned versions")
+	_, err = ndb.PruneRange(ctx, 0, 3)
+	require.ErrorIs(err, api.ErrVersionPinned, "PruneRange() should fail for pinned start versions")
+
+	unpin1()
+	unpin1()
+	err = ndb.Prune(0)
+	require.ErrorIs(err, api.ErrVersionPinned, "releasing a pin twice should have no effect")
+
+	unpin2()
+	err = ndb.Prune(0)
+	require.NoError(err, "Prune(0)")
+
+	_, err = ndb.Pin(0)
+	require.ErrorIs(err, api.ErrVersionNotFound, "Pin() should fail for pruned versions")
+
+	// Pruning a range should stop before the first pinned version.
+	unpin, err := ndb.Pin(3)
+	require.NoError(err, "Pin(3)")
+	pruned, err := ndb.PruneRange(ctx, 1, 10)
+	require.NoError(err, "PruneRange(1, 10)")
+	require.Equal(2, pruned)
+	require.EqualValues(3, ndb.GetEarliestVersion())
+	unpin()
+
+	// Pinned reads should never observe pruned nodes while pruning is attempted concurrently.
+	var (
+		pruneWg, readWg sync.WaitGroup
+		errCh           = make(chan error, 5)
+		stopCh          = make(chan struct{})
+	)
+	pruneWg.Add(1)
+	go func() {
+		defer pruneWg.Done()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			default:
+			}
+
+			_, err := ndb.PruneRange(ctx, ndb.GetEarliestVersion(), ndb.GetEarliestVersion())
+			switch {
+			case err == nil:
+			case errors.Is(err, api.ErrVersionPinned), errors.Is(err, api.ErrNotEarliest), errors.Is(err, api.ErrCannotPruneLatestVersion):
+			default:
+				errCh <- fmt.Errorf("PruneRange(): %w", err)
+				return
+			}
+		}
+	}()
+	for range 4 {
+		readWg.Add(1)
+		go func() {
+			defer readWg.Done()
+
+			for range 100 {
+				version := ndb.GetEarliestVersion()
+				unpin, err := ndb.Pin(version)
+				if errors.Is(err, api.ErrVersionNotFound) {
+					// Pruned concurrently.
+					continue
+				}
+				if err != nil {
+					errCh <- fmt.Errorf("Pin(%d): %w", version, err)
+					return
+				}
+
+				err = api.Visit(ctx, ndb, roots[version], func(context.Context, node.Node) bool {
+					return true
+				})
+				unpin()
+				if err != nil {
+					errCh <- fmt.Errorf("Visit(%d): %w", version, err)
+					return
+				}
+			}
+		}()
+	}
+	readWg.Wait()
+	close(stopCh)
+	pruneWg.Wait()
+	close(errCh)
+	for err := range errCh {
+		require.NoError(err)
+	}
+
+	// Close should release all pins.
+	version := ndb.GetEarliestVersion()
+	_, err = ndb.Pin(version)
+	require.NoError(err, "Pin()")
+	badgerdb := ndb.(*badgerNodeDB)
+	require.True(badgerdb.pins.IsPinned(version))
+	ndb.Close()
+	require.False(badgerdb.pins.IsPinned(version), "pins should be released on Close()")
+}
+
+func TestRepair(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	cfg := *dbCfg
+	cfg.AllowRepair = true
+	ndb, err := New(&cfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	rdb := ndb.(api.RepairNodeDB)
+
+	var prev *node.Root
+	for version := uint64(0); version < 2; version++ {
+		root := fillDB(ctx, require, testValues[:version+1], prev, version, version, ndb)
+		root.Version = version
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(err, "Finalize({root%d})", version)
+		prev = &root
+	}
+
+	// Prepare a root that is missing from an already finalized version.
+	leaf := &node.LeafNode{Key: []byte("repaired"), Value: []byte("value")}
+	leaf.UpdateHash()
+	ptr := &node.Pointer{Clean: true, Hash: leaf.GetHash(), Node: leaf}
+	missingRoot := node.Root{Namespace: testNs, Version: 1, Type: node.RootTypeState, Hash: ptr.Hash}
+	emptyRoot := node.Root{Namespace: testNs, Version: 1, Type: node.RootTypeState}
+	emptyRoot.Hash.Empty()
+	require.False(ndb.HasRoot(missingRoot), "root should be missing before repair")
+
+	repair := func(trustedRoots []node.Root) error {
+		batch, err := rdb.NewBatchForRepair(emptyRoot, 1, trustedRoots)
+		if err != nil {
+			return err
+		}
+		defer batch.Reset()
+
+		if err = batch.PutNode(ptr); err != nil {
+			return err
+		}
+		return batch.Commit(missingRoot)
+	}
+
+	// Regular batches should not be able to commit into finalized versions.
+	batch, err := ndb.NewBatch(emptyRoot, 1, false)
+	require.NoError(err, "NewBatch()")
+	err = batch.PutNode(ptr)
+	require.NoError(err, "PutNode()")
+	err = batch.Commit(missingRoot)
+	require.ErrorIs(err, api.ErrAlreadyFinalized)
+	batch.Reset()
+
+	// Repairs should only be possible for finalized versions and trusted roots.
+	_, err = rdb.NewBatchForRepair(emptyRoot, 2, nil)
+	require.ErrorIs(err, api.ErrNotFinalized)
+	err = repair([]node.Root{*prev})
+	require.ErrorIs(err, api.ErrRootNotTrusted)
+	require.False(ndb.HasRoot(missingRoot), "untrusted root should not be inserted")
+
+	err = repair([]node.Root{*prev, missingRoot})
+	require.NoError(err, "repair")
+	require.True(ndb.HasRoot(missingRoot), "repaired root should exist")
+	require.True(ndb.HasRoot(*prev), "finalized root should still exist")
+
+	nd, err := ndb.GetNode(missingRoot, &node.Pointer{Clean: true, Hash: missingRoot.Hash})
+	require.NoError(err, "GetNode()")
+	require.EqualValues(leaf.Value, nd.(*node.LeafNode).Value)
+
+	// Repaired roots should not carry any finalization bookkeeping.
+	badgerdb := ndb.(*badgerNodeDB)
+	tx := badgerdb.db.NewTransactionAt(versionToTs(1), false)
+	defer tx.Discard()
+	rootHash := api.TypedHashFromRoot(missingRoot)
+	_, err = tx.Get(rootUpdatedNodesKeyFmt.Encode(uint64(1), &rootHash))
+	require.ErrorIs(err, badger.ErrKeyNotFound, "updated nodes should not be stored")
+
+	// Repairs should not be possible unless enabled.
+	ndb2, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb2.Close()
+	_, err = ndb2.(api.RepairNodeDB).NewBatchForRepair(emptyRoot, 1, []node.Root{missingRoot})
+	require.ErrorIs(err, api.ErrRepairNotAllowed)
+}
+
+func TestCorruption(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+	hdb := ndb.(api.HealthNodeDB)
+
+	root0 := fillDB(ctx, require, testValues[:1], nil, 0, 0, ndb)
+	root0.Version = 0
+	err = ndb.Finalize([]node.Root{root0})
+	require.NoError(err, "Finalize({root0})")
+	root1 := fillDB(ctx, require, testValues[:2], &root0, 0, 1, ndb)
+
+	require.True(ndb.HasRoot(root0), "HasRoot(root0)")
+	require.NoError(hdb.LastError(), "LastError()")
+
+	corrupt := func(key []byte, value []byte) {
+		tx := badgerdb.db.NewTransactionAt(tsMetadata, true)
+		defer tx.Discard()
+		if value == nil {
+			err = tx.Delete(key)
+		} else {
+			err = tx.Set(key, value)
+		}
+		require.NoError(err, "corrupt")
+		err = tx.CommitAt(tsMetadata, nil)
+		require.NoError(err, "CommitAt()")
+
+		// Make sure that corrupted metadata is read from the database.
+		badgerdb.rootsMetaCache.invalidate(0, math.MaxUint64)
+	}
+
+	// Corrupted roots metadata should be reported without panicking.
+	corrupt(rootsMetadataKeyFmt.Encode(uint64(0)), []byte("corrupted"))
+	require.False(ndb.HasRoot(root0), "HasRoot should return false on corrupted metadata")
+	require.ErrorIs(hdb.LastError(), api.ErrCorruptedDB)
+	_, err = ndb.HasRoots([]node.Root{root0})
+	require.ErrorIs(err, api.ErrCorruptedDB)
+
+	// A subsequent successful lookup should clear the error.
+	require.True(ndb.HasRoot(root1), "HasRoot(root1)")
+	require.NoError(hdb.LastError(), "LastError()")
+
+	// A missing updated nodes index should fail finalization instead of panicking.
+	rootHash1 := api.TypedHashFromRoot(root1)
+	corrupt(rootUpdatedNodesKeyFmt.Encode(uint64(1), &rootHash1), nil)
+	err = ndb.Finalize([]node.Root{root1})
+	require.ErrorIs(err, api.ErrCorruptedDB)
+
+	// So should a corrupted one.
+	corrupt(rootUpdatedNodesKeyFmt.Encode(uint64(1), &rootHash1), []byte("corrupted"))
+	err = ndb.Finalize([]node.Root{root1})
+	require.ErrorIs(err, api.ErrCorruptedDB)
+	require.True(ndb.HasRoot(root1), "failed finalization should not remove the root")
+}
+
+func TestVerifyNodeHashes(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	cfg := *dbCfg
+	cfg.VerifyNodeHashes = true
+	cfg.MetricsEnabled = true
+	ndb, err := New(&cfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+
+	root := fillDB(ctx, require, testValues[:1], nil, 0, 0, ndb)
+	root.Version = 0
+	err = ndb.Finalize([]node.Root{root})
+	require.NoError(err, "Finalize()")
+
+	ptr := &node.Pointer{Clean: true, Hash: root.Hash}
+	_, err = ndb.GetNode(root, ptr)
+	require.NoError(err, "GetNode()")
+
+	// Flip a bit in the value of the (leaf) root node so that it still unmarshals.
+	nodeKey := nodeKeyFmt.Encode(&root.Hash)
+	tx := badgerdb.db.NewTransactionAt(versionToTs(0), false)
+	item, err := tx.Get(nodeKey)
+	require.NoError(err, "Get(node)")
+	data, err := item.ValueCopy(nil)
+	require.NoError(err, "ValueCopy()")
+	tx.Discard()
+	data[len(data)-1] ^= 0x01
+
+	batch := badgerdb.db.NewWriteBatchAt(versionToTs(0))
+	err = batch.Set(nodeKey, data)
+	require.NoError(err, "Set(node)")
+	err = batch.Flush()
+	require.NoError(err, "Flush()")
+
+	corruptions := nodeCorruptionCount.With(badgerdb.metrics.labels())
+	before := testutil.ToFloat64(corruptions)
+	_, err = ndb.GetNode(root, ptr)
+	require.ErrorIs(err, api.ErrNodeCorrupted, "GetNode() should detect the corrupted node")
+	require.EqualValues(before+1, testutil.ToFloat64(corruptions), "corruption should be counted")
+
+	// Without verification the corruption goes unnoticed.
+	badgerdb.verifyNodeHashes = false
+	n, err := ndb.GetNode(root, ptr)
+	require.NoError(err, "GetNode()")
+	require.NotEqualValues(testValues[0], n.(*node.LeafNode).Value)
+
+	// Writing a node that does not match its hash should fail.
+	badgerdb.verifyNodeHashes = true
+	leaf := &node.LeafNode{Key: []byte("key"), Value: []byte("value")}
+	leaf.UpdateHash()
+	leaf.Value = []byte("other value")
+	b, err := ndb.NewBatch(root, 1, false)
+	require.NoError(err, "NewBatch()")
+	defer b.Reset()
+	err = b.PutNode(&node.Pointer{Clean: true, Hash: leaf.GetHash(), Node: leaf})
+	require.ErrorIs(err, api.ErrNodeCorrupted, "PutNode() should detect the mismatching node")
+}
+
+func TestRootCache(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "oasis-storage-database-test")
+	require.NoError(err, "TempDir()")
+	defer os.RemoveAll(dir)
+
+	cfg := &api.Config{
+		DB:                dir,
+		Namespace:         testNs,
+		MaxCacheSize:      16 * 1024 * 1024,
+		NoFsync:           true,
+		RootCacheVersions: 3,
+	}
+	ndb, err := New(cfg)
+	require.NoError(err, "New()")
+
+	var (
+		roots []node.Root
+		prev  *node.Root
+	)
+	commitVersion := func(version uint64) node.Root {
+		root := fillDB(ctx, require, testValues[:version%3+1], prev, version, version, ndb)
+		root.Version = version
+		roots = append(roots, root)
+		prev = &roots[len(roots)-1]
+		return root
+	}
+	for version := uint64(0); version < 5; version++ {
+		root := commitVersion(version)
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(err, "Finalize({root%d})", version)
+	}
+	ndb.Close()
+
+	// Reopening the database should warm up the cache with the last finalized versions.
+	ndb, err = New(cfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	cache := ndb.(*badgerNodeDB).rootCache
+
+	require.Equal(3, cache.len(), "cache should be bounded")
+	for _, root := range roots {
+		cached := cache.has(root.Version, api.TypedHashFromRoot(root))
+		require.Equal(root.Version >= 2, cached, "root %d should be cached iff recent", root.Version)
+		require.True(ndb.HasRoot(root), "HasRoot(root%d)", root.Version)
+	}
+
+	// Non-finalized roots should be removed from the cache when the version is finalized.
+	root5 := commitVersion(5)
+	otherRoot5 := fillDB(ctx, require, [][]byte{[]byte("not finalized")}, &roots[4], 4, 5, ndb)
+	otherRoot5.Version = 5
+	require.True(cache.has(5, api.TypedHashFromRoot(root5)))
+	require.True(cache.has(5, api.TypedHashFromRoot(otherRoot5)))
+	require.Equal(3, cache.len(), "earliest version should be evicted")
+	require.False(cache.has(2, api.TypedHashFromRoot(roots[2])), "earliest version should be evicted")
+
+	err = ndb.Finalize([]node.Root{root5})
+	require.NoError(err, "Finalize({root5})")
+	require.True(cache.has(5, api.TypedHashFromRoot(root5)))
+	require.False(cache.has(5, api.TypedHashFromRoot(otherRoot5)), "non-finalized root should be removed")
+	require.False(ndb.HasRoot(otherRoot5))
+
+	// Pruned versions should be invalidated.
+	pruned, err := ndb.PruneRange(ctx, 0, 3)
+	require.NoError(err, "PruneRange(0, 3)")
+	require.Equal(4, pruned)
+	require.False(cache.has(3, api.TypedHashFromRoot(roots[3])), "pruned root should be removed")
+	require.False(ndb.HasRoot(roots[3]))
+	require.True(ndb.HasRoot(roots[4]))
+	require.True(ndb.HasRoot(root5))
+}
+
+func TestRootsMetadataCache(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	// The root cache is disabled so that all lookups need the roots metadata.
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+	cache := badgerdb.rootsMetaCache
+
+	root0 := fillDB(ctx, require, testValues, nil, 0, 0, ndb)
+	root0.Version = 0
+	err = ndb.Finalize([]node.Root{root0})
+	require.NoError(err, "Finalize({root0})")
+
+	rootA := fillDB(ctx, require, [][]byte{[]byte("finalized value")}, &root0, 0, 1, ndb)
+	rootB := fillDB(ctx, require, [][]byte{[]byte("discarded value")}, &root0, 0, 1, ndb)
+	require.True(ndb.HasRoot(rootA), "HasRoot(rootA)")
+	require.True(ndb.HasRoot(rootB), "HasRoot(rootB)")
+	cached, _, ok := cache.get(1)
+	require.True(ok, "committed roots metadata should be cached")
+	require.Len(cached.Roots, 2)
+
+	// An interrupted finalization must not modify the cached metadata.
+	errInterrupted := fmt.Errorf("interrupted")
+	badgerdb.finalizeInterruptFn = func() error {
+		return errInterrupted
+	}
+	err = ndb.Finalize([]node.Root{rootA})
+	require.ErrorIs(err, errInterrupted, "Finalize({rootA}) should be interrupted")
+	require.Len(cached.Roots, 2, "cached metadata should not be modified")
+	require.True(ndb.HasRoot(rootB), "metadata has not been committed")
+
+	// The cache must not serve stale metadata once finalization is complete.
+	badgerdb.finalizeInterruptFn = nil
+	err = ndb.Finalize([]node.Root{rootA})
+	require.NoError(err, "Finalize({rootA})")
+	require.True(ndb.HasRoot(rootA), "HasRoot(rootA)")
+	require.False(ndb.HasRoot(rootB), "discarded root should not be served from the cache")
+	roots, err := ndb.GetRootsForVersion(1)
+	require.NoError(err, "GetRootsForVersion(1)")
+	require.Equal([]node.Root{rootA}, roots)
+
+	// Concurrent readers filling the cache must not resurrect stale metadata.
+	var (
+		wg   sync.WaitGroup
+		stop = make(chan struct{})
+	)
+	prev := rootA
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				latest, _ := ndb.GetLatestVersion()
+				for version := ndb.GetEarliestVersion(); version <= latest+1; version++ {
+					_, _ = ndb.GetRootsForVersion(version)
+				}
+			}
+		}()
+	}
+	for version := uint64(2); version < 20; version++ {
+		keep := fillDB(ctx, require, [][]byte{[]byte(fmt.Sprintf("kept %d", version))}, &prev, prev.Version, version, ndb)
+		discard := fillDB(ctx, require, [][]byte{[]byte(fmt.Sprintf("discarded %d", version))}, &prev, prev.Version, version, ndb)
+		require.True(ndb.HasRoot(discard), "HasRoot(discard%d)", version)
+
+		err = ndb.Finalize([]node.Root{keep})
+		require.NoError(err, "Finalize({keep%d})", version)
+		require.True(ndb.HasRoot(keep), "HasRoot(keep%d)", version)
+		require.False(ndb.HasRoot(discard), "HasRoot(discard%d)", version)
+		prev = keep
+	}
+	close(stop)
+	wg.Wait()
+
+	// Pruned versions should be invalidated.
+	_, err = ndb.PruneRange(ctx, 0, 10)
+	require.NoError(err, "PruneRange(0, 10)")
+	for version := uint64(0); version <= 10; version++ {
+		_, _, ok = cache.get(version)
+		require.False(ok, "pruned version %d should not be cached", version)
+	}
+}
+
+func BenchmarkHasRoot(b *testing.B) {
+	for _, cacheEnabled := range []bool{false, true} {
+		b.Run(fmt.Sprintf("RootsMetadataCache=%t", cacheEnabled), func(b *testing.B) {
+			require := require.New(b)
+			ctx := context.Background()
+
+			// The root cache is disabled so that every lookup needs the roots metadata.
+			ndb, err := New(dbCfg)
+			require.NoError(err, "New()")
+			defer ndb.Close()
+			if !cacheEnabled {
+				ndb.(*badgerNodeDB).rootsMetaCache = nil
+			}
+
+			// The hot version of a busy runtime has many roots.
+			var roots []node.Root
+			for i := range 32 {
+				root := fillDB(ctx, require, [][]byte{[]byte(fmt.Sprintf("value %d", i))}, nil, 0, 0, ndb)
+				root.Version = 0
+				roots = append(roots, root)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var i int
+				for pb.Next() {
+					if !ndb.HasRoot(roots[i%len(roots)]) {
+						b.Fatal("HasRoot: root not found")
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func TestBadgerOptions(t *testing.T) {
+	enabled, disabled := true, false
+	db := &badgerNodeDB{logger: logging.GetLogger("mkvs/db/badger/test")}
+
+	for _, tc := range []struct {
+		name  string
+		opts  api.BadgerOptions
+		check func(*require.Assertions, badger.Options)
+	}{
+		{"MemTableSize", api.BadgerOptions{MemTableSize: 8 << 20}, func(r *require.Assertions, o badger.Options) {
+			r.EqualValues(8<<20, o.MemTableSize)
+		}},
+		{"BaseTableSize", api.BadgerOptions{BaseTableSize: 16 << 20}, func(r *require.Assertions, o badger.Options) {
+			r.EqualValues(16<<20, o.BaseTableSize)
+		}},
+		{"BlockCacheSize", api.BadgerOptions{BlockCacheSize: 1 << 30}, func(r *require.Assertions, o badger.Options) {
+			r.EqualValues(1<<30, o.BlockCacheSize, "block cache size should override max cache size")
+		}},
+		{"NumCompactors", api.BadgerOptions{NumCompactors: 8}, func(r *require.Assertions, o badger.Options) {
+			r.Equal(8, o.NumCompactors)
+		}},
+		{"CompactL0OnClose", api.BadgerOptions{CompactL0OnClose: &enabled}, func(r *require.Assertions, o badger.Options) {
+			r.True(o.CompactL0OnClose)
+		}},
+		{"ValueThreshold", api.BadgerOptions{ValueThreshold: 4096}, func(r *require.Assertions, o badger.Options) {
+			r.EqualValues(4096, o.ValueThreshold)
+		}},
+		{"SyncWrites", api.BadgerOptions{SyncWrites: &enabled}, func(r *require.Assertions, o badger.Options) {
+			r.True(o.SyncWrites, "sync writes should override no fsync")
+		}},
+		{"SyncWritesDisabled", api.BadgerOptions{SyncWrites: &disabled}, func(r *require.Assertions, o badger.Options) {
+			r.False(o.SyncWrites)
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			cfg := *dbCfg
+			cfg.BadgerOptions = &tc.opts
+			require.NoError(cfg.BadgerOptions.Validate(), "Validate")
+			tc.check(require, commonConfigToBadgerOptions(&cfg, db))
+
+			ndb, err := New(&cfg)
+			require.NoError(err, "New()")
+			ndb.Close()
+		})
+	}
+
+	// Defaults should be kept when no options are configured.
+	defaults := commonConfigToBadgerOptions(dbCfg, db)
+	tuned := commonConfigToBadgerOptions(&api.Config{
+		Namespace:     dbCfg.Namespace,
+		MaxCacheSize:  dbCfg.MaxCacheSize,
+		NoFsync:       dbCfg.NoFsync,
+		MemoryOnly:    dbCfg.MemoryOnly,
+		BadgerOptions: &api.BadgerOptions{},
+	}, db)
+	require.Equal(t, defaults.MemTableSize, tuned.MemTableSize)
+	require.Equal(t, defaults.NumCompactors, tuned.NumCompactors)
+	require.Equal(t, defaults.ValueThreshold, tuned.ValueThreshold)
+	require.Equal(t, defaults.BlockCacheSize, tuned.BlockCacheSize)
+
+	// Invalid options should be rejected.
+	for _, opts := range []api.BadgerOptions{
+		{MemTableSize: -1},
+		{NumCompactors: 1},
+		{ValueThreshold: api.MaxBadgerValueThreshold + 1},
+	} {
+		cfg := *dbCfg
+		cfg.BadgerOptions = &opts
+		_, err := New(&cfg)
+		require.Error(t, err, "New() should fail with invalid options")
+	}
+}
+
+func TestMemoryOnlyConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(*api.Config)
+	}{
+		{"ReadOnly", func(cfg *api.Config) { cfg.ReadOnly = true }},
+		{"AllowResumeMultipart", func(cfg *api.Config) { cfg.AllowResumeMultipart = true }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := *dbCfg
+			tc.modify(&cfg)
+			_, err := New(&cfg)
+			require.Error(t, err, "New() should fail for memory-only databases")
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	stats, err := ndb.Stats(true)
+	require.NoError(err, "Stats()")
+	require.NotNil(stats.NumRoots)
+	require.Zero(*stats.NumRoots)
+	require.Nil(stats.LatestVersion)
+
+	root1 := fillDB(ctx, require, testValues, nil, 1, 2, ndb)
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+	fillDB(ctx, require, testValues[:1], &root1, 2, 3, ndb)
+
+	stats, err = ndb.Stats(true)
+	require.NoError(err, "Stats()")
+	require.NotNil(stats.NumRoots)
+	require.EqualValues(2, *stats.NumRoots)
+	require.EqualValues(0, stats.EarliestVersion)
+	require.NotNil(stats.LatestVersion)
+	require.EqualValues(2, *stats.LatestVersion)
+
+	size, err := ndb.Size()
+	require.NoError(err, "Size()")
+	require.Equal(stats.Size(), size)
+
+	// Roots should only be counted when requested.
+	stats, err = ndb.Stats(false)
+	require.NoError(err, "Stats()")
+	require.Nil(stats.NumRoots)
+	require.EqualValues(2, *stats.LatestVersion)
+}
+
+func TestVersionStats(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+	statsdb := ndb.(api.VersionStatsNodeDB)
+
+	// rootNodes returns the sizes of all serialized nodes of the given root.
+	rootNodes := func(root node.Root) map[hash.Hash]uint64 {
+		nodes := make(map[hash.Hash]uint64)
+		err := api.Visit(ctx, ndb, root, func(_ context.Context, n node.Node) bool {
+			data, err := n.MarshalBinary()
+			require.NoError(err, "MarshalBinary()")
+			nodes[n.GetHash()] = uint64(len(data))
+			return true
+		})
+		require.NoError(err, "Visit()")
+		return nodes
+	}
+	// writtenNodes returns the number and size of the nodes of the given root that are not part
+	// of the given base root and must have been written when committing the root.
+	writtenNodes := func(root node.Root, base map[hash.Hash]uint64) (numNodes uint64, numBytes uint64) {
+		for h, size := range rootNodes(root) {
+			if _, ok := base[h]; ok {
+				continue
+			}
+			numNodes++
+			numBytes += size
+		}
+		return
+	}
+	requireStats := func(version, expectedNodes, expectedBytes uint64) {
+		numNodes, numBytes, err := statsdb.VersionStats(version)
+		require.NoError(err, "VersionStats(%d)", version)
+		require.Equal(expectedNodes, numNodes, "number of nodes written in version %d", version)
+		require.Equal(expectedBytes, numBytes, "bytes written in version %d", version)
+	}
+
+	requireStats(1, 0, 0)
+
+	// All nodes of the first root are written.
+	root1 := fillDB(ctx, require, testValues, nil, 0, 1, ndb)
+	nodes1, bytes1 := writtenNodes(root1, nil)
+	require.NotZero(nodes1)
+	requireStats(1, nodes1, bytes1)
+
+	// Only updated nodes of derived roots are written, for each of the roots.
+	rootA := fillDB(ctx, require, [][]byte{[]byte("a")}, &root1, 1, 2, ndb)
+	rootB := fillDB(ctx, require, [][]byte{[]byte("b")}, &root1, 1, 2, ndb)
+	base := rootNodes(root1)
+	nodesA, bytesA := writtenNodes(rootA, base)
+	nodesB, bytesB := writtenNodes(rootB, base)
+	require.NotZero(nodesA)
+	requireStats(2, nodesA+nodesB, bytesA+bytesB)
+
+	// Contributions of discarded roots are subtracted on finalization.
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+	err = ndb.Finalize([]node.Root{rootA})
+	require.NoError(err, "Finalize({rootA})")
+	requireStats(1, nodes1, bytes1)
+	requireStats(2, nodesA, bytesA)
+
+	// Statistics are removed together with the version.
+	_, err = ndb.PruneRange(ctx, 0, 1)
+	require.NoError(err, "PruneRange()")
+	_, _, err = statsdb.VersionStats(1)
+	require.ErrorIs(err, api.ErrVersionNotFound, "VersionStats() should fail for pruned versions")
+	requireStats(2, nodesA, bytesA)
+
+	tx := badgerdb.db.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: rootStatsKeyFmt.Encode(uint64(1))})
+	defer it.Close()
+	it.Rewind()
+	require.False(it.Valid(), "statistics of pruned versions should be removed")
+}
+
+func TestGetUpdatedNodes(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+	udb := ndb.(api.UpdatedNodesNodeDB)
+
+	rootNodes := func(root node.Root) map[hash.Hash]bool {
+		nodes := make(map[hash.Hash]bool)
+		err := api.Visit(ctx, ndb, root, func(_ context.Context, n node.Node) bool {
+			nodes[n.GetHash()] = true
+			return true
+		})
+		require.NoError(err, "Visit()")
+		return nodes
+	}
+	updatedNodes := func(root node.Root) map[hash.Hash]bool {
+		nodes, err := udb.GetUpdatedNodes(root)
+		require.NoError(err, "GetUpdatedNodes()")
+		hashes := make(map[hash.Hash]bool)
+		for _, n := range nodes {
+			hashes[n.GetHash()] = true
+		}
+		require.Len(hashes, len(nodes), "updated nodes should be unique")
+		return hashes
+	}
+
+	// All nodes of the first root are added.
+	root1 := fillDB(ctx, require, testValues, nil, 0, 1, ndb)
+	nodes1 := rootNodes(root1)
+	require.Equal(nodes1, updatedNodes(root1))
+
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+	_, err = udb.GetUpdatedNodes(root1)
+	require.ErrorIs(err, api.ErrAlreadyFinalized, "GetUpdatedNodes() should fail for finalized versions")
+
+	// Only nodes added by a derived root are returned, removed nodes are not.
+	rootA := fillDB(ctx, require, [][]byte{[]byte("updated value")}, &root1, 1, 2, ndb)
+	nodesA := rootNodes(rootA)
+	addedA := updatedNodes(rootA)
+	require.NotEmpty(addedA)
+	for h := range nodesA {
+		require.Equal(!nodes1[h], addedA[h], "node %s should be returned iff it was added", h)
+	}
+
+	tx := badgerdb.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+	var removed int
+	_, err = visitUpdatedNodes(tx, 2, api.TypedHashFromRoot(rootA), func(nodes []updatedNode) {
+		for _, n := range nodes {
+			if n.Removed {
+				require.False(addedA[n.Hash], "removed node should not be returned")
+				removed++
+			}
+		}
+	})
+	require.NoError(err, "visitUpdatedNodes()")
+	require.NotZero(removed, "derived root should remove nodes")
+
+	// Unknown roots should be reported.
+	bogusRoot := rootA
+	bogusRoot.Hash = hash.NewFromBytes([]byte("bogus root"))
+	_, err = udb.GetUpdatedNodes(bogusRoot)
+	require.ErrorIs(err, api.ErrRootNotFound)
+
+	err = ndb.Finalize([]node.Root{rootA})
+	require.NoError(err, "Finalize({rootA})")
+	_, err = udb.GetUpdatedNodes(rootA)
+	require.ErrorIs(err, api.ErrAlreadyFinalized, "GetUpdatedNodes() should fail once finalized")
+}
+
+func TestResumeMultipartRestore(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "oasis-storage-database-test")
+	require.NoError(err, "TempDir()")
+	defer os.RemoveAll(dir)
+
+	// Create a checkpoint that consists of multiple chunks.
+	values := make([][]byte, 0, 100)
+	for i := range 100 {
+		values = append(values, []byte(fmt.Sprintf("resumable value %d", i)))
+	}
+	srcdb, err := New(&api.Config{
+		Namespace:    testNs,
+		MaxCacheSize: 16 * 1024 * 1024,
+		NoFsync:      true,
+		MemoryOnly:   true,
+	})
+	require.NoError(err, "New()")
+	defer srcdb.Close()
+	fc, err := checkpoint.NewFileCreator(dir, srcdb)
+	require.NoError(err, "NewFileCreator()")
+	ckRoot := fillDB(ctx, require, values, nil, 1, 2, srcdb)
+	ckMeta, err := fc.CreateCheckpoint(ctx, ckRoot, 1024)
+	require.NoError(err, "CreateCheckpoint()")
+	require.Greater(len(ckMeta.Chunks), 2, "pointless test with too few chunks")
+
+	restoreChunks := func(ndb api.NodeDB, indices ...int) {
+		rs, err := checkpoint.NewRestorer(ndb)
+		require.NoError(err, "NewRestorer()")
+		err = rs.StartRestore(ctx, ckMeta)
+		require.NoError(err, "StartRestore()")
+		for _, idx := range indices {
+			chunkMeta, err := ckMeta.GetChunkMetadata(uint64(idx))
+			require.NoError(err, "GetChunkMetadata(%d)", idx)
+			var buf bytes.Buffer
+			err = fc.GetCheckpointChunk(ctx, chunkMeta, &buf)
+			require.NoError(err, "GetCheckpointChunk(%d)", idx)
+			_, err = rs.RestoreChunk(ctx, uint64(idx), &buf)
+			require.NoError(err, "RestoreChunk(%d)", idx)
+		}
+	}
+	// interruptRestore starts a restore, imports the first chunk and closes the database.
+	interruptRestore := func(cfg *api.Config) {
+		ndb, err := New(cfg)
+		require.NoError(err, "New()")
+		err = ndb.StartMultipartInsert(ckRoot.Version)
+		require.NoError(err, "StartMultipartInsert()")
+		restoreChunks(ndb, 0)
+		ndb.Close()
+	}
+	newCfg := func(name string, allowResume bool) *api.Config {
+		return &api.Config{
+			DB:                   dir + "/" + name,
+			Namespace:            testNs,
+			MaxCacheSize:         16 * 1024 * 1024,
+			NoFsync:              true,
+			AllowResumeMultipart: allowResume,
+		}
+	}
+
+	t.Run("Discard", func(_ *testing.T) {
+		cfg := newCfg("discard", false)
+		interruptRestore(cfg)
+
+		// Without opting in, the multipart restore should be cleaned up on open.
+		ndb, err := New(cfg)
+		require.NoError(err, "New()")
+		defer ndb.Close()
+		badgerdb := ndb.(*badgerNodeDB)
+		require.EqualValues(0, ndb.GetMultipartVersion())
+		verifyNodes(require, badgerdb, keySet{})
+		checkNoLogKeys(require, badgerdb)
+	})
+
+	t.Run("Resume", func(_ *testing.T) {
+		cfg := newCfg("resume", true)
+		interruptRestore(cfg)
+
+		ndb, err := New(cfg)
+		require.NoError(err, "New()")
+		defer ndb.Close()
+		badgerdb := ndb.(*badgerNodeDB)
+		require.EqualValues(ckRoot.Version, ndb.GetMultipartVersion())
+		require.True(ndb.HasRoot(ckRoot), "root of the restored chunk should be kept")
+
+		// Multipart insert at the same version should be a no-op, a different one should fail.
+		err = ndb.StartMultipartInsert(ckRoot.Version)
+		require.NoError(err, "StartMultipartInsert()")
+		err = ndb.StartMultipartInsert(ckRoot.Version + 1)
+		require.ErrorIs(err, api.ErrMultipartInProgress)
+
+		// Restore all chunks, the already restored one should be skipped.
+		indices := make([]int, 0, len(ckMeta.Chunks))
+		for i := range ckMeta.Chunks {
+			indices = append(indices, i)
+		}
+		restoreChunks(ndb, indices...)
+
+		err = ndb.Finalize([]node.Root{ckRoot})
+		require.NoError(err, "Finalize()")
+		require.EqualValues(0, ndb.GetMultipartVersion())
+		checkNoLogKeys(require, badgerdb)
+
+		tree := mkvs.NewWithRoot(nil, ndb, ckRoot)
+		defer tree.Close()
+		for i, value := range values {
+			v, err := tree.Get(ctx, []byte(strconv.Itoa(i)))
+			require.NoError(err, "Get(%d)", i)
+			require.Equal(value, v)
+		}
+	})
+
+	t.Run("AbortResumed", func(_ *testing.T) {
+		cfg := newCfg("abort", true)
+		interruptRestore(cfg)
+
+		// Aborting a resumed multipart restore should still fully clean up.
+		ndb, err := New(cfg)
+		require.NoError(err, "New()")
+		defer ndb.Close()
+		badgerdb := ndb.(*badgerNodeDB)
+		require.EqualValues(ckRoot.Version, ndb.GetMultipartVersion())
+		err = ndb.AbortMultipartInsert()
+		require.NoError(err, "AbortMultipartInsert()")
+		require.EqualValues(0, ndb.GetMultipartVersion())
+		verifyNodes(require, badgerdb, keySet{})
+		checkNoLogKeys(require, badgerdb)
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ns := common.NewTestNamespaceFromSeed([]byte("badger node db metrics test ns"), 0)
+	ndb, err := New(&api.Config{
+		Namespace:      ns,
+		MaxCacheSize:   16 * 1024 * 1024,
+		NoFsync:        true,
+		MemoryOnly:     true,
+		MetricsEnabled: true,
+	})
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	labels := prometheus.Labels{"runtime": ns.String()}
+	labelsWith := func(name, value string) prometheus.Labels {
+		return prometheus.Labels{"runtime": ns.String(), name: value}
+	}
+
+	emptyRoot := node.Root{Namespace: ns, Type: node.RootTypeState}
+	emptyRoot.Hash.Empty()
+	var roots []node.Root
+	for version := uint64(0); version < 3; version++ {
+		prev := emptyRoot
+		if version > 0 {
+			prev = roots[version-1]
+		}
+		tree := mkvs.NewWithRoot(nil, ndb, prev)
+		err = tree.Insert(ctx, []byte(strconv.FormatUint(version, 10)), []byte("value"))
+		require.NoError(err, "Insert()")
+		_, rootHash, err := tree.Commit(ctx, ns, version)
+		require.NoError(err, "Commit()")
+		tree.Close()
+
+		root := node.Root{Namespace: ns, Version: version, Type: node.RootTypeState, Hash: rootHash}
+		roots = append(roots, root)
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(err, "Finalize()")
+	}
+	require.EqualValues(3, testutil.ToFloat64(batchCommitCount.With(labels)))
+	require.Positive(testutil.ToFloat64(batchCommitBytes.With(labels)))
+	require.EqualValues(1, testutil.CollectAndCount(batchSize))
+	require.EqualValues(2, testutil.ToFloat64(latestFinalizedVersionGauge.With(labels)))
+	require.EqualValues(0, testutil.ToFloat64(multipartInProgressGauge.With(labels)))
+
+	// Node lookups.
+	hits := testutil.ToFloat64(getNodeCount.With(labelsWith("result", "hit")))
+	misses := testutil.ToFloat64(getNodeCount.With(labelsWith("result", "miss")))
+	_, err = ndb.GetNode(roots[2], &node.Pointer{Clean: true, Hash: roots[2].Hash})
+	require.NoError(err, "GetNode()")
+	_, err = ndb.GetNode(roots[2], &node.Pointer{Clean: true, Hash: hash.NewFromBytes([]byte("missing"))})
+	require.ErrorIs(err, api.ErrNodeNotFound)
+	require.Equal(hits+1, testutil.ToFloat64(getNodeCount.With(labelsWith("result", "hit"))))
+	require.Equal(misses+1, testutil.ToFloat64(getNodeCount.With(labelsWith("result", "miss"))))
+
+	// Write log lookups.
+	_, err = ndb.GetWriteLog(ctx, roots[0], roots[1])
+	require.NoError(err, "GetWriteLog()")
+	_, err = ndb.GetWriteLog(ctx, roots[1], roots[2])
+	require.NoError(err, "GetWriteLog()")
+	_, err = ndb.GetWriteLog(ctx, emptyRoot, roots[1])
+	require.ErrorIs(err, api.ErrWriteLogNotFound)
+	require.EqualValues(2, testutil.ToFloat64(getWriteLogCount.With(labelsWith("result", "found"))))
+	require.EqualValues(1, testutil.ToFloat64(getWriteLogCount.With(labelsWith("result", "not_found"))))
+
+	// Pruning.
+	_, err = ndb.PruneRange(ctx, 0, 0)
+	require.NoError(err, "PruneRange()")
+	require.EqualValues(1, testutil.ToFloat64(earliestVersionGauge.With(labels)))
+
+	// Multipart restores.
+	err = ndb.StartMultipartInsert(3)
+	require.NoError(err, "StartMultipartInsert()")
+	require.EqualValues(1, testutil.ToFloat64(multipartInProgressGauge.With(labels)))
+	err = ndb.AbortMultipartInsert()
+	require.NoError(err, "AbortMultipartInsert()")
+	require.EqualValues(0, testutil.ToFloat64(multipartInProgressGauge.With(labels)))
+}
+
+func TestExportImportRoot(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	srcDB, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer srcDB.Close()
+
+	// Build a tree with a few thousand keys.
+	emptyRoot := node.Root{
+		Namespace: testNs,
+		Version:   1,
+		Type:      node.RootTypeState,
+	}
+	emptyRoot.Hash.Empty()
+
+	var wl writelog.WriteLog
+	for i := 0; i < 5000; i++ {
+		wl = append(wl, writelog.LogEntry{
+			Key:   []byte(fmt.Sprintf("key %d", i)),
+			Value: []byte(fmt.Sprintf("value %d", i)),
+		})
+	}
+	tree := mkvs.NewWithRoot(nil, srcDB, emptyRoot)
+	err = tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(wl))
+	require.NoError(err, "ApplyWriteLog()")
+	_, rootHash, err := tree.Commit(ctx, testNs, 1)
+	require.NoError(err, "Commit()")
+	tree.Close()
+
+	root := node.Root{
+		Namespace: testNs,
+		Version:   1,
+		Type:      node.RootTypeState,
+		Hash:      rootHash,
+	}
+	err = srcDB.Finalize([]node.Root{root})
+	require.NoError(err, "Finalize()")
+
+	var export bytes.Buffer
+	err = api.ExportRoot(ctx, srcDB, root, &export)
+	require.NoError(err, "ExportRoot()")
+
+	// Importing into a finalized version should fail.
+	_, err = api.ImportRoot(ctx, srcDB, bytes.NewReader(export.Bytes()))
+	require.ErrorIs(err, api.ErrAlreadyFinalized, "ImportRoot() into finalized version")
+
+	// Importing a corrupted export should fail.
+	dstDB, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer dstDB.Close()
+
+	corrupted := bytes.Clone(export.Bytes())
+	idx := bytes.LastIndex(corrupted, []byte("value 4999"))
+	require.NotEqual(-1, idx)
+	corrupted[idx] = 'V'
+	_, err = api.ImportRoot(ctx, dstDB, bytes.NewReader(corrupted))
+	require.ErrorIs(err, api.ErrExportCorrupted, "ImportRoot() with corrupted export")
+	require.False(dstDB.HasRoot(root), "corrupted root should not be imported")
+
+	// Round-trip.
+	imported, err := api.ImportRoot(ctx, dstDB, bytes.NewReader(export.Bytes()))
+	require.NoError(err, "ImportRoot()")
+	require.True(imported.Equal(&root), "imported root should match exported root")
+	err = dstDB.Finalize([]node.Root{imported})
+	require.NoError(err, "Finalize()")
+
+	tree = mkvs.NewWithRoot(nil, dstDB, imported)
+	defer tree.Close()
+	for _, entry := range wl {
+		value, err := tree.Get(ctx, entry.Key)
+		require.NoError(err, "Get()")
+		require.Equal(entry.Value, value)
+	}
+
+	var reexport bytes.Buffer
+	err = api.ExportRoot(ctx, dstDB, imported, &reexport)
+	require.NoError(err, "ExportRoot()")
+	require.Equal(export.Bytes(), reexport.Bytes(), "re-export should be identical")
+}
+
+func TestTombstones(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	cfg := *dbCfg
+	cfg.TombstoneRetentionVersions = 3
+	ndb, err := New(&cfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	tdb := ndb.(api.TombstoneNodeDB)
+
+	key := func(i int) []byte {
+		return []byte(fmt.Sprintf("key %d", i))
+	}
+
+	var roots []node.Root
+	commit := func(wl writelog.WriteLog) node.Root {
+		version := uint64(len(roots))
+		prevRoot := node.Root{
+			Namespace: testNs,
+			Version:   version,
+			Type:      node.RootTypeState,
+		}
+		prevRoot.Hash.Empty()
+		if version > 0 {
+			prevRoot = roots[version-1]
+		}
+
+		tree := mkvs.NewWithRoot(nil, ndb, prevRoot)
+		defer tree.Close()
+		err := tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(wl))
+		require.NoError(err, "ApplyWriteLog()")
+		_, rootHash, err := tree.Commit(ctx, testNs, version)
+		require.NoError(err, "Commit()")
+
+		root := node.Root{
+			Namespace: testNs,
+			Version:   version,
+			Type:      node.RootTypeState,
+			Hash:      rootHash,
+		}
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(err, "Finalize(%d)", version)
+		roots = append(roots, root)
+		return root
+	}
+
+	// Version 0 inserts keys, version 1 deletes some of them and version 3 re-inserts one.
+	var wl writelog.WriteLog
+	for i := 0; i < 100; i++ {
+		wl = append(wl, writelog.LogEntry{Key: key(i), Value: []byte("value")})
+	}
+	commit(wl)
+	commit(writelog.WriteLog{{Key: key(1)}, {Key: key(2)}})
+	commit(writelog.WriteLog{{Key: key(0), Value: []byte("updated value")}})
+	commit(writelog.WriteLog{{Key: key(2), Value: []byte("reinserted value")}})
+
+	// Deletion proofs should verify against the root at the deletion version.
+	proof, err := tdb.GetDeletionProof(ctx, roots[2], key(1))
+	require.NoError(err, "GetDeletionProof()")
+	require.EqualValues(1, proof.DeletedVersion)
+	require.EqualValues(2, proof.Version)
+	require.NoError(proof.Verify(ctx, roots[1].Hash), "Verify()")
+	require.Error(proof.Verify(ctx, roots[2].Hash), "Verify() with the wrong root should fail")
+	require.Error(api.VerifyKeyAbsent(ctx, roots[0].Hash, &proof.Proof, key(1)), "proof should not verify against the root before the deletion")
+
+	proof, err = tdb.GetDeletionProof(ctx, roots[1], key(1))
+	require.NoError(err, "GetDeletionProof() at the deletion version")
+	require.NoError(proof.Verify(ctx, roots[1].Hash), "Verify()")
+
+	// Keys that were never deleted or deleted later should have no tombstones.
+	_, err = tdb.GetDeletionProof(ctx, roots[2], key(0))
+	require.ErrorIs(err, api.ErrTombstoneNotFound, "GetDeletionProof() for a key that was never deleted")
+	_, err = tdb.GetDeletionProof(ctx, roots[0], key(1))
+	require.ErrorIs(err, api.ErrTombstoneNotFound, "GetDeletionProof() before the deletion")
+
+	// Re-inserted keys should only have tombstones before the re-insertion.
+	proof, err = tdb.GetDeletionProof(ctx, roots[2], key(2))
+	require.NoError(err, "GetDeletionProof()")
+	require.NoError(proof.Verify(ctx, roots[1].Hash), "Verify()")
+	_, err = tdb.GetDeletionProof(ctx, roots[3], key(2))
+	require.ErrorIs(err, api.ErrTombstoneNotFound, "GetDeletionProof() after the re-insertion")
+
+	// Proofs should remain available after the main tree is pruned past the deletion version.
+	_, err = ndb.PruneRange(ctx, 0, 2)
+	require.NoError(err, "PruneRange()")
+	require.EqualValues(3, ndb.GetEarliestVersion())
+	require.False(ndb.HasRoot(roots[1]), "deletion root should be pruned")
+
+	for _, root := range roots[1:4] {
+		proof, err = tdb.GetDeletionProof(ctx, root, key(1))
+		require.NoError(err, "GetDeletionProof() at version %d after pruning", root.Version)
+		require.EqualValues(1, proof.DeletedVersion)
+		require.NoError(proof.Verify(ctx, roots[1].Hash), "Verify() after pruning")
+	}
+
+	// Tombstones should be pruned based on their own retention horizon.
+	require.EqualValues(1, tdb.GetEarliestTombstoneVersion())
+	commit(writelog.WriteLog{{Key: key(3)}})
+	require.EqualValues(2, tdb.GetEarliestTombstoneVersion())
+	_, err = tdb.GetDeletionProof(ctx, roots[4], key(1))
+	require.ErrorIs(err, api.ErrTombstoneNotFound, "GetDeletionProof() beyond the retention horizon")
+	_, err = tdb.GetDeletionProof(ctx, roots[3], key(1))
+	require.ErrorIs(err, api.ErrTombstoneNotFound, "GetDeletionProof() beyond the retention horizon")
+
+	proof, err = tdb.GetDeletionProof(ctx, roots[4], key(3))
+	require.NoError(err, "GetDeletionProof()")
+	require.NoError(proof.Verify(ctx, roots[4].Hash), "Verify()")
+
+	tx := ndb.(*badgerNodeDB).db.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+	th := tombstoneKeyHash(node.RootTypeState, key(1))
+	_, err = tx.Get(tombstoneKeyFmt.Encode(&th, uint64(1)))
+	require.ErrorIs(err, badger.ErrKeyNotFound, "tombstone should be removed")
+}
+
+func TestVisitConcurrent(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+
+	values := make([][]byte, 0, 1000)
+	for i := 0; i < cap(values); i++ {
+		values = append(values, []byte(fmt.Sprintf("value %d", i)))
+	}
+	root := fillDB(ctx, require, values, nil, 0, 0, ndb)
+	root.Version = 0
+
+	collect := func(opts api.VisitOptions, skip func(node.Node) bool) map[hash.Hash]bool {
+		var (
+			lock       sync.Mutex
+			visited    = make(map[hash.Hash]bool)
+			duplicates int
+		)
+		err := api.VisitWithOptions(ctx, ndb, root, func(_ context.Context, n node.Node) bool {
+			lock.Lock()
+			defer lock.Unlock()
+
+			h := n.GetHash()
+			if visited[h] {
+				duplicates++
+			}
+			visited[h] = true
+			return !skip(n)
+		}, opts)
+		require.NoError(err, "VisitWithOptions()")
+		require.Zero(duplicates, "nodes should only be visited once")
+		return visited
+	}
+	visitAll := func(node.Node) bool { return false }
+
+	// Concurrent traversal should visit the same nodes as sequential traversal.
+	sequential := collect(api.VisitOptions{}, visitAll)
+	concurrent := collect(api.VisitOptions{Concurrency: 4}, visitAll)
+	require.Equal(sequential, concurrent)
+
+	// Returning false should stop descent into the subtree.
+	rootNode, err := ndb.GetNode(root, &node.Pointer{Clean: true, Hash: root.Hash})
+	require.NoError(err, "GetNode()")
+	skipped := rootNode.(*node.InternalNode).Left.Hash
+	skipLeft := func(n node.Node) bool {
+		h := n.GetHash()
+		return h.Equal(&skipped)
+	}
+
+	sequential = collect(api.VisitOptions{}, skipLeft)
+	concurrent = collect(api.VisitOptions{Concurrency: 4}, skipLeft)
+	require.Equal(sequential, concurrent)
+	require.True(concurrent[skipped], "skipped node itself should be visited")
+	require.Less(len(concurrent), len(collect(api.VisitOptions{}, visitAll)))
+}
+
+func TestNodeCache(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	cfg := *dbCfg
+	cfg.NodeCacheSize = 16 * 1024 * 1024
+	ndb, err := New(&cfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	cache := ndb.(*badgerNodeDB).nodeCache
+
+	// Two unrelated roots so that pruning the first one removes all of its nodes.
+	root0 := fillDB(ctx, require, testValues, nil, 0, 0, ndb)
+	root0.Version = 0
+	require.NoError(ndb.Finalize([]node.Root{root0}), "Finalize(0)")
+	root1 := fillDB(ctx, require, testValues[:1], nil, 1, 1, ndb)
+	root1.Version = 1
+	require.NoError(ndb.Finalize([]node.Root{root1}), "Finalize(1)")
+
+	visit := func(root node.Root) map[hash.Hash]bool {
+		visited := make(map[hash.Hash]bool)
+		err := api.Visit(ctx, ndb, root, func(_ context.Context, n node.Node) bool {
+			visited[n.GetHash()] = true
+			return true
+		})
+		require.NoError(err, "Visit()")
+		return visited
+	}
+	nodes0 := visit(root0)
+	cached := cache.cache.Len()
+	require.NotZero(cached, "visited nodes should be cached")
+	visit(root0)
+	require.Equal(cached, cache.cache.Len(), "repeated walks should be served from the cache")
+
+	// Modifying a returned node must not affect the cached node.
+	ptr := &node.Pointer{Clean: true, Hash: root0.Hash}
+	n, err := ndb.GetNode(root0, ptr)
+	require.NoError(err, "GetNode()")
+	n.(*node.InternalNode).Left.Node = &node.LeafNode{}
+	n, err = ndb.GetNode(root0, ptr)
+	require.NoError(err, "GetNode()")
+	require.Nil(n.(*node.InternalNode).Left.Node, "cached node should not be modified")
+
+	// Pruning should remove nodes from the cache.
+	nodes1 := visit(root1)
+	require.NoError(ndb.Prune(0), "Prune(0)")
+	for h := range nodes0 {
+		if nodes1[h] {
+			continue
+		}
+		_, ok := cache.cache.Get(h)
+		require.False(ok, "pruned nodes should be removed from the cache")
+	}
+}
+
+func BenchmarkGetNodeRootWalk(b *testing.B) {
+	for _, cacheSize := range []uint64{0, 64 * 1024 * 1024} {
+		for _, verify := range []bool{false, true} {
+			b.Run(fmt.Sprintf("NodeCacheSize=%d/VerifyNodeHashes=%t", cacheSize, verify), func(b *testing.B) {
+				require := require.New(b)
+				ctx := context.Background()
+
+				cfg := *dbCfg
+				cfg.NodeCacheSize = cacheSize
+				cfg.RootCacheVersions = 1
+				cfg.VerifyNodeHashes = verify
+				ndb, err := New(&cfg)
+				require.NoError(err, "New()")
+				defer ndb.Close()
+
+				values := make([][]byte, 0, 10_000)
+				for i := 0; i < cap(values); i++ {
+					values = append(values, []byte(fmt.Sprintf("value %d", i)))
+				}
+				root := fillDB(ctx, require, values, nil, 0, 0, ndb)
+				root.Version = 0
+				require.NoError(ndb.Finalize([]node.Root{root}), "Finalize()")
+
+				visitor := func(context.Context, node.Node) bool { return true }
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if err = api.Visit(ctx, ndb, root, visitor); err != nil {
+						b.Fatalf("Visit: %s", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkGetNode(b *testing.B) {
+	for _, rootCacheVersions := range []uint64{0, 1} {
+		b.Run(fmt.Sprintf("RootCacheVersions=%d", rootCacheVersions), func(b *testing.B) {
+			require := require.New(b)
+			ctx := context.Background()
+
+			// Disable the node cache so that every lookup hits the backing store.
+			cfg := *dbCfg
+			cfg.RootCacheVersions = rootCacheVersions
+			ndb, err := New(&cfg)
+			require.NoError(err, "New()")
+			defer ndb.Close()
+
+			root := fillDB(ctx, require, testValues, nil, 0, 0, ndb)
+			root.Version = 0
+			require.NoError(ndb.Finalize([]node.Root{root}), "Finalize()")
+			ptr := &node.Pointer{Clean: true, Hash: root.Hash}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err = ndb.GetNode(root, ptr); err != nil {
+					b.Fatalf("GetNode: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func TestTriggerGC(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "oasis-storage-database-test")
+	require.NoError(err, "TempDir()")
+	defer os.RemoveAll(dir)
+
+	cfg := *dbCfg
+	cfg.MemoryOnly = false
+	cfg.DB = dir
+
+	ndb, err := New(&cfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	gcdb := ndb.(api.GCNodeDB)
+
+	root := fillDB(ctx, require, testValues, nil, 0, 1, ndb)
+	err = ndb.Finalize([]node.Root{root})
+	require.NoError(err, "Finalize({root})")
+
+	stats, err := gcdb.TriggerGC(ctx, 0.5)
+	require.NoError(err, "TriggerGC()")
+	require.NotNil(stats, "TriggerGC() should return statistics")
+
+	for _, ratio := range []float64{0, 1, -0.5} {
+		_, err = gcdb.TriggerGC(ctx, ratio)
+		require.Error(err, "TriggerGC() should fail with discard ratio %f", ratio)
+	}
+
+	// Only one manual garbage collection may run at a time.
+	badgerdb := ndb.(*badgerNodeDB)
+	badgerdb.manualGCLock.Lock()
+	_, err = gcdb.TriggerGC(ctx, 0.5)
+	require.ErrorIs(err, api.ErrGCInProgress, "TriggerGC() should fail while another one is running")
+	badgerdb.manualGCLock.Unlock()
+
+	// Garbage collection should stop once the context is cancelled.
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = gcdb.TriggerGC(cancelledCtx, 0.5)
+	require.ErrorIs(err, context.Canceled, "TriggerGC() should fail with a cancelled context")
+
+	// Memory-only databases have no value log to collect.
+	memdb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer memdb.Close()
+	_, err = memdb.(api.GCNodeDB).TriggerGC(ctx, 0.5)
+	require.Error(err, "TriggerGC() should fail for memory-only databases")
+}
+
+func TestFinalizeRecovery(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "oasis-storage-database-test")
+	require.NoError(err, "TempDir()")
+	defer os.RemoveAll(dir)
+
+	cfg := *dbCfg
+	cfg.MemoryOnly = false
+	cfg.DB = dir
+
+	ndb, err := New(&cfg)
+	require.NoError(err, "New()")
+	badgerdb := ndb.(*badgerNodeDB)
+
+	root1 := fillDB(ctx, require, testValues, nil, 0, 1, ndb)
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+
+	// Create two competing roots in the next version.
+	updatedValues := [][]byte{[]byte("updated value")}
+	rootA := fillDB(ctx, require, updatedValues, &root1, 1, 2, ndb)
+	rootB := fillDB(ctx, require, [][]byte{[]byte("discarded value")}, &root1, 1, 2, ndb)
+
+	// Interrupt finalization after removals have been flushed.
+	errInterrupted := fmt.Errorf("interrupted")
+	badgerdb.finalizeInterruptFn = func() error {
+		return errInterrupted
+	}
+	err = ndb.Finalize([]node.Root{rootA})
+	require.ErrorIs(err, errInterrupted, "Finalize({rootA}) should be interrupted")
+
+	lastFinalizedVersion, _ := badgerdb.meta.getLastFinalizedVersion()
+	require.EqualValues(1, lastFinalizedVersion, "metadata should not be committed")
+	intent, err := badgerdb.loadFinalizeIntent()
+	require.NoError(err, "loadFinalizeIntent()")
+	require.NotNil(intent, "finalize intent should be recorded")
+	require.EqualValues(2, intent.Version)
+
+	// Retrying with a different set of roots is not safe.
+	badgerdb.finalizeInterruptFn = nil
+	err = ndb.Finalize([]node.Root{rootB})
+	require.Error(err, "Finalize({rootB}) should fail")
+
+	// Reopening the database should complete the finalization.
+	ndb.Close()
+	ndb, err = New(&cfg)
+	require.NoError(err, "New() after interruption")
+	defer ndb.Close()
+	badgerdb = ndb.(*badgerNodeDB)
+
+	lastFinalizedVersion, _ = badgerdb.meta.getLastFinalizedVersion()
+	require.EqualValues(2, lastFinalizedVersion, "finalization should be completed")
+	intent, err = badgerdb.loadFinalizeIntent()
+	require.NoError(err, "loadFinalizeIntent()")
+	require.Nil(intent, "finalize intent should be removed")
+	require.True(ndb.HasRoot(rootA), "finalized root should exist")
+	require.False(ndb.HasRoot(rootB), "discarded root should be removed")
+
+	tree := mkvs.NewWithRoot(nil, ndb, rootA)
+	defer tree.Close()
+	value, err := tree.Get(ctx, []byte("0"))
+	require.NoError(err, "Get()")
+	require.Equal(updatedValues[0], value)
+	value, err = tree.Get(ctx, []byte("1"))
+	require.NoError(err, "Get()")
+	require.Equal(testValues[1], value)
+
+	// Later versions should finalize normally.
+	root3 := fillDB(ctx, require, testValues, &rootA, 2, 3, ndb)
+	err = ndb.Finalize([]node.Root{root3})
+	require.NoError(err, "Finalize({root3})")
+}
+
+func TestRootLineage(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	ldb := ndb.(api.LineageNodeDB)
+
+	values := func(prefix string) [][]byte {
+		return [][]byte{[]byte(prefix + " 0"), []byte(prefix + " 1")}
+	}
+
+	// Build the following graph:
+	//
+	//   root1 (v1) -> rootA (v2) -> rootA2 (v2) -> root3 (v3)
+	//              -> rootB (v2)
+	root1 := fillDB(ctx, require, values("root1"), nil, 0, 1, ndb)
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+
+	rootA := fillDB(ctx, require, values("rootA"), &root1, 1, 2, ndb)
+	rootB := fillDB(ctx, require, values("rootB"), &root1, 1, 2, ndb)
+	rootA2 := fillDB(ctx, require, values("rootA2"), &rootA, 1, 2, ndb)
+	root3 := fillDB(ctx, require, values("root3"), &rootA2, 2, 3, ndb)
+
+	derived, err := ldb.GetDerivedRoots(root1)
+	require.NoError(err, "GetDerivedRoots(root1)")
+	require.ElementsMatch([]node.Root{rootA, rootB}, derived)
+
+	derived, err = ldb.GetDerivedRoots(rootA)
+	require.NoError(err, "GetDerivedRoots(rootA)")
+	require.Equal([]node.Root{rootA2}, derived, "same version derived root")
+
+	derived, err = ldb.GetDerivedRoots(root3)
+	require.NoError(err, "GetDerivedRoots(root3)")
+	require.Empty(derived)
+
+	lineage, err := ldb.GetRootLineage(root1, 0)
+	require.NoError(err, "GetRootLineage(root1)")
+	require.Len(lineage, 3)
+	require.ElementsMatch([]node.Root{rootA, rootB}, lineage[0])
+	require.Equal([]node.Root{rootA2}, lineage[1])
+	require.Equal([]node.Root{root3}, lineage[2])
+
+	lineage, err = ldb.GetRootLineage(root1, 1)
+	require.NoError(err, "GetRootLineage(root1, 1)")
+	require.Len(lineage, 1)
+
+	// Unknown roots and roots from other namespaces should be rejected.
+	bogus := rootA
+	bogus.Hash[0]++
+	_, err = ldb.GetDerivedRoots(bogus)
+	require.ErrorIs(err, api.ErrRootNotFound)
+	_, err = ldb.GetRootLineage(bogus, 0)
+	require.ErrorIs(err, api.ErrRootNotFound)
+	bogus = rootA
+	bogus.Namespace = common.NewTestNamespaceFromSeed([]byte("other ns"), 0)
+	_, err = ldb.GetDerivedRoots(bogus)
+	require.ErrorIs(err, api.ErrBadNamespace)
+
+	// Discarded roots should no longer be reported.
+	err = ndb.Finalize([]node.Root{rootA2})
+	require.NoError(err, "Finalize({rootA2})")
+
+	derived, err = ldb.GetDerivedRoots(root1)
+	require.NoError(err, "GetDerivedRoots(root1)")
+	require.Equal([]node.Root{rootA}, derived)
+	_, err = ldb.GetDerivedRoots(rootB)
+	require.ErrorIs(err, api.ErrRootNotFound)
+}
+
+func TestBatchClose(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+
+	emptyRoot := node.Root{Namespace: testNs, Type: node.RootTypeState}
+	emptyRoot.Hash.Empty()
+	leaf := &node.LeafNode{Key: []byte("key"), Value: []byte("value")}
+	leaf.UpdateHash()
+	root := node.Root{Namespace: testNs, Type: node.RootTypeState, Hash: leaf.Hash}
+
+	// Closing a batch should release its resources and be idempotent.
+	batch, err := ndb.NewBatch(emptyRoot, 0, false)
+	require.NoError(err, "NewBatch()")
+	require.EqualValues(1, badgerdb.openBatches.Load())
+	batch.Close()
+	batch.Close()
+	batch.Reset()
+	require.EqualValues(0, badgerdb.openBatches.Load(), "Close() should release batch resources")
+	err = batch.Commit(root)
+	require.ErrorIs(err, api.ErrBatchClosed, "Commit() should fail after Close()")
+
+	// Committing a batch should release its resources.
+	batch, err = ndb.NewBatch(emptyRoot, 0, false)
+	require.NoError(err, "NewBatch()")
+	err = batch.PutNode(&node.Pointer{Clean: true, Hash: leaf.Hash, Node: leaf})
+	require.NoError(err, "PutNode()")
+	err = batch.Commit(root)
+	require.NoError(err, "Commit()")
+	require.EqualValues(0, badgerdb.openBatches.Load(), "Commit() should release batch resources")
+	err = batch.Commit(root)
+	require.ErrorIs(err, api.ErrBatchClosed, "Commit() should fail after Commit()")
+	batch.Close()
+	require.True(ndb.HasRoot(root), "committed root should exist")
+
+	// Committing trees should not leak batches.
+	fillDB(ctx, require, testValues, nil, 1, 1, ndb)
+	require.EqualValues(0, badgerdb.openBatches.Load(), "tree commits should release batch resources")
+
+	// Abandoned multipart batches should eventually release their write batches and the read
+	// transaction.
+	err = ndb.StartMultipartInsert(5)
+	require.NoError(err, "StartMultipartInsert()")
+	func() {
+		batch, err := ndb.NewBatch(emptyRoot, 5, true)
+		require.NoError(err, "NewBatch()")
+		require.NotNil(batch.(*badgerBatch).readTxn, "chunk batches should hold a read transaction")
+		require.EqualValues(1, badgerdb.openBatches.Load())
+	}()
+	require.Eventually(func() bool {
+		runtime.GC()
+		return badgerdb.openBatches.Load() == 0
+	}, 10*time.Second, 10*time.Millisecond, "abandoned batches should release their resources")
+
+	err = ndb.AbortMultipartInsert()
+	require.NoError(err, "AbortMultipartInsert()")
+}
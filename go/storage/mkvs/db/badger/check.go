@@ -3,6 +3,7 @@ package badger
 import (
 	"container/list"
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/dgraph-io/badger/v4"
@@ -16,6 +17,9 @@ import (
 
 const maxLRUEntries = 3000000
 
+// ErrInconsistent is the error returned when a consistency check discovers problems.
+var ErrInconsistent = errors.New("mkvs/badger/check: database is inconsistent")
+
 type lruElement struct {
 	hash *hash.Hash
 	elem *list.Element
@@ -61,6 +65,8 @@ func (cc *checkerCommon) checkNodes(root node.Root) error {
 }
 
 func checkSanityInternal(ctx context.Context, db *badgerNodeDB, display DisplayHelper) error {
+	metaTxn := db.metaDB.NewTransactionAt(maxTimestamp, false)
+	defer metaTxn.Discard()
 	txn := db.db.NewTransactionAt(maxTimestamp, false)
 	defer txn.Discard()
 
@@ -72,7 +78,7 @@ func checkSanityInternal(ctx context.Context, db *badgerNodeDB, display DisplayH
 		itOpts.Prefix = rootsMetadataKeyFmt.Encode()
 		itOpts.Reverse = true
 
-		itR := txn.NewIterator(itOpts)
+		itR := metaTxn.NewIterator(itOpts)
 		defer itR.Close()
 
 		itR.Seek(lastRootsMetadataKey)
@@ -86,7 +92,7 @@ func checkSanityInternal(ctx context.Context, db *badgerNodeDB, display DisplayH
 		}
 
 		itOpts.Reverse = false
-		itF := txn.NewIterator(itOpts)
+		itF := metaTxn.NewIterator(itOpts)
 		defer itF.Close()
 
 		itF.Rewind()
@@ -109,7 +115,7 @@ func checkSanityInternal(ctx context.Context, db *badgerNodeDB, display DisplayH
 	itOpts := badger.DefaultIteratorOptions
 	itOpts.Reverse = true
 	itOpts.Prefix = rootsMetadataKeyFmt.Encode()
-	it := txn.NewIterator(itOpts)
+	it := metaTxn.NewIterator(itOpts)
 	defer it.Close()
 
 	display.DisplayStepBegin("checking per-version storage trees")
@@ -208,9 +214,156 @@ func checkSanityInternal(ctx context.Context, db *badgerNodeDB, display DisplayH
 
 // CheckSanity checks the sanity of the node database by traversing all stored trees.
 func CheckSanity(ctx context.Context, cfg *api.Config, display DisplayHelper) error {
+	db, err := openForCheck(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return checkSanityInternal(ctx, db, display)
+}
+
+// CheckVersion checks the consistency of the given version of the node database.
+//
+// For every root of the given version the tree is traversed to make sure that all referenced nodes
+// exist and can be unmarshalled. Root node entries must match the roots metadata and no pending
+// updated nodes entries may remain in case the version has been finalized. All discovered problems
+// are reported in the returned error which wraps ErrInconsistent.
+//
+// The database is opened in read-only mode.
+func CheckVersion(ctx context.Context, cfg *api.Config, version uint64) error {
+	db, err := openForCheck(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err = db.load(); err != nil {
+		return fmt.Errorf("mkvs/badger/check: failed to load metadata: %w", err)
+	}
+
+	problems, err := checkVersionInternal(ctx, db, version)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %w", ErrInconsistent, errors.Join(problems...))
+	}
+	return nil
+}
+
+func checkVersionInternal(ctx context.Context, db *badgerNodeDB, version uint64) ([]error, error) {
+	if version < db.meta.getEarliestVersion() {
+		return nil, fmt.Errorf("mkvs/badger/check: version %d has been pruned", version)
+	}
+
+	metaTxn := db.metaDB.NewTransactionAt(tsMetadata, false)
+	defer metaTxn.Discard()
+	txn := db.db.NewTransactionAt(versionToTs(version), false)
+	defer txn.Discard()
+
+	rootsMeta, err := loadRootsMetadata(metaTxn, version)
+	if err != nil {
+		return nil, fmt.Errorf("mkvs/badger/check: %w", err)
+	}
+
+	var problems []error
+
+	// Make sure all roots have root nodes and that all trees are complete.
+	for rootHash := range rootsMeta.Roots {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		root := node.Root{
+			Namespace: db.namespace,
+			Version:   version,
+			Type:      rootHash.Type(),
+			Hash:      rootHash.Hash(),
+		}
+		if root.Hash.IsEmpty() {
+			continue
+		}
+
+		if _, err = txn.Get(rootNodeKeyFmt.Encode(&rootHash)); err != nil {
+			problems = append(problems, fmt.Errorf("missing root node for root %s: %w", rootHash, err))
+			continue
+		}
+		if err = api.Visit(ctx, db, root, func(context.Context, node.Node) bool { return true }); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			problems = append(problems, fmt.Errorf("bad tree for root %s: %w", rootHash, err))
+		}
+	}
+
+	// Make sure all root nodes written in this version are in the roots metadata.
+	problems = append(problems, checkVersionRootNodes(txn, rootsMeta)...)
+
+	// Make sure there are no pending updated nodes for finalized versions.
+	if lastFinalizedVersion, exists := db.meta.getLastFinalizedVersion(); exists && version <= lastFinalizedVersion {
+		it := metaTxn.NewIterator(badger.IteratorOptions{Prefix: rootUpdatedNodesKeyFmt.Encode(version)})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			var (
+				v        uint64
+				rootHash api.TypedHash
+			)
+			if !rootUpdatedNodesKeyFmt.Decode(it.Item().Key(), &v, &rootHash) {
+				problems = append(problems, fmt.Errorf("undecodable root updated nodes key (%v)", it.Item().Key()))
+				continue
+			}
+			problems = append(problems, fmt.Errorf("dangling updated nodes for root %s of finalized version", rootHash))
+		}
+
+		cit := metaTxn.NewIterator(badger.IteratorOptions{Prefix: rootUpdatedNodesChunkKeyFmt.Encode(version)})
+		defer cit.Close()
+
+		for cit.Rewind(); cit.Valid(); cit.Next() {
+			var (
+				v        uint64
+				rootHash api.TypedHash
+				chunk    uint64
+			)
+			if !rootUpdatedNodesChunkKeyFmt.Decode(cit.Item().Key(), &v, &rootHash, &chunk) {
+				problems = append(problems, fmt.Errorf("undecodable root updated nodes chunk key (%v)", cit.Item().Key()))
+				continue
+			}
+			problems = append(problems, fmt.Errorf("dangling updated nodes chunk %d for root %s of finalized version", chunk, rootHash))
+		}
+	}
+
+	return problems, nil
+}
+
+func checkVersionRootNodes(txn *badger.Txn, rootsMeta *rootsMetadata) []error {
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: rootNodeKeyFmt.Encode()})
+	defer it.Close()
+
+	var problems []error
+	for it.Rewind(); it.Valid(); it.Next() {
+		if it.Item().Version() != versionToTs(rootsMeta.version) {
+			continue
+		}
+
+		var rootHash api.TypedHash
+		if !rootNodeKeyFmt.Decode(it.Item().Key(), &rootHash) {
+			problems = append(problems, fmt.Errorf("undecodable root node key (%v)", it.Item().Key()))
+			continue
+		}
+		if _, ok := rootsMeta.Roots[rootHash]; !ok {
+			problems = append(problems, fmt.Errorf("root node %s without roots metadata", rootHash))
+		}
+	}
+	return problems
+}
+
+func openForCheck(cfg *api.Config) (*badgerNodeDB, error) {
 	db := &badgerNodeDB{
-		logger:           logging.GetLogger("mkvs/db/badger/migrate"),
+		logger:           logging.GetLogger("mkvs/db/badger/check"),
 		namespace:        cfg.Namespace,
+		readOnly:         true,
 		discardWriteLogs: cfg.DiscardWriteLogs,
 	}
 	roCfg := *cfg
@@ -219,12 +372,16 @@ func CheckSanity(ctx context.Context, cfg *api.Config, display DisplayHelper) er
 
 	var err error
 	if db.db, err = badger.OpenManaged(opts); err != nil {
-		return fmt.Errorf("mkvs/badger/check: failed to open database: %w", err)
+		return nil, fmt.Errorf("mkvs/badger/check: failed to open database: %w", err)
 	}
-	defer db.Close()
 
 	// Make sure that we can discard any deleted/invalid metadata.
 	db.db.SetDiscardTs(tsMetadata)
 
-	return checkSanityInternal(ctx, db, display)
+	if err = db.openMetadataStore(&roCfg, opts, isSplitMetadata(cfg)); err != nil {
+		_ = db.db.Close()
+		return nil, fmt.Errorf("mkvs/badger/check: %w", err)
+	}
+
+	return db, nil
 }
@@ -0,0 +1,78 @@
+package badger
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+func TestCheckVersion(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "mkvs.test.badger.check")
+	require.NoError(err, "TempDir")
+	defer os.RemoveAll(dir)
+
+	cfg := &api.Config{
+		DB:           dir,
+		Namespace:    testNs,
+		MaxCacheSize: 16 * 1024 * 1024,
+		NoFsync:      true,
+	}
+
+	ndb, err := New(cfg)
+	require.NoError(err, "New()")
+	root1 := fillDB(ctx, require, testValues, nil, 1, 2, ndb)
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+	root2 := fillDB(ctx, require, [][]byte{[]byte("finalized")}, &root1, 2, 3, ndb)
+	_ = fillDB(ctx, require, [][]byte{[]byte("discarded")}, &root1, 2, 3, ndb)
+	err = ndb.Finalize([]node.Root{root2})
+	require.NoError(err, "Finalize({root2})")
+	ndb.Close()
+
+	err = CheckVersion(ctx, cfg, 2)
+	require.NoError(err, "CheckVersion(2)")
+	err = CheckVersion(ctx, cfg, 3)
+	require.NoError(err, "CheckVersion(3)")
+
+	// Corrupt the database.
+	ndb, err = New(cfg)
+	require.NoError(err, "New()")
+	bdb := ndb.(*badgerNodeDB)
+
+	bogusRoot := api.TypedHashFromParts(node.RootTypeState, hash.NewFromBytes([]byte("bogus")))
+	batch := bdb.db.NewWriteBatchAt(versionToTs(3))
+	err = batch.Delete(nodeKeyFmt.Encode(&root2.Hash))
+	require.NoError(err, "Delete(root node)")
+	err = batch.Set(rootNodeKeyFmt.Encode(&bogusRoot), []byte{})
+	require.NoError(err, "Set(bogus root node)")
+	err = batch.Flush()
+	require.NoError(err, "Flush()")
+
+	tx := bdb.db.NewTransactionAt(tsMetadata, true)
+	err = tx.Set(rootUpdatedNodesKeyFmt.Encode(uint64(3), &bogusRoot), cbor.Marshal([]updatedNode{}))
+	require.NoError(err, "Set(bogus updated nodes)")
+	err = tx.CommitAt(tsMetadata, nil)
+	require.NoError(err, "CommitAt()")
+	tx.Discard()
+	ndb.Close()
+
+	// All problems should be reported.
+	err = CheckVersion(ctx, cfg, 3)
+	require.ErrorIs(err, ErrInconsistent)
+	require.ErrorContains(err, "bad tree for root")
+	require.ErrorContains(err, "without roots metadata")
+	require.ErrorContains(err, "dangling updated nodes")
+
+	err = CheckVersion(ctx, cfg, 2)
+	require.NoError(err, "CheckVersion(2) should not be affected")
+}
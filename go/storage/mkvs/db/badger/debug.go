@@ -0,0 +1,204 @@
+package badger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/keyformat"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// ErrDebugIterateDisabled is the error returned by DebugIterate when raw key/value iteration has
+// not been enabled via BadgerOptions.DebugIterate.
+var ErrDebugIterateDisabled = errors.New("mkvs/badger: debug iteration is disabled")
+
+// KeyspacePrefix is the prefix of a keyspace that can be iterated via DebugIterate.
+type KeyspacePrefix byte
+
+// Keyspaces that can be iterated via DebugIterate. The values must match the prefixes of the
+// corresponding key formats.
+const (
+	// KeyspaceNodes is the keyspace of nodes.
+	KeyspaceNodes KeyspacePrefix = 0x00
+	// KeyspaceWriteLogs is the keyspace of write logs.
+	KeyspaceWriteLogs KeyspacePrefix = 0x01
+	// KeyspaceRootsMetadata is the keyspace of roots metadata.
+	KeyspaceRootsMetadata KeyspacePrefix = 0x02
+	// KeyspaceMultipartLog is the keyspace of the multipart restore node log.
+	KeyspaceMultipartLog KeyspacePrefix = 0x05
+	// KeyspaceRootNodes is the keyspace of root nodes.
+	KeyspaceRootNodes KeyspacePrefix = 0x06
+)
+
+// debugKeyspace describes a keyspace that can be iterated via DebugIterate.
+type debugKeyspace struct {
+	name   string
+	keyFmt *keyformat.KeyFormat
+	// metadata is true iff the keyspace is stored at the metadata timestamp instead of at the
+	// version timestamps.
+	metadata bool
+	// format decodes the given key into a human-readable form.
+	format func(key []byte) (string, bool)
+}
+
+var debugKeyspaces = map[KeyspacePrefix]*debugKeyspace{
+	KeyspaceNodes: {
+		name:   "nodes",
+		keyFmt: nodeKeyFmt,
+		format: func(key []byte) (string, bool) {
+			var h hash.Hash
+			if !nodeKeyFmt.Decode(key, &h) {
+				return "", false
+			}
+			return fmt.Sprintf("node(hash=%s)", h), true
+		},
+	},
+	KeyspaceWriteLogs: {
+		name:   "write_logs",
+		keyFmt: writeLogKeyFmt,
+		format: func(key []byte) (string, bool) {
+			var (
+				version          uint64
+				newRoot, oldRoot api.TypedHash
+			)
+			if !writeLogKeyFmt.Decode(key, &version, &newRoot, &oldRoot) {
+				return "", false
+			}
+			return fmt.Sprintf("write_log(version=%d new_root=%s old_root=%s)", version, newRoot, oldRoot), true
+		},
+	},
+	KeyspaceRootsMetadata: {
+		name:     "roots_metadata",
+		keyFmt:   rootsMetadataKeyFmt,
+		metadata: true,
+		format: func(key []byte) (string, bool) {
+			var version uint64
+			if !rootsMetadataKeyFmt.Decode(key, &version) {
+				return "", false
+			}
+			return fmt.Sprintf("roots_metadata(version=%d)", version), true
+		},
+	},
+	KeyspaceMultipartLog: {
+		name:     "multipart_log",
+		keyFmt:   multipartRestoreNodeLogKeyFmt,
+		metadata: true,
+		format: func(key []byte) (string, bool) {
+			var h api.TypedHash
+			if !multipartRestoreNodeLogKeyFmt.Decode(key, &h) {
+				return "", false
+			}
+			return fmt.Sprintf("multipart_log(hash=%s)", h), true
+		},
+	},
+	KeyspaceRootNodes: {
+		name:   "root_nodes",
+		keyFmt: rootNodeKeyFmt,
+		format: func(key []byte) (string, bool) {
+			var h api.TypedHash
+			if !rootNodeKeyFmt.Decode(key, &h) {
+				return "", false
+			}
+			return fmt.Sprintf("root_node(hash=%s)", h), true
+		},
+	},
+}
+
+// String returns the name of the keyspace.
+func (p KeyspacePrefix) String() string {
+	if ks, ok := debugKeyspaces[p]; ok {
+		return ks.name
+	}
+	return fmt.Sprintf("[unknown keyspace: 0x%02x]", byte(p))
+}
+
+// UnmarshalText decodes a text-serialized keyspace name.
+func (p *KeyspacePrefix) UnmarshalText(text []byte) error {
+	for prefix, ks := range debugKeyspaces {
+		if ks.name == string(text) {
+			*p = prefix
+			return nil
+		}
+	}
+	return fmt.Errorf("mkvs/badger: unknown keyspace: %s", string(text))
+}
+
+// KeyspaceNames returns the names of all keyspaces that can be iterated via DebugIterate.
+func KeyspaceNames() []string {
+	names := make([]string, 0, len(debugKeyspaces))
+	for _, ks := range debugKeyspaces {
+		names = append(names, ks.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatKey decodes the given raw key of the given keyspace into a human-readable form using the
+// keyspace's key format. Keys that cannot be decoded are returned in hex.
+func (p KeyspacePrefix) FormatKey(key []byte) string {
+	if ks, ok := debugKeyspaces[p]; ok {
+		if s, ok := ks.format(key); ok {
+			return s
+		}
+	}
+	return fmt.Sprintf("[malformed key: %x]", key)
+}
+
+// DebugIterator is a node database that supports raw key/value iteration for debugging.
+type DebugIterator interface {
+	// DebugIterate iterates over all keys in the given keyspace as visible at the given version
+	// and invokes the callback with copies of each key and value. Iteration stops when the
+	// callback returns false.
+	//
+	// The roots metadata and multipart log keyspaces are not versioned, so the version is ignored
+	// for them and their latest state is iterated.
+	DebugIterate(ctx context.Context, version uint64, prefix KeyspacePrefix, fn func(key, value []byte) bool) error
+}
+
+// DebugIterate implements DebugIterator.
+func (d *badgerNodeDB) DebugIterate(ctx context.Context, version uint64, prefix KeyspacePrefix, fn func(key, value []byte) bool) error {
+	if !d.debugIterate {
+		return ErrDebugIterateDisabled
+	}
+	ks, ok := debugKeyspaces[prefix]
+	if !ok {
+		return fmt.Errorf("mkvs/badger: unknown keyspace: 0x%02x", byte(prefix))
+	}
+
+	var tx *badger.Txn
+	if ks.metadata {
+		tx = d.metaDB.NewTransactionAt(tsMetadata, false)
+	} else {
+		if version < d.meta.getEarliestVersion() {
+			return api.ErrVersionNotFound
+		}
+		tx = d.db.NewTransactionAt(versionToTs(version), false)
+	}
+	defer tx.Discard()
+
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = ks.keyFmt.Encode()
+	it := tx.NewIterator(opts)
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		item := it.Item()
+		value, err := item.ValueCopy(nil)
+		if err != nil {
+			return fmt.Errorf("mkvs/badger: failed to read value: %w", err)
+		}
+		if !fn(item.KeyCopy(nil), value) {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package badger
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+func TestDebugIterate(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	for prefix, ks := range debugKeyspaces {
+		require.Equal(byte(prefix), ks.keyFmt.Prefix(), "keyspace %s should match its key format", ks.name)
+
+		var decoded KeyspacePrefix
+		require.NoError(decoded.UnmarshalText([]byte(prefix.String())), "UnmarshalText(%s)", prefix)
+		require.Equal(prefix, decoded)
+	}
+	var decoded KeyspacePrefix
+	require.Error(decoded.UnmarshalText([]byte("metadata")), "unsupported keyspaces should be rejected")
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	err = ndb.(DebugIterator).DebugIterate(ctx, 0, KeyspaceNodes, func([]byte, []byte) bool { return true })
+	require.ErrorIs(err, ErrDebugIterateDisabled, "DebugIterate() should be disabled by default")
+	ndb.Close()
+
+	cfg := *dbCfg
+	cfg.BadgerOptions = &api.BadgerOptions{DebugIterate: true}
+	ndb, err = New(&cfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	di := ndb.(DebugIterator)
+
+	root1 := fillDB(ctx, require, testValues, nil, 1, 2, ndb)
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+	root2 := fillDB(ctx, require, [][]byte{[]byte("updated")}, &root1, 2, 3, ndb)
+	err = ndb.Finalize([]node.Root{root2})
+	require.NoError(err, "Finalize({root2})")
+
+	type entry struct {
+		key   string
+		value []byte
+	}
+	iterate := func(version uint64, prefix KeyspacePrefix) []entry {
+		var entries []entry
+		err := di.DebugIterate(ctx, version, prefix, func(key, value []byte) bool {
+			entries = append(entries, entry{prefix.FormatKey(key), value})
+			return true
+		})
+		require.NoError(err, "DebugIterate(%d, %s)", version, prefix)
+		return entries
+	}
+
+	// Root nodes are versioned.
+	rootNodes := iterate(2, KeyspaceRootNodes)
+	require.Len(rootNodes, 1)
+	require.Equal("root_node(hash="+api.TypedHashFromRoot(root1).String()+")", rootNodes[0].key)
+	require.Len(iterate(3, KeyspaceRootNodes), 2)
+
+	// Nodes written by later versions should not be visible.
+	nodes2 := iterate(2, KeyspaceNodes)
+	require.NotEmpty(nodes2)
+	require.Greater(len(iterate(3, KeyspaceNodes)), len(nodes2))
+	for _, e := range nodes2 {
+		require.True(strings.HasPrefix(e.key, "node(hash="), "node key %s", e.key)
+		n, err := node.UnmarshalBinary(e.value)
+		require.NoError(err, "UnmarshalBinary()")
+		require.Equal(e.key, "node(hash="+n.GetHash().String()+")")
+	}
+
+	writeLogs := iterate(3, KeyspaceWriteLogs)
+	require.Len(writeLogs, 2)
+	require.Contains(writeLogs[1].key, "version=3")
+
+	// Roots metadata is not versioned.
+	rootsMeta := iterate(0, KeyspaceRootsMetadata)
+	require.GreaterOrEqual(len(rootsMeta), 2)
+	require.Empty(iterate(0, KeyspaceMultipartLog))
+
+	// Iteration should stop once the callback returns false.
+	var visited int
+	err = di.DebugIterate(ctx, 3, KeyspaceNodes, func([]byte, []byte) bool {
+		visited++
+		return false
+	})
+	require.NoError(err, "DebugIterate()")
+	require.Equal(1, visited)
+
+	// Callbacks should get copies.
+	var keys [][]byte
+	err = di.DebugIterate(ctx, 3, KeyspaceNodes, func(key, _ []byte) bool {
+		keys = append(keys, key)
+		return true
+	})
+	require.NoError(err, "DebugIterate()")
+	for i := 1; i < len(keys); i++ {
+		require.NotEqual(keys[i-1], keys[i], "keys should not be reused")
+	}
+
+	require.Equal("[malformed key: 00]", KeyspaceNodes.FormatKey([]byte{0x00}))
+	err = di.DebugIterate(ctx, 3, KeyspacePrefix(0x04), func([]byte, []byte) bool { return true })
+	require.Error(err, "unknown keyspaces should be rejected")
+}
@@ -0,0 +1,58 @@
+package badger
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"syscall"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/y"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// corruptionMessages are fragments of error messages that badger reports for corrupted manifests,
+// tables and value logs without using a sentinel error.
+var corruptionMessages = []string{
+	"bad magic",
+	"checksum mismatch",
+	"unsupported version",
+	"corrupt",
+}
+
+// classifyError maps a badger-internal error to a node database error category.
+func classifyError(err error) api.ErrorCategory {
+	if category := api.ErrorCategoryOf(err); category != api.ErrorCategoryUnknown {
+		return category
+	}
+
+	var errno syscall.Errno
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, badger.ErrConflict):
+		return api.ErrorCategoryConflict
+	case errors.Is(err, badger.ErrTxnTooBig),
+		errors.Is(err, syscall.ENOSPC),
+		errors.Is(err, syscall.EDQUOT),
+		errors.Is(err, syscall.ENOMEM),
+		errors.Is(err, syscall.EMFILE),
+		errors.Is(err, syscall.ENFILE):
+		return api.ErrorCategoryResourceExhausted
+	case errors.Is(err, y.ErrChecksumMismatch),
+		errors.Is(err, badger.ErrTruncateNeeded),
+		errors.Is(err, node.ErrMalformedNode):
+		return api.ErrorCategoryCorruption
+	case errors.As(err, &errno), errors.As(err, &pathErr):
+		return api.ErrorCategoryIO
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range corruptionMessages {
+		if strings.Contains(msg, fragment) {
+			return api.ErrorCategoryCorruption
+		}
+	}
+	return api.ErrorCategoryUnknown
+}
@@ -0,0 +1,148 @@
+package badger
+
+import (
+	"fmt"
+	"io/fs"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/y"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// faultyTxn is a transaction that fails all lookups with the given error.
+type faultyTxn struct {
+	err error
+}
+
+func (t *faultyTxn) Get([]byte) (*badger.Item, error) {
+	return nil, t.err
+}
+
+func TestClassifyError(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		category api.ErrorCategory
+	}{
+		{y.ErrChecksumMismatch, api.ErrorCategoryCorruption},
+		{fmt.Errorf("while reading block: %w", y.ErrChecksumMismatch), api.ErrorCategoryCorruption},
+		{badger.ErrTruncateNeeded, api.ErrorCategoryCorruption},
+		{fmt.Errorf("manifest has bad magic"), api.ErrorCategoryCorruption},
+		{fmt.Errorf("%w: bad roots metadata", api.ErrCorruptedDB), api.ErrorCategoryCorruption},
+		{node.ErrMalformedNode, api.ErrorCategoryCorruption},
+		{badger.ErrTxnTooBig, api.ErrorCategoryResourceExhausted},
+		{&fs.PathError{Op: "write", Path: "000001.vlog", Err: syscall.ENOSPC}, api.ErrorCategoryResourceExhausted},
+		{syscall.EMFILE, api.ErrorCategoryResourceExhausted},
+		{badger.ErrConflict, api.ErrorCategoryConflict},
+		{&fs.PathError{Op: "read", Path: "000001.sst", Err: syscall.EIO}, api.ErrorCategoryIO},
+		{syscall.EIO, api.ErrorCategoryIO},
+		{badger.ErrDBClosed, api.ErrorCategoryUnknown},
+	} {
+		require.Equal(t, tc.category, classifyError(tc.err), "classifyError(%v)", tc.err)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	require := require.New(t)
+
+	cfg := *dbCfg
+	cfg.MetricsEnabled = true
+	cfg.CorruptionErrorThreshold = 2
+	cfg.CorruptionErrorWindow = time.Minute
+	ndb, err := New(&cfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+	hdb := ndb.(api.HealthNodeDB)
+
+	now := time.Now()
+	badgerdb.corruption.now = func() time.Time { return now }
+
+	root := node.Root{
+		Namespace: testNs,
+		Version:   1,
+		Type:      node.RootTypeState,
+		Hash:      hash.NewFromBytes([]byte("root")),
+	}
+	checkRoot := func(injected error) error {
+		return badgerdb.checkRoot(&faultyTxn{injected}, root)
+	}
+	categoryCount := func(category api.ErrorCategory) float64 {
+		return testutil.ToFloat64(errorCategoryCount.With(badgerdb.metrics.labelsWith("category", string(category))))
+	}
+
+	require.True(hdb.Health().Healthy, "database should initially be healthy")
+
+	// Injected errors should be classified and counted.
+	conflicts := categoryCount(api.ErrorCategoryConflict)
+	err = checkRoot(badger.ErrConflict)
+	require.ErrorIs(err, badger.ErrConflict)
+	require.Equal(api.ErrorCategoryConflict, api.ErrorCategoryOf(err))
+	require.NotErrorIs(err, api.ErrCorruptedDB)
+	require.Equal(conflicts+1, categoryCount(api.ErrorCategoryConflict))
+
+	exhausted := categoryCount(api.ErrorCategoryResourceExhausted)
+	err = checkRoot(&fs.PathError{Op: "write", Path: "000001.vlog", Err: syscall.ENOSPC})
+	require.Equal(api.ErrorCategoryResourceExhausted, api.ErrorCategoryOf(err))
+	require.Equal(exhausted+1, categoryCount(api.ErrorCategoryResourceExhausted))
+	require.True(hdb.Health().Healthy, "non-corruption errors should not affect health")
+
+	// Corruption should make the database unhealthy once the threshold is reached.
+	corruptions := categoryCount(api.ErrorCategoryCorruption)
+	err = checkRoot(y.ErrChecksumMismatch)
+	require.ErrorIs(err, y.ErrChecksumMismatch)
+	require.ErrorIs(err, api.ErrCorruptedDB, "corruption-class errors should match ErrCorruptedDB")
+	require.Equal(corruptions+1, categoryCount(api.ErrorCategoryCorruption))
+	health := hdb.Health()
+	require.True(health.Healthy, "database should be healthy below the threshold")
+	require.EqualValues(1, health.CorruptionErrors)
+
+	now = now.Add(30 * time.Second)
+	_ = checkRoot(badger.ErrTruncateNeeded)
+	health = hdb.Health()
+	require.False(health.Healthy, "database should be unhealthy once the threshold is reached")
+	require.EqualValues(2, health.CorruptionErrors)
+	require.Contains(health.LastCorruptionError, badger.ErrTruncateNeeded.Error())
+
+	// Errors outside the window should no longer count.
+	now = now.Add(45 * time.Second)
+	health = hdb.Health()
+	require.True(health.Healthy, "database should recover once errors fall outside the window")
+	require.EqualValues(1, health.CorruptionErrors)
+
+	// Lookups that did not fail should not be counted.
+	require.ErrorIs(checkRoot(badger.ErrKeyNotFound), api.ErrRootNotFound)
+	require.Equal(corruptions+2, categoryCount(api.ErrorCategoryCorruption))
+}
+
+func TestErrorClassificationDisabled(t *testing.T) {
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+
+	root := node.Root{
+		Namespace: testNs,
+		Version:   1,
+		Type:      node.RootTypeState,
+		Hash:      hash.NewFromBytes([]byte("root")),
+	}
+	for range 10 {
+		err = badgerdb.checkRoot(&faultyTxn{y.ErrChecksumMismatch}, root)
+		require.ErrorIs(err, api.ErrCorruptedDB)
+	}
+
+	health := badgerdb.Health()
+	require.True(health.Healthy, "database should always be healthy without a threshold")
+	require.Zero(health.CorruptionErrors)
+	require.NotEmpty(health.LastCorruptionError)
+}
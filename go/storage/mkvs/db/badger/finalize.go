@@ -0,0 +1,138 @@
+package badger
+
+import (
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// finalizeIntent is the record of a finalization that has started removing data but has not yet
+// committed its metadata.
+//
+// NOTE: Public fields of this structure are part of the on-disk format.
+type finalizeIntent struct {
+	_ struct{} `cbor:",toarray"` // nolint
+
+	// Version is the version being finalized.
+	Version uint64
+	// Roots is the full set of finalized roots, including any roots finalized transitively.
+	Roots []api.TypedHash
+}
+
+// loadFinalizeIntent loads the finalize intent, returning nil if there is none.
+func (d *badgerNodeDB) loadFinalizeIntent() (*finalizeIntent, error) {
+	tx := d.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	item, err := tx.Get(finalizeIntentKeyFmt.Encode())
+	switch err {
+	case nil:
+	case badger.ErrKeyNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("mkvs/badger: failed to read finalize intent: %w", err)
+	}
+
+	var intent finalizeIntent
+	if err = item.Value(func(data []byte) error {
+		return cbor.UnmarshalTrusted(data, &intent)
+	}); err != nil {
+		return nil, fmt.Errorf("mkvs/badger: corrupted finalize intent: %w", err)
+	}
+	return &intent, nil
+}
+
+// putFinalizeIntent durably records the intent to finalize the given set of roots. In case an
+// earlier finalization of the same version has been interrupted, it makes sure that the set of
+// finalized roots is the same as otherwise data of the now finalized roots may already be gone.
+//
+// Assumes metaUpdateLock is held when called.
+func (d *badgerNodeDB) putFinalizeIntent(version uint64, finalizedRoots map[api.TypedHash]bool) error {
+	prev, err := d.loadFinalizeIntent()
+	if err != nil {
+		return err
+	}
+	if prev != nil && prev.Version == version {
+		if len(prev.Roots) != len(finalizedRoots) {
+			return fmt.Errorf("mkvs/badger: finalized roots differ from interrupted finalization")
+		}
+		for _, root := range prev.Roots {
+			if !finalizedRoots[root] {
+				return fmt.Errorf("mkvs/badger: finalized roots differ from interrupted finalization")
+			}
+		}
+	}
+
+	intent := finalizeIntent{Version: version}
+	for root := range finalizedRoots {
+		intent.Roots = append(intent.Roots, root)
+	}
+
+	tx := d.metaDB.NewTransactionAt(tsMetadata, true)
+	defer tx.Discard()
+
+	if err = tx.Set(finalizeIntentKeyFmt.Encode(), cbor.Marshal(&intent)); err != nil {
+		return err
+	}
+	if err = tx.CommitAt(tsMetadata, nil); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to commit finalize intent: %w", err)
+	}
+	return d.syncMetadata()
+}
+
+// recoverFinalize completes a finalization that has been interrupted after it started removing
+// data but before its metadata was committed. Finalization is idempotent up to the metadata
+// commit, so it is simply repeated with the recorded set of roots.
+func (d *badgerNodeDB) recoverFinalize() error {
+	intent, err := d.loadFinalizeIntent()
+	if err != nil || intent == nil {
+		return err
+	}
+
+	lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion()
+	if exists && intent.Version <= lastFinalizedVersion {
+		// Stale intent, the metadata has already been committed.
+		if d.readOnly {
+			return nil
+		}
+		tx := d.metaDB.NewTransactionAt(tsMetadata, true)
+		defer tx.Discard()
+
+		if err = tx.Delete(finalizeIntentKeyFmt.Encode()); err != nil {
+			return err
+		}
+		return tx.CommitAt(tsMetadata, nil)
+	}
+
+	if d.readOnly {
+		d.logger.Warn("database has an interrupted finalization, open it read-write to complete it",
+			"version", intent.Version,
+		)
+		return nil
+	}
+
+	d.logger.Warn("resuming interrupted finalization",
+		"version", intent.Version,
+		"roots", intent.Roots,
+	)
+
+	// Finalization of a multipart restore also needs to clean up the restore metadata.
+	if d.meta.getMultipartVersion() == intent.Version {
+		d.multipartVersion = intent.Version
+	}
+
+	roots := make([]node.Root, 0, len(intent.Roots))
+	for _, th := range intent.Roots {
+		roots = append(roots, node.Root{
+			Namespace: d.namespace,
+			Version:   intent.Version,
+			Type:      th.Type(),
+			Hash:      th.Hash(),
+		})
+	}
+	return d.Finalize(roots)
+}
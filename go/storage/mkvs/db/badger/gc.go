@@ -0,0 +1,75 @@
+package badger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// gcRejectedRetryInterval is the interval after which a value log garbage collection that was
+// rejected due to the background garbage collection running concurrently is retried.
+const gcRejectedRetryInterval = time.Second
+
+var _ api.GCNodeDB = (*badgerNodeDB)(nil)
+
+// Implements api.GCNodeDB.
+func (d *badgerNodeDB) TriggerGC(ctx context.Context, discardRatio float64) (*api.GCStats, error) {
+	if discardRatio <= 0 || discardRatio >= 1 {
+		return nil, fmt.Errorf("mkvs/badger: invalid discard ratio: %f", discardRatio)
+	}
+	if d.readOnly {
+		return nil, api.ErrReadOnly
+	}
+	if d.memoryOnly {
+		return nil, fmt.Errorf("mkvs/badger: garbage collection not supported in memory-only mode")
+	}
+	if !d.manualGCLock.TryLock() {
+		return nil, api.ErrGCInProgress
+	}
+	defer d.manualGCLock.Unlock()
+
+	valueLogFileSize := d.db.Opts().ValueLogFileSize
+
+	d.logger.Info("starting manual value log garbage collection",
+		"discard_ratio", discardRatio,
+	)
+
+	var stats api.GCStats
+	for {
+		if err := ctx.Err(); err != nil {
+			return &stats, err
+		}
+
+		err := d.db.RunValueLogGC(discardRatio)
+		switch {
+		case err == nil:
+			// At least the given ratio of the rewritten file could be discarded.
+			stats.FilesRewritten++
+			stats.ReclaimedBytesEstimate += uint64(discardRatio * float64(valueLogFileSize))
+		case errors.Is(err, badger.ErrNoRewrite):
+			d.logger.Info("manual value log garbage collection finished",
+				"files_rewritten", stats.FilesRewritten,
+				"reclaimed_bytes_estimate", stats.ReclaimedBytesEstimate,
+			)
+			return &stats, nil
+		case errors.Is(err, badger.ErrRejected):
+			if d.db.IsClosed() {
+				return &stats, fmt.Errorf("mkvs/badger: database closed")
+			}
+
+			// The background garbage collection is running, wait for it to finish.
+			select {
+			case <-time.After(gcRejectedRetryInterval):
+			case <-ctx.Done():
+				return &stats, ctx.Err()
+			}
+		default:
+			return &stats, fmt.Errorf("mkvs/badger: value log garbage collection failed: %w", err)
+		}
+	}
+}
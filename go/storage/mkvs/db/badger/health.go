@@ -0,0 +1,125 @@
+package badger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+var _ api.HealthNodeDB = (*badgerNodeDB)(nil)
+
+// Implements api.HealthNodeDB.
+func (d *badgerNodeDB) LastError() error {
+	d.lastErrorLock.RLock()
+	defer d.lastErrorLock.RUnlock()
+
+	return d.lastError
+}
+
+// Implements api.HealthNodeDB.
+func (d *badgerNodeDB) Health() *api.Health {
+	return d.corruption.health()
+}
+
+// setLastError records the outcome of an operation that cannot report errors to the caller.
+func (d *badgerNodeDB) setLastError(err error) {
+	if err != nil {
+		d.logger.Error("node database operation failed",
+			"err", err,
+		)
+	}
+
+	d.lastErrorLock.Lock()
+	defer d.lastErrorLock.Unlock()
+
+	d.lastError = err
+}
+
+// failure classifies an unexpected error encountered during the given operation, records it in
+// the metrics and the health of the database and returns the error wrapped with its category.
+func (d *badgerNodeDB) failure(op string, err error) error {
+	category := classifyError(err)
+	d.metrics.failure(op, category)
+	if category == api.ErrorCategoryCorruption {
+		d.corruption.record(err)
+	}
+	return api.NewCategorizedError(category, err)
+}
+
+// corruptionTracker tracks the rate of corruption-class errors in order to determine the health
+// of the database.
+type corruptionTracker struct {
+	sync.Mutex
+
+	// threshold is the number of errors within the window after which the database is unhealthy.
+	// If zero, the database is always considered healthy and error times are not tracked.
+	threshold uint64
+	// window is the window over which errors are counted.
+	window time.Duration
+	// now returns the current time. It is only overridden in tests.
+	now func() time.Time
+
+	// times are the times of the errors within the current window, oldest first.
+	times []time.Time
+	// lastErr is the last recorded error.
+	lastErr error
+}
+
+func newCorruptionTracker(cfg *api.Config) *corruptionTracker {
+	window := cfg.CorruptionErrorWindow
+	if window == 0 {
+		window = api.DefaultCorruptionErrorWindow
+	}
+
+	return &corruptionTracker{
+		threshold: cfg.CorruptionErrorThreshold,
+		window:    window,
+		now:       time.Now,
+	}
+}
+
+// record records a corruption-class error.
+func (t *corruptionTracker) record(err error) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.lastErr = err
+	if t.threshold == 0 {
+		return
+	}
+
+	now := t.now()
+	t.expireLocked(now)
+	// Only the errors needed to reach the threshold are retained.
+	if uint64(len(t.times)) >= t.threshold {
+		t.times = t.times[1:]
+	}
+	t.times = append(t.times, now)
+}
+
+// health returns the health of the database based on the recorded errors.
+func (t *corruptionTracker) health() *api.Health {
+	t.Lock()
+	defer t.Unlock()
+
+	t.expireLocked(t.now())
+
+	h := &api.Health{
+		Healthy:          t.threshold == 0 || uint64(len(t.times)) < t.threshold,
+		CorruptionErrors: uint64(len(t.times)),
+	}
+	if t.lastErr != nil {
+		h.LastCorruptionError = t.lastErr.Error()
+	}
+	return h
+}
+
+// expireLocked removes the errors that fall outside the window ending at the given time.
+func (t *corruptionTracker) expireLocked(now time.Time) {
+	var i int
+	for i < len(t.times) && now.Sub(t.times[i]) >= t.window {
+		i++
+	}
+	t.times = t.times[i:]
+}
@@ -45,5 +45,34 @@ func commonConfigToBadgerOptions(cfg *api.Config, db *badgerNodeDB) badger.Optio
 		opts = opts.WithInMemory(true).WithDir("").WithValueDir("")
 	}
 
+	return applyBadgerOptions(opts, cfg.BadgerOptions)
+}
+
+// applyBadgerOptions applies the configured tuning options on top of the given badger options.
+func applyBadgerOptions(opts badger.Options, bo *api.BadgerOptions) badger.Options {
+	if bo == nil {
+		return opts
+	}
+	if bo.MemTableSize > 0 {
+		opts = opts.WithMemTableSize(bo.MemTableSize)
+	}
+	if bo.BaseTableSize > 0 {
+		opts = opts.WithBaseTableSize(bo.BaseTableSize)
+	}
+	if bo.BlockCacheSize > 0 {
+		opts = opts.WithBlockCacheSize(bo.BlockCacheSize)
+	}
+	if bo.NumCompactors > 0 {
+		opts = opts.WithNumCompactors(bo.NumCompactors)
+	}
+	if bo.CompactL0OnClose != nil {
+		opts = opts.WithCompactL0OnClose(*bo.CompactL0OnClose)
+	}
+	if bo.ValueThreshold > 0 {
+		opts = opts.WithValueThreshold(bo.ValueThreshold)
+	}
+	if bo.SyncWrites != nil {
+		opts = opts.WithSyncWrites(*bo.SyncWrites)
+	}
 	return opts
 }
@@ -0,0 +1,71 @@
+package badger
+
+import (
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// Keys on the hot paths (node and root node lookups) are encoded by appending into caller-provided
+// buffers instead of via the key formats, which allocate a fresh key (and an intermediate copy of
+// each element) on every call. The encodings must be identical to the ones of the key formats.
+var (
+	// nodeKeyPrefix is the encoded prefix of nodeKeyFmt.
+	nodeKeyPrefix = nodeKeyFmt.Encode()
+	// nodeKeySize is the size of an encoded nodeKeyFmt key.
+	nodeKeySize = len(nodeKeyPrefix) + hash.Size
+
+	// rootNodeKeyPrefix is the encoded prefix of rootNodeKeyFmt.
+	rootNodeKeyPrefix = rootNodeKeyFmt.Encode()
+	// rootNodeKeySize is the size of an encoded rootNodeKeyFmt key.
+	rootNodeKeySize = len(rootNodeKeyPrefix) + api.TypedHashSize
+)
+
+// appendNodeKey appends the nodeKeyFmt key of the given node hash to dst.
+func appendNodeKey(dst []byte, h *hash.Hash) []byte {
+	dst = append(dst, nodeKeyPrefix...)
+	return append(dst, h[:]...)
+}
+
+// appendRootNodeKey appends the rootNodeKeyFmt key of the given root hash to dst.
+func appendRootNodeKey(dst []byte, h *api.TypedHash) []byte {
+	dst = append(dst, rootNodeKeyPrefix...)
+	return append(dst, h[:]...)
+}
+
+// newNodeKey returns the nodeKeyFmt key of the given node hash in a freshly allocated slice, which
+// is required when the key is retained by badger (e.g., when set in a write batch).
+func newNodeKey(h *hash.Hash) []byte {
+	return appendNodeKey(make([]byte, 0, nodeKeySize), h)
+}
+
+// newRootNodeKey returns the rootNodeKeyFmt key of the given root hash in a freshly allocated
+// slice, which is required when the key is retained by badger (e.g., when set in a write batch).
+func newRootNodeKey(h *api.TypedHash) []byte {
+	return appendRootNodeKey(make([]byte, 0, rootNodeKeySize), h)
+}
+
+// keyBufferPool is a pool of scratch buffers for encoding keys that are only used for the
+// duration of a lookup and are not retained by badger afterwards.
+var keyBufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, max(nodeKeySize, rootNodeKeySize))
+		return &buf
+	},
+}
+
+// getKeyBuffer returns an empty scratch key buffer from the pool.
+//
+// The buffer must be returned via putKeyBuffer once neither the key nor any badger item obtained
+// by looking it up is used anymore.
+func getKeyBuffer() *[]byte {
+	buf := keyBufferPool.Get().(*[]byte)
+	*buf = (*buf)[:0]
+	return buf
+}
+
+// putKeyBuffer returns a scratch key buffer to the pool.
+func putKeyBuffer(buf *[]byte) {
+	keyBufferPool.Put(buf)
+}
@@ -0,0 +1,86 @@
+package badger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+func FuzzNodeKey(f *testing.F) {
+	// Seed corpus.
+	h := hash.NewFromBytes([]byte("node"))
+	f.Add(make([]byte, hash.Size), []byte{})
+	f.Add(h[:], []byte("dst"))
+
+	// Fuzzing.
+	f.Fuzz(func(t *testing.T, data []byte, dst []byte) {
+		var h hash.Hash
+		if err := h.UnmarshalBinary(data); err != nil {
+			return
+		}
+
+		expected := nodeKeyFmt.Encode(&h)
+		require.Equal(t, expected, newNodeKey(&h), "newNodeKey should match the key format")
+		require.Len(t, expected, nodeKeySize)
+
+		key := appendNodeKey(append([]byte{}, dst...), &h)
+		require.Equal(t, dst, key[:len(dst)], "appendNodeKey should preserve dst")
+		require.Equal(t, expected, key[len(dst):], "appendNodeKey should match the key format")
+
+		var decoded hash.Hash
+		require.True(t, nodeKeyFmt.Decode(key[len(dst):], &decoded), "key should decode")
+		require.Equal(t, h, decoded)
+	})
+}
+
+func FuzzRootNodeKey(f *testing.F) {
+	// Seed corpus.
+	for _, rootType := range []node.RootType{node.RootTypeInvalid, node.RootTypeState, node.RootTypeIO} {
+		th := api.TypedHashFromParts(rootType, hash.NewFromBytes([]byte("root")))
+		f.Add(th[:], []byte{})
+	}
+
+	// Fuzzing.
+	f.Fuzz(func(t *testing.T, data []byte, dst []byte) {
+		var th api.TypedHash
+		if err := th.UnmarshalBinary(data); err != nil {
+			return
+		}
+
+		expected := rootNodeKeyFmt.Encode(&th)
+		require.Equal(t, expected, newRootNodeKey(&th), "newRootNodeKey should match the key format")
+		require.Len(t, expected, rootNodeKeySize)
+
+		key := appendRootNodeKey(append([]byte{}, dst...), &th)
+		require.Equal(t, dst, key[:len(dst)], "appendRootNodeKey should preserve dst")
+		require.Equal(t, expected, key[len(dst):], "appendRootNodeKey should match the key format")
+
+		var decoded api.TypedHash
+		require.True(t, rootNodeKeyFmt.Decode(key[len(dst):], &decoded), "key should decode")
+		require.Equal(t, th, decoded)
+	})
+}
+
+func TestKeyBuffer(t *testing.T) {
+	require := require.New(t)
+
+	h := hash.NewFromBytes([]byte("node"))
+	buf := getKeyBuffer()
+	*buf = appendNodeKey(*buf, &h)
+	putKeyBuffer(buf)
+
+	buf = getKeyBuffer()
+	defer putKeyBuffer(buf)
+	require.Empty(*buf, "buffers from the pool should be empty")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		b := getKeyBuffer()
+		*b = appendNodeKey(*b, &h)
+		putKeyBuffer(b)
+	})
+	require.Zero(allocs, "encoding into a pooled buffer should not allocate")
+}
@@ -0,0 +1,41 @@
+package badger
+
+import (
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+var _ api.LineageNodeDB = (*badgerNodeDB)(nil)
+
+// Implements api.LineageNodeDB.
+func (d *badgerNodeDB) GetDerivedRoots(root node.Root) ([]node.Root, error) {
+	if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+		return nil, err
+	}
+	if root.Version < d.meta.getEarliestVersion() {
+		return nil, api.ErrRootNotFound
+	}
+
+	rootsMeta, err := d.getRootsMetadata(root.Version)
+	if err != nil {
+		return nil, err
+	}
+	derivedRoots, ok := rootsMeta.Roots[api.TypedHashFromRoot(root)]
+	if !ok {
+		return nil, api.ErrRootNotFound
+	}
+	if len(derivedRoots) == 0 {
+		return nil, nil
+	}
+
+	nextRootsMeta, err := d.getRootsMetadata(root.Version + 1)
+	if err != nil {
+		return nil, err
+	}
+	return api.ResolveDerivedRoots(root, derivedRoots, rootsMeta.Roots, nextRootsMeta.Roots), nil
+}
+
+// Implements api.LineageNodeDB.
+func (d *badgerNodeDB) GetRootLineage(root node.Root, maxDepth int) ([][]node.Root, error) {
+	return api.GetRootLineage(d, root, maxDepth)
+}
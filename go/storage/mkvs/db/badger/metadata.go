@@ -2,6 +2,8 @@ package badger
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"sync"
 
 	"github.com/dgraph-io/badger/v4"
@@ -129,7 +131,7 @@ func loadRootsMetadata(tx *badger.Txn, version uint64) (*rootsMetadata, error) {
 	switch err {
 	case nil:
 		if err = item.Value(func(val []byte) error { return cbor.Unmarshal(val, &rootsMeta) }); err != nil {
-			return nil, fmt.Errorf("mkvs/badger: error reading roots metadata: %w", err)
+			return nil, fmt.Errorf("%w: error reading roots metadata: %w", api.ErrCorruptedDB, err)
 		}
 	case badger.ErrKeyNotFound:
 		rootsMeta.Roots = make(map[api.TypedHash][]api.TypedHash)
@@ -143,3 +145,70 @@ func loadRootsMetadata(tx *badger.Txn, version uint64) (*rootsMetadata, error) {
 func (rm *rootsMetadata) save(tx *badger.Txn) error {
 	return tx.Set(rootsMetadataKeyFmt.Encode(rm.version), cbor.Marshal(rm))
 }
+
+// rootsMetadataSet is a set of roots metadata of multiple versions, loaded and updated within a
+// single transaction, so that the metadata of each version is only loaded and saved once even
+// when multiple roots are committed.
+type rootsMetadataSet struct {
+	tx    *badger.Txn
+	cache *rootsMetadataCache
+
+	metas map[uint64]*rootsMetadata
+	dirty map[uint64]struct{}
+}
+
+func newRootsMetadataSet(tx *badger.Txn, cache *rootsMetadataCache) *rootsMetadataSet {
+	return &rootsMetadataSet{
+		tx:    tx,
+		cache: cache,
+		metas: make(map[uint64]*rootsMetadata),
+		dirty: make(map[uint64]struct{}),
+	}
+}
+
+// load returns the roots metadata for the given version, loading it from the cache or the
+// database on first access.
+func (s *rootsMetadataSet) load(version uint64) (*rootsMetadata, error) {
+	if rootsMeta, ok := s.metas[version]; ok {
+		return rootsMeta, nil
+	}
+
+	rootsMeta, _, cached := s.cache.get(version)
+	if cached {
+		// Cached metadata is shared, so it needs to be cloned before it can be updated.
+		rootsMeta = rootsMeta.clone()
+	} else {
+		var err error
+		if rootsMeta, err = loadRootsMetadata(s.tx, version); err != nil {
+			return nil, err
+		}
+	}
+	s.metas[version] = rootsMeta
+	return rootsMeta, nil
+}
+
+// markDirty marks the roots metadata for the given version as needing to be saved.
+func (s *rootsMetadataSet) markDirty(version uint64) {
+	s.dirty[version] = struct{}{}
+}
+
+// save saves the roots metadata of all modified versions to the transaction.
+func (s *rootsMetadataSet) save() error {
+	for _, version := range slices.Sorted(maps.Keys(s.dirty)) {
+		if err := s.metas[version].save(s.tx); err != nil {
+			return err
+		}
+	}
+	s.dirty = make(map[uint64]struct{})
+	return nil
+}
+
+// committed updates the cache with all loaded roots metadata once the transaction has been
+// committed. The set must not be used afterwards.
+//
+// Must be called while holding metaUpdateLock.
+func (s *rootsMetadataSet) committed() {
+	for _, rootsMeta := range s.metas {
+		s.cache.put(rootsMeta)
+	}
+}
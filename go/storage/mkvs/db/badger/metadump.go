@@ -0,0 +1,629 @@
+package badger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// metadataDumpFormatVersion is the version of the metadata dump format.
+const metadataDumpFormatVersion = 1
+
+// ErrMetadataDumpCorrupted is the error returned when a metadata dump cannot be decoded.
+var ErrMetadataDumpCorrupted = errors.New("mkvs/badger: corrupted metadata dump")
+
+// metadataDumpHeader is the header of a metadata dump.
+type metadataDumpHeader struct {
+	// Version is the dump format version.
+	Version uint16 `json:"v"`
+
+	// Metadata is the database metadata.
+	Metadata serializedMetadata `json:"metadata"`
+}
+
+// metadataDumpVersion are the roots of a single version in a metadata dump.
+type metadataDumpVersion struct {
+	// Version is the version of the roots.
+	Version uint64 `json:"version"`
+
+	// Roots is the roots metadata of the version.
+	Roots map[api.TypedHash][]api.TypedHash `json:"roots"`
+
+	// RootNodes are the roots whose root node entries exist in the version.
+	RootNodes []api.TypedHash `json:"root_nodes"`
+}
+
+// ExportMetadata writes a dump of the database metadata, the roots metadata and the root node
+// entries of all versions that have not been pruned into the given writer, so that the metadata
+// can later be restored using RepairMetadata.
+//
+// The dump consists of a CBOR-encoded header followed by the roots of each version in ascending
+// version order.
+func ExportMetadata(cfg *api.Config, w io.Writer) error {
+	db, err := openForMetadataRecovery(cfg, "export")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx := db.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	item, err := tx.Get(metadataKeyFmt.Encode())
+	switch err {
+	case nil:
+	case badger.ErrKeyNotFound:
+		return fmt.Errorf("mkvs/badger: no database metadata to export")
+	default:
+		return fmt.Errorf("mkvs/badger: failed to read database metadata: %w", err)
+	}
+
+	var meta serializedMetadata
+	if err = item.Value(func(data []byte) error {
+		return cbor.UnmarshalTrusted(data, &meta)
+	}); err != nil {
+		return fmt.Errorf("%w: failed to load database metadata: %w", api.ErrCorruptedDB, err)
+	}
+	if err = checkMetadataCompatible(&meta, cfg.Namespace); err != nil {
+		return err
+	}
+
+	rootNodes, err := db.scanRootNodes(meta.EarliestVersion)
+	if err != nil {
+		return err
+	}
+	rootsMetaVersions, err := scanRootsMetadataVersions(tx, meta.EarliestVersion)
+	if err != nil {
+		return err
+	}
+	versions := maps.Clone(rootsMetaVersions)
+	for version := range rootNodes {
+		versions[version] = struct{}{}
+	}
+
+	enc := cbor.NewEncoder(w)
+	if err = enc.Encode(&metadataDumpHeader{
+		Version:  metadataDumpFormatVersion,
+		Metadata: meta,
+	}); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to encode metadata dump header: %w", err)
+	}
+	for _, version := range slices.Sorted(maps.Keys(versions)) {
+		var rootsMeta *rootsMetadata
+		if rootsMeta, err = loadRootsMetadata(tx, version); err != nil {
+			return fmt.Errorf("mkvs/badger: failed to load roots metadata of version %d: %w", version, err)
+		}
+		if err = enc.Encode(&metadataDumpVersion{
+			Version:   version,
+			Roots:     rootsMeta.Roots,
+			RootNodes: rootNodes[version],
+		}); err != nil {
+			return fmt.Errorf("mkvs/badger: failed to encode roots of version %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// RepairMetadata restores the database metadata from a dump produced by ExportMetadata.
+//
+// The database metadata is always replaced. Roots metadata and root node entries are only restored
+// for finalized versions in the dump where they are missing or corrupted, everything that was
+// written after the dump has been taken is kept. Versions pruned after the dump has been taken
+// are detected based on the remaining roots metadata and are not restored.
+func RepairMetadata(cfg *api.Config, r io.Reader) error {
+	if cfg.ReadOnly {
+		return api.ErrReadOnly
+	}
+
+	dec := cbor.NewDecoder(r)
+
+	var hdr metadataDumpHeader
+	if err := dec.Decode(&hdr); err != nil {
+		return fmt.Errorf("%w: failed to decode header: %w", ErrMetadataDumpCorrupted, err)
+	}
+	if hdr.Version != metadataDumpFormatVersion {
+		return fmt.Errorf("mkvs/badger: unsupported metadata dump format version: %d", hdr.Version)
+	}
+	if err := checkMetadataCompatible(&hdr.Metadata, cfg.Namespace); err != nil {
+		return err
+	}
+	meta := hdr.Metadata
+
+	db, err := openForMetadataRecovery(cfg, "repair")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx := db.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	// Pruning removes roots metadata, so the earliest remaining roots metadata tells whether the
+	// database has been pruned since the dump has been taken.
+	rootsMetaVersions, err := scanRootsMetadataVersions(tx, 0)
+	if err != nil {
+		return err
+	}
+	if len(rootsMetaVersions) > 0 {
+		if earliestVersion := slices.Min(slices.Collect(maps.Keys(rootsMetaVersions))); earliestVersion > meta.EarliestVersion {
+			db.logger.Warn("database has been pruned since the metadata dump has been taken",
+				"dump_earliest_version", meta.EarliestVersion,
+				"earliest_version", earliestVersion,
+			)
+			meta.EarliestVersion = earliestVersion
+		}
+	}
+
+	rootNodes, err := db.scanRootNodes(meta.EarliestVersion)
+	if err != nil {
+		return err
+	}
+
+	dataBatch := db.db.NewManagedWriteBatch()
+	defer dataBatch.Cancel()
+	metaBatch := db.metaDB.NewWriteBatchAt(tsMetadata)
+	defer metaBatch.Cancel()
+
+	var (
+		lastVersion          uint64
+		numVersions          int
+		numRestoredRootsMeta int
+		numRestoredRootNodes int
+	)
+	for {
+		var entry metadataDumpVersion
+		if err = dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: failed to decode roots: %w", ErrMetadataDumpCorrupted, err)
+		}
+		if numVersions > 0 && entry.Version <= lastVersion {
+			return fmt.Errorf("%w: versions out of order", ErrMetadataDumpCorrupted)
+		}
+		lastVersion = entry.Version
+		numVersions++
+
+		// Only finalized versions are restored as roots in later versions may have been
+		// discarded since the dump has been taken.
+		if entry.Version < meta.EarliestVersion || meta.LastFinalizedVersion == nil || entry.Version > *meta.LastFinalizedVersion {
+			continue
+		}
+
+		var valid bool
+		if valid, err = hasValidRootsMetadata(tx, entry.Version); err != nil {
+			return err
+		}
+		if !valid {
+			rootsMeta := &rootsMetadata{
+				Roots:   entry.Roots,
+				version: entry.Version,
+			}
+			if rootsMeta.Roots == nil {
+				rootsMeta.Roots = make(map[api.TypedHash][]api.TypedHash)
+			}
+			if err = metaBatch.Set(rootsMetadataKeyFmt.Encode(entry.Version), cbor.Marshal(rootsMeta)); err != nil {
+				return err
+			}
+			numRestoredRootsMeta++
+		}
+
+		for _, rootHash := range entry.RootNodes {
+			if slices.Contains(rootNodes[entry.Version], rootHash) {
+				continue
+			}
+			e := badger.NewEntry(newRootNodeKey(&rootHash), []byte{})
+			if err = dataBatch.SetEntryAt(e, versionToTs(entry.Version)); err != nil {
+				return err
+			}
+			rootNodes[entry.Version] = append(rootNodes[entry.Version], rootHash)
+			numRestoredRootNodes++
+		}
+	}
+
+	// The database may have been finalized further since the dump has been taken.
+	if lastFinalizedVersion, ok, err := inferLastFinalizedVersion(tx, rootNodes); err != nil {
+		return err
+	} else if ok && (meta.LastFinalizedVersion == nil || lastFinalizedVersion > *meta.LastFinalizedVersion) {
+		meta.LastFinalizedVersion = &lastFinalizedVersion
+	}
+	if meta.MultipartVersion, err = db.inferMultipartVersion(tx, rootNodes); err != nil {
+		return err
+	}
+
+	if err = db.writeRecoveredMetadata(dataBatch, metaBatch, &meta); err != nil {
+		return err
+	}
+
+	db.logger.Info("restored database metadata",
+		"earliest_version", meta.EarliestVersion,
+		"last_finalized_version", meta.LastFinalizedVersion,
+		"num_versions", numVersions,
+		"restored_roots_metadata", numRestoredRootsMeta,
+		"restored_root_nodes", numRestoredRootNodes,
+	)
+	return nil
+}
+
+// ReconstructMetadata rebuilds the database metadata and the roots metadata of all versions
+// starting at the given earliest version from the root node entries and the write logs in case
+// there is no dump to restore them from.
+//
+// Derived roots are reconstructed from the write logs. In case a version has no write logs (e.g.,
+// because they are discarded), each root of the preceding version without known derived roots
+// is conservatively linked to all roots of the same type in the version. This may keep nodes
+// around after pruning, but never removes nodes that are still in use.
+//
+// Root node entries of pruned versions whose roots had derived roots are only removed by badger
+// compactions, so the earliest version should be given in case it is known.
+func ReconstructMetadata(cfg *api.Config, earliestVersion uint64) error {
+	if cfg.ReadOnly {
+		return api.ErrReadOnly
+	}
+
+	db, err := openForMetadataRecovery(cfg, "reconstruct")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rootNodes, err := db.scanRootNodes(earliestVersion)
+	if err != nil {
+		return err
+	}
+	if len(rootNodes) == 0 {
+		return fmt.Errorf("mkvs/badger: no roots to reconstruct metadata from")
+	}
+	versions := slices.Sorted(maps.Keys(rootNodes))
+
+	roots := make(map[uint64]map[api.TypedHash][]api.TypedHash, len(rootNodes))
+	for version, rootHashes := range rootNodes {
+		roots[version] = make(map[api.TypedHash][]api.TypedHash, len(rootHashes))
+		for _, rootHash := range rootHashes {
+			roots[version][rootHash] = []api.TypedHash{}
+		}
+	}
+
+	hasWriteLogs, err := db.linkDerivedRoots(roots, versions[0])
+	if err != nil {
+		return err
+	}
+	var numConservative int
+	for _, version := range versions {
+		nextRoots, ok := roots[version+1]
+		if !ok || hasWriteLogs[version+1] {
+			continue
+		}
+		for rootHash, derivedRoots := range roots[version] {
+			if len(derivedRoots) > 0 {
+				continue
+			}
+			for nextRootHash := range nextRoots {
+				if nextRootHash.Type() == rootHash.Type() {
+					roots[version][rootHash] = append(roots[version][rootHash], nextRootHash)
+				}
+			}
+			numConservative++
+		}
+	}
+
+	tx := db.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	meta := serializedMetadata{
+		Version:         dbVersion,
+		Namespace:       cfg.Namespace,
+		EarliestVersion: versions[0],
+	}
+	if lastFinalizedVersion, ok, err := inferLastFinalizedVersion(tx, rootNodes); err != nil {
+		return err
+	} else if ok {
+		meta.LastFinalizedVersion = &lastFinalizedVersion
+	}
+	if meta.MultipartVersion, err = db.inferMultipartVersion(tx, rootNodes); err != nil {
+		return err
+	}
+
+	dataBatch := db.db.NewManagedWriteBatch()
+	defer dataBatch.Cancel()
+	metaBatch := db.metaDB.NewWriteBatchAt(tsMetadata)
+	defer metaBatch.Cancel()
+
+	// Remove any roots metadata that is not reconstructed as it cannot be trusted.
+	rootsMetaVersions, err := scanRootsMetadataVersions(tx, 0)
+	if err != nil {
+		return err
+	}
+	for version := range rootsMetaVersions {
+		if _, ok := roots[version]; ok {
+			continue
+		}
+		if err = metaBatch.Delete(rootsMetadataKeyFmt.Encode(version)); err != nil {
+			return err
+		}
+	}
+	for _, version := range versions {
+		for _, derivedRoots := range roots[version] {
+			slices.SortFunc(derivedRoots, func(a, b api.TypedHash) int {
+				return bytes.Compare(a[:], b[:])
+			})
+		}
+		rootsMeta := &rootsMetadata{
+			Roots:   roots[version],
+			version: version,
+		}
+		if err = metaBatch.Set(rootsMetadataKeyFmt.Encode(version), cbor.Marshal(rootsMeta)); err != nil {
+			return err
+		}
+	}
+
+	if err = db.writeRecoveredMetadata(dataBatch, metaBatch, &meta); err != nil {
+		return err
+	}
+
+	db.logger.Info("reconstructed database metadata",
+		"earliest_version", meta.EarliestVersion,
+		"last_finalized_version", meta.LastFinalizedVersion,
+		"num_versions", len(versions),
+		"conservatively_linked_roots", numConservative,
+	)
+	return nil
+}
+
+// openForMetadataRecovery opens the database without loading its metadata.
+//
+// The stores are opened in the layout that is on disk, so that metadata is never moved between
+// the stores before it has been recovered.
+func openForMetadataRecovery(cfg *api.Config, op string) (*badgerNodeDB, error) {
+	db := &badgerNodeDB{
+		logger:           logging.GetLogger("mkvs/db/badger/" + op),
+		namespace:        cfg.Namespace,
+		readOnly:         cfg.ReadOnly,
+		discardWriteLogs: cfg.DiscardWriteLogs,
+	}
+	opts := commonConfigToBadgerOptions(cfg, db)
+
+	var err error
+	if db.db, err = badger.OpenManaged(opts); err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	var split bool
+	if !opts.InMemory {
+		_, err = os.Stat(metadataStoreOptions(opts).Dir)
+		split = err == nil
+	}
+	if err = db.openMetadataStore(cfg, opts, split); err != nil {
+		_ = db.db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// checkMetadataCompatible checks that the given database metadata is for the current database
+// version and the given namespace.
+func checkMetadataCompatible(meta *serializedMetadata, namespace common.Namespace) error {
+	if meta.Version != dbVersion {
+		return fmt.Errorf("incompatible database version (expected: %d got: %d)",
+			dbVersion,
+			meta.Version,
+		)
+	}
+	if !meta.Namespace.Equal(&namespace) {
+		return fmt.Errorf("incompatible namespace (expected: %s got: %s)",
+			namespace,
+			meta.Namespace,
+		)
+	}
+	return nil
+}
+
+// scanRootNodes returns the roots whose root node entries exist, grouped by version, ignoring
+// versions before the given earliest version.
+func (d *badgerNodeDB) scanRootNodes(earliestVersion uint64) (map[uint64][]api.TypedHash, error) {
+	tx := d.db.NewTransactionAt(math.MaxUint64, false)
+	defer tx.Discard()
+
+	// The same root may exist in multiple versions, so all versions of the entries are needed.
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: rootNodeKeyFmt.Encode(), AllVersions: true})
+	defer it.Close()
+
+	rootNodes := make(map[uint64][]api.TypedHash)
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		if item.IsDeletedOrExpired() {
+			continue
+		}
+		var rootHash api.TypedHash
+		if !rootNodeKeyFmt.Decode(item.Key(), &rootHash) {
+			return nil, fmt.Errorf("%w: malformed root node key", api.ErrCorruptedDB)
+		}
+		version := tsToVersion(item.Version())
+		if version < earliestVersion {
+			continue
+		}
+		rootNodes[version] = append(rootNodes[version], rootHash)
+	}
+	return rootNodes, nil
+}
+
+// scanRootsMetadataVersions returns the versions starting at the given earliest version for
+// which roots metadata records exist.
+func scanRootsMetadataVersions(tx *badger.Txn, earliestVersion uint64) (map[uint64]struct{}, error) {
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: rootsMetadataKeyFmt.Encode()})
+	defer it.Close()
+
+	versions := make(map[uint64]struct{})
+	for it.Seek(rootsMetadataKeyFmt.Encode(earliestVersion)); it.Valid(); it.Next() {
+		var version uint64
+		if !rootsMetadataKeyFmt.Decode(it.Item().Key(), &version) {
+			return nil, fmt.Errorf("%w: malformed roots metadata key", api.ErrCorruptedDB)
+		}
+		versions[version] = struct{}{}
+	}
+	return versions, nil
+}
+
+// hasValidRootsMetadata returns true iff the roots metadata of the given version exists and can
+// be decoded.
+func hasValidRootsMetadata(tx *badger.Txn, version uint64) (bool, error) {
+	item, err := tx.Get(rootsMetadataKeyFmt.Encode(version))
+	switch err {
+	case nil:
+	case badger.ErrKeyNotFound:
+		return false, nil
+	default:
+		return false, fmt.Errorf("mkvs/badger: error reading roots metadata: %w", err)
+	}
+
+	var rootsMeta rootsMetadata
+	err = item.Value(func(val []byte) error { return cbor.Unmarshal(val, &rootsMeta) })
+	return err == nil, nil
+}
+
+// linkDerivedRoots adds the roots derived from each root to the given roots based on the write
+// logs starting at the given earliest version. It returns the versions that have write logs.
+//
+// Write logs do not record the version of the old root, so it is assumed to be the preceding
+// version in case the old root exists there and the same version otherwise.
+func (d *badgerNodeDB) linkDerivedRoots(roots map[uint64]map[api.TypedHash][]api.TypedHash, earliestVersion uint64) (map[uint64]bool, error) {
+	tx := d.db.NewTransactionAt(math.MaxUint64, false)
+	defer tx.Discard()
+
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: writeLogKeyFmt.Encode()})
+	defer it.Close()
+
+	hasWriteLogs := make(map[uint64]bool)
+	for it.Seek(writeLogKeyFmt.Encode(earliestVersion)); it.Valid(); it.Next() {
+		var (
+			version     uint64
+			rootHash    api.TypedHash
+			oldRootHash api.TypedHash
+		)
+		if !writeLogKeyFmt.Decode(it.Item().Key(), &version, &rootHash, &oldRootHash) {
+			return nil, fmt.Errorf("%w: malformed write log key", api.ErrCorruptedDB)
+		}
+		hasWriteLogs[version] = true
+
+		if _, ok := roots[version][rootHash]; !ok {
+			continue
+		}
+		if oldRootHash.Hash().IsEmpty() {
+			continue
+		}
+
+		oldVersion := version
+		switch {
+		case version > 0 && roots[version-1][oldRootHash] != nil:
+			oldVersion = version - 1
+		case oldRootHash != rootHash && roots[version][oldRootHash] != nil:
+		default:
+			// The old root no longer exists.
+			continue
+		}
+		if derivedRoots := roots[oldVersion][oldRootHash]; !slices.Contains(derivedRoots, rootHash) {
+			roots[oldVersion][oldRootHash] = append(derivedRoots, rootHash)
+		}
+	}
+	return hasWriteLogs, nil
+}
+
+// inferLastFinalizedVersion returns the last version with roots that precedes all versions with
+// updated nodes, which are only kept until a version is finalized.
+func inferLastFinalizedVersion(tx *badger.Txn, rootNodes map[uint64][]api.TypedHash) (uint64, bool, error) {
+	// Additional updated nodes chunks are only written together with the first chunk, so it is
+	// enough to look at the first chunks. Keys are ordered by version.
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: rootUpdatedNodesKeyFmt.Encode()})
+	defer it.Close()
+
+	firstUnfinalizedVersion := uint64(math.MaxUint64)
+	if it.Rewind(); it.Valid() {
+		var rootHash api.TypedHash
+		if !rootUpdatedNodesKeyFmt.Decode(it.Item().Key(), &firstUnfinalizedVersion, &rootHash) {
+			return 0, false, fmt.Errorf("%w: malformed root updated nodes key", api.ErrCorruptedDB)
+		}
+	}
+
+	var (
+		lastFinalizedVersion uint64
+		ok                   bool
+	)
+	for version := range rootNodes {
+		if version < firstUnfinalizedVersion && (!ok || version > lastFinalizedVersion) {
+			lastFinalizedVersion = version
+			ok = true
+		}
+	}
+	return lastFinalizedVersion, ok, nil
+}
+
+// inferMultipartVersion returns the version of an interrupted multipart restore based on the
+// roots recorded in the multipart restore log.
+func (d *badgerNodeDB) inferMultipartVersion(tx *badger.Txn, rootNodes map[uint64][]api.TypedHash) (uint64, error) {
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: multipartRestoreNodeLogKeyFmt.Encode()})
+	defer it.Close()
+
+	var logged bool
+	for it.Rewind(); it.Valid(); it.Next() {
+		logged = true
+
+		var h api.TypedHash
+		if !multipartRestoreNodeLogKeyFmt.Decode(it.Item().Key(), &h) {
+			return 0, fmt.Errorf("%w: malformed multipart restore log key", api.ErrCorruptedDB)
+		}
+		if h.Type() == node.RootTypeInvalid {
+			continue
+		}
+		for version, rootHashes := range rootNodes {
+			if slices.Contains(rootHashes, h) {
+				return version, nil
+			}
+		}
+	}
+	if logged {
+		d.logger.Warn("unable to determine the version of an interrupted multipart restore, its nodes will not be removed")
+	}
+	return multipartVersionNone, nil
+}
+
+// writeRecoveredMetadata flushes the given batches, followed by the recovered database metadata.
+func (d *badgerNodeDB) writeRecoveredMetadata(dataBatch, metaBatch *badger.WriteBatch, meta *serializedMetadata) error {
+	if err := dataBatch.Flush(); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to flush root nodes: %w", err)
+	}
+	if err := syncStore(d.db); err != nil {
+		return err
+	}
+	if err := metaBatch.Flush(); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to flush roots metadata: %w", err)
+	}
+	if err := syncStore(d.metaDB); err != nil {
+		return err
+	}
+
+	// Write the database metadata last so that the database is only usable once everything else
+	// has been recovered.
+	tx := d.metaDB.NewTransactionAt(tsMetadata, true)
+	defer tx.Discard()
+
+	if err := tx.Set(metadataKeyFmt.Encode(), cbor.Marshal(meta)); err != nil {
+		return err
+	}
+	if err := tx.CommitAt(tsMetadata, nil); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to commit database metadata: %w", err)
+	}
+	return syncStore(d.metaDB)
+}
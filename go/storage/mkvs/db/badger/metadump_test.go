@@ -0,0 +1,184 @@
+package badger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// fillVersions commits a root into each of the given number of versions and finalizes all but the
+// last one.
+func fillVersions(ctx context.Context, require *require.Assertions, ndb api.NodeDB, numVersions uint64) []node.Root {
+	var (
+		roots []node.Root
+		prev  *node.Root
+	)
+	for version := uint64(0); version < numVersions; version++ {
+		root := fillDB(ctx, require, testValues[:version%3+1], prev, version, version, ndb)
+		root.Version = version
+		if version < numVersions-1 {
+			err := ndb.Finalize([]node.Root{root})
+			require.NoError(err, "Finalize({root%d})", version)
+		}
+		roots = append(roots, root)
+		prev = &roots[len(roots)-1]
+	}
+	return roots
+}
+
+// corruptMetadata applies the given modifications to the metadata of a closed database.
+func corruptMetadata(require *require.Assertions, cfg *api.Config, fn func(tx *badger.Txn)) {
+	db, err := openForMetadataRecovery(cfg, "test")
+	require.NoError(err, "openForMetadataRecovery()")
+	defer db.Close()
+
+	tx := db.metaDB.NewTransactionAt(tsMetadata, true)
+	defer tx.Discard()
+
+	fn(tx)
+	err = tx.CommitAt(tsMetadata, nil)
+	require.NoError(err, "CommitAt()")
+}
+
+func TestRepairMetadata(t *testing.T) {
+	for _, split := range []bool{false, true} {
+		t.Run(fmt.Sprintf("SplitMetadata=%t", split), func(t *testing.T) {
+			testRepairMetadata(t, split)
+		})
+	}
+}
+
+func testRepairMetadata(t *testing.T, split bool) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "oasis-storage-database-test")
+	require.NoError(err, "TempDir()")
+	defer os.RemoveAll(dir)
+
+	cfg := splitTestConfig(dir, split)
+	ndb, err := New(cfg)
+	require.NoError(err, "New()")
+	roots := fillVersions(ctx, require, ndb, 4)
+	ndb.Close()
+
+	// Take a dump while versions 0-2 are finalized.
+	var dump bytes.Buffer
+	err = ExportMetadata(cfg, &dump)
+	require.NoError(err, "ExportMetadata()")
+
+	// Make progress after the dump has been taken.
+	ndb, err = New(cfg)
+	require.NoError(err, "New()")
+	err = ndb.Finalize([]node.Root{roots[3]})
+	require.NoError(err, "Finalize({root3})")
+	root4 := fillDB(ctx, require, [][]byte{[]byte("updated value")}, &roots[3], 3, 4, ndb)
+	_, err = ndb.PruneRange(ctx, 0, 0)
+	require.NoError(err, "PruneRange(0, 0)")
+	ndb.Close()
+
+	// Corrupt the database metadata and the roots metadata of a version.
+	corruptMetadata(require, cfg, func(tx *badger.Txn) {
+		require.NoError(tx.Set(metadataKeyFmt.Encode(), []byte("corrupted")))
+		require.NoError(tx.Set(rootsMetadataKeyFmt.Encode(uint64(1)), []byte("corrupted")))
+	})
+	_, err = New(cfg)
+	require.Error(err, "New() should fail with corrupted metadata")
+
+	// Repairing from a malformed dump should fail without touching the database.
+	err = RepairMetadata(cfg, bytes.NewReader(dump.Bytes()[:dump.Len()-1]))
+	require.ErrorIs(err, ErrMetadataDumpCorrupted, "RepairMetadata() with a truncated dump")
+	_, err = New(cfg)
+	require.Error(err, "New() should still fail")
+
+	err = RepairMetadata(cfg, &dump)
+	require.NoError(err, "RepairMetadata()")
+
+	ndb, err = New(cfg)
+	require.NoError(err, "New() after repair")
+	defer ndb.Close()
+
+	require.EqualValues(1, ndb.GetEarliestVersion(), "pruning after the dump should be kept")
+	lastFinalizedVersion, ok := ndb.GetLatestVersion()
+	require.True(ok)
+	require.EqualValues(3, lastFinalizedVersion, "finalization after the dump should be kept")
+	for _, root := range roots[1:] {
+		require.True(ndb.HasRoot(root), "root %d should exist", root.Version)
+	}
+	requireRootValues(ctx, require, ndb, roots[1], testValues[:2])
+	requireRootValues(ctx, require, ndb, roots[3], testValues)
+
+	// The database should keep working.
+	err = ndb.Finalize([]node.Root{root4})
+	require.NoError(err, "Finalize({root4})")
+	_, err = ndb.PruneRange(ctx, 1, 2)
+	require.NoError(err, "PruneRange(1, 2)")
+	requireRootValues(ctx, require, ndb, roots[3], testValues)
+}
+
+func TestReconstructMetadata(t *testing.T) {
+	for _, discardWriteLogs := range []bool{false, true} {
+		t.Run(fmt.Sprintf("DiscardWriteLogs=%t", discardWriteLogs), func(t *testing.T) {
+			testReconstructMetadata(t, discardWriteLogs)
+		})
+	}
+}
+
+func testReconstructMetadata(t *testing.T, discardWriteLogs bool) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "oasis-storage-database-test")
+	require.NoError(err, "TempDir()")
+	defer os.RemoveAll(dir)
+
+	cfg := splitTestConfig(dir, false)
+	cfg.DiscardWriteLogs = discardWriteLogs
+	ndb, err := New(cfg)
+	require.NoError(err, "New()")
+	roots := fillVersions(ctx, require, ndb, 4)
+	ndb.Close()
+
+	// Remove all metadata without a dump to restore it from.
+	corruptMetadata(require, cfg, func(tx *badger.Txn) {
+		require.NoError(tx.Delete(metadataKeyFmt.Encode()))
+		for version := range uint64(len(roots)) {
+			require.NoError(tx.Delete(rootsMetadataKeyFmt.Encode(version)))
+		}
+	})
+
+	err = ReconstructMetadata(cfg, 0)
+	require.NoError(err, "ReconstructMetadata()")
+
+	ndb, err = New(cfg)
+	require.NoError(err, "New() after reconstruction")
+	defer ndb.Close()
+
+	require.EqualValues(0, ndb.GetEarliestVersion())
+	lastFinalizedVersion, ok := ndb.GetLatestVersion()
+	require.True(ok)
+	require.EqualValues(2, lastFinalizedVersion, "version with updated nodes should not be finalized")
+	for _, root := range roots {
+		require.True(ndb.HasRoot(root), "root %d should exist", root.Version)
+	}
+	for _, root := range roots[:3] {
+		derivedRoots, err := ndb.(api.LineageNodeDB).GetDerivedRoots(root)
+		require.NoError(err, "GetDerivedRoots(root%d)", root.Version)
+		require.Equal([]node.Root{roots[root.Version+1]}, derivedRoots, "derived roots of root %d", root.Version)
+	}
+
+	// The database should keep working and pruning should not remove nodes still in use.
+	err = ndb.Finalize([]node.Root{roots[3]})
+	require.NoError(err, "Finalize({root3})")
+	_, err = ndb.PruneRange(ctx, 0, 2)
+	require.NoError(err, "PruneRange(0, 2)")
+	requireRootValues(ctx, require, ndb, roots[3], testValues)
+}
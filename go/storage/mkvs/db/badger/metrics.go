@@ -0,0 +1,309 @@
+package badger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+const (
+	metricsOpCheckRoot = "check_root"
+	metricsOpGetNode   = "get_node"
+	metricsOpHasRoot   = "has_root"
+	metricsOpRoots     = "roots"
+	metricsOpClose     = "close"
+
+	metricsOpBackgroundPrune = "background_prune"
+)
+
+var (
+	getNodeCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_storage_mkvs_db_get_node",
+			Help: "Number of node lookups by result (hit or miss).",
+		},
+		[]string{"runtime", "result"},
+	)
+	nodeCacheCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_storage_mkvs_db_node_cache",
+			Help: "Number of node cache lookups by result (hit or miss).",
+		},
+		[]string{"runtime", "result"},
+	)
+	getWriteLogCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_storage_mkvs_db_get_write_log",
+			Help: "Number of write log lookups by result (found or not_found).",
+		},
+		[]string{"runtime", "result"},
+	)
+	getWriteLogHops = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "oasis_storage_mkvs_db_get_write_log_hops",
+			Help:    "Number of hops traversed by successful write log lookups.",
+			Buckets: prometheus.LinearBuckets(1, 1, api.MaxWriteLogHopsLimit),
+		},
+		[]string{"runtime"},
+	)
+	batchCommitCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_storage_mkvs_db_batch_commits",
+			Help: "Number of committed batches.",
+		},
+		[]string{"runtime"},
+	)
+	batchCommitBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_storage_mkvs_db_batch_commit_bytes",
+			Help: "Number of bytes written by committed batches.",
+		},
+		[]string{"runtime"},
+	)
+	batchSize = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "oasis_storage_mkvs_db_batch_size_bytes",
+			Help:    "Size of committed batches (bytes).",
+			Buckets: prometheus.ExponentialBuckets(1024, 4, 10),
+		},
+	)
+	finalizeDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "oasis_storage_mkvs_db_finalize_duration_seconds",
+			Help: "Time spent finalizing a version (seconds).",
+		},
+		[]string{"runtime"},
+	)
+	pruneDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "oasis_storage_mkvs_db_prune_duration_seconds",
+			Help: "Time spent pruning a range of versions (seconds).",
+		},
+		[]string{"runtime"},
+	)
+	earliestVersionGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_storage_mkvs_db_earliest_version",
+			Help: "Earliest version in the node database.",
+		},
+		[]string{"runtime"},
+	)
+	latestFinalizedVersionGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_storage_mkvs_db_latest_finalized_version",
+			Help: "Latest finalized version in the node database.",
+		},
+		[]string{"runtime"},
+	)
+	multipartInProgressGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_storage_mkvs_db_multipart_in_progress",
+			Help: "Whether a multipart restore is in progress (1) or not (0).",
+		},
+		[]string{"runtime"},
+	)
+	pruneBacklogGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_storage_mkvs_db_prune_backlog",
+			Help: "Number of versions waiting to be pruned in the background.",
+		},
+		[]string{"runtime"},
+	)
+	nodeCorruptionCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_storage_mkvs_db_node_corruptions",
+			Help: "Number of nodes read from the database whose hash did not match.",
+		},
+		[]string{"runtime"},
+	)
+	errorCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_storage_mkvs_db_errors",
+			Help: "Number of unexpected node database errors by operation.",
+		},
+		[]string{"runtime", "operation"},
+	)
+	errorCategoryCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_storage_mkvs_db_error_categories",
+			Help: "Number of unexpected node database errors by category (corruption, resource_exhausted, conflict, io or unknown).",
+		},
+		[]string{"runtime", "category"},
+	)
+
+	dbCollectors = []prometheus.Collector{
+		getNodeCount,
+		nodeCacheCount,
+		getWriteLogCount,
+		getWriteLogHops,
+		batchCommitCount,
+		batchCommitBytes,
+		batchSize,
+		finalizeDuration,
+		pruneDuration,
+		earliestVersionGauge,
+		latestFinalizedVersionGauge,
+		multipartInProgressGauge,
+		pruneBacklogGauge,
+		nodeCorruptionCount,
+		errorCount,
+		errorCategoryCount,
+	}
+
+	metricsOnce sync.Once
+)
+
+// dbMetrics reports the metrics of a single node database. All methods are no-ops in case the
+// receiver is nil.
+type dbMetrics struct {
+	runtime string
+}
+
+// newDBMetrics creates a new metrics reporter for the node database with the given configuration.
+// In case metrics are not enabled in the configuration, nil is returned.
+func newDBMetrics(cfg *api.Config) *dbMetrics {
+	if !cfg.MetricsEnabled {
+		return nil
+	}
+
+	metricsOnce.Do(func() {
+		prometheus.MustRegister(dbCollectors...)
+	})
+
+	return &dbMetrics{
+		runtime: cfg.Namespace.String(),
+	}
+}
+
+func (m *dbMetrics) labels() prometheus.Labels {
+	return prometheus.Labels{"runtime": m.runtime}
+}
+
+func (m *dbMetrics) labelsWith(name, value string) prometheus.Labels {
+	return prometheus.Labels{"runtime": m.runtime, name: value}
+}
+
+// getNode records a node lookup.
+func (m *dbMetrics) getNode(hit bool) {
+	if m == nil {
+		return
+	}
+
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	getNodeCount.With(m.labelsWith("result", result)).Inc()
+}
+
+// nodeCache records a node cache lookup.
+func (m *dbMetrics) nodeCache(hit bool) {
+	if m == nil {
+		return
+	}
+
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	nodeCacheCount.With(m.labelsWith("result", result)).Inc()
+}
+
+// getWriteLog records a write log lookup. Zero hops means that the write log was not found.
+func (m *dbMetrics) getWriteLog(hops int) {
+	if m == nil {
+		return
+	}
+
+	if hops == 0 {
+		getWriteLogCount.With(m.labelsWith("result", "not_found")).Inc()
+		return
+	}
+	getWriteLogCount.With(m.labelsWith("result", "found")).Inc()
+	getWriteLogHops.With(m.labels()).Observe(float64(hops))
+}
+
+// nodeCorrupted records a node whose hash did not match.
+func (m *dbMetrics) nodeCorrupted() {
+	if m == nil {
+		return
+	}
+
+	nodeCorruptionCount.With(m.labels()).Inc()
+}
+
+// batchCommit records a committed batch of the given size.
+func (m *dbMetrics) batchCommit(size int64) {
+	if m == nil {
+		return
+	}
+
+	batchCommitCount.With(m.labels()).Inc()
+	batchCommitBytes.With(m.labels()).Add(float64(size))
+	batchSize.Observe(float64(size))
+}
+
+// finalize records the duration of a successful finalization that started at the given time.
+func (m *dbMetrics) finalize(start time.Time) {
+	if m == nil {
+		return
+	}
+
+	finalizeDuration.With(m.labels()).Observe(time.Since(start).Seconds())
+}
+
+// prune records the duration of pruning that started at the given time.
+func (m *dbMetrics) prune(start time.Time) {
+	if m == nil {
+		return
+	}
+
+	pruneDuration.With(m.labels()).Observe(time.Since(start).Seconds())
+}
+
+// versions records the current earliest and latest finalized versions.
+func (m *dbMetrics) versions(meta *metadata) {
+	if m == nil {
+		return
+	}
+
+	earliestVersionGauge.With(m.labels()).Set(float64(meta.getEarliestVersion()))
+	if latest, exists := meta.getLastFinalizedVersion(); exists {
+		latestFinalizedVersionGauge.With(m.labels()).Set(float64(latest))
+	}
+}
+
+// multipart records whether a multipart restore is in progress.
+func (m *dbMetrics) multipart(version uint64) {
+	if m == nil {
+		return
+	}
+
+	var inProgress float64
+	if version != multipartVersionNone {
+		inProgress = 1
+	}
+	multipartInProgressGauge.With(m.labels()).Set(inProgress)
+}
+
+// pruneBacklog records the number of versions waiting to be pruned in the background.
+func (m *dbMetrics) pruneBacklog(versions uint64) {
+	if m == nil {
+		return
+	}
+
+	pruneBacklogGauge.With(m.labels()).Set(float64(versions))
+}
+
+// failure records an unexpected error of the given category during the given operation.
+func (m *dbMetrics) failure(op string, category api.ErrorCategory) {
+	if m == nil {
+		return
+	}
+
+	errorCount.With(m.labelsWith("operation", op)).Inc()
+	errorCategoryCount.With(m.labelsWith("category", string(category))).Inc()
+}
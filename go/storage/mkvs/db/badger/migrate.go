@@ -1393,6 +1393,12 @@ func Migrate(cfg *api.Config, helper MigrationHelper) (uint64, error) {
 	// Make sure that we can discard any deleted/invalid metadata.
 	db.db.SetDiscardTs(tsMetadata)
 
+	// Migrations only know about a single store, so move any split metadata back first. It is
+	// split again once the database is opened with metadata splitting enabled.
+	if err = db.openMetadataStore(cfg, opts, false); err != nil {
+		return 0, fmt.Errorf("mkvs/badger/migrate: %w", err)
+	}
+
 	// Load metadata.
 	lastVersion, err := func() (uint64, error) {
 		tx := db.db.NewTransactionAt(tsMetadata, false)
@@ -0,0 +1,96 @@
+package badger
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+var _ api.MultiCommitNodeDB = (*badgerNodeDB)(nil)
+
+// Implements api.MultiCommitNodeDB.
+func (d *badgerNodeDB) CommitMulti(batches []api.Batch, roots []node.Root) error {
+	if len(batches) != len(roots) {
+		return fmt.Errorf("mkvs/badger: malformed multi-root commit (batches: %d roots: %d)",
+			len(batches), len(roots),
+		)
+	}
+	if len(batches) == 0 {
+		return nil
+	}
+
+	bas := make([]*badgerBatch, 0, len(batches))
+	for i, batch := range batches {
+		ba, ok := batch.(*badgerBatch)
+		if !ok || ba.db != d {
+			return fmt.Errorf("mkvs/badger: batch not created by this database")
+		}
+		if ba.chunk {
+			return fmt.Errorf("mkvs/badger: chunk batches cannot be committed together")
+		}
+		if ba.released {
+			return api.ErrBatchClosed
+		}
+		if roots[i].Version != roots[0].Version {
+			return fmt.Errorf("mkvs/badger: multi-root commit version mismatch (expected: %d got: %d)",
+				roots[0].Version, roots[i].Version,
+			)
+		}
+		bas = append(bas, ba)
+	}
+
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	// Update the set of roots for all affected versions, loading and saving each only once.
+	tx := d.metaDB.NewTransactionAt(versionToTs(roots[0].Version), true)
+	defer tx.Discard()
+
+	metas := newRootsMetadataSet(tx, d.rootsMetaCache)
+	exists := make([]bool, len(bas))
+	for i, ba := range bas {
+		var err error
+		if exists[i], err = ba.prepareCommit(metas, roots[i]); err != nil {
+			return err
+		}
+	}
+	if err := metas.save(); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to save roots metadata: %w", err)
+	}
+	for i, ba := range bas {
+		if exists[i] {
+			continue
+		}
+		if err := ba.flush(); err != nil {
+			return err
+		}
+	}
+	if err := d.syncData(); err != nil {
+		return err
+	}
+	if d.commitMultiInterruptFn != nil {
+		if err := d.commitMultiInterruptFn(); err != nil {
+			return err
+		}
+	}
+
+	// Commit root metadata updates. This is done last, so in case we fail, we can still retry.
+	if err := tx.CommitAt(tsMetadata, nil); err != nil {
+		return err
+	}
+	metas.committed()
+	for i, ba := range bas {
+		if exists[i] {
+			ba.Reset()
+			if err := ba.BaseBatch.Commit(roots[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := ba.finishCommit(roots[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
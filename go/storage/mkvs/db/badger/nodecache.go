@@ -0,0 +1,83 @@
+package badger
+
+import (
+	"github.com/oasisprotocol/oasis-core/go/common/cache/lru"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// nodeCache is a bounded in-memory cache of unmarshalled nodes, keyed by node hash.
+//
+// As nodes are content-addressed, cached nodes never need to be invalidated. Entries are still
+// removed when nodes are removed from the database in order to not waste memory on stale nodes.
+type nodeCache struct {
+	cache   *lru.Cache
+	metrics *dbMetrics
+}
+
+// newNodeCache creates a new node cache that holds at most maxSize bytes of nodes. If maxSize is
+// zero, nil is returned and all operations on the cache are no-ops.
+func newNodeCache(maxSize uint64, metrics *dbMetrics) *nodeCache {
+	if maxSize == 0 {
+		return nil
+	}
+	return &nodeCache{
+		cache:   lru.New(lru.Capacity(maxSize, true)),
+		metrics: metrics,
+	}
+}
+
+// get returns a copy of the cached node with the given hash.
+func (c *nodeCache) get(h hash.Hash) (node.Node, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	cached, ok := c.cache.Get(h)
+	c.metrics.nodeCache(ok)
+	if !ok {
+		return nil, false
+	}
+	return copyNode(cached.(node.Node)), true
+}
+
+// put caches a copy of the given node.
+func (c *nodeCache) put(n node.Node) {
+	if c == nil {
+		return
+	}
+
+	_ = c.cache.Put(n.GetHash(), copyNode(n))
+}
+
+// remove removes the node with the given hash from the cache.
+func (c *nodeCache) remove(h hash.Hash) {
+	if c == nil {
+		return
+	}
+
+	c.cache.Remove(h)
+}
+
+// copyNode makes a copy of the given node so that callers cannot modify the cached node. Leaf
+// nodes embedded in internal nodes are copied as well, the same as when unmarshalling a node.
+func copyNode(n node.Node) node.Node {
+	switch n := n.(type) {
+	case *node.InternalNode:
+		leafNode := n.LeafNode.ExtractUnchecked()
+		if leafNode != nil && n.LeafNode.Node != nil {
+			leafNode.Node = n.LeafNode.Node.ExtractUnchecked()
+		}
+		return &node.InternalNode{
+			Clean:          true,
+			Hash:           n.Hash,
+			Label:          n.Label,
+			LabelBitLength: n.LabelBitLength,
+			LeafNode:       leafNode,
+			Left:           n.Left.ExtractUnchecked(),
+			Right:          n.Right.ExtractUnchecked(),
+		}
+	default:
+		return n.ExtractUnchecked()
+	}
+}
@@ -0,0 +1,130 @@
+package badger
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+)
+
+const (
+	// defaultMultipartNodeFilterLimit is the default maximum number of existing nodes for which
+	// a multipart node filter is built.
+	defaultMultipartNodeFilterLimit = 64 * 1024 * 1024
+
+	// nodeFilterBitsPerNode is the minimum number of filter bits per node. Together with the
+	// number of probes this gives a false positive rate of about 0.25%.
+	nodeFilterBitsPerNode = 16
+	// nodeFilterProbes is the number of bits probed for each node.
+	nodeFilterProbes = hash.Size / 8
+)
+
+// nodeFilter is a bloom filter over the hashes of nodes that existed in the database when a
+// multipart restore started.
+//
+// A node not contained in the filter is guaranteed to not have existed before the restore, so it
+// can be recorded in the multipart node log without reading the database. Nodes that may be
+// contained in the filter still need to be looked up.
+//
+// As node hashes are uniformly distributed, the probed bits are taken directly from the hash.
+type nodeFilter struct {
+	bits []uint64
+	mask uint64
+}
+
+// newNodeFilter creates a new empty node filter sized for the given number of nodes.
+func newNodeFilter(numNodes uint64) *nodeFilter {
+	if numNodes == 0 {
+		return &nodeFilter{}
+	}
+	numBits := uint64(1) << bits.Len64(numNodes*nodeFilterBitsPerNode-1)
+	numBits = max(numBits, 64)
+	return &nodeFilter{
+		bits: make([]uint64, numBits/64),
+		mask: numBits - 1,
+	}
+}
+
+// add adds the given node hash to the filter.
+func (f *nodeFilter) add(h *hash.Hash) {
+	for i := range nodeFilterProbes {
+		bit := binary.LittleEndian.Uint64(h[i*8:]) & f.mask
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// mayContain returns false iff the given node hash has definitely not been added to the filter.
+func (f *nodeFilter) mayContain(h *hash.Hash) bool {
+	if len(f.bits) == 0 {
+		return false
+	}
+	for i := range nodeFilterProbes {
+		bit := binary.LittleEndian.Uint64(h[i*8:]) & f.mask
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// newMultipartNodeFilter creates the node filter for a multipart restore at the given version.
+// In case the filter is disabled or the database contains too many nodes, nil is returned and all
+// inserted nodes are looked up.
+func (d *badgerNodeDB) newMultipartNodeFilter(version uint64) (*nodeFilter, error) {
+	if d.multipartNodeFilterLimit < 0 {
+		return nil, nil
+	}
+	f, err := d.buildNodeFilter(version, uint64(d.multipartNodeFilterLimit))
+	if err != nil {
+		return nil, fmt.Errorf("mkvs/badger: failed to build multipart node filter: %w", err)
+	}
+	if f == nil {
+		d.logger.Info("too many existing nodes for a multipart node filter, checking nodes individually",
+			"limit", d.multipartNodeFilterLimit,
+		)
+	}
+	return f, nil
+}
+
+// buildNodeFilter builds a node filter over all nodes visible at the given version. In case there
+// are more than limit nodes, nil is returned.
+func (d *badgerNodeDB) buildNodeFilter(version uint64, limit uint64) (*nodeFilter, error) {
+	tx := d.db.NewTransactionAt(versionToTs(version), false)
+	defer tx.Discard()
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = nodeKeyFmt.Encode()
+
+	// Count the nodes first so that the filter can be sized accordingly.
+	var numNodes uint64
+	it := tx.NewIterator(opts)
+	for it.Rewind(); it.Valid(); it.Next() {
+		numNodes++
+		if numNodes > limit {
+			it.Close()
+			return nil, nil
+		}
+	}
+	it.Close()
+
+	f := newNodeFilter(numNodes)
+	if numNodes == 0 {
+		return f, nil
+	}
+
+	it = tx.NewIterator(opts)
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		var h hash.Hash
+		if !nodeKeyFmt.Decode(it.Item().Key(), &h) {
+			return nil, fmt.Errorf("mkvs/badger: malformed node key")
+		}
+		f.add(&h)
+	}
+	return f, nil
+}
@@ -0,0 +1,169 @@
+package badger
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+)
+
+// pruneIntent is the record of a pruning that has started removing data but has not yet
+// committed its metadata.
+//
+// NOTE: Public fields of this structure are part of the on-disk format.
+type pruneIntent struct {
+	_ struct{} `cbor:",toarray"` // nolint
+
+	// StartVersion is the first version being pruned.
+	StartVersion uint64
+	// EndVersion is the last version being pruned.
+	EndVersion uint64
+}
+
+// pruneStage collects the removals of a single version being pruned so that they are only applied
+// once the whole version has been traversed successfully.
+type pruneStage struct {
+	lock sync.Mutex
+
+	// versioned are the keys of items removed at the timestamp of the version.
+	versioned [][]byte
+	// meta are the keys of removed metadata items.
+	meta [][]byte
+}
+
+// deleteVersioned stages the removal of an item stored at the timestamp of the version. It is
+// safe for concurrent use.
+func (s *pruneStage) deleteVersioned(key []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.versioned = append(s.versioned, key)
+}
+
+// deleteMeta stages the removal of a metadata item.
+func (s *pruneStage) deleteMeta(key []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.meta = append(s.meta, key)
+}
+
+// apply adds the staged removals to the given managed batch and metadata transaction.
+func (s *pruneStage) apply(tx *badger.Txn, batch *badger.WriteBatch, ts uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, key := range s.versioned {
+		if err := batch.DeleteAt(key, ts); err != nil {
+			return err
+		}
+	}
+	for _, key := range s.meta {
+		if err := tx.Delete(key); err != nil {
+			return fmt.Errorf("mkvs/badger: failed to remove metadata: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadPruneIntent loads the prune intent, returning nil if there is none.
+func (d *badgerNodeDB) loadPruneIntent() (*pruneIntent, error) {
+	tx := d.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	item, err := tx.Get(pruneIntentKeyFmt.Encode())
+	switch err {
+	case nil:
+	case badger.ErrKeyNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("mkvs/badger: failed to read prune intent: %w", err)
+	}
+
+	var intent pruneIntent
+	if err = item.Value(func(data []byte) error {
+		return cbor.UnmarshalTrusted(data, &intent)
+	}); err != nil {
+		return nil, fmt.Errorf("mkvs/badger: corrupted prune intent: %w", err)
+	}
+	return &intent, nil
+}
+
+// putPruneIntent durably records the intent to prune the given range of versions.
+//
+// Assumes metaUpdateLock is held when called.
+func (d *badgerNodeDB) putPruneIntent(startVersion, endVersion uint64) error {
+	intent := pruneIntent{
+		StartVersion: startVersion,
+		EndVersion:   endVersion,
+	}
+
+	tx := d.metaDB.NewTransactionAt(tsMetadata, true)
+	defer tx.Discard()
+
+	if err := tx.Set(pruneIntentKeyFmt.Encode(), cbor.Marshal(&intent)); err != nil {
+		return err
+	}
+	if err := tx.CommitAt(tsMetadata, nil); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to commit prune intent: %w", err)
+	}
+	return d.syncMetadata()
+}
+
+// recoverPrune completes the metadata updates of a pruning that has been interrupted after it
+// started removing data but before its metadata was committed.
+//
+// Pruned trees cannot be traversed again once some of their nodes are gone, so any nodes that were
+// not yet removed remain in the database. The pruned versions are never served again though.
+func (d *badgerNodeDB) recoverPrune() error {
+	intent, err := d.loadPruneIntent()
+	if err != nil || intent == nil {
+		return err
+	}
+
+	stale := d.meta.getEarliestVersion() > intent.EndVersion
+	if d.readOnly {
+		if !stale {
+			d.logger.Warn("database has an interrupted pruning, open it read-write to complete it",
+				"start_version", intent.StartVersion,
+				"end_version", intent.EndVersion,
+			)
+		}
+		return nil
+	}
+
+	tx := d.metaDB.NewTransactionAt(tsMetadata, true)
+	defer tx.Discard()
+
+	if !stale {
+		d.logger.Warn("completing interrupted pruning, some pruned nodes may remain in the database",
+			"start_version", intent.StartVersion,
+			"end_version", intent.EndVersion,
+		)
+
+		for version := intent.StartVersion; version <= intent.EndVersion; version++ {
+			if err = tx.Delete(rootsMetadataKeyFmt.Encode(version)); err != nil {
+				return fmt.Errorf("mkvs/badger: failed to remove roots metadata: %w", err)
+			}
+			if err = deleteVersionStats(tx, version); err != nil {
+				return fmt.Errorf("mkvs/badger: failed to remove version statistics: %w", err)
+			}
+		}
+		if err = d.meta.setEarliestVersion(tx, intent.EndVersion+1); err != nil {
+			return fmt.Errorf("mkvs/badger: failed to set earliest version: %w", err)
+		}
+	}
+	if err = tx.Delete(pruneIntentKeyFmt.Encode()); err != nil {
+		return err
+	}
+	if err = tx.CommitAt(tsMetadata, nil); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to commit metadata: %w", err)
+	}
+	d.rootsMetaCache.invalidate(intent.StartVersion, intent.EndVersion)
+
+	// Discard everything invalidated at or below the last pruned version.
+	d.db.SetDiscardTs(versionToTs(intent.EndVersion + 1))
+	return nil
+}
@@ -0,0 +1,148 @@
+package badger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// pruner is a background worker that prunes versions of the node database which are older than
+// the configured number of versions to keep.
+//
+// Versions are pruned one at a time with metaUpdateLock being released between versions so that
+// concurrent finalizations are never starved.
+type pruner struct {
+	db *badgerNodeDB
+
+	interval     time.Duration
+	keepVersions uint64
+
+	kickCh chan struct{}
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+	doneCh   chan struct{}
+}
+
+// newPruner creates a new background pruner for the given node database. In case background
+// pruning is not enabled in the configuration, nil is returned.
+func newPruner(d *badgerNodeDB, cfg *api.Config) *pruner {
+	if cfg.PruneInterval <= 0 || cfg.ReadOnly {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &pruner{
+		db:           d,
+		interval:     cfg.PruneInterval,
+		keepVersions: cfg.KeepVersions,
+		kickCh:       make(chan struct{}, 1),
+		ctx:          ctx,
+		cancel:       cancel,
+		doneCh:       make(chan struct{}),
+	}
+}
+
+// start starts the pruner.
+func (p *pruner) start() {
+	if p == nil {
+		return
+	}
+
+	go p.run()
+}
+
+// stop stops the pruner and waits for it to terminate. Any in-progress pruning is interrupted
+// after the version that is currently being pruned.
+func (p *pruner) stop() {
+	if p == nil {
+		return
+	}
+
+	p.stopOnce.Do(func() {
+		p.cancel()
+		<-p.doneCh
+	})
+}
+
+// kick notifies the pruner that a new version has been finalized.
+func (p *pruner) kick() {
+	if p == nil {
+		return
+	}
+
+	select {
+	case p.kickCh <- struct{}{}:
+	default:
+	}
+}
+
+// backlog returns the earliest version and the number of versions that should be pruned.
+func (p *pruner) backlog() (uint64, uint64) {
+	earliestVersion := p.db.meta.getEarliestVersion()
+	lastFinalizedVersion, exists := p.db.meta.getLastFinalizedVersion()
+	if !exists || lastFinalizedVersion < p.keepVersions {
+		return earliestVersion, 0
+	}
+	horizon := lastFinalizedVersion - p.keepVersions
+	if earliestVersion >= horizon {
+		return earliestVersion, 0
+	}
+	return earliestVersion, horizon - earliestVersion
+}
+
+func (p *pruner) run() {
+	defer close(p.doneCh)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.kickCh:
+		}
+
+		p.prune()
+	}
+}
+
+// prune prunes all versions in the current backlog, one version at a time.
+func (p *pruner) prune() {
+	for {
+		version, backlog := p.backlog()
+		p.db.metrics.pruneBacklog(backlog)
+		if backlog == 0 {
+			return
+		}
+
+		_, err := p.db.pruneRange(p.ctx, version, version, true)
+		switch {
+		case err == nil:
+		case errors.Is(err, api.ErrMultipartInProgress):
+			// Do not prune while a multipart restore is in progress, retry later.
+			return
+		case errors.Is(err, api.ErrVersionPinned):
+			// Do not prune while the version is pinned, retry later.
+			return
+		case errors.Is(err, api.ErrNotEarliest):
+			// The version has been pruned concurrently.
+			continue
+		case p.ctx.Err() != nil:
+			return
+		default:
+			p.db.logger.Error("failed to prune version in background",
+				"err", err,
+				"version", version,
+			)
+			_ = p.db.failure(metricsOpBackgroundPrune, err)
+			return
+		}
+	}
+}
@@ -24,9 +24,13 @@ func RenameNamespace(cfg *api.Config, newNamespace common.Namespace) error {
 	if db.db, err = badger.OpenManaged(opts); err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
+	if err = db.openMetadataStore(cfg, opts, isSplitMetadata(cfg)); err != nil {
+		_ = db.db.Close()
+		return err
+	}
 	defer db.Close()
 
-	tx := db.db.NewTransactionAt(tsMetadata, true)
+	tx := db.metaDB.NewTransactionAt(tsMetadata, true)
 	defer tx.Discard()
 
 	item, err := tx.Get(metadataKeyFmt.Encode())
@@ -0,0 +1,66 @@
+package badger
+
+import (
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+var _ api.RepairNodeDB = (*badgerNodeDB)(nil)
+
+// Implements api.RepairNodeDB.
+func (d *badgerNodeDB) NewBatchForRepair(oldRoot node.Root, version uint64, trustedRoots []node.Root) (api.Batch, error) {
+	if d.readOnly {
+		return nil, api.ErrReadOnly
+	}
+	if !d.allowRepair {
+		return nil, api.ErrRepairNotAllowed
+	}
+
+	trusted := make(map[api.TypedHash]bool, len(trustedRoots))
+	for _, root := range trustedRoots {
+		if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+			return nil, err
+		}
+		if root.Version != version {
+			return nil, fmt.Errorf("mkvs/badger: trusted root version mismatch (expected: %d got: %d)",
+				version, root.Version,
+			)
+		}
+		trusted[api.TypedHashFromRoot(root)] = true
+	}
+
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	if d.multipartVersion != multipartVersionNone {
+		return nil, api.ErrMultipartInProgress
+	}
+	if err := d.checkRepairVersionLocked(version); err != nil {
+		return nil, err
+	}
+
+	return d.newBatch(&badgerBatch{
+		oldRoot:      oldRoot,
+		version:      version,
+		trustedRoots: trusted,
+	}, &batchResources{
+		db:  d,
+		bat: d.db.NewWriteBatchAt(versionToTs(version)),
+	}), nil
+}
+
+// checkRepairVersionLocked checks that the given version is finalized and has not been pruned.
+//
+// Assumes metaUpdateLock is held when called.
+func (d *badgerNodeDB) checkRepairVersionLocked(version uint64) error {
+	lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion()
+	if !exists || lastFinalizedVersion < version {
+		return api.ErrNotFinalized
+	}
+	if version < d.meta.getEarliestVersion() {
+		return api.ErrVersionNotFound
+	}
+	return nil
+}
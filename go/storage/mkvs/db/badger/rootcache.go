@@ -0,0 +1,113 @@
+package badger
+
+import (
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// rootCache is a bounded in-memory set of roots known to exist, grouped by version.
+//
+// The cache only ever contains roots that are present in the database, so a cache hit can be used
+// to skip reading the roots metadata and root node keys. A cache miss says nothing about whether
+// the root exists and callers must fall through to the database.
+type rootCache struct {
+	sync.RWMutex
+
+	maxVersions int
+	versions    map[uint64]map[api.TypedHash]struct{}
+}
+
+// newRootCache creates a new root cache that holds roots for at most maxVersions versions. If
+// maxVersions is zero, nil is returned and all operations on the cache are no-ops.
+func newRootCache(maxVersions uint64) *rootCache {
+	if maxVersions == 0 {
+		return nil
+	}
+	return &rootCache{
+		maxVersions: int(maxVersions), //nolint:gosec
+		versions:    make(map[uint64]map[api.TypedHash]struct{}),
+	}
+}
+
+// has returns true iff the given root is known to exist.
+func (c *rootCache) has(version uint64, rootHash api.TypedHash) bool {
+	if c == nil {
+		return false
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	_, ok := c.versions[version][rootHash]
+	return ok
+}
+
+// add records that the given root exists. In case the cache holds more versions than allowed,
+// the earliest versions are evicted.
+func (c *rootCache) add(version uint64, rootHash api.TypedHash) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	roots, ok := c.versions[version]
+	if !ok {
+		roots = make(map[api.TypedHash]struct{})
+		c.versions[version] = roots
+	}
+	roots[rootHash] = struct{}{}
+
+	for len(c.versions) > c.maxVersions {
+		earliest := version
+		for v := range c.versions {
+			earliest = min(earliest, v)
+		}
+		delete(c.versions, earliest)
+	}
+}
+
+// remove removes the given root from the cache.
+func (c *rootCache) remove(version uint64, rootHash api.TypedHash) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	roots, ok := c.versions[version]
+	if !ok {
+		return
+	}
+	delete(roots, rootHash)
+	if len(roots) == 0 {
+		delete(c.versions, version)
+	}
+}
+
+// removeVersion removes all roots of the given version from the cache.
+func (c *rootCache) removeVersion(version uint64) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.versions, version)
+}
+
+// len returns the number of versions in the cache.
+func (c *rootCache) len() int {
+	if c == nil {
+		return 0
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	return len(c.versions)
+}
@@ -0,0 +1,106 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// rootsBatchSize is the maximum number of versions that are read from the database before the
+// iterator is released and the decoded roots are sent to the consumer.
+const rootsBatchSize = 128
+
+// Implements api.NodeDB.
+func (d *badgerNodeDB) Roots(ctx context.Context, startVersion, endVersion uint64) (<-chan api.VersionRoots, error) {
+	if startVersion > endVersion {
+		return nil, fmt.Errorf("mkvs/badger: invalid version range [%d, %d]", startVersion, endVersion)
+	}
+
+	ch := make(chan api.VersionRoots)
+	go func() {
+		defer close(ch)
+
+		version := max(startVersion, d.meta.getEarliestVersion())
+		for {
+			// Decode a batch of versions and release the iterator before sending anything so that
+			// a slow consumer does not keep the iterator open.
+			batch, next, more, err := d.loadRootsBatch(version, endVersion)
+			if err != nil {
+				d.logger.Error("failed to load roots",
+					"err", err,
+					"version", version,
+				)
+				_ = d.failure(metricsOpRoots, err)
+				return
+			}
+
+			for _, vr := range batch {
+				select {
+				case ch <- vr:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if !more {
+				return
+			}
+			version = next
+		}
+	}()
+	return ch, nil
+}
+
+// loadRootsBatch loads the roots of up to rootsBatchSize versions in the given (inclusive) range.
+//
+// In case there may be more versions in the range, it returns the version to continue from.
+func (d *badgerNodeDB) loadRootsBatch(startVersion, endVersion uint64) ([]api.VersionRoots, uint64, bool, error) {
+	tx := d.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: rootsMetadataKeyFmt.Encode()})
+	defer it.Close()
+
+	var (
+		batch   []api.VersionRoots
+		scanned int
+	)
+	for it.Seek(rootsMetadataKeyFmt.Encode(startVersion)); it.Valid(); it.Next() {
+		var version uint64
+		if !rootsMetadataKeyFmt.Decode(it.Item().Key(), &version) {
+			return nil, 0, false, fmt.Errorf("mkvs/badger: corrupted roots metadata key")
+		}
+		if version > endVersion {
+			break
+		}
+		if scanned == rootsBatchSize {
+			return batch, version, true, nil
+		}
+		scanned++
+
+		var rootsMeta rootsMetadata
+		if err := it.Item().Value(func(val []byte) error { return cbor.Unmarshal(val, &rootsMeta) }); err != nil {
+			return nil, 0, false, fmt.Errorf("mkvs/badger: error reading roots metadata: %w", err)
+		}
+		if len(rootsMeta.Roots) == 0 {
+			continue
+		}
+
+		roots := make([]node.Root, 0, len(rootsMeta.Roots))
+		for rootHash := range rootsMeta.Roots {
+			roots = append(roots, node.Root{
+				Namespace: d.namespace,
+				Version:   version,
+				Type:      rootHash.Type(),
+				Hash:      rootHash.Hash(),
+			})
+		}
+		batch = append(batch, api.VersionRoots{Version: version, Roots: roots})
+	}
+	return batch, 0, false, nil
+}
@@ -0,0 +1,129 @@
+package badger
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// rootsMetadataCacheVersions is the number of versions for which decoded roots metadata is cached.
+const rootsMetadataCacheVersions = 8
+
+// rootsMetadataCache is a small cache of decoded roots metadata, keyed by version.
+//
+// Entries are only updated and invalidated while holding metaUpdateLock, right after the roots
+// metadata has been committed, so the cache never holds uncommitted metadata. Readers that do not
+// hold metaUpdateLock may fill the cache on a miss. The generation is bumped on every update so
+// that metadata read before a concurrent update is never inserted after it.
+//
+// Cached roots metadata is shared and must not be modified.
+type rootsMetadataCache struct {
+	sync.RWMutex
+
+	maxVersions int
+	generation  uint64
+	versions    map[uint64]*rootsMetadata
+}
+
+// newRootsMetadataCache creates a new roots metadata cache that holds metadata for at most
+// maxVersions versions. If maxVersions is zero, nil is returned and all operations on the cache
+// are no-ops.
+func newRootsMetadataCache(maxVersions int) *rootsMetadataCache {
+	if maxVersions == 0 {
+		return nil
+	}
+	return &rootsMetadataCache{
+		maxVersions: maxVersions,
+		versions:    make(map[uint64]*rootsMetadata),
+	}
+}
+
+// get returns the cached roots metadata for the given version and the current generation, which
+// needs to be passed to fill in case of a miss.
+func (c *rootsMetadataCache) get(version uint64) (*rootsMetadata, uint64, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	rootsMeta, ok := c.versions[version]
+	return rootsMeta, c.generation, ok
+}
+
+// fill caches roots metadata loaded after a miss, unless the cache has been updated since the
+// given generation. Versions without any roots are not cached.
+func (c *rootsMetadataCache) fill(generation uint64, rootsMeta *rootsMetadata) {
+	if c == nil || len(rootsMeta.Roots) == 0 {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.generation != generation {
+		return
+	}
+	c.putLocked(rootsMeta)
+}
+
+// put caches committed roots metadata, replacing any previously cached metadata of the same
+// version.
+//
+// Must be called while holding metaUpdateLock.
+func (c *rootsMetadataCache) put(rootsMeta *rootsMetadata) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	c.putLocked(rootsMeta)
+}
+
+func (c *rootsMetadataCache) putLocked(rootsMeta *rootsMetadata) {
+	c.versions[rootsMeta.version] = rootsMeta
+
+	for len(c.versions) > c.maxVersions {
+		earliest := rootsMeta.version
+		for v := range c.versions {
+			earliest = min(earliest, v)
+		}
+		delete(c.versions, earliest)
+	}
+}
+
+// invalidate removes the roots metadata of the given versions from the cache.
+//
+// Must be called while holding metaUpdateLock.
+func (c *rootsMetadataCache) invalidate(startVersion, endVersion uint64) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	for v := range c.versions {
+		if v >= startVersion && v <= endVersion {
+			delete(c.versions, v)
+		}
+	}
+}
+
+// clone returns a deep copy of the roots metadata that may be modified.
+func (rm *rootsMetadata) clone() *rootsMetadata {
+	roots := make(map[api.TypedHash][]api.TypedHash, len(rm.Roots))
+	for rootHash, derivedRoots := range rm.Roots {
+		roots[rootHash] = slices.Clone(derivedRoots)
+	}
+	return &rootsMetadata{
+		Roots:   roots,
+		version: rm.version,
+	}
+}
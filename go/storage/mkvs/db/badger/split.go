@@ -0,0 +1,271 @@
+package badger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// metadataDirName is the name of the subdirectory holding the metadata store in case metadata is
+// split from the nodes.
+const metadataDirName = "meta"
+
+// metadataStoreOptions derives the options of the metadata store from the options of the main
+// store. The metadata store only holds small values, so it is opened with small tables and caches.
+func metadataStoreOptions(opts badger.Options) badger.Options {
+	dir := filepath.Join(opts.Dir, metadataDirName)
+	if opts.InMemory {
+		dir = ""
+	}
+	return opts.
+		WithDir(dir).
+		WithValueDir(dir).
+		WithMemTableSize(16 << 20).
+		WithBaseTableSize(2 << 20).
+		WithBlockCacheSize(8 << 20).
+		WithValueLogFileSize(64 << 20).
+		WithNumCompactors(2)
+}
+
+// isSplitMetadata returns true iff the configuration requests a split metadata store.
+func isSplitMetadata(cfg *api.Config) bool {
+	return cfg.BadgerOptions != nil && cfg.BadgerOptions.SplitMetadata
+}
+
+// openMetadataStore opens the store holding database metadata. In case the layout on disk does
+// not match the requested one, metadata is moved between the stores first.
+//
+// Must be called after the main store has been opened and before any metadata is loaded.
+func (d *badgerNodeDB) openMetadataStore(cfg *api.Config, opts badger.Options, split bool) error {
+	d.metaDB = d.db
+
+	metaOpts := metadataStoreOptions(opts)
+	var exists bool
+	if !opts.InMemory {
+		_, err := os.Stat(metaOpts.Dir)
+		switch {
+		case err == nil:
+			exists = true
+		case errors.Is(err, fs.ErrNotExist):
+		default:
+			return fmt.Errorf("failed to stat metadata store: %w", err)
+		}
+	}
+
+	if opts.ReadOnly && split != exists {
+		// Metadata cannot be moved without writing, use whatever layout is there.
+		d.logger.Warn("metadata store layout differs from configuration, open the database read-write to migrate it",
+			"split_metadata", split,
+		)
+		split = exists
+	}
+	if !split && !exists {
+		return nil
+	}
+
+	metaDB, err := badger.OpenManaged(metaOpts)
+	if err != nil {
+		return fmt.Errorf("failed to open metadata store: %w", err)
+	}
+	// Make sure that we can discard any deleted/invalid metadata.
+	metaDB.SetDiscardTs(tsMetadata)
+
+	if !split {
+		// Move metadata back into the main store and remove the metadata store.
+		if err = d.moveMetadata(metaDB, d.db); err != nil {
+			_ = metaDB.Close()
+			return err
+		}
+		if err = metaDB.Close(); err != nil {
+			return fmt.Errorf("failed to close metadata store: %w", err)
+		}
+		if err = os.RemoveAll(metaOpts.Dir); err != nil {
+			return fmt.Errorf("failed to remove metadata store: %w", err)
+		}
+		return nil
+	}
+
+	if !opts.ReadOnly {
+		if err = d.moveMetadata(d.db, metaDB); err != nil {
+			_ = metaDB.Close()
+			return err
+		}
+	}
+	d.metaDB = metaDB
+	d.syncSplitStores = !opts.InMemory && !opts.SyncWrites && !cfg.NoFsync
+	return nil
+}
+
+// moveMetadata moves all metadata from the source to the destination store.
+//
+// Metadata is copied before it is removed from the source. The database metadata is copied last
+// and removed last, so an interrupted move is simply repeated the next time the database is opened.
+func (d *badgerNodeDB) moveMetadata(src, dst *badger.DB) error {
+	srcTx := src.NewTransactionAt(tsMetadata, false)
+	defer srcTx.Discard()
+
+	metadataKey := metadataKeyFmt.Encode()
+	if _, err := srcTx.Get(migrationMetaKeyFmt.Encode()); err == nil {
+		return api.ErrUpgradeInProgress
+	}
+	metaItem, err := srcTx.Get(metadataKey)
+	switch err {
+	case nil:
+	case badger.ErrKeyNotFound:
+		// Nothing to move.
+		return nil
+	default:
+		return fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	d.logger.Info("moving metadata between stores",
+		"to_metadata_store", src == d.db,
+	)
+
+	dstTx := dst.NewTransactionAt(tsMetadata, false)
+	_, err = dstTx.Get(metadataKey)
+	dstTx.Discard()
+	switch err {
+	case nil:
+		// Metadata has already been copied, only the removal was interrupted.
+	case badger.ErrKeyNotFound:
+		if err = copyMetadata(srcTx, metaItem, dst); err != nil {
+			return fmt.Errorf("failed to copy metadata: %w", err)
+		}
+	default:
+		return fmt.Errorf("failed to read metadata: %w", err)
+	}
+
+	if err = removeMetadata(srcTx, src); err != nil {
+		return fmt.Errorf("failed to remove moved metadata: %w", err)
+	}
+	return nil
+}
+
+// copyMetadata copies all metadata visible in the given source transaction into the destination
+// store. The database metadata item is copied last, once everything else has been made durable.
+func copyMetadata(srcTx *badger.Txn, metaItem *badger.Item, dst *badger.DB) error {
+	metadataKey := metadataKeyFmt.Encode()
+
+	batch := dst.NewWriteBatchAt(tsMetadata)
+	defer batch.Cancel()
+
+	it := srcTx.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		if item.Version() != tsMetadata || bytes.Equal(item.Key(), metadataKey) {
+			continue
+		}
+		value, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if err = batch.Set(item.KeyCopy(nil), value); err != nil {
+			return err
+		}
+	}
+	if err := batch.Flush(); err != nil {
+		return err
+	}
+	if err := syncStore(dst); err != nil {
+		return err
+	}
+
+	value, err := metaItem.ValueCopy(nil)
+	if err != nil {
+		return err
+	}
+	metaBatch := dst.NewWriteBatchAt(tsMetadata)
+	defer metaBatch.Cancel()
+
+	if err = metaBatch.Set(metadataKey, value); err != nil {
+		return err
+	}
+	if err = metaBatch.Flush(); err != nil {
+		return err
+	}
+	return syncStore(dst)
+}
+
+// removeMetadata removes all metadata visible in the given source transaction from the source
+// store. The database metadata item is removed last.
+func removeMetadata(srcTx *badger.Txn, src *badger.DB) error {
+	batch := src.NewWriteBatchAt(tsMetadata)
+	defer batch.Cancel()
+
+	metadataKey := metadataKeyFmt.Encode()
+
+	it := srcTx.NewIterator(badger.IteratorOptions{})
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		if item.Version() != tsMetadata || bytes.Equal(item.Key(), metadataKey) {
+			continue
+		}
+		if err := batch.Delete(item.KeyCopy(nil)); err != nil {
+			return err
+		}
+	}
+	if err := batch.Flush(); err != nil {
+		return err
+	}
+	if err := syncStore(src); err != nil {
+		return err
+	}
+
+	metaBatch := src.NewWriteBatchAt(tsMetadata)
+	defer metaBatch.Cancel()
+
+	if err := metaBatch.Delete(metadataKey); err != nil {
+		return err
+	}
+	if err := metaBatch.Flush(); err != nil {
+		return err
+	}
+	return syncStore(src)
+}
+
+// syncStore syncs the given store unless it only lives in memory.
+func syncStore(db *badger.DB) error {
+	if db.Opts().InMemory {
+		return nil
+	}
+	return db.Sync()
+}
+
+// syncData makes sure that everything written to the main store is durable before metadata that
+// depends on it is committed to a split metadata store.
+//
+// Writes to a single store are persisted in order, so this is only needed in case the stores are
+// split and writes are not synced anyway.
+func (d *badgerNodeDB) syncData() error {
+	if !d.syncSplitStores {
+		return nil
+	}
+	if err := d.db.Sync(); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to sync data: %w", err)
+	}
+	return nil
+}
+
+// syncMetadata makes sure that everything written to a split metadata store (e.g. an intent) is
+// durable before data that depends on it is written to the main store.
+func (d *badgerNodeDB) syncMetadata() error {
+	if !d.syncSplitStores {
+		return nil
+	}
+	if err := d.metaDB.Sync(); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to sync metadata: %w", err)
+	}
+	return nil
+}
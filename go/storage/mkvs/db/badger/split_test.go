@@ -0,0 +1,293 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+func splitTestConfig(dir string, split bool) *api.Config {
+	syncWrites := false
+
+	cfg := *dbCfg
+	cfg.MemoryOnly = false
+	cfg.NoFsync = false
+	cfg.DB = dir
+	cfg.BadgerOptions = &api.BadgerOptions{
+		// Disable synced writes so that the stores are explicitly synced.
+		SyncWrites:    &syncWrites,
+		SplitMetadata: split,
+	}
+	return &cfg
+}
+
+func hasMetadataKey(db *badger.DB) bool {
+	tx := db.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	_, err := tx.Get(metadataKeyFmt.Encode())
+	return err == nil
+}
+
+func requireRootValues(ctx context.Context, require *require.Assertions, ndb api.NodeDB, root node.Root, values [][]byte) {
+	tree := mkvs.NewWithRoot(nil, ndb, root)
+	defer tree.Close()
+
+	for i, v := range values {
+		value, err := tree.Get(ctx, []byte(strconv.Itoa(i)))
+		require.NoError(err, "Get()")
+		require.Equal(v, value)
+	}
+}
+
+func TestSplitMetadata(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "oasis-storage-database-test")
+	require.NoError(err, "TempDir()")
+	defer os.RemoveAll(dir)
+	metaDir := filepath.Join(dir, metadataDirName)
+
+	// Create a database with metadata stored alongside the nodes.
+	ndb, err := New(splitTestConfig(dir, false))
+	require.NoError(err, "New()")
+	root1 := fillDB(ctx, require, testValues, nil, 0, 1, ndb)
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+	ndb.Close()
+	require.NoDirExists(metaDir)
+
+	// Reopening with split metadata should move metadata into the metadata store.
+	ndb, err = New(splitTestConfig(dir, true))
+	require.NoError(err, "New() with split metadata")
+	badgerdb := ndb.(*badgerNodeDB)
+	require.DirExists(metaDir)
+	require.NotSame(badgerdb.db, badgerdb.metaDB, "metadata store should be separate")
+	require.False(hasMetadataKey(badgerdb.db), "metadata should be removed from the main store")
+	require.True(hasMetadataKey(badgerdb.metaDB), "metadata should be in the metadata store")
+
+	lastFinalizedVersion, ok := ndb.GetLatestVersion()
+	require.True(ok)
+	require.EqualValues(1, lastFinalizedVersion)
+	require.True(ndb.HasRoot(root1), "migrated root should exist")
+	requireRootValues(ctx, require, ndb, root1, testValues)
+
+	// The split database should keep working.
+	updatedValues := [][]byte{[]byte("updated value")}
+	root2 := fillDB(ctx, require, updatedValues, &root1, 1, 2, ndb)
+	err = ndb.Finalize([]node.Root{root2})
+	require.NoError(err, "Finalize({root2})")
+	stats, err := badgerdb.Stats(true)
+	require.NoError(err, "Stats()")
+	require.NotNil(stats.NumRoots)
+	require.EqualValues(2, *stats.NumRoots)
+	ndb.Close()
+
+	err = CheckVersion(ctx, splitTestConfig(dir, true), 2)
+	require.NoError(err, "CheckVersion() with split metadata")
+
+	// Reopening without split metadata should move metadata back.
+	ndb, err = New(splitTestConfig(dir, false))
+	require.NoError(err, "New() without split metadata")
+	defer ndb.Close()
+	badgerdb = ndb.(*badgerNodeDB)
+	require.NoDirExists(metaDir)
+	require.Same(badgerdb.db, badgerdb.metaDB)
+	require.True(hasMetadataKey(badgerdb.db), "metadata should be in the main store")
+
+	lastFinalizedVersion, ok = ndb.GetLatestVersion()
+	require.True(ok)
+	require.EqualValues(2, lastFinalizedVersion)
+	require.True(ndb.HasRoot(root1))
+	require.True(ndb.HasRoot(root2))
+	requireRootValues(ctx, require, ndb, root2, append(updatedValues, testValues[1:]...))
+}
+
+func TestSplitMetadataInterruptedMove(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "oasis-storage-database-test")
+	require.NoError(err, "TempDir()")
+	defer os.RemoveAll(dir)
+
+	ndb, err := New(splitTestConfig(dir, false))
+	require.NoError(err, "New()")
+	root1 := fillDB(ctx, require, testValues, nil, 0, 1, ndb)
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+	ndb.Close()
+
+	// Copy metadata into the metadata store, but crash before removing it from the main store.
+	func() {
+		cfg := splitTestConfig(dir, true)
+		db := &badgerNodeDB{logger: logging.GetLogger("mkvs/db/badger/test")}
+		opts := commonConfigToBadgerOptions(cfg, db)
+		mainDB, err := badger.OpenManaged(opts)
+		require.NoError(err, "OpenManaged()")
+		defer mainDB.Close()
+		metaDB, err := badger.OpenManaged(metadataStoreOptions(opts))
+		require.NoError(err, "OpenManaged() metadata store")
+		defer metaDB.Close()
+
+		tx := mainDB.NewTransactionAt(tsMetadata, false)
+		defer tx.Discard()
+		item, err := tx.Get(metadataKeyFmt.Encode())
+		require.NoError(err, "Get(metadata)")
+		err = copyMetadata(tx, item, metaDB)
+		require.NoError(err, "copyMetadata()")
+	}()
+
+	// Reopening should complete the move.
+	ndb, err = New(splitTestConfig(dir, true))
+	require.NoError(err, "New() after interrupted move")
+	defer ndb.Close()
+	badgerdb := ndb.(*badgerNodeDB)
+	require.False(hasMetadataKey(badgerdb.db), "metadata should be removed from the main store")
+
+	tx := badgerdb.db.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+	_, err = tx.Get(rootsMetadataKeyFmt.Encode(uint64(1)))
+	require.ErrorIs(err, badger.ErrKeyNotFound, "roots metadata should be removed from the main store")
+
+	require.True(ndb.HasRoot(root1), "migrated root should exist")
+	requireRootValues(ctx, require, ndb, root1, testValues)
+}
+
+func TestSplitMetadataFinalizeRecovery(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "oasis-storage-database-test")
+	require.NoError(err, "TempDir()")
+	defer os.RemoveAll(dir)
+
+	cfg := splitTestConfig(dir, true)
+	ndb, err := New(cfg)
+	require.NoError(err, "New()")
+	badgerdb := ndb.(*badgerNodeDB)
+	require.True(badgerdb.syncSplitStores, "split stores should be synced explicitly")
+
+	root1 := fillDB(ctx, require, testValues, nil, 0, 1, ndb)
+	err = ndb.Finalize([]node.Root{root1})
+	require.NoError(err, "Finalize({root1})")
+
+	updatedValues := [][]byte{[]byte("updated value")}
+	rootA := fillDB(ctx, require, updatedValues, &root1, 1, 2, ndb)
+	rootB := fillDB(ctx, require, [][]byte{[]byte("discarded value")}, &root1, 1, 2, ndb)
+
+	// Interrupt finalization after removals have been flushed to the main store.
+	errInterrupted := fmt.Errorf("interrupted")
+	badgerdb.finalizeInterruptFn = func() error {
+		return errInterrupted
+	}
+	err = ndb.Finalize([]node.Root{rootA})
+	require.ErrorIs(err, errInterrupted, "Finalize({rootA}) should be interrupted")
+	ndb.Close()
+
+	// Reopening the database should complete the finalization using the intent recorded in the
+	// metadata store.
+	ndb, err = New(cfg)
+	require.NoError(err, "New() after interruption")
+	defer ndb.Close()
+	badgerdb = ndb.(*badgerNodeDB)
+
+	lastFinalizedVersion, _ := badgerdb.meta.getLastFinalizedVersion()
+	require.EqualValues(2, lastFinalizedVersion, "finalization should be completed")
+	intent, err := badgerdb.loadFinalizeIntent()
+	require.NoError(err, "loadFinalizeIntent()")
+	require.Nil(intent, "finalize intent should be removed")
+	require.True(ndb.HasRoot(rootA), "finalized root should exist")
+	require.False(ndb.HasRoot(rootB), "discarded root should be removed")
+	requireRootValues(ctx, require, ndb, rootA, append(updatedValues, testValues[1:]...))
+}
+
+func TestPruneRecovery(t *testing.T) {
+	for _, split := range []bool{false, true} {
+		t.Run(fmt.Sprintf("SplitMetadata=%t", split), func(t *testing.T) {
+			testPruneRecovery(t, split)
+		})
+	}
+}
+
+func testPruneRecovery(t *testing.T, split bool) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	dir, err := os.MkdirTemp("", "oasis-storage-database-test")
+	require.NoError(err, "TempDir()")
+	defer os.RemoveAll(dir)
+
+	cfg := splitTestConfig(dir, split)
+	ndb, err := New(cfg)
+	require.NoError(err, "New()")
+	badgerdb := ndb.(*badgerNodeDB)
+
+	var (
+		roots []node.Root
+		prev  *node.Root
+	)
+	for version := uint64(0); version < 4; version++ {
+		root := fillDB(ctx, require, testValues[:version%3+1], prev, version, version, ndb)
+		root.Version = version
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(err, "Finalize({root%d})", version)
+		roots = append(roots, root)
+		prev = &roots[len(roots)-1]
+	}
+
+	// Interrupt pruning after removals have been flushed.
+	errInterrupted := fmt.Errorf("interrupted")
+	badgerdb.pruneInterruptFn = func() error {
+		return errInterrupted
+	}
+	_, err = ndb.PruneRange(ctx, 0, 1)
+	require.ErrorIs(err, errInterrupted, "PruneRange() should be interrupted")
+	require.EqualValues(0, ndb.GetEarliestVersion(), "metadata should not be committed")
+	intent, err := badgerdb.loadPruneIntent()
+	require.NoError(err, "loadPruneIntent()")
+	require.NotNil(intent, "prune intent should be recorded")
+	require.EqualValues(0, intent.StartVersion)
+	require.EqualValues(1, intent.EndVersion)
+	ndb.Close()
+
+	// Reopening the database should complete the pruning.
+	ndb, err = New(cfg)
+	require.NoError(err, "New() after interruption")
+	defer ndb.Close()
+	badgerdb = ndb.(*badgerNodeDB)
+
+	require.EqualValues(2, ndb.GetEarliestVersion(), "pruning should be completed")
+	intent, err = badgerdb.loadPruneIntent()
+	require.NoError(err, "loadPruneIntent()")
+	require.Nil(intent, "prune intent should be removed")
+	tx := badgerdb.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+	for _, root := range roots[:2] {
+		require.False(ndb.HasRoot(root), "pruned root %d should not exist", root.Version)
+		_, err = tx.Get(rootsMetadataKeyFmt.Encode(root.Version))
+		require.ErrorIs(err, badger.ErrKeyNotFound, "roots metadata of pruned version %d should be removed", root.Version)
+	}
+	for _, root := range roots[2:] {
+		require.True(ndb.HasRoot(root), "root %d should still exist", root.Version)
+	}
+
+	// Later versions should prune normally.
+	pruned, err := ndb.PruneRange(ctx, 2, 2)
+	require.NoError(err, "PruneRange(2, 2)")
+	require.Equal(1, pruned)
+	require.EqualValues(3, ndb.GetEarliestVersion())
+	requireRootValues(ctx, require, ndb, roots[3], testValues)
+}
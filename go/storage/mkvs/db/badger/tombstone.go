@@ -0,0 +1,338 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/syncer"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
+)
+
+var _ api.TombstoneNodeDB = (*badgerNodeDB)(nil)
+
+// pendingKeys are the keys deleted and inserted by a root which are turned into tombstones once
+// the version of the root is finalized.
+type pendingKeys struct {
+	// Deleted are the keys deleted by the root.
+	Deleted [][]byte `json:"deleted,omitempty"`
+	// Inserted are the keys inserted or updated by the root.
+	Inserted [][]byte `json:"inserted,omitempty"`
+}
+
+func newPendingKeys(writeLog writelog.WriteLog) *pendingKeys {
+	var pk pendingKeys
+	for _, entry := range writeLog {
+		switch entry.Value {
+		case nil:
+			pk.Deleted = append(pk.Deleted, entry.Key)
+		default:
+			pk.Inserted = append(pk.Inserted, entry.Key)
+		}
+	}
+	return &pk
+}
+
+// tombstone is the record of a key deletion.
+type tombstone struct {
+	// Key is the deleted key.
+	Key []byte `json:"key"`
+	// Root is the hash of the finalized root in the deletion version.
+	Root hash.Hash `json:"root"`
+	// ReinsertedVersion is the version in which the key was inserted again, zero if it has not
+	// been inserted since the deletion.
+	ReinsertedVersion uint64 `json:"reinserted_version,omitempty"`
+	// Proof is the proof of the key being absent in the root.
+	Proof syncer.Proof `json:"proof"`
+}
+
+func tombstoneKeyHash(typ node.RootType, key []byte) api.TypedHash {
+	return api.TypedHashFromParts(typ, hash.NewFromBytes(key))
+}
+
+// tombstoneHorizon returns the earliest deletion version for which tombstones are retained when
+// the given version is the last finalized version.
+func (d *badgerNodeDB) tombstoneHorizon(lastFinalizedVersion uint64) uint64 {
+	if lastFinalizedVersion < d.tombstoneRetention {
+		return 0
+	}
+	return lastFinalizedVersion - d.tombstoneRetention + 1
+}
+
+// Implements api.TombstoneNodeDB.
+func (d *badgerNodeDB) GetEarliestTombstoneVersion() uint64 {
+	lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion()
+	if !exists {
+		return 0
+	}
+	return d.tombstoneHorizon(lastFinalizedVersion)
+}
+
+// Implements api.TombstoneNodeDB.
+func (d *badgerNodeDB) GetDeletionProof(_ context.Context, root node.Root, key []byte) (*api.DeletionProof, error) {
+	if d.tombstoneRetention == 0 {
+		return nil, api.ErrTombstoneNotFound
+	}
+	if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+		return nil, err
+	}
+	lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion()
+	if !exists || root.Version > lastFinalizedVersion {
+		return nil, api.ErrNotFinalized
+	}
+	// Roots of pruned versions can no longer be checked, but tombstones are still retained.
+	if root.Version >= d.meta.getEarliestVersion() && !d.HasRoot(root) {
+		return nil, api.ErrRootNotFound
+	}
+	horizon := d.tombstoneHorizon(lastFinalizedVersion)
+	if root.Version < horizon {
+		return nil, api.ErrTombstoneNotFound
+	}
+
+	tx := d.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	// Find the latest deletion at or before the requested version.
+	th := tombstoneKeyHash(root.Type, key)
+	it := tx.NewIterator(badger.IteratorOptions{
+		Prefix:  tombstoneKeyFmt.Encode(&th),
+		Reverse: true,
+	})
+	defer it.Close()
+
+	it.Seek(tombstoneKeyFmt.Encode(&th, root.Version))
+	if !it.Valid() {
+		return nil, api.ErrTombstoneNotFound
+	}
+
+	var (
+		decKeyHash     api.TypedHash
+		deletedVersion uint64
+	)
+	if !tombstoneKeyFmt.Decode(it.Item().Key(), &decKeyHash, &deletedVersion) {
+		return nil, fmt.Errorf("mkvs/badger: corrupted tombstone key")
+	}
+	if deletedVersion < horizon {
+		return nil, api.ErrTombstoneNotFound
+	}
+
+	var ts tombstone
+	if err := it.Item().Value(func(data []byte) error {
+		return cbor.UnmarshalTrusted(data, &ts)
+	}); err != nil {
+		return nil, fmt.Errorf("mkvs/badger: corrupted tombstone: %w", err)
+	}
+	if ts.ReinsertedVersion != 0 && ts.ReinsertedVersion <= root.Version {
+		return nil, api.ErrTombstoneNotFound
+	}
+
+	return &api.DeletionProof{
+		Key:            ts.Key,
+		Version:        root.Version,
+		DeletedVersion: deletedVersion,
+		Root:           ts.Root,
+		Proof:          ts.Proof,
+	}, nil
+}
+
+// updateTombstonesLocked records tombstones for keys deleted by the finalized roots of the given
+// version, marks earlier tombstones of re-inserted keys and removes tombstones beyond the
+// retention horizon. Tombstone updates are added to the given batch.
+//
+// Assumes metaUpdateLock is held when called.
+func (d *badgerNodeDB) updateTombstonesLocked(
+	tx *badger.Txn,
+	batch *badger.WriteBatch,
+	version uint64,
+	rootsMeta *rootsMetadata,
+	finalizedRoots map[api.TypedHash]bool,
+) error {
+	if d.tombstoneRetention == 0 {
+		return nil
+	}
+
+	horizon := d.tombstoneHorizon(version)
+	if err := d.pruneTombstonesLocked(tx, batch, horizon); err != nil {
+		return err
+	}
+
+	// Determine the final root of each type in this version and collect keys changed by any of
+	// the finalized roots of that type. A key is considered deleted when it was deleted by any of
+	// the roots and is absent in the final root.
+	finalRoots := make(map[node.RootType][]api.TypedHash)
+	changedKeys := make(map[node.RootType]map[string]bool)
+	for rootHash := range finalizedRoots {
+		derivedRoots, ok := rootsMeta.Roots[rootHash]
+		if h := rootHash.Hash(); !ok && !h.IsEmpty() {
+			continue
+		}
+
+		final := true
+		for _, derivedRoot := range derivedRoots {
+			if finalizedRoots[derivedRoot] {
+				final = false
+				break
+			}
+		}
+		if final {
+			finalRoots[rootHash.Type()] = append(finalRoots[rootHash.Type()], rootHash)
+		}
+
+		item, err := tx.Get(rootPendingKeysKeyFmt.Encode(version, &rootHash))
+		switch err {
+		case nil:
+		case badger.ErrKeyNotFound:
+			continue
+		default:
+			return err
+		}
+
+		var pk pendingKeys
+		if err = item.Value(func(data []byte) error {
+			return cbor.UnmarshalTrusted(data, &pk)
+		}); err != nil {
+			return fmt.Errorf("mkvs/badger: corrupted root pending keys: %w", err)
+		}
+
+		keys := changedKeys[rootHash.Type()]
+		if keys == nil {
+			keys = make(map[string]bool)
+			changedKeys[rootHash.Type()] = keys
+		}
+		for _, key := range pk.Deleted {
+			keys[string(key)] = true
+		}
+		for _, key := range pk.Inserted {
+			if _, ok := keys[string(key)]; !ok {
+				keys[string(key)] = false
+			}
+		}
+	}
+
+	for typ, keys := range changedKeys {
+		if len(finalRoots[typ]) != 1 {
+			d.logger.Warn("not recording tombstones for ambiguous final roots",
+				"version", version,
+				"root_type", typ,
+				"roots", finalRoots[typ],
+			)
+			continue
+		}
+
+		finalRoot := node.Root{
+			Namespace: d.namespace,
+			Version:   version,
+			Type:      typ,
+			Hash:      finalRoots[typ][0].Hash(),
+		}
+		for key, deleted := range keys {
+			proof, present, err := api.ProveKey(context.Background(), d, finalRoot, []byte(key))
+			if err != nil {
+				return fmt.Errorf("mkvs/badger: failed to build proof: %w", err)
+			}
+
+			th := tombstoneKeyHash(typ, []byte(key))
+			switch {
+			case present:
+				if err = d.markReinsertedLocked(tx, batch, th, version, horizon); err != nil {
+					return err
+				}
+			case deleted:
+				ts := tombstone{
+					Key:   []byte(key),
+					Root:  finalRoot.Hash,
+					Proof: *proof,
+				}
+				if err = batch.Set(tombstoneKeyFmt.Encode(&th, version), cbor.Marshal(&ts)); err != nil {
+					return err
+				}
+				if err = batch.Set(tombstoneVersionKeyFmt.Encode(version, &th), []byte{}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// markReinsertedLocked marks the latest tombstone of the given key as re-inserted in the given
+// version.
+//
+// Assumes metaUpdateLock is held when called.
+func (d *badgerNodeDB) markReinsertedLocked(
+	tx *badger.Txn,
+	batch *badger.WriteBatch,
+	th api.TypedHash,
+	version uint64,
+	horizon uint64,
+) error {
+	it := tx.NewIterator(badger.IteratorOptions{
+		Prefix:  tombstoneKeyFmt.Encode(&th),
+		Reverse: true,
+	})
+	defer it.Close()
+
+	it.Seek(tombstoneKeyFmt.Encode(&th, version))
+	if !it.Valid() {
+		return nil
+	}
+
+	var (
+		decKeyHash     api.TypedHash
+		deletedVersion uint64
+	)
+	if !tombstoneKeyFmt.Decode(it.Item().Key(), &decKeyHash, &deletedVersion) {
+		return fmt.Errorf("mkvs/badger: corrupted tombstone key")
+	}
+	// Tombstones beyond the horizon are being removed.
+	if deletedVersion < horizon {
+		return nil
+	}
+
+	var ts tombstone
+	if err := it.Item().Value(func(data []byte) error {
+		return cbor.UnmarshalTrusted(data, &ts)
+	}); err != nil {
+		return fmt.Errorf("mkvs/badger: corrupted tombstone: %w", err)
+	}
+	if ts.ReinsertedVersion != 0 {
+		return nil
+	}
+
+	ts.ReinsertedVersion = version
+	return batch.Set(it.Item().KeyCopy(nil), cbor.Marshal(&ts))
+}
+
+// pruneTombstonesLocked removes all tombstones of deletions before the given horizon.
+//
+// Assumes metaUpdateLock is held when called.
+func (d *badgerNodeDB) pruneTombstonesLocked(tx *badger.Txn, batch *badger.WriteBatch, horizon uint64) error {
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: tombstoneVersionKeyFmt.Encode()})
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		var (
+			deletedVersion uint64
+			th             api.TypedHash
+		)
+		if !tombstoneVersionKeyFmt.Decode(it.Item().Key(), &deletedVersion, &th) {
+			return fmt.Errorf("mkvs/badger: corrupted tombstone index key")
+		}
+		if deletedVersion >= horizon {
+			break
+		}
+
+		if err := batch.Delete(it.Item().KeyCopy(nil)); err != nil {
+			return err
+		}
+		if err := batch.Delete(tombstoneKeyFmt.Encode(&th, deletedVersion)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package badger
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+var _ api.UpdatedNodesNodeDB = (*badgerNodeDB)(nil)
+
+// defaultUpdatedNodesChunkSize is the default maximum size in bytes of a single serialized root
+// updated nodes record.
+const defaultUpdatedNodesChunkSize = 4 * 1024 * 1024
+
+// updatedNodeSize is the size of a single serialized updated node. It does not depend on whether
+// the node was removed.
+var updatedNodeSize = len(cbor.Marshal(&updatedNode{}))
+
+// updatedNodes is the set of nodes updated by a batch, mapping node hashes to whether the node was
+// removed.
+type updatedNodes map[hash.Hash]bool
+
+// add records an updated node and returns the change in the number of recorded nodes.
+//
+// Duplicate updates are only recorded once. A node that is both inserted and removed in the same
+// batch must have existed before the batch and remains unchanged, so the updates cancel out.
+func (un *updatedNodes) add(h hash.Hash, removed bool) int {
+	if *un == nil {
+		*un = make(updatedNodes)
+	}
+
+	prev, ok := (*un)[h]
+	switch {
+	case !ok:
+		(*un)[h] = removed
+		return 1
+	case prev == removed:
+		return 0
+	default:
+		delete(*un, h)
+		return -1
+	}
+}
+
+// chunks returns the updated nodes ordered by hash and split into chunks whose serialized entries
+// do not exceed the given chunk size. There is always at least one (possibly empty) chunk.
+func (un updatedNodes) chunks(chunkSize int) [][]updatedNode {
+	nodes := make([]updatedNode, 0, len(un))
+	for h, removed := range un {
+		nodes = append(nodes, updatedNode{Removed: removed, Hash: h})
+	}
+	slices.SortFunc(nodes, func(a, b updatedNode) int {
+		return bytes.Compare(a.Hash[:], b.Hash[:])
+	})
+
+	var chunks [][]updatedNode
+	perChunk := max(chunkSize/updatedNodeSize, 1)
+	for len(nodes) > perChunk {
+		chunks = append(chunks, nodes[:perChunk])
+		nodes = nodes[perChunk:]
+	}
+	return append(chunks, nodes)
+}
+
+// putUpdatedNodes stores the updated nodes of the given root, splitting them across multiple
+// records in case they would exceed the configured chunk size.
+//
+// The first chunk is stored in the root updated nodes record as part of the given metadata
+// transaction so that the record always exists for committed roots. Any further chunks are
+// flushed separately as they could otherwise exceed the transaction size limit.
+func (d *badgerNodeDB) putUpdatedNodes(tx *badger.Txn, version uint64, rootHash api.TypedHash, nodes updatedNodes) error {
+	chunks := nodes.chunks(d.updatedNodesChunkSize)
+	if err := tx.Set(rootUpdatedNodesKeyFmt.Encode(version, &rootHash), cbor.Marshal(chunks[0])); err != nil {
+		return fmt.Errorf("mkvs/badger: set returned error: %w", err)
+	}
+
+	batch := d.metaDB.NewWriteBatchAt(tsMetadata)
+	defer batch.Cancel()
+
+	// Remove chunks left behind by a previously failed commit of the same root.
+	if err := func() error {
+		it := tx.NewIterator(badger.IteratorOptions{Prefix: rootUpdatedNodesChunkKeyFmt.Encode(version, &rootHash)})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := batch.Delete(it.Item().KeyCopy(nil)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}(); err != nil {
+		return err
+	}
+
+	for i, chunk := range chunks[1:] {
+		key := rootUpdatedNodesChunkKeyFmt.Encode(version, &rootHash, uint64(i+1))
+		if err := batch.Set(key, cbor.Marshal(chunk)); err != nil {
+			return fmt.Errorf("mkvs/badger: set returned error: %w", err)
+		}
+	}
+	if err := batch.Flush(); err != nil {
+		return fmt.Errorf("mkvs/badger: failed to flush updated nodes batch: %w", err)
+	}
+	return nil
+}
+
+// visitUpdatedNodes calls the given function for each chunk of updated nodes of the given root
+// and returns the keys of all of its updated nodes records.
+func visitUpdatedNodes(tx *badger.Txn, version uint64, rootHash api.TypedHash, fn func([]updatedNode)) ([][]byte, error) {
+	decode := func(item *badger.Item) error {
+		var nodes []updatedNode
+		if err := item.Value(func(data []byte) error {
+			return cbor.UnmarshalTrusted(data, &nodes)
+		}); err != nil {
+			return fmt.Errorf("%w: corrupted root updated nodes index for root %s: %w", api.ErrCorruptedDB, rootHash, err)
+		}
+		fn(nodes)
+		return nil
+	}
+
+	key := rootUpdatedNodesKeyFmt.Encode(version, &rootHash)
+	item, err := tx.Get(key)
+	switch err {
+	case nil:
+	case badger.ErrKeyNotFound:
+		return nil, fmt.Errorf("%w: missing root updated nodes index for root %s", api.ErrCorruptedDB, rootHash)
+	default:
+		return nil, fmt.Errorf("mkvs/badger: failed to read root updated nodes index: %w", err)
+	}
+	if err = decode(item); err != nil {
+		return nil, err
+	}
+	keys := [][]byte{key}
+
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: rootUpdatedNodesChunkKeyFmt.Encode(version, &rootHash)})
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		if err = decode(it.Item()); err != nil {
+			return nil, err
+		}
+		keys = append(keys, it.Item().KeyCopy(nil))
+	}
+	return keys, nil
+}
+
+// Implements api.UpdatedNodesNodeDB.
+func (d *badgerNodeDB) GetUpdatedNodes(root node.Root) ([]node.Node, error) {
+	if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+		return nil, err
+	}
+
+	added, err := d.getAddedNodes(root)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]node.Node, 0, len(added))
+	for _, h := range added {
+		n, err := d.GetNode(root, &node.Pointer{Clean: true, Hash: h})
+		if err != nil {
+			// The root may have been discarded by a concurrent finalization.
+			if lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion(); exists && root.Version <= lastFinalizedVersion {
+				return nil, api.ErrAlreadyFinalized
+			}
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// getAddedNodes returns the hashes of the nodes added by the given root that has not yet been
+// finalized.
+func (d *badgerNodeDB) getAddedNodes(root node.Root) ([]hash.Hash, error) {
+	// Make sure that the updated nodes are not removed by a concurrent finalization.
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	if lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion(); exists && root.Version <= lastFinalizedVersion {
+		return nil, api.ErrAlreadyFinalized
+	}
+
+	rootsMeta, err := d.getRootsMetadata(root.Version)
+	if err != nil {
+		return nil, err
+	}
+	rootHash := api.TypedHashFromRoot(root)
+	if _, ok := rootsMeta.Roots[rootHash]; !ok {
+		return nil, api.ErrRootNotFound
+	}
+
+	tx := d.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	var added []hash.Hash
+	if _, err = visitUpdatedNodes(tx, root.Version, rootHash, func(updatedNodes []updatedNode) {
+		for _, n := range updatedNodes {
+			if !n.Removed {
+				added = append(added, n.Hash)
+			}
+		}
+	}); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
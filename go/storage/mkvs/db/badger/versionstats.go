@@ -0,0 +1,94 @@
+package badger
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+var _ api.VersionStatsNodeDB = (*badgerNodeDB)(nil)
+
+// rootStats are the statistics of the nodes written by batches committing a root.
+type rootStats struct {
+	// Nodes is the number of written nodes.
+	Nodes uint64 `json:"nodes"`
+	// Bytes is the total size of the written serialized nodes.
+	Bytes uint64 `json:"bytes"`
+}
+
+// addRootStats adds the given statistics to the stored statistics of the given root.
+func addRootStats(tx *badger.Txn, version uint64, rootHash api.TypedHash, stats rootStats) error {
+	key := rootStatsKeyFmt.Encode(version, &rootHash)
+
+	var stored rootStats
+	item, err := tx.Get(key)
+	switch {
+	case err == nil:
+		if err = item.Value(func(data []byte) error {
+			return cbor.UnmarshalTrusted(data, &stored)
+		}); err != nil {
+			return fmt.Errorf("%w: corrupted statistics of root %s: %w", api.ErrCorruptedDB, rootHash, err)
+		}
+	case errors.Is(err, badger.ErrKeyNotFound):
+	default:
+		return fmt.Errorf("mkvs/badger: failed to get root statistics: %w", err)
+	}
+
+	stored.Nodes += stats.Nodes
+	stored.Bytes += stats.Bytes
+	if err = tx.Set(key, cbor.Marshal(&stored)); err != nil {
+		return fmt.Errorf("mkvs/badger: set returned error: %w", err)
+	}
+	return nil
+}
+
+// versionStatsKeys returns the keys of the statistics of all roots of the given version.
+func versionStatsKeys(tx *badger.Txn, version uint64) [][]byte {
+	var keys [][]byte
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: rootStatsKeyFmt.Encode(version)})
+	defer it.Close()
+	for it.Rewind(); it.Valid(); it.Next() {
+		keys = append(keys, it.Item().KeyCopy(nil))
+	}
+	return keys
+}
+
+// deleteVersionStats removes the statistics of all roots of the given version.
+func deleteVersionStats(tx *badger.Txn, version uint64) error {
+	for _, key := range versionStatsKeys(tx, version) {
+		if err := tx.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Implements api.VersionStatsNodeDB.
+func (d *badgerNodeDB) VersionStats(version uint64) (uint64, uint64, error) {
+	if version < d.meta.getEarliestVersion() {
+		return 0, 0, api.ErrVersionNotFound
+	}
+
+	tx := d.metaDB.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	var total rootStats
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: rootStatsKeyFmt.Encode(version)})
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		var stats rootStats
+		if err := it.Item().Value(func(data []byte) error {
+			return cbor.UnmarshalTrusted(data, &stats)
+		}); err != nil {
+			return 0, 0, fmt.Errorf("%w: corrupted version statistics: %w", api.ErrCorruptedDB, err)
+		}
+		total.Nodes += stats.Nodes
+		total.Bytes += stats.Bytes
+	}
+	return total.Nodes, total.Bytes, nil
+}
@@ -0,0 +1,106 @@
+package badger
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// writeLogPruneBatchSize is the maximum number of write logs removed using a single write batch.
+const writeLogPruneBatchSize = 1024
+
+// Implements api.NodeDB.
+func (d *badgerNodeDB) PruneWriteLogs(ctx context.Context, beforeVersion uint64) (int, error) {
+	if d.readOnly {
+		return 0, api.ErrReadOnly
+	}
+	if err := api.CheckPruneWriteLogs(d, beforeVersion); err != nil {
+		return 0, err
+	}
+
+	var (
+		pruned  int
+		startAt = writeLogKeyFmt.Encode()
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return pruned, err
+		}
+
+		n, next, more, err := d.pruneWriteLogsBatch(startAt, beforeVersion)
+		pruned += n
+		if err != nil {
+			return pruned, err
+		}
+		if !more {
+			break
+		}
+		startAt = next
+	}
+
+	if pruned > 0 {
+		d.logger.Info("pruned write logs",
+			"before_version", beforeVersion,
+			"pruned", pruned,
+		)
+	}
+	return pruned, nil
+}
+
+// pruneWriteLogsBatch removes up to writeLogPruneBatchSize write logs of versions before the given
+// version, starting at the given key.
+//
+// Each batch is committed on its own, so an interrupted pruning leaves the database consistent.
+// In case there may be more write logs to remove, it returns the key to continue from.
+func (d *badgerNodeDB) pruneWriteLogsBatch(startAt []byte, beforeVersion uint64) (int, []byte, bool, error) {
+	tx := d.db.NewTransactionAt(math.MaxUint64, false)
+	defer tx.Discard()
+
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: writeLogKeyFmt.Encode()})
+	defer it.Close()
+
+	batch := d.db.NewManagedWriteBatch()
+	defer batch.Cancel()
+
+	var (
+		pruned int
+		next   []byte
+		more   bool
+	)
+	for it.Seek(startAt); it.Valid(); it.Next() {
+		var (
+			version       uint64
+			endRootHash   api.TypedHash
+			startRootHash api.TypedHash
+		)
+		if !writeLogKeyFmt.Decode(it.Item().Key(), &version, &endRootHash, &startRootHash) {
+			return 0, nil, false, fmt.Errorf("%w: corrupted write log key", api.ErrCorruptedDB)
+		}
+		if version >= beforeVersion {
+			break
+		}
+		if pruned == writeLogPruneBatchSize {
+			next = it.Item().KeyCopy(nil)
+			more = true
+			break
+		}
+
+		// Write logs are stored at the timestamp of their version.
+		if err := batch.DeleteAt(it.Item().KeyCopy(nil), versionToTs(version)); err != nil {
+			return 0, nil, false, err
+		}
+		pruned++
+	}
+	if pruned == 0 {
+		return 0, nil, false, nil
+	}
+
+	if err := batch.Flush(); err != nil {
+		return 0, nil, false, fmt.Errorf("mkvs/badger: failed to flush batch: %w", err)
+	}
+	return pruned, next, more, nil
+}
@@ -6,12 +6,14 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
 	backendBadger "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/badger"
 	backendPathBadger "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/pathbadger"
+	backendPebble "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/pebble"
 )
 
 // Backends contains the factories for all the backend implementations.
 var Backends = []api.Factory{
 	backendBadger.Factory,
 	backendPathBadger.Factory,
+	backendPebble.Factory,
 }
 
 // GetBackendByName returns the backend implementation factory with the given name.
@@ -0,0 +1,925 @@
+// Package memory provides a node database that keeps everything in Go maps.
+//
+// The database is meant for tests that need a node database but not persistence, where even
+// a memory-only badger database is too heavy. It implements the NodeDB API, including version
+// finalization, pruning, pins, write logs and multipart inserts. None of the optional node
+// database interfaces (repairs, tombstones, lineage, health checks, multi-root commits and
+// garbage collection) are supported, and neither is background pruning.
+//
+// All data is lost when the database is closed, so it cannot be opened read-only or resume an
+// interrupted multipart restore. Unreachable nodes are removed eagerly on finalization and
+// pruning. The sizes reported by Size and Stats only approximate the encoded contents.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
+)
+
+const multipartVersionNone uint64 = 0
+
+var _ api.NodeDB = (*memoryNodeDB)(nil)
+
+// writeLogKey identifies a write log between two roots of the same version.
+type writeLogKey struct {
+	endRoot   api.TypedHash
+	startRoot api.TypedHash
+}
+
+// versionData are the roots and write logs stored under a given version.
+type versionData struct {
+	// roots maps each root to the roots derived from it.
+	roots map[api.TypedHash][]api.TypedHash
+	// writeLogs are the encoded write logs that result in the roots of the version.
+	writeLogs map[writeLogKey][]byte
+}
+
+type memoryNodeDB struct {
+	logger *logging.Logger
+
+	namespace        common.Namespace
+	discardWriteLogs bool
+	maxWriteLogHops  uint8
+
+	mu sync.RWMutex
+
+	nodes    map[hash.Hash][]byte
+	versions map[uint64]*versionData
+
+	earliestVersion      uint64
+	lastFinalizedVersion uint64
+	finalized            bool
+
+	multipartVersion      uint64
+	multipartNodes        map[hash.Hash]struct{}
+	multipartRoots        map[api.TypedHash]struct{}
+	multipartBytesWritten uint64
+
+	pins api.VersionPins
+}
+
+// New creates a new in-memory node database.
+func New(cfg *api.Config) (api.NodeDB, error) {
+	// The database is always memory-only, even if not explicitly configured as such.
+	memCfg := *cfg
+	memCfg.MemoryOnly = true
+	if err := memCfg.ValidateMemoryOnly(); err != nil {
+		return nil, fmt.Errorf("mkvs/memory: invalid configuration: %w", err)
+	}
+	maxWriteLogHops, err := cfg.WriteLogHops()
+	if err != nil {
+		return nil, err
+	}
+
+	return &memoryNodeDB{
+		logger:           logging.GetLogger("mkvs/db/memory"),
+		namespace:        cfg.Namespace,
+		discardWriteLogs: cfg.DiscardWriteLogs,
+		maxWriteLogHops:  maxWriteLogHops,
+		nodes:            make(map[hash.Hash][]byte),
+		versions:         make(map[uint64]*versionData),
+	}, nil
+}
+
+func (d *memoryNodeDB) sanityCheckNamespace(ns common.Namespace) error {
+	if !ns.Equal(&d.namespace) {
+		return api.ErrBadNamespace
+	}
+	return nil
+}
+
+// hasRootLocked returns true iff the given root has been stored under its version or any
+// earlier version that has not been pruned yet.
+func (d *memoryNodeDB) hasRootLocked(root node.Root) bool {
+	rootHash := api.TypedHashFromRoot(root)
+	if vd, ok := d.versions[root.Version]; ok {
+		if _, ok = vd.roots[rootHash]; ok {
+			return true
+		}
+	}
+	for version, vd := range d.versions {
+		if version >= root.Version || version < d.earliestVersion {
+			continue
+		}
+		if _, ok := vd.roots[rootHash]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *memoryNodeDB) GetNode(root node.Root, ptr *node.Pointer) (node.Node, error) {
+	if ptr == nil || !ptr.IsClean() {
+		panic("mkvs/memory: attempted to get invalid pointer from node database")
+	}
+	if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	// If the version is earlier than the earliest version, we don't have the node (it was pruned).
+	if root.Version < d.earliestVersion {
+		return nil, api.ErrNodeNotFound
+	}
+	if !d.hasRootLocked(root) {
+		return nil, api.ErrRootNotFound
+	}
+
+	data, ok := d.nodes[ptr.Hash]
+	if !ok {
+		return nil, api.ErrNodeNotFound
+	}
+	n, err := node.UnmarshalBinary(data)
+	if err != nil {
+		return nil, fmt.Errorf("mkvs/memory: failed to unmarshal node: %w", err)
+	}
+	return n, nil
+}
+
+func (d *memoryNodeDB) GetWriteLog(ctx context.Context, startRoot, endRoot node.Root) (writelog.Iterator, error) {
+	if d.discardWriteLogs {
+		return nil, api.ErrWriteLogNotFound
+	}
+	if !endRoot.Follows(&startRoot) {
+		return nil, api.ErrRootMustFollowOld
+	}
+	if err := d.sanityCheckNamespace(startRoot.Namespace); err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	logs, logRoots, err := d.findWriteLogLocked(ctx, startRoot, endRoot)
+	d.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var index int
+	return api.ReviveHashedDBWriteLogs(ctx,
+		func() (node.Root, api.HashedDBWriteLog, error) {
+			if index >= len(logs) {
+				return node.Root{}, nil, nil
+			}
+
+			root := node.Root{
+				Namespace: endRoot.Namespace,
+				Version:   endRoot.Version,
+				Type:      logRoots[index].Type(),
+				Hash:      logRoots[index].Hash(),
+			}
+			var log api.HashedDBWriteLog
+			if err := cbor.UnmarshalTrusted(logs[index], &log); err != nil {
+				return node.Root{}, nil, err
+			}
+			index++
+			return root, log, nil
+		},
+		func(root node.Root, h hash.Hash) (*node.LeafNode, error) {
+			leaf, err := d.GetNode(root, &node.Pointer{Hash: h, Clean: true})
+			if err != nil {
+				return nil, err
+			}
+			return leaf.(*node.LeafNode), nil
+		},
+		func() {},
+	)
+}
+
+// findWriteLogLocked searches for a path of write logs from the start root to the end root and
+// returns the encoded write logs together with the roots that they result in.
+func (d *memoryNodeDB) findWriteLogLocked(ctx context.Context, startRoot, endRoot node.Root) ([][]byte, []api.TypedHash, error) {
+	// If the version is earlier than the earliest version, we don't have the roots.
+	if endRoot.Version < d.earliestVersion {
+		return nil, nil, api.ErrWriteLogNotFound
+	}
+	if !d.hasRootLocked(endRoot) {
+		return nil, nil, api.ErrRootNotFound
+	}
+	vd, ok := d.versions[endRoot.Version]
+	if !ok {
+		return nil, nil, api.ErrWriteLogNotFound
+	}
+
+	// Start at the end root and search towards the start root, see the badger backend for
+	// why the number of hops is limited.
+	type wlItem struct {
+		depth       uint8
+		endRootHash api.TypedHash
+		logs        [][]byte
+		logRoots    []api.TypedHash
+	}
+	queue := []*wlItem{{depth: 0, endRootHash: api.TypedHashFromRoot(endRoot)}}
+	startRootHash := api.TypedHashFromRoot(startRoot)
+	for len(queue) > 0 {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+
+		curItem := queue[0]
+		queue = queue[1:]
+
+		for key, data := range vd.writeLogs {
+			if !key.endRoot.Equal(&curItem.endRootHash) {
+				continue
+			}
+
+			nextItem := wlItem{
+				depth:       curItem.depth + 1,
+				endRootHash: key.startRoot,
+				logs:        append(slices.Clone(curItem.logs), data),
+				logRoots:    append(slices.Clone(curItem.logRoots), curItem.endRootHash),
+			}
+			if nextItem.endRootHash.Equal(&startRootHash) {
+				return nextItem.logs, nextItem.logRoots, nil
+			}
+			if nextItem.depth < d.maxWriteLogHops {
+				queue = append(queue, &nextItem)
+			}
+		}
+	}
+	return nil, nil, api.ErrWriteLogNotFound
+}
+
+func (d *memoryNodeDB) GetLatestVersion() (uint64, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.lastFinalizedVersion, d.finalized
+}
+
+func (d *memoryNodeDB) GetEarliestVersion() uint64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.earliestVersion
+}
+
+func (d *memoryNodeDB) GetRootsForVersion(version uint64) ([]node.Root, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.rootsForVersionLocked(version), nil
+}
+
+func (d *memoryNodeDB) rootsForVersionLocked(version uint64) []node.Root {
+	vd, ok := d.versions[version]
+	if !ok || version < d.earliestVersion {
+		return nil
+	}
+
+	roots := make([]node.Root, 0, len(vd.roots))
+	for rootHash := range vd.roots {
+		roots = append(roots, node.Root{
+			Namespace: d.namespace,
+			Version:   version,
+			Type:      rootHash.Type(),
+			Hash:      rootHash.Hash(),
+		})
+	}
+	return roots
+}
+
+func (d *memoryNodeDB) Roots(ctx context.Context, startVersion, endVersion uint64) (<-chan api.VersionRoots, error) {
+	if startVersion > endVersion {
+		return nil, fmt.Errorf("mkvs/memory: invalid version range [%d, %d]", startVersion, endVersion)
+	}
+
+	// Take a snapshot so that the database is not locked while the consumer is slow.
+	var versionRoots []api.VersionRoots
+	d.mu.RLock()
+	for _, version := range slices.Sorted(maps.Keys(d.versions)) {
+		if version < startVersion || version > endVersion {
+			continue
+		}
+		if roots := d.rootsForVersionLocked(version); len(roots) > 0 {
+			versionRoots = append(versionRoots, api.VersionRoots{Version: version, Roots: roots})
+		}
+	}
+	d.mu.RUnlock()
+
+	ch := make(chan api.VersionRoots)
+	go func() {
+		defer close(ch)
+
+		for _, vr := range versionRoots {
+			select {
+			case ch <- vr:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func (d *memoryNodeDB) HasRoot(root node.Root) bool {
+	if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+		return false
+	}
+	// An empty root is always implicitly present.
+	if root.Hash.IsEmpty() {
+		return true
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	// If the version is earlier than the earliest version, we don't have the root.
+	if root.Version < d.earliestVersion {
+		return false
+	}
+	vd, ok := d.versions[root.Version]
+	if !ok {
+		return false
+	}
+	_, ok = vd.roots[api.TypedHashFromRoot(root)]
+	return ok
+}
+
+func (d *memoryNodeDB) HasRoots(roots []node.Root) ([]bool, error) {
+	return api.HasRoots(d, roots)
+}
+
+func (d *memoryNodeDB) Finalize(roots []node.Root) error {
+	if len(roots) == 0 {
+		return fmt.Errorf("mkvs/memory: need at least one root to finalize")
+	}
+	version := roots[0].Version
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.multipartVersion != multipartVersionNone && d.multipartVersion != version {
+		return api.ErrInvalidMultipartVersion
+	}
+	// Make sure that the previous version has been finalized (if we are not restoring).
+	if d.multipartVersion == multipartVersionNone && version > 0 && d.finalized && d.lastFinalizedVersion < (version-1) {
+		return api.ErrNotFinalized
+	}
+	// Make sure that this version has not yet been finalized.
+	if d.finalized && version <= d.lastFinalizedVersion {
+		return api.ErrAlreadyFinalized
+	}
+
+	// Determine the set of finalized roots. Finalization is transitive, so if
+	// a parent root is finalized the child should be considered finalized too.
+	finalizedRoots := make(map[api.TypedHash]bool)
+	for _, root := range roots {
+		if root.Version != version {
+			return fmt.Errorf("mkvs/memory: roots to finalize don't have matching versions")
+		}
+		finalizedRoots[api.TypedHashFromRoot(root)] = true
+	}
+
+	vd := d.versions[version]
+	var versionRoots map[api.TypedHash][]api.TypedHash
+	if vd != nil {
+		versionRoots = vd.roots
+	}
+	for updated := true; updated; {
+		updated = false
+
+		for rootHash, derivedRoots := range versionRoots {
+			for _, nextRoot := range derivedRoots {
+				if !finalizedRoots[rootHash] && finalizedRoots[nextRoot] {
+					finalizedRoots[rootHash] = true
+					updated = true
+				}
+			}
+		}
+	}
+
+	// Sanity check the input roots list.
+	for iroot := range finalizedRoots {
+		h := iroot.Hash()
+		if _, ok := versionRoots[iroot]; !ok && !h.IsEmpty() {
+			return api.ErrRootNotFound
+		}
+	}
+
+	// Discard non-finalized roots together with their write logs.
+	var discarded bool
+	for rootHash := range versionRoots {
+		if finalizedRoots[rootHash] {
+			continue
+		}
+		delete(vd.roots, rootHash)
+		for key := range vd.writeLogs {
+			if key.endRoot.Equal(&rootHash) {
+				delete(vd.writeLogs, key)
+			}
+		}
+		discarded = true
+	}
+
+	d.lastFinalizedVersion = version
+	d.finalized = true
+
+	// Clean up the multipart insert log, but keep the restored nodes.
+	d.clearMultipartLocked()
+
+	if discarded {
+		d.collectGarbageLocked()
+	}
+	return nil
+}
+
+func (d *memoryNodeDB) Pin(version uint64) (func(), error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Same as api.CheckPinVersion, which cannot be used as the lock is already held.
+	if !d.finalized || version > d.lastFinalizedVersion {
+		return nil, api.ErrNotFinalized
+	}
+	if version < d.earliestVersion {
+		return nil, api.ErrVersionNotFound
+	}
+	return d.pins.Pin(version)
+}
+
+func (d *memoryNodeDB) Prune(version uint64) error {
+	_, err := d.pruneRange(context.Background(), version, version, true)
+	return err
+}
+
+func (d *memoryNodeDB) PruneRange(ctx context.Context, startVersion, endVersion uint64) (int, error) {
+	return d.pruneRange(ctx, startVersion, endVersion, false)
+}
+
+// pruneRange prunes versions in the given (inclusive) range. In case exact is set, it is an
+// error if any version in the range cannot be pruned. Otherwise the range is truncated.
+func (d *memoryNodeDB) pruneRange(ctx context.Context, startVersion, endVersion uint64, exact bool) (int, error) {
+	if startVersion > endVersion {
+		return 0, fmt.Errorf("mkvs/memory: invalid prune range [%d, %d]", startVersion, endVersion)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.multipartVersion != multipartVersionNone {
+		return 0, api.ErrMultipartInProgress
+	}
+
+	// Make sure that the versions that we try to prune have been finalized.
+	if !d.finalized || d.lastFinalizedVersion < startVersion || (exact && d.lastFinalizedVersion < endVersion) {
+		return 0, api.ErrNotFinalized
+	}
+	// Make sure that the first version that we are trying to prune is the earliest version.
+	if startVersion != d.earliestVersion {
+		return 0, api.ErrNotEarliest
+	}
+	// Make sure that we are not trying to prune the only finalized version.
+	if endVersion >= d.lastFinalizedVersion {
+		if exact || startVersion == d.lastFinalizedVersion {
+			return 0, api.ErrCannotPruneLatestVersion
+		}
+		endVersion = d.lastFinalizedVersion - 1
+	}
+	// Make sure that we are not trying to prune any pinned versions.
+	endVersion, err := d.pins.PrunableEnd(startVersion, endVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		pruned   int
+		pruneErr error
+	)
+	for version := startVersion; version <= endVersion; version++ {
+		if pruneErr = ctx.Err(); pruneErr != nil {
+			break
+		}
+		delete(d.versions, version)
+		pruned++
+	}
+	if pruned == 0 {
+		return 0, pruneErr
+	}
+
+	d.earliestVersion = startVersion + uint64(pruned)
+	d.collectGarbageLocked()
+
+	return pruned, pruneErr
+}
+
+// collectGarbageLocked removes all nodes that are not reachable from any stored root.
+func (d *memoryNodeDB) collectGarbageLocked() {
+	var pending []hash.Hash
+	for _, vd := range d.versions {
+		for rootHash := range vd.roots {
+			pending = append(pending, rootHash.Hash())
+		}
+	}
+
+	reachable := make(map[hash.Hash]struct{}, len(d.nodes))
+	for len(pending) > 0 {
+		h := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if h.IsEmpty() {
+			continue
+		}
+		if _, ok := reachable[h]; ok {
+			continue
+		}
+		data, ok := d.nodes[h]
+		if !ok {
+			// Chunks of a multipart restore may reference nodes that have not been restored yet.
+			continue
+		}
+		reachable[h] = struct{}{}
+
+		n, err := node.UnmarshalBinary(data)
+		if err != nil {
+			d.logger.Error("failed to unmarshal node during garbage collection",
+				"err", err,
+				"hash", h,
+			)
+			continue
+		}
+		if in, ok := n.(*node.InternalNode); ok {
+			for _, ptr := range []*node.Pointer{in.LeafNode, in.Left, in.Right} {
+				if ptr != nil {
+					pending = append(pending, ptr.Hash)
+				}
+			}
+		}
+	}
+
+	for h := range d.nodes {
+		if _, ok := reachable[h]; !ok {
+			delete(d.nodes, h)
+		}
+	}
+}
+
+func (d *memoryNodeDB) PruneWriteLogs(ctx context.Context, beforeVersion uint64) (int, error) {
+	if err := api.CheckPruneWriteLogs(d, beforeVersion); err != nil {
+		return 0, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var pruned int
+	for _, version := range slices.Sorted(maps.Keys(d.versions)) {
+		if version >= beforeVersion {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return pruned, err
+		}
+
+		vd := d.versions[version]
+		pruned += len(vd.writeLogs)
+		clear(vd.writeLogs)
+	}
+	return pruned, nil
+}
+
+func (d *memoryNodeDB) StartMultipartInsert(version uint64) error {
+	if version == multipartVersionNone {
+		return api.ErrInvalidMultipartVersion
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.multipartVersion != multipartVersionNone {
+		if d.multipartVersion != version {
+			return api.ErrMultipartInProgress
+		}
+		// Multipart already initialized at the same version, so this was
+		// probably called e.g. as part of a further checkpoint restore.
+		return nil
+	}
+
+	d.multipartVersion = version
+	d.multipartNodes = make(map[hash.Hash]struct{})
+	d.multipartRoots = make(map[api.TypedHash]struct{})
+	d.multipartBytesWritten = 0
+
+	return nil
+}
+
+func (d *memoryNodeDB) AbortMultipartInsert() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.multipartVersion == multipartVersionNone {
+		return nil
+	}
+
+	for h := range d.multipartNodes {
+		delete(d.nodes, h)
+	}
+	if vd, ok := d.versions[d.multipartVersion]; ok {
+		for rootHash := range d.multipartRoots {
+			delete(vd.roots, rootHash)
+		}
+	}
+	d.clearMultipartLocked()
+
+	return nil
+}
+
+func (d *memoryNodeDB) clearMultipartLocked() {
+	d.multipartVersion = multipartVersionNone
+	d.multipartNodes = nil
+	d.multipartRoots = nil
+	d.multipartBytesWritten = 0
+}
+
+func (d *memoryNodeDB) GetMultipartVersion() uint64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.multipartVersion
+}
+
+func (d *memoryNodeDB) MultipartProgress() *api.MultipartProgress {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.multipartVersion == multipartVersionNone {
+		return nil
+	}
+	return &api.MultipartProgress{
+		Version:      d.multipartVersion,
+		NodesWritten: uint64(len(d.multipartNodes) + len(d.multipartRoots)),
+		BytesWritten: d.multipartBytesWritten,
+	}
+}
+
+func (d *memoryNodeDB) NewBatch(oldRoot node.Root, version uint64, chunk bool) (api.Batch, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.multipartVersion != multipartVersionNone && d.multipartVersion != version {
+		return nil, api.ErrInvalidMultipartVersion
+	}
+	if chunk != (d.multipartVersion != multipartVersionNone) {
+		return nil, api.ErrMultipartInProgress
+	}
+
+	return &memoryBatch{
+		db:      d,
+		oldRoot: oldRoot,
+		version: version,
+		chunk:   chunk,
+		nodes:   make(map[hash.Hash][]byte),
+	}, nil
+}
+
+func (d *memoryNodeDB) Size() (int64, error) {
+	stats, err := d.Stats(false)
+	if err != nil {
+		return 0, err
+	}
+	return stats.Size(), nil
+}
+
+// Implements api.NodeDB.
+//
+// All stored data is accounted for as LSM size.
+func (d *memoryNodeDB) Stats(countRoots bool) (*api.Stats, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	stats := api.Stats{
+		EarliestVersion: d.earliestVersion,
+	}
+	if d.finalized {
+		latestVersion := d.lastFinalizedVersion
+		stats.LatestVersion = &latestVersion
+	}
+	for _, data := range d.nodes {
+		stats.LSMSize += int64(len(data))
+	}
+	var numRoots uint64
+	for _, vd := range d.versions {
+		numRoots += uint64(len(vd.roots))
+		for _, data := range vd.writeLogs {
+			stats.LSMSize += int64(len(data))
+		}
+	}
+	if countRoots {
+		stats.NumRoots = &numRoots
+	}
+	return &stats, nil
+}
+
+func (d *memoryNodeDB) Sync() error {
+	return nil
+}
+
+func (d *memoryNodeDB) Close() {
+	d.pins.Close()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	clear(d.nodes)
+	clear(d.versions)
+}
+
+type memoryBatch struct {
+	api.BaseBatch
+
+	db *memoryNodeDB
+
+	oldRoot node.Root
+	version uint64
+	chunk   bool
+
+	nodes map[hash.Hash][]byte
+	// writeLog is the encoded write log, prepared when the write log is put into the batch.
+	writeLog []byte
+
+	// size is the number of bytes written by the batch.
+	size int64
+	// entries is the number of entries written by the batch.
+	entries int
+}
+
+func (ba *memoryBatch) PutNode(ptr *node.Pointer) error {
+	data, err := ptr.Node.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	h := ptr.Node.GetHash()
+	if _, ok := ba.nodes[h]; !ok {
+		ba.size += int64(len(data))
+		ba.entries++
+	}
+	ba.nodes[h] = data
+	return nil
+}
+
+func (ba *memoryBatch) PutWriteLog(writeLog writelog.WriteLog, annotations writelog.Annotations) error {
+	if ba.chunk {
+		return fmt.Errorf("mkvs/memory: cannot put write log in chunk mode")
+	}
+	if ba.db.discardWriteLogs {
+		return nil
+	}
+
+	// Replace any previously put write log.
+	if ba.writeLog != nil {
+		ba.size -= int64(len(ba.writeLog))
+		ba.entries--
+		ba.writeLog = nil
+	}
+	if writeLog != nil && annotations != nil {
+		ba.writeLog = cbor.Marshal(api.MakeHashedDBWriteLog(writeLog, annotations))
+		ba.size += int64(len(ba.writeLog))
+		ba.entries++
+	}
+	return nil
+}
+
+// Implements api.Batch.
+//
+// Nodes are not removed explicitly. Instead, nodes that are no longer reachable from any root
+// are removed when versions are finalized or pruned.
+func (ba *memoryBatch) RemoveNodes([]*node.Pointer) error {
+	if ba.chunk {
+		return fmt.Errorf("mkvs/memory: cannot remove nodes in chunk mode")
+	}
+	return nil
+}
+
+func (ba *memoryBatch) Commit(root node.Root) error {
+	ba.db.mu.Lock()
+	exists, err := ba.commitLocked(root)
+	ba.db.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if exists {
+		// Root already exists, no need to do anything since if the hash matches, everything will
+		// be identical and we would just be duplicating work.
+		ba.db.logger.Debug("root already exists, skipping commit",
+			"root", root,
+		)
+	}
+
+	ba.Reset()
+	return ba.BaseBatch.Commit(root)
+}
+
+// commitLocked checks that the given root may be committed and stores it together with the
+// batch contents.
+//
+// It returns true in case the root already exists and nothing has been stored.
+func (ba *memoryBatch) commitLocked(root node.Root) (bool, error) {
+	d := ba.db
+	if d.multipartVersion != multipartVersionNone && d.multipartVersion != root.Version {
+		return false, api.ErrInvalidMultipartVersion
+	}
+	if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+		return false, err
+	}
+	if !root.Follows(&ba.oldRoot) {
+		return false, api.ErrRootMustFollowOld
+	}
+	// Make sure that the version that we try to commit into has not yet been finalized.
+	if d.finalized && d.lastFinalizedVersion >= root.Version {
+		return false, api.ErrAlreadyFinalized
+	}
+
+	rootHash := api.TypedHashFromRoot(root)
+	vd := d.versions[root.Version]
+	if vd != nil {
+		// If we are importing a chunk, there can be multiple commits for the same root.
+		if _, ok := vd.roots[rootHash]; ok && !ba.chunk {
+			return true, nil
+		}
+	}
+
+	// Check the old root before changing anything.
+	oldRootHash := api.TypedHashFromRoot(ba.oldRoot)
+	var oldVd *versionData
+	if !ba.chunk && !ba.oldRoot.Hash.IsEmpty() {
+		if ba.oldRoot.Version < d.earliestVersion && ba.oldRoot.Version != root.Version {
+			return false, api.ErrPreviousVersionMismatch
+		}
+		oldVd = d.versions[ba.oldRoot.Version]
+		if oldVd == nil {
+			return false, api.ErrRootNotFound
+		}
+		if _, ok := oldVd.roots[oldRootHash]; !ok {
+			return false, api.ErrRootNotFound
+		}
+	}
+
+	if vd == nil {
+		vd = &versionData{
+			roots:     make(map[api.TypedHash][]api.TypedHash),
+			writeLogs: make(map[writeLogKey][]byte),
+		}
+		d.versions[root.Version] = vd
+	}
+	if _, ok := vd.roots[rootHash]; !ok {
+		// Create root with no derived roots.
+		vd.roots[rootHash] = []api.TypedHash{}
+		if d.multipartVersion != multipartVersionNone {
+			d.multipartRoots[rootHash] = struct{}{}
+		}
+	}
+	if oldVd != nil {
+		oldVd.roots[oldRootHash] = append(oldVd.roots[oldRootHash], rootHash)
+	}
+	if !ba.chunk && ba.writeLog != nil {
+		vd.writeLogs[writeLogKey{endRoot: rootHash, startRoot: oldRootHash}] = ba.writeLog
+	}
+
+	for h, data := range ba.nodes {
+		if _, ok := d.nodes[h]; !ok && d.multipartVersion != multipartVersionNone {
+			d.multipartNodes[h] = struct{}{}
+		}
+		d.nodes[h] = data
+	}
+	if d.multipartVersion != multipartVersionNone {
+		d.multipartBytesWritten += uint64(ba.size)
+	}
+	return false, nil
+}
+
+func (ba *memoryBatch) Size() (int64, int) {
+	return ba.size, ba.entries
+}
+
+func (ba *memoryBatch) Reset() {
+	clear(ba.nodes)
+	ba.writeLog = nil
+	ba.size = 0
+	ba.entries = 0
+}
+
+func (ba *memoryBatch) Close() {
+	ba.Reset()
+}
+
+func (ba *memoryBatch) VisitCleanNode(*node.Pointer, *node.Pointer) error {
+	return nil
+}
+
+func (ba *memoryBatch) VisitDirtyNode(*node.Pointer, *node.Pointer) error {
+	return nil
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+var (
+	testNs = common.NewTestNamespaceFromSeed([]byte("memory node db test ns"), 0)
+
+	dbCfg = &api.Config{
+		Namespace: testNs,
+	}
+)
+
+func commitVersion(ctx context.Context, require *require.Assertions, ndb api.NodeDB, prevRoot node.Root, version uint64, key, value string) node.Root {
+	tree := mkvs.NewWithRoot(nil, ndb, prevRoot)
+	defer tree.Close()
+
+	err := tree.Insert(ctx, []byte(key), []byte(value))
+	require.NoError(err, "Insert")
+	_, rootHash, err := tree.Commit(ctx, testNs, version)
+	require.NoError(err, "Commit")
+
+	root := node.Root{
+		Namespace: testNs,
+		Version:   version,
+		Type:      node.RootTypeState,
+		Hash:      rootHash,
+	}
+	err = ndb.Finalize([]node.Root{root})
+	require.NoError(err, "Finalize")
+	return root
+}
+
+func TestConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		modify func(*api.Config)
+	}{
+		{"ReadOnly", func(cfg *api.Config) { cfg.ReadOnly = true }},
+		{"AllowResumeMultipart", func(cfg *api.Config) { cfg.AllowResumeMultipart = true }},
+		{"MaxWriteLogHops", func(cfg *api.Config) { cfg.MaxWriteLogHops = api.MaxWriteLogHopsLimit + 1 }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := *dbCfg
+			tc.modify(&cfg)
+			_, err := New(&cfg)
+			require.Error(t, err, "New() should fail with invalid configuration")
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	memdb := ndb.(*memoryNodeDB)
+
+	root := node.Root{Namespace: testNs, Type: node.RootTypeState}
+	root.Hash.Empty()
+	roots := make([]node.Root, 0, 3)
+	for version := range uint64(3) {
+		root = commitVersion(ctx, require, ndb, root, version, fmt.Sprintf("key %d", version), "value")
+		roots = append(roots, root)
+	}
+	numNodes := len(memdb.nodes)
+
+	pruned, err := ndb.PruneRange(ctx, 0, 1)
+	require.NoError(err, "PruneRange")
+	require.Equal(2, pruned)
+	require.EqualValues(2, ndb.GetEarliestVersion())
+	require.Less(len(memdb.nodes), numNodes, "unreachable nodes should be removed")
+
+	// Nodes shared with the remaining version should be kept.
+	tree := mkvs.NewWithRoot(nil, ndb, roots[2])
+	defer tree.Close()
+	for version := range 3 {
+		value, err := tree.Get(ctx, []byte(fmt.Sprintf("key %d", version)))
+		require.NoError(err, "Get")
+		require.Equal([]byte("value"), value)
+	}
+
+	_, err = ndb.GetNode(roots[0], &node.Pointer{Hash: roots[0].Hash, Clean: true})
+	require.ErrorIs(err, api.ErrNodeNotFound, "nodes of pruned versions should not be found")
+}
+
+func TestAbortMultipartInsert(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := New(dbCfg)
+	require.NoError(err, "New()")
+	defer ndb.Close()
+	memdb := ndb.(*memoryNodeDB)
+
+	root := node.Root{Namespace: testNs, Type: node.RootTypeState}
+	root.Hash.Empty()
+	root = commitVersion(ctx, require, ndb, root, 0, "foo", "bar")
+	numNodes := len(memdb.nodes)
+
+	// Restore a root at a later version from a single chunk.
+	err = ndb.StartMultipartInsert(5)
+	require.NoError(err, "StartMultipartInsert")
+
+	leaf := &node.LeafNode{Key: []byte("baz"), Value: []byte("qux")}
+	leaf.UpdateHash()
+	restored := node.Root{Namespace: testNs, Version: 5, Type: node.RootTypeState, Hash: leaf.Hash}
+
+	batch, err := ndb.NewBatch(restored, 5, true)
+	require.NoError(err, "NewBatch")
+	err = batch.PutNode(&node.Pointer{Clean: true, Hash: leaf.Hash, Node: leaf})
+	require.NoError(err, "PutNode")
+	err = batch.Commit(restored)
+	require.NoError(err, "Commit")
+
+	progress := ndb.MultipartProgress()
+	require.NotNil(progress, "MultipartProgress")
+	require.EqualValues(5, progress.Version)
+	require.NotZero(progress.NodesWritten)
+	require.True(ndb.HasRoot(restored), "restored root should exist")
+
+	err = ndb.AbortMultipartInsert()
+	require.NoError(err, "AbortMultipartInsert")
+	require.Nil(ndb.MultipartProgress())
+	require.False(ndb.HasRoot(restored), "restored root should be removed")
+	require.Len(memdb.nodes, numNodes, "restored nodes should be removed")
+	require.True(ndb.HasRoot(root), "existing root should be kept")
+}
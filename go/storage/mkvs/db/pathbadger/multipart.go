@@ -82,6 +82,19 @@ func (d *badgerNodeDB) AbortMultipartInsert() error {
 	return d.cleanMultipartLocked(true)
 }
 
+// Implements api.NodeDB.
+func (d *badgerNodeDB) GetMultipartVersion() uint64 {
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	return d.multipartVersion
+}
+
+// Implements api.NodeDB.
+func (d *badgerNodeDB) MultipartProgress() *api.MultipartProgress {
+	return api.UntrackedMultipartProgress(d)
+}
+
 // Assumes metaUpdateLock is held when called.
 func (d *badgerNodeDB) cleanMultipartLocked(removeNodes bool) error {
 	var (
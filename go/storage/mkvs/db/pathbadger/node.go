@@ -234,6 +234,10 @@ func (ba *badgerBatch) PutNode(ptr *node.Pointer) error {
 
 	// Root node is special.
 	if iptr.isRoot() {
+		ba.size += int64(len(value) - len(ba.newRootValue))
+		if ba.newRootValue == nil {
+			ba.entries++
+		}
 		ba.newRootValue = value
 		return nil
 	}
@@ -243,6 +247,8 @@ func (ba *badgerBatch) PutNode(ptr *node.Pointer) error {
 	})
 
 	dbKey := ba.deriveNodeDbKey(key)
+	ba.size += int64(len(dbKey) + len(value))
+	ba.entries++
 	if ba.seqNo != 0 {
 		// Need to commit at tsMetadata so this can be garbage-collected upon finalization.
 		return ba.batMeta.Set(dbKey, value)
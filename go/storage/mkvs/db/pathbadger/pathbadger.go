@@ -2,7 +2,9 @@
 package pathbadger
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"sync"
 	"sync/atomic"
 
@@ -49,7 +51,8 @@ func New(cfg *api.Config) (api.NodeDB, error) {
 	earliestVersion := db.meta.getEarliestVersion()
 	db.db.SetDiscardTs(versionToTs(earliestVersion))
 
-	// Cleanup any multipart restore remnants, since they can't be used anymore.
+	// Cleanup any multipart restore remnants, since they can't be used anymore. Resuming an
+	// interrupted multipart restore is not supported by this backend.
 	if err = db.cleanMultipartLocked(true); err != nil {
 		_ = db.db.Close()
 		return nil, fmt.Errorf("mkvs/pathbadger: failed to clean leftovers from multipart restore: %w", err)
@@ -80,6 +83,9 @@ type badgerNodeDB struct { // nolint: maligned
 	// cannot be detected.
 	metaUpdateLock sync.Mutex
 	meta           metadata
+	// pins are the versions pinned by readers. Versions are pinned and pins are checked while
+	// holding metaUpdateLock.
+	pins api.VersionPins
 
 	closeOnce sync.Once
 }
@@ -225,6 +231,16 @@ func (d *badgerNodeDB) HasRoot(root node.Root) bool {
 	return true
 }
 
+// Implements api.NodeDB.
+func (d *badgerNodeDB) Roots(ctx context.Context, startVersion, endVersion uint64) (<-chan api.VersionRoots, error) {
+	return api.Roots(ctx, d, d.logger, startVersion, endVersion)
+}
+
+// Implements api.NodeDB.
+func (d *badgerNodeDB) HasRoots(roots []node.Root) ([]bool, error) {
+	return api.HasRoots(d, roots)
+}
+
 // Implements api.NodeDB.
 func (d *badgerNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
 	if d.readOnly {
@@ -505,6 +521,22 @@ func (d *badgerNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
 	return nil
 }
 
+// Implements api.NodeDB.
+func (d *badgerNodeDB) PruneRange(ctx context.Context, startVersion, endVersion uint64) (int, error) {
+	return api.PruneRange(ctx, d, startVersion, endVersion)
+}
+
+// Implements api.NodeDB.
+func (d *badgerNodeDB) Pin(version uint64) (func(), error) {
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	if err := api.CheckPinVersion(d, version); err != nil {
+		return nil, err
+	}
+	return d.pins.Pin(version)
+}
+
 // Implements api.NodeDB.
 func (d *badgerNodeDB) Prune(version uint64) error {
 	if d.readOnly {
@@ -531,6 +563,10 @@ func (d *badgerNodeDB) Prune(version uint64) error {
 	if version == lastFinalizedVersion {
 		return api.ErrCannotPruneLatestVersion
 	}
+	// Make sure that the version that we are trying to prune is not pinned.
+	if d.pins.IsPinned(version) {
+		return api.ErrVersionPinned
+	}
 
 	// Remove all roots in version.
 	batch := d.db.NewWriteBatchAt(versionToTs(version))
@@ -732,6 +768,40 @@ func (d *badgerNodeDB) Size() (int64, error) {
 	return lsm + vlog, nil
 }
 
+// Implements api.NodeDB.
+func (d *badgerNodeDB) Stats(countRoots bool) (*api.Stats, error) {
+	var stats api.Stats
+	stats.LSMSize, stats.ValueLogSize = d.db.Size()
+
+	// Badger does not expose value log discard statistics, so pending garbage is estimated from
+	// the stale data in LSM tables.
+	for _, level := range d.db.Levels() {
+		stats.PendingGarbage += level.StaleDatSize
+	}
+
+	stats.EarliestVersion = d.meta.getEarliestVersion()
+	if version, exists := d.meta.getLastFinalizedVersion(); exists {
+		stats.LatestVersion = &version
+	}
+
+	if !countRoots {
+		return &stats, nil
+	}
+
+	tx := d.db.NewTransactionAt(math.MaxUint64, false)
+	defer tx.Discard()
+
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: rootNodeKeyFmt.Encode()})
+	defer it.Close()
+
+	var numRoots uint64
+	for it.Rewind(); it.Valid(); it.Next() {
+		numRoots++
+	}
+	stats.NumRoots = &numRoots
+	return &stats, nil
+}
+
 // Implements api.NodeDB.
 func (d *badgerNodeDB) Sync() error {
 	return d.db.Sync()
@@ -740,6 +810,8 @@ func (d *badgerNodeDB) Sync() error {
 // Implements api.NodeDB.
 func (d *badgerNodeDB) Close() {
 	d.closeOnce.Do(func() {
+		d.pins.Close()
+
 		if d.gc != nil {
 			d.gc.Stop()
 		}
@@ -774,6 +846,12 @@ type badgerBatch struct {
 	updatedNodes []updatedNode
 	newRootValue []byte
 
+	// size is the number of bytes of node updates in the batch. Write logs are only encoded on
+	// commit and are not included.
+	size int64
+	// entries is the number of node updates in the batch.
+	entries int
+
 	mpLock *sync.Mutex
 }
 
@@ -813,6 +891,8 @@ func (ba *badgerBatch) RemoveNodes(nodes []*node.Pointer) error {
 			Removed: true,
 			Key:     iptr.dbKey(),
 		})
+		ba.size += int64(len(iptr.dbKey()))
+		ba.entries++
 	}
 	return nil
 }
@@ -924,6 +1004,11 @@ func (ba *badgerBatch) Commit(root node.Root) error {
 	return ba.BaseBatch.Commit(root)
 }
 
+// Implements api.Batch.
+func (ba *badgerBatch) Size() (int64, int) {
+	return ba.size, ba.entries
+}
+
 // Implements api.Batch.
 func (ba *badgerBatch) Reset() {
 	ba.bat.Cancel()
@@ -937,9 +1022,16 @@ func (ba *badgerBatch) Reset() {
 	ba.annotations = nil
 	ba.updatedNodes = nil
 	ba.newRootValue = nil
+	ba.size = 0
+	ba.entries = 0
 
 	if ba.mpLock != nil {
 		ba.mpLock.Unlock()
 		ba.mpLock = nil
 	}
 }
+
+// Implements api.Batch.
+func (ba *badgerBatch) Close() {
+	ba.Reset()
+}
@@ -164,3 +164,79 @@ func (d *badgerNodeDB) GetWriteLog(_ context.Context, startRoot, endRoot node.Ro
 
 	return writelog.NewStaticIterator(wl), nil
 }
+
+// writeLogPruneBatchSize is the maximum number of write logs removed using a single write batch.
+const writeLogPruneBatchSize = 1024
+
+// Implements api.NodeDB.
+func (d *badgerNodeDB) PruneWriteLogs(ctx context.Context, beforeVersion uint64) (int, error) {
+	if d.readOnly {
+		return 0, api.ErrReadOnly
+	}
+	if err := api.CheckPruneWriteLogs(d, beforeVersion); err != nil {
+		return 0, err
+	}
+
+	tx := d.db.NewTransactionAt(tsMetadata, false)
+	defer tx.Discard()
+
+	it := tx.NewIterator(badger.IteratorOptions{Prefix: writeLogKeyFmt.Encode()})
+	defer it.Close()
+
+	// Each batch is flushed on its own, so an interrupted pruning leaves the database consistent.
+	var (
+		batch   *badger.WriteBatch
+		pruned  int
+		pending int
+	)
+	flush := func() error {
+		if batch == nil {
+			return nil
+		}
+		defer func() { batch = nil }()
+		if err := batch.Flush(); err != nil {
+			batch.Cancel()
+			return fmt.Errorf("mkvs/pathbadger: failed to flush batch: %w", err)
+		}
+		pruned += pending
+		pending = 0
+		return nil
+	}
+
+	var err error
+	for it.Rewind(); it.Valid(); it.Next() {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		var (
+			version       uint64
+			endRootHash   api.TypedHash
+			startRootHash api.TypedHash
+		)
+		if !writeLogKeyFmt.Decode(it.Item().Key(), &version, &endRootHash, &startRootHash) {
+			err = fmt.Errorf("%w: corrupted write log key", api.ErrCorruptedDB)
+			break
+		}
+		if version >= beforeVersion {
+			break
+		}
+
+		if batch == nil {
+			batch = d.db.NewWriteBatchAt(tsMetadata)
+		}
+		if err = batch.Delete(it.Item().KeyCopy(nil)); err != nil {
+			break
+		}
+		pending++
+		if pending == writeLogPruneBatchSize {
+			if err = flush(); err != nil {
+				return pruned, err
+			}
+		}
+	}
+	if ferr := flush(); ferr != nil {
+		return pruned, ferr
+	}
+	return pruned, err
+}
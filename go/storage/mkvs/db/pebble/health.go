@@ -0,0 +1,64 @@
+package pebble
+
+import (
+	"errors"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+var _ api.HealthNodeDB = (*pebbleNodeDB)(nil)
+
+// Implements api.HealthNodeDB.
+func (d *pebbleNodeDB) LastError() error {
+	d.lastErrorLock.RLock()
+	defer d.lastErrorLock.RUnlock()
+
+	return d.lastError
+}
+
+// Implements api.HealthNodeDB.
+//
+// The pebble backend does not support a corruption error threshold, so the database is always
+// reported as healthy and only the last corruption-class error is reported.
+func (d *pebbleNodeDB) Health() *api.Health {
+	d.lastErrorLock.RLock()
+	defer d.lastErrorLock.RUnlock()
+
+	h := &api.Health{
+		Healthy: true,
+	}
+	if d.lastCorruptionError != nil {
+		h.LastCorruptionError = d.lastCorruptionError.Error()
+	}
+	return h
+}
+
+// setLastError records the outcome of an operation that cannot report errors to the caller.
+func (d *pebbleNodeDB) setLastError(err error) {
+	if err != nil {
+		d.logger.Error("node database operation failed",
+			"err", err,
+		)
+	}
+
+	d.lastErrorLock.Lock()
+	defer d.lastErrorLock.Unlock()
+
+	d.lastError = err
+	if errors.Is(err, api.ErrCorruptedDB) {
+		d.lastCorruptionError = err
+	}
+}
+
+// corruption records a corruption-class error returned to the caller and returns it.
+func (d *pebbleNodeDB) corruption(err error) error {
+	d.logger.Error("node database is corrupted",
+		"err", err,
+	)
+
+	d.lastErrorLock.Lock()
+	defer d.lastErrorLock.Unlock()
+
+	d.lastCorruptionError = err
+	return err
+}
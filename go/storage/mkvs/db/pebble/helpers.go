@@ -0,0 +1,59 @@
+package pebble
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// defaultCacheSize is the block cache size used when no maximum cache size is configured.
+const defaultCacheSize = 64 * 1024 * 1024
+
+// commonConfigToPebbleOptions prepares a pebble option struct with common options.
+//
+// The caller is responsible for releasing the reference to the block cache once the database
+// has been opened.
+func commonConfigToPebbleOptions(cfg *api.Config, db *pebbleNodeDB) *pebble.Options {
+	cacheSize := cfg.MaxCacheSize
+	if cacheSize == 0 {
+		cacheSize = defaultCacheSize
+	}
+
+	opts := &pebble.Options{
+		Cache:    pebble.NewCache(cacheSize),
+		Logger:   &logAdapter{logger: db.logger},
+		ReadOnly: cfg.ReadOnly,
+	}
+
+	if cfg.MemoryOnly {
+		db.logger.Warn("using memory-only mode, data will not be persisted")
+		opts.FS = vfs.NewMem()
+	}
+
+	return opts
+}
+
+// logAdapter is a pebble logger that forwards messages to the node logger.
+type logAdapter struct {
+	logger *logging.Logger
+}
+
+// Infof implements pebble.Logger.
+func (l *logAdapter) Infof(format string, args ...any) {
+	l.logger.Info(fmt.Sprintf(format, args...))
+}
+
+// Errorf implements pebble.Logger.
+func (l *logAdapter) Errorf(format string, args ...any) {
+	l.logger.Error(fmt.Sprintf(format, args...))
+}
+
+// Fatalf implements pebble.Logger.
+func (l *logAdapter) Fatalf(format string, args ...any) {
+	l.logger.Error(fmt.Sprintf(format, args...))
+	panic(fmt.Sprintf(format, args...))
+}
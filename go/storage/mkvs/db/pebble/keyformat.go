@@ -0,0 +1,60 @@
+package pebble
+
+import (
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/keyformat"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+var (
+	// keyFormat is the namespace for the pebble database key formats.
+	keyFormat = keyformat.NewNamespace("pebble")
+
+	// metadataKeyFmt is the key format for metadata.
+	//
+	// Value is CBOR-serialized metadata.
+	metadataKeyFmt = keyFormat.New(0x00)
+
+	// nodeKeyFmt is the key format for nodes: (node hash, inverted version).
+	//
+	// Value is a versioned value containing the serialized node.
+	nodeKeyFmt = keyFormat.New(0x01, &hash.Hash{}, uint64(0))
+
+	// rootNodeKeyFmt is the key format for root nodes: (typed node hash, inverted version).
+	//
+	// Value is a versioned value without any data.
+	rootNodeKeyFmt = keyFormat.New(0x02, &api.TypedHash{}, uint64(0))
+
+	// writeLogKeyFmt is the key format for write logs: (version, new root, old root).
+	//
+	// Value is CBOR-serialized write log.
+	writeLogKeyFmt = keyFormat.New(0x03, uint64(0), &api.TypedHash{}, &api.TypedHash{})
+
+	// rootsMetadataKeyFmt is the key format for roots metadata: (version).
+	//
+	// Value is CBOR-serialized rootsMetadata.
+	rootsMetadataKeyFmt = keyFormat.New(0x04, uint64(0))
+
+	// rootUpdatedNodesKeyFmt is the key format for the pending updated nodes for the given root
+	// that need to be removed only in case the given root is not among the finalized roots. The
+	// key format is (version, root).
+	//
+	// Value is CBOR-serialized []updatedNode.
+	rootUpdatedNodesKeyFmt = keyFormat.New(0x05, uint64(0), &api.TypedHash{})
+
+	// tombstoneKeyFmt is the key format for the index of tombstones written at a given version:
+	// (version, typed hash). Node hashes use the invalid root type while root nodes use their
+	// root type. Once the version is pruned, the tombstones and all entries they shadow are
+	// removed.
+	//
+	// Value is empty.
+	tombstoneKeyFmt = keyFormat.New(0x06, uint64(0), &api.TypedHash{})
+
+	// multipartRestoreNodeLogKeyFmt is the key format for the nodes inserted during a chunk
+	// restore. Once a set of chunks is fully restored, these entries should be removed. If chunk
+	// restoration is interrupted for any reason, the nodes associated with these keys should be
+	// removed, along with these entries.
+	//
+	// Value is empty.
+	multipartRestoreNodeLogKeyFmt = keyFormat.New(0x07, &api.TypedHash{})
+)
@@ -0,0 +1,41 @@
+package pebble
+
+import (
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+var _ api.LineageNodeDB = (*pebbleNodeDB)(nil)
+
+// Implements api.LineageNodeDB.
+func (d *pebbleNodeDB) GetDerivedRoots(root node.Root) ([]node.Root, error) {
+	if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+		return nil, err
+	}
+	if root.Version < d.meta.getEarliestVersion() {
+		return nil, api.ErrRootNotFound
+	}
+
+	rootsMeta, err := loadRootsMetadata(d.db, root.Version)
+	if err != nil {
+		return nil, err
+	}
+	derivedRoots, ok := rootsMeta.Roots[api.TypedHashFromRoot(root)]
+	if !ok {
+		return nil, api.ErrRootNotFound
+	}
+	if len(derivedRoots) == 0 {
+		return nil, nil
+	}
+
+	nextRootsMeta, err := loadRootsMetadata(d.db, root.Version+1)
+	if err != nil {
+		return nil, err
+	}
+	return api.ResolveDerivedRoots(root, derivedRoots, rootsMeta.Roots, nextRootsMeta.Roots), nil
+}
+
+// Implements api.LineageNodeDB.
+func (d *pebbleNodeDB) GetRootLineage(root node.Root, maxDepth int) ([][]node.Root, error) {
+	return api.GetRootLineage(d, root, maxDepth)
+}
@@ -0,0 +1,1396 @@
+// Package pebble provides a Pebble-backed node database.
+package pebble
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cockroachdb/pebble"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
+)
+
+const (
+	// dbVersion is the internal database version. We start with 7 to make sure this is distinct
+	// from the badger backends which use database versions 5 and 6.
+	dbVersion = 7
+
+	// multipartVersionNone is the value used for the multipart version in metadata
+	// when no multipart restore is in progress.
+	multipartVersionNone uint64 = 0
+
+	// maxWriteLogVisited is the maximum number of write logs visited during a single write log
+	// path search.
+	maxWriteLogVisited = 1024
+
+	// pruneRangeChunkSize is the maximum number of versions pruned using a single write batch.
+	pruneRangeChunkSize = 128
+
+	// writeLogPruneBatchSize is the maximum number of write logs removed using a single write
+	// batch.
+	writeLogPruneBatchSize = 1024
+)
+
+// New creates a new Pebble-backed node database.
+func New(cfg *api.Config) (api.NodeDB, error) {
+	maxWriteLogHops, err := cfg.WriteLogHops()
+	if err != nil {
+		return nil, fmt.Errorf("mkvs/pebble: invalid configuration: %w", err)
+	}
+
+	db := &pebbleNodeDB{
+		logger:           logging.GetLogger("mkvs/db/pebble"),
+		namespace:        cfg.Namespace,
+		readOnly:         cfg.ReadOnly,
+		discardWriteLogs: cfg.DiscardWriteLogs,
+		maxWriteLogHops:  maxWriteLogHops,
+		writeOpts:        pebble.Sync,
+	}
+	if cfg.NoFsync {
+		db.writeOpts = pebble.NoSync
+	}
+
+	opts := commonConfigToPebbleOptions(cfg, db)
+	defer opts.Cache.Unref()
+
+	if db.db, err = pebble.Open(cfg.DB, opts); err != nil {
+		return nil, fmt.Errorf("mkvs/pebble: failed to open database: %w", err)
+	}
+
+	// Load database metadata.
+	if err = db.load(); err != nil {
+		_ = db.db.Close()
+		return nil, fmt.Errorf("mkvs/pebble: failed to load metadata: %w", err)
+	}
+
+	// Cleanup any multipart restore remnants, unless the restore should be resumed.
+	switch version := db.meta.getMultipartVersion(); {
+	case version != multipartVersionNone && cfg.AllowResumeMultipart:
+		db.logger.Info("keeping interrupted multipart restore for resumption",
+			"version", version,
+		)
+		db.multipartVersion = version
+	default:
+		if err = db.cleanMultipartLocked(true); err != nil {
+			_ = db.db.Close()
+			return nil, fmt.Errorf("mkvs/pebble: failed to clean leftovers from multipart restore: %w", err)
+		}
+	}
+
+	return db, nil
+}
+
+type pebbleNodeDB struct { // nolint: maligned
+	logger *logging.Logger
+
+	namespace common.Namespace
+
+	readOnly         bool
+	discardWriteLogs bool
+	maxWriteLogHops  uint8
+
+	multipartVersion uint64
+
+	db        *pebble.DB
+	writeOpts *pebble.WriteOptions
+
+	// metaUpdateLock must be held at any point where metadata is read and updated. All metadata
+	// updates go through indexed batches which do not detect conflicts.
+	metaUpdateLock sync.Mutex
+	meta           metadata
+	// pins are the versions pinned by readers. Versions are pinned and pins are checked while
+	// holding metaUpdateLock.
+	pins api.VersionPins
+
+	// lastErrorLock protects lastError and lastCorruptionError.
+	lastErrorLock sync.RWMutex
+	// lastError is the last error encountered by an operation that cannot report errors.
+	lastError error
+	// lastCorruptionError is the last corruption-class error encountered by any operation.
+	lastCorruptionError error
+
+	closeOnce sync.Once
+}
+
+func (d *pebbleNodeDB) load() error {
+	// Load metadata.
+	data, closer, err := d.db.Get(metadataKeyFmt.Encode())
+	switch {
+	case err == nil:
+		// Metadata already exists, just load it and verify that it is
+		// compatible with what we have here.
+		err = cbor.UnmarshalTrusted(data, &d.meta.value)
+		closer.Close()
+		if err != nil {
+			return err
+		}
+
+		if d.meta.value.Version != dbVersion {
+			return fmt.Errorf("incompatible database version (expected: %d got: %d)",
+				dbVersion,
+				d.meta.value.Version,
+			)
+		}
+		if !d.meta.value.Namespace.Equal(&d.namespace) {
+			return fmt.Errorf("incompatible namespace (expected: %s got: %s)",
+				d.namespace,
+				d.meta.value.Namespace,
+			)
+		}
+		return nil
+	case errors.Is(err, pebble.ErrNotFound):
+	default:
+		return err
+	}
+
+	// No metadata exists, create some.
+	d.meta.value.Version = dbVersion
+	d.meta.value.Namespace = d.namespace
+
+	batch := d.db.NewBatch()
+	defer batch.Close()
+	if err = d.meta.save(batch); err != nil {
+		return err
+	}
+	return batch.Commit(d.writeOpts)
+}
+
+func (d *pebbleNodeDB) sanityCheckNamespace(ns common.Namespace) error {
+	if !ns.Equal(&d.namespace) {
+		return api.ErrBadNamespace
+	}
+	return nil
+}
+
+func (d *pebbleNodeDB) checkRoot(r pebble.Reader, root node.Root) error {
+	rootHash := api.TypedHashFromRoot(root)
+	if _, _, err := getVersioned(r, rootNodeKey(&rootHash, root.Version)); err != nil {
+		switch {
+		case errors.Is(err, errVersionedNotFound):
+			return api.ErrRootNotFound
+		default:
+			d.logger.Error("failed to check root existence",
+				"err", err,
+			)
+			return fmt.Errorf("mkvs/pebble: failed to check root existence while getting node from backing store: %w", err)
+		}
+	}
+	return nil
+}
+
+// Assumes metaUpdateLock is held when called.
+func (d *pebbleNodeDB) cleanMultipartLocked(removeNodes bool) error {
+	var version uint64
+
+	if d.multipartVersion != multipartVersionNone {
+		version = d.multipartVersion
+	} else {
+		version = d.meta.getMultipartVersion()
+	}
+	if version == multipartVersionNone {
+		// No multipart in progress, but it's not an error to call in a situation like this.
+		return nil
+	}
+
+	prefix := multipartRestoreNodeLogKeyFmt.Encode()
+	it, err := d.db.NewIter(&pebble.IterOptions{
+		LowerBound: prefix,
+		UpperBound: prefixEnd(prefix),
+	})
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	batch := d.db.NewBatch()
+	defer batch.Close()
+
+	var logged bool
+	for it.First(); it.Valid(); it.Next() {
+		key := it.Key()
+		if removeNodes {
+			if !logged {
+				d.logger.Info("removing some nodes from a multipart restore")
+				logged = true
+			}
+			var hash api.TypedHash
+			if !multipartRestoreNodeLogKeyFmt.Decode(key, &hash) {
+				panic("mkvs/pebble: bad iterator")
+			}
+			// Nodes logged during the restore were not present before, so the entries can be
+			// removed outright instead of being shadowed by a tombstone.
+			switch hash.Type() {
+			case node.RootTypeInvalid:
+				h := hash.Hash()
+				if err = batch.Delete(nodeKey(&h, version), nil); err != nil {
+					return err
+				}
+			default:
+				if err = batch.Delete(rootNodeKey(&hash, version), nil); err != nil {
+					return err
+				}
+			}
+		}
+		if err = batch.Delete(key, nil); err != nil {
+			return err
+		}
+	}
+	if err = it.Error(); err != nil {
+		return err
+	}
+
+	// Node removals and the metadata update are committed atomically.
+	if err = d.meta.setMultipartVersion(batch, multipartVersionNone); err != nil {
+		return err
+	}
+	if err = batch.Commit(d.writeOpts); err != nil {
+		return err
+	}
+
+	d.multipartVersion = multipartVersionNone
+	return nil
+}
+
+func (d *pebbleNodeDB) GetNode(root node.Root, ptr *node.Pointer) (node.Node, error) {
+	if ptr == nil || !ptr.IsClean() {
+		panic("mkvs/pebble: attempted to get invalid pointer from node database")
+	}
+	if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+		return nil, err
+	}
+	// If the version is earlier than the earliest version, we don't have the node (it was pruned).
+	// Note that the key can still be present in the database until it gets compacted.
+	if root.Version < d.meta.getEarliestVersion() {
+		return nil, api.ErrNodeNotFound
+	}
+
+	// Check if the root actually exists.
+	if err := d.checkRoot(d.db, root); err != nil {
+		return nil, err
+	}
+
+	data, _, err := getVersioned(d.db, nodeKey(&ptr.Hash, root.Version))
+	switch {
+	case err == nil:
+	case errors.Is(err, errVersionedNotFound):
+		return nil, api.ErrNodeNotFound
+	default:
+		d.logger.Error("failed to Get node from backing store",
+			"err", err,
+		)
+		return nil, fmt.Errorf("mkvs/pebble: failed to Get node from backing store: %w", err)
+	}
+
+	n, err := node.UnmarshalBinary(data)
+	if err != nil {
+		d.logger.Error("failed to unmarshal node",
+			"err", err,
+		)
+		return nil, fmt.Errorf("mkvs/pebble: failed to unmarshal node: %w", err)
+	}
+	return n, nil
+}
+
+func (d *pebbleNodeDB) GetWriteLog(ctx context.Context, startRoot, endRoot node.Root) (writelog.Iterator, error) {
+	if d.discardWriteLogs {
+		return nil, api.ErrWriteLogNotFound
+	}
+	if !endRoot.Follows(&startRoot) {
+		return nil, api.ErrRootMustFollowOld
+	}
+	if err := d.sanityCheckNamespace(startRoot.Namespace); err != nil {
+		return nil, err
+	}
+	// If the version is earlier than the earliest version, we don't have the roots.
+	if endRoot.Version < d.meta.getEarliestVersion() {
+		return nil, api.ErrWriteLogNotFound
+	}
+
+	snap := d.db.NewSnapshot()
+	closeSnap := true
+	defer func() {
+		if closeSnap {
+			snap.Close()
+		}
+	}()
+
+	// Check if the root actually exists.
+	if err := d.checkRoot(snap, endRoot); err != nil {
+		return nil, err
+	}
+
+	// Start at the end root and search towards the start root. See the badger backend for the
+	// rationale behind the limits on the number of hops and visited write logs.
+	maxAllowedHops := d.maxWriteLogHops
+	var visited int
+
+	type wlItem struct {
+		depth       uint8
+		endRootHash api.TypedHash
+		logKeys     [][]byte
+		logRoots    []api.TypedHash
+	}
+	queue := []*wlItem{{depth: 0, endRootHash: api.TypedHashFromRoot(endRoot)}}
+	startRootHash := api.TypedHashFromRoot(startRoot)
+	for len(queue) > 0 {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		curItem := queue[0]
+		queue = queue[1:]
+
+		wl, err := func() (writelog.Iterator, error) {
+			// Iterate over all write logs that result in the current item.
+			prefix := writeLogKeyFmt.Encode(endRoot.Version, &curItem.endRootHash)
+			it, err := snap.NewIter(&pebble.IterOptions{
+				LowerBound: prefix,
+				UpperBound: prefixEnd(prefix),
+			})
+			if err != nil {
+				return nil, err
+			}
+			defer it.Close()
+
+			for it.First(); it.Valid(); it.Next() {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+
+				visited++
+				if visited > maxWriteLogVisited {
+					d.logger.Warn("write log search visited too many write logs",
+						"start_root", startRoot,
+						"end_root", endRoot,
+						"max_hops", maxAllowedHops,
+					)
+					return nil, api.ErrWriteLogNotFound
+				}
+
+				var decVersion uint64
+				var decEndRootHash api.TypedHash
+				var decStartRootHash api.TypedHash
+
+				if !writeLogKeyFmt.Decode(it.Key(), &decVersion, &decEndRootHash, &decStartRootHash) {
+					// This should not happen as the iterator bounds should take care of it.
+					panic("mkvs/pebble: bad iterator")
+				}
+
+				nextItem := wlItem{
+					depth:       curItem.depth + 1,
+					endRootHash: decStartRootHash,
+					// Only store log keys to avoid keeping everything in memory while
+					// we are searching for the right path.
+					logKeys:  append(curItem.logKeys, append([]byte{}, it.Key()...)),
+					logRoots: append(curItem.logRoots, curItem.endRootHash),
+				}
+				if nextItem.endRootHash.Equal(&startRootHash) {
+					// Path has been found, deserialize and stream write logs.
+					var index int
+					closeSnap = false
+					return api.ReviveHashedDBWriteLogs(ctx,
+						func() (node.Root, api.HashedDBWriteLog, error) {
+							if index >= len(nextItem.logKeys) {
+								return node.Root{}, nil, nil
+							}
+
+							key := nextItem.logKeys[index]
+							root := node.Root{
+								Namespace: endRoot.Namespace,
+								Version:   endRoot.Version,
+								Type:      nextItem.logRoots[index].Type(),
+								Hash:      nextItem.logRoots[index].Hash(),
+							}
+
+							data, closer, err := snap.Get(key)
+							if err != nil {
+								return node.Root{}, nil, err
+							}
+							defer closer.Close()
+
+							var log api.HashedDBWriteLog
+							if err = cbor.UnmarshalTrusted(data, &log); err != nil {
+								return node.Root{}, nil, err
+							}
+
+							index++
+							return root, log, nil
+						},
+						func(root node.Root, h hash.Hash) (*node.LeafNode, error) {
+							leaf, err := d.GetNode(root, &node.Pointer{Hash: h, Clean: true})
+							if err != nil {
+								return nil, err
+							}
+							return leaf.(*node.LeafNode), nil
+						},
+						func() {
+							snap.Close()
+						},
+					)
+				}
+
+				if nextItem.depth < maxAllowedHops {
+					queue = append(queue, &nextItem)
+				}
+			}
+
+			return nil, it.Error()
+		}()
+		if wl != nil || err != nil {
+			return wl, err
+		}
+	}
+
+	return nil, api.ErrWriteLogNotFound
+}
+
+func (d *pebbleNodeDB) GetLatestVersion() (uint64, bool) {
+	return d.meta.getLastFinalizedVersion()
+}
+
+func (d *pebbleNodeDB) GetEarliestVersion() uint64 {
+	return d.meta.getEarliestVersion()
+}
+
+func (d *pebbleNodeDB) GetRootsForVersion(version uint64) (roots []node.Root, err error) {
+	// If the version is earlier than the earliest version, we don't have the roots.
+	if version < d.meta.getEarliestVersion() {
+		return nil, nil
+	}
+
+	rootsMeta, err := loadRootsMetadata(d.db, version)
+	if err != nil {
+		return nil, err
+	}
+
+	for rootHash := range rootsMeta.Roots {
+		roots = append(roots, node.Root{
+			Namespace: d.namespace,
+			Version:   version,
+			Type:      rootHash.Type(),
+			Hash:      rootHash.Hash(),
+		})
+	}
+	return
+}
+
+func (d *pebbleNodeDB) HasRoot(root node.Root) bool {
+	if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+		return false
+	}
+
+	// An empty root is always implicitly present.
+	if root.Hash.IsEmpty() {
+		return true
+	}
+
+	// If the version is earlier than the earliest version, we don't have the root.
+	if root.Version < d.meta.getEarliestVersion() {
+		return false
+	}
+
+	rootsMeta, err := loadRootsMetadata(d.db, root.Version)
+	if err != nil {
+		d.setLastError(fmt.Errorf("mkvs/pebble: failed to load roots metadata: %w", err))
+		return false
+	}
+	d.setLastError(nil)
+
+	_, exists := rootsMeta.Roots[api.TypedHashFromRoot(root)]
+	return exists
+}
+
+func (d *pebbleNodeDB) Roots(ctx context.Context, startVersion, endVersion uint64) (<-chan api.VersionRoots, error) {
+	return api.Roots(ctx, d, d.logger, startVersion, endVersion)
+}
+
+func (d *pebbleNodeDB) HasRoots(roots []node.Root) ([]bool, error) {
+	exists := make([]bool, len(roots))
+
+	// Group roots by version so that roots metadata is loaded only once per version.
+	byVersion := make(map[uint64][]int)
+	earliestVersion := d.meta.getEarliestVersion()
+	for i, root := range roots {
+		if err := d.sanityCheckNamespace(root.Namespace); err != nil {
+			continue
+		}
+
+		// An empty root is always implicitly present.
+		if root.Hash.IsEmpty() {
+			exists[i] = true
+			continue
+		}
+
+		// If the version is earlier than the earliest version, we don't have the root.
+		if root.Version < earliestVersion {
+			continue
+		}
+
+		byVersion[root.Version] = append(byVersion[root.Version], i)
+	}
+
+	for version, indices := range byVersion {
+		rootsMeta, err := loadRootsMetadata(d.db, version)
+		if err != nil {
+			return nil, fmt.Errorf("mkvs/pebble: failed to load roots metadata: %w", err)
+		}
+
+		for _, i := range indices {
+			_, exists[i] = rootsMeta.Roots[api.TypedHashFromRoot(roots[i])]
+		}
+	}
+	return exists, nil
+}
+
+// putTombstone marks the given versioned key as deleted as of the given version and records
+// the tombstone so that it can be removed once the version is pruned.
+func putTombstone(batch *pebble.Batch, version uint64, th *api.TypedHash) error {
+	var key []byte
+	switch th.Type() {
+	case node.RootTypeInvalid:
+		h := th.Hash()
+		key = nodeKey(&h, version)
+	default:
+		key = rootNodeKey(th, version)
+	}
+
+	if err := batch.Set(key, []byte{versionedDeleted}, nil); err != nil {
+		return err
+	}
+	return batch.Set(tombstoneKeyFmt.Encode(version, th), []byte{}, nil)
+}
+
+func (d *pebbleNodeDB) Finalize(roots []node.Root) error { // nolint: gocyclo
+	if d.readOnly {
+		return api.ErrReadOnly
+	}
+
+	if len(roots) == 0 {
+		return fmt.Errorf("mkvs/pebble: need at least one root to finalize")
+	}
+	version := roots[0].Version
+
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	if d.multipartVersion != multipartVersionNone && d.multipartVersion != version {
+		return api.ErrInvalidMultipartVersion
+	}
+
+	// Make sure that the previous version has been finalized (if we are not restoring).
+	lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion()
+	if d.multipartVersion == multipartVersionNone && version > 0 && exists && lastFinalizedVersion < (version-1) {
+		return api.ErrNotFinalized
+	}
+	// Make sure that this version has not yet been finalized.
+	if exists && version <= lastFinalizedVersion {
+		return api.ErrAlreadyFinalized
+	}
+
+	// All removals and metadata updates are committed atomically.
+	batch := d.db.NewIndexedBatch()
+	defer batch.Close()
+
+	// Determine the set of finalized roots. Finalization is transitive, so if
+	// a parent root is finalized the child should be considered finalized too.
+	finalizedRoots := make(map[api.TypedHash]bool)
+	for _, root := range roots {
+		if root.Version != version {
+			return fmt.Errorf("mkvs/pebble: roots to finalize don't have matching versions")
+		}
+		finalizedRoots[api.TypedHashFromRoot(root)] = true
+	}
+
+	var rootsChanged bool
+	rootsMeta, err := loadRootsMetadata(batch, version)
+	if err != nil {
+		return err
+	}
+
+	for updated := true; updated; {
+		updated = false
+
+		for rootHash, derivedRoots := range rootsMeta.Roots {
+			if len(derivedRoots) == 0 {
+				continue
+			}
+
+			for _, nextRoot := range derivedRoots {
+				if !finalizedRoots[rootHash] && finalizedRoots[nextRoot] {
+					finalizedRoots[rootHash] = true
+					updated = true
+				}
+			}
+		}
+	}
+
+	// Sanity check the input roots list.
+	for iroot := range finalizedRoots {
+		h := iroot.Hash()
+		if _, ok := rootsMeta.Roots[iroot]; !ok && !h.IsEmpty() {
+			return api.ErrRootNotFound
+		}
+	}
+
+	// Go through all roots and prune them based on whether they are finalized or not.
+	maybeLoneNodes := make(map[hash.Hash]bool)
+	notLoneNodes := make(map[hash.Hash]bool)
+
+	for rootHash := range rootsMeta.Roots {
+		rootUpdatedNodesKey := rootUpdatedNodesKeyFmt.Encode(version, &rootHash)
+
+		// Load hashes of nodes added during this version for this root.
+		var updatedNodes []updatedNode
+		if err = func() error {
+			data, closer, err := batch.Get(rootUpdatedNodesKey)
+			switch {
+			case err == nil:
+			case errors.Is(err, pebble.ErrNotFound):
+				return d.corruption(fmt.Errorf("%w: missing root updated nodes index for root %s", api.ErrCorruptedDB, rootHash))
+			default:
+				return fmt.Errorf("mkvs/pebble: failed to read root updated nodes index: %w", err)
+			}
+			defer closer.Close()
+
+			if err = cbor.UnmarshalTrusted(data, &updatedNodes); err != nil {
+				return d.corruption(fmt.Errorf("%w: corrupted root updated nodes index for root %s: %w", api.ErrCorruptedDB, rootHash, err))
+			}
+			return nil
+		}(); err != nil {
+			return err
+		}
+
+		if finalizedRoots[rootHash] {
+			// Make sure not to remove any nodes shared with finalized roots.
+			for _, n := range updatedNodes {
+				if n.Removed {
+					maybeLoneNodes[n.Hash] = true
+				} else {
+					notLoneNodes[n.Hash] = true
+				}
+			}
+		} else {
+			// Remove any non-finalized roots. Removal only shadows entries as of this version so
+			// nodes that are resurrected in any later version remain available as long as we make
+			// sure that these nodes are not shared with any finalized roots added in the same
+			// version.
+			for _, n := range updatedNodes {
+				if !n.Removed {
+					maybeLoneNodes[n.Hash] = true
+				}
+			}
+
+			delete(rootsMeta.Roots, rootHash)
+			rootsChanged = true
+
+			// Remove the root node entry so that the discarded root is no longer reachable.
+			if err = putTombstone(batch, version, &rootHash); err != nil {
+				return err
+			}
+
+			// Remove write logs for the non-finalized root.
+			if !d.discardWriteLogs {
+				prefix := writeLogKeyFmt.Encode(version, &rootHash)
+				if err = batch.DeleteRange(prefix, prefixEnd(prefix), nil); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Set of updated nodes no longer needed after finalization.
+		if err = batch.Delete(rootUpdatedNodesKey, nil); err != nil {
+			return err
+		}
+	}
+
+	// Clean any lone nodes.
+	for h := range maybeLoneNodes {
+		if notLoneNodes[h] {
+			continue
+		}
+
+		th := api.TypedHashFromParts(node.RootTypeInvalid, h)
+		if err = putTombstone(batch, version, &th); err != nil {
+			return err
+		}
+	}
+
+	// Save roots metadata if changed.
+	if rootsChanged {
+		if err = rootsMeta.save(batch); err != nil {
+			return fmt.Errorf("mkvs/pebble: failed to save roots metadata: %w", err)
+		}
+	}
+
+	// Update last finalized version.
+	if err = d.meta.setLastFinalizedVersion(batch, version); err != nil {
+		return fmt.Errorf("mkvs/pebble: failed to set last finalized version: %w", err)
+	}
+
+	if err = batch.Commit(d.writeOpts); err != nil {
+		return fmt.Errorf("mkvs/pebble: failed to commit batch: %w", err)
+	}
+
+	// Clean multipart metadata if there is any.
+	if d.multipartVersion != multipartVersionNone {
+		if err = d.cleanMultipartLocked(false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *pebbleNodeDB) Pin(version uint64) (func(), error) {
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	if err := api.CheckPinVersion(d, version); err != nil {
+		return nil, err
+	}
+	return d.pins.Pin(version)
+}
+
+func (d *pebbleNodeDB) Prune(version uint64) error {
+	_, err := d.pruneRange(context.Background(), version, version, true)
+	return err
+}
+
+func (d *pebbleNodeDB) PruneRange(ctx context.Context, startVersion, endVersion uint64) (int, error) {
+	return d.pruneRange(ctx, startVersion, endVersion, false)
+}
+
+func (d *pebbleNodeDB) PruneWriteLogs(ctx context.Context, beforeVersion uint64) (int, error) {
+	if d.readOnly {
+		return 0, api.ErrReadOnly
+	}
+	if err := api.CheckPruneWriteLogs(d, beforeVersion); err != nil {
+		return 0, err
+	}
+
+	it, err := d.db.NewIter(&pebble.IterOptions{
+		LowerBound: writeLogKeyFmt.Encode(),
+		UpperBound: writeLogKeyFmt.Encode(beforeVersion),
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	batch := d.db.NewBatch()
+	defer batch.Close()
+
+	// Each batch is committed on its own, so an interrupted pruning leaves the database consistent.
+	var pruned, pending int
+	commit := func() error {
+		if pending == 0 {
+			return nil
+		}
+		if err := batch.Commit(d.writeOpts); err != nil {
+			return fmt.Errorf("mkvs/pebble: failed to commit batch: %w", err)
+		}
+		batch.Reset()
+		pruned += pending
+		pending = 0
+		return nil
+	}
+
+	for it.First(); it.Valid(); it.Next() {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if err = batch.Delete(it.Key(), nil); err != nil {
+			break
+		}
+		pending++
+		if pending == writeLogPruneBatchSize {
+			if err = commit(); err != nil {
+				return pruned, err
+			}
+		}
+	}
+	if err == nil {
+		err = it.Error()
+	}
+	if cerr := commit(); cerr != nil {
+		return pruned, cerr
+	}
+	return pruned, err
+}
+
+func (d *pebbleNodeDB) pruneRange(ctx context.Context, startVersion, endVersion uint64, exact bool) (int, error) {
+	if d.readOnly {
+		return 0, api.ErrReadOnly
+	}
+	if startVersion > endVersion {
+		return 0, fmt.Errorf("mkvs/pebble: invalid prune range [%d, %d]", startVersion, endVersion)
+	}
+
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	if d.multipartVersion != multipartVersionNone {
+		return 0, api.ErrMultipartInProgress
+	}
+
+	// Make sure that the versions that we try to prune have been finalized.
+	lastFinalizedVersion, exists := d.meta.getLastFinalizedVersion()
+	if !exists || lastFinalizedVersion < startVersion || (exact && lastFinalizedVersion < endVersion) {
+		return 0, api.ErrNotFinalized
+	}
+	// Make sure that the first version that we are trying to prune is the earliest version.
+	if startVersion != d.meta.getEarliestVersion() {
+		return 0, api.ErrNotEarliest
+	}
+	// Make sure that we are not trying to prune the only finalized version.
+	if endVersion >= lastFinalizedVersion {
+		if exact || startVersion == lastFinalizedVersion {
+			return 0, api.ErrCannotPruneLatestVersion
+		}
+		endVersion = lastFinalizedVersion - 1
+	}
+	// Make sure that we are not trying to prune any pinned versions.
+	endVersion, err := d.pins.PrunableEnd(startVersion, endVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	var pruned int
+	for chunkStart := startVersion; chunkStart <= endVersion; {
+		chunkEnd := min(endVersion, chunkStart+pruneRangeChunkSize-1)
+		n, err := d.pruneChunkLocked(ctx, chunkStart, chunkEnd)
+		pruned += n
+		if err != nil {
+			return pruned, err
+		}
+		chunkStart = chunkEnd + 1
+	}
+	return pruned, nil
+}
+
+// pruneChunkLocked prunes versions in the given range using a single write batch. In case the
+// context is canceled, versions pruned so far are committed.
+func (d *pebbleNodeDB) pruneChunkLocked(ctx context.Context, startVersion, endVersion uint64) (int, error) {
+	batch := d.db.NewIndexedBatch()
+	defer batch.Close()
+
+	var (
+		pruned   int
+		pruneErr error
+	)
+	for version := startVersion; version <= endVersion; version++ {
+		if pruneErr = ctx.Err(); pruneErr != nil {
+			break
+		}
+		if pruneErr = d.pruneVersionLocked(batch, version); pruneErr != nil {
+			break
+		}
+		pruned++
+	}
+	if pruned == 0 {
+		return 0, pruneErr
+	}
+
+	// Update metadata.
+	lastPruned := startVersion + uint64(pruned) - 1 //nolint:gosec
+	if err := d.meta.setEarliestVersion(batch, lastPruned+1); err != nil {
+		return 0, fmt.Errorf("mkvs/pebble: failed to set earliest version: %w", err)
+	}
+	if err := batch.Commit(d.writeOpts); err != nil {
+		return 0, fmt.Errorf("mkvs/pebble: failed to commit batch: %w", err)
+	}
+
+	return pruned, pruneErr
+}
+
+// pruneVersionLocked removes all roots, nodes, tombstones and write logs of the given version.
+func (d *pebbleNodeDB) pruneVersionLocked(batch *pebble.Batch, version uint64) error {
+	rootsMeta, err := loadRootsMetadata(batch, version)
+	if err != nil {
+		return err
+	}
+
+	for rootHash, derivedRoots := range rootsMeta.Roots {
+		if len(derivedRoots) > 0 {
+			// Not a lone root.
+			continue
+		}
+
+		// Traverse the root and prune all items created in this version.
+		root := node.Root{
+			Namespace: d.namespace,
+			Version:   version,
+			Type:      rootHash.Type(),
+			Hash:      rootHash.Hash(),
+		}
+		var innerErr error
+		err := api.Visit(context.Background(), d, root, func(_ context.Context, n node.Node) bool {
+			h := n.GetHash()
+			key := nodeKey(&h, version)
+
+			var entryVersion uint64
+			if _, entryVersion, innerErr = getVersioned(d.db, key); innerErr != nil {
+				return false
+			}
+			if entryVersion == version {
+				if innerErr = deleteVersionedAtOrBelow(batch, key); innerErr != nil {
+					return false
+				}
+			}
+			return true
+		})
+		if innerErr != nil {
+			return innerErr
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = deleteVersionedAtOrBelow(batch, rootNodeKey(&rootHash, version)); err != nil {
+			return err
+		}
+	}
+
+	// Remove all tombstones of this version together with any entries they shadow. These are no
+	// longer reachable as all earlier versions have already been pruned.
+	if err = func() error {
+		prefix := tombstoneKeyFmt.Encode(version)
+		it, err := d.db.NewIter(&pebble.IterOptions{
+			LowerBound: prefix,
+			UpperBound: prefixEnd(prefix),
+		})
+		if err != nil {
+			return err
+		}
+		defer it.Close()
+
+		for it.First(); it.Valid(); it.Next() {
+			var (
+				decVersion uint64
+				th         api.TypedHash
+			)
+			if !tombstoneKeyFmt.Decode(it.Key(), &decVersion, &th) {
+				panic("mkvs/pebble: bad iterator")
+			}
+
+			var key []byte
+			switch th.Type() {
+			case node.RootTypeInvalid:
+				h := th.Hash()
+				key = nodeKey(&h, version)
+			default:
+				key = rootNodeKey(&th, version)
+			}
+			if err = deleteVersionedAtOrBelow(batch, key); err != nil {
+				return err
+			}
+		}
+		if err = it.Error(); err != nil {
+			return err
+		}
+		return batch.DeleteRange(prefix, prefixEnd(prefix), nil)
+	}(); err != nil {
+		return err
+	}
+
+	// Delete roots metadata.
+	if err = batch.Delete(rootsMetadataKeyFmt.Encode(version), nil); err != nil {
+		return fmt.Errorf("mkvs/pebble: failed to remove roots metadata: %w", err)
+	}
+
+	// Prune all write logs in version.
+	if !d.discardWriteLogs {
+		prefix := writeLogKeyFmt.Encode(version)
+		if err = batch.DeleteRange(prefix, prefixEnd(prefix), nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *pebbleNodeDB) StartMultipartInsert(version uint64) error {
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	if version == multipartVersionNone {
+		return api.ErrInvalidMultipartVersion
+	}
+
+	if d.multipartVersion != multipartVersionNone {
+		if d.multipartVersion != version {
+			return api.ErrMultipartInProgress
+		}
+		// Multipart already initialized at the same version, so this was
+		// probably called e.g. as part of a further checkpoint restore.
+		return nil
+	}
+
+	batch := d.db.NewBatch()
+	defer batch.Close()
+	if err := d.meta.setMultipartVersion(batch, version); err != nil {
+		return err
+	}
+	if err := batch.Commit(d.writeOpts); err != nil {
+		return err
+	}
+
+	d.multipartVersion = version
+
+	return nil
+}
+
+func (d *pebbleNodeDB) AbortMultipartInsert() error {
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	return d.cleanMultipartLocked(true)
+}
+
+func (d *pebbleNodeDB) GetMultipartVersion() uint64 {
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	return d.multipartVersion
+}
+
+func (d *pebbleNodeDB) MultipartProgress() *api.MultipartProgress {
+	return api.UntrackedMultipartProgress(d)
+}
+
+func (d *pebbleNodeDB) NewBatch(oldRoot node.Root, version uint64, chunk bool) (api.Batch, error) {
+	if d.readOnly {
+		return nil, api.ErrReadOnly
+	}
+
+	d.metaUpdateLock.Lock()
+	defer d.metaUpdateLock.Unlock()
+
+	if d.multipartVersion != multipartVersionNone && d.multipartVersion != version {
+		return nil, api.ErrInvalidMultipartVersion
+	}
+	if chunk != (d.multipartVersion != multipartVersionNone) {
+		return nil, api.ErrMultipartInProgress
+	}
+
+	return &pebbleBatch{
+		db:        d,
+		bat:       d.db.NewIndexedBatch(),
+		multipart: d.multipartVersion != multipartVersionNone,
+		oldRoot:   oldRoot,
+		version:   version,
+		chunk:     chunk,
+	}, nil
+}
+
+func (d *pebbleNodeDB) Size() (int64, error) {
+	return int64(d.db.Metrics().DiskSpaceUsage()), nil //nolint:gosec
+}
+
+func (d *pebbleNodeDB) Stats(countRoots bool) (*api.Stats, error) {
+	var stats api.Stats
+	metrics := d.db.Metrics()
+	stats.LSMSize = int64(metrics.DiskSpaceUsage())        //nolint:gosec
+	stats.PendingGarbage = int64(metrics.Table.ZombieSize) //nolint:gosec
+
+	stats.EarliestVersion = d.meta.getEarliestVersion()
+	if version, exists := d.meta.getLastFinalizedVersion(); exists {
+		stats.LatestVersion = &version
+	}
+
+	if !countRoots {
+		return &stats, nil
+	}
+
+	// Count root nodes whose most recent entry is present as these are removed once a root is
+	// pruned. The most recent entry of each root node comes first due to inverted versions.
+	prefix := rootNodeKeyFmt.Encode()
+	it, err := d.db.NewIter(&pebble.IterOptions{
+		LowerBound: prefix,
+		UpperBound: prefixEnd(prefix),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var (
+		numRoots uint64
+		lastRoot []byte
+	)
+	for it.First(); it.Valid(); it.Next() {
+		root := versionedPrefix(it.Key())
+		if lastRoot != nil && string(root) == string(lastRoot) {
+			continue
+		}
+		lastRoot = append(lastRoot[:0], root...)
+
+		if value := it.Value(); len(value) > 0 && value[0] == versionedPresent {
+			numRoots++
+		}
+	}
+	if err = it.Error(); err != nil {
+		return nil, err
+	}
+	stats.NumRoots = &numRoots
+	return &stats, nil
+}
+
+func (d *pebbleNodeDB) Sync() error {
+	return d.db.LogData(nil, pebble.Sync)
+}
+
+func (d *pebbleNodeDB) Close() {
+	d.closeOnce.Do(func() {
+		d.pins.Close()
+
+		if err := d.db.Close(); err != nil {
+			d.logger.Error("close returned error",
+				"err", err,
+			)
+		}
+	})
+}
+
+type pebbleBatch struct {
+	api.BaseBatch
+
+	db *pebbleNodeDB
+	// bat is the batch holding all node and metadata updates which are committed atomically.
+	bat *pebble.Batch
+	// multipart specifies whether inserted nodes should be logged for a multipart restore.
+	multipart bool
+
+	oldRoot node.Root
+	version uint64
+	chunk   bool
+
+	writeLog     writelog.WriteLog
+	annotations  writelog.Annotations
+	updatedNodes []updatedNode
+}
+
+// Implements api.Batch.
+func (ba *pebbleBatch) PutWriteLog(writeLog writelog.WriteLog, annotations writelog.Annotations) error {
+	if ba.chunk {
+		return fmt.Errorf("mkvs/pebble: cannot put write log in chunk mode")
+	}
+	if ba.db.discardWriteLogs {
+		return nil
+	}
+
+	ba.writeLog = writeLog
+	ba.annotations = annotations
+	return nil
+}
+
+// Implements api.Batch.
+func (ba *pebbleBatch) RemoveNodes(nodes []*node.Pointer) error {
+	if ba.chunk {
+		return fmt.Errorf("mkvs/pebble: cannot remove nodes in chunk mode")
+	}
+
+	for _, ptr := range nodes {
+		ba.updatedNodes = append(ba.updatedNodes, updatedNode{
+			Removed: true,
+			Hash:    ptr.GetHash(),
+		})
+	}
+	return nil
+}
+
+// Implements api.Batch.
+func (ba *pebbleBatch) Commit(root node.Root) error {
+	if ba.bat == nil {
+		return api.ErrBatchClosed
+	}
+
+	ba.db.metaUpdateLock.Lock()
+	defer ba.db.metaUpdateLock.Unlock()
+
+	if ba.db.multipartVersion != multipartVersionNone && ba.db.multipartVersion != root.Version {
+		return api.ErrInvalidMultipartVersion
+	}
+
+	if err := ba.db.sanityCheckNamespace(root.Namespace); err != nil {
+		return err
+	}
+	if !root.Follows(&ba.oldRoot) {
+		return api.ErrRootMustFollowOld
+	}
+
+	// Make sure that the version that we try to commit into has not yet been finalized.
+	lastFinalizedVersion, exists := ba.db.meta.getLastFinalizedVersion()
+	if exists && lastFinalizedVersion >= root.Version {
+		return api.ErrAlreadyFinalized
+	}
+
+	// Update the set of roots for this version. As the batch is indexed, reads observe any
+	// metadata updates made earlier in the same batch.
+	rootsMeta, err := loadRootsMetadata(ba.bat, root.Version)
+	if err != nil {
+		return err
+	}
+
+	rootHash := api.TypedHashFromRoot(root)
+	if err = ba.bat.Set(rootNodeKey(&rootHash, root.Version), versionedValue(nil), nil); err != nil {
+		return err
+	}
+	if ba.multipart {
+		if err = ba.bat.Set(multipartRestoreNodeLogKeyFmt.Encode(&rootHash), []byte{}, nil); err != nil {
+			return err
+		}
+	}
+
+	if rootsMeta.Roots[rootHash] != nil {
+		// Root already exists, no need to do anything since if the hash matches, everything will
+		// be identical and we would just be duplicating work.
+		//
+		// If we are importing a chunk, there can be multiple commits for the same root.
+		if !ba.chunk {
+			ba.Reset()
+			return ba.BaseBatch.Commit(root)
+		}
+	} else {
+		// Create root with no derived roots.
+		rootsMeta.Roots[rootHash] = []api.TypedHash{}
+
+		if err = rootsMeta.save(ba.bat); err != nil {
+			return fmt.Errorf("mkvs/pebble: failed to save roots metadata: %w", err)
+		}
+	}
+
+	if ba.chunk {
+		// Skip most of metadata updates if we are just importing chunks.
+		key := rootUpdatedNodesKeyFmt.Encode(root.Version, &rootHash)
+		if err = ba.bat.Set(key, cbor.Marshal([]updatedNode{}), nil); err != nil {
+			return fmt.Errorf("mkvs/pebble: set returned error: %w", err)
+		}
+	} else {
+		// Update the root link for the old root.
+		oldRootHash := api.TypedHashFromRoot(ba.oldRoot)
+		if !ba.oldRoot.Hash.IsEmpty() {
+			if ba.oldRoot.Version < ba.db.meta.getEarliestVersion() && ba.oldRoot.Version != root.Version {
+				return api.ErrPreviousVersionMismatch
+			}
+
+			var oldRootsMeta *rootsMetadata
+			oldRootsMeta, err = loadRootsMetadata(ba.bat, ba.oldRoot.Version)
+			if err != nil {
+				return err
+			}
+
+			if _, ok := oldRootsMeta.Roots[oldRootHash]; !ok {
+				return api.ErrRootNotFound
+			}
+
+			oldRootsMeta.Roots[oldRootHash] = append(oldRootsMeta.Roots[oldRootHash], rootHash)
+			if err = oldRootsMeta.save(ba.bat); err != nil {
+				return fmt.Errorf("mkvs/pebble: failed to save old roots metadata: %w", err)
+			}
+		}
+
+		// Store updated nodes (only needed until the version is finalized).
+		key := rootUpdatedNodesKeyFmt.Encode(root.Version, &rootHash)
+		if err = ba.bat.Set(key, cbor.Marshal(ba.updatedNodes), nil); err != nil {
+			return fmt.Errorf("mkvs/pebble: set returned error: %w", err)
+		}
+
+		// Store write log.
+		if ba.writeLog != nil && ba.annotations != nil {
+			log := api.MakeHashedDBWriteLog(ba.writeLog, ba.annotations)
+			key := writeLogKeyFmt.Encode(root.Version, &rootHash, &oldRootHash)
+			if err = ba.bat.Set(key, cbor.Marshal(log), nil); err != nil {
+				return fmt.Errorf("mkvs/pebble: set new write log returned error: %w", err)
+			}
+		}
+	}
+
+	// Commit node and metadata updates atomically.
+	if err = ba.bat.Commit(ba.db.writeOpts); err != nil {
+		return fmt.Errorf("mkvs/pebble: failed to commit batch: %w", err)
+	}
+
+	ba.Reset()
+
+	return ba.BaseBatch.Commit(root)
+}
+
+// Implements api.Batch.
+func (ba *pebbleBatch) Size() (int64, int) {
+	if ba.bat == nil {
+		return 0, 0
+	}
+	return int64(ba.bat.Len()), int(ba.bat.Count())
+}
+
+// Implements api.Batch.
+func (ba *pebbleBatch) Reset() {
+	if ba.bat == nil {
+		// Batch has been closed.
+		return
+	}
+	_ = ba.bat.Close()
+	ba.bat = ba.db.db.NewIndexedBatch()
+	ba.writeLog = nil
+	ba.annotations = nil
+	ba.updatedNodes = nil
+}
+
+// Implements api.Batch.
+func (ba *pebbleBatch) Close() {
+	if ba.bat == nil {
+		return
+	}
+	_ = ba.bat.Close()
+	ba.bat = nil
+	ba.writeLog = nil
+	ba.annotations = nil
+	ba.updatedNodes = nil
+}
+
+// Implements api.Batch.
+func (ba *pebbleBatch) PutNode(ptr *node.Pointer) error {
+	data, err := ptr.Node.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	h := ptr.Node.GetHash()
+	ba.updatedNodes = append(ba.updatedNodes, updatedNode{Hash: h})
+	key := nodeKey(&h, ba.version)
+	if ba.multipart {
+		// Only log nodes that were not already present so that an aborted restore does not
+		// remove any nodes that existed before it was started.
+		_, _, err = getVersioned(ba.db.db, key)
+		switch {
+		case err == nil:
+		case errors.Is(err, errVersionedNotFound):
+			th := api.TypedHashFromParts(node.RootTypeInvalid, h)
+			if err = ba.bat.Set(multipartRestoreNodeLogKeyFmt.Encode(&th), []byte{}, nil); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+
+	return ba.bat.Set(key, versionedValue(data), nil)
+}
+
+// Implements api.Batch.
+func (ba *pebbleBatch) VisitCleanNode(*node.Pointer, *node.Pointer) error {
+	return nil
+}
+
+// Implements api.Batch.
+func (ba *pebbleBatch) VisitDirtyNode(*node.Pointer, *node.Pointer) error {
+	return nil
+}
@@ -0,0 +1,100 @@
+package pebble
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/cockroachdb/pebble"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+)
+
+// Nodes and root nodes are versioned by appending the inverted version to the key so that the
+// most recent entry at or below a given version can be found with a single seek. Each versioned
+// value starts with a marker byte which allows entries to be deleted as of a given version while
+// keeping them available in earlier versions.
+const (
+	// versionedDeleted is the marker of a versioned value that has been deleted.
+	versionedDeleted byte = 0x00
+	// versionedPresent is the marker of a versioned value that is present.
+	versionedPresent byte = 0x01
+)
+
+// errVersionedNotFound is the error returned when a versioned key is not present at the given
+// version.
+var errVersionedNotFound = errors.New("mkvs/pebble: versioned key not found")
+
+// invertVersion converts a version to its inverted representation used in versioned keys.
+func invertVersion(version uint64) uint64 {
+	return math.MaxUint64 - version
+}
+
+func nodeKey(h *hash.Hash, version uint64) []byte {
+	return nodeKeyFmt.Encode(h, invertVersion(version))
+}
+
+func rootNodeKey(th *api.TypedHash, version uint64) []byte {
+	return rootNodeKeyFmt.Encode(th, invertVersion(version))
+}
+
+// versionedPrefix returns the prefix shared by all versions of the given versioned key.
+func versionedPrefix(key []byte) []byte {
+	return key[:len(key)-8]
+}
+
+// versionedValue returns a versioned value holding the given data.
+func versionedValue(data []byte) []byte {
+	return append([]byte{versionedPresent}, data...)
+}
+
+// getVersioned returns the data of the most recent entry of the given versioned key at or below
+// the version encoded in the key, together with the version of the entry.
+//
+// In case there is no such entry or the entry has been deleted, errVersionedNotFound is returned.
+func getVersioned(r pebble.Reader, key []byte) ([]byte, uint64, error) {
+	it, err := r.NewIter(&pebble.IterOptions{
+		LowerBound: key,
+		UpperBound: prefixEnd(versionedPrefix(key)),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer it.Close()
+
+	if !it.First() {
+		if err = it.Error(); err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, errVersionedNotFound
+	}
+
+	value := it.Value()
+	if len(value) == 0 || value[0] != versionedPresent {
+		return nil, 0, errVersionedNotFound
+	}
+	entryKey := it.Key()
+	version := invertVersion(binary.BigEndian.Uint64(entryKey[len(entryKey)-8:]))
+
+	return append([]byte{}, value[1:]...), version, nil
+}
+
+// deleteVersionedAtOrBelow removes all entries of the given versioned key at or below the version
+// encoded in the key.
+func deleteVersionedAtOrBelow(b *pebble.Batch, key []byte) error {
+	return b.DeleteRange(key, prefixEnd(versionedPrefix(key)), nil)
+}
+
+// prefixEnd returns the smallest key that is larger than all keys with the given prefix or nil
+// in case there is no such key.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		end[i]++
+		if end[i] != 0 {
+			return end[:i+1]
+		}
+	}
+	return nil
+}
@@ -24,7 +24,8 @@ func (t *tree) SyncIterate(ctx context.Context, request *syncer.IterateRequest)
 	if !t.cache.pendingRoot.IsClean() {
 		return nil, syncer.ErrDirtyRoot
 	}
-	pb, err := syncer.NewProofBuilderForVersion(request.Tree.Root.Hash, request.Tree.Root.Hash, request.ProofVersion)
+	proofVersion := syncer.NegotiateProofVersion(request.ProofVersion, request.MaxProofVersion)
+	pb, err := syncer.NewProofBuilderForVersion(request.Tree.Root.Hash, request.Tree.Root.Hash, proofVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -67,9 +68,10 @@ func (t *tree) newFetcherSyncIterate(key node.Key, prefetch uint16) readSyncFetc
 				Root:     t.cache.syncRoot,
 				Position: ptr.Hash,
 			},
-			Key:          key,
-			Prefetch:     prefetch,
-			ProofVersion: syncProofsVersion,
+			Key:             key,
+			Prefetch:        prefetch,
+			ProofVersion:    syncProofsVersion,
+			MaxProofVersion: syncMaxProofsVersion,
 		})
 		if err != nil {
 			return nil, err
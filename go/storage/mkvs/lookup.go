@@ -8,8 +8,13 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/syncer"
 )
 
-// Use version 0 proofs in sync requests for now.
-const syncProofsVersion uint16 = 0
+const (
+	// syncProofsVersion is the proof version requested from servers that do not support proof
+	// version negotiation.
+	syncProofsVersion uint16 = 0
+	// syncMaxProofsVersion is the maximum proof version understood when syncing.
+	syncMaxProofsVersion uint16 = syncer.LatestProofVersion
+)
 
 // Implements Tree.
 func (t *tree) Get(ctx context.Context, key []byte) ([]byte, error) {
@@ -51,7 +56,8 @@ func (t *tree) SyncGet(ctx context.Context, request *syncer.GetRequest) (*syncer
 	// Remember where the path from root to target node ends (will end).
 	t.cache.markPosition()
 
-	pb, err := syncer.NewProofBuilderForVersion(request.Tree.Root.Hash, request.Tree.Position, request.ProofVersion)
+	proofVersion := syncer.NegotiateProofVersion(request.ProofVersion, request.MaxProofVersion)
+	pb, err := syncer.NewProofBuilderForVersion(request.Tree.Root.Hash, request.Tree.Position, proofVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -82,6 +88,7 @@ func (t *tree) newFetcherSyncGet(key node.Key, includeSiblings bool) readSyncFet
 			Key:             key,
 			IncludeSiblings: includeSiblings,
 			ProofVersion:    syncProofsVersion,
+			MaxProofVersion: syncMaxProofsVersion,
 		})
 		if err != nil {
 			return nil, err
@@ -20,6 +20,10 @@ var (
 	// ErrKnownRootMismatch is the error returned by CommitKnown when the known
 	// root mismatches.
 	ErrKnownRootMismatch = errors.New("mkvs: known root mismatch")
+
+	// ErrWriteLogNotSorted is the error returned by ApplyWriteLogStreaming when
+	// the write log keys are not sorted in strictly increasing order.
+	ErrWriteLogNotSorted = errors.New("mkvs: write log not sorted")
 )
 
 // ImmutableKeyValueTree is the immutable key-value store tree interface.
@@ -73,6 +77,16 @@ type OverlayTree interface {
 	//
 	// Returns the underlying tree on success.
 	Commit(ctx context.Context) (KeyValueTree, error)
+
+	// WriteLog returns the modifications held by the overlay in key order, where removals have
+	// nil values. Modifications held by any overlays below this one are not included.
+	WriteLog() writelog.WriteLog
+
+	// RootHash computes the root hash that the tree at the bottom of the overlay stack would
+	// have after committing all modifications of this overlay and of any overlays below it.
+	//
+	// None of the trees are modified and nothing is persisted.
+	RootHash(ctx context.Context) (hash.Hash, error)
 }
 
 // Tree is a general MKVS tree interface.
@@ -87,7 +101,8 @@ type Tree interface {
 
 	// ApplyWriteLog applies the operations from a write log to the current tree.
 	//
-	// The caller is responsible for calling Commit.
+	// The caller is responsible for calling Commit. To apply large sorted write
+	// logs with bounded memory use, see ApplyWriteLogStreaming.
 	ApplyWriteLog(ctx context.Context, wl writelog.Iterator) error
 
 	// CommitKnown checks that the computed root matches a known root and
@@ -96,7 +111,7 @@ type Tree interface {
 	//
 	// In case the computed root doesn't match the known root, the update
 	// is NOT committed and ErrKnownRootMismatch is returned.
-	CommitKnown(ctx context.Context, root node.Root) (writelog.WriteLog, error)
+	CommitKnown(ctx context.Context, root node.Root, options ...CommitOption) (writelog.WriteLog, error)
 
 	// Commit commits tree updates to the underlying database and returns
 	// the write log and new merkle root.
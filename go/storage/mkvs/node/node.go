@@ -90,9 +90,10 @@ type Root struct {
 	Hash hash.Hash `json:"hash"`
 }
 
-// String returns the string representation of a storage root.
+// String returns the canonical string representation of a storage root, i.e.
+// "<namespace>:<version>:<type>:<hash>". Use ParseRoot to parse it.
 func (r Root) String() string {
-	return fmt.Sprintf("<Root ns=%s version=%d type=%v hash=%s>", r.Namespace, r.Version, r.Type, r.Hash)
+	return r.Namespace.String() + rootSeparator + r.ShortString()
 }
 
 // Empty sets the storage root to an empty root.
@@ -0,0 +1,111 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+)
+
+// rootSeparator separates the components of the string representation of a storage root.
+const rootSeparator = ":"
+
+var (
+	// ErrMalformedRoot is the error when a malformed storage root string is encountered.
+	ErrMalformedRoot = errors.New("mkvs: malformed root")
+	// ErrMalformedRootType is the error when a malformed storage root type string is encountered.
+	ErrMalformedRootType = errors.New("mkvs: malformed root type")
+)
+
+// ParseRootType parses the string representation of a valid storage root type.
+func ParseRootType(s string) (RootType, error) {
+	for rt := RootTypeInvalid + 1; rt <= RootTypeMax; rt++ {
+		if s == rt.String() {
+			return rt, nil
+		}
+	}
+	return RootTypeInvalid, fmt.Errorf("%w: '%s'", ErrMalformedRootType, s)
+}
+
+// ShortString returns the short string representation of a storage root which omits the
+// namespace, i.e. "<version>:<type>:<hash>".
+func (r Root) ShortString() string {
+	return strings.Join([]string{
+		strconv.FormatUint(r.Version, 10),
+		r.Type.String(),
+		r.Hash.String(),
+	}, rootSeparator)
+}
+
+// ParseRoot parses the full string representation of a storage root, as returned by String.
+//
+// Only the canonical form is accepted, i.e. the namespace and the hash must be lowercase hex and
+// the version must not have leading zeros.
+func ParseRoot(s string) (Root, error) {
+	parts := strings.SplitN(s, rootSeparator, 2)
+	if len(parts) != 2 {
+		return Root{}, fmt.Errorf("%w: expected <namespace>:<version>:<type>:<hash>", ErrMalformedRoot)
+	}
+
+	var ns common.Namespace
+	if err := ns.UnmarshalHex(parts[0]); err != nil {
+		return Root{}, fmt.Errorf("%w: bad namespace: %w", ErrMalformedRoot, err)
+	}
+	root, err := parseShortRoot(ns, parts[1])
+	if err != nil {
+		return Root{}, err
+	}
+	if root.String() != s {
+		return Root{}, fmt.Errorf("%w: non-canonical form", ErrMalformedRoot)
+	}
+	return root, nil
+}
+
+// ParseRootInNamespace parses either the full or the short string representation of a storage
+// root. In case the short form is used, the root is in the given namespace. In case the full form
+// is used, the namespace must match the given namespace.
+func ParseRootInNamespace(ns common.Namespace, s string) (Root, error) {
+	if strings.Count(s, rootSeparator) != 2 {
+		root, err := ParseRoot(s)
+		if err != nil {
+			return Root{}, err
+		}
+		if !root.Namespace.Equal(&ns) {
+			return Root{}, fmt.Errorf("%w: namespace mismatch (expected: %s got: %s)", ErrMalformedRoot, ns, root.Namespace)
+		}
+		return root, nil
+	}
+
+	root, err := parseShortRoot(ns, s)
+	if err != nil {
+		return Root{}, err
+	}
+	if root.ShortString() != s {
+		return Root{}, fmt.Errorf("%w: non-canonical form", ErrMalformedRoot)
+	}
+	return root, nil
+}
+
+func parseShortRoot(ns common.Namespace, s string) (Root, error) {
+	parts := strings.Split(s, rootSeparator)
+	if len(parts) != 3 {
+		return Root{}, fmt.Errorf("%w: expected <version>:<type>:<hash>", ErrMalformedRoot)
+	}
+
+	root := Root{
+		Namespace: ns,
+	}
+	var err error
+	if root.Version, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return Root{}, fmt.Errorf("%w: bad version: %w", ErrMalformedRoot, err)
+	}
+	if root.Type, err = ParseRootType(parts[1]); err != nil {
+		return Root{}, fmt.Errorf("%w: %w", ErrMalformedRoot, err)
+	}
+	if err = root.Hash.UnmarshalHex(parts[2]); err != nil {
+		return Root{}, fmt.Errorf("%w: bad hash: %w", ErrMalformedRoot, err)
+	}
+	return root, nil
+}
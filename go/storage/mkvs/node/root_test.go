@@ -0,0 +1,155 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+)
+
+const (
+	goldenNamespace = "8000000000000000000000000000000000000000000000000000000000000001"
+	goldenHash      = "c672b8d1ef56ed28ab87c3622c5114069bdd3ad7b8f9737498d0c01ecef0967a"
+)
+
+func goldenRoot(t *testing.T, version uint64, typ RootType) Root {
+	root := Root{
+		Version: version,
+		Type:    typ,
+	}
+	require.NoError(t, root.Namespace.UnmarshalHex(goldenNamespace), "UnmarshalHex")
+	require.NoError(t, root.Hash.UnmarshalHex(goldenHash), "UnmarshalHex")
+	return root
+}
+
+func TestRootStringGolden(t *testing.T) {
+	require := require.New(t)
+
+	// The canonical format must never change as operators rely on it when passing roots between
+	// tools.
+	for _, tc := range []struct {
+		root  Root
+		full  string
+		short string
+	}{
+		{
+			goldenRoot(t, 0, RootTypeState),
+			goldenNamespace + ":0:state-root:" + goldenHash,
+			"0:state-root:" + goldenHash,
+		},
+		{
+			goldenRoot(t, 42, RootTypeIO),
+			goldenNamespace + ":42:io-root:" + goldenHash,
+			"42:io-root:" + goldenHash,
+		},
+		{
+			goldenRoot(t, 18446744073709551615, RootTypeState),
+			goldenNamespace + ":18446744073709551615:state-root:" + goldenHash,
+			"18446744073709551615:state-root:" + goldenHash,
+		},
+	} {
+		require.Equal(tc.full, tc.root.String())
+		require.Equal(tc.short, tc.root.ShortString())
+
+		root, err := ParseRoot(tc.full)
+		require.NoError(err, "ParseRoot")
+		require.True(root.Equal(&tc.root))
+
+		root, err = ParseRootInNamespace(tc.root.Namespace, tc.short)
+		require.NoError(err, "ParseRootInNamespace(short)")
+		require.True(root.Equal(&tc.root))
+
+		root, err = ParseRootInNamespace(tc.root.Namespace, tc.full)
+		require.NoError(err, "ParseRootInNamespace(full)")
+		require.True(root.Equal(&tc.root))
+
+		_, err = ParseRoot(tc.short)
+		require.ErrorIs(err, ErrMalformedRoot, "ParseRoot should reject the short form")
+	}
+}
+
+func TestParseRootInvalid(t *testing.T) {
+	require := require.New(t)
+
+	for _, s := range []string{
+		"",
+		":::",
+		goldenNamespace,
+		goldenNamespace + ":1:state-root",
+		goldenNamespace + ":1:state-root:" + goldenHash + ":",
+		goldenNamespace + ":-1:state-root:" + goldenHash,
+		goldenNamespace + ":+1:state-root:" + goldenHash,
+		goldenNamespace + ":01:state-root:" + goldenHash,
+		goldenNamespace + ": 1:state-root:" + goldenHash,
+		goldenNamespace + ":18446744073709551616:state-root:" + goldenHash,
+		goldenNamespace + ":1:state:" + goldenHash,
+		goldenNamespace + ":1:invalid:" + goldenHash,
+		goldenNamespace + ":1:STATE-ROOT:" + goldenHash,
+		goldenNamespace + ":1:state-root:" + goldenHash[2:],
+		goldenNamespace + ":1:state-root:" + goldenHash + "00",
+		goldenNamespace + ":1:state-root:C672b8d1ef56ed28ab87c3622c5114069bdd3ad7b8f9737498d0c01ecef0967a",
+		"8000000000000000000000000000000000000000000000000000000000000001A:1:state-root:" + goldenHash,
+		"0x8000000000000000000000000000000000000000000000000000000000000001:1:state-root:" + goldenHash,
+		"<Root ns=" + goldenNamespace + " version=1 type=state-root hash=" + goldenHash + ">",
+	} {
+		_, err := ParseRoot(s)
+		require.ErrorIs(err, ErrMalformedRoot, "ParseRoot(%q)", s)
+	}
+
+	// The namespace of the full form must match.
+	var otherNs common.Namespace
+	_, err := ParseRootInNamespace(otherNs, goldenNamespace+":1:state-root:"+goldenHash)
+	require.ErrorIs(err, ErrMalformedRoot)
+
+	for _, s := range []string{
+		"1:state-root",
+		"01:state-root:" + goldenHash,
+		"1:io:" + goldenHash,
+		"1:state-root:" + goldenHash[:62] + "0A",
+	} {
+		_, err = ParseRootInNamespace(otherNs, s)
+		require.ErrorIs(err, ErrMalformedRoot, "ParseRootInNamespace(%q)", s)
+	}
+}
+
+func TestParseRootType(t *testing.T) {
+	require := require.New(t)
+
+	for _, rt := range []RootType{RootTypeState, RootTypeIO} {
+		parsed, err := ParseRootType(rt.String())
+		require.NoError(err, "ParseRootType")
+		require.Equal(rt, parsed)
+	}
+	for _, s := range []string{"", "invalid", RootTypeInvalid.String(), RootType(42).String()} {
+		_, err := ParseRootType(s)
+		require.ErrorIs(err, ErrMalformedRootType, "ParseRootType(%q)", s)
+	}
+}
+
+func FuzzParseRoot(f *testing.F) {
+	// Seed corpus.
+	f.Add(goldenNamespace + ":0:state-root:" + goldenHash)
+	f.Add(goldenNamespace + ":42:io-root:" + goldenHash)
+	f.Add("42:io-root:" + goldenHash)
+	f.Add(goldenNamespace + ":01:state-root:" + goldenHash)
+
+	f.Fuzz(func(t *testing.T, s string) {
+		// Any successfully parsed root must round-trip to the exact same string.
+		if root, err := ParseRoot(s); err == nil {
+			require.Equal(t, s, root.String())
+
+			reparsed, err := ParseRoot(root.String())
+			require.NoError(t, err, "ParseRoot")
+			require.True(t, reparsed.Equal(&root))
+		}
+
+		var ns common.Namespace
+		if root, err := ParseRootInNamespace(ns, s); err == nil {
+			require.True(t, root.Namespace.Equal(&ns))
+			if s != root.String() {
+				require.Equal(t, s, root.ShortString())
+			}
+		}
+	})
+}
@@ -3,11 +3,15 @@ package mkvs
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/tidwall/btree"
 
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/syncer"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
 )
 
 var _ OverlayTree = (*treeOverlay)(nil)
@@ -130,6 +134,43 @@ func (o *treeOverlay) Commit(ctx context.Context) (KeyValueTree, error) {
 	return o.inner, nil
 }
 
+// Implements OverlayTree.
+func (o *treeOverlay) WriteLog() writelog.WriteLog {
+	keys := slices.Sorted(maps.Keys(o.dirty))
+	log := make(writelog.WriteLog, 0, len(keys))
+	for _, key := range keys {
+		// Removed keys are not present in the overlay and have nil values.
+		value, _ := o.overlay.Get(key)
+		log = append(log, writelog.LogEntry{Key: []byte(key), Value: value})
+	}
+	return log
+}
+
+// Implements OverlayTree.
+func (o *treeOverlay) RootHash(ctx context.Context) (hash.Hash, error) {
+	// Collect the modifications of all overlays down to the underlying tree.
+	var writeLogs []writelog.WriteLog
+	var inner KeyValueTree = o
+	for {
+		switch t := inner.(type) {
+		case *treeOverlay:
+			if t.inner == nil {
+				return hash.Hash{}, ErrClosed
+			}
+			writeLogs = append(writeLogs, t.WriteLog())
+			inner = t.inner
+		case *treeOverlayWrapper:
+			inner = t.Tree
+		case *tree:
+			// Modifications of inner overlays must be applied first.
+			slices.Reverse(writeLogs)
+			return t.speculativeRootHash(ctx, writeLogs)
+		default:
+			return hash.Hash{}, fmt.Errorf("tree overlay: root hash not supported for %T", inner)
+		}
+	}
+}
+
 // Implements ClosableTree.
 func (o *treeOverlay) Close() {
 	if o.inner == nil {
@@ -250,8 +291,78 @@ func (tow *treeOverlayWrapper) Commit(context.Context) (KeyValueTree, error) {
 	return tow.Tree, nil
 }
 
+// Implements OverlayTree.
+func (tow *treeOverlayWrapper) WriteLog() writelog.WriteLog {
+	t, ok := tow.Tree.(*tree)
+	if !ok {
+		panic("write log not supported")
+	}
+	return t.pendingLog()
+}
+
+// Implements OverlayTree.
+func (tow *treeOverlayWrapper) RootHash(ctx context.Context) (hash.Hash, error) {
+	t, ok := tow.Tree.(*tree)
+	if !ok {
+		return hash.Hash{}, fmt.Errorf("tree overlay: root hash not supported for %T", tow.Tree)
+	}
+	return t.speculativeRootHash(ctx, nil)
+}
+
 // NewOverlayWrapper wraps an existing tree so it can behave as an overlay tree without any actual
 // overlay overhead.
 func NewOverlayWrapper(inner Tree) OverlayTree {
 	return &treeOverlayWrapper{inner}
 }
+
+// pendingLog returns the pending (uncommitted) updates of the tree in key order, where removals
+// have nil values.
+func (t *tree) pendingLog() writelog.WriteLog {
+	t.cache.Lock()
+	defer t.cache.Unlock()
+
+	log := make(writelog.WriteLog, 0, len(t.pendingWriteLog))
+	for _, entry := range t.pendingWriteLog {
+		log = append(log, writelog.LogEntry{Key: entry.key, Value: entry.value})
+	}
+	slices.SortFunc(log, func(a, b writelog.LogEntry) int {
+		return node.Key(a.Key).Compare(node.Key(b.Key))
+	})
+	return log
+}
+
+// speculativeRootHash computes the root hash that the tree would have after committing its
+// pending updates followed by the given write logs, without modifying the tree.
+//
+// The updates are applied to a separate tree that shares the node database and the read syncer
+// of this tree and is committed without persisting anything.
+func (t *tree) speculativeRootHash(ctx context.Context, writeLogs []writelog.WriteLog) (hash.Hash, error) {
+	t.cache.Lock()
+	if t.cache.isClosed() {
+		t.cache.Unlock()
+		return hash.Hash{}, ErrClosed
+	}
+	if t.withoutWriteLog && !t.cache.pendingRoot.IsClean() {
+		t.cache.Unlock()
+		return hash.Hash{}, fmt.Errorf("mkvs: cannot compute root hash of a dirty tree without a write log")
+	}
+	root := t.cache.getSyncRoot()
+	rs, ndb := t.cache.rs, t.cache.db
+	t.cache.Unlock()
+
+	writeLogs = append([]writelog.WriteLog{t.pendingLog()}, writeLogs...)
+
+	st := NewWithRoot(rs, ndb, root)
+	defer st.Close()
+
+	for _, log := range writeLogs {
+		if err := st.ApplyWriteLog(ctx, writelog.NewStaticIterator(log)); err != nil {
+			return hash.Hash{}, err
+		}
+	}
+	_, rootHash, err := st.Commit(ctx, root.Namespace, root.Version, NoPersist())
+	if err != nil {
+		return hash.Hash{}, err
+	}
+	return rootHash, nil
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	db "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	memoryDb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/memory"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
 )
@@ -223,3 +225,150 @@ func TestOverlay(t *testing.T) {
 	_, err = tree.Get(ctx, []byte("key"))
 	require.NoError(t, err, "Get")
 }
+
+func TestOverlaySpeculative(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := memoryDb.New(&db.Config{Namespace: testNs})
+	require.NoError(err, "New")
+	defer ndb.Close()
+
+	// Commit some items into the underlying tree.
+	tree := New(nil, ndb, node.RootTypeState)
+	defer tree.Close()
+	err = tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(writelog.WriteLog{
+		writelog.LogEntry{Key: []byte("key 1"), Value: []byte("one")},
+		writelog.LogEntry{Key: []byte("key 2"), Value: []byte("two")},
+		writelog.LogEntry{Key: []byte("key 3"), Value: []byte("three")},
+	}))
+	require.NoError(err, "ApplyWriteLog")
+	_, rootHash, err := tree.Commit(ctx, testNs, 0)
+	require.NoError(err, "Commit")
+
+	// Layer a block-level overlay and a transaction-level overlay on top.
+	blockOverlay := NewOverlay(tree)
+	defer blockOverlay.Close()
+	err = blockOverlay.Insert(ctx, []byte("key 4"), []byte("four"))
+	require.NoError(err, "Insert")
+	err = blockOverlay.Remove(ctx, []byte("key 2"))
+	require.NoError(err, "Remove")
+
+	txOverlay := NewOverlay(blockOverlay)
+	err = txOverlay.Insert(ctx, []byte("key 1"), []byte("uno"))
+	require.NoError(err, "Insert")
+	err = txOverlay.Remove(ctx, []byte("key 4"))
+	require.NoError(err, "Remove")
+
+	// Reads should go through all overlays and removals should shadow inner values.
+	for _, tc := range []struct {
+		key   string
+		value []byte
+	}{
+		{"key 1", []byte("uno")},
+		{"key 2", nil},
+		{"key 3", []byte("three")},
+		{"key 4", nil},
+	} {
+		var value []byte
+		value, err = txOverlay.Get(ctx, []byte(tc.key))
+		require.NoError(err, "Get")
+		require.Equal(tc.value, value, "value of %s from nested overlay should be correct", tc.key)
+	}
+	value, err := tree.Get(ctx, []byte("key 2"))
+	require.NoError(err, "Get")
+	require.Equal([]byte("two"), value, "removals should not propagate to the inner tree")
+
+	// Each overlay should only report its own modifications.
+	require.EqualValues(writelog.WriteLog{
+		{Key: []byte("key 2"), Value: nil},
+		{Key: []byte("key 4"), Value: []byte("four")},
+	}, blockOverlay.WriteLog())
+	require.EqualValues(writelog.WriteLog{
+		{Key: []byte("key 1"), Value: []byte("uno")},
+		{Key: []byte("key 4"), Value: nil},
+	}, txOverlay.WriteLog())
+
+	// Computing the root hash should not modify the trees or persist anything.
+	blockRootHash, err := blockOverlay.RootHash(ctx)
+	require.NoError(err, "RootHash")
+	txRootHash, err := txOverlay.RootHash(ctx)
+	require.NoError(err, "RootHash")
+	require.NotEqual(blockRootHash, txRootHash, "nested overlay root hash should include its modifications")
+	roots, err := ndb.GetRootsForVersion(1)
+	require.NoError(err, "GetRootsForVersion")
+	require.Empty(roots, "computing the root hash should not persist any roots")
+	wrapperRootHash, err := NewOverlayWrapper(tree).RootHash(ctx)
+	require.NoError(err, "RootHash")
+	require.Equal(rootHash, wrapperRootHash, "root hash of a clean tree should be unchanged")
+
+	// Discarding the transaction-level overlay should keep the block-level one intact.
+	txOverlay.Close()
+	_, err = txOverlay.RootHash(ctx)
+	require.ErrorIs(err, ErrClosed, "RootHash should fail on a closed overlay")
+	h, err := blockOverlay.RootHash(ctx)
+	require.NoError(err, "RootHash")
+	require.Equal(blockRootHash, h)
+
+	// The computed root hash should match the one of a real commit.
+	_, err = blockOverlay.Commit(ctx)
+	require.NoError(err, "Commit")
+	_, committedRootHash, err := tree.Commit(ctx, testNs, 1)
+	require.NoError(err, "Commit")
+	require.Equal(blockRootHash, committedRootHash, "computed root hash should match the committed one")
+}
+
+func TestSpeculativeOverlay(t *testing.T) {
+	ctx := context.Background()
+	require := require.New(t)
+
+	ndb, err := memoryDb.New(&db.Config{Namespace: testNs})
+	require.NoError(err, "New")
+	defer ndb.Close()
+
+	tree := New(nil, ndb, node.RootTypeState)
+	defer tree.Close()
+	err = tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(writelog.WriteLog{
+		writelog.LogEntry{Key: []byte("key 1"), Value: []byte("one")},
+		writelog.LogEntry{Key: []byte("key 2"), Value: []byte("two")},
+	}))
+	require.NoError(err, "ApplyWriteLog")
+	_, rootHash, err := tree.Commit(ctx, testNs, 0)
+	require.NoError(err, "Commit")
+
+	// Without overrides the state should be unchanged.
+	overlay, err := NewSpeculativeOverlay(ctx, tree, nil, DefaultOverrideLimits())
+	require.NoError(err, "NewSpeculativeOverlay")
+	value, err := overlay.Get(ctx, []byte("key 2"))
+	require.NoError(err, "Get")
+	require.Equal([]byte("two"), value)
+	h, err := overlay.RootHash(ctx)
+	require.NoError(err, "RootHash")
+	require.Equal(rootHash, h)
+	overlay.Close()
+
+	// Overrides should be layered on top of the state.
+	overrides := writelog.WriteLog{
+		writelog.LogEntry{Key: []byte("key 1"), Value: []byte("uno")},
+		writelog.LogEntry{Key: []byte("key 2")},
+		writelog.LogEntry{Key: []byte("key 3"), Value: []byte("tres")},
+	}
+	overlay, err = NewSpeculativeOverlay(ctx, tree, overrides, DefaultOverrideLimits())
+	require.NoError(err, "NewSpeculativeOverlay")
+	require.Equal(overrides, overlay.WriteLog())
+	value, err = overlay.Get(ctx, []byte("key 2"))
+	require.NoError(err, "Get")
+	require.Nil(value, "overridden key should be removed")
+	overlay.Close()
+
+	// Nothing should be persisted.
+	value, err = tree.Get(ctx, []byte("key 1"))
+	require.NoError(err, "Get")
+	require.Equal([]byte("one"), value)
+
+	// Limits should be enforced.
+	_, err = NewSpeculativeOverlay(ctx, tree, overrides, OverrideLimits{MaxCount: 2, MaxSize: 1024})
+	require.ErrorIs(err, ErrTooManyOverrides)
+	_, err = NewSpeculativeOverlay(ctx, tree, overrides, OverrideLimits{MaxCount: 3, MaxSize: 16})
+	require.ErrorIs(err, ErrOverridesTooLarge)
+}
@@ -0,0 +1,87 @@
+package mkvs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
+)
+
+const (
+	// DefaultMaxOverrideCount is the default maximum number of state overrides.
+	DefaultMaxOverrideCount = 64
+
+	// DefaultMaxOverrideSize is the default maximum total size (keys and values) of state
+	// overrides in bytes.
+	DefaultMaxOverrideSize = 64 * 1024
+)
+
+var (
+	// ErrTooManyOverrides is the error returned when the number of state overrides exceeds the
+	// configured limit.
+	ErrTooManyOverrides = errors.New("mkvs: too many state overrides")
+
+	// ErrOverridesTooLarge is the error returned when the total size of state overrides exceeds
+	// the configured limit.
+	ErrOverridesTooLarge = errors.New("mkvs: state overrides too large")
+)
+
+// OverrideLimits are the limits applied to state overrides.
+type OverrideLimits struct {
+	// MaxCount is the maximum number of overrides.
+	MaxCount uint64
+
+	// MaxSize is the maximum total size of override keys and values in bytes.
+	MaxSize uint64
+}
+
+// DefaultOverrideLimits returns the default state override limits.
+func DefaultOverrideLimits() OverrideLimits {
+	return OverrideLimits{
+		MaxCount: DefaultMaxOverrideCount,
+		MaxSize:  DefaultMaxOverrideSize,
+	}
+}
+
+// Validate checks the given state overrides against the limits.
+func (l *OverrideLimits) Validate(overrides writelog.WriteLog) error {
+	if uint64(len(overrides)) > l.MaxCount {
+		return fmt.Errorf("%w: %d (max: %d)", ErrTooManyOverrides, len(overrides), l.MaxCount)
+	}
+	var size uint64
+	for _, entry := range overrides {
+		size += uint64(len(entry.Key) + len(entry.Value))
+	}
+	if size > l.MaxSize {
+		return fmt.Errorf("%w: %d bytes (max: %d)", ErrOverridesTooLarge, size, l.MaxSize)
+	}
+	return nil
+}
+
+// NewSpeculativeOverlay creates a new overlay over the given tree with the given state overrides
+// layered on top. Overrides with nil values remove the corresponding keys.
+//
+// The overlay is meant for speculative execution (e.g., gas estimation) where the caller wants to
+// see the state as-if it were different. The caller must close the overlay without committing it
+// so that nothing is persisted.
+func NewSpeculativeOverlay(ctx context.Context, inner KeyValueTree, overrides writelog.WriteLog, limits OverrideLimits) (OverlayTree, error) {
+	if err := limits.Validate(overrides); err != nil {
+		return nil, err
+	}
+
+	overlay := NewOverlay(inner)
+	for _, entry := range overrides {
+		var err error
+		if entry.Value == nil {
+			err = overlay.Remove(ctx, entry.Key)
+		} else {
+			err = overlay.Insert(ctx, entry.Key, entry.Value)
+		}
+		if err != nil {
+			overlay.Close()
+			return nil, fmt.Errorf("mkvs: failed to apply state override: %w", err)
+		}
+	}
+	return overlay, nil
+}
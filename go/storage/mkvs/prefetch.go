@@ -39,9 +39,10 @@ func (t *tree) doPrefetchPrefixes(ctx context.Context, prefixes [][]byte, limit
 					Root:     t.cache.syncRoot,
 					Position: t.cache.syncRoot.Hash,
 				},
-				Prefixes:     prefixes,
-				Limit:        limit,
-				ProofVersion: syncProofsVersion,
+				Prefixes:        prefixes,
+				Limit:           limit,
+				ProofVersion:    syncProofsVersion,
+				MaxProofVersion: syncMaxProofsVersion,
 			})
 			if err != nil {
 				return nil, err
@@ -76,7 +77,8 @@ func (t *tree) SyncGetPrefixes(ctx context.Context, request *syncer.GetPrefixesR
 		}
 	}
 
-	pb, err := syncer.NewProofBuilderForVersion(request.Tree.Root.Hash, request.Tree.Root.Hash, request.ProofVersion)
+	proofVersion := syncer.NegotiateProofVersion(request.ProofVersion, request.MaxProofVersion)
+	pb, err := syncer.NewProofBuilderForVersion(request.Tree.Root.Hash, request.Tree.Root.Hash, proofVersion)
 	if err != nil {
 		return nil, err
 	}
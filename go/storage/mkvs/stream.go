@@ -0,0 +1,245 @@
+package mkvs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	db "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
+)
+
+// DefaultStreamingWindowSize is the default number of write log entries that are applied before
+// completed subtrees are flushed by ApplyWriteLogStreaming.
+const DefaultStreamingWindowSize = 1024
+
+// streamingBatch is a batch wrapper which ignores on-commit hooks.
+//
+// Hooks registered by doCommit would otherwise retain all committed nodes until the batch is
+// committed. The streaming apply updates the in-memory state of flushed nodes itself.
+type streamingBatch struct {
+	db.Batch
+}
+
+// Implements db.Batch.
+func (b *streamingBatch) OnCommit(func()) {}
+
+// ApplyWriteLogStreaming applies the given write log to the given root and commits the resulting
+// root under the given version into the node database, returning the new root hash.
+//
+// Write log entries must be sorted by key in strictly increasing order. Entries are applied in
+// windows of windowSize entries and after each window, all subtrees containing only keys smaller
+// than the next key are written to the database batch and dropped from memory. Peak memory use is
+// thus proportional to the depth of the tree times the window size instead of the size of the
+// write log. The resulting root is identical to applying the same write log via ApplyWriteLog
+// and committing the tree.
+//
+// In contrast to Commit, the write log of the new root is not stored in the node database. Note
+// that node database backends may still keep per-node bookkeeping for the pending batch.
+//
+// In case root has an empty hash, the write log is applied to an empty tree of the given root
+// type and namespace.
+func ApplyWriteLogStreaming(
+	ctx context.Context,
+	ndb db.NodeDB,
+	root node.Root,
+	version uint64,
+	wl writelog.Iterator,
+	windowSize int,
+) (hash.Hash, error) {
+	if windowSize <= 0 {
+		windowSize = DefaultStreamingWindowSize
+	}
+
+	var t *tree
+	oldRoot := root
+	switch root.Hash.IsEmpty() {
+	case true:
+		t = New(nil, ndb, root.Type, WithoutWriteLog()).(*tree)
+		oldRoot.Version = version
+	case false:
+		t = NewWithRoot(nil, ndb, root, WithoutWriteLog()).(*tree)
+	}
+	defer t.Close()
+
+	dbBatch, err := ndb.NewBatch(oldRoot, version, false)
+	if err != nil {
+		return hash.Hash{}, err
+	}
+	defer dbBatch.Close()
+	batch := &streamingBatch{dbBatch}
+
+	var (
+		lastKey []byte
+		window  []writelog.LogEntry
+		applied bool
+	)
+	for {
+		// Collect the next window of entries.
+		window = window[:0]
+		for len(window) < windowSize {
+			more, err := wl.Next()
+			if err != nil {
+				return hash.Hash{}, err
+			}
+			if !more {
+				break
+			}
+			entry, err := wl.Value()
+			if err != nil {
+				return hash.Hash{}, err
+			}
+			if lastKey != nil && bytes.Compare(lastKey, entry.Key) >= 0 {
+				return hash.Hash{}, fmt.Errorf("%w: key %X follows key %X", ErrWriteLogNotSorted, entry.Key, lastKey)
+			}
+			lastKey = entry.Key
+			window = append(window, entry)
+		}
+		if len(window) == 0 {
+			break
+		}
+
+		// All subtrees before the first key of this window are complete, flush them.
+		if applied {
+			if err = t.flushBefore(ctx, batch, window[0].Key); err != nil {
+				return hash.Hash{}, err
+			}
+			if err = batch.RemoveNodes(t.pendingRemovedNodes); err != nil {
+				return hash.Hash{}, err
+			}
+			t.pendingRemovedNodes = nil
+		}
+
+		for _, entry := range window {
+			if entry.Value == nil {
+				err = t.Remove(ctx, entry.Key)
+			} else {
+				err = t.Insert(ctx, entry.Key, entry.Value)
+			}
+			if err != nil {
+				return hash.Hash{}, err
+			}
+		}
+		applied = true
+	}
+
+	rootHash, err := doCommit(ctx, t.cache, batch, t.cache.pendingRoot, nil)
+	if err != nil {
+		return hash.Hash{}, err
+	}
+	if err = batch.RemoveNodes(t.pendingRemovedNodes); err != nil {
+		return hash.Hash{}, err
+	}
+	t.pendingRemovedNodes = nil
+
+	if err = batch.Commit(node.Root{
+		Namespace: root.Namespace,
+		Version:   version,
+		Type:      root.Type,
+		Hash:      rootHash,
+	}); err != nil {
+		return hash.Hash{}, err
+	}
+	return rootHash, nil
+}
+
+// flushBefore commits all dirty subtrees of the pending root that only contain keys smaller than
+// the given key into the batch and drops them from memory.
+//
+// Flushed subtrees hanging off the path to the given key are kept as (dirty) nodes without their
+// children, as later removals may need to merge them with their parent, while everything below
+// them is only kept as clean pointers.
+func (t *tree) flushBefore(ctx context.Context, batch db.Batch, key node.Key) error {
+	ptr := t.cache.pendingRoot
+	var bitDepth node.Depth
+	for ptr != nil && !ptr.Clean {
+		n, ok := ptr.Node.(*node.InternalNode)
+		if !ok {
+			return nil
+		}
+
+		_, keyRemainder := key.Split(bitDepth, key.BitLength())
+		cpLength := n.Label.CommonPrefixLen(n.LabelBitLength, keyRemainder, key.BitLength()-bitDepth)
+		if cpLength < n.LabelBitLength {
+			// Key diverges from the node's label, the whole subtree is either before or after it.
+			if bitDepth+cpLength < key.BitLength() && key.GetBit(bitDepth+cpLength) {
+				for _, child := range []*node.Pointer{n.LeafNode, n.Left, n.Right} {
+					if err := t.flushSubtree(ctx, batch, child, ptr); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+
+		bitLength := bitDepth + n.LabelBitLength
+		if key.BitLength() == bitLength {
+			return nil
+		}
+		if err := t.flushSubtree(ctx, batch, n.LeafNode, ptr); err != nil {
+			return err
+		}
+		if !key.GetBit(bitLength) {
+			ptr = n.Left
+		} else {
+			if err := t.flushSubtree(ctx, batch, n.Left, ptr); err != nil {
+				return err
+			}
+			ptr = n.Right
+		}
+		bitDepth = bitLength
+	}
+	return nil
+}
+
+// flushSubtree commits the dirty subtree rooted at the given pointer into the batch.
+//
+// Leaf nodes are kept in memory, while internal nodes stay dirty (as their label may still change)
+// and only keep clean pointers to their children.
+func (t *tree) flushSubtree(ctx context.Context, batch db.Batch, ptr, parent *node.Pointer) error {
+	if ptr == nil || ptr.Clean {
+		return nil
+	}
+
+	switch n := ptr.Node.(type) {
+	case *node.InternalNode:
+		if err := t.flushNode(ctx, batch, n.LeafNode, ptr, false); err != nil {
+			return err
+		}
+		for _, child := range []*node.Pointer{n.Left, n.Right} {
+			if err := t.flushNode(ctx, batch, child, ptr, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *node.LeafNode:
+		return t.flushNode(ctx, batch, ptr, parent, false)
+	default:
+		return nil
+	}
+}
+
+// flushNode commits the dirty subtree rooted at the given pointer into the batch and marks the
+// pointer as clean, optionally dropping the node from memory.
+func (t *tree) flushNode(ctx context.Context, batch db.Batch, ptr, parent *node.Pointer, drop bool) error {
+	if ptr == nil || ptr.Clean {
+		return nil
+	}
+	if _, err := doCommit(ctx, t.cache, batch, ptr, parent); err != nil {
+		return err
+	}
+
+	ptr.Clean = true
+	switch n := ptr.Node.(type) {
+	case *node.InternalNode:
+		n.Clean = true
+	case *node.LeafNode:
+		n.Clean = true
+	}
+	if drop {
+		ptr.Node = nil
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package mkvs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	db "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	badgerDb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/badger"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
+)
+
+const (
+	streamingEntries      = 1_000_000
+	streamingChunkEntries = 100_000
+	// streamingHeapBudget is the maximum heap growth allowed while streaming the synthetic write
+	// log, which is far below what is needed to keep the whole tree in memory.
+	streamingHeapBudget = 64 * 1024 * 1024
+)
+
+// syntheticWriteLog is a write log iterator which generates sorted entries on the fly.
+type syntheticWriteLog struct {
+	n     int
+	i     int
+	entry writelog.LogEntry
+}
+
+func newSyntheticWriteLog(n int) *syntheticWriteLog {
+	return &syntheticWriteLog{n: n}
+}
+
+func (s *syntheticWriteLog) Next() (bool, error) {
+	if s.i >= s.n {
+		s.entry = writelog.LogEntry{}
+		return false, nil
+	}
+	s.entry = writelog.LogEntry{
+		Key:   []byte(fmt.Sprintf("key %08d", s.i)),
+		Value: []byte(fmt.Sprintf("value %d", s.i)),
+	}
+	s.i++
+	return true, nil
+}
+
+func (s *syntheticWriteLog) Value() (writelog.LogEntry, error) {
+	if s.entry.Key == nil {
+		return writelog.LogEntry{}, writelog.ErrIteratorInvalid
+	}
+	return s.entry, nil
+}
+
+func TestApplyWriteLogStreamingBoundedMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	require := require.New(t)
+	ctx := context.Background()
+
+	// Compute the reference root using the non-streaming path, committing the tree in chunks to
+	// keep the memory use of the test itself bounded.
+	dir, err := os.MkdirTemp("", "mkvs.test.streaming")
+	require.NoError(err, "TempDir")
+	defer os.RemoveAll(dir)
+
+	refDb, err := badgerDb.New(&db.Config{
+		DB:           dir,
+		NoFsync:      true,
+		Namespace:    testNs,
+		MaxCacheSize: 16 * 1024 * 1024,
+	})
+	require.NoError(err, "badgerDb.New")
+	defer refDb.Close()
+
+	tree := New(nil, refDb, node.RootTypeState)
+	wl := newSyntheticWriteLog(streamingEntries)
+	var expectedRoot node.Root
+	for version, more := uint64(0), true; more; version++ {
+		var n int
+		for ; n < streamingChunkEntries; n++ {
+			more, err = wl.Next()
+			require.NoError(err, "Next")
+			if !more {
+				break
+			}
+			entry, _ := wl.Value()
+			err = tree.Insert(ctx, entry.Key, entry.Value)
+			require.NoError(err, "Insert")
+		}
+		if n == 0 {
+			break
+		}
+
+		_, rootHash, cErr := tree.Commit(ctx, testNs, version)
+		require.NoError(cErr, "Commit")
+		expectedRoot = node.Root{Namespace: testNs, Version: version, Type: node.RootTypeState, Hash: rootHash}
+		err = refDb.Finalize([]node.Root{expectedRoot})
+		require.NoError(err, "Finalize")
+	}
+	tree.Close()
+
+	// Stream the same write log into a node database that does not retain anything and track the
+	// peak heap use while doing so.
+	nopDb, _ := db.NewNopNodeDB()
+	emptyRoot := node.Root{Namespace: testNs, Type: node.RootTypeState}
+	emptyRoot.Hash.Empty()
+
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	baseline := ms.HeapAlloc
+
+	var (
+		wg       sync.WaitGroup
+		peak     uint64
+		stopCh   = make(chan struct{})
+		interval = 10 * time.Millisecond
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			var sample runtime.MemStats
+			runtime.ReadMemStats(&sample)
+			peak = max(peak, sample.HeapAlloc)
+
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	rootHash, err := ApplyWriteLogStreaming(ctx, nopDb, emptyRoot, expectedRoot.Version, newSyntheticWriteLog(streamingEntries), DefaultStreamingWindowSize)
+	close(stopCh)
+	wg.Wait()
+	require.NoError(err, "ApplyWriteLogStreaming")
+	require.Equal(expectedRoot.Hash, rootHash, "streaming root should be equal to the non-streaming root")
+
+	var growth uint64
+	if peak > baseline {
+		growth = peak - baseline
+	}
+	require.Less(growth, uint64(streamingHeapBudget), "heap growth should be bounded")
+}
@@ -0,0 +1,167 @@
+package syncer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+const (
+	methodSyncGet         = "sync_get"
+	methodSyncGetPrefixes = "sync_get_prefixes"
+	methodSyncIterate     = "sync_iterate"
+)
+
+var (
+	coalescedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_storage_syncer_coalesced_requests",
+			Help: "Number of read syncer requests served by joining an identical in-flight request.",
+		},
+		[]string{"method"},
+	)
+	upstreamRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_storage_syncer_upstream_requests",
+			Help: "Number of read syncer requests forwarded to the remote syncer.",
+		},
+		[]string{"method"},
+	)
+
+	coalescerCollectors = []prometheus.Collector{
+		coalescedRequests,
+		upstreamRequests,
+	}
+
+	coalescerMetricsOnce sync.Once
+)
+
+// coalesceKey identifies identical requests.
+type coalesceKey struct {
+	root    node.Root
+	request hash.Hash
+}
+
+// inFlightRequest is a request to the underlying read syncer shared by all of its waiters.
+type inFlightRequest struct {
+	done chan struct{}
+	rsp  *ProofResponse
+	err  error
+
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Coalescer is a ReadSyncer which coalesces concurrent identical requests so that they share a
+// single request to the underlying (remote) read syncer.
+//
+// All waiters receive the same response, which they must treat as read-only. The shared request
+// is only canceled once all of its waiters have gone away.
+type Coalescer struct {
+	rs ReadSyncer
+
+	mu       sync.Mutex
+	inFlight map[coalesceKey]*inFlightRequest
+}
+
+// NewCoalescer creates a new request coalescing read syncer.
+func NewCoalescer(rs ReadSyncer) *Coalescer {
+	coalescerMetricsOnce.Do(func() {
+		prometheus.MustRegister(coalescerCollectors...)
+	})
+
+	return &Coalescer{
+		rs:       rs,
+		inFlight: make(map[coalesceKey]*inFlightRequest),
+	}
+}
+
+// Implements ReadSyncer.
+func (c *Coalescer) SyncGet(ctx context.Context, request *GetRequest) (*ProofResponse, error) {
+	return c.do(ctx, methodSyncGet, request.Tree.Root, request, func(ctx context.Context) (*ProofResponse, error) {
+		return c.rs.SyncGet(ctx, request)
+	})
+}
+
+// Implements ReadSyncer.
+func (c *Coalescer) SyncGetPrefixes(ctx context.Context, request *GetPrefixesRequest) (*ProofResponse, error) {
+	return c.do(ctx, methodSyncGetPrefixes, request.Tree.Root, request, func(ctx context.Context) (*ProofResponse, error) {
+		return c.rs.SyncGetPrefixes(ctx, request)
+	})
+}
+
+// Implements ReadSyncer.
+func (c *Coalescer) SyncIterate(ctx context.Context, request *IterateRequest) (*ProofResponse, error) {
+	return c.do(ctx, methodSyncIterate, request.Tree.Root, request, func(ctx context.Context) (*ProofResponse, error) {
+		return c.rs.SyncIterate(ctx, request)
+	})
+}
+
+func (c *Coalescer) do(
+	ctx context.Context,
+	method string,
+	root node.Root,
+	request any,
+	fetch func(context.Context) (*ProofResponse, error),
+) (*ProofResponse, error) {
+	key := coalesceKey{
+		root:    root,
+		request: hash.NewFromBytes(append([]byte(method), cbor.Marshal(request)...)),
+	}
+
+	c.mu.Lock()
+	req, ok := c.inFlight[key]
+	if ok {
+		req.waiters++
+		coalescedRequests.WithLabelValues(method).Inc()
+	} else {
+		// The shared request must not be canceled when the waiter that started it goes away
+		// while others remain, so it only inherits the context values.
+		fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		req = &inFlightRequest{
+			done:    make(chan struct{}),
+			waiters: 1,
+			cancel:  cancel,
+		}
+		c.inFlight[key] = req
+		upstreamRequests.WithLabelValues(method).Inc()
+
+		go func() {
+			rsp, err := fetch(fetchCtx)
+
+			c.mu.Lock()
+			if c.inFlight[key] == req {
+				delete(c.inFlight, key)
+			}
+			c.mu.Unlock()
+
+			req.rsp, req.err = rsp, err
+			cancel()
+			close(req.done)
+		}()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-req.done:
+		return req.rsp, req.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		req.waiters--
+		if req.waiters == 0 {
+			// Nobody is interested in the result anymore. Make sure that new requests start a
+			// fresh fetch instead of joining the canceled one.
+			req.cancel()
+			if c.inFlight[key] == req {
+				delete(c.inFlight, key)
+			}
+		}
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,168 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+// countingSyncer is a read syncer that counts requests and blocks them until released.
+type countingSyncer struct {
+	nopReadSyncer
+
+	calls    atomic.Int32
+	canceled atomic.Int32
+	release  chan struct{}
+	err      error
+}
+
+func (s *countingSyncer) SyncGet(ctx context.Context, _ *GetRequest) (*ProofResponse, error) {
+	s.calls.Add(1)
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		s.canceled.Add(1)
+		return nil, ctx.Err()
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &ProofResponse{Proof: Proof{UntrustedRoot: hash.NewFromBytes([]byte("root"))}}, nil
+}
+
+func (c *Coalescer) waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int
+	for _, req := range c.inFlight {
+		n += req.waiters
+	}
+	return n
+}
+
+func testGetRequest(key string) *GetRequest {
+	return &GetRequest{
+		Tree: TreeID{
+			Root: node.Root{Version: 1, Type: node.RootTypeState, Hash: hash.NewFromBytes([]byte("root"))},
+		},
+		Key: []byte(key),
+	}
+}
+
+func TestCoalescer(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rs := &countingSyncer{release: make(chan struct{})}
+	c := NewCoalescer(rs)
+
+	const numRequests = 50
+	var (
+		wg        sync.WaitGroup
+		responses = make([]*ProofResponse, numRequests)
+		errs      = make([]error, numRequests)
+	)
+	for i := range numRequests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = c.SyncGet(ctx, testGetRequest("key"))
+		}()
+	}
+	require.Eventually(func() bool { return c.waiters() == numRequests }, 5*time.Second, time.Millisecond)
+	close(rs.release)
+	wg.Wait()
+
+	require.EqualValues(1, rs.calls.Load(), "identical requests should result in a single upstream call")
+	for i := range numRequests {
+		require.NoError(errs[i])
+		require.Equal(responses[0], responses[i], "all waiters should receive the same response")
+	}
+	require.Zero(c.waiters(), "no requests should remain in flight")
+
+	// Different requests should not be coalesced.
+	_, err := c.SyncGet(ctx, testGetRequest("other key"))
+	require.NoError(err)
+	_, err = c.SyncGet(ctx, testGetRequest("key"))
+	require.NoError(err)
+	require.EqualValues(3, rs.calls.Load())
+}
+
+func TestCoalescerError(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	errFetch := errors.New("fetch failed")
+	rs := &countingSyncer{release: make(chan struct{}), err: errFetch}
+	c := NewCoalescer(rs)
+
+	errCh := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := c.SyncGet(ctx, testGetRequest("key"))
+			errCh <- err
+		}()
+	}
+	require.Eventually(func() bool { return c.waiters() == 2 }, 5*time.Second, time.Millisecond)
+	close(rs.release)
+
+	require.ErrorIs(<-errCh, errFetch, "error should be propagated to all waiters")
+	require.ErrorIs(<-errCh, errFetch, "error should be propagated to all waiters")
+	require.EqualValues(1, rs.calls.Load())
+}
+
+func TestCoalescerCancel(t *testing.T) {
+	require := require.New(t)
+
+	rs := &countingSyncer{release: make(chan struct{})}
+	c := NewCoalescer(rs)
+
+	// The first waiter starts the shared fetch and then goes away.
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := c.SyncGet(firstCtx, testGetRequest("key"))
+		firstErr <- err
+	}()
+	require.Eventually(func() bool { return c.waiters() == 1 }, 5*time.Second, time.Millisecond)
+
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+	secondRsp := make(chan *ProofResponse, 1)
+	go func() {
+		rsp, _ := c.SyncGet(secondCtx, testGetRequest("key"))
+		secondRsp <- rsp
+	}()
+	require.Eventually(func() bool { return c.waiters() == 2 }, 5*time.Second, time.Millisecond)
+
+	cancelFirst()
+	require.ErrorIs(<-firstErr, context.Canceled)
+	require.Zero(rs.canceled.Load(), "shared fetch should not be canceled while others wait")
+
+	// The remaining waiter should still receive the result.
+	close(rs.release)
+	require.NotNil(<-secondRsp, "remaining waiter should receive the response")
+	require.EqualValues(1, rs.calls.Load())
+
+	// Once all waiters go away, the shared fetch should be canceled.
+	rs.release = make(chan struct{})
+	thirdCtx, cancelThird := context.WithCancel(context.Background())
+	thirdErr := make(chan error, 1)
+	go func() {
+		_, err := c.SyncGet(thirdCtx, testGetRequest("key"))
+		thirdErr <- err
+	}()
+	require.Eventually(func() bool { return c.waiters() == 1 }, 5*time.Second, time.Millisecond)
+	cancelThird()
+	require.ErrorIs(<-thirdErr, context.Canceled)
+	require.Eventually(func() bool { return rs.canceled.Load() == 1 }, 5*time.Second, time.Millisecond)
+}
@@ -257,17 +257,6 @@ type verifyResult struct {
 	writeLog writelog.WriteLog
 }
 
-func (vr *verifyResult) addLeafToWriteLog(leaf *node.Pointer) {
-	if leaf == nil {
-		return
-	}
-	leafNode, ok := leaf.Node.(*node.LeafNode)
-	if !ok {
-		return
-	}
-	vr.writeLog = append(vr.writeLog, writelog.LogEntry{Key: leafNode.Key, Value: leafNode.Value})
-}
-
 // VerifyProof verifies a proof and generates an in-memory subtree representing
 // the nodes which are included in the proof.
 func (pv *ProofVerifier) VerifyProof(ctx context.Context, root hash.Hash, proof *Proof) (*node.Pointer, error) {
@@ -305,111 +294,82 @@ func (pv *ProofVerifier) verifyProofOpts(ctx context.Context, root hash.Hash, pr
 		return nil, errors.New("verifier: empty proof")
 	}
 
-	var res verifyResult
-	idx, rootPtr, err := pv.verifyProof(ctx, proof, 0, opts, &res)
+	// The whole-proof verifier is a thin wrapper around the streaming verifier which retains
+	// the verified subtree in memory.
+	stream, err := newVerifierStream(root, proof.V, true)
 	if err != nil {
 		return nil, err
 	}
-	// Make sure that all of the entries in the proof have been used. The returned index should
-	// point to just beyond the last element.
-	if idx != len(proof.Entries) {
-		return nil, fmt.Errorf("verifier: unused entries in proof")
-	}
-	rootNodeHash := rootPtr.GetHash()
-	if rootNodeHash.IsEmpty() {
-		// Make sure that in case the root node is empty we always return nil
-		// and not a pointer that represents nil.
-		rootPtr = nil
-	}
 
-	if !rootNodeHash.Equal(&root) {
-		return nil, fmt.Errorf("verifier: bad root (expected: %s got: %s)",
-			root,
-			rootNodeHash,
-		)
-	}
+	var res verifyResult
+	for _, entry := range proof.Entries {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 
-	res.rootPtr = rootPtr
+		kv, err := stream.Push(entry)
+		if err != nil {
+			return nil, err
+		}
+		if opts.writeLog && kv != nil {
+			res.writeLog = append(res.writeLog, *kv)
+		}
+	}
+	if res.rootPtr, err = stream.finish(); err != nil {
+		return nil, err
+	}
 
 	return &res, nil
 }
 
-func (pv *ProofVerifier) verifyProof(ctx context.Context, proof *Proof, idx int, opts *verifyOpts, res *verifyResult) (int, *node.Pointer, error) {
-	if ctx.Err() != nil {
-		return -1, nil, ctx.Err()
+// ConvertProof converts a proof into the given proof version.
+//
+// The source proof is verified against its untrusted root before conversion, so the caller
+// must still verify the converted proof against an independently obtained root. Converting a
+// version 1 proof which omits a leaf node that is embedded in an internal node into version 0
+// is not possible as version 0 requires such leaf nodes to be present.
+func ConvertProof(ctx context.Context, proof *Proof, proofVersion uint16) (*Proof, error) {
+	if proof.V == proofVersion {
+		return proof, nil
 	}
-	if idx >= len(proof.Entries) {
-		return -1, nil, errors.New("verifier: malformed proof")
+
+	var pv ProofVerifier
+	rootPtr, err := pv.VerifyProof(ctx, proof.UntrustedRoot, proof)
+	if err != nil {
+		return nil, fmt.Errorf("proof: failed to verify source proof: %w", err)
 	}
 
-	entry := proof.Entries[idx]
-	if entry == nil {
-		return idx + 1, nil, nil
+	pb, err := NewProofBuilderForVersion(proof.UntrustedRoot, proof.UntrustedRoot, proofVersion)
+	if err != nil {
+		return nil, err
 	}
-	if len(entry) == 0 {
-		return -1, nil, errors.New("verifier: malformed proof")
+	if err = includeVerifiedSubtree(pb, rootPtr); err != nil {
+		return nil, err
 	}
+	return pb.Build(ctx)
+}
 
-	switch entry[0] {
-	case proofEntryFull:
-		// Full node.
-		n, err := node.UnmarshalBinary(entry[1:])
-		if err != nil {
-			return -1, nil, err
-		}
-
-		// For internal nodes, also decode children.
-		pos := idx + 1
-		if nd, ok := n.(*node.InternalNode); ok {
-			switch proof.V {
-			case 0:
-				// In version 0, the leaf node is included in the internal node.
-				if opts.writeLog {
-					res.addLeafToWriteLog(nd.LeafNode)
-				}
-			case 1:
-				// In version 1, the leaf node is added separately, as a child.
-				// Leaf.
-				pos, nd.LeafNode, err = pv.verifyProof(ctx, proof, pos, opts, res)
-				if err != nil {
-					return -1, nil, err
-				}
-			default:
-				// Checked in verifyProofOpts.
-				panic("unexpected proof version")
-			}
-
-			// Left.
-			pos, nd.Left, err = pv.verifyProof(ctx, proof, pos, opts, res)
-			if err != nil {
-				return -1, nil, err
-			}
-			// Right.
-			pos, nd.Right, err = pv.verifyProof(ctx, proof, pos, opts, res)
-			if err != nil {
-				return -1, nil, err
-			}
+func includeVerifiedSubtree(pb *ProofBuilder, ptr *node.Pointer) error {
+	if ptr == nil || ptr.Node == nil {
+		return nil
+	}
 
-			// Recompute hash as hashes were not recomputed for compact encoding.
-			nd.UpdateHash()
+	if nd, ok := ptr.Node.(*node.InternalNode); ok {
+		if pb.Version() == 0 && nd.LeafNode != nil && nd.LeafNode.Node == nil {
+			return fmt.Errorf("%w: leaf node %s not included in proof", ErrUnsupportedProofVersion, nd.LeafNode.Hash)
 		}
-
-		ptr := &node.Pointer{Clean: true, Hash: n.GetHash(), Node: n}
-
-		if opts.writeLog {
-			res.addLeafToWriteLog(ptr)
+		children := []*node.Pointer{nd.Left, nd.Right}
+		if pb.Version() > 0 {
+			// Since version 1, the leaf node is included separately, as a child.
+			children = append(children, nd.LeafNode)
 		}
-
-		return pos, ptr, nil
-	case proofEntryHash:
-		// Hash of a node.
-		var h hash.Hash
-		if err := h.UnmarshalBinary(entry[1:]); err != nil {
-			return -1, nil, err
+		for _, child := range children {
+			if err := includeVerifiedSubtree(pb, child); err != nil {
+				return err
+			}
 		}
-
-		return idx + 1, &node.Pointer{Clean: true, Hash: h}, nil
-	default:
-		return -1, nil, fmt.Errorf("verifier: unexpected entry in proof (%x)", entry[0])
 	}
+
+	pb.Include(ptr.Node)
+	return nil
 }
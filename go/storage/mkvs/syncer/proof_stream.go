@@ -0,0 +1,220 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
+)
+
+// streamFrame is an internal node on the current traversal path whose children are still being
+// verified.
+type streamFrame struct {
+	nd *node.InternalNode
+	// slot is the index of the next child slot to fill (0 = leaf, 1 = left, 2 = right).
+	slot int
+}
+
+// VerifierStream verifies a proof whose entries are supplied incrementally, in the same pre-order
+// traversal order as they appear in Proof.Entries.
+//
+// Only the internal nodes on the path from the root to the current entry are kept in memory and
+// completed subtrees are collapsed into their hashes, so memory use is proportional to the depth
+// of the tree instead of the size of the proof.
+//
+// Key/value pairs are yielded as soon as their leaf nodes are decoded. Since the proof can only be
+// authenticated once the root hash has been recomputed, yielded pairs MUST NOT be trusted until
+// Finish returns without error.
+type VerifierStream struct {
+	root    hash.Hash
+	version uint16
+
+	// retainNodes makes the stream keep the full verified subtree in memory instead of
+	// collapsing completed subtrees into hashes.
+	retainNodes bool
+
+	stack   []*streamFrame
+	rootPtr *node.Pointer
+	entries uint64
+	done    bool
+	err     error
+}
+
+// NewVerifierStream creates a new streaming verifier for proofs of the given version, rooted at
+// the given (independently obtained) root hash.
+func NewVerifierStream(root hash.Hash, proofVersion uint16) (*VerifierStream, error) {
+	return newVerifierStream(root, proofVersion, false)
+}
+
+func newVerifierStream(root hash.Hash, proofVersion uint16, retainNodes bool) (*VerifierStream, error) {
+	if proofVersion < MinimumProofVersion || proofVersion > LatestProofVersion {
+		return nil, fmt.Errorf("verifier: unsupported proof version: %d", proofVersion)
+	}
+
+	return &VerifierStream{
+		root:        root,
+		version:     proofVersion,
+		retainNodes: retainNodes,
+	}, nil
+}
+
+// Push feeds the next proof entry into the verifier.
+//
+// If the entry contains a leaf node, its key/value pair is returned. Any error is sticky and
+// is returned by all subsequent calls.
+func (s *VerifierStream) Push(entry []byte) (*writelog.LogEntry, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	kv, err := s.push(entry)
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+	return kv, nil
+}
+
+func (s *VerifierStream) push(entry []byte) (*writelog.LogEntry, error) {
+	if s.done {
+		return nil, fmt.Errorf("verifier: unused entries in proof")
+	}
+	s.entries++
+
+	if entry == nil {
+		return nil, s.complete(nil)
+	}
+	if len(entry) == 0 {
+		return nil, errors.New("verifier: malformed proof")
+	}
+
+	switch entry[0] {
+	case proofEntryFull:
+		// Full node.
+		n, err := node.UnmarshalBinary(entry[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		if nd, ok := n.(*node.InternalNode); ok {
+			// Children follow the internal node, so it can only be completed later.
+			frame := &streamFrame{nd: nd}
+			switch s.version {
+			case 0:
+				// In version 0, the leaf node is included in the internal node.
+				frame.slot = 1
+			case 1:
+				// In version 1, the leaf node is added separately, as a child.
+			default:
+				// Checked in newVerifierStream.
+				panic("unexpected proof version")
+			}
+			s.stack = append(s.stack, frame)
+
+			if s.version == 0 {
+				return leafToLogEntry(nd.LeafNode), nil
+			}
+			return nil, nil
+		}
+
+		ptr := &node.Pointer{Clean: true, Hash: n.GetHash(), Node: n}
+		kv := leafToLogEntry(ptr)
+		return kv, s.complete(ptr)
+	case proofEntryHash:
+		// Hash of a node.
+		var h hash.Hash
+		if err := h.UnmarshalBinary(entry[1:]); err != nil {
+			return nil, err
+		}
+
+		return nil, s.complete(&node.Pointer{Clean: true, Hash: h})
+	default:
+		return nil, fmt.Errorf("verifier: unexpected entry in proof (%x)", entry[0])
+	}
+}
+
+// complete attaches a completed subtree to its parent and completes any ancestors whose children
+// are now all known.
+func (s *VerifierStream) complete(ptr *node.Pointer) error {
+	for len(s.stack) > 0 {
+		frame := s.stack[len(s.stack)-1]
+		if !s.retainNodes && ptr != nil && ptr.Node != nil {
+			ptr = &node.Pointer{Clean: true, Hash: ptr.Hash}
+		}
+
+		switch frame.slot {
+		case 0:
+			frame.nd.LeafNode = ptr
+		case 1:
+			frame.nd.Left = ptr
+		case 2:
+			frame.nd.Right = ptr
+		}
+		frame.slot++
+		if frame.slot <= 2 {
+			return nil
+		}
+
+		// All children are known, recompute hash as hashes were not recomputed for compact
+		// encoding.
+		s.stack = s.stack[:len(s.stack)-1]
+		frame.nd.UpdateHash()
+		ptr = &node.Pointer{Clean: true, Hash: frame.nd.GetHash(), Node: frame.nd}
+	}
+
+	// The root node has been completed, so the proof can be authenticated.
+	s.done = true
+
+	rootNodeHash := ptr.GetHash()
+	if !rootNodeHash.Equal(&s.root) {
+		return fmt.Errorf("verifier: bad root (expected: %s got: %s)",
+			s.root,
+			rootNodeHash,
+		)
+	}
+	if rootNodeHash.IsEmpty() {
+		// Make sure that in case the root node is empty we always return nil
+		// and not a pointer that represents nil.
+		ptr = nil
+	}
+	s.rootPtr = ptr
+
+	return nil
+}
+
+// Depth returns the number of internal nodes currently waiting for their children.
+func (s *VerifierStream) Depth() int {
+	return len(s.stack)
+}
+
+// Finish checks that the proof has been fully consumed and verified against the root.
+func (s *VerifierStream) Finish() error {
+	_, err := s.finish()
+	return err
+}
+
+func (s *VerifierStream) finish() (*node.Pointer, error) {
+	switch {
+	case s.err != nil:
+		return nil, s.err
+	case s.entries == 0:
+		return nil, errors.New("verifier: empty proof")
+	case !s.done:
+		return nil, errors.New("verifier: malformed proof")
+	default:
+		return s.rootPtr, nil
+	}
+}
+
+func leafToLogEntry(leaf *node.Pointer) *writelog.LogEntry {
+	if leaf == nil {
+		return nil
+	}
+	leafNode, ok := leaf.Node.(*node.LeafNode)
+	if !ok {
+		return nil
+	}
+	return &writelog.LogEntry{Key: leafNode.Key, Value: leafNode.Value}
+}
@@ -0,0 +1,353 @@
+package syncer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
+)
+
+// syntheticProof generates a complete proof for a perfect binary tree with the given number of
+// levels of internal nodes without ever materializing the whole proof in memory.
+//
+// The generated tree is not a valid MKVS tree (labels are not set) but the verifier only
+// authenticates the node hashes, so it is sufficient for exercising the verifier.
+type syntheticProof struct {
+	version   uint16
+	depth     int
+	valueSize int
+}
+
+func (sp *syntheticProof) leaf(index uint64) *node.LeafNode {
+	key := binary.BigEndian.AppendUint64(nil, index)
+	value := make([]byte, sp.valueSize)
+	for i := range value {
+		value[i] = byte(index) + byte(i)
+	}
+
+	leaf := &node.LeafNode{Clean: true, Key: key, Value: value}
+	leaf.UpdateHash()
+	return leaf
+}
+
+func (sp *syntheticProof) internal(left, right hash.Hash) *node.InternalNode {
+	nd := &node.InternalNode{
+		Clean: true,
+		Left:  &node.Pointer{Clean: true, Hash: left},
+		Right: &node.Pointer{Clean: true, Hash: right},
+	}
+	nd.UpdateHash()
+	return nd
+}
+
+func (sp *syntheticProof) hash(level int, index uint64) hash.Hash {
+	if level == sp.depth {
+		return sp.leaf(index).GetHash()
+	}
+	return sp.internal(sp.hash(level+1, 2*index), sp.hash(level+1, 2*index+1)).GetHash()
+}
+
+// RootHash returns the root hash of the generated tree.
+func (sp *syntheticProof) RootHash() hash.Hash {
+	return sp.hash(0, 0)
+}
+
+// Emit calls fn for each proof entry in pre-order traversal.
+func (sp *syntheticProof) Emit(fn func(entry []byte) error) error {
+	return sp.emit(0, 0, fn)
+}
+
+func (sp *syntheticProof) emit(level int, index uint64, fn func(entry []byte) error) error {
+	var (
+		data []byte
+		err  error
+	)
+	if level == sp.depth {
+		data, err = sp.leaf(index).CompactMarshalBinaryV1()
+		if err != nil {
+			return err
+		}
+		return fn(append([]byte{proofEntryFull}, data...))
+	}
+
+	nd := &node.InternalNode{Clean: true}
+	switch sp.version {
+	case 0:
+		data, err = nd.CompactMarshalBinaryV0()
+	default:
+		data, err = nd.CompactMarshalBinaryV1()
+	}
+	if err != nil {
+		return err
+	}
+	if err = fn(append([]byte{proofEntryFull}, data...)); err != nil {
+		return err
+	}
+	if sp.version > 0 {
+		// No leaf node.
+		if err = fn(nil); err != nil {
+			return err
+		}
+	}
+	if err = sp.emit(level+1, 2*index, fn); err != nil {
+		return err
+	}
+	return sp.emit(level+1, 2*index+1, fn)
+}
+
+// Build materializes the whole proof.
+func (sp *syntheticProof) Build() (*Proof, error) {
+	proof := &Proof{
+		V:             sp.version,
+		UntrustedRoot: sp.RootHash(),
+	}
+	err := sp.Emit(func(entry []byte) error {
+		proof.Entries = append(proof.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+func verifyStream(root hash.Hash, proof *Proof) (writelog.WriteLog, error) {
+	stream, err := NewVerifierStream(root, proof.V)
+	if err != nil {
+		return nil, err
+	}
+
+	var wl writelog.WriteLog
+	for _, entry := range proof.Entries {
+		kv, err := stream.Push(entry)
+		if err != nil {
+			return nil, err
+		}
+		if kv != nil {
+			wl = append(wl, *kv)
+		}
+	}
+	if err = stream.Finish(); err != nil {
+		return nil, err
+	}
+	return wl, nil
+}
+
+func TestVerifierStream(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	var verifier ProofVerifier
+	for _, version := range []uint16{0, 1} {
+		sp := syntheticProof{version: version, depth: 6, valueSize: 16}
+		proof, err := sp.Build()
+		require.NoError(err, "Build")
+		root := sp.RootHash()
+
+		expected, err := verifier.VerifyProofToWriteLog(ctx, root, proof)
+		require.NoError(err, "VerifyProofToWriteLog")
+		require.Len(expected, 1<<sp.depth)
+
+		wl, err := verifyStream(root, proof)
+		require.NoError(err, "streaming verification should succeed")
+		require.Equal(expected, wl, "streaming verifier should yield the same key/value pairs")
+
+		// Only the path to the current entry should be retained.
+		stream, err := NewVerifierStream(root, version)
+		require.NoError(err, "NewVerifierStream")
+		var maxDepth int
+		for _, entry := range proof.Entries {
+			_, err = stream.Push(entry)
+			require.NoError(err, "Push")
+			if d := stream.Depth(); d > maxDepth {
+				maxDepth = d
+			}
+		}
+		require.NoError(stream.Finish(), "Finish")
+		require.Equal(sp.depth, maxDepth, "stream should only retain the current path")
+
+		// Truncated proof.
+		stream, err = NewVerifierStream(root, version)
+		require.NoError(err, "NewVerifierStream")
+		for _, entry := range proof.Entries[:len(proof.Entries)-1] {
+			_, err = stream.Push(entry)
+			require.NoError(err, "Push")
+		}
+		require.Error(stream.Finish(), "truncated proof should fail to validate")
+
+		// Extra entries.
+		stream, err = NewVerifierStream(root, version)
+		require.NoError(err, "NewVerifierStream")
+		for _, entry := range proof.Entries {
+			_, err = stream.Push(entry)
+			require.NoError(err, "Push")
+		}
+		_, err = stream.Push(proof.Entries[0])
+		require.Error(err, "proof with extra data should fail to validate")
+		require.Error(stream.Finish(), "errors should be sticky")
+	}
+
+	// A hash-only root is checked as soon as it is pushed.
+	var root, other hash.Hash
+	root.FromBytes([]byte("root"))
+	other.FromBytes([]byte("other"))
+	stream, err := NewVerifierStream(root, LatestProofVersion)
+	require.NoError(err, "NewVerifierStream")
+	_, err = stream.Push(append([]byte{proofEntryHash}, other[:]...))
+	require.Error(err, "bad root should be detected immediately")
+
+	// Empty proof.
+	stream, err = NewVerifierStream(root, LatestProofVersion)
+	require.NoError(err, "NewVerifierStream")
+	require.Error(stream.Finish(), "empty proof should fail to validate")
+
+	_, err = NewVerifierStream(root, LatestProofVersion+1)
+	require.Error(err, "unsupported proof version should be rejected")
+}
+
+func FuzzVerifierStream(f *testing.F) {
+	// Seed corpus.
+	for _, raw := range []string{
+		"omdlbnRyaWVzhUoBASQAa2V5IDACRgEBAQAAAlghAsFltYRhD4dAwHOdOmEigY1r02pJH6InhiibKlh9neYlWCECpsJnkjOnIgc4+yfvpsqCcIYHh5eld1hNMWTT7arAfHFYIQLhNTLWRbks1RBf52ulnlOTO+7D5EZNMYFzTx8U46sCnm51bnRydXN0ZWRfcm9vdFggWeZ8L9wIuOEN0Iu2uO/mFPzJZey4liX5fxf4fwcQRhM=",
+		"o2F2AWdlbnRyaWVzh0oBASQAa2V5IDAC9lghAibniky28BTAIiYrb3z9/rTq7r91woTo2EqR91Pf16P9RgEBAwCAAvZYIQIwwW7eyXCi2yXyFCzFD9U+Ssy1gwSwiskBQfk+9KCUA1QBAAUAa2V5IDkHAAAAdmFsdWUgOW51bnRydXN0ZWRfcm9vdFggWeZ8L9wIuOEN0Iu2uO/mFPzJZey4liX5fxf4fwcQRhM=",
+	} {
+		data, _ := base64.StdEncoding.DecodeString(raw)
+		f.Add(data, uint16(0), uint8(0), uint8(0))
+		f.Add(data, uint16(2), uint8(0), uint8(0))
+		f.Add(data, uint16(0), uint8(1), uint8(3))
+	}
+	for _, version := range []uint16{0, 1} {
+		sp := syntheticProof{version: version, depth: 2, valueSize: 4}
+		proof, err := sp.Build()
+		if err != nil {
+			f.Fatalf("failed to build synthetic proof: %s", err)
+		}
+		f.Add(cbor.Marshal(proof), uint16(0), uint8(0), uint8(0))
+		f.Add(cbor.Marshal(proof), uint16(3), uint8(0), uint8(0))
+		f.Add(cbor.Marshal(proof), uint16(0), uint8(2), uint8(5))
+	}
+
+	// Fuzzing.
+	f.Fuzz(func(t *testing.T, data []byte, truncate uint16, swapA, swapB uint8) {
+		var proof Proof
+		if err := cbor.Unmarshal(data, &proof); err != nil {
+			return
+		}
+
+		// Truncate the stream.
+		if n := int(truncate); n > 0 && n <= len(proof.Entries) {
+			proof.Entries = proof.Entries[:len(proof.Entries)-n]
+		}
+		// Reorder the stream.
+		if a, b := int(swapA), int(swapB); a < len(proof.Entries) && b < len(proof.Entries) {
+			proof.Entries[a], proof.Entries[b] = proof.Entries[b], proof.Entries[a]
+		}
+
+		var verifier ProofVerifier
+		expected, expectedErr := verifier.VerifyProofToWriteLog(context.Background(), proof.UntrustedRoot, &proof)
+		wl, err := verifyStream(proof.UntrustedRoot, &proof)
+		switch expectedErr {
+		case nil:
+			require.NoError(t, err, "streaming verifier should accept proofs accepted by the buffered verifier")
+			require.Equal(t, expected, wl, "streaming verifier should yield the same key/value pairs")
+		default:
+			require.Error(t, err, "streaming verifier should reject proofs rejected by the buffered verifier")
+		}
+	})
+}
+
+// peakHeap tracks the peak heap usage above a baseline.
+type peakHeap struct {
+	baseline uint64
+	peak     uint64
+}
+
+func newPeakHeap() *peakHeap {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return &peakHeap{baseline: ms.HeapAlloc}
+}
+
+func (ph *peakHeap) sample() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	if ms.HeapAlloc > ph.baseline && ms.HeapAlloc-ph.baseline > ph.peak {
+		ph.peak = ms.HeapAlloc - ph.baseline
+	}
+}
+
+func BenchmarkVerifierStream(b *testing.B) {
+	const sampleInterval = 4096
+
+	sp := syntheticProof{version: LatestProofVersion, depth: 16, valueSize: 64}
+	root := sp.RootHash()
+
+	b.Run("Buffered", func(b *testing.B) {
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			ph := newPeakHeap()
+			proof, err := sp.Build()
+			if err != nil {
+				b.Fatalf("failed to build proof: %s", err)
+			}
+			ph.sample()
+
+			var verifier ProofVerifier
+			rootPtr, err := verifier.VerifyProof(context.Background(), root, proof)
+			if err != nil {
+				b.Fatalf("failed to verify proof: %s", err)
+			}
+			ph.sample()
+			runtime.KeepAlive(rootPtr)
+			runtime.KeepAlive(proof)
+
+			if ph.peak > peak {
+				peak = ph.peak
+			}
+		}
+		b.ReportMetric(float64(peak), "peak-bytes")
+	})
+
+	b.Run("Stream", func(b *testing.B) {
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			ph := newPeakHeap()
+			stream, err := NewVerifierStream(root, sp.version)
+			if err != nil {
+				b.Fatalf("failed to create stream: %s", err)
+			}
+
+			var n int
+			err = sp.Emit(func(entry []byte) error {
+				if _, err := stream.Push(entry); err != nil {
+					return err
+				}
+				if n++; n%sampleInterval == 0 {
+					ph.sample()
+				}
+				return nil
+			})
+			if err != nil {
+				b.Fatalf("failed to verify proof: %s", err)
+			}
+			if err = stream.Finish(); err != nil {
+				b.Fatalf("failed to verify proof: %s", err)
+			}
+			ph.sample()
+
+			if ph.peak > peak {
+				peak = ph.peak
+			}
+		}
+		b.ReportMetric(float64(peak), "peak-bytes")
+	})
+}
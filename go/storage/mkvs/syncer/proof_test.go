@@ -76,3 +76,51 @@ func FuzzProof(f *testing.F) {
 		_, _ = verifier.VerifyProof(context.Background(), proof.UntrustedRoot, &proof)
 	})
 }
+
+func TestConvertProof(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	var rootHash hash.Hash
+	err := rootHash.UnmarshalHex("59e67c2fdc08b8e10dd08bb6b8efe614fcc965ecb89625f97f17f87f07104613")
+	require.NoError(err)
+
+	rawProofV0, _ := base64.StdEncoding.DecodeString("omdlbnRyaWVzhUoBASQAa2V5IDACRgEBAQAAAlghAsFltYRhD4dAwHOdOmEigY1r02pJH6InhiibKlh9neYlWCECpsJnkjOnIgc4+yfvpsqCcIYHh5eld1hNMWTT7arAfHFYIQLhNTLWRbks1RBf52ulnlOTO+7D5EZNMYFzTx8U46sCnm51bnRydXN0ZWRfcm9vdFggWeZ8L9wIuOEN0Iu2uO/mFPzJZey4liX5fxf4fwcQRhM=")
+	rawProofV1, _ := base64.StdEncoding.DecodeString("o2F2AWdlbnRyaWVzh0oBASQAa2V5IDAC9kYBAQEAAAL2WCECwWW1hGEPh0DAc506YSKBjWvTakkfoieGKJsqWH2d5iVYIQKmwmeSM6ciBzj7J++myoJwhgeHl6V3WE0xZNPtqsB8cVghAuE1MtZFuSzVEF/na6WeU5M77sPkRk0xgXNPHxTjqwKebnVudHJ1c3RlZF9yb290WCBZ5nwv3Ai44Q3Qi7a47+YU/Mll7LiWJfl/F/h/BxBGEw==")
+
+	var proofV0, proofV1 Proof
+	require.NoError(cbor.Unmarshal(rawProofV0, &proofV0))
+	require.NoError(cbor.Unmarshal(rawProofV1, &proofV1))
+
+	var verifier ProofVerifier
+	for _, tc := range []struct {
+		src      *Proof
+		version  uint16
+		expected []byte
+	}{
+		{&proofV0, 1, rawProofV1},
+		{&proofV1, 0, rawProofV0},
+		{&proofV0, 0, rawProofV0},
+		{&proofV1, 1, rawProofV1},
+	} {
+		converted, err := ConvertProof(ctx, tc.src, tc.version)
+		require.NoError(err, "ConvertProof(%d -> %d)", tc.src.V, tc.version)
+		require.EqualValues(tc.version, converted.V)
+		require.Equal(tc.expected, cbor.Marshal(converted), "converted proof should match test vector (%d -> %d)", tc.src.V, tc.version)
+
+		_, err = verifier.VerifyProof(ctx, rootHash, converted)
+		require.NoError(err, "converted proof should verify (%d -> %d)", tc.src.V, tc.version)
+	}
+
+	_, err = ConvertProof(ctx, &proofV0, LatestProofVersion+1)
+	require.Error(err, "converting into an unsupported version should fail")
+}
+
+func TestNegotiateProofVersion(t *testing.T) {
+	require := require.New(t)
+
+	require.EqualValues(0, NegotiateProofVersion(0, 0), "legacy requests should use the exact version")
+	require.EqualValues(1, NegotiateProofVersion(1, 0), "legacy requests should use the exact version")
+	require.EqualValues(1, NegotiateProofVersion(0, 1))
+	require.EqualValues(LatestProofVersion, NegotiateProofVersion(0, LatestProofVersion+1), "newest mutually-supported version should be used")
+}
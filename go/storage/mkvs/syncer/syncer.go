@@ -40,6 +40,10 @@ type GetRequest struct {
 	// ProofVersion specifies the proof version to use. If not specified,
 	// the default (0) version is used for backwards compatibility.
 	ProofVersion uint16 `json:"proof_version,omitempty"`
+	// MaxProofVersion specifies the maximum proof version understood by the
+	// client. If specified, it takes precedence over ProofVersion and the
+	// newest version supported by both sides is used.
+	MaxProofVersion uint16 `json:"max_proof_version,omitempty"`
 }
 
 // GetPrefixesRequest is a request for the SyncGetPrefixes operation.
@@ -51,6 +55,10 @@ type GetPrefixesRequest struct {
 	// ProofVersion specifies the proof version to use. If not specified,
 	// the default (0) version is used for backwards compatibility.
 	ProofVersion uint16 `json:"proof_version,omitempty"`
+	// MaxProofVersion specifies the maximum proof version understood by the
+	// client. If specified, it takes precedence over ProofVersion and the
+	// newest version supported by both sides is used.
+	MaxProofVersion uint16 `json:"max_proof_version,omitempty"`
 }
 
 // IterateRequest is a request for the SyncIterate operation.
@@ -62,6 +70,22 @@ type IterateRequest struct {
 	// ProofVersion specifies the proof version to use. If not specified,
 	// the default (0) version is used for backwards compatibility.
 	ProofVersion uint16 `json:"proof_version,omitempty"`
+	// MaxProofVersion specifies the maximum proof version understood by the
+	// client. If specified, it takes precedence over ProofVersion and the
+	// newest version supported by both sides is used.
+	MaxProofVersion uint16 `json:"max_proof_version,omitempty"`
+}
+
+// NegotiateProofVersion returns the proof version that should be used to answer a request
+// carrying the given exact and maximum proof versions.
+//
+// In case the maximum version is specified, the newest version supported by both sides is
+// selected. Otherwise the exact version is used for backwards compatibility.
+func NegotiateProofVersion(proofVersion, maxProofVersion uint16) uint16 {
+	if maxProofVersion == 0 {
+		return proofVersion
+	}
+	return min(maxProofVersion, LatestProofVersion)
 }
 
 // ProofResponse is a response for requests that produce proofs.
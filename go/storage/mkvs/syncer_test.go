@@ -540,3 +540,108 @@ func TestTreeProofs(t *testing.T) {
 		}
 	}
 }
+
+// versionedReadSyncer is a read syncer that records the proof versions requested by the client
+// and returned by the server. It can emulate a server that does not support proof version
+// negotiation and therefore ignores the maximum proof version.
+type versionedReadSyncer struct {
+	rs     syncer.ReadSyncer
+	legacy bool
+
+	requested []uint16
+	returned  []uint16
+}
+
+func (r *versionedReadSyncer) negotiate(maxProofVersion *uint16) {
+	r.requested = append(r.requested, *maxProofVersion)
+	if r.legacy {
+		*maxProofVersion = 0
+	}
+}
+
+func (r *versionedReadSyncer) record(rsp *syncer.ProofResponse, err error) (*syncer.ProofResponse, error) {
+	if err == nil {
+		r.returned = append(r.returned, rsp.Proof.V)
+	}
+	return rsp, err
+}
+
+func (r *versionedReadSyncer) SyncGet(ctx context.Context, request *syncer.GetRequest) (*syncer.ProofResponse, error) {
+	req := *request
+	r.negotiate(&req.MaxProofVersion)
+	return r.record(r.rs.SyncGet(ctx, &req))
+}
+
+func (r *versionedReadSyncer) SyncGetPrefixes(ctx context.Context, request *syncer.GetPrefixesRequest) (*syncer.ProofResponse, error) {
+	req := *request
+	r.negotiate(&req.MaxProofVersion)
+	return r.record(r.rs.SyncGetPrefixes(ctx, &req))
+}
+
+func (r *versionedReadSyncer) SyncIterate(ctx context.Context, request *syncer.IterateRequest) (*syncer.ProofResponse, error) {
+	req := *request
+	r.negotiate(&req.MaxProofVersion)
+	return r.record(r.rs.SyncIterate(ctx, &req))
+}
+
+func TestSyncProofVersionNegotiation(t *testing.T) {
+	ctx := context.Background()
+	keys, values := generateKeyValuePairsEx("", 50)
+
+	tree := New(nil, nil, node.RootTypeState)
+	defer tree.Close()
+	for i, key := range keys {
+		err := tree.Insert(ctx, key, values[i])
+		require.NoError(t, err, "Insert")
+	}
+	root := node.Root{Type: node.RootTypeState}
+	_, rootHash, err := tree.Commit(ctx, root.Namespace, root.Version)
+	require.NoError(t, err, "Commit")
+	root.Hash = rootHash
+
+	for _, tc := range []struct {
+		name         string
+		legacy       bool
+		proofVersion uint16
+	}{
+		{"NewServer", false, syncer.LatestProofVersion},
+		{"LegacyServer", true, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			server := &versionedReadSyncer{rs: tree, legacy: tc.legacy}
+
+			// Fetch individual keys.
+			remote := NewWithRoot(server, nil, root)
+			defer remote.Close()
+			for i, key := range keys {
+				value, err := remote.Get(ctx, key)
+				require.NoError(err, "Get")
+				require.Equal(values[i], value)
+			}
+
+			// Prefetch and iterate over all keys.
+			remote = NewWithRoot(server, nil, root)
+			defer remote.Close()
+			err := remote.PrefetchPrefixes(ctx, [][]byte{[]byte("key")}, 10)
+			require.NoError(err, "PrefetchPrefixes")
+			it := remote.NewIterator(ctx, IteratorPrefetch(10))
+			defer it.Close()
+			var count int
+			for it.Rewind(); it.Valid(); it.Next() {
+				count++
+			}
+			require.NoError(it.Err(), "iterator")
+			require.Equal(len(keys), count)
+
+			require.NotEmpty(server.requested)
+			for _, v := range server.requested {
+				require.EqualValues(syncer.LatestProofVersion, v, "client should request the latest proof version")
+			}
+			for _, v := range server.returned {
+				require.Equal(tc.proofVersion, v, "server should return the negotiated proof version")
+			}
+		})
+	}
+}
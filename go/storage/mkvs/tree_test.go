@@ -6,8 +6,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -17,7 +19,9 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	db "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
 	badgerDb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/badger"
+	memoryDb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/memory"
 	pathBadgerDb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/pathbadger"
+	pebbleDb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/pebble"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/syncer"
 	mkvsTests "github.com/oasisprotocol/oasis-core/go/storage/mkvs/tests"
@@ -969,6 +973,100 @@ func testApplyWriteLog(t *testing.T, ndb db.NodeDB, _ NodeDBFactory) {
 	require.True(t, rootHash.IsEmpty(), "root hash must be empty after removal of all items")
 }
 
+func testApplyWriteLogStreaming(t *testing.T, ndb db.NodeDB, _ NodeDBFactory) {
+	ctx := context.Background()
+
+	// Populate the tree, including keys that are prefixes of each other.
+	expected := make(map[string][]byte)
+	tree := New(nil, ndb, node.RootTypeState)
+	keys, values := generateKeyValuePairsEx("", 300)
+	longKeys, longValues := generateLongKeyValuePairs()
+	keys, values = append(keys, longKeys...), append(values, longValues...)
+	for i := range keys {
+		err := tree.Insert(ctx, keys[i], values[i])
+		require.NoError(t, err, "Insert")
+		expected[string(keys[i])] = values[i]
+	}
+	_, rootHash, err := tree.Commit(ctx, testNs, 0)
+	require.NoError(t, err, "Commit")
+	root := node.Root{Namespace: testNs, Version: 0, Type: node.RootTypeState, Hash: rootHash}
+
+	// Remove, update and insert keys all over the tree.
+	updates := make(map[string][]byte)
+	for i := 0; i < 600; i++ {
+		key := fmt.Sprintf("key %d", i)
+		switch {
+		case i >= 300:
+			updates[key] = []byte(fmt.Sprintf("new value %d", i))
+		case i%3 == 0:
+			updates[key] = nil
+		case i%3 == 1:
+			updates[key] = []byte(fmt.Sprintf("updated value %d", i))
+		}
+	}
+	for i, key := range longKeys {
+		if i%2 == 0 {
+			updates[string(key)] = nil
+		}
+	}
+	updates[""] = []byte("empty key")
+	updates["missing key"] = nil
+
+	var writeLog writelog.WriteLog
+	for key, value := range updates {
+		writeLog = append(writeLog, writelog.LogEntry{Key: []byte(key), Value: value})
+		if value == nil {
+			delete(expected, key)
+		} else {
+			expected[key] = value
+		}
+	}
+	slices.SortFunc(writeLog, func(a, b writelog.LogEntry) int {
+		return bytes.Compare(a.Key, b.Key)
+	})
+
+	// Compute the expected root using the non-streaming path.
+	tree = NewWithRoot(nil, ndb, root)
+	err = tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(writeLog))
+	require.NoError(t, err, "ApplyWriteLog")
+	_, expectedHash, err := tree.Commit(ctx, testNs, 1, NoPersist())
+	require.NoError(t, err, "Commit")
+	tree.Close()
+
+	newHash, err := ApplyWriteLogStreaming(ctx, ndb, root, 1, writelog.NewStaticIterator(writeLog), 3)
+	require.NoError(t, err, "ApplyWriteLogStreaming")
+	require.Equal(t, expectedHash, newHash, "streaming root should be equal to the non-streaming root")
+
+	// Make sure everything has been persisted.
+	newRoot := node.Root{Namespace: testNs, Version: 1, Type: node.RootTypeState, Hash: newHash}
+	require.True(t, ndb.HasRoot(newRoot), "new root should exist")
+
+	tree = NewWithRoot(nil, ndb, newRoot)
+	defer tree.Close()
+	for key, value := range expected {
+		var v []byte
+		v, err = tree.Get(ctx, []byte(key))
+		require.NoError(t, err, "Get")
+		require.EqualValues(t, value, v, "value should be correct")
+	}
+
+	it := tree.NewIterator(ctx)
+	defer it.Close()
+	var count int
+	for it.Rewind(); it.Valid(); it.Next() {
+		count++
+	}
+	require.NoError(t, it.Err(), "iterator")
+	require.Equal(t, len(expected), count, "tree should contain all keys")
+
+	// Unsorted write logs should be rejected.
+	_, err = ApplyWriteLogStreaming(ctx, ndb, newRoot, 2, writelog.NewStaticIterator(writelog.WriteLog{
+		{Key: []byte("b"), Value: []byte("b")},
+		{Key: []byte("a"), Value: []byte("a")},
+	}), 3)
+	require.ErrorIs(t, err, ErrWriteLogNotSorted)
+}
+
 func testOnCommitHooks(t *testing.T, ndb db.NodeDB, _ NodeDBFactory) {
 	var emptyRoot hash.Hash
 	emptyRoot.Empty()
@@ -1160,6 +1258,125 @@ func testGetRootsForVersion(t *testing.T, ndb db.NodeDB, _ NodeDBFactory) {
 	require.Len(t, roots, 0, "GetRootsForVersion should return no roots for later versions")
 }
 
+func testRoots(t *testing.T, ndb db.NodeDB, _ NodeDBFactory) {
+	ctx := context.Background()
+
+	// Create and finalize a state root in versions 3-6, leaving earlier versions empty.
+	var finalized []node.Root
+	for version := uint64(3); version < 7; version++ {
+		tree := New(nil, ndb, node.RootTypeState)
+		err := tree.Insert(ctx, []byte("foo"), []byte(fmt.Sprintf("bar %d", version)))
+		require.NoError(t, err, "Insert")
+		_, rootHash, err := tree.Commit(ctx, testNs, version)
+		require.NoError(t, err, "Commit")
+
+		root := node.Root{
+			Namespace: testNs,
+			Version:   version,
+			Type:      node.RootTypeState,
+			Hash:      rootHash,
+		}
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(t, err, "Finalize")
+		finalized = append(finalized, root)
+	}
+
+	collect := func(startVersion, endVersion uint64) []db.VersionRoots {
+		ch, err := ndb.Roots(ctx, startVersion, endVersion)
+		require.NoError(t, err, "Roots")
+
+		var all []db.VersionRoots
+		for vr := range ch {
+			all = append(all, vr)
+		}
+		return all
+	}
+
+	all := collect(0, math.MaxUint64)
+	require.Len(t, all, len(finalized), "Roots should skip versions without roots")
+	for i, vr := range all {
+		require.Equal(t, finalized[i].Version, vr.Version, "Roots should return versions in ascending order")
+		require.Equal(t, []node.Root{finalized[i]}, vr.Roots, "Roots should return the correct roots")
+	}
+
+	all = collect(4, 5)
+	require.Len(t, all, 2)
+	require.EqualValues(t, 4, all[0].Version)
+	require.EqualValues(t, 5, all[1].Version)
+
+	require.Empty(t, collect(10, 20), "Roots should return nothing for later versions")
+
+	_, err := ndb.Roots(ctx, 2, 1)
+	require.Error(t, err, "Roots should fail for an invalid range")
+
+	// Canceling the context should close the channel.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	ch, err := ndb.Roots(cancelCtx, 0, math.MaxUint64)
+	require.NoError(t, err, "Roots")
+	<-ch
+	cancel()
+	for range ch {
+	}
+}
+
+func testPruneWriteLogs(t *testing.T, ndb db.NodeDB, _ NodeDBFactory) {
+	ctx := context.Background()
+
+	// Create and finalize a state root in versions 0-3, each with a write log.
+	var roots []node.Root
+	tree := New(nil, ndb, node.RootTypeState)
+	for version := uint64(0); version < 4; version++ {
+		err := tree.Insert(ctx, []byte(fmt.Sprintf("foo %d", version)), []byte("bar"))
+		require.NoError(t, err, "Insert")
+		_, rootHash, err := tree.Commit(ctx, testNs, version)
+		require.NoError(t, err, "Commit")
+
+		root := node.Root{
+			Namespace: testNs,
+			Version:   version,
+			Type:      node.RootTypeState,
+			Hash:      rootHash,
+		}
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(t, err, "Finalize")
+		roots = append(roots, root)
+	}
+
+	_, err := ndb.PruneWriteLogs(ctx, 5)
+	require.ErrorIs(t, err, db.ErrNotFinalized, "PruneWriteLogs should refuse to prune non-finalized versions")
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	pruned, err := ndb.PruneWriteLogs(cancelCtx, 3)
+	require.ErrorIs(t, err, context.Canceled, "PruneWriteLogs should be cancellable")
+	require.Zero(t, pruned)
+
+	pruned, err = ndb.PruneWriteLogs(ctx, 3)
+	require.NoError(t, err, "PruneWriteLogs")
+	require.Equal(t, 3, pruned, "PruneWriteLogs should remove write logs of versions 0-2")
+
+	_, err = ndb.GetWriteLog(ctx, roots[0], roots[1])
+	require.ErrorIs(t, err, db.ErrWriteLogNotFound, "pruned write log should be gone")
+	_, err = ndb.GetWriteLog(ctx, roots[1], roots[2])
+	require.ErrorIs(t, err, db.ErrWriteLogNotFound, "pruned write log should be gone")
+	wli, err := ndb.GetWriteLog(ctx, roots[2], roots[3])
+	require.NoError(t, err, "write logs of later versions should be kept")
+	_ = writelog.DrainIterator(wli)
+
+	// Roots and nodes should be kept.
+	for version, root := range roots[:3] {
+		tree = NewWithRoot(nil, ndb, root)
+		value, err := tree.Get(ctx, []byte(fmt.Sprintf("foo %d", version)))
+		require.NoError(t, err, "Get")
+		require.Equal(t, []byte("bar"), value)
+		tree.Close()
+	}
+
+	pruned, err = ndb.PruneWriteLogs(ctx, 3)
+	require.NoError(t, err, "PruneWriteLogs")
+	require.Zero(t, pruned, "PruneWriteLogs should be idempotent")
+}
+
 func testSize(t *testing.T, ndb db.NodeDB, factory NodeDBFactory) {
 	ctx := context.Background()
 
@@ -2359,6 +2576,7 @@ func testBackend(
 		{"InsertCommitEach", testInsertCommitEach},
 		{"Remove", testRemove},
 		{"ApplyWriteLog", testApplyWriteLog},
+		{"ApplyWriteLogStreaming", testApplyWriteLogStreaming},
 		{"SyncerBasic", testSyncerBasic},
 		{"SyncerRootEmptyLabelNeedsDeref", testSyncerRootEmptyLabelNeedsDeref},
 		{"SyncerRemove", testSyncerRemove},
@@ -2375,6 +2593,8 @@ func testBackend(
 		{"BasicWriteLog", testBasicWriteLog},
 		{"HasRoot", testHasRoot},
 		{"GetRootsForVersion", testGetRootsForVersion},
+		{"Roots", testRoots},
+		{"PruneWriteLogs", testPruneWriteLogs},
 		{"Size", testSize},
 		{"FinalizeEmpty", testFinalizeEmpty},
 		{"PruneBasic", testPruneBasic},
@@ -2441,6 +2661,33 @@ func TestBadgerBackend(t *testing.T) {
 	}, nil)
 }
 
+func TestBadgerBackendSplitMetadata(t *testing.T) {
+	testBackend(t, func(t *testing.T) (NodeDBFactory, func()) {
+		// Create a new random temporary directory under /tmp.
+		dir, err := os.MkdirTemp("", "mkvs.test.badger.split")
+		require.NoError(t, err, "TempDir")
+
+		// Create a Badger-backed Node DB factory with metadata in a separate store.
+		factory := func(ns common.Namespace) (db.NodeDB, error) {
+			return badgerDb.New(&db.Config{
+				DB:           dir,
+				NoFsync:      true,
+				Namespace:    ns,
+				MaxCacheSize: 16 * 1024 * 1024,
+				BadgerOptions: &db.BadgerOptions{
+					SplitMetadata: true,
+				},
+			})
+		}
+
+		cleanup := func() {
+			os.RemoveAll(dir)
+		}
+
+		return factory, cleanup
+	}, nil)
+}
+
 func TestPathBadgerBackend(t *testing.T) {
 	testBackend(t, func(t *testing.T) (NodeDBFactory, func()) {
 		// Create a new random temporary directory under /tmp.
@@ -2467,6 +2714,55 @@ func TestPathBadgerBackend(t *testing.T) {
 	})
 }
 
+func TestPebbleBackend(t *testing.T) {
+	testBackend(t, func(t *testing.T) (NodeDBFactory, func()) {
+		// Create a new random temporary directory under /tmp.
+		dir, err := os.MkdirTemp("", "mkvs.test.pebble")
+		require.NoError(t, err, "TempDir")
+
+		// Create a Pebble-backed Node DB factory.
+		factory := func(ns common.Namespace) (db.NodeDB, error) {
+			return pebbleDb.New(&db.Config{
+				DB:           dir,
+				NoFsync:      true,
+				Namespace:    ns,
+				MaxCacheSize: 16 * 1024 * 1024,
+			})
+		}
+
+		cleanup := func() {
+			os.RemoveAll(dir)
+		}
+
+		return factory, cleanup
+	}, nil)
+}
+
+func TestMemoryBackend(t *testing.T) {
+	testBackend(t, func(t *testing.T) (NodeDBFactory, func()) {
+		// Create a map-backed Node DB factory.
+		factory := func(ns common.Namespace) (db.NodeDB, error) {
+			return memoryDb.New(&db.Config{
+				Namespace:    ns,
+				MaxCacheSize: 16 * 1024 * 1024,
+			})
+		}
+
+		cleanup := func() {}
+
+		return factory, cleanup
+	}, []string{
+		// Reopening a memory-only database loses all data.
+		"Size",
+		"PruneBasic",
+		"PruneManyVersions",
+		"PruneLoneRoots",
+		"PruneLoneRootsShared4",
+		"PruneForkedRoots",
+		"IncompatibleDB",
+	})
+}
+
 func BenchmarkInsertCommitBatch1(b *testing.B) {
 	benchmarkInsertBatch(b, 1, true)
 }
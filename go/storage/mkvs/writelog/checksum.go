@@ -0,0 +1,82 @@
+package writelog
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+)
+
+// ErrChecksumMismatch is the error returned when a write log entry does not match its checksum.
+var ErrChecksumMismatch = errors.New("mkvs: write log entry checksum mismatch")
+
+// ChecksumMismatchError is the error returned when a write log entry does not match its checksum.
+type ChecksumMismatchError struct {
+	// Index is the index of the offending entry in the write log.
+	Index int
+	// Key is the key of the offending entry.
+	Key []byte
+}
+
+// Error implements the error interface.
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("%s: entry %d (key %X)", ErrChecksumMismatch, e.Index, e.Key)
+}
+
+// Unwrap returns ErrChecksumMismatch.
+func (e *ChecksumMismatchError) Unwrap() error {
+	return ErrChecksumMismatch
+}
+
+// Checksum computes the checksum of the write log entry.
+func (k *LogEntry) Checksum() hash.Hash {
+	return hash.NewFrom(k)
+}
+
+// NewChecksumAnnotations computes checksum annotations for all entries of the given write log.
+func NewChecksumAnnotations(wl WriteLog) Annotations {
+	anns := make(Annotations, len(wl))
+	for i := range wl {
+		checksum := wl[i].Checksum()
+		anns[i].Checksum = &checksum
+	}
+	return anns
+}
+
+// ChecksumAnnotations converts checksums of write log entries (e.g., as produced by the runtime)
+// into checksum annotations.
+func ChecksumAnnotations(checksums []hash.Hash) Annotations {
+	if checksums == nil {
+		return nil
+	}
+	anns := make(Annotations, len(checksums))
+	for i := range checksums {
+		anns[i].Checksum = &checksums[i]
+	}
+	return anns
+}
+
+// VerifyChecksums verifies the write log against its checksum annotations.
+//
+// Entries without a checksum are not verified and a nil annotations slice is always valid. In
+// case of a mismatch, a ChecksumMismatchError describing the first offending entry is returned.
+func VerifyChecksums(wl WriteLog, anns Annotations) error {
+	if anns == nil {
+		return nil
+	}
+	if len(anns) != len(wl) {
+		return fmt.Errorf("%w: %d annotations for %d entries", ErrChecksumMismatch, len(anns), len(wl))
+	}
+	for i := range wl {
+		if anns[i].Checksum == nil {
+			continue
+		}
+		if checksum := wl[i].Checksum(); !checksum.Equal(anns[i].Checksum) {
+			return &ChecksumMismatchError{
+				Index: i,
+				Key:   wl[i].Key,
+			}
+		}
+	}
+	return nil
+}
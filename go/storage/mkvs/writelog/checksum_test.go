@@ -0,0 +1,56 @@
+package writelog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+)
+
+func TestChecksums(t *testing.T) {
+	require := require.New(t)
+
+	wl := makeWriteLog()
+	wl = append(wl, LogEntry{Key: []byte("deleted")})
+	anns := NewChecksumAnnotations(wl)
+	require.Len(anns, len(wl))
+	require.NoError(VerifyChecksums(wl, anns), "VerifyChecksums")
+	require.NoError(VerifyChecksums(wl, nil), "VerifyChecksums without annotations")
+
+	// Flipped value byte.
+	corrupted := make(WriteLog, len(wl))
+	copy(corrupted, wl)
+	corrupted[42].Value = append([]byte{}, wl[42].Value...)
+	corrupted[42].Value[0] ^= 0x01
+	err := VerifyChecksums(corrupted, anns)
+	require.ErrorIs(err, ErrChecksumMismatch)
+	var mismatchErr *ChecksumMismatchError
+	require.True(errors.As(err, &mismatchErr))
+	require.Equal(42, mismatchErr.Index)
+	require.Equal(wl[42].Key, mismatchErr.Key)
+
+	// Deletion turned into an insertion of an empty value.
+	copy(corrupted, wl)
+	corrupted[len(wl)-1].Value = []byte{}
+	err = VerifyChecksums(corrupted, anns)
+	require.ErrorIs(err, ErrChecksumMismatch)
+
+	// Checksums produced elsewhere (e.g., by the runtime).
+	checksums := make([]hash.Hash, len(wl))
+	for i := range wl {
+		checksums[i] = wl[i].Checksum()
+	}
+	require.Equal(anns, ChecksumAnnotations(checksums))
+	require.Nil(ChecksumAnnotations(nil))
+
+	// Entries without checksums are skipped.
+	anns[42].Checksum = nil
+	copy(corrupted, wl)
+	corrupted[42].Value = []byte("something else")
+	require.NoError(VerifyChecksums(corrupted, anns))
+
+	// Mismatched lengths.
+	require.ErrorIs(VerifyChecksums(wl[:10], anns), ErrChecksumMismatch)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
 )
 
@@ -87,4 +88,8 @@ type Annotations []LogEntryAnnotation
 // Entries in a WriteLogAnnotation correspond to WriteLog entries at their respective indexes.
 type LogEntryAnnotation struct {
 	InsertedNode *node.Pointer
+
+	// Checksum is an optional checksum of the write log entry used to detect corruption of the
+	// entry between the point where the write log was produced and where it is applied.
+	Checksum *hash.Hash
 }
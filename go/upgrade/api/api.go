@@ -3,6 +3,7 @@ package api
 
 import (
 	"context"
+	stdErrors "errors"
 	"fmt"
 	"io"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/errors"
 	"github.com/oasisprotocol/oasis-core/go/common/prettyprint"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
 	"github.com/oasisprotocol/oasis-core/go/common/version"
 )
 
@@ -26,6 +28,9 @@ const (
 	// LogEventConsensusUpgrade is a log event value that signals the consensus upgrade handler was
 	// called.
 	LogEventConsensusUpgrade = "upgrade/consensus-upgrade"
+	// LogEventHaltedByOperator is a log event value that signals the consensus layer has reached
+	// the halt epoch requested by the node operator.
+	LogEventHaltedByOperator = "upgrade/halted-by-operator"
 )
 
 // UpgradeStage is used in the upgrade descriptor to store completed stages.
@@ -83,6 +88,11 @@ var (
 	// ErrBadDescriptor is the error returned when the provided descriptor is bad.
 	ErrBadDescriptor = errors.New(ModuleName, 8, "upgrade: bad descriptor")
 
+	// ErrHaltedByOperator is the error returned by the consensus upgrade function when it detects
+	// that the consensus layer has reached the halt epoch requested by the node operator. Unlike
+	// ErrStopForUpgrade, block processing may resume once the halt epoch is cleared.
+	ErrHaltedByOperator = errors.New(ModuleName, 9, "upgrade: halted by operator")
+
 	_ prettyprint.PrettyPrinter = (*Descriptor)(nil)
 )
 
@@ -229,6 +239,90 @@ func (pu *PendingUpgrade) PushStage(stage UpgradeStage) {
 	pu.LastCompletedStage = stage
 }
 
+// HaltStatus is the status of the operator-requested consensus halt.
+type HaltStatus struct {
+	// Epoch is the epoch at which the consensus layer should halt. It is beacon.EpochInvalid in
+	// case no halt has been requested.
+	Epoch beacon.EpochTime `json:"epoch"`
+
+	// Halted is true iff the consensus layer has reached the halt epoch and is not processing
+	// any blocks.
+	Halted bool `json:"halted,omitempty"`
+
+	// Height is the height at which the consensus layer halted.
+	Height int64 `json:"height,omitempty"`
+
+	// Reason is the reason for the halt.
+	Reason string `json:"reason,omitempty"`
+}
+
+// EventKind is the kind of an upgrade event.
+type EventKind uint8
+
+const (
+	// EventScheduled is the event emitted when an upgrade has been scheduled.
+	EventScheduled EventKind = 1
+	// EventEpochReached is the event emitted when the consensus layer has reached the upgrade
+	// epoch.
+	EventEpochReached EventKind = 2
+	// EventMigrationStarted is the event emitted when a migration handler stage starts.
+	EventMigrationStarted EventKind = 3
+	// EventMigrationCompleted is the event emitted when a migration handler stage completes.
+	EventMigrationCompleted EventKind = 4
+	// EventCancelled is the event emitted when an upgrade has been cancelled.
+	EventCancelled EventKind = 5
+
+	EventScheduledName          = "scheduled"
+	EventEpochReachedName       = "epoch_reached"
+	EventMigrationStartedName   = "migration_started"
+	EventMigrationCompletedName = "migration_completed"
+	EventCancelledName          = "cancelled"
+)
+
+// String returns a string representation of an EventKind.
+func (k EventKind) String() string {
+	switch k {
+	case EventScheduled:
+		return EventScheduledName
+	case EventEpochReached:
+		return EventEpochReachedName
+	case EventMigrationStarted:
+		return EventMigrationStartedName
+	case EventMigrationCompleted:
+		return EventMigrationCompletedName
+	case EventCancelled:
+		return EventCancelledName
+	default:
+		return fmt.Sprintf("[unknown upgrade event kind: %d]", k)
+	}
+}
+
+// Event is an upgrade progress event.
+type Event struct {
+	// Kind is the event kind.
+	Kind EventKind `json:"kind"`
+
+	// Descriptor is the descriptor of the upgrade the event refers to.
+	Descriptor *Descriptor `json:"descriptor"`
+
+	// Height is the consensus height at which the upgrade epoch was reached. It is only set for
+	// events emitted once the upgrade epoch has been reached.
+	Height int64 `json:"height,omitempty"`
+
+	// Stage is the migration stage. It is only set for migration events.
+	Stage UpgradeStage `json:"stage,omitempty"`
+
+	// Error is the error that caused the migration stage to fail. It is only set for migration
+	// completion events.
+	Error string `json:"error,omitempty"`
+}
+
+// IsStop returns true iff the given error, returned by the consensus upgrade function, means
+// that the consensus layer should stop processing blocks.
+func IsStop(err error) bool {
+	return stdErrors.Is(err, ErrStopForUpgrade) || stdErrors.Is(err, ErrHaltedByOperator)
+}
+
 // Backend defines the interface for upgrade managers.
 type Backend interface {
 	// SubmitDescriptor submits the serialized descriptor to the upgrade manager
@@ -242,8 +336,16 @@ type Backend interface {
 	HasPendingUpgradeAt(int64) (bool, error)
 
 	// CancelUpgrade cancels a specific pending upgrade, unless it is already in progress.
+	//
+	// In case no such upgrade exists, this returns ErrUpgradeNotFound.
 	CancelUpgrade(*Descriptor) error
 
+	// CancelUpgradeByName cancels the pending upgrade with the given handler name, regardless of
+	// the other descriptor fields, unless it is already in progress.
+	//
+	// In case no such upgrade exists, this returns ErrUpgradeNotFound.
+	CancelUpgradeByName(HandlerName) error
+
 	// GetUpgrade returns the pending upgrade (if any) that has the given descriptor.
 	//
 	// In case no such upgrade exists, this returns ErrUpgradeNotFound.
@@ -258,8 +360,23 @@ type Backend interface {
 	// used to determine which part it is.
 	//
 	// It is idempotent with respect to the current upgrade descriptor.
+	//
+	// In case the returned error satisfies IsStop, the caller must stop processing blocks.
 	ConsensusUpgrade(any, beacon.EpochTime, int64) error
 
+	// SetHaltEpoch sets the epoch at which the consensus layer should halt. Passing
+	// beacon.EpochInvalid clears the halt epoch, allowing block processing to resume.
+	//
+	// The halt epoch is persisted and is cleared automatically when the node is restarted with
+	// a different binary version.
+	SetHaltEpoch(beacon.EpochTime) error
+
+	// GetHaltStatus returns the status of the operator-requested consensus halt.
+	GetHaltStatus() (*HaltStatus, error)
+
+	// WatchEvents returns a channel that produces a stream of upgrade progress events.
+	WatchEvents() (<-chan *Event, pubsub.ClosableSubscription, error)
+
 	// Close cleans up any upgrader state and database handles.
 	Close()
 }
@@ -0,0 +1,24 @@
+// Package config implements global configuration options.
+package config
+
+// Config is the upgrade configuration structure.
+type Config struct {
+	// HaltEpoch is the epoch at which the consensus layer should stop processing blocks
+	// (zero means that no halt is requested).
+	//
+	// The halt can be cleared at runtime via the node controller, after which the configured
+	// epoch is ignored until it is changed. It is also ignored once the node binary is replaced.
+	HaltEpoch uint64 `yaml:"halt_epoch,omitempty"`
+}
+
+// Validate validates the configuration settings.
+func (c *Config) Validate() error {
+	return nil
+}
+
+// DefaultConfig returns the default configuration settings.
+func DefaultConfig() Config {
+	return Config{
+		HaltEpoch: 0,
+	}
+}
@@ -2,6 +2,7 @@ package upgrade
 
 import (
 	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
 	"github.com/oasisprotocol/oasis-core/go/upgrade/api"
 )
 
@@ -22,7 +23,11 @@ func (u *dummyUpgradeManager) HasPendingUpgradeAt(int64) (bool, error) {
 }
 
 func (u *dummyUpgradeManager) CancelUpgrade(*api.Descriptor) error {
-	return nil
+	return api.ErrUpgradeNotFound
+}
+
+func (u *dummyUpgradeManager) CancelUpgradeByName(api.HandlerName) error {
+	return api.ErrUpgradeNotFound
 }
 
 func (u *dummyUpgradeManager) GetUpgrade(*api.Descriptor) (*api.PendingUpgrade, error) {
@@ -37,6 +42,22 @@ func (u *dummyUpgradeManager) ConsensusUpgrade(any, beacon.EpochTime, int64) err
 	return nil
 }
 
+func (u *dummyUpgradeManager) SetHaltEpoch(beacon.EpochTime) error {
+	return nil
+}
+
+func (u *dummyUpgradeManager) GetHaltStatus() (*api.HaltStatus, error) {
+	return &api.HaltStatus{Epoch: beacon.EpochInvalid}, nil
+}
+
+func (u *dummyUpgradeManager) WatchEvents() (<-chan *api.Event, pubsub.ClosableSubscription, error) {
+	ch := make(chan *api.Event)
+	sub := pubsub.NewBroker(false).Subscribe()
+	sub.Unwrap(ch)
+
+	return ch, sub, nil
+}
+
 func (u *dummyUpgradeManager) Close() {
 }
 
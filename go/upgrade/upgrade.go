@@ -14,6 +14,10 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/cbor"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/common/persistent"
+	"github.com/oasisprotocol/oasis-core/go/common/pubsub"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	"github.com/oasisprotocol/oasis-core/go/config"
+	control "github.com/oasisprotocol/oasis-core/go/control/api"
 	"github.com/oasisprotocol/oasis-core/go/upgrade/api"
 	"github.com/oasisprotocol/oasis-core/go/upgrade/migrations"
 )
@@ -22,8 +26,26 @@ var (
 	_ api.Backend = (*upgradeManager)(nil)
 
 	metadataStoreKey = []byte("descriptors")
+	haltStoreKey     = []byte("halt")
 )
 
+// haltControl is the persisted operator-requested consensus halt.
+type haltControl struct {
+	// Epoch is the halt epoch.
+	Epoch beacon.EpochTime `json:"epoch"`
+	// ConfigEpoch is the configured halt epoch at the time the control was last set.
+	ConfigEpoch beacon.EpochTime `json:"config_epoch"`
+	// SoftwareVersion is the version of the binary that set the control.
+	SoftwareVersion string `json:"software_version"`
+
+	// Halted is true iff the consensus layer has reached the halt epoch.
+	Halted bool `json:"halted,omitempty"`
+	// Height is the height at which the consensus layer halted.
+	Height int64 `json:"height,omitempty"`
+	// Reason is the reason for the halt.
+	Reason string `json:"reason,omitempty"`
+}
+
 type upgradeManager struct {
 	sync.Mutex
 
@@ -31,7 +53,13 @@ type upgradeManager struct {
 	pending    []*api.PendingUpgrade
 	shouldStop bool
 
-	dataDir string
+	halt        api.HaltStatus
+	configEpoch beacon.EpochTime
+
+	dataDir         string
+	shutdownReasons *control.ShutdownReasonRegistry
+
+	notifier *pubsub.Broker
 
 	logger *logging.Logger
 }
@@ -62,7 +90,14 @@ func (u *upgradeManager) SubmitDescriptor(descriptor *api.Descriptor) error {
 		"epoch", pending.Descriptor.Epoch,
 	)
 
-	return u.flushDescriptorLocked()
+	if err := u.flushDescriptorLocked(); err != nil {
+		return err
+	}
+	u.notifier.Broadcast(&api.Event{
+		Kind:       api.EventScheduled,
+		Descriptor: descriptor,
+	})
+	return nil
 }
 
 // Implements api.Backend.
@@ -97,30 +132,57 @@ func (u *upgradeManager) CancelUpgrade(descriptor *api.Descriptor) error {
 		return api.ErrBadDescriptor
 	}
 
-	u.Lock()
-	defer u.Unlock()
+	return u.cancelUpgrades(descriptor.Equals)
+}
 
-	if len(u.pending) == 0 {
-		// Make sure nothing is saved.
-		return u.flushDescriptorLocked()
+// Implements api.Backend.
+func (u *upgradeManager) CancelUpgradeByName(name api.HandlerName) error {
+	if err := name.ValidateBasic(); err != nil {
+		return fmt.Errorf("%w: %w", api.ErrBadDescriptor, err)
 	}
 
-	var pending []*api.PendingUpgrade
+	return u.cancelUpgrades(func(descriptor *api.Descriptor) bool {
+		return descriptor.Handler == name
+	})
+}
+
+// cancelUpgrades cancels all pending upgrades with descriptors matching the given predicate. In
+// case any of them is already in progress, nothing is cancelled.
+func (u *upgradeManager) cancelUpgrades(match func(*api.Descriptor) bool) error {
+	u.Lock()
+	defer u.Unlock()
+
+	var pending, cancelled []*api.PendingUpgrade
 	for _, pu := range u.pending {
-		if !pu.Descriptor.Equals(descriptor) {
+		if !match(pu.Descriptor) {
 			pending = append(pending, pu)
 			continue
 		}
 		if pu.UpgradeHeight != api.InvalidUpgradeHeight || pu.HasAnyStages() {
 			return api.ErrUpgradeInProgress
 		}
+		cancelled = append(cancelled, pu)
+	}
+	if len(cancelled) == 0 {
+		return api.ErrUpgradeNotFound
 	}
+
 	oldPending := u.pending
 	u.pending = pending
 	if err := u.flushDescriptorLocked(); err != nil {
 		u.pending = oldPending
 		return err
 	}
+	for _, pu := range cancelled {
+		u.logger.Info("upgrade cancelled",
+			"handler", pu.Descriptor.Handler,
+			"epoch", pu.Descriptor.Epoch,
+		)
+		u.notifier.Broadcast(&api.Event{
+			Kind:       api.EventCancelled,
+			Descriptor: pu.Descriptor,
+		})
+	}
 	return nil
 }
 
@@ -248,7 +310,7 @@ func (u *upgradeManager) StartupUpgrade() error {
 		if err != nil {
 			return err
 		}
-		if err := handler.StartupUpgrade(); err != nil {
+		if err := u.runMigrationLocked(pu, api.UpgradeStageStartup, handler.StartupUpgrade); err != nil {
 			return err
 		}
 		pu.PushStage(api.UpgradeStageStartup)
@@ -257,6 +319,43 @@ func (u *upgradeManager) StartupUpgrade() error {
 	return u.flushDescriptorLocked()
 }
 
+// recordShutdownLocked records and persists the reason for the node shutdown required by the
+// given upgrade so that it is reported by the node status after the restart.
+//
+// In case a reason has already been recorded by the node, that reason is kept.
+func (u *upgradeManager) recordShutdownLocked(pu *api.PendingUpgrade) {
+	u.shutdownReasons.Record(control.ShutdownReason{
+		Kind:            control.ShutdownReasonUpgrade,
+		Component:       api.ModuleName,
+		Message:         fmt.Sprintf("upgrade '%s' reached at height %d", pu.Descriptor.Handler, pu.UpgradeHeight),
+		RestartIntended: true,
+	})
+	if err := u.shutdownReasons.Persist(u.dataDir); err != nil {
+		u.logger.Error("failed to persist shutdown reason",
+			"err", err,
+		)
+	}
+}
+
+// recordHaltLocked records and persists the reason for the node shutdown caused by reaching the
+// operator-requested halt epoch so that it is reported by the node status after the restart.
+//
+// In case a reason has already been recorded by the node, that reason is kept.
+func (u *upgradeManager) recordHaltLocked() {
+	if !u.shutdownReasons.Record(control.ShutdownReason{
+		Kind:      control.ShutdownReasonHalt,
+		Component: api.ModuleName,
+		Message:   fmt.Sprintf("%s at height %d", u.halt.Reason, u.halt.Height),
+	}) {
+		return
+	}
+	if err := u.shutdownReasons.Persist(u.dataDir); err != nil {
+		u.logger.Error("failed to persist shutdown reason",
+			"err", err,
+		)
+	}
+}
+
 // Implements api.Backend.
 func (u *upgradeManager) ConsensusUpgrade(privateCtx any, currentEpoch beacon.EpochTime, currentHeight int64) error {
 	u.Lock()
@@ -268,6 +367,30 @@ func (u *upgradeManager) ConsensusUpgrade(privateCtx any, currentEpoch beacon.Ep
 		return api.ErrStopForUpgrade
 	}
 
+	// Refuse to proceed while the operator-requested halt epoch has been reached.
+	if u.halt.Epoch != beacon.EpochInvalid && currentEpoch >= u.halt.Epoch {
+		if !u.halt.Halted {
+			u.halt.Halted = true
+			u.halt.Height = currentHeight
+			u.halt.Reason = fmt.Sprintf("reached operator-requested halt epoch %d", u.halt.Epoch)
+
+			u.logger.Warn("halting consensus as requested by the operator",
+				"halt_epoch", u.halt.Epoch,
+				"epoch", currentEpoch,
+				"height", currentHeight,
+				logging.LogEvent, api.LogEventHaltedByOperator,
+			)
+
+			if err := u.flushHaltLocked(); err != nil {
+				u.logger.Error("failed to persist halt status",
+					"err", err,
+				)
+			}
+		}
+		u.recordHaltLocked()
+		return api.ErrHaltedByOperator
+	}
+
 	for _, pu := range u.pending {
 		// If we haven't reached the upgrade epoch yet, we run normally;
 		// startup made sure we're an appropriate binary for that.
@@ -279,6 +402,11 @@ func (u *upgradeManager) ConsensusUpgrade(privateCtx any, currentEpoch beacon.Ep
 			if err := u.flushDescriptorLocked(); err != nil {
 				return err
 			}
+			u.notifier.Broadcast(&api.Event{
+				Kind:       api.EventEpochReached,
+				Descriptor: pu.Descriptor,
+				Height:     pu.UpgradeHeight,
+			})
 			// Check if we can proceed in place (e.g. without a restart).
 			u.shouldStop = func() bool {
 				if err := pu.Descriptor.EnsureCompatible(); err != nil {
@@ -298,6 +426,7 @@ func (u *upgradeManager) ConsensusUpgrade(privateCtx any, currentEpoch beacon.Ep
 				return false
 			}()
 			if u.shouldStop {
+				u.recordShutdownLocked(pu)
 				return api.ErrStopForUpgrade
 			}
 			// We can continue with the upgrade in place.
@@ -325,7 +454,9 @@ func (u *upgradeManager) ConsensusUpgrade(privateCtx any, currentEpoch beacon.Ep
 			if err != nil {
 				return err
 			}
-			if err := handler.ConsensusUpgrade(privateCtx); err != nil {
+			if err := u.runMigrationLocked(pu, api.UpgradeStageConsensus, func() error {
+				return handler.ConsensusUpgrade(privateCtx)
+			}); err != nil {
 				return err
 			}
 		}
@@ -334,6 +465,135 @@ func (u *upgradeManager) ConsensusUpgrade(privateCtx any, currentEpoch beacon.Ep
 	return u.flushDescriptorLocked()
 }
 
+// runMigrationLocked runs the given migration handler stage of the pending upgrade and emits
+// the corresponding migration events.
+//
+// NOTE: Assumes lock is held.
+func (u *upgradeManager) runMigrationLocked(pu *api.PendingUpgrade, stage api.UpgradeStage, fn func() error) error {
+	u.notifier.Broadcast(&api.Event{
+		Kind:       api.EventMigrationStarted,
+		Descriptor: pu.Descriptor,
+		Height:     pu.UpgradeHeight,
+		Stage:      stage,
+	})
+
+	err := fn()
+
+	ev := &api.Event{
+		Kind:       api.EventMigrationCompleted,
+		Descriptor: pu.Descriptor,
+		Height:     pu.UpgradeHeight,
+		Stage:      stage,
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	u.notifier.Broadcast(ev)
+
+	return err
+}
+
+// Implements api.Backend.
+func (u *upgradeManager) SetHaltEpoch(epoch beacon.EpochTime) error {
+	u.Lock()
+	defer u.Unlock()
+
+	oldHalt := u.halt
+	u.halt = api.HaltStatus{Epoch: epoch}
+	if err := u.flushHaltLocked(); err != nil {
+		u.halt = oldHalt
+		return err
+	}
+
+	switch {
+	case epoch == beacon.EpochInvalid:
+		u.logger.Info("halt epoch cleared",
+			"was_halted", oldHalt.Halted,
+		)
+	default:
+		u.logger.Info("halt epoch set",
+			"halt_epoch", epoch,
+		)
+	}
+
+	return nil
+}
+
+// Implements api.Backend.
+func (u *upgradeManager) GetHaltStatus() (*api.HaltStatus, error) {
+	u.Lock()
+	defer u.Unlock()
+
+	halt := u.halt
+	return &halt, nil
+}
+
+func (u *upgradeManager) loadHalt() error {
+	u.Lock()
+	defer u.Unlock()
+
+	u.halt = api.HaltStatus{Epoch: u.configEpoch}
+
+	var hc haltControl
+	switch err := u.store.GetCBOR(haltStoreKey, &hc); err {
+	case nil:
+	case persistent.ErrNotFound:
+		return nil
+	default:
+		return fmt.Errorf("can't decode stored halt control: %w", err)
+	}
+
+	switch {
+	case hc.SoftwareVersion != version.SoftwareVersion:
+		// The binary has been replaced, clear the halt epoch.
+		u.logger.Info("binary replaced, clearing halt epoch",
+			"halt_epoch", hc.Epoch,
+		)
+		u.halt = api.HaltStatus{Epoch: beacon.EpochInvalid}
+		return u.flushHaltLocked()
+	case hc.ConfigEpoch != u.configEpoch:
+		// The configured halt epoch has changed, it takes precedence.
+		return u.flushHaltLocked()
+	default:
+		u.halt = api.HaltStatus{
+			Epoch:  hc.Epoch,
+			Halted: hc.Halted,
+			Height: hc.Height,
+			Reason: hc.Reason,
+		}
+	}
+
+	if u.halt.Epoch != beacon.EpochInvalid {
+		u.logger.Info("loaded halt epoch",
+			"halt_epoch", u.halt.Epoch,
+			"halted", u.halt.Halted,
+		)
+	}
+
+	return nil
+}
+
+// NOTE: Assumes lock is held.
+func (u *upgradeManager) flushHaltLocked() error {
+	return u.store.PutCBOR(haltStoreKey, &haltControl{
+		Epoch:           u.halt.Epoch,
+		ConfigEpoch:     u.configEpoch,
+		SoftwareVersion: version.SoftwareVersion,
+		Halted:          u.halt.Halted,
+		Height:          u.halt.Height,
+		Reason:          u.halt.Reason,
+	})
+}
+
+// Implements api.Backend.
+func (u *upgradeManager) WatchEvents() (<-chan *api.Event, pubsub.ClosableSubscription, error) {
+	ch := make(chan *api.Event)
+	sub := u.notifier.Subscribe()
+	sub.Unwrap(ch)
+
+	return ch, sub, nil
+}
+
 // Implements api.Backend.
 func (u *upgradeManager) Close() {
 	u.Lock()
@@ -345,13 +605,32 @@ func (u *upgradeManager) Close() {
 // New constructs and returns a new upgrade manager. It potentially also checks for and loads any
 // pending upgrade descriptors; if this node is not the one intended to be run according
 // to the loaded descriptor, New will return an error.
-func New(store *persistent.CommonStore, dataDir string, checkStatus bool) (api.Backend, error) {
+//
+// Shutdown reasons are recorded in the given registry shared with the rest of the node.
+func New(
+	store *persistent.CommonStore,
+	dataDir string,
+	shutdownReasons *control.ShutdownReasonRegistry,
+	checkStatus bool,
+) (api.Backend, error) {
 	svcStore := store.GetServiceStore(api.ModuleName)
 
+	configEpoch := beacon.EpochInvalid
+	if haltEpoch := config.GlobalConfig.Upgrade.HaltEpoch; haltEpoch != 0 {
+		configEpoch = beacon.EpochTime(haltEpoch)
+	}
+
 	upgrader := &upgradeManager{
-		store:   svcStore,
-		dataDir: dataDir,
-		logger:  logging.GetLogger(api.ModuleName),
+		store:           svcStore,
+		configEpoch:     configEpoch,
+		dataDir:         dataDir,
+		shutdownReasons: shutdownReasons,
+		notifier:        pubsub.NewBroker(false),
+		logger:          logging.GetLogger(api.ModuleName),
+	}
+
+	if err := upgrader.loadHalt(); err != nil {
+		return nil, err
 	}
 
 	if checkStatus {
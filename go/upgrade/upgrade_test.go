@@ -0,0 +1,427 @@
+package upgrade
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/persistent"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	"github.com/oasisprotocol/oasis-core/go/config"
+	control "github.com/oasisprotocol/oasis-core/go/control/api"
+	"github.com/oasisprotocol/oasis-core/go/upgrade/api"
+	"github.com/oasisprotocol/oasis-core/go/upgrade/migrations"
+)
+
+const (
+	testBlocksPerEpoch = 10
+
+	testInPlaceHandler = "__test-in-place"
+	testRestartHandler = "__test-restart"
+)
+
+type inPlaceMigrationHandler struct{}
+
+func (h *inPlaceMigrationHandler) HasStartupUpgrade() bool {
+	return false
+}
+
+func (h *inPlaceMigrationHandler) StartupUpgrade() error {
+	return nil
+}
+
+func (h *inPlaceMigrationHandler) ConsensusUpgrade(any) error {
+	return nil
+}
+
+type restartMigrationHandler struct {
+	inPlaceMigrationHandler
+}
+
+func (h *restartMigrationHandler) HasStartupUpgrade() bool {
+	return true
+}
+
+func init() {
+	migrations.Register(testInPlaceHandler, &inPlaceMigrationHandler{})
+	migrations.Register(testRestartHandler, &restartMigrationHandler{})
+}
+
+// runChain drives a mock chain through the consensus upgrade function until it refuses to
+// proceed or the given height is reached, and returns the last processed height.
+func runChain(u api.Backend, startHeight, endHeight int64) (int64, error) {
+	for height := startHeight; height <= endHeight; height++ {
+		epoch := beacon.EpochTime(height / testBlocksPerEpoch)
+		if err := u.ConsensusUpgrade(nil, epoch, height); err != nil {
+			return height, err
+		}
+	}
+	return endHeight, nil
+}
+
+// mockChain is a mock consensus layer that calls the consensus upgrade function for every block
+// and stops processing blocks when asked to, like the consensus backend does.
+type mockChain struct {
+	u api.Backend
+
+	// height is the height of the last processed block.
+	height int64
+	// stopErr is the error that stopped block processing (if any).
+	stopErr error
+}
+
+// run tries to process the given number of blocks. Processing stops early in case the consensus
+// upgrade function asks the consensus layer to stop, and is retried from the same block on the
+// next run.
+func (c *mockChain) run(blocks int64) error {
+	for end := c.height + blocks; c.height < end; {
+		height := c.height + 1
+		err := c.u.ConsensusUpgrade(nil, beacon.EpochTime(height/testBlocksPerEpoch), height)
+		switch {
+		case err == nil:
+		case api.IsStop(err):
+			c.stopErr = err
+			return nil
+		default:
+			return err
+		}
+		c.height = height
+		c.stopErr = nil
+	}
+	return nil
+}
+
+func TestHaltEpoch(t *testing.T) {
+	require := require.New(t)
+
+	dataDir := t.TempDir()
+	store, err := persistent.NewCommonStore(dataDir)
+	require.NoError(err, "NewCommonStore")
+	defer store.Close()
+
+	reasons, err := control.OpenShutdownReasonRegistry(dataDir)
+	require.NoError(err, "OpenShutdownReasonRegistry")
+	u, err := New(store, dataDir, reasons, true)
+	require.NoError(err, "New")
+	chain := &mockChain{u: u}
+
+	status, err := u.GetHaltStatus()
+	require.NoError(err, "GetHaltStatus")
+	require.Equal(beacon.EpochInvalid, status.Epoch, "no halt epoch should be set by default")
+
+	// Halt at the first block of epoch 3.
+	err = u.SetHaltEpoch(3)
+	require.NoError(err, "SetHaltEpoch")
+	require.NoError(chain.run(100), "run")
+	require.ErrorIs(chain.stopErr, api.ErrHaltedByOperator, "chain should be halted")
+	require.EqualValues(29, chain.height, "the first block of the halt epoch should not be processed")
+
+	status, err = u.GetHaltStatus()
+	require.NoError(err, "GetHaltStatus")
+	require.True(status.Halted)
+	require.EqualValues(3, status.Epoch)
+	require.EqualValues(30, status.Height)
+	require.NotEmpty(status.Reason)
+
+	// The halt should be recorded as the shutdown reason.
+	reason, err := control.LoadShutdownReason(dataDir)
+	require.NoError(err, "LoadShutdownReason")
+	require.NotNil(reason, "shutdown reason should be recorded")
+	require.Equal(control.ShutdownReasonHalt, reason.Kind)
+	require.Equal(api.ModuleName, reason.Component)
+	require.Contains(reason.Message, status.Reason)
+	require.False(reason.RestartIntended)
+
+	// Retrying must not proceed nor change the recorded halt height.
+	require.NoError(chain.run(10), "run")
+	require.ErrorIs(chain.stopErr, api.ErrHaltedByOperator)
+	require.EqualValues(29, chain.height)
+	status, err = u.GetHaltStatus()
+	require.NoError(err, "GetHaltStatus")
+	require.EqualValues(30, status.Height)
+
+	// The halt and its reason should survive restarts.
+	u.Close()
+	reasons, err = control.OpenShutdownReasonRegistry(dataDir)
+	require.NoError(err, "OpenShutdownReasonRegistry")
+	require.Equal(reason, reasons.LastReason())
+	u, err = New(store, dataDir, reasons, true)
+	require.NoError(err, "New")
+	chain.u = u
+	restored, err := u.GetHaltStatus()
+	require.NoError(err, "GetHaltStatus")
+	require.Equal(status, restored, "halt status should survive restarts")
+	require.NoError(chain.run(10), "run")
+	require.ErrorIs(chain.stopErr, api.ErrHaltedByOperator, "halt should survive restarts")
+	require.EqualValues(29, chain.height)
+
+	// Clearing the halt epoch should resume block processing.
+	err = u.SetHaltEpoch(beacon.EpochInvalid)
+	require.NoError(err, "SetHaltEpoch(EpochInvalid)")
+	status, err = u.GetHaltStatus()
+	require.NoError(err, "GetHaltStatus")
+	require.False(status.Halted)
+	require.NoError(chain.run(71), "run")
+	require.NoError(chain.stopErr, "block processing should resume after clearing the halt epoch")
+	require.EqualValues(100, chain.height)
+	u.Close()
+
+	// A changed configured halt epoch takes precedence.
+	config.GlobalConfig.Upgrade.HaltEpoch = 12
+	defer func() {
+		config.GlobalConfig.Upgrade.HaltEpoch = 0
+	}()
+	u, err = New(store, dataDir, control.NewShutdownReasonRegistry(), true)
+	require.NoError(err, "New")
+	chain.u = u
+	require.NoError(chain.run(100), "run")
+	require.ErrorIs(chain.stopErr, api.ErrHaltedByOperator)
+	require.EqualValues(119, chain.height)
+	u.Close()
+
+	// Replacing the binary should clear the halt epoch.
+	oldVersion := version.SoftwareVersion
+	version.SoftwareVersion = oldVersion + "-replaced"
+	defer func() {
+		version.SoftwareVersion = oldVersion
+	}()
+	u, err = New(store, dataDir, control.NewShutdownReasonRegistry(), true)
+	require.NoError(err, "New")
+	defer u.Close()
+	chain.u = u
+	status, err = u.GetHaltStatus()
+	require.NoError(err, "GetHaltStatus")
+	require.Equal(beacon.EpochInvalid, status.Epoch, "replacing the binary should clear the halt epoch")
+	require.False(status.Halted)
+	require.NoError(chain.run(81), "run")
+	require.NoError(chain.stopErr, "block processing should resume after replacing the binary")
+	require.EqualValues(200, chain.height)
+}
+
+func TestWatchEvents(t *testing.T) {
+	require := require.New(t)
+
+	dataDir := t.TempDir()
+	store, err := persistent.NewCommonStore(dataDir)
+	require.NoError(err, "NewCommonStore")
+	defer store.Close()
+
+	u, err := New(store, dataDir, control.NewShutdownReasonRegistry(), true)
+	require.NoError(err, "New")
+	defer u.Close()
+
+	ch, sub, err := u.WatchEvents()
+	require.NoError(err, "WatchEvents")
+	defer sub.Close()
+
+	requireEvent := func(kind api.EventKind, descriptor *api.Descriptor) *api.Event {
+		select {
+		case ev := <-ch:
+			require.Equal(kind, ev.Kind, "unexpected event: %+v", ev)
+			require.True(ev.Descriptor.Equals(descriptor))
+			return ev
+		case <-time.After(time.Second):
+			require.FailNow("timed out waiting for event", kind.String())
+			return nil
+		}
+	}
+
+	upgrade := &api.Descriptor{
+		Versioned: cbor.NewVersioned(api.LatestDescriptorVersion),
+		Handler:   testInPlaceHandler,
+		Target:    version.Versions,
+		Epoch:     2,
+	}
+	cancelled := *upgrade
+	cancelled.Epoch = 5
+
+	err = u.SubmitDescriptor(upgrade)
+	require.NoError(err, "SubmitDescriptor")
+	requireEvent(api.EventScheduled, upgrade)
+	err = u.SubmitDescriptor(&cancelled)
+	require.NoError(err, "SubmitDescriptor")
+	requireEvent(api.EventScheduled, &cancelled)
+
+	err = u.CancelUpgrade(&cancelled)
+	require.NoError(err, "CancelUpgrade")
+	requireEvent(api.EventCancelled, &cancelled)
+
+	pending, err := u.PendingUpgrades()
+	require.NoError(err, "PendingUpgrades")
+	require.Len(pending, 1)
+
+	// Reaching the upgrade epoch runs the migration handler in place.
+	for height := int64(19); height <= 21; height++ {
+		err = u.ConsensusUpgrade(struct{}{}, beacon.EpochTime(height/testBlocksPerEpoch), height)
+		require.NoError(err, "ConsensusUpgrade")
+	}
+	ev := requireEvent(api.EventEpochReached, upgrade)
+	require.EqualValues(20, ev.Height)
+	ev = requireEvent(api.EventMigrationStarted, upgrade)
+	require.Equal(api.UpgradeStageConsensus, ev.Stage)
+	ev = requireEvent(api.EventMigrationCompleted, upgrade)
+	require.Equal(api.UpgradeStageConsensus, ev.Stage)
+	require.EqualValues(20, ev.Height)
+	require.Empty(ev.Error)
+
+	pending, err = u.PendingUpgrades()
+	require.NoError(err, "PendingUpgrades")
+	require.Empty(pending, "completed upgrades should be removed")
+
+	select {
+	case ev = <-ch:
+		require.FailNow("unexpected event", "%+v", ev)
+	default:
+	}
+}
+
+func TestCancelUpgrade(t *testing.T) {
+	require := require.New(t)
+
+	dataDir := t.TempDir()
+	store, err := persistent.NewCommonStore(dataDir)
+	require.NoError(err, "NewCommonStore")
+	defer store.Close()
+
+	u, err := New(store, dataDir, control.NewShutdownReasonRegistry(), true)
+	require.NoError(err, "New")
+	defer u.Close()
+
+	upgrade := &api.Descriptor{
+		Versioned: cbor.NewVersioned(api.LatestDescriptorVersion),
+		Handler:   testInPlaceHandler,
+		Target:    version.Versions,
+		Epoch:     2,
+	}
+	other := *upgrade
+	other.Epoch = 5
+
+	// Cancelling upgrades that are not pending should fail.
+	err = u.CancelUpgrade(upgrade)
+	require.ErrorIs(err, api.ErrUpgradeNotFound, "CancelUpgrade with no pending upgrades")
+	err = u.CancelUpgradeByName(testInPlaceHandler)
+	require.ErrorIs(err, api.ErrUpgradeNotFound, "CancelUpgradeByName with no pending upgrades")
+
+	err = u.SubmitDescriptor(upgrade)
+	require.NoError(err, "SubmitDescriptor")
+	err = u.CancelUpgrade(&other)
+	require.ErrorIs(err, api.ErrUpgradeNotFound, "CancelUpgrade with a different descriptor")
+	err = u.CancelUpgradeByName("__test-unknown")
+	require.ErrorIs(err, api.ErrUpgradeNotFound, "CancelUpgradeByName with a different name")
+	err = u.CancelUpgradeByName("x")
+	require.ErrorIs(err, api.ErrBadDescriptor, "CancelUpgradeByName with an invalid name")
+
+	// Cancelling by name should ignore the other descriptor fields.
+	err = u.CancelUpgradeByName(testInPlaceHandler)
+	require.NoError(err, "CancelUpgradeByName")
+	pending, err := u.PendingUpgrades()
+	require.NoError(err, "PendingUpgrades")
+	require.Empty(pending)
+
+	err = u.SubmitDescriptor(upgrade)
+	require.NoError(err, "SubmitDescriptor")
+	err = u.CancelUpgrade(upgrade)
+	require.NoError(err, "CancelUpgrade")
+	err = u.CancelUpgrade(upgrade)
+	require.ErrorIs(err, api.ErrUpgradeNotFound, "cancelling twice should fail")
+
+	// Upgrades in progress cannot be cancelled.
+	err = u.SubmitDescriptor(upgrade)
+	require.NoError(err, "SubmitDescriptor")
+	_, err = runChain(u, 19, 20)
+	require.NoError(err, "runChain")
+	err = u.CancelUpgradeByName(testInPlaceHandler)
+	require.ErrorIs(err, api.ErrUpgradeInProgress)
+}
+
+func TestStopForUpgradeShutdownReason(t *testing.T) {
+	require := require.New(t)
+
+	dataDir := t.TempDir()
+	store, err := persistent.NewCommonStore(dataDir)
+	require.NoError(err, "NewCommonStore")
+	defer store.Close()
+
+	reasons, err := control.OpenShutdownReasonRegistry(dataDir)
+	require.NoError(err, "OpenShutdownReasonRegistry")
+	u, err := New(store, dataDir, reasons, true)
+	require.NoError(err, "New")
+	defer u.Close()
+
+	err = u.SubmitDescriptor(&api.Descriptor{
+		Versioned: cbor.NewVersioned(api.LatestDescriptorVersion),
+		Handler:   testRestartHandler,
+		Target:    version.Versions,
+		Epoch:     2,
+	})
+	require.NoError(err, "SubmitDescriptor")
+
+	// Upgrades that can proceed in place should not record a shutdown reason.
+	_, err = runChain(u, 1, 19)
+	require.NoError(err, "runChain")
+	reason, err := control.LoadShutdownReason(dataDir)
+	require.NoError(err, "LoadShutdownReason")
+	require.Nil(reason, "no shutdown reason should be recorded before the upgrade epoch")
+
+	// Reaching the upgrade epoch of an upgrade with a startup stage requires a restart.
+	height, err := runChain(u, 20, 30)
+	require.ErrorIs(err, api.ErrStopForUpgrade)
+	require.EqualValues(20, height)
+
+	reason, err = control.LoadShutdownReason(dataDir)
+	require.NoError(err, "LoadShutdownReason")
+	require.NotNil(reason, "shutdown reason should be recorded")
+	require.Equal(control.ShutdownReasonUpgrade, reason.Kind)
+	require.Equal(api.ModuleName, reason.Component)
+	require.Contains(reason.Message, testRestartHandler)
+	require.True(reason.RestartIntended)
+	require.Equal(reason, reasons.Reason(), "the shared registry should hold the upgrade reason")
+
+	// The reason is reported after the restart and cleared afterwards.
+	reasons, err = control.OpenShutdownReasonRegistry(dataDir)
+	require.NoError(err, "OpenShutdownReasonRegistry")
+	require.Equal(reason, reasons.LastReason())
+	reason, err = control.LoadShutdownReason(dataDir)
+	require.NoError(err, "LoadShutdownReason")
+	require.Nil(reason, "reported shutdown reason should be cleared")
+}
+
+func TestStopForUpgradeShutdownReasonFirstWins(t *testing.T) {
+	require := require.New(t)
+
+	dataDir := t.TempDir()
+	store, err := persistent.NewCommonStore(dataDir)
+	require.NoError(err, "NewCommonStore")
+	defer store.Close()
+
+	reasons := control.NewShutdownReasonRegistry()
+	u, err := New(store, dataDir, reasons, true)
+	require.NoError(err, "New")
+	defer u.Close()
+
+	err = u.SubmitDescriptor(&api.Descriptor{
+		Versioned: cbor.NewVersioned(api.LatestDescriptorVersion),
+		Handler:   testRestartHandler,
+		Target:    version.Versions,
+		Epoch:     2,
+	})
+	require.NoError(err, "SubmitDescriptor")
+
+	// The operator requests a shutdown before the upgrade epoch is reached.
+	require.True(reasons.Record(control.ShutdownReason{
+		Kind:       control.ShutdownReasonOperator,
+		Annotation: "host maintenance",
+	}))
+
+	_, err = runChain(u, 1, 30)
+	require.ErrorIs(err, api.ErrStopForUpgrade)
+
+	reason, err := control.LoadShutdownReason(dataDir)
+	require.NoError(err, "LoadShutdownReason")
+	require.NotNil(reason, "shutdown reason should be recorded")
+	require.Equal(control.ShutdownReasonOperator, reason.Kind, "the first recorded reason should be kept")
+	require.Equal("host maintenance", reason.Annotation)
+}
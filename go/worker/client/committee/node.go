@@ -135,6 +135,10 @@ func (sr *SubmitTxSubscription) Stop() {
 // the returned subscription. Not doing so may leak resources associated with tracking the submitted
 // transaction.
 func (n *Node) SubmitTx(ctx context.Context, tx []byte) (*SubmitTxSubscription, *protocol.Error, error) {
+	if n.commonNode.Mirror {
+		return nil, nil, committee.ErrMirroredRuntime
+	}
+
 	// Make sure consensus is synced.
 	select {
 	case <-n.commonNode.Consensus.Synced():
@@ -169,6 +173,9 @@ func (n *Node) SubmitTx(ctx context.Context, tx []byte) (*SubmitTxSubscription,
 }
 
 func (n *Node) CheckTx(ctx context.Context, tx []byte) (*protocol.CheckTxResult, error) {
+	if n.commonNode.Mirror {
+		return nil, committee.ErrMirroredRuntime
+	}
 	return n.commonNode.TxPool.SubmitTx(ctx, tx, &txpool.TransactionMeta{Local: true, Discard: true})
 }
 
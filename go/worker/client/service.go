@@ -25,7 +25,7 @@ type service struct {
 }
 
 func (s *service) submitTx(ctx context.Context, request *api.SubmitTxRequest) (*committee.SubmitTxSubscription, *protocol.Error, error) {
-	rt := s.w.runtimes[request.RuntimeID]
+	rt := s.w.getRuntime(request.RuntimeID)
 	if rt == nil {
 		return nil, nil, api.ErrNoHostedRuntime
 	}
@@ -91,7 +91,7 @@ func (s *service) SubmitTxNoWait(ctx context.Context, request *api.SubmitTxReque
 
 // Implements api.RuntimeClient.
 func (s *service) CheckTx(ctx context.Context, request *api.CheckTxRequest) error {
-	rt := s.w.runtimes[request.RuntimeID]
+	rt := s.w.getRuntime(request.RuntimeID)
 	if rt == nil {
 		return api.ErrNoHostedRuntime
 	}
@@ -294,7 +294,7 @@ func (s *service) GetEvents(ctx context.Context, request *api.GetEventsRequest)
 
 // Implements api.RuntimeClient.
 func (s *service) Query(ctx context.Context, request *api.QueryRequest) (*api.QueryResponse, error) {
-	rt := s.w.runtimes[request.RuntimeID]
+	rt := s.w.getRuntime(request.RuntimeID)
 	if rt == nil {
 		return nil, api.ErrNoHostedRuntime
 	}
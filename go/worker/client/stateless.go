@@ -8,28 +8,47 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/p2p/rpc"
 	storage "github.com/oasisprotocol/oasis-core/go/storage/api"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/syncer"
 	storagePub "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/pub"
 )
 
-type statelessStorage struct {
+// remoteSyncer is a read syncer that queries storage state via the storagepub protocol.
+type remoteSyncer struct {
 	rpc storagePub.Client
 }
 
-func (s *statelessStorage) SyncGet(ctx context.Context, request *storage.GetRequest) (*storage.ProofResponse, error) {
-	rsp, _, err := s.rpc.Get(ctx, request)
+func (r *remoteSyncer) SyncGet(ctx context.Context, request *storage.GetRequest) (*storage.ProofResponse, error) {
+	rsp, _, err := r.rpc.Get(ctx, request)
 	return rsp, err
 }
 
-func (s *statelessStorage) SyncGetPrefixes(ctx context.Context, request *storage.GetPrefixesRequest) (*storage.ProofResponse, error) {
-	rsp, _, err := s.rpc.GetPrefixes(ctx, request)
+func (r *remoteSyncer) SyncGetPrefixes(ctx context.Context, request *storage.GetPrefixesRequest) (*storage.ProofResponse, error) {
+	rsp, _, err := r.rpc.GetPrefixes(ctx, request)
 	return rsp, err
 }
 
-func (s *statelessStorage) SyncIterate(ctx context.Context, request *storage.IterateRequest) (*storage.ProofResponse, error) {
-	rsp, _, err := s.rpc.Iterate(ctx, request)
+func (r *remoteSyncer) SyncIterate(ctx context.Context, request *storage.IterateRequest) (*storage.ProofResponse, error) {
+	rsp, _, err := r.rpc.Iterate(ctx, request)
 	return rsp, err
 }
 
+type statelessStorage struct {
+	// rs coalesces concurrent identical requests to remote nodes.
+	rs *syncer.Coalescer
+}
+
+func (s *statelessStorage) SyncGet(ctx context.Context, request *storage.GetRequest) (*storage.ProofResponse, error) {
+	return s.rs.SyncGet(ctx, request)
+}
+
+func (s *statelessStorage) SyncGetPrefixes(ctx context.Context, request *storage.GetPrefixesRequest) (*storage.ProofResponse, error) {
+	return s.rs.SyncGetPrefixes(ctx, request)
+}
+
+func (s *statelessStorage) SyncIterate(ctx context.Context, request *storage.IterateRequest) (*storage.ProofResponse, error) {
+	return s.rs.SyncIterate(ctx, request)
+}
+
 func (s *statelessStorage) GetDiff(context.Context, *storage.GetDiffRequest) (storage.WriteLogIterator, error) {
 	return nil, storage.ErrUnsupported
 }
@@ -55,6 +74,8 @@ func (s *statelessStorage) Initialized() <-chan struct{} {
 // storagepub protocol to query storage state.
 func NewStatelessStorage(p2p rpc.P2P, chainContext string, runtimeID common.Namespace) storage.Backend {
 	return &statelessStorage{
-		rpc: storagePub.NewClient(p2p, chainContext, runtimeID),
+		rs: syncer.NewCoalescer(&remoteSyncer{
+			rpc: storagePub.NewClient(p2p, chainContext, runtimeID),
+		}),
 	}
 }
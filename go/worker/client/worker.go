@@ -3,6 +3,7 @@ package client
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/grpc"
@@ -23,8 +24,12 @@ type Worker struct {
 	commonWorker *workerCommon.Worker
 	registration *registration.Worker
 
-	runtimes map[common.Namespace]*committee.Node
+	runtimesLock sync.RWMutex
+	runtimes     map[common.Namespace]*committee.Node
+	started      bool
+	stopped      bool
 
+	stopCh chan struct{}
 	quitCh chan struct{}
 	initCh chan struct{}
 
@@ -47,18 +52,30 @@ func (w *Worker) Start() error {
 		return nil
 	}
 
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
 	// Wait for all runtimes to terminate.
 	go func() {
 		defer close(w.quitCh)
 
-		for _, rt := range w.runtimes {
+		if w.commonWorker.GetConfig().AllowDynamicRuntimes {
+			// Runtimes may be added until the worker is stopped.
+			<-w.stopCh
+		}
+
+		for _, rt := range w.getRuntimes() {
 			<-rt.Quit()
 		}
 	}()
 
-	// Wait for all runtimes to be initialized.
+	// Wait for all configured runtimes to be initialized.
+	runtimes := make([]*committee.Node, 0, len(w.runtimes))
+	for _, rt := range w.runtimes {
+		runtimes = append(runtimes, rt)
+	}
 	go func() {
-		for _, rt := range w.runtimes {
+		for _, rt := range runtimes {
 			<-rt.Initialized()
 		}
 
@@ -75,6 +92,7 @@ func (w *Worker) Start() error {
 			return err
 		}
 	}
+	w.started = true
 
 	return nil
 }
@@ -86,6 +104,15 @@ func (w *Worker) Stop() {
 		return
 	}
 
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
+	if w.stopped {
+		return
+	}
+	w.stopped = true
+	close(w.stopCh)
+
 	for id, rt := range w.runtimes {
 		w.logger.Info("stopping services for runtime",
 			"runtime_id", id,
@@ -111,7 +138,7 @@ func (w *Worker) Cleanup() {
 		return
 	}
 
-	for _, rt := range w.runtimes {
+	for _, rt := range w.getRuntimes() {
 		rt.Cleanup()
 	}
 }
@@ -122,6 +149,47 @@ func (w *Worker) Initialized() <-chan struct{} {
 	return w.initCh
 }
 
+func (w *Worker) getRuntime(id common.Namespace) *committee.Node {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
+	return w.runtimes[id]
+}
+
+func (w *Worker) getRuntimes() []*committee.Node {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
+	runtimes := make([]*committee.Node, 0, len(w.runtimes))
+	for _, rt := range w.runtimes {
+		runtimes = append(runtimes, rt)
+	}
+	return runtimes
+}
+
+// addRuntime sets up the runtime client for a runtime added while the node is running.
+func (w *Worker) addRuntime(commonNode *committeeCommon.Node) error {
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
+	if w.stopped {
+		return fmt.Errorf("client worker stopped")
+	}
+	if err := w.registerRuntime(commonNode); err != nil {
+		return err
+	}
+	if !w.started {
+		return nil
+	}
+
+	id := commonNode.Runtime.ID()
+	w.logger.Info("starting services for runtime",
+		"runtime_id", id,
+	)
+
+	return w.runtimes[id].Start()
+}
+
 func (w *Worker) registerRuntime(commonNode *committeeCommon.Node) error {
 	id := commonNode.Runtime.ID()
 
@@ -134,8 +202,10 @@ func (w *Worker) registerRuntime(commonNode *committeeCommon.Node) error {
 		rp  registration.RoleProvider
 	)
 
-	switch config.GlobalConfig.Mode {
-	case config.ModeClient, config.ModeStatelessClient:
+	switch {
+	case commonNode.Mirror:
+		// Mirrored runtimes are only followed, so the node never registers for them.
+	case config.GlobalConfig.Mode.IsClientOnly():
 		// When a node is a client node and it has an entity configured,
 		// we register it with the observer role as this may be needed
 		// for confidential runtimes.
@@ -199,6 +269,7 @@ func New(
 		commonWorker: commonWorker,
 		registration: registration,
 		runtimes:     make(map[common.Namespace]*committee.Node),
+		stopCh:       make(chan struct{}),
 		quitCh:       make(chan struct{}),
 		initCh:       make(chan struct{}),
 		logger:       logging.GetLogger("worker/client"),
@@ -214,6 +285,7 @@ func New(
 			return nil, err
 		}
 	}
+	commonWorker.OnRuntimeAdded(w.addRuntime)
 
 	srv := &service{w: w}
 	// Attach the runtime client worker's internal GRPC interface.
@@ -2,7 +2,9 @@ package api
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	"github.com/oasisprotocol/oasis-core/go/common/version"
 	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
 )
@@ -27,6 +29,8 @@ const (
 	StatusStateWaitingWorkersInit StatusState = 6
 	// StatusStateRuntimeSuspended is the runtime suspended status state.
 	StatusStateRuntimeSuspended StatusState = 7
+	// StatusStateLagging is the lagging behind the latest runtime state status state.
+	StatusStateLagging StatusState = 8
 )
 
 // String returns a string representation of a status state.
@@ -48,6 +52,8 @@ func (s StatusState) String() string {
 		return "waiting for workers to initialize"
 	case StatusStateRuntimeSuspended:
 		return "runtime suspended"
+	case StatusStateLagging:
+		return "lagging behind"
 	default:
 		return "[invalid status state]"
 	}
@@ -72,6 +78,8 @@ func (s StatusState) MarshalText() ([]byte, error) {
 		return []byte(StatusStateWaitingWorkersInit.String()), nil
 	case StatusStateRuntimeSuspended:
 		return []byte(StatusStateRuntimeSuspended.String()), nil
+	case StatusStateLagging:
+		return []byte(StatusStateLagging.String()), nil
 	default:
 		return nil, fmt.Errorf("invalid StatusState: %d", s)
 	}
@@ -96,6 +104,8 @@ func (s *StatusState) UnmarshalText(text []byte) error {
 		*s = StatusStateWaitingWorkersInit
 	case StatusStateRuntimeSuspended.String():
 		*s = StatusStateRuntimeSuspended
+	case StatusStateLagging.String():
+		*s = StatusStateLagging
 	default:
 		return fmt.Errorf("invalid StatusState: %s", string(text))
 	}
@@ -123,8 +133,13 @@ type Status struct {
 	// Liveness is the node's liveness status for the current epoch.
 	Liveness *LivenessStatus `json:"liveness,omitempty"`
 
+	// Lag is the node's lag behind the latest runtime state.
+	Lag *LagStatus `json:"lag,omitempty"`
+
 	// Peers is the list of peers in the runtime P2P network.
 	Peers []string `json:"peers"`
+	// CommitteePeers is the connection state of peers in the current committee.
+	CommitteePeers []CommitteePeerStatus `json:"committee_peers,omitempty"`
 
 	// Host is the runtime host status.
 	Host HostStatus `json:"host"`
@@ -153,3 +168,70 @@ type LivenessStatus struct {
 	// with the highest rank.
 	MissedProposals uint64 `json:"missed_proposals"`
 }
+
+// LagStatus is the status of how far the node is behind the latest runtime state.
+type LagStatus struct {
+	// ConsensusHeight is the number of consensus blocks between the block containing the latest
+	// roothash round and the block containing the latest round processed by the committee node.
+	ConsensusHeight uint64 `json:"consensus_height"`
+
+	// Rounds is the number of roothash rounds not yet processed by the committee node.
+	Rounds uint64 `json:"rounds"`
+
+	// Storage is the local storage lag, if the node has local storage.
+	Storage *StorageLagStatus `json:"storage,omitempty"`
+
+	// Issues are the reasons for the lag being considered unhealthy.
+	Issues []string `json:"issues,omitempty"`
+}
+
+// IsHealthy returns true iff the lag is within the healthy thresholds.
+func (l *LagStatus) IsHealthy() bool {
+	return len(l.Issues) == 0
+}
+
+// StorageLagStatus is the status of how far local storage is behind the latest runtime state.
+type StorageLagStatus struct {
+	// FinalizedRounds is the number of roothash rounds not yet finalized in local storage.
+	FinalizedRounds uint64 `json:"finalized_rounds"`
+
+	// SinceLastFinalize is the time elapsed since the last successful finalization.
+	SinceLastFinalize time.Duration `json:"since_last_finalize"`
+}
+
+// CommitteePeerState is the connection state of a committee peer.
+type CommitteePeerState string
+
+const (
+	// CommitteePeerStateConnecting is the state of a peer that is being dialed.
+	CommitteePeerStateConnecting CommitteePeerState = "connecting"
+	// CommitteePeerStateConnected is the state of a connected peer.
+	CommitteePeerStateConnected CommitteePeerState = "connected"
+	// CommitteePeerStateDisconnected is the state of a peer that is not connected and will be
+	// re-dialed once the back-off expires.
+	CommitteePeerStateDisconnected CommitteePeerState = "disconnected"
+	// CommitteePeerStateExpiring is the state of a peer that is no longer a committee member and
+	// will be dropped once the grace period expires.
+	CommitteePeerStateExpiring CommitteePeerState = "expiring"
+)
+
+// CommitteePeerStatus is the connection status of a committee peer.
+type CommitteePeerStatus struct {
+	// NodeID is the node identifier of the peer.
+	NodeID signature.PublicKey `json:"node_id"`
+
+	// PeerID is the P2P identifier of the peer.
+	PeerID string `json:"peer_id"`
+
+	// State is the connection state.
+	State CommitteePeerState `json:"state"`
+
+	// Since is the time of the last state change.
+	Since time.Time `json:"since"`
+
+	// DialAttempts is the number of dial attempts since the peer became a committee member.
+	DialAttempts uint64 `json:"dial_attempts"`
+
+	// LastError is the error of the last failed dial attempt (if any).
+	LastError string `json:"last_error,omitempty"`
+}
@@ -39,6 +39,7 @@ type CommitteeInfo struct { // nolint: revive
 	Committee  *scheduler.Committee
 	PublicKeys map[signature.PublicKey]struct{}
 	Peers      map[signature.PublicKey]struct{}
+	NodePeers  map[signature.PublicKey]signature.PublicKey
 }
 
 // HasRole checks whether the node has the given role.
@@ -129,6 +130,21 @@ func (e *EpochSnapshot) IsExecutorBackupWorker() bool {
 	return e.executorCommittee.HasRole(scheduler.RoleBackupWorker)
 }
 
+// committeeMembers returns the node descriptors of all members of the current committees.
+func (e *EpochSnapshot) committeeMembers() []*node.Node {
+	if e.executorCommittee == nil {
+		return nil
+	}
+
+	members := make([]*node.Node, 0, len(e.executorCommittee.Committee.Members))
+	for _, member := range e.executorCommittee.Committee.Members {
+		if n := e.nodes.Lookup(member.PublicKey); n != nil {
+			members = append(members, n)
+		}
+	}
+	return members
+}
+
 // Nodes returns a node descriptor lookup interface.
 func (e *EpochSnapshot) Nodes() nodes.NodeDescriptorLookup {
 	return e.nodes
@@ -210,6 +226,7 @@ func (g *Group) EpochTransition(ctx context.Context, height int64) error {
 		)
 		publicKeys := make(map[signature.PublicKey]struct{})
 		peers := make(map[signature.PublicKey]struct{})
+		nodePeers := make(map[signature.PublicKey]signature.PublicKey)
 		for index, member := range cm.Members {
 			publicKeys[member.PublicKey] = struct{}{}
 			if member.PublicKey.Equal(publicIdentity) {
@@ -224,6 +241,7 @@ func (g *Group) EpochTransition(ctx context.Context, height int64) error {
 			}
 
 			peers[n.P2P.ID] = struct{}{}
+			nodePeers[member.PublicKey] = n.P2P.ID
 		}
 
 		ci := &CommitteeInfo{
@@ -232,6 +250,7 @@ func (g *Group) EpochTransition(ctx context.Context, height int64) error {
 			Committee:  cm,
 			PublicKeys: publicKeys,
 			Peers:      peers,
+			NodePeers:  nodePeers,
 		}
 
 		switch cm.Kind {
@@ -0,0 +1,101 @@
+package committee
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oasisprotocol/oasis-core/go/worker/common/api"
+)
+
+const (
+	// maxHealthyConsensusHeightLag is the maximum number of consensus blocks the committee node
+	// may be behind the latest roothash round before it is considered lagging.
+	maxHealthyConsensusHeightLag = 50
+	// maxHealthyRoundLag is the maximum number of roothash rounds the committee node may be
+	// behind before it is considered lagging.
+	maxHealthyRoundLag = 10
+	// maxHealthyStorageRoundLag is the maximum number of roothash rounds local storage may be
+	// behind before it is considered lagging.
+	maxHealthyStorageRoundLag = 15
+	// maxHealthyFinalizeDelay is the maximum time local storage may go without finalizing a
+	// round while it is behind before finalization is considered stalled.
+	maxHealthyFinalizeDelay = 5 * time.Minute
+)
+
+// storageFinalization tracks the latest round finalized in local storage.
+type storageFinalization struct {
+	sync.Mutex
+
+	known bool
+	round uint64
+	at    time.Time
+}
+
+// NotifyStorageFinalized notifies the committee node that local storage has successfully
+// finalized the given round.
+func (n *Node) NotifyStorageFinalized(round uint64) {
+	n.storageFinalized.Lock()
+	defer n.storageFinalized.Unlock()
+
+	n.storageFinalized.known = true
+	n.storageFinalized.round = round
+	n.storageFinalized.at = time.Now()
+}
+
+// Guarded by n.CrossNode.
+func (n *Node) getLagStatusLocked(latestRound uint64, latestHeight int64, now time.Time) *api.LagStatus {
+	if n.CurrentBlock == nil {
+		return nil
+	}
+
+	lag := api.LagStatus{
+		ConsensusHeight: lagOf(uint64(max(latestHeight, 0)), uint64(max(n.CurrentBlockHeight, 0))),
+		Rounds:          lagOf(latestRound, n.CurrentBlock.Header.Round),
+	}
+
+	n.storageFinalized.Lock()
+	if n.storageFinalized.known {
+		lag.Storage = &api.StorageLagStatus{
+			FinalizedRounds:   lagOf(latestRound, n.storageFinalized.round),
+			SinceLastFinalize: now.Sub(n.storageFinalized.at),
+		}
+	}
+	n.storageFinalized.Unlock()
+
+	if lag.ConsensusHeight > maxHealthyConsensusHeightLag {
+		lag.Issues = append(lag.Issues, fmt.Sprintf("consensus height lag %d exceeds %d", lag.ConsensusHeight, maxHealthyConsensusHeightLag))
+	}
+	if lag.Rounds > maxHealthyRoundLag {
+		lag.Issues = append(lag.Issues, fmt.Sprintf("round lag %d exceeds %d", lag.Rounds, maxHealthyRoundLag))
+	}
+	if s := lag.Storage; s != nil {
+		if s.FinalizedRounds > maxHealthyStorageRoundLag {
+			lag.Issues = append(lag.Issues, fmt.Sprintf("storage finalized round lag %d exceeds %d", s.FinalizedRounds, maxHealthyStorageRoundLag))
+		}
+		// An idle runtime does not produce new rounds, so only a lagging storage can stall.
+		if s.FinalizedRounds > 0 && s.SinceLastFinalize > maxHealthyFinalizeDelay {
+			lag.Issues = append(lag.Issues, fmt.Sprintf("storage finalization stalled for %s", s.SinceLastFinalize.Truncate(time.Second)))
+		}
+	}
+
+	return &lag
+}
+
+func updateLagMetrics(labels prometheus.Labels, lag *api.LagStatus) {
+	consensusHeightLag.With(labels).Set(float64(lag.ConsensusHeight))
+	roundLag.With(labels).Set(float64(lag.Rounds))
+	if lag.Storage != nil {
+		storageFinalizedRoundLag.With(labels).Set(float64(lag.Storage.FinalizedRounds))
+		storageSinceLastFinalize.With(labels).Set(lag.Storage.SinceLastFinalize.Seconds())
+	}
+}
+
+func lagOf(latest, current uint64) uint64 {
+	if current >= latest {
+		return 0
+	}
+	return latest - current
+}
@@ -0,0 +1,121 @@
+package committee
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/api"
+)
+
+func newLagTestNode(round uint64, height int64) *Node {
+	n := &Node{}
+	for _, state := range []*uint32{
+		&n.consensusSynced,
+		&n.runtimeRegistryDescriptor,
+		&n.keymanagerAvailable,
+		&n.hostedRuntimeProvisioned,
+		&n.historyReindexingDone,
+		&n.workersInitialized,
+	} {
+		atomic.StoreUint32(state, 1)
+	}
+
+	var ns common.Namespace
+	n.CurrentBlock = block.NewGenesisBlock(ns, 0)
+	n.CurrentBlock.Header.Round = round
+	n.CurrentBlockHeight = height
+	return n
+}
+
+func TestLagStatus(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Now()
+	n := newLagTestNode(100, 1000)
+
+	// No lag without local storage.
+	lag := n.getLagStatusLocked(100, 1000, now)
+	require.NotNil(lag)
+	require.Zero(lag.ConsensusHeight)
+	require.Zero(lag.Rounds)
+	require.Nil(lag.Storage)
+	require.True(lag.IsHealthy())
+	require.Equal(api.StatusStateReady, n.getStatusStateLocked(lag))
+
+	// Caught up local storage.
+	n.NotifyStorageFinalized(100)
+	lag = n.getLagStatusLocked(100, 1000, now)
+	require.NotNil(lag.Storage)
+	require.Zero(lag.Storage.FinalizedRounds)
+	require.True(lag.IsHealthy())
+
+	// Idle runtime should not be considered stalled.
+	lag = n.getLagStatusLocked(100, 1000, now.Add(time.Hour))
+	require.True(lag.IsHealthy(), "idle runtime should be healthy: %v", lag.Issues)
+
+	// Committee node slightly behind.
+	lag = n.getLagStatusLocked(105, 1010, now)
+	require.EqualValues(10, lag.ConsensusHeight)
+	require.EqualValues(5, lag.Rounds)
+	require.EqualValues(5, lag.Storage.FinalizedRounds)
+	require.True(lag.IsHealthy())
+
+	// Committee node too far behind.
+	lag = n.getLagStatusLocked(100+maxHealthyRoundLag+1, 1000+maxHealthyConsensusHeightLag+1, now)
+	require.Len(lag.Issues, 2)
+	require.Equal(api.StatusStateLagging, n.getStatusStateLocked(lag))
+
+	// Not ready states take precedence.
+	atomic.StoreUint32(&n.workersInitialized, 0)
+	require.Equal(api.StatusStateWaitingWorkersInit, n.getStatusStateLocked(lag))
+}
+
+func TestLagStatusStorageFinalizer(t *testing.T) {
+	require := require.New(t)
+
+	labels := prometheus.Labels{"runtime": "lag test"}
+	now := time.Now()
+
+	// The committee node keeps up, but local storage finalization is stuck.
+	n := newLagTestNode(120, 1200)
+	n.NotifyStorageFinalized(110)
+
+	lag := n.getLagStatusLocked(120, 1200, now.Add(time.Minute))
+	require.Zero(lag.Rounds)
+	require.EqualValues(10, lag.Storage.FinalizedRounds)
+	require.True(lag.IsHealthy(), "storage within thresholds should be healthy: %v", lag.Issues)
+	require.Equal(api.StatusStateReady, n.getStatusStateLocked(lag))
+
+	// Finalization stalls.
+	lag = n.getLagStatusLocked(120, 1200, now.Add(maxHealthyFinalizeDelay+time.Minute))
+	require.Len(lag.Issues, 1)
+	require.Contains(lag.Issues[0], "stalled")
+	require.Equal(api.StatusStateLagging, n.getStatusStateLocked(lag))
+
+	updateLagMetrics(labels, lag)
+	require.EqualValues(0, testutil.ToFloat64(roundLag.With(labels)))
+	require.EqualValues(10, testutil.ToFloat64(storageFinalizedRoundLag.With(labels)))
+	require.InDelta(lag.Storage.SinceLastFinalize.Seconds(), testutil.ToFloat64(storageSinceLastFinalize.With(labels)), 0.001)
+	require.Greater(testutil.ToFloat64(storageSinceLastFinalize.With(labels)), maxHealthyFinalizeDelay.Seconds())
+
+	// Storage falls too far behind.
+	lag = n.getLagStatusLocked(120+maxHealthyStorageRoundLag, 1300, now)
+	require.Len(lag.Issues, 3)
+	updateLagMetrics(labels, lag)
+	require.EqualValues(maxHealthyStorageRoundLag, testutil.ToFloat64(roundLag.With(labels)))
+	require.EqualValues(10+maxHealthyStorageRoundLag, testutil.ToFloat64(storageFinalizedRoundLag.With(labels)))
+	require.EqualValues(100, testutil.ToFloat64(consensusHeightLag.With(labels)))
+
+	// Storage catches up.
+	n.NotifyStorageFinalized(120 + maxHealthyStorageRoundLag)
+	lag = n.getLagStatusLocked(120+maxHealthyStorageRoundLag, 1200, now)
+	require.Zero(lag.Storage.FinalizedRounds)
+	require.Len(lag.Issues, 1, "only the committee node should be lagging")
+}
@@ -25,6 +25,7 @@ import (
 	roothash "github.com/oasisprotocol/oasis-core/go/roothash/api"
 	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
 	runtime "github.com/oasisprotocol/oasis-core/go/runtime/api"
+	"github.com/oasisprotocol/oasis-core/go/runtime/bundle"
 	"github.com/oasisprotocol/oasis-core/go/runtime/host"
 	"github.com/oasisprotocol/oasis-core/go/runtime/host/protocol"
 	runtimeRegistry "github.com/oasisprotocol/oasis-core/go/runtime/registry"
@@ -36,6 +37,10 @@ import (
 
 const periodicMetricsInterval = 60 * time.Second
 
+// ErrMirroredRuntime is the error returned when an operation is not available because the runtime
+// is only mirrored.
+var ErrMirroredRuntime = fmt.Errorf("worker/common/committee: runtime is only mirrored")
+
 var (
 	processedBlockCount = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -107,10 +112,47 @@ var (
 		},
 		[]string{"runtime"},
 	)
+	consensusHeightLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_consensus_height_lag",
+			Help: "Number of consensus blocks between the latest and the last processed roothash round.",
+		},
+		[]string{"runtime"},
+	)
+	roundLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_round_lag",
+			Help: "Number of roothash rounds not yet processed by the worker.",
+		},
+		[]string{"runtime"},
+	)
+	storageFinalizedRoundLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_storage_finalized_round_lag",
+			Help: "Number of roothash rounds not yet finalized in local storage.",
+		},
+		[]string{"runtime"},
+	)
+	storageSinceLastFinalize = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "oasis_worker_storage_since_last_finalize_seconds",
+			Help: "Time since the last successful local storage finalization.",
+		},
+		[]string{"runtime"},
+	)
+
+	committeeMessagesRateLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "oasis_worker_p2p_committee_messages_rate_limited",
+			Help: "Number of inbound committee messages dropped due to per-peer rate limits.",
+		},
+		[]string{"runtime", "kind"},
+	)
 
 	nodeCollectors = []prometheus.Collector{
 		processedBlockCount,
 		failedRoundCount,
+		committeeMessagesRateLimited,
 		epochTransitionCount,
 		epochNumber,
 		// Periodically collected metrics.
@@ -120,6 +162,10 @@ var (
 		livenessTotalRounds,
 		livenessLiveRounds,
 		livenessRatio,
+		consensusHeightLag,
+		roundLag,
+		storageFinalizedRoundLag,
+		storageSinceLastFinalize,
 	}
 
 	metricsOnce sync.Once
@@ -149,6 +195,10 @@ type Node struct {
 	Runtime         runtimeRegistry.Runtime
 	RuntimeRegistry runtimeRegistry.Registry
 
+	// Mirror is true iff the runtime state is only mirrored. Mirrored runtimes follow blocks but
+	// never accept, gossip or schedule transactions.
+	Mirror bool
+
 	HostNode control.NodeController
 
 	Identity         *identity.Identity
@@ -159,8 +209,20 @@ type Node struct {
 	Group            *Group
 	P2P              p2pAPI.Service
 	TxPool           txpool.TransactionPool
+	TxValidators     *txpool.StatelessValidators
 	notifier         protocol.Notifier
 
+	// txValidators are the additional stateless transaction validators enabled for the runtime
+	// by the node configuration.
+	txValidators []string
+	// bundleTxValidators are the stateless transaction validators requested by the bundle
+	// metadata of each provisioned runtime version.
+	// Guarded by .CrossNode.
+	bundleTxValidators map[version.Version][]string
+
+	// committeePeers maintains connections to committee members, nil if P2P is disabled.
+	committeePeers *committeePeers
+
 	txTopic string
 
 	ctx       context.Context
@@ -173,6 +235,19 @@ type Node struct {
 
 	hooks []NodeHooks
 
+	// hostCalls tracks in-flight host calls that are drained before a graceful runtime restart.
+	hostCalls hostCallTracker
+	// restartCh receives runtime restart requests handled by the worker.
+	restartCh chan *restartRequest
+	// restartDoneCh is closed once the last runtime restart started by the worker completes.
+	restartDoneCh chan struct{}
+	// hostedRuntimeStarted is set once the worker has started the hosted runtime and accepts
+	// restart requests.
+	hostedRuntimeStarted uint32
+	// runtimeRestarting is set while the hosted runtime is being restarted.
+	// Guarded by .CrossNode.
+	runtimeRestarting bool
+
 	// Status states.
 	consensusSynced           uint32
 	runtimeRegistryDescriptor uint32
@@ -190,10 +265,12 @@ type Node struct {
 	CurrentDescriptor     *registry.Runtime
 	CurrentEpoch          beacon.EpochTime
 
+	storageFinalized storageFinalization
+
 	logger *logging.Logger
 }
 
-func (n *Node) getStatusStateLocked() api.StatusState {
+func (n *Node) getStatusStateLocked(lag *api.LagStatus) api.StatusState {
 	if atomic.LoadUint32(&n.consensusSynced) == 0 {
 		return api.StatusStateWaitingConsensusSync
 	}
@@ -216,6 +293,9 @@ func (n *Node) getStatusStateLocked() api.StatusState {
 	if n.resumeCh != nil {
 		return api.StatusStateRuntimeSuspended
 	}
+	if lag != nil && !lag.IsHealthy() {
+		return api.StatusStateLagging
+	}
 
 	return api.StatusStateReady
 }
@@ -232,6 +312,7 @@ func (n *Node) Start() error {
 	}
 
 	go n.worker()
+	go n.committeePeers.run()
 	if cmmetrics.Enabled() {
 		go n.metricsWorker()
 	}
@@ -243,7 +324,9 @@ func (n *Node) Start() error {
 func (n *Node) Stop() {
 	n.stopOnce.Do(func() {
 		close(n.stopCh)
-		n.TxPool.Stop()
+		if !n.Mirror {
+			n.TxPool.Stop()
+		}
 		n.KeyManagerClient.SetKeyManagerID(nil)
 	})
 }
@@ -275,13 +358,24 @@ func (n *Node) GetStatus() (*api.Status, error) {
 	defer n.CrossNode.Unlock()
 
 	var status api.Status
-	status.Status = n.getStatusStateLocked()
 
 	if n.CurrentBlock != nil {
 		status.LatestRound = n.CurrentBlock.Header.Round
 		status.LatestHeight = n.CurrentBlockHeight
 	}
 
+	rs, rsErr := n.Consensus.RootHash().GetRuntimeState(n.ctx, &roothash.RuntimeRequest{
+		RuntimeID: n.Runtime.ID(),
+		Height:    consensus.HeightLatest,
+	})
+	if rsErr == nil {
+		status.Lag = n.getLagStatusLocked(rs.LastBlock.Header.Round, rs.LastBlockHeight, time.Now())
+		if status.Lag != nil {
+			updateLagMetrics(n.getMetricLabels(), status.Lag)
+		}
+	}
+	status.Status = n.getStatusStateLocked(status.Lag)
+
 	if n.CurrentDescriptor != nil {
 		activeDeploy := n.CurrentDescriptor.ActiveDeployment(n.CurrentEpoch)
 		if activeDeploy != nil {
@@ -302,11 +396,7 @@ func (n *Node) GetStatus() (*api.Status, error) {
 
 		// Include liveness statistics if the node is an executor committee member.
 		if epoch.IsExecutorMember() {
-			rs, err := n.Consensus.RootHash().GetRuntimeState(n.ctx, &roothash.RuntimeRequest{
-				RuntimeID: n.Runtime.ID(),
-				Height:    consensus.HeightLatest,
-			})
-			if err == nil && rs.LivenessStatistics != nil {
+			if rsErr == nil && rs.LivenessStatistics != nil {
 				status.Liveness = &api.LivenessStatus{
 					TotalRounds: rs.LivenessStatistics.TotalRounds,
 				}
@@ -321,6 +411,7 @@ func (n *Node) GetStatus() (*api.Status, error) {
 	}
 
 	status.Peers = n.P2P.Peers(n.Runtime.ID())
+	status.CommitteePeers = n.committeePeers.status()
 
 	status.Host.Versions = n.RuntimeRegistry.GetBundleRegistry().GetVersions(n.Runtime.ID())
 
@@ -357,6 +448,10 @@ func (n *Node) handleEpochTransitionLocked(height int64) {
 		}
 	}
 
+	// Dial committee members right away so that connections are established before the first
+	// round of the epoch.
+	n.committeePeers.update(epoch.committeeMembers())
+
 	epochNumber.With(n.getMetricLabels()).Set(float64(epoch.epochNumber))
 }
 
@@ -366,6 +461,7 @@ func (n *Node) handleSuspendLocked(int64) {
 
 	// Suspend group.
 	n.Group.Suspend()
+	n.committeePeers.update(nil)
 
 	// If the runtime has been suspended, we need to switch to checking the latest registry
 	// descriptor instead of the active one as otherwise we may miss deployment updates and never
@@ -403,6 +499,7 @@ func (n *Node) handleSuspendLocked(int64) {
 				}
 
 				n.CurrentDescriptor = rt
+				n.TxValidators.SetMaxTxSize(rt.TxnScheduler.MaxBatchSizeBytes)
 
 				n.updateHostedRuntimeVersionLocked()
 				n.CrossNode.Unlock()
@@ -433,19 +530,16 @@ func (n *Node) updateHostedRuntimeVersionLocked() {
 		*activeVersion = activeDeploy.Version
 	}
 
-	// For compute nodes, determine if there is a next version and activate it early.
-	var nextVersion *version.Version
-	if config.GlobalConfig.Mode == config.ModeCompute {
-		nextDeploy := n.CurrentDescriptor.NextDeployment(epoch)
-		preWarmEpochs := beacon.EpochTime(config.GlobalConfig.Runtime.PreWarmEpochs)
-
-		if nextDeploy != nil && nextDeploy.ValidFrom-epoch <= preWarmEpochs {
-			nextVersion = new(version.Version)
-			*nextVersion = nextDeploy.Version
-		}
-	}
+	// Determine if there is a next version and activate it early as a warm standby.
+	nextVersion := standbyVersion(
+		n.CurrentDescriptor,
+		config.GlobalConfig.Mode,
+		epoch,
+		beacon.EpochTime(config.GlobalConfig.Runtime.PreWarmEpochs),
+	)
 
 	n.SetHostedRuntimeVersion(activeVersion, nextVersion)
+	n.enableTxValidatorsLocked(activeVersion)
 
 	if _, err := n.GetHostedRuntimeActiveVersion(); err != nil {
 		n.logger.Warn("failed to activate runtime version(s)",
@@ -457,6 +551,46 @@ func (n *Node) updateHostedRuntimeVersionLocked() {
 	}
 }
 
+// provisionHostedRuntimeComponent provisions the given runtime component and records the
+// stateless transaction validators requested by its bundle metadata.
+func (n *Node) provisionHostedRuntimeComponent(comp *bundle.ExplodedComponent) error {
+	if err := n.ProvisionHostedRuntimeComponent(comp); err != nil {
+		return err
+	}
+	if !comp.ID().IsRONL() {
+		return nil
+	}
+
+	n.CrossNode.Lock()
+	defer n.CrossNode.Unlock()
+
+	if n.bundleTxValidators == nil {
+		n.bundleTxValidators = make(map[version.Version][]string)
+	}
+	n.bundleTxValidators[comp.Version] = comp.TxValidators
+	return nil
+}
+
+// enableTxValidatorsLocked enables the stateless transaction validators requested by the node
+// configuration and by the bundle metadata of the given runtime version.
+func (n *Node) enableTxValidatorsLocked(activeVersion *version.Version) {
+	names := append([]string{}, n.txValidators...)
+	if activeVersion != nil {
+		names = append(names, n.bundleTxValidators[*activeVersion]...)
+	}
+
+	if err := n.TxValidators.Enable(names); err != nil {
+		n.logger.Error("failed to enable stateless transaction validators",
+			"err", err,
+			"validators", names,
+		)
+		return
+	}
+	n.logger.Debug("enabled stateless transaction validators",
+		"validators", n.TxValidators.Enabled(),
+	)
+}
+
 // Guarded by n.CrossNode.
 func (n *Node) handleNewBlockLocked(blk *block.Block, height int64) {
 	processedBlockCount.With(n.getMetricLabels()).Inc()
@@ -499,6 +633,7 @@ func (n *Node) handleNewBlockLocked(blk *block.Block, height int64) {
 			return
 		}
 		n.CurrentDescriptor = rs.Runtime
+		n.TxValidators.SetMaxTxSize(n.CurrentDescriptor.TxnScheduler.MaxBatchSizeBytes)
 
 		n.CurrentEpoch, err = n.Consensus.Beacon().GetEpoch(n.ctx, height)
 		if err != nil {
@@ -560,22 +695,25 @@ func (n *Node) handleNewBlockLocked(blk *block.Block, height int64) {
 		return
 	}
 
-	n.TxPool.ProcessBlock(bi)
+	// Mirrored runtimes do not process transactions.
+	if !n.Mirror {
+		n.TxPool.ProcessBlock(bi)
 
-	// Fetch incoming messages.
-	inMsgs, err := n.Consensus.RootHash().GetIncomingMessageQueue(n.ctx, &roothash.InMessageQueueRequest{
-		RuntimeID: n.Runtime.ID(),
-		Height:    consensusBlk.Height,
-	})
-	if err != nil {
-		n.logger.Error("failed to query incoming messages",
-			"err", err,
-			"height", height,
-			"round", bi.RuntimeBlock.Header.Round,
-		)
-		return
+		// Fetch incoming messages.
+		inMsgs, err := n.Consensus.RootHash().GetIncomingMessageQueue(n.ctx, &roothash.InMessageQueueRequest{
+			RuntimeID: n.Runtime.ID(),
+			Height:    consensusBlk.Height,
+		})
+		if err != nil {
+			n.logger.Error("failed to query incoming messages",
+				"err", err,
+				"height", height,
+				"round", bi.RuntimeBlock.Header.Round,
+			)
+			return
+		}
+		n.TxPool.ProcessIncomingMessages(inMsgs)
 	}
-	n.TxPool.ProcessIncomingMessages(inMsgs)
 
 	for _, hooks := range n.hooks {
 		hooks.HandleNewBlockLocked(bi)
@@ -589,6 +727,13 @@ func (n *Node) handleRuntimeHostEventLocked(ev *host.Event) {
 	switch {
 	case ev.Started != nil:
 		atomic.StoreUint32(&n.hostedRuntimeProvisioned, 1)
+
+		if n.runtimeRestarting {
+			// Re-establish the hosted runtime version after a restart.
+			n.logger.Info("hosted runtime restarted")
+			n.runtimeRestarting = false
+			n.updateHostedRuntimeVersionLocked()
+		}
 	case ev.FailedToStart != nil, ev.Stopped != nil:
 		atomic.StoreUint32(&n.hostedRuntimeProvisioned, 0)
 	}
@@ -614,12 +759,15 @@ func (n *Node) worker() {
 	n.logger.Info("consensus has finished initial synchronization")
 	atomic.StoreUint32(&n.consensusSynced, 1)
 
-	// Start the transaction pool after consensus is synced.
-	if err := n.TxPool.Start(); err != nil {
-		n.logger.Error("failed to start transaction pool",
-			"err", err,
-		)
-		return
+	// Start the transaction pool after consensus is synced. Mirrored runtimes do not process
+	// transactions.
+	if !n.Mirror {
+		if err := n.TxPool.Start(); err != nil {
+			n.logger.Error("failed to start transaction pool",
+				"err", err,
+			)
+			return
+		}
 	}
 
 	// Wait for the runtime.
@@ -645,6 +793,7 @@ func (n *Node) worker() {
 	// Initialize the CurrentDescriptor to make sure there is one even if the runtime gets
 	// suspended.
 	n.CurrentDescriptor = rt
+	n.TxValidators.SetMaxTxSize(rt.TxnScheduler.MaxBatchSizeBytes)
 
 	// If the runtime requires a key manager, wait for the key manager to actually become available
 	// before processing any requests.
@@ -711,7 +860,7 @@ func (n *Node) worker() {
 
 	// Provision all known components.
 	for _, comp := range bundleRegistry.Components(n.Runtime.ID()) {
-		if err := n.ProvisionHostedRuntimeComponent(comp); err != nil {
+		if err := n.provisionHostedRuntimeComponent(comp); err != nil {
 			n.logger.Error("failed to provision runtime component",
 				"err", err,
 				"id", comp.ID(),
@@ -734,6 +883,8 @@ func (n *Node) worker() {
 
 	hrt.Start()
 	defer hrt.Stop()
+	defer n.waitRestart()
+	atomic.StoreUint32(&n.hostedRuntimeStarted, 1)
 
 	// Start the runtime's notifier.
 	n.notifier.Start()
@@ -752,6 +903,9 @@ func (n *Node) worker() {
 				defer n.CrossNode.Unlock()
 				n.handleNewBlockLocked(blk.Block, blk.Height)
 			}()
+		case req := <-n.restartCh:
+			// Received a runtime restart request.
+			n.startRestart(hrt, req)
 		case ev := <-hrtEventCh:
 			// Received a hosted runtime event.
 			func() {
@@ -763,7 +917,7 @@ func (n *Node) worker() {
 			switch {
 			case compNotify.Added != nil:
 				// Received a new version of a runtime component.
-				if err := n.ProvisionHostedRuntimeComponent(compNotify.Added); err != nil {
+				if err := n.provisionHostedRuntimeComponent(compNotify.Added); err != nil {
 					n.logger.Error("failed to provision hosted runtime",
 						"err", err,
 						"id", compNotify.Added.ID(),
@@ -806,6 +960,16 @@ func (n *Node) updatePeriodicMetrics() {
 
 	n.logger.Debug("updating periodic worker node metrics")
 
+	rs, err := n.Consensus.RootHash().GetRuntimeState(n.ctx, &roothash.RuntimeRequest{
+		RuntimeID: n.Runtime.ID(),
+		Height:    consensus.HeightLatest,
+	})
+	if err == nil {
+		if lag := n.getLagStatusLocked(rs.LastBlock.Header.Round, rs.LastBlockHeight, time.Now()); lag != nil {
+			updateLagMetrics(labels, lag)
+		}
+	}
+
 	epoch := n.Group.GetEpochSnapshot()
 	cmte := epoch.GetExecutorCommittee()
 	if cmte == nil {
@@ -822,10 +986,6 @@ func (n *Node) updatePeriodicMetrics() {
 		return
 	}
 
-	rs, err := n.Consensus.RootHash().GetRuntimeState(n.ctx, &roothash.RuntimeRequest{
-		RuntimeID: n.Runtime.ID(),
-		Height:    consensus.HeightLatest,
-	})
 	if err != nil || rs.LivenessStatistics == nil {
 		return
 	}
@@ -876,6 +1036,8 @@ func NewNode(
 	lightProvider consensus.LightProvider,
 	p2pHost p2pAPI.Service,
 	txPoolCfg tpConfig.Config,
+	txValidators []string,
+	mirror bool,
 ) (*Node, error) {
 	metricsOnce.Do(func() {
 		prometheus.MustRegister(nodeCollectors...)
@@ -897,6 +1059,7 @@ func NewNode(
 		HostNode:        hostNode,
 		Runtime:         runtime,
 		RuntimeRegistry: rtRegistry,
+		Mirror:          mirror,
 		Identity:        identity,
 		KeyManager:      keymanager,
 		Consensus:       consensus,
@@ -909,9 +1072,22 @@ func NewNode(
 		stopCh:          make(chan struct{}),
 		quitCh:          make(chan struct{}),
 		initCh:          make(chan struct{}),
+		restartCh:       make(chan *restartRequest),
+		txValidators:    txValidators,
 		logger:          logging.GetLogger("worker/common/committee").With("runtime_id", runtime.ID()),
 	}
 
+	// Prepare the committee peer connection manager.
+	if h := p2pHost.Host(); h != nil {
+		n.committeePeers = newCommitteePeers(
+			ctx,
+			&hostDialer{h},
+			p2pAPI.ImportantNodeCompute.Tag(runtime.ID()),
+			identity.P2PSigner.Public(),
+			n.logger,
+		)
+	}
+
 	// Prepare the key manager client wrapper.
 	n.KeyManagerClient = NewKeyManagerClientWrapper(p2pHost, consensus, chainContext, n.logger)
 
@@ -930,6 +1106,12 @@ func NewNode(
 
 	// Prepare transaction pool.
 	n.TxPool = txpool.New(runtime.ID(), txPoolCfg, rhn.GetHostedRuntime(), runtime.History(), n)
+	n.TxValidators = txpool.NewStatelessValidators(runtime.ID())
+
+	// Mirrored runtimes never take part in transaction gossip.
+	if mirror {
+		return n, nil
+	}
 
 	// Register transaction message handler as that is something that all workers must handle.
 	p2pHost.RegisterHandler(txTopic, &txMsgHandler{n})
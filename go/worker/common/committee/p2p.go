@@ -41,6 +41,12 @@ func (h *txMsgHandler) HandleMessage(ctx context.Context, _ signature.PublicKey,
 	case config.ModeStatelessClient:
 		// Ignore transactions on stateless clients.
 	default:
+		// Reject malformed transactions before they are queued for checking by the runtime. As
+		// the error is permanent, the message is not relayed and the peer is penalized.
+		if err := h.n.TxValidators.ValidateTx(tx); err != nil {
+			return p2pError.Permanent(err)
+		}
+
 		// Queue in local transaction pool if we are not running a stateless client.
 		result, err := h.n.TxPool.SubmitTx(ctx, tx, &txpool.TransactionMeta{Local: false})
 		switch {
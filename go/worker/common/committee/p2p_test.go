@@ -0,0 +1,114 @@
+package committee
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	p2pError "github.com/oasisprotocol/oasis-core/go/p2p/error"
+	"github.com/oasisprotocol/oasis-core/go/runtime/host/protocol"
+	"github.com/oasisprotocol/oasis-core/go/runtime/txpool"
+)
+
+// checkTxCountingPool is a transaction pool that counts the transactions queued for checking by
+// the runtime.
+type checkTxCountingPool struct {
+	txpool.TransactionPool
+
+	checkTxCount int
+}
+
+func (p *checkTxCountingPool) SubmitTx(context.Context, []byte, *txpool.TransactionMeta) (*protocol.CheckTxResult, error) {
+	p.checkTxCount++
+	return &protocol.CheckTxResult{}, nil
+}
+
+// testEnvelope is a standard runtime transaction envelope.
+type testEnvelope struct {
+	_ struct{} `cbor:",toarray"`
+
+	Body       []byte
+	AuthProofs []testAuthProof
+}
+
+type testAuthProof struct {
+	Signature []byte `json:"signature,omitempty"`
+}
+
+type testEnvelopeBody struct {
+	Version uint16 `json:"v"`
+}
+
+func newTestEnvelope(version uint16, sig []byte) []byte {
+	return cbor.Marshal(&testEnvelope{
+		Body:       cbor.Marshal(&testEnvelopeBody{Version: version}),
+		AuthProofs: []testAuthProof{{Signature: sig}},
+	})
+}
+
+func TestTxMsgHandlerStatelessValidation(t *testing.T) {
+	require := require.New(t)
+
+	runtimeID := common.NewTestNamespaceFromSeed([]byte("worker common committee tx gossip"), 0)
+	pool := &checkTxCountingPool{}
+	n := &Node{
+		TxPool:       pool,
+		TxValidators: txpool.NewStatelessValidators(runtimeID),
+		txValidators: []string{txpool.StatelessValidatorEnvelope},
+		logger:       logging.GetLogger("worker/common/committee/test"),
+	}
+
+	// The node configuration enables the envelope validator and the bundle of the active runtime
+	// version enables the signature validator.
+	activeVersion := version.Version{Major: 1}
+	n.bundleTxValidators = map[version.Version][]string{
+		activeVersion: {txpool.StatelessValidatorSignature},
+	}
+	n.enableTxValidatorsLocked(&activeVersion)
+	require.Equal([]string{
+		txpool.StatelessValidatorSize,
+		txpool.StatelessValidatorEnvelope,
+		txpool.StatelessValidatorSignature,
+	}, n.TxValidators.Enabled())
+	n.TxValidators.SetMaxTxSize(4096)
+
+	h := &txMsgHandler{n}
+	gossip := func(tx []byte) error {
+		msg, err := h.DecodeMessage(cbor.Marshal(tx))
+		require.NoError(err, "DecodeMessage")
+		return h.HandleMessage(context.Background(), signature.PublicKey{}, msg, false)
+	}
+
+	// Malformed transactions should be rejected without being checked by the runtime.
+	for _, tx := range [][]byte{
+		nil,
+		make([]byte, 4097),
+		[]byte("not an envelope"),
+		newTestEnvelope(txpool.EnvelopeVersion+1, make([]byte, 64)),
+		newTestEnvelope(txpool.EnvelopeVersion, make([]byte, 1024)),
+	} {
+		err := gossip(tx)
+		require.ErrorIs(err, txpool.ErrMalformedTransaction, "malformed transactions should be rejected")
+		require.True(p2pError.IsPermanent(err), "rejections should penalize the peer")
+	}
+	require.Zero(pool.checkTxCount, "malformed transactions should not be checked by the runtime")
+
+	// Well-formed transactions should be checked by the runtime.
+	require.NoError(gossip(newTestEnvelope(txpool.EnvelopeVersion, make([]byte, 64))))
+	require.Equal(1, pool.checkTxCount, "well-formed transactions should be checked by the runtime")
+
+	// Validators requested by other runtime versions should not be enabled.
+	n.enableTxValidatorsLocked(&version.Version{Major: 2})
+	require.Equal([]string{
+		txpool.StatelessValidatorSize,
+		txpool.StatelessValidatorEnvelope,
+	}, n.TxValidators.Enabled())
+	require.NoError(gossip(newTestEnvelope(txpool.EnvelopeVersion, make([]byte, 1024))))
+	require.Equal(2, pool.checkTxCount)
+}
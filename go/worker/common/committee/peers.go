@@ -0,0 +1,315 @@
+package committee
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/libp2p/go-libp2p/core"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+
+	cmnBackoff "github.com/oasisprotocol/oasis-core/go/common/backoff"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	p2pAPI "github.com/oasisprotocol/oasis-core/go/p2p/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/api"
+)
+
+const (
+	// committeePeerCheckInterval is the interval at which committee peer connections are checked.
+	committeePeerCheckInterval = 5 * time.Second
+
+	// committeePeerGracePeriod is the time connections to members of an expired committee are
+	// kept before they are dropped.
+	committeePeerGracePeriod = 2 * time.Minute
+
+	// committeePeerBackOffInitialInterval is the initial interval between failed dial attempts.
+	committeePeerBackOffInitialInterval = time.Second
+
+	// committeePeerBackOffMaxInterval is the maximum interval between failed dial attempts.
+	committeePeerBackOffMaxInterval = time.Minute
+)
+
+// peerDialer manages P2P connections to peers.
+type peerDialer interface {
+	// IsConnected returns true iff the peer is connected.
+	IsConnected(id core.PeerID) bool
+
+	// Connect establishes a connection to the peer.
+	Connect(ctx context.Context, info peer.AddrInfo) error
+
+	// Protect protects the peer connection from being pruned.
+	Protect(id core.PeerID, tag string)
+
+	// Unprotect removes the protection of the peer connection.
+	Unprotect(id core.PeerID, tag string)
+
+	// ClosePeer closes all connections to the peer.
+	ClosePeer(id core.PeerID) error
+}
+
+type hostDialer struct {
+	host core.Host
+}
+
+func (d *hostDialer) IsConnected(id core.PeerID) bool {
+	return d.host.Network().Connectedness(id) == network.Connected
+}
+
+func (d *hostDialer) Connect(ctx context.Context, info peer.AddrInfo) error {
+	return d.host.Connect(ctx, info)
+}
+
+func (d *hostDialer) Protect(id core.PeerID, tag string) {
+	d.host.ConnManager().Protect(id, tag)
+}
+
+func (d *hostDialer) Unprotect(id core.PeerID, tag string) {
+	d.host.ConnManager().Unprotect(id, tag)
+}
+
+func (d *hostDialer) ClosePeer(id core.PeerID) error {
+	return d.host.Network().ClosePeer(id)
+}
+
+type committeePeer struct {
+	nodeID signature.PublicKey
+	info   peer.AddrInfo
+
+	state    api.CommitteePeerState
+	since    time.Time
+	dialing  bool
+	attempts uint64
+	lastErr  error
+
+	bo      *backoff.ExponentialBackOff
+	nextTry time.Time
+
+	// expiresAt is the time the peer is dropped at, zero for members of the current committee.
+	expiresAt time.Time
+}
+
+func (p *committeePeer) setState(state api.CommitteePeerState, now time.Time) {
+	if p.state == state {
+		return
+	}
+	p.state = state
+	p.since = now
+}
+
+// committeePeers proactively establishes and maintains connections to members of the committees
+// the node is part of, so that the first round after an epoch transition does not need to wait
+// for peers to be dialed.
+type committeePeers struct {
+	logger *logging.Logger
+
+	ctx    context.Context
+	dialer peerDialer
+	tag    string
+	selfID signature.PublicKey
+	nowFn  func() time.Time
+
+	mu    sync.Mutex
+	peers map[core.PeerID]*committeePeer
+}
+
+func newCommitteePeers(
+	ctx context.Context,
+	dialer peerDialer,
+	tag string,
+	selfID signature.PublicKey,
+	logger *logging.Logger,
+) *committeePeers {
+	return &committeePeers{
+		logger: logger,
+		ctx:    ctx,
+		dialer: dialer,
+		tag:    tag,
+		selfID: selfID,
+		nowFn:  time.Now,
+		peers:  make(map[core.PeerID]*committeePeer),
+	}
+}
+
+// update sets the members of the current committees and immediately dials any members that are
+// not yet connected. Peers that are no longer members are dropped after the grace period.
+func (cp *committeePeers) update(members []*node.Node) {
+	if cp == nil {
+		return
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	now := cp.nowFn()
+	for _, p := range cp.peers {
+		if p.expiresAt.IsZero() {
+			p.expiresAt = now.Add(committeePeerGracePeriod)
+		}
+	}
+
+	for _, n := range members {
+		if n == nil || n.P2P.ID.Equal(cp.selfID) {
+			continue
+		}
+
+		pid, err := p2pAPI.PublicKeyToPeerID(n.P2P.ID)
+		if err != nil {
+			cp.logger.Warn("invalid committee peer identifier",
+				"err", err,
+				"node_id", n.ID,
+			)
+			continue
+		}
+		var addrs []multiaddr.Multiaddr
+		for _, addr := range n.P2P.Addresses {
+			ma, err := addr.MultiAddress()
+			if err != nil {
+				continue
+			}
+			addrs = append(addrs, ma)
+		}
+
+		p, ok := cp.peers[pid]
+		if !ok {
+			bo := cmnBackoff.NewExponentialBackOff()
+			bo.InitialInterval = committeePeerBackOffInitialInterval
+			bo.MaxInterval = committeePeerBackOffMaxInterval
+			bo.Reset()
+
+			p = &committeePeer{
+				bo: bo,
+			}
+			p.setState(api.CommitteePeerStateDisconnected, now)
+			cp.peers[pid] = p
+			cp.dialer.Protect(pid, cp.tag)
+		}
+		p.nodeID = n.ID
+		p.info = peer.AddrInfo{ID: pid, Addrs: addrs}
+		p.expiresAt = time.Time{}
+	}
+
+	cp.refreshLocked(now)
+}
+
+// refreshLocked updates the connection state of all peers, dials disconnected peers and drops
+// expired ones.
+func (cp *committeePeers) refreshLocked(now time.Time) {
+	for pid, p := range cp.peers {
+		if !p.expiresAt.IsZero() && !now.Before(p.expiresAt) {
+			delete(cp.peers, pid)
+			cp.dialer.Unprotect(pid, cp.tag)
+			if err := cp.dialer.ClosePeer(pid); err != nil {
+				cp.logger.Debug("failed to close connection to expired committee peer",
+					"err", err,
+					"peer_id", pid,
+				)
+			}
+			continue
+		}
+		if p.dialing {
+			continue
+		}
+
+		connected := cp.dialer.IsConnected(pid)
+		switch {
+		case !p.expiresAt.IsZero():
+			p.setState(api.CommitteePeerStateExpiring, now)
+		case connected:
+			p.setState(api.CommitteePeerStateConnected, now)
+		case now.Before(p.nextTry):
+			p.setState(api.CommitteePeerStateDisconnected, now)
+		default:
+			p.setState(api.CommitteePeerStateConnecting, now)
+			p.dialing = true
+			p.attempts++
+			go cp.dial(pid, p.info)
+		}
+	}
+}
+
+func (cp *committeePeers) dial(pid core.PeerID, info peer.AddrInfo) {
+	err := cp.dialer.Connect(cp.ctx, info)
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	p, ok := cp.peers[pid]
+	if !ok {
+		return
+	}
+	now := cp.nowFn()
+	p.dialing = false
+	p.lastErr = err
+
+	if err != nil {
+		cp.logger.Debug("failed to connect to committee peer",
+			"err", err,
+			"peer_id", pid,
+		)
+		p.nextTry = now.Add(p.bo.NextBackOff())
+		p.setState(api.CommitteePeerStateDisconnected, now)
+		return
+	}
+	p.bo.Reset()
+	p.nextTry = time.Time{}
+	if p.expiresAt.IsZero() {
+		p.setState(api.CommitteePeerStateConnected, now)
+	}
+}
+
+// run periodically updates the connection state of all peers until the context is canceled.
+func (cp *committeePeers) run() {
+	if cp == nil {
+		return
+	}
+
+	ticker := time.NewTicker(committeePeerCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cp.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cp.mu.Lock()
+		cp.refreshLocked(cp.nowFn())
+		cp.mu.Unlock()
+	}
+}
+
+// status returns the connection status of all tracked peers.
+func (cp *committeePeers) status() []api.CommitteePeerStatus {
+	if cp == nil {
+		return nil
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	statuses := make([]api.CommitteePeerStatus, 0, len(cp.peers))
+	for pid, p := range cp.peers {
+		st := api.CommitteePeerStatus{
+			NodeID:       p.nodeID,
+			PeerID:       pid.String(),
+			State:        p.state,
+			Since:        p.since,
+			DialAttempts: p.attempts,
+		}
+		if p.lastErr != nil {
+			st.LastError = p.lastErr.Error()
+		}
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].PeerID < statuses[j].PeerID
+	})
+	return statuses
+}
@@ -0,0 +1,207 @@
+package committee
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	memorySigner "github.com/oasisprotocol/oasis-core/go/common/crypto/signature/signers/memory"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	p2pAPI "github.com/oasisprotocol/oasis-core/go/p2p/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/api"
+)
+
+type testDialer struct {
+	sync.Mutex
+
+	connected map[core.PeerID]bool
+	failing   map[core.PeerID]bool
+	protected map[core.PeerID]bool
+	closed    map[core.PeerID]bool
+
+	dialCh chan core.PeerID
+}
+
+func newTestDialer() *testDialer {
+	return &testDialer{
+		connected: make(map[core.PeerID]bool),
+		failing:   make(map[core.PeerID]bool),
+		protected: make(map[core.PeerID]bool),
+		closed:    make(map[core.PeerID]bool),
+		dialCh:    make(chan core.PeerID, 16),
+	}
+}
+
+func (d *testDialer) IsConnected(id core.PeerID) bool {
+	d.Lock()
+	defer d.Unlock()
+	return d.connected[id]
+}
+
+func (d *testDialer) Connect(_ context.Context, info peer.AddrInfo) error {
+	d.Lock()
+	defer d.Unlock()
+
+	d.dialCh <- info.ID
+	if d.failing[info.ID] {
+		return fmt.Errorf("dial failed")
+	}
+	d.connected[info.ID] = true
+	return nil
+}
+
+func (d *testDialer) Protect(id core.PeerID, _ string) {
+	d.Lock()
+	defer d.Unlock()
+	d.protected[id] = true
+}
+
+func (d *testDialer) Unprotect(id core.PeerID, _ string) {
+	d.Lock()
+	defer d.Unlock()
+	delete(d.protected, id)
+}
+
+func (d *testDialer) ClosePeer(id core.PeerID) error {
+	d.Lock()
+	defer d.Unlock()
+	d.closed[id] = true
+	delete(d.connected, id)
+	return nil
+}
+
+func newTestCommitteeNode(t *testing.T, port int) (*node.Node, core.PeerID) {
+	nodeSigner := memorySigner.NewTestSigner(fmt.Sprintf("committee peers test: node %d", port))
+	p2pSigner := memorySigner.NewTestSigner(fmt.Sprintf("committee peers test: p2p %d", port))
+
+	n := &node.Node{ID: nodeSigner.Public()}
+	n.P2P.ID = p2pSigner.Public()
+	n.P2P.Addresses = []node.Address{
+		{TCPAddr: net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}},
+	}
+
+	pid, err := p2pAPI.PublicKeyToPeerID(n.P2P.ID)
+	require.NoError(t, err, "PublicKeyToPeerID")
+	return n, pid
+}
+
+func TestCommitteePeers(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	self, _ := newTestCommitteeNode(t, 1000)
+	n1, pid1 := newTestCommitteeNode(t, 1001)
+	n2, pid2 := newTestCommitteeNode(t, 1002)
+	n3, pid3 := newTestCommitteeNode(t, 1003)
+
+	dialer := newTestDialer()
+	dialer.failing[pid2] = true
+
+	now := time.Now()
+	cp := newCommitteePeers(ctx, dialer, "test", self.P2P.ID, logging.GetLogger("committee/peers/test"))
+	cp.nowFn = func() time.Time {
+		return now
+	}
+
+	statusOf := func(pid core.PeerID) *api.CommitteePeerStatus {
+		for _, st := range cp.status() {
+			if st.PeerID == pid.String() {
+				return &st
+			}
+		}
+		return nil
+	}
+	waitDials := func(pids ...core.PeerID) {
+		dialed := make(map[core.PeerID]bool)
+		for range pids {
+			select {
+			case pid := <-dialer.dialCh:
+				dialed[pid] = true
+			case <-time.After(time.Second):
+				require.FailNow("peer not dialed")
+			}
+		}
+		for _, pid := range pids {
+			require.True(dialed[pid], "peer %s should be dialed", pid)
+		}
+		require.Eventually(func() bool {
+			for _, pid := range pids {
+				if statusOf(pid).State == api.CommitteePeerStateConnecting {
+					return false
+				}
+			}
+			return true
+		}, time.Second, 10*time.Millisecond)
+	}
+	refresh := func() {
+		cp.mu.Lock()
+		defer cp.mu.Unlock()
+		cp.refreshLocked(now)
+	}
+
+	// Epoch transition: members should be dialed before any round message is sent.
+	cp.update([]*node.Node{self, n1, n2})
+	require.Len(cp.status(), 2, "own node should not be tracked")
+	for _, pid := range []core.PeerID{pid1, pid2} {
+		st := statusOf(pid)
+		require.NotNil(st)
+		require.EqualValues(1, st.DialAttempts, "dial should start on epoch transition")
+		require.True(dialer.protected[pid], "peer connection should be protected")
+	}
+	waitDials(pid1, pid2)
+
+	require.Equal(api.CommitteePeerStateConnected, statusOf(pid1).State)
+	st := statusOf(pid2)
+	require.Equal(api.CommitteePeerStateDisconnected, st.State)
+	require.Equal("dial failed", st.LastError)
+
+	// Failed peers should not be re-dialed before the back-off expires.
+	refresh()
+	require.EqualValues(1, statusOf(pid2).DialAttempts)
+	now = now.Add(committeePeerBackOffMaxInterval)
+	dialer.Lock()
+	dialer.failing[pid2] = false
+	dialer.Unlock()
+	refresh()
+	waitDials(pid2)
+	st = statusOf(pid2)
+	require.EqualValues(2, st.DialAttempts)
+	require.Equal(api.CommitteePeerStateConnected, st.State)
+	require.Empty(st.LastError)
+
+	// Next epoch: members of the old committee expire after the grace period.
+	cp.update([]*node.Node{n1, n3})
+	waitDials(pid3)
+	require.Equal(api.CommitteePeerStateConnected, statusOf(pid1).State)
+	require.Equal(api.CommitteePeerStateConnected, statusOf(pid3).State)
+	require.Equal(api.CommitteePeerStateExpiring, statusOf(pid2).State)
+
+	now = now.Add(committeePeerGracePeriod / 2)
+	refresh()
+	require.NotNil(statusOf(pid2), "expired peer should be kept during the grace period")
+
+	now = now.Add(committeePeerGracePeriod)
+	refresh()
+	require.Nil(statusOf(pid2), "expired peer should be dropped")
+	require.False(dialer.protected[pid2])
+	require.True(dialer.closed[pid2])
+	require.False(dialer.closed[pid1])
+
+	// Members should stay connected.
+	require.Len(cp.status(), 2)
+
+	// Disabled P2P should be handled gracefully.
+	var nilPeers *committeePeers
+	require.Nil(nilPeers.status())
+	nilPeers.update([]*node.Node{n1})
+}
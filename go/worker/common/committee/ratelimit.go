@@ -0,0 +1,177 @@
+package committee
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+const (
+	// MessageKindProposal is the rate limited message kind of batch proposals.
+	MessageKindProposal = "proposal"
+	// MessageKindOther is the rate limited message kind of all other committee messages.
+	MessageKindOther = "other"
+
+	// messageRateLimitSlack is the factor by which the derived rate limits exceed the expected
+	// legitimate message rate, accounting for republishing and relaying delays.
+	messageRateLimitSlack = 4
+	// defaultMessageInterval is the expected interval between legitimate messages of a single
+	// peer in case the runtime does not configure a batch flush timeout.
+	defaultMessageInterval = time.Second
+)
+
+// MessageRateLimit is a limit on the rate of inbound committee messages of a single kind from a
+// single peer.
+type MessageRateLimit struct {
+	// Rate is the number of messages per second.
+	Rate float64
+	// Burst is the maximum number of messages that are accepted at once.
+	Burst float64
+}
+
+// DeriveMessageRateLimits derives the committee message rate limits from the round parameters
+// of the given runtime.
+//
+// Each worker proposes at most one batch per round and, unless batches fill up, at most once
+// per batch flush timeout. The limits allow several times that so that legitimate peers are
+// never limited while a flooding peer is cut off quickly.
+func DeriveMessageRateLimits(rt *registry.Runtime) map[string]MessageRateLimit {
+	interval := rt.TxnScheduler.BatchFlushTimeout
+	if interval <= 0 {
+		interval = defaultMessageInterval
+	}
+	rate := messageRateLimitSlack / interval.Seconds()
+
+	return map[string]MessageRateLimit{
+		MessageKindProposal: {
+			Rate:  rate,
+			Burst: messageRateLimitSlack,
+		},
+		MessageKindOther: {
+			Rate:  rate,
+			Burst: messageRateLimitSlack,
+		},
+	}
+}
+
+type messageRateLimitKey struct {
+	peerID signature.PublicKey
+	kind   string
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) take(limit MessageRateLimit, now time.Time) bool {
+	b.tokens = min(limit.Burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// MessageRateLimiter enforces per-peer, per-kind rate limits on inbound committee messages of a
+// runtime using token buckets.
+//
+// It is meant to be consulted before any expensive message processing (e.g., signature
+// verification) takes place.
+type MessageRateLimiter struct {
+	mu sync.Mutex
+
+	epoch   beacon.EpochTime
+	limits  map[string]MessageRateLimit
+	buckets map[messageRateLimitKey]*tokenBucket
+	exempt  map[messageRateLimitKey]uint64
+
+	now       func() time.Time
+	runtimeID string
+}
+
+// NewMessageRateLimiter creates a new committee message rate limiter for the given runtime.
+//
+// Until limits are configured via Update, all messages are allowed.
+func NewMessageRateLimiter(runtimeID common.Namespace) *MessageRateLimiter {
+	return &MessageRateLimiter{
+		epoch:     beacon.EpochInvalid,
+		buckets:   make(map[messageRateLimitKey]*tokenBucket),
+		exempt:    make(map[messageRateLimitKey]uint64),
+		now:       time.Now,
+		runtimeID: runtimeID.String(),
+	}
+}
+
+// Update derives the rate limits for the given epoch from the runtime descriptor.
+//
+// As committees change between epochs, all state of the previous epoch is discarded. Updating
+// the limits for the current epoch is a no-op.
+func (l *MessageRateLimiter) Update(epoch beacon.EpochTime, rt *registry.Runtime) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if epoch == l.epoch {
+		return
+	}
+	l.epoch = epoch
+	l.limits = DeriveMessageRateLimits(rt)
+	clear(l.buckets)
+	clear(l.exempt)
+}
+
+// Allow records an inbound message of the given kind from the given peer and returns true iff
+// the message is within the rate limits.
+func (l *MessageRateLimiter) Allow(peerID signature.PublicKey, kind string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit, ok := l.limits[kind]
+	if !ok {
+		return true
+	}
+
+	key := messageRateLimitKey{peerID, kind}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens: limit.Burst,
+			last:   l.now(),
+		}
+		l.buckets[key] = bucket
+	}
+	if bucket.take(limit, l.now()) {
+		return true
+	}
+
+	committeeMessagesRateLimited.With(prometheus.Labels{
+		"runtime": l.runtimeID,
+		"kind":    kind,
+	}).Inc()
+	return false
+}
+
+// AllowExempt returns true iff this is the first message of the given kind from the given peer
+// that is exempt from the rate limits in the given round.
+//
+// The caller is responsible for only exempting messages that are required for the round to make
+// progress (e.g., the proposal of the round's primary scheduler), so that those are never
+// dropped even if the peer has exhausted its rate limits.
+func (l *MessageRateLimiter) AllowExempt(peerID signature.PublicKey, kind string, round uint64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := messageRateLimitKey{peerID, kind}
+	if last, ok := l.exempt[key]; ok && last >= round {
+		return false
+	}
+	l.exempt[key] = round
+	return true
+}
@@ -0,0 +1,73 @@
+package committee
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+func TestMessageRateLimiter(t *testing.T) {
+	require := require.New(t)
+
+	var runtimeID common.Namespace
+	rt := &registry.Runtime{
+		TxnScheduler: registry.TxnSchedulerParameters{
+			BatchFlushTimeout: time.Second,
+		},
+	}
+	flooder := signature.NewPublicKey("0000000000000000000000000000000000000000000000000000000000000001")
+	proposer := signature.NewPublicKey("0000000000000000000000000000000000000000000000000000000000000002")
+
+	now := time.Now()
+	l := NewMessageRateLimiter(runtimeID)
+	l.now = func() time.Time { return now }
+
+	// Without limits everything is allowed.
+	for range 100 {
+		require.True(l.Allow(flooder, MessageKindProposal))
+	}
+
+	l.Update(1, rt)
+	limit := DeriveMessageRateLimits(rt)[MessageKindProposal]
+
+	flood := func() int {
+		var allowed int
+		for range 1000 {
+			if l.Allow(flooder, MessageKindProposal) {
+				allowed++
+			}
+		}
+		return allowed
+	}
+
+	// Flood from a fake peer.
+	require.EqualValues(limit.Burst, flood(), "only the burst should be allowed")
+	require.True(l.Allow(flooder, MessageKindOther), "other kinds should be limited separately")
+
+	// Legitimate round messages from other peers still pass while the flood continues.
+	for round := range uint64(10) {
+		now = now.Add(rt.TxnScheduler.BatchFlushTimeout)
+		require.EqualValues(limit.Rate*rt.TxnScheduler.BatchFlushTimeout.Seconds(), flood(), "flooder should remain limited")
+		require.True(l.Allow(proposer, MessageKindProposal), "legitimate proposal for round %d should pass", round)
+	}
+
+	// Exempt messages are allowed once per round.
+	require.True(l.AllowExempt(flooder, MessageKindProposal, 20))
+	require.False(l.AllowExempt(flooder, MessageKindProposal, 20), "exemption should only apply once per round")
+	require.False(l.AllowExempt(flooder, MessageKindProposal, 19), "exemption should not apply to past rounds")
+	require.True(l.AllowExempt(flooder, MessageKindProposal, 21))
+
+	// Updating the limits for the same epoch keeps the state.
+	l.Update(1, rt)
+	require.False(l.AllowExempt(flooder, MessageKindProposal, 21))
+
+	// A new epoch resets the state.
+	l.Update(2, rt)
+	require.True(l.Allow(flooder, MessageKindProposal), "buckets should be reset on epoch transition")
+	require.True(l.AllowExempt(flooder, MessageKindProposal, 21), "exemptions should be reset on epoch transition")
+}
@@ -0,0 +1,205 @@
+package committee
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	control "github.com/oasisprotocol/oasis-core/go/control/api"
+	"github.com/oasisprotocol/oasis-core/go/runtime/host"
+)
+
+const (
+	// runtimeRestartDrainTimeout is the maximum duration to wait for in-flight host calls to
+	// complete before a graceful runtime restart proceeds anyway.
+	runtimeRestartDrainTimeout = 30 * time.Second
+
+	// runtimeRestartTimeout is the duration to wait for the runtime to be torn down and restarted.
+	runtimeRestartTimeout = 10 * time.Second
+)
+
+// hostCallTracker tracks in-flight calls into the hosted runtime.
+type hostCallTracker struct {
+	sync.Mutex
+
+	inflight  uint64
+	drainedCh chan struct{}
+}
+
+// begin marks the start of a call and returns the function marking its completion.
+func (t *hostCallTracker) begin() func() {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.inflight == 0 {
+		t.drainedCh = make(chan struct{})
+	}
+	t.inflight++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.Lock()
+			defer t.Unlock()
+
+			t.inflight--
+			if t.inflight == 0 {
+				close(t.drainedCh)
+			}
+		})
+	}
+}
+
+// count returns the number of in-flight calls.
+func (t *hostCallTracker) count() uint64 {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.inflight
+}
+
+// wait waits for all in-flight calls to complete.
+func (t *hostCallTracker) wait(ctx context.Context) error {
+	t.Lock()
+	if t.inflight == 0 {
+		t.Unlock()
+		return nil
+	}
+	drainedCh := t.drainedCh
+	t.Unlock()
+
+	select {
+	case <-drainedCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// restartRequest is a request to restart the hosted runtime.
+type restartRequest struct {
+	force bool
+	errCh chan error
+}
+
+// TrackHostCall marks the start of a call into the hosted runtime which should be allowed to
+// complete before the runtime is gracefully restarted. The returned function must be called once
+// the call completes.
+//
+// Only batch executions are tracked, as these are the calls whose interruption is costly. Other
+// host calls (e.g., queries and transaction checks) are short-lived and are simply retried by
+// their callers in case the runtime is restarted while they are in flight.
+func (n *Node) TrackHostCall() func() {
+	return n.hostCalls.begin()
+}
+
+// RestartRuntime tears down and restarts the hosted runtime.
+//
+// Unless force is set, in-flight host calls are given a chance to complete first. Once the
+// runtime has been restarted, the hosted runtime version is re-established and workers observe
+// the restart via runtime host events. Only one restart may be in progress at a time.
+func (n *Node) RestartRuntime(ctx context.Context, force bool) error {
+	req := &restartRequest{
+		force: force,
+		errCh: make(chan error, 1),
+	}
+
+	// The worker only accepts restart requests once the hosted runtime has been started.
+	if atomic.LoadUint32(&n.hostedRuntimeStarted) == 0 {
+		return control.ErrRuntimeNotStarted
+	}
+	select {
+	case n.restartCh <- req:
+	case <-n.quitCh:
+		return control.ErrRuntimeNotStarted
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-req.errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startRestart starts restarting the given hosted runtime in the background so that the worker
+// keeps processing blocks while the in-flight host calls drain.
+//
+// Must be called from the worker.
+func (n *Node) startRestart(hrt host.Runtime, req *restartRequest) {
+	if n.restartDoneCh != nil {
+		select {
+		case <-n.restartDoneCh:
+		default:
+			req.errCh <- control.ErrRuntimeRestarting
+			return
+		}
+	}
+
+	doneCh := make(chan struct{})
+	n.restartDoneCh = doneCh
+
+	go func() {
+		defer close(doneCh)
+		req.errCh <- n.restartHostedRuntime(hrt, req.force)
+	}()
+}
+
+// waitRestart aborts any runtime restart in progress and waits for it to complete.
+//
+// Must be called from the worker.
+func (n *Node) waitRestart() {
+	if n.restartDoneCh == nil {
+		return
+	}
+	n.cancelCtx()
+	<-n.restartDoneCh
+}
+
+// restartHostedRuntime restarts the given hosted runtime.
+func (n *Node) restartHostedRuntime(hrt host.Runtime, force bool) error {
+	n.logger.Info("restarting hosted runtime",
+		"force", force,
+		"inflight_calls", n.hostCalls.count(),
+	)
+
+	if !force {
+		drainCtx, cancel := context.WithTimeout(n.ctx, runtimeRestartDrainTimeout)
+		defer cancel()
+
+		if err := n.hostCalls.wait(drainCtx); err != nil {
+			if n.ctx.Err() != nil {
+				// The worker is terminating.
+				return n.ctx.Err()
+			}
+			n.logger.Warn("in-flight host calls did not complete in time, restarting anyway",
+				"err", err,
+				"inflight_calls", n.hostCalls.count(),
+			)
+		}
+	}
+
+	n.CrossNode.Lock()
+	n.runtimeRestarting = true
+	n.CrossNode.Unlock()
+
+	restartCtx, cancel := context.WithTimeout(n.ctx, runtimeRestartTimeout)
+	defer cancel()
+
+	// Forced abort kills the runtime and starts it again.
+	if err := hrt.Abort(restartCtx, true); err != nil {
+		n.logger.Error("failed to restart hosted runtime",
+			"err", err,
+		)
+
+		n.CrossNode.Lock()
+		n.runtimeRestarting = false
+		n.CrossNode.Unlock()
+
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package committee
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	control "github.com/oasisprotocol/oasis-core/go/control/api"
+	"github.com/oasisprotocol/oasis-core/go/runtime/host"
+)
+
+func TestHostCallTracker(t *testing.T) {
+	require := require.New(t)
+
+	var tracker hostCallTracker
+
+	// Nothing in flight.
+	require.NoError(tracker.wait(context.Background()), "wait should return immediately")
+
+	done1 := tracker.begin()
+	done2 := tracker.begin()
+	require.EqualValues(2, tracker.count())
+
+	// Waiting should time out while calls are in flight.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(tracker.wait(ctx), context.DeadlineExceeded)
+
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- tracker.wait(context.Background())
+	}()
+
+	done1()
+	done1() // Completion should be idempotent.
+	require.EqualValues(1, tracker.count())
+	select {
+	case <-waitCh:
+		require.FailNow("wait should block until all calls complete")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	done2()
+	select {
+	case err := <-waitCh:
+		require.NoError(err, "wait should return once all calls complete")
+	case <-time.After(time.Second):
+		require.FailNow("wait should return once all calls complete")
+	}
+	require.EqualValues(0, tracker.count())
+
+	// The tracker should be reusable.
+	done3 := tracker.begin()
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(tracker.wait(ctx), context.DeadlineExceeded)
+	done3()
+	require.NoError(tracker.wait(context.Background()))
+}
+
+// testHostRuntime is a hosted runtime that records abort requests.
+type testHostRuntime struct {
+	host.Runtime
+
+	abortCh  chan bool
+	abortErr error
+}
+
+func (r *testHostRuntime) Abort(_ context.Context, force bool) error {
+	r.abortCh <- force
+	return r.abortErr
+}
+
+func TestRestartHostedRuntime(t *testing.T) {
+	require := require.New(t)
+
+	newNode := func() *Node {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		return &Node{
+			ctx:       ctx,
+			cancelCtx: cancel,
+			logger:    logging.GetLogger("worker/common/committee/test"),
+		}
+	}
+	newRequest := func(force bool) *restartRequest {
+		return &restartRequest{
+			force: force,
+			errCh: make(chan error, 1),
+		}
+	}
+	requireAbort := func(hrt *testHostRuntime, force bool) {
+		select {
+		case f := <-hrt.abortCh:
+			require.Equal(force, f, "runtime should be aborted with the right force flag")
+		case <-time.After(time.Second):
+			require.FailNow("runtime should be aborted")
+		}
+	}
+	requireResult := func(req *restartRequest) error {
+		select {
+		case err := <-req.errCh:
+			return err
+		case <-time.After(time.Second):
+			require.FailNow("restart should complete")
+			return nil
+		}
+	}
+
+	n := newNode()
+	hrt := &testHostRuntime{abortCh: make(chan bool, 1)}
+
+	// A graceful restart should wait for in-flight calls without blocking the worker.
+	done := n.TrackHostCall()
+	req := newRequest(false)
+	n.startRestart(hrt, req)
+	select {
+	case <-hrt.abortCh:
+		require.FailNow("runtime should not be aborted while calls are in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Concurrent restarts should be refused.
+	concurrent := newRequest(true)
+	n.startRestart(hrt, concurrent)
+	require.ErrorIs(requireResult(concurrent), control.ErrRuntimeRestarting)
+
+	done()
+	requireAbort(hrt, true)
+	require.NoError(requireResult(req), "graceful restart should succeed")
+	n.CrossNode.Lock()
+	require.True(n.runtimeRestarting, "runtime should be marked as restarting")
+	n.runtimeRestarting = false
+	n.CrossNode.Unlock()
+
+	// A forced restart should not wait for in-flight calls.
+	done = n.TrackHostCall()
+	defer done()
+	req = newRequest(true)
+	n.startRestart(hrt, req)
+	requireAbort(hrt, true)
+	require.NoError(requireResult(req), "forced restart should succeed")
+
+	// A failed restart should not leave the runtime marked as restarting.
+	hrt.abortErr = errors.New("abort failed")
+	n = newNode()
+	req = newRequest(true)
+	n.startRestart(hrt, req)
+	requireAbort(hrt, true)
+	require.ErrorIs(requireResult(req), hrt.abortErr)
+	n.CrossNode.Lock()
+	require.False(n.runtimeRestarting, "runtime should not be marked as restarting")
+	n.CrossNode.Unlock()
+
+	// Terminating the worker should abort a draining restart.
+	n = newNode()
+	done = n.TrackHostCall()
+	defer done()
+	req = newRequest(false)
+	n.startRestart(hrt, req)
+	n.waitRestart()
+	require.ErrorIs(requireResult(req), context.Canceled)
+	require.Empty(hrt.abortCh, "runtime should not be aborted after termination")
+}
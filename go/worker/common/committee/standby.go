@@ -0,0 +1,34 @@
+package committee
+
+import (
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	"github.com/oasisprotocol/oasis-core/go/config"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+// hasStandbyRuntime returns true iff nodes in the given mode provision the next runtime version
+// as a warm standby ahead of its activation, so that they can keep executing batches and serving
+// queries across the version switch.
+func hasStandbyRuntime(mode config.NodeMode) bool {
+	return mode == config.ModeCompute || mode.IsClientOnly()
+}
+
+// standbyVersion returns the version of the next deployment that should be provisioned as a warm
+// standby alongside the active version, if any.
+//
+// To bound the cost of running two versions at the same time, the next version is only
+// provisioned once its deployment becomes active within the given number of epochs.
+func standbyVersion(rt *registry.Runtime, mode config.NodeMode, epoch, preWarmEpochs beacon.EpochTime) *version.Version {
+	if !hasStandbyRuntime(mode) {
+		return nil
+	}
+
+	nextDeploy := rt.NextDeployment(epoch)
+	if nextDeploy == nil || nextDeploy.ValidFrom-epoch > preWarmEpochs {
+		return nil
+	}
+
+	nextVersion := nextDeploy.Version
+	return &nextVersion
+}
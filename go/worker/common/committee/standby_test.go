@@ -0,0 +1,45 @@
+package committee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	beacon "github.com/oasisprotocol/oasis-core/go/beacon/api"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+	"github.com/oasisprotocol/oasis-core/go/config"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+func TestStandbyVersion(t *testing.T) {
+	require := require.New(t)
+
+	v1 := version.Version{Major: 1}
+	v2 := version.Version{Major: 2}
+	rt := &registry.Runtime{
+		Deployments: []*registry.VersionInfo{
+			{Version: v1, ValidFrom: 0},
+			{Version: v2, ValidFrom: 10},
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		mode     config.NodeMode
+		epoch    beacon.EpochTime
+		expected *version.Version
+	}{
+		{"OutsideWindow", config.ModeCompute, 7, nil},
+		{"WindowStart", config.ModeCompute, 8, &v2},
+		{"BeforeActivation", config.ModeCompute, 9, &v2},
+		{"Activated", config.ModeCompute, 10, nil},
+		{"Client", config.ModeClient, 9, &v2},
+		{"StatelessClient", config.ModeStatelessClient, 9, &v2},
+		{"Validator", config.ModeValidator, 9, nil},
+	} {
+		require.Equal(tc.expected, standbyVersion(rt, tc.mode, tc.epoch, 2), tc.name)
+	}
+
+	// Without a window, no standby is provisioned.
+	require.Nil(standbyVersion(rt, config.ModeCompute, 9, 0))
+}
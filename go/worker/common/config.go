@@ -3,6 +3,7 @@ package common
 import (
 	"fmt"
 
+	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
 	"github.com/oasisprotocol/oasis-core/go/common/node"
 	"github.com/oasisprotocol/oasis-core/go/config"
@@ -15,6 +16,18 @@ type Config struct { // nolint: maligned
 
 	TxPool tpConfig.Config
 
+	// TxValidators are the names of the additional stateless transaction validators enabled for
+	// each runtime.
+	TxValidators map[common.Namespace][]string
+
+	// AllowDynamicRuntimes specifies whether runtimes that are not configured may be added while
+	// the node is running (e.g., by adding their bundle).
+	AllowDynamicRuntimes bool
+
+	// WriteLogChecksums specifies whether checksums of executor batch write logs should be
+	// requested from the runtime and verified before the write logs are applied.
+	WriteLogChecksums bool
+
 	logger *logging.Logger
 }
 
@@ -30,10 +43,23 @@ func NewConfig() (*Config, error) {
 		sentryAddresses = append(sentryAddresses, tlsAddr)
 	}
 
+	// Parse stateless transaction validator configuration.
+	txValidators := make(map[common.Namespace][]string)
+	for id, names := range config.GlobalConfig.Runtime.TxValidators {
+		var runtimeID common.Namespace
+		if err := runtimeID.UnmarshalHex(id); err != nil {
+			return nil, fmt.Errorf("worker: bad runtime identifier in tx validators (%s): %w", id, err)
+		}
+		txValidators[runtimeID] = names
+	}
+
 	cfg := Config{
-		SentryAddresses: sentryAddresses,
-		TxPool:          config.GlobalConfig.Runtime.TxPool,
-		logger:          logging.GetLogger("worker/config"),
+		SentryAddresses:      sentryAddresses,
+		TxPool:               config.GlobalConfig.Runtime.TxPool,
+		TxValidators:         txValidators,
+		AllowDynamicRuntimes: config.GlobalConfig.Runtime.AllowDynamicRuntimes,
+		WriteLogChecksums:    config.GlobalConfig.Storage.WriteLogChecksums,
+		logger:               logging.GetLogger("worker/config"),
 	}
 
 	return &cfg, nil
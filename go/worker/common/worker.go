@@ -1,7 +1,9 @@
 package common
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/identity"
@@ -16,6 +18,22 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/worker/common/committee"
 )
 
+var (
+	// ErrDynamicRuntimesNotAllowed is the error returned when adding a runtime that is not
+	// configured while dynamic runtimes are not allowed.
+	ErrDynamicRuntimesNotAllowed = errors.New("worker/common: dynamic runtimes not allowed")
+
+	// ErrStopped is the error returned when adding a runtime after the worker has been stopped.
+	ErrStopped = errors.New("worker/common: worker stopped")
+)
+
+// RuntimeAddedHook is a hook invoked when a runtime is added while the node is running.
+//
+// It is used by per-runtime workers to set up their runtime components for the added runtime
+// before the runtime's common committee node is started. Hooks are invoked while holding the
+// worker's runtimes lock, so they must not call back into the worker's runtime accessors.
+type RuntimeAddedHook func(*committee.Node) error
+
 // Worker is a garbage bag with lower level services and common runtime objects.
 type Worker struct {
 	enabled bool
@@ -32,8 +50,14 @@ type Worker struct {
 	RuntimeRegistry runtimeRegistry.Registry
 	Provisioner     host.Provisioner
 
-	runtimes map[common.Namespace]*committee.Node
+	runtimesLock      sync.RWMutex
+	runtimes          map[common.Namespace]*committee.Node
+	dynamicRuntimes   map[common.Namespace]struct{}
+	runtimeAddedHooks []RuntimeAddedHook
+	started           bool
+	stopped           bool
 
+	stopCh chan struct{}
 	quitCh chan struct{}
 	initCh chan struct{}
 
@@ -56,18 +80,30 @@ func (w *Worker) Start() error {
 		return nil
 	}
 
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
 	// Wait for all runtimes to terminate.
 	go func() {
 		defer close(w.quitCh)
 
-		for _, rt := range w.runtimes {
+		if w.cfg.AllowDynamicRuntimes {
+			// Runtimes may be added until the worker is stopped.
+			<-w.stopCh
+		}
+
+		for _, rt := range w.GetRuntimes() {
 			<-rt.Quit()
 		}
 	}()
 
-	// Wait for all runtimes to be initialized.
+	// Wait for all configured runtimes to be initialized.
+	runtimes := make([]*committee.Node, 0, len(w.runtimes))
+	for _, rt := range w.runtimes {
+		runtimes = append(runtimes, rt)
+	}
 	go func() {
-		for _, rt := range w.runtimes {
+		for _, rt := range runtimes {
 			<-rt.Initialized()
 		}
 
@@ -84,6 +120,7 @@ func (w *Worker) Start() error {
 			return err
 		}
 	}
+	w.started = true
 
 	return nil
 }
@@ -95,6 +132,15 @@ func (w *Worker) Stop() {
 		return
 	}
 
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
+	if w.stopped {
+		return
+	}
+	w.stopped = true
+	close(w.stopCh)
+
 	for id, rt := range w.runtimes {
 		w.logger.Info("stopping services for runtime",
 			"runtime_id", id,
@@ -120,7 +166,7 @@ func (w *Worker) Cleanup() {
 		return
 	}
 
-	for _, rt := range w.runtimes {
+	for _, rt := range w.GetRuntimes() {
 		rt.Cleanup()
 	}
 }
@@ -136,18 +182,103 @@ func (w *Worker) GetConfig() Config {
 	return w.cfg
 }
 
-// GetRuntimes returns a map of configured runtimes.
+// GetRuntimes returns a map of configured and dynamically added runtimes.
 func (w *Worker) GetRuntimes() map[common.Namespace]*committee.Node {
-	return w.runtimes
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
+	runtimes := make(map[common.Namespace]*committee.Node, len(w.runtimes))
+	for id, rt := range w.runtimes {
+		runtimes[id] = rt
+	}
+	return runtimes
 }
 
 // GetRuntime returns a common committee node for the given runtime (if available).
 //
-// In case the runtime with the specified id was not configured for this node it returns nil.
+// In case the runtime with the specified id was not configured or added for this node it
+// returns nil.
 func (w *Worker) GetRuntime(id common.Namespace) *committee.Node {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
 	return w.runtimes[id]
 }
 
+// IsDynamicRuntime returns true iff the given runtime was added while the node was running.
+func (w *Worker) IsDynamicRuntime(id common.Namespace) bool {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
+	_, ok := w.dynamicRuntimes[id]
+	return ok
+}
+
+// OnRuntimeAdded registers a hook that is invoked for each runtime added while the node is
+// running.
+//
+// Hooks must be registered before the worker is started.
+func (w *Worker) OnRuntimeAdded(hook RuntimeAddedHook) {
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
+	w.runtimeAddedHooks = append(w.runtimeAddedHooks, hook)
+}
+
+// AddRuntime adds a runtime while the node is running, e.g., after its bundle has been added.
+//
+// The runtime is only added in case it is part of the configured runtime set or dynamic runtimes
+// are allowed. Its per-runtime components are set up by the registered runtime added hooks and
+// are started immediately in case the worker is already running. Adding a runtime that has
+// already been added is a no-op.
+func (w *Worker) AddRuntime(rt runtimeRegistry.Runtime, configured bool) (*committee.Node, error) {
+	if !w.enabled {
+		return nil, fmt.Errorf("worker/common: worker is disabled")
+	}
+	if !rt.IsManaged() {
+		return nil, fmt.Errorf("worker/common: runtime %s is not managed", rt.ID())
+	}
+	if !configured && !w.cfg.AllowDynamicRuntimes {
+		return nil, ErrDynamicRuntimesNotAllowed
+	}
+
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
+	id := rt.ID()
+	if node, ok := w.runtimes[id]; ok {
+		return node, nil
+	}
+	if w.stopped {
+		return nil, ErrStopped
+	}
+
+	if err := w.registerRuntime(rt); err != nil {
+		return nil, err
+	}
+	node := w.runtimes[id]
+
+	for _, hook := range w.runtimeAddedHooks {
+		if err := hook(node); err != nil {
+			delete(w.runtimes, id)
+			return nil, fmt.Errorf("worker/common: failed to set up runtime %s: %w", id, err)
+		}
+	}
+	w.dynamicRuntimes[id] = struct{}{}
+
+	if w.started {
+		w.logger.Info("starting services for runtime",
+			"runtime_id", id,
+		)
+
+		if err := node.Start(); err != nil {
+			return nil, err
+		}
+	}
+
+	return node, nil
+}
+
 func (w *Worker) registerRuntime(runtime runtimeRegistry.Runtime) error {
 	id := runtime.ID()
 	w.logger.Info("registering new runtime",
@@ -166,6 +297,8 @@ func (w *Worker) registerRuntime(runtime runtimeRegistry.Runtime) error {
 		w.LightProvider,
 		w.P2P,
 		w.cfg.TxPool,
+		w.cfg.TxValidators[id],
+		config.GlobalConfig.Storage.Mirror.IsMirrored(id),
 	)
 	if err != nil {
 		return err
@@ -222,6 +355,8 @@ func New(
 		RuntimeRegistry: runtimeRegistry,
 		Provisioner:     provisioner,
 		runtimes:        make(map[common.Namespace]*committee.Node),
+		dynamicRuntimes: make(map[common.Namespace]struct{}),
+		stopCh:          make(chan struct{}),
 		quitCh:          make(chan struct{}),
 		initCh:          make(chan struct{}),
 		logger:          logging.GetLogger("worker/common"),
@@ -36,6 +36,7 @@ import (
 
 var (
 	errMsgFromNonTxnSched = fmt.Errorf("executor: received txn scheduler dispatch msg from non-txn scheduler")
+	errRateLimited        = fmt.Errorf("executor: committee message rate limit exceeded")
 
 	// abortTimeout is the duration to wait for the runtime to abort.
 	abortTimeout = 5 * time.Second
@@ -74,6 +75,7 @@ type Node struct { // nolint: maligned
 	state            NodeState
 	stateTransitions *pubsub.Broker
 	proposals        *proposalQueue
+	limiter          *committee.MessageRateLimiter
 	committee        *scheduler.Committee
 	commitPool       *commitment.Pool
 
@@ -506,12 +508,7 @@ func (n *Node) startSchedulingBatch(ctx context.Context, batch []*txpool.TxQueue
 	}
 
 	// Submit response to the round worker.
-	n.processedBatchCh <- &processedBatch{
-		proposal:        &proposal,
-		rank:            n.rank,
-		computed:        &rsp.Batch,
-		txInputWriteLog: rsp.TxInputWriteLog,
-	}
+	n.processedBatchCh <- newProcessedBatch(&proposal, n.rank, rsp)
 }
 
 func (n *Node) runtimeExecuteTxBatch(
@@ -544,17 +541,31 @@ func (n *Node) runtimeExecuteTxBatch(
 		return nil, err
 	}
 
+	// Request write log checksums in case they are enabled and the runtime supports them.
+	var checksums bool
+	if n.commonCfg.WriteLogChecksums {
+		rtInfo, err := rt.GetInfo(ctx)
+		if err != nil {
+			n.logger.Error("failed to retrieve runtime information",
+				"err", err,
+			)
+			return nil, err
+		}
+		checksums = rtInfo.Features.HasWriteLogChecksums()
+	}
+
 	rq := &protocol.Body{
 		RuntimeExecuteTxBatchRequest: &protocol.RuntimeExecuteTxBatchRequest{
-			Mode:           mode,
-			ConsensusBlock: *consensusBlk,
-			RoundResults:   roundResults,
-			IORoot:         inputRoot,
-			Inputs:         inputs,
-			InMessages:     inMsgs,
-			Block:          *blk,
-			Epoch:          epoch,
-			MaxMessages:    state.Runtime.Executor.MaxMessages,
+			Mode:              mode,
+			ConsensusBlock:    *consensusBlk,
+			RoundResults:      roundResults,
+			IORoot:            inputRoot,
+			Inputs:            inputs,
+			InMessages:        inMsgs,
+			Block:             *blk,
+			Epoch:             epoch,
+			MaxMessages:       state.Runtime.Executor.MaxMessages,
+			WriteLogChecksums: checksums,
 		},
 	}
 	batchSize.With(n.getMetricLabels()).Observe(float64(len(inputs)))
@@ -579,6 +590,10 @@ func (n *Node) runtimeExecuteTxBatch(
 	)
 	defer cancelCallFn()
 
+	// Allow the batch to complete before the runtime is gracefully restarted.
+	done := n.commonNode.TrackHostCall()
+	defer done()
+
 	rsp, err := rt.Call(callCtx, rq)
 	switch {
 	case err == nil:
@@ -614,6 +629,16 @@ func (n *Node) runtimeExecuteTxBatch(
 		return nil, fmt.Errorf("malformed response from runtime")
 	}
 
+	// Verify the write logs against the checksums produced by the runtime.
+	if checksums {
+		if err = verifyWriteLogChecksums(rsp.RuntimeExecuteTxBatchResponse); err != nil {
+			n.logger.Error("runtime produced corrupted write logs",
+				"err", err,
+			)
+			return nil, err
+		}
+	}
+
 	return rsp.RuntimeExecuteTxBatchResponse, nil
 }
 
@@ -647,12 +672,7 @@ func (n *Node) startProcessingBatch(ctx context.Context, proposal *commitment.Pr
 	}
 
 	// Submit response to the round worker.
-	n.processedBatchCh <- &processedBatch{
-		proposal:        proposal,
-		rank:            rank,
-		computed:        &rsp.Batch,
-		txInputWriteLog: rsp.TxInputWriteLog,
-	}
+	n.processedBatchCh <- newProcessedBatch(proposal, rank, rsp)
 }
 
 func (n *Node) abortBatch(state *StateProcessingBatch) {
@@ -717,33 +737,56 @@ func (n *Node) proposeBatch(
 		ctx, cancel := context.WithCancel(roundCtx)
 		defer cancel()
 
-		// Store final I/O root.
 		var emptyRoot hash.Hash
 		emptyRoot.Empty()
 
-		err := n.storage.Apply(ctx, &storage.ApplyRequest{
+		// Store final I/O root and update state root. Either both are stored or neither is.
+		request := &storage.ApplyBatchRequest{
 			Namespace: lastHeader.Namespace,
-			RootType:  storage.RootTypeIO,
-			SrcRound:  lastHeader.Round + 1,
-			SrcRoot:   emptyRoot,
 			DstRound:  lastHeader.Round + 1,
-			DstRoot:   *batch.Header.IORoot,
-			WriteLog:  append(processed.txInputWriteLog, batch.IOWriteLog...),
-		})
-		if err != nil {
-			return err
+			Ops: []storage.ApplyOp{
+				{
+					RootType: storage.RootTypeIO,
+					SrcRound: lastHeader.Round + 1,
+					SrcRoot:  emptyRoot,
+					DstRoot:  *batch.Header.IORoot,
+					WriteLog: append(processed.txInputWriteLog, batch.IOWriteLog...),
+
+					WriteLogAnnotations: processed.ioWriteLogAnns,
+				},
+				{
+					RootType: storage.RootTypeState,
+					SrcRound: lastHeader.Round,
+					SrcRoot:  lastHeader.StateRoot,
+					DstRoot:  *batch.Header.StateRoot,
+					WriteLog: batch.StateWriteLog,
+
+					WriteLogAnnotations: processed.stateWriteLogAnns,
+				},
+			},
 		}
-		// Update state root.
-		err = n.storage.Apply(ctx, &storage.ApplyRequest{
-			Namespace: lastHeader.Namespace,
-			RootType:  storage.RootTypeState,
-			SrcRound:  lastHeader.Round,
-			SrcRoot:   lastHeader.StateRoot,
-			DstRound:  lastHeader.Round + 1,
-			DstRoot:   *batch.Header.StateRoot,
-			WriteLog:  batch.StateWriteLog,
-		})
-		if err != nil {
+		_, err := n.storage.ApplyBatch(ctx, request)
+		switch {
+		case err == nil:
+		case errors.Is(err, storage.ErrUnsupported):
+			// The node database cannot commit both roots together, so store them one by one
+			// without the atomicity guarantee.
+			for _, op := range request.Ops {
+				if err = n.storage.Apply(ctx, &storage.ApplyRequest{
+					Namespace: request.Namespace,
+					RootType:  op.RootType,
+					SrcRound:  op.SrcRound,
+					SrcRoot:   op.SrcRoot,
+					DstRound:  request.DstRound,
+					DstRoot:   op.DstRoot,
+					WriteLog:  op.WriteLog,
+
+					WriteLogAnnotations: op.WriteLogAnnotations,
+				}); err != nil {
+					return err
+				}
+			}
+		default:
 			return err
 		}
 
@@ -1557,6 +1600,7 @@ func NewNode(
 		roleProvider:     roleProvider,
 		committeeTopic:   committeeTopic,
 		proposals:        newPendingProposals(),
+		limiter:          committee.NewMessageRateLimiter(commonNode.Runtime.ID()),
 		ctx:              ctx,
 		cancelCtx:        cancel,
 		stopCh:           make(chan struct{}),
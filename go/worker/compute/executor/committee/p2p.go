@@ -9,6 +9,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
 	p2p "github.com/oasisprotocol/oasis-core/go/p2p/api"
 	p2pError "github.com/oasisprotocol/oasis-core/go/p2p/error"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/committee"
 )
 
 type committeeMsgHandler struct {
@@ -45,15 +46,53 @@ func (h *committeeMsgHandler) AuthorizeMessage(_ context.Context, peerID signatu
 	}
 
 	// Only known committee members are allowed to submit messages on this topic.
-	committee := epoch.GetExecutorCommittee()
-	if committee == nil {
+	ci := epoch.GetExecutorCommittee()
+	if ci == nil {
 		return fmt.Errorf("executor committee is not yet known")
 	}
 
-	if _, ok := committee.Peers[peerID]; !ok {
+	if _, ok := ci.Peers[peerID]; !ok {
 		return p2pError.Permanent(fmt.Errorf("peer is not authorized to publish committee messages"))
 	}
-	return nil
+
+	// Enforce rate limits before any signatures are verified. As the error is permanent, the
+	// message is not relayed and the peer is penalized.
+	h.n.limiter.Update(epoch.GetEpochNumber(), epoch.GetRuntime())
+	kind := committeeMessageKind(cm)
+	if h.n.limiter.Allow(peerID, kind) {
+		return nil
+	}
+	// Never drop the proposal of the current round's primary scheduler.
+	if h.isPrimaryProposal(ci, peerID, cm) && h.n.limiter.AllowExempt(peerID, kind, cm.Proposal.Header.Round) {
+		return nil
+	}
+	return p2pError.Permanent(errRateLimited)
+}
+
+// isPrimaryProposal returns true iff the message is a proposal for the current (or the next, in
+// case the local node is just behind) round published by the round's primary scheduler.
+func (h *committeeMsgHandler) isPrimaryProposal(ci *committee.CommitteeInfo, peerID signature.PublicKey, cm *p2p.CommitteeMessage) bool {
+	if cm.Proposal == nil {
+		return false
+	}
+	round := cm.Proposal.Header.Round
+	if current := h.n.proposals.Round(); round != current && round != current+1 {
+		return false
+	}
+	scheduler, ok := ci.Committee.Scheduler(round, 0)
+	if !ok {
+		return false
+	}
+	return ci.NodePeers[scheduler.PublicKey].Equal(peerID)
+}
+
+func committeeMessageKind(cm *p2p.CommitteeMessage) string {
+	switch {
+	case cm.Proposal != nil:
+		return committee.MessageKindProposal
+	default:
+		return committee.MessageKindOther
+	}
 }
 
 func (h *committeeMsgHandler) HandleMessage(_ context.Context, _ signature.PublicKey, msg any, isOwn bool) error {
@@ -105,6 +105,14 @@ func (q *proposalQueue) Add(proposal *commitment.Proposal, rank uint64) error {
 	return nil
 }
 
+// Round returns the round for which proposals are currently being processed.
+func (q *proposalQueue) Round() uint64 {
+	q.l.RLock()
+	defer q.l.RUnlock()
+
+	return q.round
+}
+
 // Prune prunes any proposals which are not valid anymore.
 func (q *proposalQueue) Prune(round uint64) {
 	q.l.Lock()
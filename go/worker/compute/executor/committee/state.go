@@ -2,6 +2,8 @@ package committee
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
@@ -9,6 +11,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/runtime/host/protocol"
 	"github.com/oasisprotocol/oasis-core/go/runtime/transaction"
 	storage "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
 )
 
 // StateName is a symbolic state without the attached values.
@@ -169,6 +172,49 @@ type processedBatch struct {
 	computed *protocol.ComputedBatch
 
 	txInputWriteLog storage.WriteLog
+
+	// ioWriteLogAnns and stateWriteLogAnns are the checksum annotations of the I/O (including the
+	// transaction inputs) and state write logs as produced by the runtime (if any).
+	ioWriteLogAnns    writelog.Annotations
+	stateWriteLogAnns writelog.Annotations
+}
+
+// newProcessedBatch creates a new processed batch from the runtime response.
+//
+// Write log checksums produced by the runtime are carried along so that they can be verified
+// again right before the write logs are applied to storage.
+func newProcessedBatch(proposal *commitment.Proposal, rank uint64, rsp *protocol.RuntimeExecuteTxBatchResponse) *processedBatch {
+	batch := &processedBatch{
+		proposal:        proposal,
+		rank:            rank,
+		computed:        &rsp.Batch,
+		txInputWriteLog: rsp.TxInputWriteLog,
+	}
+	if cs := rsp.WriteLogChecksums; cs != nil {
+		batch.ioWriteLogAnns = writelog.ChecksumAnnotations(cs.IO)
+		batch.stateWriteLogAnns = writelog.ChecksumAnnotations(cs.State)
+	}
+	return batch
+}
+
+// verifyWriteLogChecksums verifies the write logs of the runtime response against the checksums
+// produced by the runtime.
+func verifyWriteLogChecksums(rsp *protocol.RuntimeExecuteTxBatchResponse) error {
+	cs := rsp.WriteLogChecksums
+	if cs == nil {
+		return fmt.Errorf("runtime did not produce write log checksums")
+	}
+	ioWriteLog := slices.Concat(rsp.TxInputWriteLog, rsp.Batch.IOWriteLog)
+	if len(cs.IO) != len(ioWriteLog) || len(cs.State) != len(rsp.Batch.StateWriteLog) {
+		return fmt.Errorf("%w: runtime produced checksums for a different number of entries", writelog.ErrChecksumMismatch)
+	}
+	if err := writelog.VerifyChecksums(ioWriteLog, writelog.ChecksumAnnotations(cs.IO)); err != nil {
+		return fmt.Errorf("bad I/O write log: %w", err)
+	}
+	if err := writelog.VerifyChecksums(rsp.Batch.StateWriteLog, writelog.ChecksumAnnotations(cs.State)); err != nil {
+		return fmt.Errorf("bad state write log: %w", err)
+	}
+	return nil
 }
 
 type proposedBatch struct {
@@ -3,6 +3,7 @@ package executor
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/logging"
@@ -21,10 +22,14 @@ type Worker struct {
 	commonWorker *workerCommon.Worker
 	registration *registration.Worker
 
-	runtimes map[common.Namespace]*committee.Node
+	runtimesLock sync.RWMutex
+	runtimes     map[common.Namespace]*committee.Node
+	started      bool
+	stopped      bool
 
 	ctx       context.Context
 	cancelCtx context.CancelFunc
+	stopCh    chan struct{}
 	quitCh    chan struct{}
 	initCh    chan struct{}
 
@@ -47,20 +52,32 @@ func (w *Worker) Start() error {
 		return nil
 	}
 
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
 	// Wait for all runtimes and all proxies to terminate.
 	go func() {
 		defer close(w.quitCh)
 		defer (w.cancelCtx)()
 
-		for _, rt := range w.runtimes {
+		if w.commonWorker.GetConfig().AllowDynamicRuntimes {
+			// Runtimes may be added until the worker is stopped.
+			<-w.stopCh
+		}
+
+		for _, rt := range w.getRuntimes() {
 			<-rt.Quit()
 		}
 	}()
 
-	// Wait for all runtimes to be initialized and for the node
+	// Wait for all configured runtimes to be initialized and for the node
 	// to be registered for the current epoch.
+	runtimes := make([]*committee.Node, 0, len(w.runtimes))
+	for _, rt := range w.runtimes {
+		runtimes = append(runtimes, rt)
+	}
 	go func() {
-		for _, rt := range w.runtimes {
+		for _, rt := range runtimes {
 			<-rt.Initialized()
 		}
 
@@ -79,6 +96,7 @@ func (w *Worker) Start() error {
 			return err
 		}
 	}
+	w.started = true
 
 	return nil
 }
@@ -90,6 +108,15 @@ func (w *Worker) Stop() {
 		return
 	}
 
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
+	if w.stopped {
+		return
+	}
+	w.stopped = true
+	close(w.stopCh)
+
 	for id, rt := range w.runtimes {
 		w.logger.Info("stopping services for runtime",
 			"runtime_id", id,
@@ -115,7 +142,7 @@ func (w *Worker) Cleanup() {
 		return
 	}
 
-	for _, rt := range w.runtimes {
+	for _, rt := range w.getRuntimes() {
 		rt.Cleanup()
 	}
 }
@@ -131,11 +158,57 @@ func (w *Worker) Initialized() <-chan struct{} {
 // In case the runtime with the specified id was not registered it
 // returns nil.
 func (w *Worker) GetRuntime(id common.Namespace) *committee.Node {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
 	return w.runtimes[id]
 }
 
+func (w *Worker) getRuntimes() []*committee.Node {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
+	runtimes := make([]*committee.Node, 0, len(w.runtimes))
+	for _, rt := range w.runtimes {
+		runtimes = append(runtimes, rt)
+	}
+	return runtimes
+}
+
+// addRuntime sets up the executor for a runtime added while the node is running.
+func (w *Worker) addRuntime(commonNode *committeeCommon.Node) error {
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
+	if w.stopped {
+		return fmt.Errorf("executor worker stopped")
+	}
+	if err := w.registerRuntime(commonNode); err != nil {
+		return err
+	}
+	id := commonNode.Runtime.ID()
+	rt, ok := w.runtimes[id]
+	if !w.started || !ok {
+		return nil
+	}
+
+	w.logger.Info("starting services for runtime",
+		"runtime_id", id,
+	)
+
+	return rt.Start()
+}
+
 func (w *Worker) registerRuntime(commonNode *committeeCommon.Node) error {
 	id := commonNode.Runtime.ID()
+	if commonNode.Mirror {
+		// Mirrored runtimes are only followed, so the node never joins their committees.
+		w.logger.Info("skipping mirrored runtime",
+			"runtime_id", id,
+		)
+		return nil
+	}
+
 	w.logger.Info("registering new runtime",
 		"runtime_id", id,
 	)
@@ -188,6 +261,7 @@ func New(
 		runtimes:     make(map[common.Namespace]*committee.Node),
 		ctx:          ctx,
 		cancelCtx:    cancelCtx,
+		stopCh:       make(chan struct{}),
 		quitCh:       make(chan struct{}),
 		initCh:       make(chan struct{}),
 		logger:       logging.GetLogger("worker/executor"),
@@ -203,6 +277,7 @@ func New(
 			return nil, err
 		}
 	}
+	commonWorker.OnRuntimeAdded(w.addRuntime)
 
 	return w, nil
 }
@@ -15,6 +15,33 @@ type Config struct {
 
 	// EntityID to use as the node owner in registrations (public key).
 	EntityID string `yaml:"entity_id"`
+
+	// SelfCheck is the advertised address self-check configuration.
+	SelfCheck SelfCheckConfig `yaml:"self_check,omitempty"`
+}
+
+// Self-check modes.
+const (
+	// SelfCheckModeDisabled disables the self-check before registration.
+	SelfCheckModeDisabled = "disabled"
+	// SelfCheckModeWarn warns when critical advertised addresses are unreachable.
+	SelfCheckModeWarn = "warn"
+	// SelfCheckModeEnforce refuses to register when critical advertised addresses are unreachable.
+	SelfCheckModeEnforce = "enforce"
+)
+
+// SelfCheckConfig is the advertised address self-check configuration structure.
+type SelfCheckConfig struct {
+	// Mode is the self-check mode to use before each registration (disabled, warn, enforce).
+	Mode string `yaml:"mode"`
+
+	// Peers that are asked to connect back to the advertised addresses (in the form pubkey@IP:port),
+	// e.g., the node's sentries.
+	Peers []string `yaml:"peers"`
+
+	// P2P public keys of nodes that are allowed to ask this node to probe their addresses.
+	// Probe requests are refused if empty.
+	AuthorizedPubkeys []string `yaml:"authorized_pubkeys"`
 }
 
 // Validate validates the configuration settings.
@@ -34,6 +61,22 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("malformed entity ID: %w", err)
 		}
 	}
+
+	switch c.SelfCheck.Mode {
+	case SelfCheckModeDisabled:
+	case SelfCheckModeWarn, SelfCheckModeEnforce:
+		if len(c.SelfCheck.Peers) == 0 {
+			return fmt.Errorf("self_check.peers must be set when the self-check is enabled")
+		}
+	default:
+		return fmt.Errorf("unknown self-check mode: %s", c.SelfCheck.Mode)
+	}
+	for _, pubkey := range c.SelfCheck.AuthorizedPubkeys {
+		var pk signature.PublicKey
+		if err := pk.UnmarshalText([]byte(pubkey)); err != nil {
+			return fmt.Errorf("malformed self-check authorized public key: %s: %w", pubkey, err)
+		}
+	}
 	return nil
 }
 
@@ -42,5 +85,10 @@ func DefaultConfig() Config {
 	return Config{
 		Entity:   "",
 		EntityID: "",
+		SelfCheck: SelfCheckConfig{
+			Mode:              SelfCheckModeDisabled,
+			Peers:             []string{},
+			AuthorizedPubkeys: []string{},
+		},
 	}
 }
@@ -0,0 +1,105 @@
+package registration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/tls"
+	"github.com/oasisprotocol/oasis-core/go/common/identity"
+	control "github.com/oasisprotocol/oasis-core/go/control/api"
+	sentryClient "github.com/oasisprotocol/oasis-core/go/sentry/client"
+)
+
+// GetPublicKeys returns all public keys of the node together with their roles, including rotated
+// out keys that are still within their grace window.
+func (w *Worker) GetPublicKeys(context.Context) ([]*control.PublicKeyInfo, error) {
+	w.keysLock.Lock()
+	defer w.keysLock.Unlock()
+
+	keys := []*control.PublicKeyInfo{
+		{Role: control.KeyRoleNode, PublicKey: w.identity.NodeSigner.Public()},
+		{Role: control.KeyRoleConsensus, PublicKey: w.identity.ConsensusSigner.Public()},
+		{Role: control.KeyRoleP2P, PublicKey: w.identity.P2PSigner.Public()},
+		{Role: control.KeyRoleTLS, PublicKey: w.identity.TLSSigner.Public()},
+		{Role: control.KeyRoleVRF, PublicKey: w.identity.VRFSigner.Public()},
+	}
+	return append(keys, w.rotatedKeys.Keys()...), nil
+}
+
+// RotateKeys rotates the requested node keys, pushes the currently valid TLS public keys to the
+// configured sentry nodes and requests re-registration so that the node descriptor is updated.
+//
+// The libp2p host cannot change its identity while running, so rotating the P2P key returns
+// control.ErrKeyRotationNotSupported.
+func (w *Worker) RotateKeys(ctx context.Context, req *control.RotateKeysRequest) (*control.RotateKeysResponse, error) {
+	if err := req.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	if req.P2P {
+		return nil, fmt.Errorf("%w: p2p key requires a node restart", control.ErrKeyRotationNotSupported)
+	}
+
+	w.keysLock.Lock()
+	defer w.keysLock.Unlock()
+
+	gracePeriod := req.EffectiveGracePeriod()
+	rsp := &control.RotateKeysResponse{
+		GracePeriod: gracePeriod,
+	}
+
+	if req.TLS {
+		cert, err := tls.Generate(identity.CommonName)
+		if err != nil {
+			return nil, fmt.Errorf("worker/registration: failed to generate TLS certificate: %w", err)
+		}
+
+		oldPubKey := w.identity.TLSSigner.Public()
+		if err = w.identity.SetTLSCertificate(cert); err != nil {
+			return nil, fmt.Errorf("worker/registration: failed to set TLS certificate: %w", err)
+		}
+		newPubKey := w.identity.TLSSigner.Public()
+		rsp.ValidUntil = w.rotatedKeys.Add(control.KeyRoleTLS, oldPubKey, gracePeriod)
+		rsp.TLS = &newPubKey
+
+		w.logger.Info("rotated TLS key",
+			"old_pub_key", oldPubKey,
+			"new_pub_key", newPubKey,
+			"valid_until", rsp.ValidUntil,
+		)
+
+		w.pushSentryTLSPubKeys(ctx, w.rotatedKeys.ValidKeys(control.KeyRoleTLS, newPubKey))
+	}
+
+	// Re-register so that the node descriptor advertises the new keys.
+	select {
+	case w.registerCh <- struct{}{}:
+	default:
+	}
+
+	return rsp, nil
+}
+
+// pushSentryTLSPubKeys notifies all configured sentry nodes of the given upstream TLS public keys.
+//
+// Failures are logged as the sentry nodes are notified again on the next rotation.
+func (w *Worker) pushSentryTLSPubKeys(ctx context.Context, pubKeys []signature.PublicKey) {
+	for _, sentryAddr := range w.sentryAddresses {
+		client, err := sentryClient.New(sentryAddr, w.identity)
+		if err != nil {
+			w.logger.Warn("failed to create client to a sentry node",
+				"err", err,
+				"sentry_address", sentryAddr,
+			)
+			continue
+		}
+
+		if err = client.SetUpstreamTLSPubKeys(ctx, pubKeys); err != nil {
+			w.logger.Warn("failed to push TLS public keys to sentry node",
+				"err", err,
+				"sentry_address", sentryAddr,
+			)
+		}
+		client.Close()
+	}
+}
@@ -0,0 +1,127 @@
+package registration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/libp2p/go-libp2p/core"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/signature"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/config"
+	p2p "github.com/oasisprotocol/oasis-core/go/p2p/api"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+	regConfig "github.com/oasisprotocol/oasis-core/go/worker/registration/config"
+	"github.com/oasisprotocol/oasis-core/go/worker/registration/selfcheck"
+)
+
+// initSelfCheck sets up the advertised address self-check client and the probe server.
+func (w *Worker) initSelfCheck() error {
+	cfg := config.GlobalConfig.Registration.SelfCheck
+	if cfg.Mode == regConfig.SelfCheckModeDisabled && len(cfg.AuthorizedPubkeys) == 0 {
+		return nil
+	}
+
+	chainContext, err := w.consensus.Core().GetChainContext(w.ctx)
+	if err != nil {
+		return fmt.Errorf("worker/registration: failed to get consensus chain context: %w", err)
+	}
+
+	if len(cfg.AuthorizedPubkeys) > 0 {
+		authorized := make([]core.PeerID, 0, len(cfg.AuthorizedPubkeys))
+		for _, pubkey := range cfg.AuthorizedPubkeys {
+			var pk signature.PublicKey
+			if err = pk.UnmarshalText([]byte(pubkey)); err != nil {
+				return fmt.Errorf("worker/registration: malformed self-check authorized public key: %s: %w", pubkey, err)
+			}
+			peerID, grr := p2p.PublicKeyToPeerID(pk)
+			if grr != nil {
+				return fmt.Errorf("worker/registration: invalid self-check authorized public key: %s: %w", pubkey, grr)
+			}
+			authorized = append(authorized, peerID)
+		}
+		w.p2p.RegisterProtocolServer(selfcheck.NewServer(chainContext, authorized))
+	}
+
+	if cfg.Mode != regConfig.SelfCheckModeDisabled {
+		peers, grr := p2p.AddrInfosFromConsensusAddrs(cfg.Peers)
+		if grr != nil {
+			return fmt.Errorf("worker/registration: malformed self-check peer address: %w", grr)
+		}
+		w.selfCheck = selfcheck.NewClient(w.p2p, chainContext, peers)
+		w.selfCheckEnforce = cfg.Mode == regConfig.SelfCheckModeEnforce
+	}
+
+	return nil
+}
+
+// checkAdvertisedAddresses runs the self-check for the addresses of the given node descriptor
+// before it is registered. It returns an error only if the self-check is enforced.
+func (w *Worker) checkAdvertisedAddresses(nodeDesc *node.Node) error {
+	if w.selfCheck == nil {
+		return nil
+	}
+
+	report, err := w.runSelfCheck(w.ctx, nodeDesc)
+	if err == nil {
+		err = report.Err()
+	}
+	switch {
+	case err == nil:
+		return nil
+	case w.selfCheckEnforce:
+		w.logger.Error("not registering: advertised address self-check failed",
+			"err", err,
+		)
+		return fmt.Errorf("registration: advertised address self-check failed: %w", err)
+	default:
+		w.logger.Warn("advertised address self-check failed, node may be unreachable",
+			"err", err,
+		)
+		return nil
+	}
+}
+
+func (w *Worker) runSelfCheck(ctx context.Context, nodeDesc *node.Node) (*selfcheck.Report, error) {
+	if w.selfCheck == nil {
+		return nil, selfcheck.ErrDisabled
+	}
+
+	report, err := w.selfCheck.Check(ctx, nodeDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Lock()
+	w.status.SelfCheck = report
+	w.Unlock()
+
+	return report, nil
+}
+
+// RunSelfCheck asks the configured peers to connect back to the addresses that the node would
+// currently advertise and returns the aggregated results.
+func (w *Worker) RunSelfCheck(ctx context.Context) (*selfcheck.Report, error) {
+	if w.selfCheck == nil {
+		return nil, selfcheck.ErrDisabled
+	}
+
+	// Only addresses required by the registered roles are critical, so use them if known.
+	var nodeDesc node.Node
+	w.RLock()
+	if w.status.Descriptor != nil {
+		nodeDesc.Roles = w.status.Descriptor.Roles
+	}
+	w.RUnlock()
+
+	if nodeDesc.Roles.IsEmptyRole() || nodeDesc.HasRoles(registry.ConsensusAddressRequiredRoles) {
+		if addrs, err := w.gatherConsensusAddresses(w.querySentries()); err == nil {
+			nodeDesc.Consensus.Addresses = addrs
+		}
+	}
+	if nodeDesc.Roles.IsEmptyRole() || nodeDesc.HasRoles(registry.P2PAddressRequiredRoles) {
+		nodeDesc.P2P.Addresses = w.p2p.Addresses()
+	}
+
+	return w.runSelfCheck(ctx, &nodeDesc)
+}
@@ -0,0 +1,77 @@
+package selfcheck
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/libp2p/go-libp2p/core"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/libp2p/go-libp2p/core/peerstore"
+
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/p2p/protocol"
+	"github.com/oasisprotocol/oasis-core/go/p2p/rpc"
+)
+
+// Client is a self-check protocol client.
+type Client interface {
+	// Check asks the configured peers to probe the addresses advertised in the given node
+	// descriptor and aggregates the results.
+	Check(ctx context.Context, n *node.Node) (*Report, error)
+}
+
+type client struct {
+	rc    rpc.Client
+	peers []core.PeerID
+}
+
+func (c *client) Check(ctx context.Context, n *node.Node) (*Report, error) {
+	addrs := AddressesFromDescriptor(n)
+	report := newReport(n, addrs)
+	if len(addrs) == 0 {
+		return report, nil
+	}
+	if len(addrs) > MaxProbeAddresses {
+		return nil, fmt.Errorf("selfcheck: too many addresses (%d > %d)", len(addrs), MaxProbeAddresses)
+	}
+
+	request := ProbeRequest{Addresses: addrs}
+	_, _, err := c.rc.CallMulti(ctx, c.peers, MethodProbe, request, ProbeResponse{},
+		rpc.WithMaxPeerResponseTimeMulti(2*ProbeTimeout),
+		rpc.WithAggregateFn(func(rawRsp any, pf rpc.PeerFeedback) bool {
+			rsp := rawRsp.(*ProbeResponse)
+			if len(rsp.Results) != len(addrs) {
+				pf.RecordBadPeer()
+				return true
+			}
+			pf.RecordSuccess()
+
+			report.addResults(pf.PeerID(), rsp.Results)
+			return true
+		}))
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Peers) == 0 {
+		return nil, ErrNoResponses
+	}
+	return report, nil
+}
+
+// NewClient creates a new self-check protocol client that asks the given peers to probe
+// addresses.
+func NewClient(p2p rpc.P2P, chainContext string, peers []peer.AddrInfo) Client {
+	pid := protocol.NewProtocolID(chainContext, SelfCheckProtocolID, SelfCheckProtocolVersion)
+
+	peerIDs := make([]core.PeerID, 0, len(peers))
+	for _, info := range peers {
+		// The probing peers need not be registered, so make sure that they can be dialed.
+		p2p.Host().Peerstore().AddAddrs(info.ID, info.Addrs, peerstore.PermanentAddrTTL)
+		peerIDs = append(peerIDs, info.ID)
+	}
+
+	return &client{
+		rc:    rpc.NewClient(p2p.Host(), pid),
+		peers: peerIDs,
+	}
+}
@@ -0,0 +1,93 @@
+// Package selfcheck implements the advertised address self-check protocol.
+//
+// Before (re-)registering, a node can ask a configured set of peers (e.g., its sentries) to
+// connect back to each address it is about to advertise in its node descriptor. This makes it
+// possible to detect misconfigured external addresses before they end up in the registry.
+package selfcheck
+
+import (
+	"time"
+
+	"github.com/oasisprotocol/oasis-core/go/common/errors"
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	"github.com/oasisprotocol/oasis-core/go/common/version"
+)
+
+// ModuleName is the module name used for error definitions.
+const ModuleName = "worker/registration/selfcheck"
+
+var (
+	// ErrUnauthorized is the error returned when the requesting peer is not allowed to request
+	// address probes.
+	ErrUnauthorized = errors.New(ModuleName, 1, "selfcheck: unauthorized")
+
+	// ErrRateLimited is the error returned when the requesting peer requested address probes
+	// too often.
+	ErrRateLimited = errors.New(ModuleName, 2, "selfcheck: rate limited")
+
+	// ErrNoResponses is the error returned when none of the peers responded to a probe request.
+	ErrNoResponses = errors.New(ModuleName, 3, "selfcheck: no peers responded")
+
+	// ErrDisabled is the error returned when the self-check is not enabled.
+	ErrDisabled = errors.New(ModuleName, 4, "selfcheck: disabled")
+
+	// ErrUnreachable is the error returned when critical advertised addresses are unreachable.
+	ErrUnreachable = errors.New(ModuleName, 5, "selfcheck: critical addresses unreachable")
+)
+
+// SelfCheckProtocolID is a unique protocol identifier for the self-check protocol.
+const SelfCheckProtocolID = "selfcheck"
+
+// SelfCheckProtocolVersion is the supported version of the self-check protocol.
+var SelfCheckProtocolVersion = version.Version{Major: 1, Minor: 0, Patch: 0}
+
+// Constants related to the Probe method.
+const (
+	MethodProbe       = "Probe"
+	MaxProbeAddresses = 32
+
+	// ProbeTimeout is the timeout for connecting to a single probed address.
+	ProbeTimeout = 5 * time.Second
+
+	// MinProbeInterval is the minimum interval between probe requests of the same peer.
+	MinProbeInterval = 10 * time.Second
+)
+
+// AddressKind is the kind of an advertised address.
+type AddressKind string
+
+const (
+	// AddressKindConsensus is the kind of consensus addresses.
+	AddressKindConsensus AddressKind = "consensus"
+	// AddressKindP2P is the kind of P2P addresses.
+	AddressKindP2P AddressKind = "p2p"
+	// AddressKindTLS is the kind of TLS (gRPC) addresses.
+	AddressKindTLS AddressKind = "tls"
+)
+
+// ProbeAddress is an address that should be probed.
+type ProbeAddress struct {
+	// Kind is the kind of the address.
+	Kind AddressKind `json:"kind"`
+	// Address is the address to connect to.
+	Address node.Address `json:"address"`
+}
+
+// ProbeRequest is a Probe request.
+type ProbeRequest struct {
+	Addresses []ProbeAddress `json:"addresses"`
+}
+
+// ProbeResult is the result of probing a single address.
+type ProbeResult struct {
+	// Reachable is true iff a connection to the address could be established.
+	Reachable bool `json:"reachable"`
+	// Error is the reason why the address is not reachable.
+	Error string `json:"error,omitempty"`
+}
+
+// ProbeResponse is a response to a Probe request.
+type ProbeResponse struct {
+	// Results are the probe results in the same order as the requested addresses.
+	Results []ProbeResult `json:"results"`
+}
@@ -0,0 +1,111 @@
+package selfcheck
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core"
+
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
+)
+
+// Report is the result of an advertised address self-check.
+type Report struct {
+	// Time is the time of the self-check.
+	Time time.Time `json:"time"`
+
+	// Peers are the peers that probed the addresses.
+	Peers []string `json:"peers"`
+
+	// Addresses are the aggregated results for each advertised address.
+	Addresses []AddressReport `json:"addresses"`
+}
+
+// AddressReport is the aggregated self-check result of a single advertised address.
+type AddressReport struct {
+	ProbeAddress
+
+	// Critical is true iff the node's roles require addresses of this kind.
+	Critical bool `json:"critical"`
+
+	// Reachable is true iff at least one of the peers could connect to the address.
+	Reachable bool `json:"reachable"`
+
+	// Errors are the errors reported by the peers that could not connect to the address.
+	Errors []string `json:"errors,omitempty"`
+}
+
+func newReport(n *node.Node, addrs []ProbeAddress) *Report {
+	report := Report{
+		Time:      time.Now(),
+		Addresses: make([]AddressReport, 0, len(addrs)),
+	}
+	for _, addr := range addrs {
+		report.Addresses = append(report.Addresses, AddressReport{
+			ProbeAddress: addr,
+			Critical:     isCritical(n, addr.Kind),
+		})
+	}
+	return &report
+}
+
+func (r *Report) addResults(peerID core.PeerID, results []ProbeResult) {
+	r.Peers = append(r.Peers, peerID.String())
+	for i, result := range results {
+		if result.Reachable {
+			r.Addresses[i].Reachable = true
+			continue
+		}
+		r.Addresses[i].Errors = append(r.Addresses[i].Errors, fmt.Sprintf("%s: %s", peerID, result.Error))
+	}
+}
+
+// Unreachable returns the critical addresses that none of the peers could connect to.
+func (r *Report) Unreachable() []AddressReport {
+	var unreachable []AddressReport
+	for _, addr := range r.Addresses {
+		if addr.Critical && !addr.Reachable {
+			unreachable = append(unreachable, addr)
+		}
+	}
+	return unreachable
+}
+
+// Err returns an error in case any critical address is unreachable.
+func (r *Report) Err() error {
+	unreachable := r.Unreachable()
+	if len(unreachable) == 0 {
+		return nil
+	}
+
+	addrs := make([]string, 0, len(unreachable))
+	for _, addr := range unreachable {
+		addrs = append(addrs, fmt.Sprintf("%s %s", addr.Kind, addr.Address.String()))
+	}
+	return fmt.Errorf("%w: %s", ErrUnreachable, strings.Join(addrs, ", "))
+}
+
+// AddressesFromDescriptor returns the addresses advertised in the given node descriptor.
+func AddressesFromDescriptor(n *node.Node) []ProbeAddress {
+	addrs := make([]ProbeAddress, 0, len(n.Consensus.Addresses)+len(n.P2P.Addresses))
+	for _, addr := range n.Consensus.Addresses {
+		addrs = append(addrs, ProbeAddress{Kind: AddressKindConsensus, Address: addr.Address})
+	}
+	for _, addr := range n.P2P.Addresses {
+		addrs = append(addrs, ProbeAddress{Kind: AddressKindP2P, Address: addr})
+	}
+	return addrs
+}
+
+func isCritical(n *node.Node, kind AddressKind) bool {
+	switch kind {
+	case AddressKindConsensus:
+		return n.HasRoles(registry.ConsensusAddressRequiredRoles)
+	case AddressKindP2P:
+		return n.HasRoles(registry.P2PAddressRequiredRoles)
+	default:
+		return false
+	}
+}
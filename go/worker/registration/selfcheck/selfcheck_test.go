@@ -0,0 +1,126 @@
+package selfcheck
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p/core"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/node"
+)
+
+const testChainContext = "selfcheck-test"
+
+type testP2P struct {
+	host host.Host
+}
+
+func (p *testP2P) BlockPeer(core.PeerID) {}
+
+func (p *testP2P) RegisterProtocol(core.ProtocolID, int, int) {}
+
+func (p *testP2P) Host() core.Host {
+	return p.host
+}
+
+func newTestHost(t *testing.T) host.Host {
+	h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err, "libp2p.New")
+	t.Cleanup(func() { _ = h.Close() })
+	return h
+}
+
+func newTestClient(t *testing.T, prober host.Host) (Client, host.Host) {
+	h := newTestHost(t)
+	client := NewClient(&testP2P{h}, testChainContext, []peer.AddrInfo{
+		{ID: prober.ID(), Addrs: prober.Addrs()},
+	})
+	return client, h
+}
+
+func newTestAddress(t *testing.T, live bool) node.Address {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "Listen")
+
+	switch live {
+	case true:
+		t.Cleanup(func() { _ = listener.Close() })
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.Close()
+			}
+		}()
+	case false:
+		// Close the listener so that nothing is listening on the port.
+		_ = listener.Close()
+	}
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return node.Address{IP: addr.IP, Port: int64(addr.Port)}
+}
+
+func TestSelfCheck(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	liveAddr := newTestAddress(t, true)
+	deadAddr := newTestAddress(t, false)
+
+	// The node advertises a live consensus address and a live and a dead P2P address.
+	nodeDesc := node.Node{
+		Roles: node.RoleValidator | node.RoleComputeWorker,
+	}
+	nodeDesc.Consensus.Addresses = []node.ConsensusAddress{{Address: liveAddr}}
+	nodeDesc.P2P.Addresses = []node.Address{liveAddr, deadAddr}
+
+	// The prober only serves probe requests of the authorized node.
+	prober := newTestHost(t)
+	client, clientHost := newTestClient(t, prober)
+	server := NewServer(testChainContext, []core.PeerID{clientHost.ID()})
+	prober.SetStreamHandler(server.Protocol(), server.HandleStream)
+
+	report, err := client.Check(ctx, &nodeDesc)
+	require.NoError(err, "Check")
+	require.Equal([]string{prober.ID().String()}, report.Peers)
+	require.Len(report.Addresses, 3)
+
+	require.Equal(AddressKindConsensus, report.Addresses[0].Kind)
+	require.True(report.Addresses[0].Critical)
+	require.True(report.Addresses[0].Reachable, "live consensus address should be reachable")
+	require.Equal(AddressKindP2P, report.Addresses[1].Kind)
+	require.True(report.Addresses[1].Reachable, "live P2P address should be reachable")
+	require.Equal(AddressKindP2P, report.Addresses[2].Kind)
+	require.False(report.Addresses[2].Reachable, "dead P2P address should not be reachable")
+	require.Len(report.Addresses[2].Errors, 1)
+
+	unreachable := report.Unreachable()
+	require.Len(unreachable, 1)
+	require.Equal(deadAddr, unreachable[0].Address)
+	require.ErrorIs(report.Err(), ErrUnreachable)
+
+	// Repeated probe requests should be rate limited.
+	_, err = client.Check(ctx, &nodeDesc)
+	require.ErrorIs(err, ErrNoResponses, "repeated probe requests should be refused")
+
+	// Unauthorized nodes should not be able to request probes.
+	client, _ = newTestClient(t, prober)
+	_, err = client.Check(ctx, &nodeDesc)
+	require.ErrorIs(err, ErrNoResponses, "unauthorized probe requests should be refused")
+
+	// Addresses not required by the node's roles are not critical.
+	nodeDesc.Roles = node.RoleObserver
+	nodeDesc.P2P.Addresses = []node.Address{deadAddr}
+	report = newReport(&nodeDesc, AddressesFromDescriptor(&nodeDesc))
+	report.addResults(prober.ID(), []ProbeResult{{Reachable: true}, {Error: "connection refused"}})
+	require.Empty(report.Unreachable())
+	require.NoError(report.Err())
+}
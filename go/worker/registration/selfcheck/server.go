@@ -0,0 +1,116 @@
+package selfcheck
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core"
+
+	"github.com/oasisprotocol/oasis-core/go/common/cbor"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/p2p/protocol"
+	"github.com/oasisprotocol/oasis-core/go/p2p/rpc"
+)
+
+type service struct {
+	logger *logging.Logger
+
+	authorized map[core.PeerID]struct{}
+
+	mu        sync.Mutex
+	lastProbe map[core.PeerID]time.Time
+}
+
+func (s *service) HandleRequest(ctx context.Context, method string, body cbor.RawMessage) (any, error) {
+	switch method {
+	case MethodProbe:
+		var rq ProbeRequest
+		if err := cbor.Unmarshal(body, &rq); err != nil {
+			return nil, rpc.ErrBadRequest
+		}
+
+		return s.handleProbe(ctx, &rq)
+	default:
+		return nil, rpc.ErrMethodNotSupported
+	}
+}
+
+func (s *service) handleProbe(ctx context.Context, request *ProbeRequest) (*ProbeResponse, error) {
+	// Streams are authenticated by the transport, so the peer ID can be trusted.
+	peerID, ok := rpc.PeerIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	if _, ok = s.authorized[peerID]; !ok {
+		return nil, ErrUnauthorized
+	}
+	if len(request.Addresses) > MaxProbeAddresses {
+		return nil, rpc.ErrBadRequest
+	}
+	if !s.allowProbe(peerID) {
+		return nil, ErrRateLimited
+	}
+
+	s.logger.Debug("probing addresses",
+		"peer_id", peerID,
+		"num_addresses", len(request.Addresses),
+	)
+
+	rsp := ProbeResponse{
+		Results: make([]ProbeResult, len(request.Addresses)),
+	}
+	var wg sync.WaitGroup
+	for i, addr := range request.Addresses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rsp.Results[i] = probe(ctx, addr)
+		}()
+	}
+	wg.Wait()
+
+	return &rsp, nil
+}
+
+// allowProbe records a probe request of the given peer and returns true iff the peer did not
+// request probes within the last MinProbeInterval.
+func (s *service) allowProbe(peerID core.PeerID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := s.lastProbe[peerID]; ok && now.Sub(last) < MinProbeInterval {
+		return false
+	}
+	s.lastProbe[peerID] = now
+	return true
+}
+
+func probe(ctx context.Context, addr ProbeAddress) ProbeResult {
+	dialer := net.Dialer{Timeout: ProbeTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr.Address.String())
+	if err != nil {
+		return ProbeResult{Error: err.Error()}
+	}
+	_ = conn.Close()
+	return ProbeResult{Reachable: true}
+}
+
+// NewServer creates a new self-check protocol server.
+//
+// Only the given peers are allowed to request address probes and each of them at most once per
+// MinProbeInterval.
+func NewServer(chainContext string, authorized []core.PeerID) rpc.Server {
+	s := &service{
+		logger:     logging.GetLogger("worker/registration/selfcheck"),
+		authorized: make(map[core.PeerID]struct{}, len(authorized)),
+		lastProbe:  make(map[core.PeerID]time.Time),
+	}
+	for _, peerID := range authorized {
+		s.authorized[peerID] = struct{}{}
+	}
+
+	return rpc.NewServer(protocol.NewProtocolID(chainContext, SelfCheckProtocolID, SelfCheckProtocolVersion), s)
+}
@@ -32,8 +32,10 @@ import (
 	p2p "github.com/oasisprotocol/oasis-core/go/p2p/api"
 	registry "github.com/oasisprotocol/oasis-core/go/registry/api"
 	runtimeRegistry "github.com/oasisprotocol/oasis-core/go/runtime/registry"
+	scheduler "github.com/oasisprotocol/oasis-core/go/scheduler/api"
 	sentryClient "github.com/oasisprotocol/oasis-core/go/sentry/client"
 	workerCommon "github.com/oasisprotocol/oasis-core/go/worker/common"
+	"github.com/oasisprotocol/oasis-core/go/worker/registration/selfcheck"
 )
 
 const (
@@ -209,6 +211,12 @@ type Worker struct { // nolint: maligned
 	roleProviders []*roleProvider
 	registerCh    chan struct{}
 
+	selfCheck        selfcheck.Client
+	selfCheckEnforce bool
+
+	keysLock    sync.Mutex
+	rotatedKeys *control.RotatedKeyRegistry
+
 	status control.RegistrationStatus
 }
 
@@ -582,7 +590,7 @@ func (w *Worker) metricsWorker() {
 		}
 
 		// Frozen metric.
-		switch nodeStatus.IsFrozen() {
+		switch nodeStatus.IsFrozen() || nodeStatus.IsAdminFrozen(epoch) {
 		case true:
 			workerNodeStatusFrozen.Set(1)
 		case false:
@@ -736,6 +744,17 @@ func (w *Worker) GetRegistrationStatus(ctx context.Context) (*control.Registrati
 	}
 	status.NodeStatus = ns
 
+	if w.consensus != nil {
+		elig, err := w.consensus.Scheduler().GetElectionEligibility(ctx, &scheduler.GetElectionEligibilityRequest{
+			Height: consensus.HeightLatest,
+			NodeID: status.Descriptor.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		status.ElectionEligibility = elig
+	}
+
 	return status, nil
 }
 
@@ -918,6 +937,11 @@ func (w *Worker) registerNode(epoch beacon.EpochTime, hook RegisterNodeHook) (er
 		nodeDesc.P2P.Addresses = w.p2p.Addresses()
 	}
 
+	// Make sure that the advertised addresses are reachable from the outside.
+	if err = w.checkAdvertisedAddresses(&nodeDesc); err != nil {
+		return err
+	}
+
 	nodeSigners := []signature.Signer{
 		w.registrationSigner,
 		w.identity.P2PSigner,
@@ -1100,10 +1124,15 @@ func New(
 		consensus:          consensus,
 		p2p:                p2p,
 		registerCh:         make(chan struct{}, 1),
+		rotatedKeys:        control.NewRotatedKeyRegistry(),
 	}
 
 	w.storedDeregister = storedDeregister
 
+	if err = w.initSelfCheck(); err != nil {
+		return nil, err
+	}
+
 	if config.GlobalConfig.Consensus.Validator || config.GlobalConfig.Mode == config.ModeValidator {
 		rp, err := w.NewRoleProvider(node.RoleValidator)
 		if err != nil {
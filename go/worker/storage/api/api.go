@@ -2,10 +2,12 @@ package api
 
 import (
 	"context"
+	"time"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/errors"
 	storage "github.com/oasisprotocol/oasis-core/go/storage/api"
+	nodedb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
 )
 
 // ModuleName is the storage worker module name.
@@ -22,6 +24,7 @@ const (
 	StatusSyncStartCheck      StorageWorkerStatus = "sync start check"
 	StatusSyncingCheckpoints  StorageWorkerStatus = "syncing checkpoints"
 	StatusSyncingRounds       StorageWorkerStatus = "syncing rounds"
+	StatusRootMismatch        StorageWorkerStatus = "root mismatch"
 )
 
 var (
@@ -30,6 +33,9 @@ var (
 	// ErrCantPauseCheckpointer is the error returned when trying to pause the checkpointer without
 	// setting the debug flag.
 	ErrCantPauseCheckpointer = errors.New(ModuleName, 2, "worker/storage: pausing checkpointer only available in debug mode")
+	// ErrRootMismatch is the error returned when the local storage roots diverge from consensus
+	// and the runtime's storage is not served.
+	ErrRootMismatch = errors.New(ModuleName, 3, "worker/storage: local roots diverge from consensus")
 )
 
 // StorageWorker is the storage worker control API interface.
@@ -66,4 +72,74 @@ type Status struct {
 
 	// LastFinalizedRound is the last synced and finalized round.
 	LastFinalizedRound uint64 `json:"last_finalized_round"`
+
+	// Mirror is true iff the runtime state is only being mirrored.
+	Mirror bool `json:"mirror,omitempty"`
+
+	// Database are the local state database statistics.
+	Database *nodedb.Stats `json:"database,omitempty"`
+
+	// Restore is the progress of the checkpoint restore that is in progress (if any).
+	Restore *nodedb.MultipartProgress `json:"restore,omitempty"`
+
+	// Health is the health of the local state database (if the backend reports it).
+	Health *nodedb.Health `json:"health,omitempty"`
+
+	// RootDivergence is the detected divergence of local roots from consensus (if any). While
+	// roots diverge, the runtime's storage is not served.
+	RootDivergence *RootDivergence `json:"root_divergence,omitempty"`
+
+	// LastRestore is the report of the last finished checkpoint restore (if any).
+	LastRestore *CheckpointRestoreReport `json:"last_restore,omitempty"`
+}
+
+// RootDivergence is a divergence of local storage roots from consensus.
+type RootDivergence struct {
+	// Version is the earliest checked version whose local roots diverge from consensus.
+	Version uint64 `json:"version"`
+
+	// LastMatchingVersion is the latest version before the diverging version whose local roots
+	// match consensus, if any was found.
+	LastMatchingVersion *uint64 `json:"last_matching_version,omitempty"`
+}
+
+// CheckpointRestoreReport is a summary of a checkpoint restore session.
+type CheckpointRestoreReport struct {
+	// Started is the time when the restore started.
+	Started time.Time `json:"started"`
+
+	// Finished is the time when the restore finished.
+	Finished time.Time `json:"finished"`
+
+	// Successful is true iff the restore completed successfully.
+	Successful bool `json:"successful"`
+
+	// Round is the round that has been restored in case the restore was successful.
+	Round uint64 `json:"round,omitempty"`
+
+	// Providers are the per-provider statistics of the peers that served checkpoint chunks.
+	Providers []CheckpointProviderStats `json:"providers,omitempty"`
+}
+
+// CheckpointProviderStats are the statistics of a peer that served checkpoint chunks during a
+// checkpoint restore.
+type CheckpointProviderStats struct {
+	// PeerID is the peer identifier of the provider.
+	PeerID string `json:"peer_id"`
+
+	// Chunks is the number of chunks served by the provider that passed verification.
+	Chunks uint64 `json:"chunks"`
+
+	// Bytes is the total size of the chunks served by the provider that passed verification.
+	Bytes uint64 `json:"bytes"`
+
+	// BadChunks is the number of chunks served by the provider that failed verification.
+	BadChunks uint64 `json:"bad_chunks,omitempty"`
+
+	// Latency is the total latency of the chunk requests served by the provider.
+	Latency time.Duration `json:"latency"`
+
+	// Banned is true iff the provider has been banned for the rest of the restore due to serving
+	// too many bad chunks.
+	Banned bool `json:"banned,omitempty"`
 }
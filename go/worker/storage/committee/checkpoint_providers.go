@@ -0,0 +1,125 @@
+package committee
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core"
+
+	"github.com/oasisprotocol/oasis-core/go/p2p/rpc"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
+)
+
+// defaultMaxBadChunksPerProvider is the default number of bad chunks after which a provider is
+// banned for the rest of the restore session.
+const defaultMaxBadChunksPerProvider = 3
+
+type chunkOutcome uint8
+
+const (
+	chunkOutcomeOk chunkOutcome = iota
+	chunkOutcomeBad
+)
+
+// restoreAccounting keeps per-provider accounting of checkpoint chunk requests for the duration
+// of a checkpoint restore session.
+type restoreAccounting struct {
+	sync.Mutex
+
+	maxBadChunks uint64
+	started      time.Time
+	providers    map[core.PeerID]*api.CheckpointProviderStats
+}
+
+func newRestoreAccounting(maxBadChunks uint64) *restoreAccounting {
+	if maxBadChunks == 0 {
+		maxBadChunks = defaultMaxBadChunksPerProvider
+	}
+	return &restoreAccounting{
+		maxBadChunks: maxBadChunks,
+		started:      time.Now(),
+		providers:    make(map[core.PeerID]*api.CheckpointProviderStats),
+	}
+}
+
+// record records the outcome of a chunk request served by the given provider. It returns true
+// iff the provider has just been banned as a result.
+func (ra *restoreAccounting) record(pf rpc.PeerFeedback, size int, latency time.Duration, outcome chunkOutcome) bool {
+	ra.Lock()
+	defer ra.Unlock()
+
+	peerID := pf.PeerID()
+	stats := ra.providers[peerID]
+	if stats == nil {
+		stats = &api.CheckpointProviderStats{
+			PeerID: peerID.String(),
+		}
+		ra.providers[peerID] = stats
+	}
+	stats.Latency += latency
+
+	switch outcome {
+	case chunkOutcomeOk:
+		stats.Chunks++
+		stats.Bytes += uint64(size)
+		return false
+	default:
+		stats.BadChunks++
+		if stats.Banned || stats.BadChunks < ra.maxBadChunks {
+			return false
+		}
+		stats.Banned = true
+		return true
+	}
+}
+
+// isBanned returns true iff the given provider has been banned.
+func (ra *restoreAccounting) isBanned(peerID core.PeerID) bool {
+	ra.Lock()
+	defer ra.Unlock()
+
+	stats := ra.providers[peerID]
+	return stats != nil && stats.Banned
+}
+
+// filterCheckpoint returns the given checkpoint with banned providers removed from its peers.
+func (ra *restoreAccounting) filterCheckpoint(cp *storageSync.Checkpoint) *storageSync.Checkpoint {
+	peers := make([]rpc.PeerFeedback, 0, len(cp.Peers))
+	for _, pf := range cp.Peers {
+		if ra.isBanned(pf.PeerID()) {
+			continue
+		}
+		peers = append(peers, pf)
+	}
+	return &storageSync.Checkpoint{
+		Metadata: cp.Metadata,
+		Peers:    peers,
+	}
+}
+
+// report returns the restore report, with providers sorted by the number of served chunks.
+func (ra *restoreAccounting) report(successful bool, round uint64) *api.CheckpointRestoreReport {
+	ra.Lock()
+	defer ra.Unlock()
+
+	report := &api.CheckpointRestoreReport{
+		Started:    ra.started,
+		Finished:   time.Now(),
+		Successful: successful,
+	}
+	if successful {
+		report.Round = round
+	}
+	for _, stats := range ra.providers {
+		report.Providers = append(report.Providers, *stats)
+	}
+	sort.Slice(report.Providers, func(i, j int) bool {
+		if report.Providers[i].Chunks == report.Providers[j].Chunks {
+			return report.Providers[i].PeerID < report.Providers[j].PeerID
+		}
+		return report.Providers[i].Chunks > report.Providers[j].Chunks
+	})
+	return report
+}
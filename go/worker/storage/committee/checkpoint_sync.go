@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/oasisprotocol/oasis-core/go/config"
 	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
 	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
@@ -40,6 +41,10 @@ type CheckpointSyncConfig struct {
 
 	// ChunkFetcherCount specifies the number of parallel checkpoint chunk fetchers.
 	ChunkFetcherCount uint
+
+	// MaxBadChunksPerProvider specifies the number of chunks failing verification after which a
+	// provider is banned for the rest of the restore session. If zero, a default is used.
+	MaxBadChunksPerProvider uint64
 }
 
 // Validate performs configuration checks.
@@ -83,6 +88,7 @@ func (n *Node) checkpointChunkFetcher(
 	chunkDispatchCh chan *chunk,
 	chunkReturnCh chan *chunk,
 	errorCh chan int,
+	acct *restoreAccounting,
 ) {
 	for {
 		var chunk *chunk
@@ -96,16 +102,28 @@ func (n *Node) checkpointChunkFetcher(
 			}
 		}
 
+		// Only fetch chunks from providers that have not been banned.
+		cp := acct.filterCheckpoint(chunk.checkpoint)
+		if len(cp.Peers) == 0 {
+			n.logger.Error("all checkpoint providers have been banned",
+				"checkpoint_root", chunk.checkpoint.Root,
+			)
+			errorCh <- checkpointStatusNext
+			return
+		}
+
 		chunkCtx, cancel := context.WithTimeout(ctx, cpRestoreTimeout)
 		defer cancel()
 
 		// Fetch chunk from peers.
+		start := time.Now()
 		rsp, pf, err := n.storageSync.GetCheckpointChunk(chunkCtx, &storageSync.GetCheckpointChunkRequest{
 			Version: chunk.Version,
 			Root:    chunk.Root,
 			Index:   chunk.Index,
 			Digest:  chunk.Digest,
-		}, chunk.checkpoint)
+		}, cp)
+		latency := time.Since(start)
 		if err != nil {
 			n.logger.Error("failed to fetch chunk from peers",
 				"err", err,
@@ -121,6 +139,7 @@ func (n *Node) checkpointChunkFetcher(
 
 		switch {
 		case done:
+			acct.record(pf, len(rsp.Chunk), latency, chunkOutcomeOk)
 			pf.RecordSuccess()
 			// Signal to the toplevel handler that we're done.
 			chunkReturnCh <- nil
@@ -129,14 +148,23 @@ func (n *Node) checkpointChunkFetcher(
 			n.logger.Error("chunk restoration failed",
 				"chunk", chunk.Index,
 				"root", chunk.Root,
+				"peer_id", pf.PeerID(),
 				"err", err,
 			)
 
 			switch {
 			case errors.Is(err, checkpoint.ErrChunkCorrupted):
-				pf.RecordFailure()
+				if acct.record(pf, len(rsp.Chunk), latency, chunkOutcomeBad) {
+					n.logger.Warn("banning checkpoint provider for serving bad chunks",
+						"peer_id", pf.PeerID(),
+					)
+					pf.RecordBadPeer()
+				} else {
+					pf.RecordFailure()
+				}
 				chunkReturnCh <- chunk
 			case errors.Is(err, checkpoint.ErrChunkProofVerificationFailed):
+				acct.record(pf, len(rsp.Chunk), latency, chunkOutcomeBad)
 				pf.RecordBadPeer()
 
 				// Also punish all peers that advertised this checkpoint.
@@ -151,12 +179,13 @@ func (n *Node) checkpointChunkFetcher(
 				return
 			}
 		default:
+			acct.record(pf, len(rsp.Chunk), latency, chunkOutcomeOk)
 			pf.RecordSuccess()
 		}
 	}
 }
 
-func (n *Node) handleCheckpoint(check *storageSync.Checkpoint, maxParallelRequests uint) (cpStatus int, rerr error) {
+func (n *Node) handleCheckpoint(check *storageSync.Checkpoint, maxParallelRequests uint, acct *restoreAccounting) (cpStatus int, rerr error) {
 	if err := n.localStorage.Checkpointer().StartRestore(n.ctx, check.Metadata); err != nil {
 		// Any previous restores were already aborted by the driver up the call stack, so
 		// things should have been going smoothly here; bail.
@@ -193,7 +222,7 @@ func (n *Node) handleCheckpoint(check *storageSync.Checkpoint, maxParallelReques
 		workerGroup.Add(1)
 		go func() {
 			defer workerGroup.Done()
-			n.checkpointChunkFetcher(ctx, chunkDispatchCh, chunkReturnCh, errorCh)
+			n.checkpointChunkFetcher(ctx, chunkDispatchCh, chunkReturnCh, errorCh, acct)
 		}()
 	}
 	go func() {
@@ -336,12 +365,26 @@ func (n *Node) checkCheckpointUsable(cp *storageSync.Checkpoint, remainingMask o
 	return false
 }
 
-func (n *Node) syncCheckpoints(genesisRound uint64, wantOnlyGenesis bool) (*blockSummary, error) {
+func (n *Node) syncCheckpoints(genesisRound uint64, wantOnlyGenesis bool) (summary *blockSummary, rerr error) {
 	// Store roots and round info for checkpoints that finished syncing.
 	// Round and namespace info will get overwritten as rounds are skipped
 	// for errors, driven by remainingRoots.
 	var syncState blockSummary
 
+	// Keep per-provider accounting for the whole restore session and report it once done.
+	acct := newRestoreAccounting(n.checkpointSyncCfg.MaxBadChunksPerProvider)
+	defer func() {
+		report := acct.report(rerr == nil, syncState.Round)
+		n.logger.Info("checkpoint restore finished",
+			"successful", report.Successful,
+			"providers", report.Providers,
+		)
+
+		n.statusLock.Lock()
+		n.lastRestore = report
+		n.statusLock.Unlock()
+	}()
+
 	// Fetch checkpoints from peers.
 	cps, err := n.getCheckpointList()
 	if err != nil {
@@ -371,6 +414,10 @@ func (n *Node) syncCheckpoints(genesisRound uint64, wantOnlyGenesis bool) (*bloc
 		if !multipartRunning {
 			return
 		}
+		if config.GlobalConfig.Storage.CheckpointSyncResume && n.ctx.Err() != nil {
+			// Keep the multipart restore so that it can be resumed after restart.
+			return
+		}
 		if err := n.localStorage.NodeDB().AbortMultipartInsert(); err != nil {
 			n.logger.Error("error aborting multipart restore on exit from syncer",
 				"err", err,
@@ -389,11 +436,19 @@ func (n *Node) syncCheckpoints(genesisRound uint64, wantOnlyGenesis bool) (*bloc
 			// previous retries. Aborting multipart works with no multipart in
 			// progress too.
 			multipartRunning = false
-			if err := n.localStorage.NodeDB().AbortMultipartInsert(); err != nil {
-				return nil, fmt.Errorf("error aborting previous multipart restore: %w", err)
-			}
-			if err := n.localStorage.NodeDB().StartMultipartInsert(check.Root.Version); err != nil {
-				return nil, fmt.Errorf("error starting multipart insert for round %d: %w", check.Root.Version, err)
+			switch n.localStorage.NodeDB().GetMultipartVersion() {
+			case check.Root.Version:
+				// An interrupted multipart restore of the same round has been kept, resume it.
+				n.logger.Info("resuming interrupted checkpoint restore",
+					"version", check.Root.Version,
+				)
+			default:
+				if err := n.localStorage.NodeDB().AbortMultipartInsert(); err != nil {
+					return nil, fmt.Errorf("error aborting previous multipart restore: %w", err)
+				}
+				if err := n.localStorage.NodeDB().StartMultipartInsert(check.Root.Version); err != nil {
+					return nil, fmt.Errorf("error starting multipart insert for round %d: %w", check.Root.Version, err)
+				}
 			}
 			multipartRunning = true
 			remainingRoots = outstandingMaskFull
@@ -417,7 +472,7 @@ func (n *Node) syncCheckpoints(genesisRound uint64, wantOnlyGenesis bool) (*bloc
 			}
 		}
 
-		status, err := n.handleCheckpoint(check, n.checkpointSyncCfg.ChunkFetcherCount)
+		status, err := n.handleCheckpoint(check, n.checkpointSyncCfg.ChunkFetcherCount, acct)
 		switch status {
 		case checkpointStatusDone:
 			n.logger.Info("successfully restored from checkpoint", "root", check.Root, "mask", mask)
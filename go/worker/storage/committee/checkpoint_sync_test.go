@@ -0,0 +1,252 @@
+package committee
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/libp2p/go-libp2p/core"
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/p2p/rpc"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
+)
+
+type testPeerFeedback struct {
+	sync.Mutex
+
+	peerID    core.PeerID
+	successes int
+	failures  int
+	badPeer   int
+}
+
+func (pf *testPeerFeedback) RecordSuccess() {
+	pf.Lock()
+	defer pf.Unlock()
+	pf.successes++
+}
+
+func (pf *testPeerFeedback) RecordFailure() {
+	pf.Lock()
+	defer pf.Unlock()
+	pf.failures++
+}
+
+func (pf *testPeerFeedback) RecordBadPeer() {
+	pf.Lock()
+	defer pf.Unlock()
+	pf.badPeer++
+}
+
+func (pf *testPeerFeedback) PeerID() core.PeerID {
+	return pf.peerID
+}
+
+// testChunkProviders is a storage sync client that serves checkpoint chunks from fake providers
+// in a round-robin fashion.
+type testChunkProviders struct {
+	sync.Mutex
+
+	chunks   [][]byte
+	corrupt  map[core.PeerID]bool
+	feedback map[core.PeerID]*testPeerFeedback
+	requests map[core.PeerID]int
+	next     int
+}
+
+func (p *testChunkProviders) GetDiff(context.Context, *storageSync.GetDiffRequest) (*storageSync.GetDiffResponse, rpc.PeerFeedback, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
+
+func (p *testChunkProviders) GetCheckpoints(context.Context, *storageSync.GetCheckpointsRequest) ([]*storageSync.Checkpoint, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (p *testChunkProviders) GetCheckpointChunk(
+	_ context.Context,
+	request *storageSync.GetCheckpointChunkRequest,
+	cp *storageSync.Checkpoint,
+) (*storageSync.GetCheckpointChunkResponse, rpc.PeerFeedback, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	peerID := cp.Peers[p.next%len(cp.Peers)].PeerID()
+	p.next++
+	p.requests[peerID]++
+
+	chunk := p.chunks[request.Index]
+	if p.corrupt[peerID] {
+		chunk = []byte("corrupted chunk")
+	}
+	return &storageSync.GetCheckpointChunkResponse{Chunk: chunk}, p.feedback[peerID], nil
+}
+
+// testRestorer is a checkpoint restorer that only verifies chunk digests.
+type testRestorer struct {
+	checkpoint.CreateRestorer
+
+	sync.Mutex
+	cp       *checkpoint.Metadata
+	restored map[uint64]bool
+}
+
+func (r *testRestorer) StartRestore(_ context.Context, cp *checkpoint.Metadata) error {
+	r.Lock()
+	defer r.Unlock()
+	r.cp = cp
+	r.restored = make(map[uint64]bool)
+	return nil
+}
+
+func (r *testRestorer) AbortRestore(context.Context) error {
+	r.Lock()
+	defer r.Unlock()
+	r.cp = nil
+	return nil
+}
+
+func (r *testRestorer) RestoreChunk(_ context.Context, index uint64, rd io.Reader) (bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return false, err
+	}
+	if h := hash.NewFromBytes(data); !h.Equal(&r.cp.Chunks[index]) {
+		return false, checkpoint.ErrChunkCorrupted
+	}
+	r.restored[index] = true
+	if len(r.restored) == len(r.cp.Chunks) {
+		r.cp = nil
+		return true, nil
+	}
+	return false, nil
+}
+
+type testLocalBackend struct {
+	storageApi.LocalBackend
+
+	restorer *testRestorer
+}
+
+func (b *testLocalBackend) Checkpointer() checkpoint.CreateRestorer {
+	return b.restorer
+}
+
+func newTestCheckpointRestore(numChunks int, providers []core.PeerID, corrupt ...core.PeerID) (*Node, *testChunkProviders, *storageSync.Checkpoint) {
+	cp := &storageSync.Checkpoint{
+		Metadata: &checkpoint.Metadata{
+			Version: 1,
+			Root: node.Root{
+				Namespace: testNs,
+				Version:   10,
+				Type:      storageApi.RootTypeState,
+			},
+		},
+	}
+	cp.Root.Hash.Empty()
+
+	p := &testChunkProviders{
+		corrupt:  make(map[core.PeerID]bool),
+		feedback: make(map[core.PeerID]*testPeerFeedback),
+		requests: make(map[core.PeerID]int),
+	}
+	for i := 0; i < numChunks; i++ {
+		chunk := []byte(fmt.Sprintf("chunk %d", i))
+		p.chunks = append(p.chunks, chunk)
+		cp.Chunks = append(cp.Chunks, hash.NewFromBytes(chunk))
+	}
+	for _, peerID := range providers {
+		pf := &testPeerFeedback{peerID: peerID}
+		p.feedback[peerID] = pf
+		cp.Peers = append(cp.Peers, pf)
+	}
+	for _, peerID := range corrupt {
+		p.corrupt[peerID] = true
+	}
+
+	n := &Node{
+		logger:       logging.GetLogger("worker/storage/committee/test"),
+		localStorage: &testLocalBackend{restorer: &testRestorer{}},
+		storageSync:  p,
+		ctx:          context.Background(),
+	}
+	return n, p, cp
+}
+
+func TestCheckpointRestoreProviderAccounting(t *testing.T) {
+	require := require.New(t)
+
+	good1, good2, bad := core.PeerID("good provider 1"), core.PeerID("good provider 2"), core.PeerID("bad provider")
+	n, p, cp := newTestCheckpointRestore(12, []core.PeerID{good1, good2, bad}, bad)
+
+	acct := newRestoreAccounting(2)
+	status, err := n.handleCheckpoint(cp, 1, acct)
+	require.NoError(err, "handleCheckpoint")
+	require.Equal(checkpointStatusDone, status, "restore should complete using the remaining providers")
+
+	// The bad provider should not have been asked again after being banned.
+	require.Equal(2, p.requests[bad])
+	require.Equal(1, p.feedback[bad].failures)
+	require.Equal(1, p.feedback[bad].badPeer, "banned provider should be reported to peer scoring")
+	require.Zero(p.feedback[bad].successes)
+	require.Zero(p.feedback[good1].badPeer)
+	require.Zero(p.feedback[good2].badPeer)
+
+	report := acct.report(true, cp.Root.Version)
+	require.True(report.Successful)
+	require.EqualValues(10, report.Round)
+	require.Len(report.Providers, 3)
+
+	var chunks, chunkBytes uint64
+	for _, stats := range report.Providers {
+		switch stats.PeerID {
+		case bad.String():
+			require.True(stats.Banned)
+			require.EqualValues(2, stats.BadChunks)
+			require.Zero(stats.Chunks)
+		default:
+			require.False(stats.Banned)
+			require.Zero(stats.BadChunks)
+			require.NotZero(stats.Chunks)
+		}
+		chunks += stats.Chunks
+		chunkBytes += stats.Bytes
+	}
+	require.EqualValues(12, chunks, "all chunks should be accounted for")
+	var expectedBytes uint64
+	for _, chunk := range p.chunks {
+		expectedBytes += uint64(len(chunk))
+	}
+	require.Equal(expectedBytes, chunkBytes)
+	require.Equal(bad.String(), report.Providers[2].PeerID, "providers should be sorted by contribution")
+}
+
+func TestCheckpointRestoreAllProvidersBanned(t *testing.T) {
+	require := require.New(t)
+
+	bad := core.PeerID("bad provider")
+	n, p, cp := newTestCheckpointRestore(4, []core.PeerID{bad}, bad)
+
+	acct := newRestoreAccounting(1)
+	status, err := n.handleCheckpoint(cp, 1, acct)
+	require.NoError(err, "handleCheckpoint")
+	require.Equal(checkpointStatusNext, status, "restore should move on to the next checkpoint")
+	require.Equal(1, p.requests[bad])
+
+	report := acct.report(false, cp.Root.Version)
+	require.False(report.Successful)
+	require.Zero(report.Round)
+	require.Len(report.Providers, 1)
+	require.True(report.Providers[0].Banned)
+	require.Equal(bad.String(), report.Providers[0].PeerID)
+}
@@ -0,0 +1,180 @@
+package committee
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	"github.com/oasisprotocol/oasis-core/go/p2p/rpc"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/database"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
+	mkvsDB "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
+)
+
+// testDiffSource is a storage sync client that serves diffs from a local node database.
+type testDiffSource struct {
+	ndb mkvsDB.NodeDB
+}
+
+func (s *testDiffSource) GetDiff(ctx context.Context, request *storageSync.GetDiffRequest) (*storageSync.GetDiffResponse, rpc.PeerFeedback, error) {
+	it, err := s.ndb.GetWriteLog(ctx, request.StartRoot, request.EndRoot)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rsp storageSync.GetDiffResponse
+	for {
+		more, err := it.Next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if !more {
+			break
+		}
+		entry, err := it.Value()
+		if err != nil {
+			return nil, nil, err
+		}
+		rsp.WriteLog = append(rsp.WriteLog, entry)
+	}
+	return &rsp, rpc.NewNopPeerFeedback(), nil
+}
+
+func (s *testDiffSource) GetCheckpoints(context.Context, *storageSync.GetCheckpointsRequest) ([]*storageSync.Checkpoint, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (s *testDiffSource) GetCheckpointChunk(context.Context, *storageSync.GetCheckpointChunkRequest, *storageSync.Checkpoint) (*storageSync.GetCheckpointChunkResponse, rpc.PeerFeedback, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
+
+// commitSourceRound commits and finalizes the I/O and state roots of the given round.
+func commitSourceRound(t *testing.T, ndb mkvsDB.NodeDB, prevState storageApi.Root, round uint64) (storageApi.Root, storageApi.Root) {
+	ctx := context.Background()
+
+	ioTree := mkvs.New(nil, ndb, storageApi.RootTypeIO)
+	err := ioTree.Insert(ctx, []byte(fmt.Sprintf("tx-%d", round)), []byte(fmt.Sprintf("output-%d", round)))
+	require.NoError(t, err, "Insert()")
+	_, ioHash, err := ioTree.Commit(ctx, testNs, round)
+	require.NoError(t, err, "Commit()")
+	ioTree.Close()
+
+	stateTree := mkvs.NewWithRoot(nil, ndb, prevState)
+	for i := range 3 {
+		err = stateTree.Insert(ctx, []byte(fmt.Sprintf("key-%d-%d", round, i)), []byte(fmt.Sprintf("value-%d-%d", round, i)))
+		require.NoError(t, err, "Insert()")
+	}
+	err = stateTree.Insert(ctx, []byte("counter"), []byte(fmt.Sprintf("%d", round)))
+	require.NoError(t, err, "Insert()")
+	if round > 1 {
+		err = stateTree.Remove(ctx, []byte(fmt.Sprintf("key-%d-0", round-1)))
+		require.NoError(t, err, "Remove()")
+	}
+	_, stateHash, err := stateTree.Commit(ctx, testNs, round)
+	require.NoError(t, err, "Commit()")
+	stateTree.Close()
+
+	ioRoot := storageApi.Root{Namespace: testNs, Version: round, Type: storageApi.RootTypeIO, Hash: ioHash}
+	stateRoot := storageApi.Root{Namespace: testNs, Version: round, Type: storageApi.RootTypeState, Hash: stateHash}
+	err = ndb.Finalize([]storageApi.Root{ioRoot, stateRoot})
+	require.NoError(t, err, "Finalize()")
+
+	return ioRoot, stateRoot
+}
+
+// requireTreesEqual requires the given root to contain the same entries in both databases.
+func requireTreesEqual(t *testing.T, expected, actual mkvsDB.NodeDB, root storageApi.Root) {
+	ctx := context.Background()
+
+	entries := func(ndb mkvsDB.NodeDB) map[string]string {
+		tree := mkvs.NewWithRoot(nil, ndb, root)
+		defer tree.Close()
+
+		kvs := make(map[string]string)
+		it := tree.NewIterator(ctx)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			kvs[string(it.Key())] = string(it.Value())
+		}
+		require.NoError(t, it.Err(), "iterating over root %s", root)
+		return kvs
+	}
+
+	expectedEntries := entries(expected)
+	require.NotEmpty(t, expectedEntries, "source root %s should not be empty", root)
+	require.Equal(t, expectedEntries, entries(actual), "mirrored root %s should match the source", root)
+}
+
+func TestMirrorFollowsSource(t *testing.T) {
+	require := require.New(t)
+
+	const numRounds = 5
+
+	// Prepare the source with a genesis state and a number of rounds.
+	source := newTestNodeDB(t)
+	genesis := commitVersions(t, source, nil, []uint64{0}, func(uint64) string { return "genesis" })
+
+	mirror, err := database.New(&storageApi.Config{
+		Backend:      "badger",
+		DB:           t.TempDir(),
+		Namespace:    testNs,
+		MaxCacheSize: 16 * 1024 * 1024,
+		NoFsync:      true,
+		MemoryOnly:   true,
+	})
+	require.NoError(err, "database.New()")
+	t.Cleanup(mirror.Cleanup)
+	mirrorGenesis := commitVersions(t, mirror.NodeDB(), nil, []uint64{0}, func(uint64) string { return "genesis" })
+	require.Equal(genesis, mirrorGenesis, "genesis roots should match")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n := &Node{
+		logger:       logging.GetLogger("worker/storage/committee/test"),
+		localStorage: mirror,
+		storageSync:  &testDiffSource{ndb: source},
+		mirror:       true,
+		diffCh:       make(chan *fetchedDiff, 2),
+		finalizeCh:   make(chan finalizeResult, 1),
+		ctx:          ctx,
+	}
+
+	prevState := genesis
+	for round := uint64(1); round <= numRounds; round++ {
+		ioRoot, stateRoot := commitSourceRound(t, source, prevState, round)
+		prevIO := storageApi.Root{Namespace: testNs, Version: round, Type: storageApi.RootTypeIO}
+		prevIO.Hash.Empty()
+
+		// Fetch and apply the diffs the same way the mirror does when following blocks.
+		n.fetchDiff(round, prevIO, ioRoot)
+		n.fetchDiff(round, prevState, stateRoot)
+		diffs := []*fetchedDiff{<-n.diffCh, <-n.diffCh}
+		for _, diff := range diffs {
+			require.NoError(diff.err, "fetchDiff(%d)", round)
+			require.True(diff.fetched, "diff for round %d should be fetched", round)
+		}
+		for _, err = range n.applyDiffs(diffs) {
+			require.NoError(err, "applyDiffs(%d)", round)
+		}
+
+		n.finalize(&blockSummary{Round: round, Roots: []storageApi.Root{ioRoot, stateRoot}})
+		result := <-n.finalizeCh
+		require.NoError(result.err, "finalize(%d)", round)
+
+		// The mirror should have exactly the same roots as the source.
+		for _, root := range []storageApi.Root{ioRoot, stateRoot} {
+			require.True(mirror.NodeDB().HasRoot(root), "mirror should have root %s", root)
+			requireTreesEqual(t, source, mirror.NodeDB(), root)
+		}
+		latest, ok := mirror.NodeDB().GetLatestVersion()
+		require.True(ok, "mirror should be initialized")
+		require.EqualValues(round, latest, "mirror should be finalized up to the source round")
+
+		prevState = stateRoot
+	}
+}
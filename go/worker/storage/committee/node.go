@@ -17,6 +17,7 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/common/workerpool"
 	"github.com/oasisprotocol/oasis-core/go/config"
 	consensus "github.com/oasisprotocol/oasis-core/go/consensus/api"
+	control "github.com/oasisprotocol/oasis-core/go/control/api"
 	commonFlags "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/flags"
 	"github.com/oasisprotocol/oasis-core/go/p2p/rpc"
 	registryApi "github.com/oasisprotocol/oasis-core/go/registry/api"
@@ -117,6 +118,8 @@ type Node struct { // nolint: maligned
 	rpcRoleProvider registration.RoleProvider
 	roleAvailable   bool
 
+	mirror bool
+
 	logger *logging.Logger
 
 	localStorage storageApi.LocalBackend
@@ -133,11 +136,16 @@ type Node struct { // nolint: maligned
 	checkpointSyncCfg    *CheckpointSyncConfig
 	checkpointSyncForced bool
 
+	rootChecker *rootChecker
+
 	syncedLock  sync.RWMutex
 	syncedState blockSummary
 
-	statusLock sync.RWMutex
-	status     api.StorageWorkerStatus
+	statusLock  sync.RWMutex
+	status      api.StorageWorkerStatus
+	lastRestore *api.CheckpointRestoreReport
+
+	pruneLock sync.Mutex
 
 	blockCh    *channels.InfiniteChannel
 	diffCh     chan *fetchedDiff
@@ -171,6 +179,8 @@ func NewNode(
 		roleProvider:    roleProvider,
 		rpcRoleProvider: rpcRoleProvider,
 
+		mirror: commonNode.Mirror,
+
 		logger: logging.GetLogger("worker/storage/committee").With("runtime_id", commonNode.Runtime.ID()),
 
 		workerCommonCfg: workerCommonCfg,
@@ -253,6 +263,20 @@ func NewNode(
 		return nil, fmt.Errorf("failed to create checkpointer: %w", err)
 	}
 
+	// Create a root checker which quarantines the local state in case it diverges from consensus.
+	n.rootChecker = &rootChecker{
+		logger:   n.logger,
+		ndb:      localStorage.NodeDB(),
+		getBlock: commonNode.Runtime.History().GetCommittedBlock,
+	}
+	if config.GlobalConfig.Storage.RootCheck.AutoResync {
+		n.rootChecker.resync = n.resyncRoots
+	}
+	servedStorage := &quarantineBackend{
+		Backend: localStorage,
+		rc:      n.rootChecker,
+	}
+
 	// Register prune handler.
 	commonNode.Runtime.History().Pruner().RegisterHandler(&pruneHandler{
 		logger: n.logger,
@@ -260,12 +284,12 @@ func NewNode(
 	})
 
 	// Register storage sync service.
-	commonNode.P2P.RegisterProtocolServer(storageSync.NewServer(commonNode.ChainContext, commonNode.Runtime.ID(), localStorage))
+	commonNode.P2P.RegisterProtocolServer(storageSync.NewServer(commonNode.ChainContext, commonNode.Runtime.ID(), servedStorage))
 	n.storageSync = storageSync.NewClient(commonNode.P2P, commonNode.ChainContext, commonNode.Runtime.ID())
 
 	// Register storage pub service if configured.
 	if rpcRoleProvider != nil {
-		commonNode.P2P.RegisterProtocolServer(storagePub.NewServer(commonNode.ChainContext, commonNode.Runtime.ID(), localStorage))
+		commonNode.P2P.RegisterProtocolServer(storagePub.NewServer(commonNode.ChainContext, commonNode.Runtime.ID(), servedStorage))
 	}
 
 	return n, nil
@@ -315,15 +339,40 @@ func (n *Node) Initialized() <-chan struct{} {
 
 // GetStatus returns the storage committee node status.
 func (n *Node) GetStatus(context.Context) (*api.Status, error) {
+	// Database statistics are best-effort and should not prevent status reporting.
+	ndb := n.localStorage.NodeDB()
+	dbStats, err := ndb.Stats(false)
+	if err != nil {
+		n.logger.Warn("failed to get database statistics",
+			"err", err,
+		)
+	}
+	var health *mkvsDB.Health
+	if hdb, ok := ndb.(mkvsDB.HealthNodeDB); ok {
+		health = hdb.Health()
+	}
+
 	n.syncedLock.RLock()
 	defer n.syncedLock.RUnlock()
 
 	n.statusLock.RLock()
 	defer n.statusLock.RUnlock()
 
+	status := n.status
+	divergence := n.rootChecker.getDivergence()
+	if divergence != nil {
+		status = api.StatusRootMismatch
+	}
+
 	return &api.Status{
 		LastFinalizedRound: n.syncedState.Round,
-		Status:             n.status,
+		Status:             status,
+		Mirror:             n.mirror,
+		Database:           dbStats,
+		Restore:            ndb.MultipartProgress(),
+		Health:             health,
+		RootDivergence:     divergence,
+		LastRestore:        n.lastRestore,
 	}, nil
 }
 
@@ -335,6 +384,13 @@ func (n *Node) PauseCheckpointer(pause bool) error {
 	return nil
 }
 
+// CreateCheckpoint synchronously creates a checkpoint of the given finalized round, or of the
+// latest finalized round in case no round is given, and returns the metadata of the created
+// checkpoints.
+func (n *Node) CreateCheckpoint(ctx context.Context, round *uint64) ([]*checkpoint.Metadata, error) {
+	return n.checkpointer.CreateCheckpoint(ctx, round)
+}
+
 // GetLocalStorage returns the local storage backend used by this storage node.
 func (n *Node) GetLocalStorage() storageApi.LocalBackend {
 	return n.localStorage
@@ -423,6 +479,82 @@ func (n *Node) fetchDiff(round uint64, prevRoot, thisRoot storageApi.Root) {
 	}
 }
 
+// applyDiffs applies the write logs of the given diffs of the same round and returns the error of
+// applying each diff.
+//
+// All fetched write logs are first applied together so that their roots are committed at once. In
+// case that fails, they are applied one by one so that failures can be attributed to the peers
+// that served them.
+func (n *Node) applyDiffs(diffs []*fetchedDiff) []error {
+	errs := make([]error, len(diffs))
+
+	var fetched []int
+	for i, diff := range diffs {
+		if diff.fetched {
+			fetched = append(fetched, i)
+		}
+	}
+	if len(fetched) > 1 {
+		request := &storageApi.ApplyBatchRequest{
+			Namespace: diffs[fetched[0]].thisRoot.Namespace,
+			DstRound:  diffs[fetched[0]].thisRoot.Version,
+		}
+		for _, i := range fetched {
+			request.Ops = append(request.Ops, storageApi.ApplyOp{
+				RootType: diffs[i].thisRoot.Type,
+				SrcRound: diffs[i].prevRoot.Version,
+				SrcRoot:  diffs[i].prevRoot.Hash,
+				DstRoot:  diffs[i].thisRoot.Hash,
+				WriteLog: diffs[i].writeLog,
+			})
+		}
+
+		_, err := n.localStorage.ApplyBatch(n.ctx, request)
+		if err == nil {
+			for _, i := range fetched {
+				diffs[i].pf.RecordSuccess()
+			}
+			return errs
+		}
+		n.logger.Debug("can't apply write logs together, applying one by one",
+			"err", err,
+			"round", request.DstRound,
+		)
+	}
+
+	for _, i := range fetched {
+		errs[i] = n.applyDiff(diffs[i])
+	}
+	return errs
+}
+
+// applyDiff applies the write log of the given diff.
+func (n *Node) applyDiff(diff *fetchedDiff) error {
+	err := n.localStorage.Apply(n.ctx, &storageApi.ApplyRequest{
+		Namespace: diff.thisRoot.Namespace,
+		RootType:  diff.thisRoot.Type,
+		SrcRound:  diff.prevRoot.Version,
+		SrcRoot:   diff.prevRoot.Hash,
+		DstRound:  diff.thisRoot.Version,
+		DstRoot:   diff.thisRoot.Hash,
+		WriteLog:  diff.writeLog,
+	})
+	switch {
+	case err == nil:
+		diff.pf.RecordSuccess()
+	case errors.Is(err, storageApi.ErrExpectedRootMismatch):
+		diff.pf.RecordBadPeer()
+	default:
+		n.logger.Error("can't apply write log",
+			"err", err,
+			"old_root", diff.prevRoot,
+			"new_root", diff.thisRoot,
+		)
+		diff.pf.RecordSuccess()
+	}
+	return err
+}
+
 func (n *Node) finalize(summary *blockSummary) {
 	err := n.localStorage.NodeDB().Finalize(summary.Roots)
 	switch err {
@@ -455,6 +587,15 @@ func (n *Node) finalize(summary *blockSummary) {
 	}
 }
 
+// requestShutdown requests a node shutdown after the given round failed to finalize.
+func (n *Node) requestShutdown(round uint64, err error) {
+	_ = n.commonNode.HostNode.RequestShutdownEx(n.ctx, &control.ShutdownRequest{
+		Reason:    fmt.Sprintf("failed to finalize round %d: %s", round, err),
+		Kind:      control.ShutdownReasonStorageFailure,
+		Component: "worker/storage",
+	})
+}
+
 func (n *Node) initGenesis(rt *registryApi.Runtime, genesisBlock *block.Block) error {
 	n.logger.Info("initializing storage at genesis")
 
@@ -581,6 +722,7 @@ func (n *Node) flushSyncedState(summary *blockSummary) (uint64, error) {
 	if err := n.commonNode.Runtime.History().StorageSyncCheckpoint(n.syncedState.Round); err != nil {
 		return 0, err
 	}
+	n.commonNode.NotifyStorageFinalized(n.syncedState.Round)
 
 	return n.syncedState.Round, nil
 }
@@ -722,14 +864,36 @@ func (n *Node) consensusCheckpointSyncer() {
 }
 
 // This is only called from the main worker goroutine, so no locking should be necessary.
+// rootCheckWorker periodically cross-checks the local roots against consensus.
+func (n *Node) rootCheckWorker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := n.rootChecker.check(n.ctx); err != nil {
+			n.logger.Error("failed to check local roots",
+				"err", err,
+			)
+		}
+	}
+}
+
 func (n *Node) nudgeAvailability(lastSynced, latest uint64) {
 	if lastSynced == n.undefinedRound || latest == n.undefinedRound {
 		return
 	}
 	if latest-lastSynced < maximumRoundDelayForAvailability && !n.roleAvailable {
-		n.roleProvider.SetAvailable(func(_ *node.Node) error {
-			return nil
-		})
+		if n.roleProvider != nil {
+			n.roleProvider.SetAvailable(func(_ *node.Node) error {
+				return nil
+			})
+		}
 		if n.rpcRoleProvider != nil {
 			n.rpcRoleProvider.SetAvailable(func(_ *node.Node) error {
 				return nil
@@ -738,7 +902,9 @@ func (n *Node) nudgeAvailability(lastSynced, latest uint64) {
 		n.roleAvailable = true
 	}
 	if latest-lastSynced > minimumRoundDelayForUnavailability && n.roleAvailable {
-		n.roleProvider.SetUnavailable()
+		if n.roleProvider != nil {
+			n.roleProvider.SetUnavailable()
+		}
 		if n.rpcRoleProvider != nil {
 			n.rpcRoleProvider.SetUnavailable()
 		}
@@ -797,6 +963,16 @@ func (n *Node) worker() { // nolint: gocyclo
 		}
 	}
 
+	// Cross-check the local roots against consensus before serving them.
+	if err = n.rootChecker.check(n.ctx); err != nil {
+		n.logger.Error("failed to check local roots",
+			"err", err,
+		)
+	}
+	if interval := config.GlobalConfig.Storage.RootCheck.Interval; interval > 0 {
+		go n.rootCheckWorker(interval)
+	}
+
 	var fetcherGroup sync.WaitGroup
 
 	n.syncedLock.RLock()
@@ -949,6 +1125,9 @@ func (n *Node) worker() { // nolint: gocyclo
 		"genesis_round", genesisBlock.Header.Round,
 		"last_synced", cachedLastRound,
 	)
+	if cachedLastRound != n.undefinedRound {
+		n.commonNode.NotifyStorageFinalized(cachedLastRound)
+	}
 
 	outOfOrderDoneDiffs := &outOfOrderRoundQueue{}
 	outOfOrderFinalizable := &outOfOrderRoundQueue{}
@@ -1118,54 +1297,37 @@ mainLoop:
 		// Apply any writelogs that came in through fetchDiff, but only if they are for the round
 		// after the last fully applied one (lastFullyAppliedRound).
 		if len(*outOfOrderDoneDiffs) > 0 && lastFullyAppliedRound+1 == (*outOfOrderDoneDiffs)[0].GetRound() {
-			lastDiff := heap.Pop(outOfOrderDoneDiffs).(*fetchedDiff)
-			// Apply the write log if one exists.
-			err = nil
-			if lastDiff.fetched {
-				err = n.localStorage.Apply(n.ctx, &storageApi.ApplyRequest{
-					Namespace: lastDiff.thisRoot.Namespace,
-					RootType:  lastDiff.thisRoot.Type,
-					SrcRound:  lastDiff.prevRoot.Version,
-					SrcRoot:   lastDiff.prevRoot.Hash,
-					DstRound:  lastDiff.thisRoot.Version,
-					DstRoot:   lastDiff.thisRoot.Hash,
-					WriteLog:  lastDiff.writeLog,
-				})
-				switch {
-				case err == nil:
-					lastDiff.pf.RecordSuccess()
-				case errors.Is(err, storageApi.ErrExpectedRootMismatch):
-					lastDiff.pf.RecordBadPeer()
-				default:
-					n.logger.Error("can't apply write log",
-						"err", err,
-						"old_root", lastDiff.prevRoot,
-						"new_root", lastDiff.thisRoot,
-					)
-					lastDiff.pf.RecordSuccess()
-				}
+			// Apply the write logs of all diffs of the round that are available together, so
+			// that their roots are committed at once.
+			var diffs []*fetchedDiff
+			for len(*outOfOrderDoneDiffs) > 0 && lastFullyAppliedRound+1 == (*outOfOrderDoneDiffs)[0].GetRound() {
+				diffs = append(diffs, heap.Pop(outOfOrderDoneDiffs).(*fetchedDiff))
 			}
+			errs := n.applyDiffs(diffs)
 
-			syncing := syncingRounds[lastDiff.round]
-			if err != nil {
-				syncing.retry(lastDiff.thisRoot.Type)
-			} else {
-				// Check if we have fully synced the given round. If we have, we can proceed
-				// with the Finalize operation.
-				syncing.outstanding.remove(lastDiff.thisRoot.Type)
-				if syncing.outstanding.isEmpty() && syncing.awaitingRetry.isEmpty() {
-					n.logger.Debug("finished syncing round", "round", lastDiff.round)
-					delete(syncingRounds, lastDiff.round)
-					summary := hashCache[lastDiff.round]
-					delete(hashCache, lastDiff.round-1)
-
-					storageWorkerLastSyncedRound.With(n.getMetricLabels()).Set(float64(lastDiff.round))
-					storageWorkerRoundSyncLatency.With(n.getMetricLabels()).Observe(time.Since(syncing.startedAt).Seconds())
-
-					// Finalize storage for this round. This happens asynchronously
-					// with respect to Apply operations for subsequent rounds.
-					lastFullyAppliedRound = lastDiff.round
-					heap.Push(outOfOrderFinalizable, summary)
+			for i, lastDiff := range diffs {
+				err = errs[i]
+				syncing := syncingRounds[lastDiff.round]
+				if err != nil {
+					syncing.retry(lastDiff.thisRoot.Type)
+				} else {
+					// Check if we have fully synced the given round. If we have, we can proceed
+					// with the Finalize operation.
+					syncing.outstanding.remove(lastDiff.thisRoot.Type)
+					if syncing.outstanding.isEmpty() && syncing.awaitingRetry.isEmpty() {
+						n.logger.Debug("finished syncing round", "round", lastDiff.round)
+						delete(syncingRounds, lastDiff.round)
+						summary := hashCache[lastDiff.round]
+						delete(hashCache, lastDiff.round-1)
+
+						storageWorkerLastSyncedRound.With(n.getMetricLabels()).Set(float64(lastDiff.round))
+						storageWorkerRoundSyncLatency.With(n.getMetricLabels()).Observe(time.Since(syncing.startedAt).Seconds())
+
+						// Finalize storage for this round. This happens asynchronously
+						// with respect to Apply operations for subsequent rounds.
+						lastFullyAppliedRound = lastDiff.round
+						heap.Push(outOfOrderFinalizable, summary)
+					}
 				}
 			}
 
@@ -1300,7 +1462,7 @@ mainLoop:
 				// This is a cant-happen situation and there's no useful way
 				// to recover from it. Just request a node shutdown and stop fussing
 				// since, from this point onwards, syncing is effectively blocked.
-				_ = n.commonNode.HostNode.RequestShutdown(n.ctx, false)
+				n.requestShutdown(finalized.summary.Round, finalized.err)
 			}
 
 		case <-n.ctx.Done():
@@ -0,0 +1,79 @@
+package committee
+
+import (
+	"context"
+
+	control "github.com/oasisprotocol/oasis-core/go/control/api"
+	mkvsDB "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+)
+
+// PruneStorage prunes old versions of the runtime's storage as requested, never pruning any
+// version that has not yet been synced, and returns the number of pruned versions and reclaimed
+// bytes.
+//
+// Pruning is rejected while a checkpoint restore is in progress. Pinned versions, i.e. those that
+// are being read, and all versions following them are skipped.
+func (n *Node) PruneStorage(ctx context.Context, req *control.PruneStorageRequest) (*control.PruneStorageResponse, error) {
+	if err := req.ValidateBasic(); err != nil {
+		return nil, err
+	}
+
+	n.statusLock.RLock()
+	restoring := n.status == api.StatusSyncingCheckpoints
+	n.statusLock.RUnlock()
+
+	ndb := n.localStorage.NodeDB()
+	if restoring || ndb.GetMultipartVersion() != 0 {
+		return nil, control.ErrStorageRestoreInProgress
+	}
+
+	// Serialize manual pruning requests.
+	n.pruneLock.Lock()
+	defer n.pruneLock.Unlock()
+
+	beforeVersion := req.TargetEarliestVersion(0)
+	if latestVersion, ok := ndb.GetLatestVersion(); ok {
+		beforeVersion = req.TargetEarliestVersion(latestVersion)
+	}
+
+	// Make sure we never prune past what was synced.
+	lastSyncedRound, _, _ := n.GetLastSynced()
+	if lastSyncedRound == defaultUndefinedRound {
+		lastSyncedRound = 0
+	}
+	beforeVersion = min(beforeVersion, lastSyncedRound)
+
+	return pruneStorage(ctx, ndb, beforeVersion)
+}
+
+// pruneStorage prunes all versions before the given version from the node database.
+func pruneStorage(ctx context.Context, ndb mkvsDB.NodeDB, beforeVersion uint64) (*control.PruneStorageResponse, error) {
+	earliestVersion := ndb.GetEarliestVersion()
+	if beforeVersion <= earliestVersion {
+		return &control.PruneStorageResponse{
+			EarliestVersion: earliestVersion,
+		}, nil
+	}
+
+	sizeBefore, err := ndb.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	pruned, err := ndb.PruneRange(ctx, earliestVersion, beforeVersion-1)
+	if err != nil {
+		return nil, err
+	}
+
+	sizeAfter, err := ndb.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	return &control.PruneStorageResponse{
+		VersionsPruned:  uint64(pruned),
+		EarliestVersion: ndb.GetEarliestVersion(),
+		BytesReclaimed:  uint64(max(sizeBefore-sizeAfter, 0)),
+	}, nil
+}
@@ -0,0 +1,83 @@
+package committee
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db"
+	mkvsDB "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	dbTesting "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/testing"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/node"
+)
+
+var testPruneNs = common.NewTestNamespaceFromSeed([]byte("oasis storage worker prune test ns"), 0)
+
+func TestPruneStorage(t *testing.T) {
+	dbTesting.TestMultipleBackends(t, db.Backends, testPruneStorage)
+}
+
+func testPruneStorage(t *testing.T, factory mkvsDB.Factory) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ndb, err := factory.New(&mkvsDB.Config{
+		DB:           filepath.Join(t.TempDir(), "db"),
+		Namespace:    testPruneNs,
+		MaxCacheSize: 16 * 1024 * 1024,
+		NoFsync:      true,
+	})
+	require.NoError(err, "New")
+	defer ndb.Close()
+
+	var root node.Root
+	root.Empty()
+	root.Namespace = testPruneNs
+	root.Type = node.RootTypeState
+
+	for version := uint64(0); version < 10; version++ {
+		tree := mkvs.NewWithRoot(nil, ndb, root)
+		err = tree.Insert(ctx, []byte(fmt.Sprintf("key %d", version)), []byte(fmt.Sprintf("value %d", version)))
+		require.NoError(err, "Insert")
+
+		_, rootHash, cErr := tree.Commit(ctx, testPruneNs, version)
+		require.NoError(cErr, "Commit")
+		tree.Close()
+
+		root.Version = version
+		root.Hash = rootHash
+		err = ndb.Finalize([]node.Root{root})
+		require.NoError(err, "Finalize")
+	}
+
+	// Pruning should stop before versions that are being read.
+	unpin, err := ndb.Pin(5)
+	require.NoError(err, "Pin")
+
+	rsp, err := pruneStorage(ctx, ndb, 8)
+	require.NoError(err, "pruneStorage")
+	require.EqualValues(5, rsp.VersionsPruned, "versions before the pinned version should be pruned")
+	require.EqualValues(5, rsp.EarliestVersion)
+
+	_, err = ndb.GetRootsForVersion(5)
+	require.NoError(err, "pinned version should remain readable")
+
+	// Once the version is no longer read, pruning should continue.
+	unpin()
+	rsp, err = pruneStorage(ctx, ndb, 8)
+	require.NoError(err, "pruneStorage")
+	require.EqualValues(3, rsp.VersionsPruned)
+	require.EqualValues(8, rsp.EarliestVersion)
+
+	// Nothing left to prune.
+	rsp, err = pruneStorage(ctx, ndb, 8)
+	require.NoError(err, "pruneStorage")
+	require.Zero(rsp.VersionsPruned)
+	require.Zero(rsp.BytesReclaimed)
+	require.EqualValues(8, rsp.EarliestVersion)
+}
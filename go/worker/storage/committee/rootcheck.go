@@ -0,0 +1,306 @@
+package committee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	roothashApi "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/checkpoint"
+	mkvsDB "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/writelog"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+	storageSync "github.com/oasisprotocol/oasis-core/go/worker/storage/p2p/sync"
+)
+
+// rootCheckMaxBacktrack is the maximum number of versions searched for the last version whose
+// local roots match consensus.
+const rootCheckMaxBacktrack = 1000
+
+// rootChecker cross-checks the latest finalized local roots against the block headers from
+// consensus and quarantines the local state in case they diverge.
+type rootChecker struct {
+	logger *logging.Logger
+
+	ndb mkvsDB.NodeDB
+
+	// getBlock returns the committed block of the given round.
+	getBlock func(ctx context.Context, round uint64) (*block.Block, error)
+
+	// resync re-syncs all versions following the last matching version. If nil, diverging
+	// versions are not re-synced automatically.
+	resync func(ctx context.Context, divergence *api.RootDivergence) error
+
+	l          sync.RWMutex
+	divergence *api.RootDivergence
+}
+
+// check cross-checks the roots of the latest finalized version and updates the quarantine.
+//
+// Versions for which no committed block is available are not checked, so the local state may
+// legitimately be behind consensus or ahead of the local block history.
+func (rc *rootChecker) check(ctx context.Context) error {
+	divergence, err := rc.detect(ctx)
+	if err != nil {
+		return err
+	}
+	rc.setDivergence(divergence)
+	if divergence == nil {
+		return nil
+	}
+
+	rc.logger.Error("local roots diverge from consensus, refusing to serve storage",
+		"version", divergence.Version,
+		"last_matching_version", divergence.LastMatchingVersion,
+	)
+
+	if rc.resync == nil || divergence.LastMatchingVersion == nil {
+		return nil
+	}
+
+	rc.logger.Info("re-syncing diverging versions",
+		"last_matching_version", *divergence.LastMatchingVersion,
+	)
+	if err = rc.resync(ctx, divergence); err != nil {
+		return fmt.Errorf("failed to re-sync diverging versions: %w", err)
+	}
+
+	// Only lift the quarantine after the re-synced roots have been verified.
+	if divergence, err = rc.detect(ctx); err != nil {
+		return err
+	}
+	rc.setDivergence(divergence)
+	if divergence == nil {
+		rc.logger.Info("local roots match consensus after re-sync")
+	}
+	return nil
+}
+
+func (rc *rootChecker) detect(ctx context.Context) (*api.RootDivergence, error) {
+	version, exists := rc.ndb.GetLatestVersion()
+	if !exists {
+		return nil, nil
+	}
+
+	matches, known, err := rc.versionMatches(ctx, version)
+	if err != nil || !known || matches {
+		return nil, err
+	}
+
+	// Find the last matching version.
+	divergence := &api.RootDivergence{Version: version}
+	earliestVersion := rc.ndb.GetEarliestVersion()
+	for v := version; v > earliestVersion && version-v < rootCheckMaxBacktrack; {
+		v--
+
+		matches, known, err = rc.versionMatches(ctx, v)
+		switch {
+		case err != nil:
+			return nil, err
+		case !known:
+			return divergence, nil
+		case matches:
+			divergence.LastMatchingVersion = &v
+			return divergence, nil
+		default:
+			divergence.Version = v
+		}
+	}
+	return divergence, nil
+}
+
+// versionMatches checks whether the local roots of the given version match consensus.
+//
+// The second return value is false in case the version cannot be checked, either because it has
+// been pruned or because its committed block is not available.
+func (rc *rootChecker) versionMatches(ctx context.Context, version uint64) (bool, bool, error) {
+	// Make sure that the version is not pruned while it is being checked.
+	unpin, err := rc.ndb.Pin(version)
+	switch {
+	case err == nil:
+	case errors.Is(err, mkvsDB.ErrVersionNotFound):
+		return false, false, nil
+	default:
+		return false, false, fmt.Errorf("failed to pin version %d: %w", version, err)
+	}
+	defer unpin()
+
+	blk, err := rc.getBlock(ctx, version)
+	switch {
+	case err == nil:
+	case errors.Is(err, roothashApi.ErrNotFound):
+		return false, false, nil
+	default:
+		return false, false, fmt.Errorf("failed to get block for round %d: %w", version, err)
+	}
+
+	exists, err := rc.ndb.HasRoots(blk.Header.StorageRoots())
+	if err != nil {
+		return false, false, fmt.Errorf("failed to check roots of version %d: %w", version, err)
+	}
+	for _, ok := range exists {
+		if !ok {
+			return false, true, nil
+		}
+	}
+	return true, true, nil
+}
+
+func (rc *rootChecker) setDivergence(divergence *api.RootDivergence) {
+	rc.l.Lock()
+	defer rc.l.Unlock()
+
+	rc.divergence = divergence
+}
+
+// getDivergence returns the detected divergence or nil in case local roots match consensus.
+func (rc *rootChecker) getDivergence() *api.RootDivergence {
+	rc.l.RLock()
+	defer rc.l.RUnlock()
+
+	return rc.divergence
+}
+
+// err returns an error in case the local state is quarantined.
+func (rc *rootChecker) err() error {
+	divergence := rc.getDivergence()
+	if divergence == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: version %d", api.ErrRootMismatch, divergence.Version)
+}
+
+// quarantineBackend is a storage backend that refuses to serve requests while the local roots
+// diverge from consensus.
+type quarantineBackend struct {
+	storageApi.Backend
+
+	rc *rootChecker
+}
+
+func (b *quarantineBackend) SyncGet(ctx context.Context, request *storageApi.GetRequest) (*storageApi.ProofResponse, error) {
+	if err := b.rc.err(); err != nil {
+		return nil, err
+	}
+	return b.Backend.SyncGet(ctx, request)
+}
+
+func (b *quarantineBackend) SyncGetPrefixes(ctx context.Context, request *storageApi.GetPrefixesRequest) (*storageApi.ProofResponse, error) {
+	if err := b.rc.err(); err != nil {
+		return nil, err
+	}
+	return b.Backend.SyncGetPrefixes(ctx, request)
+}
+
+func (b *quarantineBackend) SyncIterate(ctx context.Context, request *storageApi.IterateRequest) (*storageApi.ProofResponse, error) {
+	if err := b.rc.err(); err != nil {
+		return nil, err
+	}
+	return b.Backend.SyncIterate(ctx, request)
+}
+
+func (b *quarantineBackend) GetDiff(ctx context.Context, request *storageApi.GetDiffRequest) (storageApi.WriteLogIterator, error) {
+	if err := b.rc.err(); err != nil {
+		return nil, err
+	}
+	return b.Backend.GetDiff(ctx, request)
+}
+
+func (b *quarantineBackend) GetCheckpoints(ctx context.Context, request *checkpoint.GetCheckpointsRequest) ([]*checkpoint.Metadata, error) {
+	if err := b.rc.err(); err != nil {
+		return nil, err
+	}
+	return b.Backend.GetCheckpoints(ctx, request)
+}
+
+func (b *quarantineBackend) GetCheckpointChunk(ctx context.Context, chunk *checkpoint.ChunkMetadata, w io.Writer) error {
+	if err := b.rc.err(); err != nil {
+		return err
+	}
+	return b.Backend.GetCheckpointChunk(ctx, chunk, w)
+}
+
+// repairNodeDB is a node database that inserts all committed roots into already finalized
+// versions, trusting only the given roots.
+type repairNodeDB struct {
+	mkvsDB.RepairNodeDB
+
+	trustedRoots []storageApi.Root
+}
+
+func (d *repairNodeDB) NewBatch(oldRoot storageApi.Root, version uint64, _ bool) (mkvsDB.Batch, error) {
+	return d.NewBatchForRepair(oldRoot, version, d.trustedRoots)
+}
+
+// resyncRoots re-syncs the roots of all versions following the last matching version from peers
+// and inserts them into the already finalized versions of the local node database.
+func (n *Node) resyncRoots(ctx context.Context, divergence *api.RootDivergence) error {
+	rdb, ok := n.localStorage.NodeDB().(mkvsDB.RepairNodeDB)
+	if !ok {
+		return fmt.Errorf("node database does not support repairs")
+	}
+	lastVersion, _ := rdb.GetLatestVersion()
+
+	prevBlk, err := n.commonNode.Runtime.History().GetCommittedBlock(ctx, *divergence.LastMatchingVersion)
+	if err != nil {
+		return fmt.Errorf("failed to get block for round %d: %w", *divergence.LastMatchingVersion, err)
+	}
+	for version := prevBlk.Header.Round + 1; version <= lastVersion; version++ {
+		blk, err := n.commonNode.Runtime.History().GetCommittedBlock(ctx, version)
+		if err != nil {
+			return fmt.Errorf("failed to get block for round %d: %w", version, err)
+		}
+		if err = n.resyncVersion(ctx, rdb, prevBlk, blk); err != nil {
+			return fmt.Errorf("failed to re-sync version %d: %w", version, err)
+		}
+		prevBlk = blk
+	}
+	return nil
+}
+
+func (n *Node) resyncVersion(ctx context.Context, rdb mkvsDB.RepairNodeDB, prevBlk, blk *block.Block) error {
+	prevRoots := prevBlk.Header.StorageRoots()
+	for i, root := range blk.Header.StorageRoots() {
+		if rdb.HasRoot(root) {
+			continue
+		}
+
+		prevRoot := prevRoots[i]
+		if mkvsDB.PolicyForRoot(root).NoChildRoots {
+			prevRoot = storageApi.Root{
+				Namespace: root.Namespace,
+				Version:   root.Version,
+				Type:      root.Type,
+			}
+			prevRoot.Hash.Empty()
+		}
+
+		rsp, pf, err := n.storageSync.GetDiff(ctx, &storageSync.GetDiffRequest{StartRoot: prevRoot, EndRoot: root})
+		if err != nil {
+			return fmt.Errorf("failed to fetch diff for %s root: %w", root.Type, err)
+		}
+
+		tree := mkvs.NewWithRoot(nil, &repairNodeDB{rdb, []storageApi.Root{root}}, prevRoot)
+		err = tree.ApplyWriteLog(ctx, writelog.NewStaticIterator(rsp.WriteLog))
+		if err == nil {
+			_, err = tree.CommitKnown(ctx, root)
+		}
+		tree.Close()
+		if err != nil {
+			pf.RecordBadPeer()
+			return fmt.Errorf("failed to insert %s root: %w", root.Type, err)
+		}
+		pf.RecordSuccess()
+
+		n.logger.Info("re-synced diverging root",
+			"root", root,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package committee
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+	"github.com/oasisprotocol/oasis-core/go/common/logging"
+	roothashApi "github.com/oasisprotocol/oasis-core/go/roothash/api"
+	"github.com/oasisprotocol/oasis-core/go/roothash/api/block"
+	storageApi "github.com/oasisprotocol/oasis-core/go/storage/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs"
+	mkvsDB "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/badger"
+	"github.com/oasisprotocol/oasis-core/go/worker/storage/api"
+)
+
+var testNs = common.NewTestNamespaceFromSeed([]byte("storage worker root check test ns"), 0)
+
+func newTestNodeDB(t *testing.T) mkvsDB.NodeDB {
+	ndb, err := badger.New(&mkvsDB.Config{
+		Namespace:    testNs,
+		MaxCacheSize: 16 * 1024 * 1024,
+		NoFsync:      true,
+		MemoryOnly:   true,
+	})
+	require.NoError(t, err, "New()")
+	t.Cleanup(ndb.Close)
+	return ndb
+}
+
+// commitVersions commits and finalizes a state root for each of the given versions, with the
+// value of each version given by the value function.
+func commitVersions(t *testing.T, ndb mkvsDB.NodeDB, prevRoot *storageApi.Root, versions []uint64, value func(uint64) string) storageApi.Root {
+	ctx := context.Background()
+
+	for _, version := range versions {
+		if prevRoot == nil {
+			prevRoot = &storageApi.Root{
+				Namespace: testNs,
+				Version:   version,
+				Type:      storageApi.RootTypeState,
+			}
+			prevRoot.Hash.Empty()
+		}
+
+		tree := mkvs.NewWithRoot(nil, ndb, *prevRoot)
+		err := tree.Insert(ctx, []byte("key"), []byte(value(version)))
+		require.NoError(t, err, "Insert()")
+		_, rootHash, err := tree.Commit(ctx, testNs, version)
+		require.NoError(t, err, "Commit()")
+		tree.Close()
+
+		root := storageApi.Root{
+			Namespace: testNs,
+			Version:   version,
+			Type:      storageApi.RootTypeState,
+			Hash:      rootHash,
+		}
+		err = ndb.Finalize([]storageApi.Root{root})
+		require.NoError(t, err, "Finalize()")
+		prevRoot = &root
+	}
+	return *prevRoot
+}
+
+func TestRootCheck(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	correctValue := func(version uint64) string { return fmt.Sprintf("value %d", version) }
+	divergentValue := func(version uint64) string { return fmt.Sprintf("bogus %d", version) }
+
+	// The consensus blocks follow the correct state.
+	correct := newTestNodeDB(t)
+	blocks := make(map[uint64]*block.Block)
+	var prevRoot *storageApi.Root
+	for version := range uint64(5) {
+		root := commitVersions(t, correct, prevRoot, []uint64{version}, correctValue)
+		prevRoot = &root
+
+		blk := &block.Block{}
+		blk.Header.Namespace = testNs
+		blk.Header.Round = version
+		blk.Header.StateRoot = root.Hash
+		blk.Header.IORoot.Empty()
+		blocks[version] = blk
+	}
+	getBlock := func(_ context.Context, round uint64) (*block.Block, error) {
+		blk, ok := blocks[round]
+		if !ok {
+			return nil, roothashApi.ErrNotFound
+		}
+		return blk, nil
+	}
+
+	// Local state that is behind consensus should not be quarantined.
+	behind := newTestNodeDB(t)
+	commitVersions(t, behind, nil, []uint64{0, 1, 2}, correctValue)
+	rc := &rootChecker{
+		logger:   logging.GetLogger("worker/storage/committee/test"),
+		ndb:      behind,
+		getBlock: getBlock,
+	}
+	require.NoError(rc.check(ctx), "check")
+	require.Nil(rc.getDivergence(), "local state that is behind should not diverge")
+	require.NoError(rc.err())
+
+	// Simulate a local state that finalized divergent roots in versions 3 and 4.
+	divergent := newTestNodeDB(t)
+	root := commitVersions(t, divergent, nil, []uint64{0, 1, 2}, correctValue)
+	commitVersions(t, divergent, &root, []uint64{3, 4}, divergentValue)
+
+	var resynced []*api.RootDivergence
+	rc = &rootChecker{
+		logger:   logging.GetLogger("worker/storage/committee/test"),
+		ndb:      divergent,
+		getBlock: getBlock,
+		resync: func(_ context.Context, divergence *api.RootDivergence) error {
+			resynced = append(resynced, divergence)
+			return nil
+		},
+	}
+	require.NoError(rc.check(ctx), "check")
+
+	divergence := rc.getDivergence()
+	require.NotNil(divergence, "divergent local state should be detected")
+	require.EqualValues(3, divergence.Version, "earliest diverging version should be reported")
+	require.NotNil(divergence.LastMatchingVersion)
+	require.EqualValues(2, *divergence.LastMatchingVersion)
+
+	// Re-sync should be triggered from the last matching version.
+	require.Len(resynced, 1, "re-sync should be triggered")
+	require.EqualValues(2, *resynced[0].LastMatchingVersion)
+
+	// The storage should not be served while quarantined.
+	require.ErrorIs(rc.err(), api.ErrRootMismatch)
+	backend := &quarantineBackend{rc: rc}
+	_, err := backend.SyncGet(ctx, &storageApi.GetRequest{})
+	require.ErrorIs(err, api.ErrRootMismatch, "SyncGet should be refused")
+	_, err = backend.GetDiff(ctx, &storageApi.GetDiffRequest{})
+	require.ErrorIs(err, api.ErrRootMismatch, "GetDiff should be refused")
+
+	// The quarantine should be lifted once the re-synced roots match consensus.
+	rc.resync = func(context.Context, *api.RootDivergence) error {
+		rc.ndb = correct
+		return nil
+	}
+	require.NoError(rc.check(ctx), "check")
+	require.Nil(rc.getDivergence(), "quarantine should be lifted after re-sync")
+	require.NoError(rc.err())
+
+	// Versions for which no block is available should not be checked.
+	delete(blocks, 4)
+	rc = &rootChecker{
+		logger:   logging.GetLogger("worker/storage/committee/test"),
+		ndb:      divergent,
+		getBlock: getBlock,
+	}
+	require.NoError(rc.check(ctx), "check")
+	require.Nil(rc.getDivergence(), "versions without blocks should not be checked")
+}
@@ -0,0 +1,51 @@
+package committee
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	control "github.com/oasisprotocol/oasis-core/go/control/api"
+	"github.com/oasisprotocol/oasis-core/go/worker/common/committee"
+)
+
+// testNodeController is a node controller that records the reasons of shutdown requests the same
+// way as the node does.
+type testNodeController struct {
+	control.NodeController
+
+	reasons *control.ShutdownReasonRegistry
+}
+
+func (c *testNodeController) RequestShutdownEx(_ context.Context, req *control.ShutdownRequest) error {
+	c.reasons.Record(req.ShutdownReason(time.Now()))
+	return nil
+}
+
+func TestRequestShutdownReason(t *testing.T) {
+	require := require.New(t)
+
+	hostNode := &testNodeController{
+		reasons: control.NewShutdownReasonRegistry(),
+	}
+	n := &Node{
+		commonNode: &committee.Node{HostNode: hostNode},
+		ctx:        context.Background(),
+	}
+
+	n.requestShutdown(42, fmt.Errorf("injected failure"))
+
+	dataDir := t.TempDir()
+	require.NoError(hostNode.reasons.Persist(dataDir), "Persist")
+	reason, err := control.LoadShutdownReason(dataDir)
+	require.NoError(err, "LoadShutdownReason")
+	require.NotNil(reason, "shutdown reason should be recorded")
+	require.Equal(control.ShutdownReasonStorageFailure, reason.Kind)
+	require.Equal("worker/storage", reason.Component)
+	require.Contains(reason.Message, "round 42")
+	require.Contains(reason.Message, "injected failure")
+	require.False(reason.RestartIntended)
+}
@@ -2,8 +2,10 @@
 package config
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/storage/mkvs/db"
 )
 
@@ -13,6 +15,22 @@ type Config struct {
 	Backend string `yaml:"backend"`
 	// Maximum in-memory cache size.
 	MaxCacheSize string `yaml:"max_cache_size"`
+	// Number of most recent versions for which existing roots are cached in memory (0 disables).
+	RootCacheVersions uint64 `yaml:"root_cache_versions,omitempty"`
+	// Maximum in-memory cache size of unmarshalled nodes (0 disables).
+	NodeCacheSize string `yaml:"node_cache_size,omitempty"`
+	// Number of versions for which tombstones of deleted keys are retained (0 disables).
+	TombstoneRetentionVersions uint64 `yaml:"tombstone_retention_versions,omitempty"`
+	// Verify hashes of nodes read from and written to the node database.
+	VerifyNodeHashes bool `yaml:"verify_node_hashes,omitempty"`
+	// Request checksums of executor batch write logs from runtimes that support them and verify
+	// them when the batch is received and before the write logs are applied.
+	WriteLogChecksums bool `yaml:"write_log_checksums,omitempty"`
+	// Number of corruption-class node database errors within the corruption error window after
+	// which storage is reported as unhealthy (0 disables).
+	CorruptionErrorThreshold uint64 `yaml:"corruption_error_threshold,omitempty"`
+	// Window over which corruption-class node database errors are counted (0 uses the default).
+	CorruptionErrorWindow time.Duration `yaml:"corruption_error_window,omitempty"`
 	// Number of concurrent storage diff fetchers.
 	FetcherCount uint `yaml:"fetcher_count"`
 
@@ -20,9 +38,82 @@ type Config struct {
 	PublicRPCEnabled bool `yaml:"public_rpc_enabled,omitempty"`
 	// Disable initial storage sync from checkpoints.
 	CheckpointSyncDisabled bool `yaml:"checkpoint_sync_disabled,omitempty"`
+	// Resume an interrupted initial storage sync from checkpoints instead of discarding the
+	// already restored state.
+	CheckpointSyncResume bool `yaml:"checkpoint_sync_resume,omitempty"`
 
 	// Storage checkpointer configuration.
 	Checkpointer CheckpointerConfig `yaml:"checkpointer,omitempty"`
+
+	// Storage root check configuration.
+	RootCheck RootCheckConfig `yaml:"root_check,omitempty"`
+
+	// Badger tuning configuration.
+	Badger BadgerConfig `yaml:"badger,omitempty"`
+
+	// Storage mirror configuration.
+	Mirror MirrorConfig `yaml:"mirror,omitempty"`
+}
+
+// MirrorConfig is the storage worker mirror configuration structure.
+type MirrorConfig struct {
+	// Runtimes is the list of runtime identifiers whose state should only be mirrored.
+	//
+	// Mirrored runtimes follow finalized blocks and sync state, but the node never registers
+	// for them and never participates in any of their committees.
+	Runtimes []string `yaml:"runtimes,omitempty"`
+}
+
+// IsMirrored returns true iff the given runtime is configured to be mirrored.
+func (c *MirrorConfig) IsMirrored(runtimeID common.Namespace) bool {
+	for _, raw := range c.Runtimes {
+		var id common.Namespace
+		if err := id.UnmarshalHex(raw); err != nil {
+			continue
+		}
+		if id.Equal(&runtimeID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates the mirror configuration settings.
+func (c *MirrorConfig) Validate() error {
+	for _, raw := range c.Runtimes {
+		var id common.Namespace
+		if err := id.UnmarshalHex(raw); err != nil {
+			return fmt.Errorf("malformed mirrored runtime identifier '%s': %w", raw, err)
+		}
+	}
+	return nil
+}
+
+// BadgerConfig is the storage worker badger tuning configuration structure.
+//
+// Unset options keep the backend defaults.
+type BadgerConfig struct {
+	// Size of each memtable.
+	MemTableSize string `yaml:"mem_table_size,omitempty"`
+	// Size of each table in the LSM tree base level.
+	BaseTableSize string `yaml:"base_table_size,omitempty"`
+	// Size of the block cache (overrides max_cache_size).
+	BlockCacheSize string `yaml:"block_cache_size,omitempty"`
+	// Number of concurrent compaction workers.
+	NumCompactors int `yaml:"num_compactors,omitempty"`
+	// Compact level zero when the database is closed.
+	CompactL0OnClose *bool `yaml:"compact_l0_on_close,omitempty"`
+	// Size above which values are stored in the value log.
+	ValueThreshold string `yaml:"value_threshold,omitempty"`
+	// Sync writes to disk.
+	SyncWrites *bool `yaml:"sync_writes,omitempty"`
+	// Maximum size of a single record of pending updated nodes.
+	UpdatedNodesChunkSize string `yaml:"updated_nodes_chunk_size,omitempty"`
+	// Maximum number of existing nodes for which a filter is built on checkpoint restore
+	// (negative disables).
+	MultipartNodeFilterLimit int64 `yaml:"multipart_node_filter_limit,omitempty"`
+	// Store root metadata in a separate small database instance.
+	SplitMetadata bool `yaml:"split_metadata,omitempty"`
 }
 
 // CheckpointerConfig is the storage worker checkpointer configuration structure.
@@ -33,11 +124,28 @@ type CheckpointerConfig struct {
 	CheckInterval time.Duration `yaml:"check_interval"`
 }
 
+// RootCheckConfig is the storage worker root check configuration structure.
+//
+// Local roots are always cross-checked against consensus at startup.
+type RootCheckConfig struct {
+	// Interval at which local roots are periodically cross-checked against consensus (0 disables).
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Automatically re-sync diverging versions from peers (enables repairs of the node database).
+	AutoResync bool `yaml:"auto_resync,omitempty"`
+}
+
 // Validate validates the configuration settings.
 func (c *Config) Validate() error {
 	if c.Backend != "auto" {
-		_, err := db.GetBackendByName(c.Backend)
-		return err
+		if _, err := db.GetBackendByName(c.Backend); err != nil {
+			return err
+		}
+	}
+	if err := c.Mirror.Validate(); err != nil {
+		return fmt.Errorf("mirror: %w", err)
+	}
+	if c.RootCheck.Interval < 0 {
+		return fmt.Errorf("root_check.interval must be non-negative")
 	}
 	return nil
 }
@@ -54,5 +162,8 @@ func DefaultConfig() Config {
 			Enabled:       false,
 			CheckInterval: 1 * time.Minute,
 		},
+		RootCheck: RootCheckConfig{
+			Interval: 10 * time.Minute,
+		},
 	}
 }
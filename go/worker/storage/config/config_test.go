@@ -0,0 +1,28 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/oasisprotocol/oasis-core/go/common"
+)
+
+func TestMirrorConfig(t *testing.T) {
+	require := require.New(t)
+
+	mirrored := common.NewTestNamespaceFromSeed([]byte("storage mirror test: mirrored"), 0)
+	other := common.NewTestNamespaceFromSeed([]byte("storage mirror test: other"), 0)
+
+	cfg := DefaultConfig()
+	require.NoError(cfg.Validate(), "default config should be valid")
+	require.False(cfg.Mirror.IsMirrored(mirrored), "no runtimes should be mirrored by default")
+
+	cfg.Mirror.Runtimes = []string{mirrored.Hex()}
+	require.NoError(cfg.Validate())
+	require.True(cfg.Mirror.IsMirrored(mirrored))
+	require.False(cfg.Mirror.IsMirrored(other))
+
+	cfg.Mirror.Runtimes = append(cfg.Mirror.Runtimes, "not a runtime id")
+	require.Error(cfg.Validate(), "malformed runtime identifiers should be rejected")
+}
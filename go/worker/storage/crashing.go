@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/oasisprotocol/oasis-core/go/common/crash"
+	"github.com/oasisprotocol/oasis-core/go/common/crypto/hash"
 	"github.com/oasisprotocol/oasis-core/go/storage/api"
 )
 
@@ -55,6 +56,13 @@ func (w *crashingWrapper) Apply(ctx context.Context, request *api.ApplyRequest)
 	return err
 }
 
+func (w *crashingWrapper) ApplyBatch(ctx context.Context, request *api.ApplyBatchRequest) ([]hash.Hash, error) {
+	crash.Here(crashPointWriteBefore)
+	newRoots, err := w.LocalBackend.ApplyBatch(ctx, request)
+	crash.Here(crashPointWriteAfter)
+	return newRoots, err
+}
+
 func newCrashingWrapper(base api.LocalBackend) api.LocalBackend {
 	return &crashingWrapper{
 		LocalBackend: base,
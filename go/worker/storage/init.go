@@ -12,8 +12,11 @@ import (
 	"github.com/oasisprotocol/oasis-core/go/config"
 
 	cmdFlags "github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/flags"
+	"github.com/oasisprotocol/oasis-core/go/oasis-node/cmd/common/metrics"
 	"github.com/oasisprotocol/oasis-core/go/storage/api"
 	"github.com/oasisprotocol/oasis-core/go/storage/database"
+	nodedb "github.com/oasisprotocol/oasis-core/go/storage/mkvs/db/api"
+	workerConfig "github.com/oasisprotocol/oasis-core/go/worker/storage/config"
 )
 
 const cfgCrashEnabled = "worker.storage.crash.enabled"
@@ -32,11 +35,21 @@ func NewLocalBackend(
 	namespace common.Namespace,
 ) (api.LocalBackend, error) {
 	cfg := &api.Config{
-		Backend:      strings.ToLower(config.GlobalConfig.Storage.Backend),
-		DB:           dataDir,
-		Namespace:    namespace,
-		MaxCacheSize: int64(config.ParseSizeInBytes(config.GlobalConfig.Storage.MaxCacheSize)),
-		NoFsync:      true, // Should be safe, storage will be re-applied on crashes.
+		Backend:                    strings.ToLower(config.GlobalConfig.Storage.Backend),
+		DB:                         dataDir,
+		Namespace:                  namespace,
+		MaxCacheSize:               int64(config.ParseSizeInBytes(config.GlobalConfig.Storage.MaxCacheSize)),
+		NoFsync:                    true, // Should be safe, storage will be re-applied on crashes.
+		RootCacheVersions:          config.GlobalConfig.Storage.RootCacheVersions,
+		NodeCacheSize:              uint64(config.ParseSizeInBytes(config.GlobalConfig.Storage.NodeCacheSize)),
+		AllowResumeMultipart:       config.GlobalConfig.Storage.CheckpointSyncResume,
+		MetricsEnabled:             metrics.Enabled(),
+		TombstoneRetentionVersions: config.GlobalConfig.Storage.TombstoneRetentionVersions,
+		BadgerOptions:              badgerOptions(&config.GlobalConfig.Storage.Badger),
+		VerifyNodeHashes:           config.GlobalConfig.Storage.VerifyNodeHashes,
+		CorruptionErrorThreshold:   config.GlobalConfig.Storage.CorruptionErrorThreshold,
+		CorruptionErrorWindow:      config.GlobalConfig.Storage.CorruptionErrorWindow,
+		AllowRepair:                config.GlobalConfig.Storage.RootCheck.AutoResync,
 	}
 
 	cfg.DB = GetLocalBackendDBDir(dataDir, cfg.Backend)
@@ -53,6 +66,22 @@ func NewLocalBackend(
 	return api.NewMetricsWrapper(impl).(api.LocalBackend), nil
 }
 
+func badgerOptions(cfg *workerConfig.BadgerConfig) *nodedb.BadgerOptions {
+	return &nodedb.BadgerOptions{
+		MemTableSize:     int64(config.ParseSizeInBytes(cfg.MemTableSize)),   // nolint: gosec
+		BaseTableSize:    int64(config.ParseSizeInBytes(cfg.BaseTableSize)),  // nolint: gosec
+		BlockCacheSize:   int64(config.ParseSizeInBytes(cfg.BlockCacheSize)), // nolint: gosec
+		NumCompactors:    cfg.NumCompactors,
+		CompactL0OnClose: cfg.CompactL0OnClose,
+		ValueThreshold:   int64(config.ParseSizeInBytes(cfg.ValueThreshold)), // nolint: gosec
+		SyncWrites:       cfg.SyncWrites,
+
+		UpdatedNodesChunkSize:    int64(config.ParseSizeInBytes(cfg.UpdatedNodesChunkSize)), // nolint: gosec
+		MultipartNodeFilterLimit: cfg.MultipartNodeFilterLimit,
+		SplitMetadata:            cfg.SplitMetadata,
+	}
+}
+
 func init() {
 	Flags.Bool(cfgCrashEnabled, false, "UNSAFE: Enable the crashing storage wrapper")
 	_ = Flags.MarkHidden(cfgCrashEnabled)
@@ -9,7 +9,7 @@ import (
 var _ api.StorageWorker = (*Worker)(nil)
 
 func (w *Worker) GetLastSyncedRound(_ context.Context, request *api.GetLastSyncedRoundRequest) (*api.GetLastSyncedRoundResponse, error) {
-	node := w.runtimes[request.RuntimeID]
+	node := w.GetRuntime(request.RuntimeID)
 	if node == nil {
 		return nil, api.ErrRuntimeNotFound
 	}
@@ -23,7 +23,7 @@ func (w *Worker) GetLastSyncedRound(_ context.Context, request *api.GetLastSynce
 }
 
 func (w *Worker) PauseCheckpointer(_ context.Context, request *api.PauseCheckpointerRequest) error {
-	node := w.runtimes[request.RuntimeID]
+	node := w.GetRuntime(request.RuntimeID)
 	if node == nil {
 		return api.ErrRuntimeNotFound
 	}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/oasisprotocol/oasis-core/go/common"
 	"github.com/oasisprotocol/oasis-core/go/common/grpc"
@@ -24,9 +25,13 @@ type Worker struct {
 	logger       *logging.Logger
 
 	initCh chan struct{}
+	stopCh chan struct{}
 	quitCh chan struct{}
 
-	runtimes map[common.Namespace]*committee.Node
+	runtimesLock sync.RWMutex
+	runtimes     map[common.Namespace]*committee.Node
+	started      bool
+	stopped      bool
 }
 
 // New constructs a new storage worker.
@@ -35,7 +40,8 @@ func New(
 	commonWorker *workerCommon.Worker,
 	registration *registration.Worker,
 ) (*Worker, error) {
-	enabled := config.GlobalConfig.Mode.HasLocalStorage() && len(commonWorker.GetRuntimes()) > 0
+	dynamic := commonWorker.GetConfig().AllowDynamicRuntimes
+	enabled := config.GlobalConfig.Mode.HasLocalStorage() && (len(commonWorker.GetRuntimes()) > 0 || dynamic)
 
 	s := &Worker{
 		enabled:      enabled,
@@ -43,6 +49,7 @@ func New(
 		registration: registration,
 		logger:       logging.GetLogger("worker/storage"),
 		initCh:       make(chan struct{}),
+		stopCh:       make(chan struct{}),
 		quitCh:       make(chan struct{}),
 		runtimes:     make(map[common.Namespace]*committee.Node),
 	}
@@ -57,6 +64,7 @@ func New(
 			return nil, fmt.Errorf("failed to create storage worker for runtime %s: %w", id, err)
 		}
 	}
+	s.commonWorker.OnRuntimeAdded(s.addRuntime)
 
 	// Attach the storage worker's internal GRPC interface.
 	storageWorkerAPI.RegisterService(grpcInternal.Server(), s)
@@ -70,18 +78,26 @@ func (w *Worker) registerRuntime(commonNode *committeeCommon.Node) error {
 		"runtime_id", id,
 	)
 
-	// Since the storage node is always coupled with another role, make sure to not add any
-	// particular role here. Instead this only serves to prevent registration until the storage node
-	// is synced by making the role provider unavailable.
-	rp, err := w.registration.NewRuntimeRoleProvider(node.RoleEmpty, id)
-	if err != nil {
-		return fmt.Errorf("failed to create role provider: %w", err)
-	}
-	var rpRPC registration.RoleProvider
-	if config.GlobalConfig.Storage.PublicRPCEnabled {
-		rpRPC, err = w.registration.NewRuntimeRoleProvider(node.RoleStorageRPC, id)
+	// Mirrored runtimes only follow and sync state, so the node never registers for them.
+	mirror := commonNode.Mirror
+
+	var (
+		rp, rpRPC registration.RoleProvider
+		err       error
+	)
+	if !mirror {
+		// Since the storage node is always coupled with another role, make sure to not add any
+		// particular role here. Instead this only serves to prevent registration until the storage
+		// node is synced by making the role provider unavailable.
+		rp, err = w.registration.NewRuntimeRoleProvider(node.RoleEmpty, id)
 		if err != nil {
-			return fmt.Errorf("failed to create rpc role provider: %w", err)
+			return fmt.Errorf("failed to create role provider: %w", err)
+		}
+		if config.GlobalConfig.Storage.PublicRPCEnabled {
+			rpRPC, err = w.registration.NewRuntimeRoleProvider(node.RoleStorageRPC, id)
+			if err != nil {
+				return fmt.Errorf("failed to create rpc role provider: %w", err)
+			}
 		}
 	}
 
@@ -110,6 +126,7 @@ func (w *Worker) registerRuntime(commonNode *committeeCommon.Node) error {
 
 	w.logger.Info("new runtime registered",
 		"runtime_id", id,
+		"mirror", mirror,
 	)
 
 	return nil
@@ -142,25 +159,38 @@ func (w *Worker) Start() error {
 		return nil
 	}
 
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
 	// Wait for all runtimes to terminate.
 	go func() {
 		defer close(w.quitCh)
 
-		for _, r := range w.runtimes {
+		if w.commonWorker.GetConfig().AllowDynamicRuntimes {
+			// Runtimes may be added until the worker is stopped.
+			<-w.stopCh
+		}
+
+		for _, r := range w.getRuntimes() {
 			<-r.Quit()
 		}
 	}()
 
-	// Start all runtimes and wait for initialization.
+	// Start all configured runtimes and wait for initialization.
+	runtimes := make([]*committee.Node, 0, len(w.runtimes))
+	for _, r := range w.runtimes {
+		runtimes = append(runtimes, r)
+	}
+	w.started = true
 	go func() {
-		w.logger.Info("starting storage sync services", "num_runtimes", len(w.runtimes))
+		w.logger.Info("starting storage sync services", "num_runtimes", len(runtimes))
 
-		for _, r := range w.runtimes {
+		for _, r := range runtimes {
 			_ = r.Start()
 		}
 
 		// Wait for runtimes to be initialized.
-		for _, r := range w.runtimes {
+		for _, r := range runtimes {
 			<-r.Initialized()
 		}
 
@@ -179,6 +209,15 @@ func (w *Worker) Stop() {
 		return
 	}
 
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
+	if w.stopped {
+		return
+	}
+	w.stopped = true
+	close(w.stopCh)
+
 	for _, r := range w.runtimes {
 		r.Stop()
 	}
@@ -197,5 +236,41 @@ func (w *Worker) Cleanup() {
 //
 // In case the runtime with the specified id was not configured for this node it returns nil.
 func (w *Worker) GetRuntime(id common.Namespace) *committee.Node {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
 	return w.runtimes[id]
 }
+
+func (w *Worker) getRuntimes() []*committee.Node {
+	w.runtimesLock.RLock()
+	defer w.runtimesLock.RUnlock()
+
+	runtimes := make([]*committee.Node, 0, len(w.runtimes))
+	for _, r := range w.runtimes {
+		runtimes = append(runtimes, r)
+	}
+	return runtimes
+}
+
+// addRuntime sets up storage for a runtime added while the node is running.
+func (w *Worker) addRuntime(commonNode *committeeCommon.Node) error {
+	w.runtimesLock.Lock()
+	defer w.runtimesLock.Unlock()
+
+	if w.stopped {
+		return fmt.Errorf("storage worker stopped")
+	}
+	if err := w.registerRuntime(commonNode); err != nil {
+		return fmt.Errorf("failed to create storage worker for runtime %s: %w", commonNode.Runtime.ID(), err)
+	}
+	if !w.started {
+		return nil
+	}
+
+	w.logger.Info("starting storage sync services for runtime",
+		"runtime_id", commonNode.Runtime.ID(),
+	)
+
+	return w.runtimes[commonNode.Runtime.ID()].Start()
+}